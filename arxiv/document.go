@@ -0,0 +1,108 @@
+// Package arxiv converts arXiv OAI-PMH ListRecords responses in the oai_dc
+// metadata format into the intermediate schema.
+package arxiv
+
+import (
+	"io"
+	"log"
+	"strings"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+	"github.com/miku/span/oaidc"
+)
+
+const (
+	// SourceID for internal bookkeeping.
+	SourceID = "143"
+	// Collection name.
+	Collection = "arXiv"
+	// Format for all records, arXiv only holds preprints.
+	Format = "ElectronicPreprint"
+	// BatchSize number of documents per batch.
+	BatchSize = 2000
+)
+
+// Arxiv source.
+type Arxiv struct{}
+
+// Record is a single OAI-PMH record with Dublin Core metadata.
+type Record struct {
+	*oaidc.Record
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(docs []*Record) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			return s.(span.Importer), nil
+		}, Items: make([]interface{}, len(docs))}
+	for i, doc := range docs {
+		batch.Items[i] = doc
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via XML decoding. The input may be a
+// single response or many concatenated responses, as written by harvesters.
+func (s Arxiv) Iterate(r io.Reader) (<-chan interface{}, error) {
+	ch := make(chan interface{})
+	var docs []*Record
+	go func() {
+		err := oaidc.Decode(r, func(record *oaidc.Record) {
+			docs = append(docs, &Record{Record: record})
+			if len(docs) == BatchSize {
+				ch <- NewBatch(docs)
+				docs = nil
+			}
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		ch <- NewBatch(docs)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// URL returns the abstract page of the preprint.
+func (doc *Record) URL() string {
+	for _, id := range doc.Metadata.DC.Identifier {
+		if strings.HasPrefix(id, "http://arxiv.org/abs/") || strings.HasPrefix(id, "https://arxiv.org/abs/") {
+			return strings.TrimSpace(id)
+		}
+	}
+	return ""
+}
+
+// Abstract returns the first description, that is not an author comment.
+func (doc *Record) Abstract() string {
+	for _, d := range doc.Metadata.DC.Description {
+		if !strings.HasPrefix(d, "Comment:") {
+			return strings.TrimSpace(d)
+		}
+	}
+	return ""
+}
+
+// ToIntermediateSchema converts an OAI record. The first dc:date is the
+// submission date of the first version. Deleted records and records without
+// a date are skipped.
+func (doc *Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	output, err := doc.Record.Convert(SourceID)
+	if err != nil {
+		return output, err
+	}
+
+	output.MegaCollection = Collection
+	output.Format = Format
+	output.Genre = "preprint"
+	output.RefType = "UNPB"
+
+	output.Abstract = doc.Abstract()
+	output.URL = nil
+	if u := doc.URL(); u != "" {
+		output.URL = append(output.URL, u)
+	}
+	return output, nil
+}
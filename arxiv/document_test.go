@@ -0,0 +1,92 @@
+package arxiv
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+	"github.com/miku/span/internal/spantest"
+)
+
+const example = `<?xml version="1.0" encoding="UTF-8"?>
+<OAI-PMH xmlns="http://www.openarchives.org/OAI/2.0/">
+<ListRecords>
+<record>
+	<header>
+		<identifier>oai:arXiv.org:0704.0001</identifier>
+		<datestamp>2008-11-13</datestamp>
+		<setSpec>physics:hep-ph</setSpec>
+	</header>
+	<metadata>
+		<oai_dc:dc xmlns:oai_dc="http://www.openarchives.org/OAI/2.0/oai_dc/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+			<dc:title>Calculation of prompt diphoton production cross sections at Tevatron and
+  LHC energies</dc:title>
+			<dc:creator>Balázs, C.</dc:creator>
+			<dc:creator>Berger, E. L.</dc:creator>
+			<dc:subject>High Energy Physics - Phenomenology</dc:subject>
+			<dc:description>A fully differential calculation in perturbative quantum chromodynamics.</dc:description>
+			<dc:description>Comment: 37 pages, 15 figures</dc:description>
+			<dc:date>2007-04-02</dc:date>
+			<dc:date>2007-07-24</dc:date>
+			<dc:type>text</dc:type>
+			<dc:identifier>http://arxiv.org/abs/0704.0001</dc:identifier>
+			<dc:identifier>Phys.Rev.D76:013009,2007</dc:identifier>
+			<dc:identifier>doi:10.1103/PhysRevD.76.013009</dc:identifier>
+		</oai_dc:dc>
+	</metadata>
+</record>
+<record>
+	<header status="deleted">
+		<identifier>oai:arXiv.org:0704.0002</identifier>
+		<datestamp>2008-11-13</datestamp>
+		<setSpec>math</setSpec>
+	</header>
+</record>
+<resumptionToken cursor="0" completeListSize="2"></resumptionToken>
+</ListRecords>
+</OAI-PMH>`
+
+func TestToIntermediateSchema(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, Arxiv{}, strings.NewReader(example))
+	if len(results) != 2 {
+		t.Fatalf("got %d records, want 2", len(results))
+	}
+	if errs[0] != nil {
+		t.Fatal(errs[0])
+	}
+	if _, ok := errs[1].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for a deleted record", errs[1])
+	}
+
+	is := results[0]
+	if is.Format != "ElectronicPreprint" || is.Genre != "preprint" {
+		t.Errorf("got format %s, genre %s, want ElectronicPreprint, preprint", is.Format, is.Genre)
+	}
+	if _, ok := finc.FormatSite[is.Format]; !ok {
+		t.Errorf("format %s has no site mapping", is.Format)
+	}
+	want := []string{"High Energy Physics - Phenomenology", "physics:hep-ph"}
+	if !reflect.DeepEqual(is.Subjects, want) {
+		t.Errorf("got %v, want %v", is.Subjects, want)
+	}
+	if is.ArticleTitle != "Calculation of prompt diphoton production cross sections at Tevatron and LHC energies" {
+		t.Errorf("got title %q", is.ArticleTitle)
+	}
+	if is.DOI != "10.1103/PhysRevD.76.013009" {
+		t.Errorf("got DOI %s", is.DOI)
+	}
+	if is.Abstract != "A fully differential calculation in perturbative quantum chromodynamics." {
+		t.Errorf("got abstract %q", is.Abstract)
+	}
+	if is.Date.Format("2006-01-02") != "2007-04-02" {
+		t.Errorf("got date %s, want 2007-04-02", is.Date.Format("2006-01-02"))
+	}
+	if len(is.Authors) != 2 || is.Authors[1].LastName != "Berger" || is.Authors[1].FirstName != "E. L." {
+		t.Errorf("got authors %v", is.Authors)
+	}
+	if !reflect.DeepEqual(is.URL, []string{"http://arxiv.org/abs/0704.0001"}) {
+		t.Errorf("got URL %v", is.URL)
+	}
+}
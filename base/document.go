@@ -0,0 +1,207 @@
+// Package base converts records of BASE (Bielefeld Academic Search Engine)
+// interface exports into the intermediate schema. Exports are Solr style XML
+// responses, with one doc element per record and typed, named fields:
+//
+//	<doc><str name="dcdocid">...</str><arr name="dccreator"><str>...</str></arr></doc>
+package base
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// SourceID for internal bookkeeping.
+	SourceID = "151"
+	// Collection name.
+	Collection = "BASE"
+	// DefaultFormat for normalized types without a mapping.
+	DefaultFormat = "ElectronicResourceRemoteAccess"
+	// BatchSize number of records per batch.
+	BatchSize = 2000
+)
+
+// normTypes maps the normalized BASE document type (dctypenorm) to format,
+// genre and RIS type. Longer codes refine shorter ones, e.g. 121 and 12.
+var normTypes = map[string][3]string{
+	"1":   {"ElectronicArticle", "document", "GEN"},
+	"11":  {"eBook", "book", "EBOOK"},
+	"111": {"ElectronicBookPart", "bookitem", "ECHAP"},
+	"12":  {"ElectronicArticle", "article", "JOUR"},
+	"121": {"ElectronicArticle", "article", "JOUR"},
+	"13":  {"ElectronicProceeding", "proceeding", "CPAPER"},
+	"14":  {"ElectronicResourceRemoteAccess", "report", "RPRT"},
+	"18":  {"ElectronicThesis", "document", "THES"},
+	"7":   {"ElectronicResourceRemoteAccess", "document", "DATA"},
+	"6":   {"ElectronicResourceRemoteAccess", "document", "COMP"},
+}
+
+// BASE source.
+type BASE struct{}
+
+// Document holds all named fields of a doc element, single values as a
+// slice of one.
+type Document struct {
+	Fields map[string][]string
+}
+
+// xmlField is a named value or an array of values.
+type xmlField struct {
+	XMLName xml.Name
+	Name    string   `xml:"name,attr"`
+	Value   string   `xml:",chardata"`
+	Values  []string `xml:",any"`
+}
+
+// UnmarshalXML collects the fields of a doc element.
+func (doc *Document) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var v struct {
+		Fields []xmlField `xml:",any"`
+	}
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+	doc.Fields = make(map[string][]string)
+	for _, f := range v.Fields {
+		if f.XMLName.Local == "arr" {
+			for _, s := range f.Values {
+				doc.Fields[f.Name] = append(doc.Fields[f.Name], strings.TrimSpace(s))
+			}
+			continue
+		}
+		doc.Fields[f.Name] = append(doc.Fields[f.Name], strings.TrimSpace(f.Value))
+	}
+	return nil
+}
+
+// Get returns the first value of a field.
+func (doc *Document) Get(name string) string {
+	if vs := doc.Fields[name]; len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(docs []*Document) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			return s.(span.Importer), nil
+		}, Items: make([]interface{}, len(docs))}
+	for i, doc := range docs {
+		batch.Items[i] = doc
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via XML decoding, one doc at a time.
+func (s BASE) Iterate(r io.Reader) (<-chan interface{}, error) {
+	ch := make(chan interface{})
+	var docs []*Document
+	go func() {
+		decoder := xml.NewDecoder(bufio.NewReader(r))
+		for {
+			t, _ := decoder.Token()
+			if t == nil {
+				break
+			}
+			switch se := t.(type) {
+			case xml.StartElement:
+				if se.Name.Local == "doc" {
+					doc := new(Document)
+					if err := decoder.DecodeElement(doc, &se); err != nil {
+						log.Fatal(err)
+					}
+					docs = append(docs, doc)
+					if len(docs) == BatchSize {
+						ch <- NewBatch(docs)
+						docs = nil
+					}
+				}
+			}
+		}
+		ch <- NewBatch(docs)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Date returns the publication date, or the publication year.
+func (doc *Document) Date() (time.Time, error) {
+	for _, v := range doc.Fields["dcdate"] {
+		for _, layout := range []string{"2006-01-02", "2006-01", "2006"} {
+			if len(v) >= len(layout) {
+				if t, err := time.Parse(layout, v[:len(layout)]); err == nil {
+					return t, nil
+				}
+			}
+		}
+	}
+	if t, err := time.Parse("2006", doc.Get("dcyear")); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("base: %s: no usable date", doc.Get("dcdocid"))
+}
+
+// types returns format, genre and RIS type for the most specific known
+// normalized type.
+func (doc *Document) types() [3]string {
+	for _, code := range doc.Fields["dctypenorm"] {
+		for i := len(code); i > 0; i-- {
+			if t, ok := normTypes[code[:i]]; ok {
+				return t
+			}
+		}
+	}
+	return [3]string{DefaultFormat, "unknown", "GEN"}
+}
+
+// ToIntermediateSchema converts a record. Records without a date are
+// skipped. The repository name goes into the data provider field.
+func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	id := doc.Get("dcdocid")
+	if id == "" {
+		return output, span.Skip{Reason: "base: record without dcdocid"}
+	}
+	output.Date, err = doc.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(id)))
+	output.SourceID = SourceID
+	output.RecordID = strings.TrimRight(enc, "=")
+	output.MegaCollection = Collection
+	output.DataProvider = doc.Get("dccollname")
+	types := doc.types()
+	output.Format, output.Genre, output.RefType = types[0], types[1], types[2]
+
+	output.ArticleTitle = doc.Get("dctitle")
+	for _, name := range doc.Fields["dccreator"] {
+		output.Authors = append(output.Authors, finc.Author{Name: name})
+	}
+	output.Abstract = doc.Get("dcdescription")
+	output.Publishers = doc.Fields["dcpublisher"]
+	output.Subjects = doc.Fields["dcsubject"]
+	output.DOI = doc.Get("dcdoi")
+	if link := doc.Get("dclink"); link != "" {
+		output.URL = append(output.URL, link)
+	}
+	for _, lang := range doc.Fields["dclang"] {
+		output.Languages = append(output.Languages, span.NormalizeLanguage(lang))
+	}
+	output.OpenAccess = doc.Get("dcoa") == "1"
+	return output, nil
+}
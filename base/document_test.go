@@ -0,0 +1,67 @@
+package base
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/internal/spantest"
+)
+
+const example = `<?xml version="1.0" encoding="UTF-8"?>
+<response>
+<lst name="responseHeader"><int name="status">0</int></lst>
+<result name="response" numFound="2" start="0">
+<doc>
+	<str name="dcdocid">ftexample:oai:repo.example.org:1</str>
+	<str name="dccollection">ftexample</str>
+	<str name="dccollname">Example Repository</str>
+	<arr name="dccreator"><str>Doe, Jane</str><str>Roe, Richard</str></arr>
+	<str name="dctitle">A repository article</str>
+	<arr name="dcsubject"><str>biology</str></arr>
+	<str name="dcdescription">An abstract.</str>
+	<arr name="dcpublisher"><str>Example University</str></arr>
+	<arr name="dcdate"><str>2016-11-02</str></arr>
+	<int name="dcyear">2016</int>
+	<arr name="dctypenorm"><str>121</str></arr>
+	<str name="dclink">https://repo.example.org/1</str>
+	<arr name="dcdoi"><str>10.1000/base</str></arr>
+	<arr name="dclang"><str>ger</str></arr>
+	<str name="dcoa">1</str>
+</doc>
+<doc>
+	<str name="dcdocid">ftexample:oai:repo.example.org:2</str>
+	<str name="dctitle">Undated</str>
+</doc>
+</result>
+</response>`
+
+func TestBASE(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, BASE{}, strings.NewReader(example))
+	if len(results) != 2 {
+		t.Fatalf("got %d records, want 2", len(results))
+	}
+	if errs[0] != nil {
+		t.Fatal(errs[0])
+	}
+	if _, ok := errs[1].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for a record without date", errs[1])
+	}
+	is := results[0]
+	if is.ArticleTitle != "A repository article" || is.DataProvider != "Example Repository" {
+		t.Errorf("got title %q, provider %q", is.ArticleTitle, is.DataProvider)
+	}
+	if len(is.Authors) != 2 || is.Authors[1].Name != "Roe, Richard" {
+		t.Errorf("got authors %v", is.Authors)
+	}
+	if is.Format != "ElectronicArticle" || is.Genre != "article" || !is.OpenAccess {
+		t.Errorf("got format %s, genre %s, open access %v", is.Format, is.Genre, is.OpenAccess)
+	}
+	if is.Date.Format("2006-01-02") != "2016-11-02" || is.DOI != "10.1000/base" {
+		t.Errorf("got date %v, DOI %s", is.Date, is.DOI)
+	}
+	if !reflect.DeepEqual(is.Languages, []string{"deu"}) || !reflect.DeepEqual(is.Subjects, []string{"biology"}) {
+		t.Errorf("got languages %v, subjects %v", is.Languages, is.Subjects)
+	}
+}
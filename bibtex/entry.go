@@ -0,0 +1,237 @@
+package bibtex
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// DefaultSourceID is used, if no source id is configured.
+	DefaultSourceID = "148"
+	// DefaultCollection is used, if no collection is configured.
+	DefaultCollection = "BibTeX"
+	// BatchSize number of entries per batch.
+	BatchSize = 2000
+)
+
+// entryTypes maps supported entry types to format, genre and RIS type.
+var entryTypes = map[string][3]string{
+	"article":       {"ElectronicArticle", "article", "JOUR"},
+	"book":          {"eBook", "book", "BOOK"},
+	"inproceedings": {"ElectronicProceeding", "proceeding", "CPAPER"},
+	"conference":    {"ElectronicProceeding", "proceeding", "CPAPER"},
+}
+
+var (
+	yearPattern  = regexp.MustCompile(`[12][0-9]{3}`)
+	andPattern   = regexp.MustCompile(`(?i)\s+and\s+`)
+	pagesPattern = regexp.MustCompile(`^\s*([^-\s]+)\s*-+\s*([^-\s]+)\s*$`)
+)
+
+// BibTeX source. Source id and collection depend on the provider, empty
+// values fall back to the defaults. Since @string definitions may appear
+// anywhere, a database is read into memory as a whole.
+type BibTeX struct {
+	SourceID   string
+	Collection string
+}
+
+// Document is an entry together with the source settings.
+type Document struct {
+	Entry
+	source BibTeX
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(docs []*Document) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			return s.(span.Importer), nil
+		}, Items: make([]interface{}, len(docs))}
+	for i, doc := range docs {
+		batch.Items[i] = doc
+	}
+	return batch
+}
+
+// Iterate parses the database and emits its entries.
+func (s BibTeX) Iterate(r io.Reader) (<-chan interface{}, error) {
+	if s.SourceID == "" {
+		s.SourceID = DefaultSourceID
+	}
+	if s.Collection == "" {
+		s.Collection = DefaultCollection
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := Parse(string(b))
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan interface{})
+	go func() {
+		var docs []*Document
+		for _, e := range entries {
+			docs = append(docs, &Document{Entry: e, source: s})
+			if len(docs) == BatchSize {
+				ch <- NewBatch(docs)
+				docs = nil
+			}
+		}
+		ch <- NewBatch(docs)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Field returns the plain text value of a field.
+func (e Entry) Field(name string) string {
+	return DecodeLaTeX(e.Fields[name])
+}
+
+// Authors splits the author field at "and" outside of braces, so corporate
+// names like {Barnes and Noble} stay intact. Names are "Last, First" or
+// "First Last".
+func (e Entry) Authors() (authors []finc.Author) {
+	var (
+		raw   = e.Fields["author"]
+		depth int
+		start int
+		names []string
+	)
+	for i := 0; i < len(raw); i++ {
+		switch raw[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		default:
+			if depth == 0 {
+				if loc := andPattern.FindStringIndex(raw[i:]); loc != nil && loc[0] == 0 {
+					names = append(names, raw[start:i])
+					i += loc[1] - 1
+					start = i + 1
+				}
+			}
+		}
+	}
+	names = append(names, raw[start:])
+	for _, name := range names {
+		if strings.HasPrefix(strings.TrimSpace(name), "{") && strings.HasSuffix(strings.TrimSpace(name), "}") {
+			if v := DecodeLaTeX(name); v != "" {
+				authors = append(authors, finc.Author{Corporation: v})
+			}
+			continue
+		}
+		name = DecodeLaTeX(name)
+		if name == "" {
+			continue
+		}
+		if parts := strings.SplitN(name, ",", 2); len(parts) == 2 {
+			authors = append(authors, finc.Author{LastName: strings.TrimSpace(parts[0]), FirstName: strings.TrimSpace(parts[1])})
+			continue
+		}
+		fields := strings.Fields(name)
+		if len(fields) == 1 {
+			authors = append(authors, finc.Author{LastName: name})
+			continue
+		}
+		authors = append(authors, finc.Author{
+			LastName:  fields[len(fields)-1],
+			FirstName: strings.Join(fields[:len(fields)-1], " "),
+		})
+	}
+	return authors
+}
+
+// Date combines year and month. A month may be a name, an abbreviation or a
+// number.
+func (e Entry) Date() (time.Time, error) {
+	year := yearPattern.FindString(e.Fields["year"])
+	if year == "" {
+		return time.Time{}, fmt.Errorf("bibtex: %s: year is missing", e.Key)
+	}
+	month := strings.TrimSpace(e.Field("month"))
+	if month == "" {
+		return time.Parse("2006", year)
+	}
+	for _, layout := range []string{"2006 January", "2006 Jan", "2006 1"} {
+		if t, err := time.Parse(layout, year+" "+month); err == nil {
+			return t, nil
+		}
+	}
+	return time.Parse("2006", year)
+}
+
+// ToIntermediateSchema converts article, book and inproceedings entries.
+// Other entry types and entries without year are skipped.
+func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	types, ok := entryTypes[doc.Type]
+	if !ok {
+		return output, span.Skip{Reason: fmt.Sprintf("bibtex: %s: unsupported entry type %s", doc.Key, doc.Type)}
+	}
+	output.Date, err = doc.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+
+	enc := fmt.Sprintf("ai-%s-%s", doc.source.SourceID, base64.URLEncoding.EncodeToString([]byte(doc.Key)))
+	output.SourceID = doc.source.SourceID
+	output.RecordID = strings.TrimRight(enc, "=")
+	output.MegaCollection = doc.source.Collection
+	output.Format, output.Genre, output.RefType = types[0], types[1], types[2]
+
+	output.ArticleTitle = doc.Field("title")
+	output.Authors = doc.Authors()
+	switch doc.Type {
+	case "article":
+		output.JournalTitle = doc.Field("journal")
+	case "inproceedings", "conference":
+		output.BookTitle = doc.Field("booktitle")
+		output.JournalTitle = output.BookTitle
+	}
+	output.Volume = doc.Field("volume")
+	output.Issue = doc.Field("number")
+	if m := pagesPattern.FindStringSubmatch(doc.Fields["pages"]); m != nil {
+		output.StartPage, output.EndPage = m[1], m[2]
+		output.Pages = m[1] + "-" + m[2]
+	} else {
+		output.Pages = doc.Field("pages")
+	}
+	output.DOI = doc.Field("doi")
+	if v := doc.Field("issn"); v != "" {
+		output.ISSN = append(output.ISSN, v)
+	}
+	if v := doc.Field("isbn"); v != "" {
+		output.ISBN = append(output.ISBN, v)
+	}
+	if v := doc.Field("url"); v != "" {
+		output.URL = append(output.URL, v)
+	}
+	if v := doc.Field("publisher"); v != "" {
+		output.Publishers = append(output.Publishers, v)
+	}
+	if v := doc.Field("address"); v != "" {
+		output.Places = append(output.Places, v)
+	}
+	output.Abstract = doc.Field("abstract")
+	for _, kw := range strings.Split(doc.Field("keywords"), ",") {
+		if kw = strings.TrimSpace(kw); kw != "" {
+			output.Subjects = append(output.Subjects, kw)
+		}
+	}
+	return output, nil
+}
@@ -0,0 +1,64 @@
+package bibtex
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+	"github.com/miku/span/internal/spantest"
+)
+
+func TestToIntermediateSchema(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, BibTeX{}, strings.NewReader(example))
+	if len(results) != 4 {
+		t.Fatalf("got %d records, want 4", len(results))
+	}
+	for _, err := range errs[:3] {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := errs[3].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for a misc entry", errs[3])
+	}
+
+	article := results[0]
+	if article.ArticleTitle != "On examples – a Survey" || article.JournalTitle != "Journal of Examples" {
+		t.Errorf("got title %q, journal %q", article.ArticleTitle, article.JournalTitle)
+	}
+	want := []finc.Author{
+		{LastName: "Müller", FirstName: "Jörg"},
+		{LastName: "Doe", FirstName: "Jane"},
+		{Corporation: "Barnes and Noble"},
+	}
+	if !reflect.DeepEqual(article.Authors, want) {
+		t.Errorf("got authors %+v, want %+v", article.Authors, want)
+	}
+	if article.Date.Format("2006-01") != "2015-05" {
+		t.Errorf("got date %v", article.Date)
+	}
+	if article.StartPage != "101" || article.EndPage != "110" || article.Pages != "101-110" {
+		t.Errorf("got pages %s, %s, %s", article.StartPage, article.EndPage, article.Pages)
+	}
+	if article.Format != "ElectronicArticle" || !reflect.DeepEqual(article.ISSN, []string{"1234-5678"}) {
+		t.Errorf("got format %s, ISSN %v", article.Format, article.ISSN)
+	}
+	if !reflect.DeepEqual(article.Subjects, []string{"examples", "surveys"}) {
+		t.Errorf("got subjects %v", article.Subjects)
+	}
+
+	book := results[1]
+	if book.Format != "eBook" || book.ArticleTitle != "A Book on Façades" || !reflect.DeepEqual(book.ISBN, []string{"9783161484100"}) {
+		t.Errorf("got format %s, title %q, ISBN %v", book.Format, book.ArticleTitle, book.ISBN)
+	}
+
+	proc := results[2]
+	if proc.Format != "ElectronicProceeding" || proc.BookTitle != "Proceedings of the Example Conference" {
+		t.Errorf("got format %s, book title %q", proc.Format, proc.BookTitle)
+	}
+	if proc.Date.Format("2006-01") != "2010-07" {
+		t.Errorf("got date %v", proc.Date)
+	}
+}
@@ -0,0 +1,73 @@
+package bibtex
+
+import (
+	"regexp"
+	"strings"
+)
+
+// accents maps an accent command and a letter to a precomposed character.
+var accents = map[string]map[string]string{
+	`"`: {"a": "ä", "e": "ë", "i": "ï", "o": "ö", "u": "ü", "y": "ÿ", "A": "Ä", "E": "Ë", "I": "Ï", "O": "Ö", "U": "Ü"},
+	`'`: {"a": "á", "c": "ć", "e": "é", "i": "í", "n": "ń", "o": "ó", "s": "ś", "u": "ú", "y": "ý", "z": "ź", "A": "Á", "E": "É", "I": "Í", "O": "Ó", "U": "Ú", "Y": "Ý"},
+	"`": {"a": "à", "e": "è", "i": "ì", "o": "ò", "u": "ù", "A": "À", "E": "È", "I": "Ì", "O": "Ò", "U": "Ù"},
+	"^": {"a": "â", "e": "ê", "i": "î", "o": "ô", "u": "û", "A": "Â", "E": "Ê", "I": "Î", "O": "Ô", "U": "Û"},
+	"~": {"a": "ã", "n": "ñ", "o": "õ", "A": "Ã", "N": "Ñ", "O": "Õ"},
+	"c": {"c": "ç", "s": "ş", "C": "Ç", "S": "Ş"},
+	"v": {"c": "č", "e": "ě", "r": "ř", "s": "š", "z": "ž", "C": "Č", "R": "Ř", "S": "Š", "Z": "Ž"},
+	"H": {"o": "ő", "u": "ű", "O": "Ő", "U": "Ű"},
+	"k": {"a": "ą", "e": "ę", "A": "Ą", "E": "Ę"},
+	"=": {"a": "ā", "e": "ē", "i": "ī", "o": "ō", "u": "ū"},
+	".": {"z": "ż", "Z": "Ż"},
+}
+
+// combining marks are used for letters missing from the accents table.
+var combining = map[string]string{
+	`"`: "\u0308", `'`: "\u0301", "`": "\u0300", "^": "\u0302", "~": "\u0303",
+	"c": "\u0327", "v": "\u030c", "H": "\u030b", "k": "\u0328", "=": "\u0304", ".": "\u0307",
+}
+
+// symbols are commands, that stand for a single character.
+var symbols = map[string]string{
+	"ss": "ß", "o": "ø", "O": "Ø", "aa": "å", "AA": "Å", "ae": "æ", "AE": "Æ",
+	"oe": "œ", "OE": "Œ", "l": "ł", "L": "Ł", "i": "ı", "j": "ȷ",
+}
+
+var (
+	// accentPattern matches \"a, \"{a}, {\"a}, \c{c}, \c c and \'\i.
+	accentPattern  = regexp.MustCompile("\\{?\\\\([\"'`^~=.]|[cvHk](?:\\s+|\\{))\\s*\\{?(\\\\?[A-Za-z])\\}?\\}?")
+	symbolPattern  = regexp.MustCompile(`\{?\\(ss|aa|AA|ae|AE|oe|OE|[oOlLij])\b\s*\}?`)
+	commandPattern = regexp.MustCompile(`\\[A-Za-z]+\*?\s*`)
+	escapeReplacer = strings.NewReplacer(
+		`\&`, "&", `\%`, "%", `\$`, "$", `\_`, "_", `\#`, "#",
+		`\{`, "\x00", `\}`, "\x01", "---", "—", "--", "–", "~", " ",
+		"``", "“", "''", "”", `\,`, " ", `\ `, " ",
+	)
+)
+
+// DecodeLaTeX turns LaTeX markup into plain text: accents and special
+// characters become unicode, escaped characters are unescaped, other commands
+// and grouping braces are dropped, keeping their arguments.
+func DecodeLaTeX(s string) string {
+	if !strings.ContainsAny(s, `\{}~-`+"`'") {
+		return strings.Join(strings.Fields(s), " ")
+	}
+	s = accentPattern.ReplaceAllStringFunc(s, func(m string) string {
+		parts := accentPattern.FindStringSubmatch(m)
+		accent := strings.TrimRight(parts[1], " \t\r\n{")
+		letter := parts[2]
+		if letter == `\i` || letter == `\j` {
+			letter = letter[1:]
+		}
+		if c, ok := accents[accent][letter]; ok {
+			return c
+		}
+		return letter + combining[accent]
+	})
+	s = symbolPattern.ReplaceAllStringFunc(s, func(m string) string {
+		return symbols[symbolPattern.FindStringSubmatch(m)[1]]
+	})
+	s = escapeReplacer.Replace(s)
+	s = commandPattern.ReplaceAllString(s, "")
+	s = strings.NewReplacer("{", "", "}", "", "\x00", "{", "\x01", "}").Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
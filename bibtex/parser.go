@@ -0,0 +1,238 @@
+// Package bibtex parses BibTeX databases and converts article, book and
+// inproceedings entries into the intermediate schema.
+package bibtex
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Entry is a single BibTeX entry. Field names are lowercase, values keep
+// their LaTeX markup, macros are already expanded.
+type Entry struct {
+	Type   string
+	Key    string
+	Fields map[string]string
+}
+
+// defaultMacros are the month abbreviations every BibTeX style defines.
+var defaultMacros = map[string]string{
+	"jan": "January", "feb": "February", "mar": "March", "apr": "April",
+	"may": "May", "jun": "June", "jul": "July", "aug": "August",
+	"sep": "September", "oct": "October", "nov": "November", "dec": "December",
+}
+
+// parser is a small recursive descent parser over a whole database.
+type parser struct {
+	s      string
+	pos    int
+	line   int
+	macros map[string]string
+}
+
+// Parse returns all regular entries of a database. @string definitions are
+// expanded, @comment and @preamble are ignored, as is any text outside of
+// entries.
+func Parse(s string) ([]Entry, error) {
+	p := &parser{s: s, line: 1, macros: make(map[string]string)}
+	for k, v := range defaultMacros {
+		p.macros[k] = v
+	}
+	var entries []Entry
+	for {
+		i := strings.IndexByte(p.s[p.pos:], '@')
+		if i < 0 {
+			return entries, nil
+		}
+		p.advance(i + 1)
+		entry, ok, err := p.entry()
+		if err != nil {
+			return entries, err
+		}
+		if ok {
+			entries = append(entries, entry)
+		}
+	}
+}
+
+func (p *parser) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("bibtex: line %d: %s", p.line, fmt.Sprintf(format, a...))
+}
+
+func (p *parser) advance(n int) {
+	p.line += strings.Count(p.s[p.pos:p.pos+n], "\n")
+	p.pos += n
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.advance(1)
+	}
+}
+
+func (p *parser) peek() byte {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+// ident reads a name, as used for entry types, field names and macros.
+func (p *parser) ident() string {
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(" \t\r\n{}(),=#\"", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// braced reads a balanced group, starting at an opening brace, and returns
+// its content.
+func (p *parser) braced() (string, error) {
+	depth, start := 0, p.pos+1
+	for i := p.pos; i < len(p.s); i++ {
+		switch p.s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				v := p.s[start:i]
+				p.advance(i + 1 - p.pos)
+				return v, nil
+			}
+		}
+	}
+	return "", p.errorf("unbalanced braces")
+}
+
+// quoted reads a string in double quotes, which may contain braced quotes.
+func (p *parser) quoted() (string, error) {
+	depth, start := 0, p.pos+1
+	for i := start; i < len(p.s); i++ {
+		switch p.s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '"':
+			if depth == 0 {
+				v := p.s[start:i]
+				p.advance(i + 1 - p.pos)
+				return v, nil
+			}
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// value reads parts joined by #, each braced, quoted, a number or a macro.
+func (p *parser) value() (string, error) {
+	var b strings.Builder
+	for {
+		p.skipSpace()
+		switch c := p.peek(); {
+		case c == '{':
+			v, err := p.braced()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(v)
+		case c == '"':
+			v, err := p.quoted()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(v)
+		default:
+			name := p.ident()
+			if name == "" {
+				return "", p.errorf("value expected")
+			}
+			if v, ok := p.macros[strings.ToLower(name)]; ok {
+				b.WriteString(v)
+			} else {
+				b.WriteString(name)
+			}
+		}
+		p.skipSpace()
+		if p.peek() != '#' {
+			return b.String(), nil
+		}
+		p.advance(1)
+	}
+}
+
+// entry parses everything after an @. The second return value is false for
+// comments, preambles and string definitions.
+func (p *parser) entry() (Entry, bool, error) {
+	typ := strings.ToLower(p.ident())
+	p.skipSpace()
+	open := p.peek()
+	if open != '{' && open != '(' {
+		// A stray @, e.g. in an email address outside of entries.
+		return Entry{}, false, nil
+	}
+	closing := byte('}')
+	if open == '(' {
+		closing = ')'
+	}
+	switch typ {
+	case "comment":
+		if open == '{' {
+			_, err := p.braced()
+			return Entry{}, false, err
+		}
+		return Entry{}, false, nil
+	case "preamble":
+		p.advance(1)
+		if _, err := p.value(); err != nil {
+			return Entry{}, false, err
+		}
+		p.skipSpace()
+		p.advance(1)
+		return Entry{}, false, nil
+	}
+	p.advance(1)
+	entry := Entry{Type: typ, Fields: make(map[string]string)}
+	if typ != "string" {
+		p.skipSpace()
+		entry.Key = p.ident()
+		p.skipSpace()
+		if p.peek() == ',' {
+			p.advance(1)
+		}
+	}
+	for {
+		p.skipSpace()
+		switch p.peek() {
+		case closing:
+			p.advance(1)
+			if typ == "string" {
+				for k, v := range entry.Fields {
+					p.macros[k] = v
+				}
+				return entry, false, nil
+			}
+			return entry, true, nil
+		case 0:
+			return entry, false, p.errorf("unterminated entry %s", entry.Key)
+		}
+		name := strings.ToLower(p.ident())
+		p.skipSpace()
+		if name == "" || p.peek() != '=' {
+			return entry, false, p.errorf("field expected in entry %s", entry.Key)
+		}
+		p.advance(1)
+		v, err := p.value()
+		if err != nil {
+			return entry, false, err
+		}
+		entry.Fields[name] = v
+		p.skipSpace()
+		if p.peek() == ',' {
+			p.advance(1)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package bibtex
+
+import (
+	"reflect"
+	"testing"
+)
+
+const example = `Some text before the first entry, mail@example.org.
+
+@string{jex = "Journal of " # {Examples}}
+
+@comment{ignored {nested} comment}
+@preamble{"\newcommand{\noop}[1]{}"}
+
+@Article{doe2015,
+	author    = {M{\"u}ller, J{\"o}rg and Jane Doe and {Barnes and Noble}},
+	title     = "On {\em examples} -- a {S}urvey",
+	journal   = jex,
+	year      = 2015,
+	month     = may,
+	volume    = {12},
+	number    = {3},
+	pages     = {101--110},
+	doi       = {10.1000/example},
+	issn      = {1234-5678},
+	keywords  = {examples, surveys},
+}
+
+@book(roe2001,
+	author = {Roe, Richard},
+	title = {A Book on Fa\c{c}ades},
+	publisher = {Example Press},
+	address = {Berlin},
+	isbn = {9783161484100},
+	year = {2001}
+)
+
+@inproceedings{conf2010,
+	author = {Jane Doe},
+	title = {A Talk},
+	booktitle = {Proceedings of the Example Conference},
+	year = {2010},
+	month = {7},
+}
+
+@misc{web, title = {A Website}, year = {2020}}
+`
+
+func TestParse(t *testing.T) {
+	entries, err := Parse(example)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, e := range entries {
+		keys = append(keys, e.Type+":"+e.Key)
+	}
+	want := []string{"article:doe2015", "book:roe2001", "inproceedings:conf2010", "misc:web"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("got %v, want %v", keys, want)
+	}
+	if got := entries[0].Fields["journal"]; got != "Journal of Examples" {
+		t.Errorf("got journal %q, want expanded macro", got)
+	}
+	if got := entries[0].Fields["month"]; got != "May" {
+		t.Errorf("got month %q, want May", got)
+	}
+	if got := entries[0].Fields["year"]; got != "2015" {
+		t.Errorf("got year %q", got)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, s := range []string{
+		`@article{x, title = {unbalanced}`,
+		`@article{x, title = "unterminated}`,
+		`@article{x, title {missing equals}}`,
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q): got nil, want error", s)
+		}
+	}
+}
+
+func TestDecodeLaTeX(t *testing.T) {
+	var cases = []struct {
+		s, want string
+	}{
+		{`M{\"u}ller`, "Müller"},
+		{`M\"{u}ller`, "Müller"},
+		{`Andr\'e`, "André"},
+		{`Fa\c{c}ade`, "Façade"},
+		{`Fa\c cade`, "Façade"},
+		{`Stra{\ss}e`, "Straße"},
+		{`\O{}stergaard`, "Østergaard"},
+		{`Ti\~{n}o`, "Tiño"},
+		{`Tom \& Jerry, 100\%`, "Tom & Jerry, 100%"},
+		{`{\em emphasized}  text`, "emphasized text"},
+		{`\textbf{bold}`, "bold"},
+		{`A {S}urvey -- 1999--2000`, "A Survey – 1999–2000"},
+		{`Dvo\v{r}\'ak`, "Dvořák"},
+		{`na\"{\i}ve`, "naïve"},
+	}
+	for _, c := range cases {
+		if got := DecodeLaTeX(c.s); got != c.want {
+			t.Errorf("DecodeLaTeX(%q): got %q, want %q", c.s, got, c.want)
+		}
+	}
+}
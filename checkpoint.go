@@ -0,0 +1,97 @@
+package span
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Checkpoint records the progress of a line oriented conversion: the byte
+// offset just past the last fully processed input line, the size of the
+// output, when that line was written, and the number of lines processed.
+type Checkpoint struct {
+	Input  int64 `json:"input"`
+	Output int64 `json:"output"`
+	Lines  int64 `json:"lines"`
+}
+
+// ReadCheckpoint reads a checkpoint from a file. A missing file yields the
+// zero checkpoint, which means start from the beginning.
+func ReadCheckpoint(filename string) (Checkpoint, error) {
+	var c Checkpoint
+	b, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(b, &c)
+	return c, err
+}
+
+// WriteCheckpoint replaces the checkpoint file atomically, so a crash while
+// writing leaves the previous checkpoint intact.
+func WriteCheckpoint(filename string, c Checkpoint) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), ".checkpoint-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// Resume positions input after the processed lines and cuts output back to
+// the size recorded in the checkpoint, discarding anything written after the
+// checkpoint, so records are neither lost nor duplicated.
+func (c Checkpoint) Resume(input io.Seeker, output *os.File) error {
+	if _, err := input.Seek(c.Input, io.SeekStart); err != nil {
+		return err
+	}
+	if err := output.Truncate(c.Output); err != nil {
+		return err
+	}
+	_, err := output.Seek(c.Output, io.SeekStart)
+	return err
+}
+
+// LineReader reads newline terminated lines and keeps track of the byte
+// offset just past the last line read and of the number of lines read. A
+// final line without newline is not returned, as it might be incomplete.
+// Lines can be set, e.g. to continue counting across files.
+type LineReader struct {
+	br     *bufio.Reader
+	Offset int64
+	Lines  int64
+}
+
+// NewLineReader reads lines from r, which is positioned at offset.
+func NewLineReader(r io.Reader, offset int64) *LineReader {
+	return &LineReader{br: bufio.NewReader(r), Offset: offset}
+}
+
+// ReadString returns the next line including the newline.
+func (r *LineReader) ReadString() (string, error) {
+	line, err := r.br.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	r.Offset += int64(len(line))
+	r.Lines++
+	return line, nil
+}
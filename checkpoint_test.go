@@ -0,0 +1,120 @@
+package span
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// copyLines copies at most n lines from r to w, all lines if n is negative.
+func copyLines(r *LineReader, w io.Writer, n int) error {
+	for i := 0; n < 0 || i < n; i++ {
+		line, err := r.ReadString()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, strings.ToUpper(line)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestCheckpointResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "span-checkpoint-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var lines []string
+	for i := 0; i < 10; i++ {
+		lines = append(lines, fmt.Sprintf("record %d\n", i))
+	}
+	input := filepath.Join(dir, "input.ldj")
+	if err := ioutil.WriteFile(input, []byte(strings.Join(lines, "")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "output.ldj")
+	checkpoint := filepath.Join(dir, "checkpoint.json")
+
+	c, err := ReadCheckpoint(checkpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != (Checkpoint{}) {
+		t.Fatalf("ReadCheckpoint: got %v for missing file, want zero value", c)
+	}
+
+	// First run: checkpoint after four lines, then crash after two more.
+	in, err := os.Open(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := os.Create(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewLineReader(in, 0)
+	if err := copyLines(r, out, 4); err != nil {
+		t.Fatal(err)
+	}
+	size, err := out.Seek(0, io.SeekCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteCheckpoint(checkpoint, Checkpoint{Input: r.Offset, Output: size, Lines: r.Lines}); err != nil {
+		t.Fatal(err)
+	}
+	if err := copyLines(r, out, 2); err != nil {
+		t.Fatal(err)
+	}
+	in.Close()
+	out.Close()
+
+	// Second run: resume from the checkpoint.
+	c, err = ReadCheckpoint(checkpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in, err = os.Open(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	out, err = os.OpenFile(output, os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Resume(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if c.Lines != 4 {
+		t.Errorf("ReadCheckpoint: got %d lines, want 4", c.Lines)
+	}
+	r = NewLineReader(in, c.Input)
+	r.Lines = c.Lines
+	if err := copyLines(r, out, -1); err != nil {
+		t.Fatal(err)
+	}
+	if r.Lines != int64(len(lines)) {
+		t.Errorf("LineReader: got %d lines, want %d", r.Lines, len(lines))
+	}
+	out.Close()
+
+	b, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.ToUpper(strings.Join(lines, ""))
+	if string(b) != want {
+		t.Errorf("resumed output: got %q, want %q", string(b), want)
+	}
+}
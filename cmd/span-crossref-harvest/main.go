@@ -0,0 +1,76 @@
+// Harvest works from the crossref REST API into line delimited JSON, which
+// can be converted with span-import -i crossref.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/crossref"
+)
+
+var errMailtoRequired = errors.New("contact address required (-mailto), for the polite pool")
+
+func main() {
+
+	showVersion := flag.Bool("v", false, "prints current program version")
+	mailto := flag.String("mailto", "", "contact address sent to the API")
+	fromIndexDate := flag.String("from-index-date", "", "only works indexed on or after this date (YYYY-MM-DD)")
+	untilIndexDate := flag.String("until-index-date", "", "only works indexed on or before this date (YYYY-MM-DD)")
+	filter := flag.String("filter", "", "additional comma separated crossref filters, e.g. type:journal-article")
+	rows := flag.Int("rows", 1000, "works per request, at most 1000")
+	interval := flag.Duration("interval", 100*time.Millisecond, "minimum time between requests")
+	retries := flag.Int("retries", 5, "retries per request")
+	endpoint := flag.String("endpoint", crossref.DefaultWorksEndpoint, "works API endpoint")
+	output := flag.String("o", "", "output file, defaults to stdout")
+
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Println(span.AppVersion)
+		os.Exit(0)
+	}
+
+	if *mailto == "" {
+		log.Fatal(errMailtoRequired)
+	}
+
+	h := crossref.NewHarvester(*mailto)
+	h.UserAgent = fmt.Sprintf("span/%s (mailto:%s)", span.AppVersion, *mailto)
+	h.Endpoint = *endpoint
+	h.Rows = *rows
+	h.Interval = *interval
+	h.Retries = *retries
+	if *fromIndexDate != "" {
+		h.Filter = append(h.Filter, "from-index-date:"+*fromIndexDate)
+	}
+	if *untilIndexDate != "" {
+		h.Filter = append(h.Filter, "until-index-date:"+*untilIndexDate)
+	}
+	if *filter != "" {
+		h.Filter = append(h.Filter, strings.Split(*filter, ",")...)
+	}
+
+	w := os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	start := time.Now()
+	n, err := h.Harvest(w)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("harvested %d works in %s", n, time.Since(start))
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// kafkaProducer sends messages to a Kafka topic. Messages are batched by the
+// underlying asynchronous producer.
+type kafkaProducer struct {
+	producer sarama.AsyncProducer
+	topic    string
+
+	wg  sync.WaitGroup
+	mu  sync.Mutex
+	err error
+}
+
+// newKafkaProducer connects to the brokers.
+func newKafkaProducer(brokers []string, topic string) (*kafkaProducer, error) {
+	config := sarama.NewConfig()
+	config.Producer.Return.Errors = true
+	config.Producer.Flush.Messages = 1000
+	config.Producer.Flush.Frequency = 500 * time.Millisecond
+	producer, err := sarama.NewAsyncProducer(brokers, config)
+	if err != nil {
+		return nil, err
+	}
+	p := &kafkaProducer{producer: producer, topic: topic}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for e := range producer.Errors() {
+			p.setErr(e.Err)
+		}
+	}()
+	return p, nil
+}
+
+// setErr keeps the first error.
+func (p *kafkaProducer) setErr(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// Produce queues a message. Blocks, if the producer cannot keep up. Returns
+// the first error of an earlier message, since errors are reported
+// asynchronously.
+func (p *kafkaProducer) Produce(key, value []byte) error {
+	p.mu.Lock()
+	err := p.err
+	p.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	p.producer.Input() <- &sarama.ProducerMessage{
+		Topic: p.topic,
+		Key:   sarama.ByteEncoder(key),
+		Value: sarama.ByteEncoder(value),
+	}
+	return nil
+}
+
+// Close flushes pending messages and returns the first error.
+func (p *kafkaProducer) Close() error {
+	if err := p.producer.Close(); err != nil {
+		p.setErr(err)
+	}
+	p.wg.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
@@ -8,12 +8,15 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"runtime"
 	"runtime/pprof"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/miku/span"
 	"github.com/miku/span/container"
@@ -23,15 +26,42 @@ import (
 // Options for worker.
 type options struct {
 	exportSchemaFunc func() finc.ExportSchema
-	tagger           span.ISILTagger
+	tagger           span.Tagger
+	marshal          func(finc.ExportSchema) ([]byte, error)
+	deletions        *span.DeletionTracker
+	fieldCap         *finc.FieldCap
+	issnReport       *span.ISSNReport
+	grep             *span.Grep
+	issnExtractor    *span.ISSNExtractor
+	deterministic    bool
+	fixtures         *span.FixtureSampler
+	schemaCheck      *span.SchemaCheck
+	coverageReport   *span.CoverageReport
+	completeness     *finc.CompletenessGate
+	sink             span.Sink
+}
+
+// Marshalers holds the available output encodings.
+var Marshalers = map[string]func(finc.ExportSchema) ([]byte, error){
+	"json": func(s finc.ExportSchema) ([]byte, error) { return json.Marshal(s) },
+	"protobuf": func(s finc.ExportSchema) ([]byte, error) {
+		m, ok := s.(finc.ProtoMarshaler)
+		if !ok {
+			return nil, errors.New("export schema does not support protobuf")
+		}
+		return m.MarshalProto()
+	},
 }
 
 // Exporters holds available export formats
 var Exporters = map[string]func() finc.ExportSchema{
 	"dummy":   func() finc.ExportSchema { return new(finc.DummySchema) },
-	"solr413": func() finc.ExportSchema { return new(finc.Solr413Schema) },
+	"solr413": func() finc.ExportSchema { s := solr413; return &s },
 }
 
+// solr413 holds the configuration for the solr413 export format.
+var solr413 = finc.Solr413Schema{IdentifierFunc: finc.RecordIdentifier}
+
 // parseTagPathString turns TAG:/path/to into single strings and returns them.
 func parseTagPathString(s string) (string, string, error) {
 	p := strings.Split(s, ":")
@@ -55,42 +85,226 @@ func parseTagPath(s string) (string, *os.File, error) {
 	return isil, file, nil
 }
 
+// parseDuring parses ISIL:YYYY-MM-DD:YYYY-MM-DD into an ISIL and a time window.
+func parseDuring(s string) (isil string, from, to time.Time, err error) {
+	p := strings.Split(s, ":")
+	if len(p) != 3 {
+		return isil, from, to, errors.New("invalid window, use ISIL:YYYY-MM-DD:YYYY-MM-DD")
+	}
+	isil = p[0]
+	if from, err = time.Parse("2006-01-02", p[1]); err != nil {
+		return isil, from, to, err
+	}
+	to, err = time.Parse("2006-01-02", p[2])
+	return isil, from, to, err
+}
+
+// splitFields splits a comma separated list of field names.
+func splitFields(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// writeTrace reads a single intermediate schema record, given as JSON or - for
+// stdin, and writes the filter trace for that record as JSON. If isil is
+// given, only the filters of that ISIL are explained.
+func writeTrace(w io.Writer, record string, tagger span.ISILTagger, isil string) error {
+	b := []byte(record)
+	if record == "-" {
+		var err error
+		if b, err = ioutil.ReadAll(os.Stdin); err != nil {
+			return err
+		}
+	}
+	var is finc.IntermediateSchema
+	if err := json.Unmarshal(b, &is); err != nil {
+		return err
+	}
+	var v interface{} = tagger.Trace(is)
+	if isil != "" {
+		v = tagger.Explain(is, isil)
+	}
+	b, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// writeUnmatched writes ISIL and ISSN tab separated for every holdings ISSN,
+// that did not match any record.
+func writeUnmatched(filename string, tracked map[string][]span.HoldingFilter) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	for isil, filters := range tracked {
+		for _, f := range filters {
+			for _, issn := range f.Unmatched() {
+				if _, err := fmt.Fprintf(w, "%s\t%s\n", isil, issn); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return w.Flush()
+}
+
 // worker iterates over string batches
 func worker(queue chan []string, out chan []byte, opts options, wg *sync.WaitGroup) {
 	defer wg.Done()
 	for batch := range queue {
+		var records []finc.IntermediateSchema
 		for _, s := range batch {
+			b := []byte(s)
+			// extracted ISSNs may be written differently in the raw record
+			if opts.grep != nil && opts.issnExtractor == nil && !opts.grep.MayMatch(b) {
+				continue
+			}
 			var err error
 			is := finc.IntermediateSchema{}
-			err = json.Unmarshal([]byte(s), &is)
+			err = json.Unmarshal(b, &is)
 			if err != nil {
 				log.Fatal(err)
 			}
+			if opts.issnExtractor != nil {
+				opts.issnExtractor.Apply(&is)
+			}
+			if opts.grep != nil && !opts.grep.Match(is) {
+				continue
+			}
+			if opts.completeness != nil && !opts.completeness.Keep(is) {
+				continue
+			}
+			if opts.issnReport != nil {
+				opts.issnReport.Add(is)
+				continue
+			}
+			if opts.fixtures != nil {
+				if opts.fixtures.Keep(is) {
+					out <- []byte(strings.TrimSpace(s))
+				}
+				continue
+			}
+			records = append(records, is)
+		}
+		// taggers, that ask a remote service, look up a whole batch at once
+		if p, ok := opts.tagger.(span.Prefetcher); ok {
+			if err := p.Prefetch(records); err != nil {
+				log.Fatal(err)
+			}
+		}
+		for _, is := range records {
 			schema := opts.exportSchemaFunc()
-			err = schema.Convert(is)
+			err := schema.Convert(is)
 			if err != nil {
 				log.Fatal(err)
 			}
-			schema.Attach(opts.tagger.Tags(is))
-			// TODO(miku): maybe move marshalling into Exporter, if we have
-			// anything else than JSON - function could be somethings like
-			// func Marshal() ([]byte, error)
-			b, err := json.Marshal(schema)
+			if opts.fieldCap != nil {
+				opts.fieldCap.Apply(schema)
+			}
+			isils := opts.tagger.Tags(is)
+			schema.Attach(isils)
+			if opts.deletions != nil {
+				opts.deletions.Seen(solr413.IdentifierFunc(is), isils)
+			}
+			if opts.coverageReport != nil {
+				opts.coverageReport.Add(is, isils)
+				continue
+			}
+			b, err := opts.marshal(schema)
 			if err != nil {
 				log.Fatal(err)
 			}
+			if opts.schemaCheck != nil {
+				if err := opts.schemaCheck.Check(b); err != nil {
+					log.Fatal(err)
+				}
+				continue
+			}
 			out <- b
 		}
 	}
 }
 
+// convert runs lines from r through the workers and writes the results to the
+// sink. It stops after limit lines, if limit is positive, and returns io.EOF,
+// once the input is exhausted. All output is flushed, when convert returns.
+// Batches are cut at multiples of size of the line count of r, so lines are
+// dispatched the same way, regardless of where a call or an input starts.
+func convert(r *span.LineReader, opts options, size, numWorkers, limit int) error {
+	// workers share a single queue, unless dispatch is deterministic
+	queues := make([]chan []string, 1)
+	if opts.deterministic {
+		queues = make([]chan []string, numWorkers)
+	}
+	for i := range queues {
+		queues[i] = make(chan []string)
+	}
+	dispatch := span.Dispatch{BatchSize: size, Workers: len(queues)}
+
+	out := make(chan []byte)
+	done := make(chan bool)
+	go opts.sink.Run(out, done)
+
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go worker(queues[i%len(queues)], out, opts, &wg)
+	}
+
+	var batch []string
+	var err error
+	for i := 0; limit <= 0 || i < limit; i++ {
+		n := int(r.Lines)
+		var line string
+		if line, err = r.ReadString(); err != nil {
+			break
+		}
+		batch = append(batch, line)
+		if dispatch.Batch(n) != dispatch.Batch(n+1) {
+			queues[dispatch.Worker(dispatch.Batch(n))] <- batch
+			batch = nil
+		}
+	}
+	if len(batch) > 0 {
+		queues[dispatch.Worker(dispatch.Batch(int(r.Lines)-1))] <- batch
+	}
+
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+	close(out)
+	<-done
+	if serr := opts.sink.Err(); serr != nil {
+		return serr
+	}
+	return err
+}
+
 func main() {
 
-	var hfiles, lfiles, any, source container.StringSlice
+	var hfiles, lfiles, any, source, during, pfiles, yfiles, grepISSN, grepDOI, noISSN, teeFiles container.StringSlice
 	flag.Var(&hfiles, "f", "ISIL:/path/to/ovid.xml")
 	flag.Var(&lfiles, "l", "ISIL:/path/to/list.txt")
 	flag.Var(&any, "any", "ISIL")
 	flag.Var(&source, "source", "ISIL:SID")
+	flag.Var(&noISSN, "no-issn", "ISIL:SID, attach records without ISSN from a source")
+	flag.Var(&yfiles, "issn-year", "ISIL:/path/to/issn-year.txt")
+	flag.Var(&pfiles, "publisher", "ISIL:/path/to/publishers.txt, requires -prefixes")
+	flag.Var(&during, "during", "ISIL:YYYY-MM-DD:YYYY-MM-DD, restrict filters of ISIL to a time window")
+	flag.Var(&teeFiles, "tee", "also write output to this file, can be repeated")
+	flag.Var(&grepISSN, "grep-issn", "only convert records with this ISSN")
+	flag.Var(&grepDOI, "grep-doi", "only convert records with this DOI")
 
 	skip := flag.Bool("skip", false, "skip errors")
 	showVersion := flag.Bool("v", false, "prints current program version")
@@ -100,6 +314,54 @@ func main() {
 	cpuprofile := flag.String("cpuprofile", "", "write cpu profile to file")
 	format := flag.String("o", "solr413", "output format")
 	listFormats := flag.Bool("list", false, "list output formats")
+	idStrategy := flag.String("id", "record_id", "id strategy: record_id, doi, hash, composite")
+	traceRecord := flag.String("trace-record", "", "print filter trace for a single JSON record (- for stdin) and exit")
+	explainISIL := flag.String("explain", "", "with -trace-record, explain why the filters of this ISIL did or did not match")
+	prefixFile := flag.String("prefixes", "", "path to TSV file mapping DOI prefixes to publishers")
+	precedence := flag.Bool("precedence", false, "per ISIL, only the first matching filter applies (order: -f, -l, -issn-year, -publisher, -source)")
+	priorFile := flag.String("prior", "", "output of a prior run, to find records to delete, requires -deletions")
+	deletionsFile := flag.String("deletions", "", "write SOLR delete documents for records, that are not attached anymore")
+	minCompleteness := flag.Int("min-completeness", 0, fmt.Sprintf("drop records with a completeness score below this value, max %d", finc.MaxCompleteness))
+	maxFieldLength := flag.Int("max-field-length", 0, "truncate long text fields to this many bytes, 0 means no limit")
+	encoding := flag.String("format", "json", "output encoding: json or protobuf (length-delimited)")
+	rawText := flag.Bool("raw-text", false, "keep whitespace and control characters in titles, publishers and allfields")
+	collectionsFile := flag.String("collections", "", "path to JSON object mapping source ids to mega collections")
+	urlMode := flag.String("url-mode", "raw", "record URLs: raw, doi-resolver or proxy-prefixed-doi-resolver")
+	urlProxy := flag.String("url-proxy", "", "proxy prefix for -url-mode proxy-prefixed-doi-resolver")
+	keepURL := flag.Bool("keep-url", false, "with -url-mode, only construct DOI URLs for records without URL")
+	authorOrder := flag.String("author-order", "", "normalize and deduplicate author names: last-first or first-last")
+	sortAuthors := flag.Bool("sort-authors", false, "sort secondary authors, requires -author-order")
+	freePolicy := flag.String("free-policy", "wall", "moving walls for free entitlements: wall or ignore-wall")
+	futurePolicy := flag.String("future-policy", "as-is", "holdings filter handling of records dated in the future: as-is, attach, drop or clamp")
+	extractISSN := flag.String("extract-issn", "", "add valid ISSNs found in a text field to the record: allfields, abstract or fulltext")
+	issnSource := flag.String("issn-source", "both", "ISSNs considered by holdings, list and ISSN year filters: both, pissn or eissn")
+	coverageReport := flag.String("coverage-report", "", "instead of records, write covered and uncovered records per source and year for this ISIL")
+	coverageFormat := flag.String("coverage-format", "tsv", "coverage report format: tsv or json")
+	issnReport := flag.Bool("issn-report", false, "instead of records, write ISSNs and their number of records")
+	validate := flag.Bool("validate", false, "report ISILs with empty or missing filters and exit")
+	remoteTagger := flag.String("remote-tagger", "", "ask this HTTP endpoint for the ISILs of records, instead of evaluating local filters")
+	remoteTimeout := flag.Duration("remote-timeout", 30*time.Second, "timeout for requests to -remote-tagger")
+	remoteRetries := flag.Int("remote-retries", 3, "number of retries for failed requests to -remote-tagger")
+	configFile := flag.String("config", "", "path to JSON tagger configuration")
+	unmatchedFile := flag.String("unmatched", "", "write holdings ISSNs, that matched no record, to file")
+	outputFile := flag.String("output", "", "write to file instead of stdout")
+	checkpointFile := flag.String("checkpoint", "", "periodically record progress in this file, requires -output and a single input file")
+	checkpointEvery := flag.Int("checkpoint-every", 1000000, "write a checkpoint after this many lines")
+	fields := flag.String("fields", "", "comma separated list of fields to output, json only")
+	excludeFields := flag.String("exclude-fields", "", "comma separated list of fields to omit, json only")
+	sortBy := flag.String("sort-by", "", "buffer all records in memory and write them sorted by id, date or issn")
+	sortMaxBytes := flag.Int64("sort-max-bytes", span.DefaultSortMaxBytes, "refuse -sort-by for inputs larger than this many bytes")
+	showStats := flag.Bool("stats", false, "write a JSON summary with record counts and memory usage to stderr")
+	diagFile := flag.String("diag-json", "", "write warnings as JSON objects to this file, e.g. /dev/fd/3")
+	teePolicy := flag.String("tee-policy", "abort", "if one output fails: abort or drop (continue with the other outputs)")
+	deterministic := flag.Bool("deterministic-dispatch", false, "assign lines to batches and batches to workers by line number, e.g. for comparable profiles")
+	fixtures := flag.Int("fixtures", 0, "instead of converting, write up to this many intermediate schema records per holdings coverage category, requires a single -f")
+	kafkaBrokers := flag.String("kafka-brokers", "", "comma separated list of kafka brokers, send records to -kafka-topic instead of stdout")
+	kafkaTopic := flag.String("kafka-topic", "", "kafka topic, record id is used as message key")
+	strictHoldings := flag.Bool("strict-holdings", false, "fail, if a holdings file cannot be read; otherwise its ISIL is skipped with a warning and none of its records are attached, always on with -deletions, since all records of a skipped ISIL would be deleted")
+	maxOpenFiles := flag.Int("max-open-files", span.DefaultMaxOpenFiles, "number of holdings files to open and parse at once")
+	checkSchema := flag.String("check-schema", "", "instead of records, report fields not declared in this SOLR schema.xml or managed-schema, json only")
+	resume := flag.Bool("resume", false, "continue from -checkpoint and append to -output")
 
 	flag.Parse()
 
@@ -126,28 +388,127 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
+	if *diagFile != "" {
+		file, err := os.Create(*diagFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		span.Diagnostics = span.NewJSONEmitter(file)
+	}
+
+	if *freePolicy != "wall" && *freePolicy != "ignore-wall" {
+		log.Fatal("unknown free policy")
+	}
+
+	selector, err := span.ParseISSNSource(*issnSource)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	future, err := span.ParseFuturePolicy(*futurePolicy)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	tagger := make(span.ISILTagger)
 
+	if *configFile != "" {
+		file, err := os.Open(*configFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		tagger, err = span.LoadISILTagger(file)
+		file.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// keep holding filters around for reporting unmatched ISSNs
+	tracked := make(map[string][]span.HoldingFilter)
+
+	// multiple holdings files for a single ISIL are merged into one filter
+	var hisils []string
+	hpaths := make(map[string][]string)
+
 	for _, s := range hfiles {
+		isil, path, err := parseTagPathString(s)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, ok := hpaths[isil]; !ok {
+			hisils = append(hisils, isil)
+		}
+		hpaths[isil] = append(hpaths[isil], path)
+	}
+
+	// with deletions, a skipped ISIL would turn all its records into deletes
+	loader := span.HoldingsLoader{MaxOpen: *maxOpenFiles, Strict: *strictHoldings || *deletionsFile != "" || *priorFile != ""}
+	tables, editions, err := loader.LoadEditions(hpaths)
+	var lerr *span.LoadError
+	if err != nil && (!errors.As(err, &lerr) || (lerr.Invalid > 0 && !*skip)) {
+		log.Fatal(err)
+	}
+	if lerr != nil && len(lerr.Failed) > 0 {
+		// go on without the institutions, whose holdings could not be loaded
+		var loaded []string
+		for _, isil := range hisils {
+			if _, ok := lerr.Failed[isil]; !ok {
+				loaded = append(loaded, isil)
+			}
+		}
+		hisils = loaded
+	}
+
+	for _, isil := range hisils {
+		f := span.NewHoldingFilterFromLicenses(tables[isil], time.Now())
+		f.Editions = editions[isil]
+		tagger[isil] = append(tagger[isil], f)
+	}
+
+	for _, s := range lfiles {
 		isil, file, err := parseTagPath(s)
 		if err != nil {
 			log.Fatal(err)
 		}
 		defer file.Close()
-		f, err := span.NewHoldingFilter(file)
+		f, err := span.NewListFilter(file)
 		if err != nil && !*skip {
 			log.Fatal(err)
 		}
 		tagger[isil] = append(tagger[isil], f)
 	}
 
-	for _, s := range lfiles {
+	for _, s := range yfiles {
 		isil, file, err := parseTagPath(s)
 		if err != nil {
 			log.Fatal(err)
 		}
 		defer file.Close()
-		f, err := span.NewListFilter(file)
+		f, err := span.NewISSNYearFilter(file)
+		if err != nil && !*skip {
+			log.Fatal(err)
+		}
+		tagger[isil] = append(tagger[isil], f)
+	}
+
+	if len(pfiles) > 0 && *prefixFile == "" {
+		log.Fatal("-publisher requires -prefixes")
+	}
+
+	for _, s := range pfiles {
+		isil, file, err := parseTagPath(s)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		prefixes, err := os.Open(*prefixFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		f, err := span.NewDOIPublisherFilter(prefixes, file)
+		prefixes.Close()
 		if err != nil && !*skip {
 			log.Fatal(err)
 		}
@@ -163,10 +524,63 @@ func main() {
 		tagger[isil] = append(tagger[isil], span.SourceFilter{SourceID: sid})
 	}
 
+	for _, s := range noISSN {
+		ss := strings.Split(s, ":")
+		if len(ss) != 2 {
+			log.Fatal("use ISIL:SID")
+		}
+		isil, sid := ss[0], ss[1]
+		f := span.AndFilter{Filters: []span.Filter{span.NoISSNFilter{}, span.SourceFilter{SourceID: sid}}}
+		tagger[isil] = append(tagger[isil], f)
+	}
+
 	for _, isil := range any {
 		tagger[isil] = []span.Filter{span.Any{}}
 	}
 
+	for _, s := range during {
+		isil, from, to, err := parseDuring(s)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for i, f := range tagger[isil] {
+			tagger[isil][i] = span.NewValidDuring(from, to, f)
+		}
+	}
+
+	// options apply to all filters, from flags and from -config alike
+	tagger.MapFilters(func(isil string, f span.Filter) span.Filter {
+		switch g := f.(type) {
+		case span.HoldingFilter:
+			g.FreeIgnoresWall = *freePolicy == "ignore-wall"
+			g.ISSNSource = selector
+			g.FuturePolicy = future
+			if *unmatchedFile != "" {
+				g.Track()
+				tracked[isil] = append(tracked[isil], g)
+			}
+			return g
+		case span.ListFilter:
+			g.ISSNSource = selector
+			return g
+		case span.ISSNYearFilter:
+			g.ISSNSource = selector
+			return g
+		}
+		return f
+	})
+
+	if *validate {
+		warnings := tagger.Validate()
+		for _, w := range warnings {
+			span.Warn(span.CodeEmptyFilter, w)
+		}
+		if len(warnings) > 0 {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	if *dumpFilters {
 		b, err := json.Marshal(tagger)
 		if err != nil {
@@ -176,69 +590,437 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *traceRecord != "" {
+		if err := writeTrace(os.Stdout, *traceRecord, tagger, *explainISIL); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(0)
+	}
+
+	if *checkpointFile != "" && (*outputFile == "" || flag.NArg() != 1) {
+		log.Fatal("-checkpoint requires -output and a single input file")
+	}
+	if *checkpointFile != "" && len(teeFiles) > 0 {
+		log.Fatal("-tee cannot be combined with -checkpoint")
+	}
+	if (*kafkaBrokers == "") != (*kafkaTopic == "") {
+		log.Fatal("-kafka-brokers and -kafka-topic must be used together")
+	}
+	if *kafkaBrokers != "" && (*checkpointFile != "" || *outputFile != "" || len(teeFiles) > 0 || *encoding != "json") {
+		log.Fatal("-kafka-brokers requires json output and cannot be combined with -checkpoint, -output or -tee")
+	}
+	if *sortBy != "" && (*checkpointFile != "" || *kafkaBrokers != "" || *encoding != "json") {
+		log.Fatal("-sort-by requires json output and cannot be combined with -checkpoint or -kafka-brokers")
+	}
+	if *teePolicy != "abort" && *teePolicy != "drop" {
+		log.Fatal("unknown tee policy")
+	}
+	if *resume && *checkpointFile == "" {
+		log.Fatal("-resume requires -checkpoint")
+	}
+	// reports and tracked ISSNs are collected over the whole run and are not
+	// part of the checkpoint; deletions are restored from the output
+	if *resume && (*unmatchedFile != "" || *issnReport || *coverageReport != "" || *fixtures > 0 || *checkSchema != "") {
+		log.Fatal("-resume cannot be combined with -unmatched, -issn-report, -coverage-report, -fixtures or -check-schema")
+	}
+	if *resume && *deletionsFile != "" && (*encoding != "json" || *fields != "" || *excludeFields != "") {
+		log.Fatal("-resume with -deletions requires json output with all fields")
+	}
+
+	f, ok := finc.IdentifierFuncs[*idStrategy]
+	if !ok {
+		log.Fatal("unknown id strategy")
+	}
+	switch *authorOrder {
+	case "", finc.LastFirst, finc.FirstLast:
+	default:
+		log.Fatal("unknown author order")
+	}
+	if *sortAuthors && *authorOrder == "" {
+		log.Fatal("-sort-authors requires -author-order")
+	}
+	solr413.IdentifierFunc = f
+	solr413.AuthorOrder = *authorOrder
+	solr413.SortAuthors = *sortAuthors
+	solr413.RawText = *rawText
+	solr413.URLBuilder, err = finc.NewURLBuilder(*urlMode, *urlProxy, *keepURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *collectionsFile != "" {
+		file, err := os.Open(*collectionsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		solr413.Collections, err = finc.ReadCollectionMap(file)
+		file.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	exportSchemaFunc, ok := Exporters[*format]
 	if !ok {
 		log.Fatal("unknown export schema")
 	}
-	opts := options{tagger: tagger, exportSchemaFunc: exportSchemaFunc}
+	marshal, ok := Marshalers[*encoding]
+	if !ok {
+		log.Fatal("unknown output encoding")
+	}
+	if *fields != "" || *excludeFields != "" {
+		if *encoding != "json" {
+			log.Fatal("-fields and -exclude-fields require json output")
+		}
+		p, err := finc.NewProjection(exportSchemaFunc(), splitFields(*fields), splitFields(*excludeFields))
+		if err != nil {
+			log.Fatal(err)
+		}
+		marshal = p.Marshal
+	}
+	opts := options{tagger: tagger, exportSchemaFunc: exportSchemaFunc, marshal: marshal, deterministic: *deterministic}
+	if *precedence {
+		opts.tagger = span.PrecedenceTagger(tagger)
+	}
+	if *remoteTagger != "" {
+		if len(tagger) > 0 {
+			log.Fatal("-remote-tagger cannot be combined with local filters")
+		}
+		opts.tagger = span.NewRemoteTagger(*remoteTagger, *remoteTimeout, *remoteRetries)
+	}
+	if *extractISSN != "" {
+		e, err := span.NewISSNExtractor(*extractISSN)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.issnExtractor = &e
+	}
+	if len(grepISSN) > 0 || len(grepDOI) > 0 {
+		g := span.NewGrep(grepISSN, grepDOI)
+		opts.grep = &g
+	}
+	if *fixtures > 0 {
+		if len(hisils) != 1 {
+			log.Fatal("-fixtures requires holdings for a single ISIL")
+		}
+		for _, f := range tagger[hisils[0]] {
+			if hf, ok := f.(span.HoldingFilter); ok {
+				opts.fixtures = span.NewFixtureSampler(hf, *fixtures)
+				break
+			}
+		}
+		if opts.fixtures == nil {
+			log.Fatal("-fixtures requires a holdings filter")
+		}
+	}
+	if *checkSchema != "" {
+		if *encoding != "json" {
+			log.Fatal("-check-schema requires json output")
+		}
+		file, err := os.Open(*checkSchema)
+		if err != nil {
+			log.Fatal(err)
+		}
+		schema, err := finc.ReadSolrSchema(file)
+		file.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.schemaCheck = span.NewSchemaCheck(schema)
+	}
+	if *issnReport {
+		opts.issnReport = span.NewISSNReport()
+	}
+	if *coverageReport != "" {
+		if *coverageFormat != "tsv" && *coverageFormat != "json" {
+			log.Fatal("unknown coverage report format")
+		}
+		opts.coverageReport = span.NewCoverageReport(*coverageReport)
+	}
+	if *minCompleteness > 0 {
+		opts.completeness = &finc.CompletenessGate{Min: *minCompleteness}
+	}
+	if *maxFieldLength > 0 {
+		opts.fieldCap = &finc.FieldCap{Max: *maxFieldLength}
+	}
+	if (*priorFile == "") != (*deletionsFile == "") {
+		log.Fatal("-prior and -deletions must be used together")
+	}
+	if *priorFile != "" {
+		file, err := os.Open(*priorFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.deletions, err = span.NewDeletionTracker(file)
+		file.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	queue := make(chan []string)
-	out := make(chan []byte)
-	done := make(chan bool)
-	go span.ByteSink(os.Stdout, out, done)
+	var teeWriters []io.Writer
+	for _, filename := range teeFiles {
+		file, err := os.Create(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		teeWriters = append(teeWriters, file)
+	}
+	var producer *kafkaProducer
+	if *kafkaBrokers != "" {
+		var err error
+		if producer, err = newKafkaProducer(strings.Split(*kafkaBrokers, ","), *kafkaTopic); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	var wg sync.WaitGroup
+	// fatal flushes pending kafka messages, before exiting with err.
+	fatal := func(err error) {
+		if producer != nil {
+			if cerr := producer.Close(); cerr != nil {
+				log.Println(cerr)
+			}
+		}
+		log.Fatal(err)
+	}
 
-	for i := 0; i < *numWorkers; i++ {
-		wg.Add(1)
-		go worker(queue, out, opts, &wg)
+	// newSink writes to w and to the -tee files or to kafka.
+	newSink := func(w io.Writer) span.Sink {
+		if producer != nil {
+			return &span.ProducerSink{Producer: producer, Key: span.JSONIDKey}
+		}
+		sink := span.NewMultiSink(append([]io.Writer{w}, teeWriters...)...)
+		sink.Delimited = *encoding == "protobuf"
+		if *teePolicy == "drop" {
+			sink.Policy = span.DropOnError
+		}
+		return sink
 	}
 
-	var batch []string
-	var i int
+	var w io.Writer = os.Stdout
+	if *outputFile != "" && *checkpointFile == "" {
+		file, err := os.Create(*outputFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		w = file
+	}
 
-	var readers []io.Reader
+	var (
+		started = time.Now()
+		sampler *span.MemSampler
+	)
+	if *showStats {
+		sampler = span.NewMemSampler(time.Second)
+	}
 
-	if flag.NArg() == 0 {
-		readers = append(readers, os.Stdin)
-	} else {
-		for _, filename := range flag.Args() {
-			file, err := os.Open(filename)
+	if *checkpointFile != "" {
+		input, err := os.Open(flag.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer input.Close()
+		output, err := os.OpenFile(*outputFile, os.O_WRONLY|os.O_CREATE, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer output.Close()
+		var c span.Checkpoint
+		if *resume {
+			if c, err = span.ReadCheckpoint(*checkpointFile); err != nil {
+				log.Fatal(err)
+			}
+			span.Info(span.CodeResume, "resuming from checkpoint",
+				"input", strconv.FormatInt(c.Input, 10), "output", strconv.FormatInt(c.Output, 10))
+		}
+		if err := c.Resume(input, output); err != nil {
+			log.Fatal(err)
+		}
+		if *resume && opts.deletions != nil {
+			// records written before the checkpoint are still attached
+			written, err := os.Open(*outputFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+			err = opts.deletions.Restore(io.LimitReader(written, c.Output))
+			written.Close()
 			if err != nil {
 				log.Fatal(err)
 			}
-			defer file.Close()
-			readers = append(readers, file)
 		}
-	}
-
-	for _, r := range readers {
-		br := bufio.NewReader(r)
+		opts.sink = newSink(output)
+		r := span.NewLineReader(input, c.Input)
+		r.Lines = c.Lines
 		for {
-			line, err := br.ReadString('\n')
-			if err == io.EOF {
-				break
+			cerr := convert(r, opts, *size, *numWorkers, *checkpointEvery)
+			if cerr != nil && cerr != io.EOF {
+				log.Fatal(cerr)
+			}
+			if err := output.Sync(); err != nil {
+				log.Fatal(err)
 			}
+			offset, err := output.Seek(0, io.SeekCurrent)
 			if err != nil {
 				log.Fatal(err)
 			}
-			batch = append(batch, line)
-			if i%*size == 0 {
-				b := make([]string, len(batch))
-				copy(b, batch)
-				queue <- b
-				batch = batch[:0]
+			if err := span.WriteCheckpoint(*checkpointFile, span.Checkpoint{Input: r.Offset, Output: offset, Lines: r.Lines}); err != nil {
+				log.Fatal(err)
+			}
+			if cerr == io.EOF {
+				break
 			}
-			i++
+		}
+	} else {
+		var readers []io.Reader
+		if flag.NArg() == 0 {
+			readers = append(readers, os.Stdin)
+		} else {
+			for _, filename := range flag.Args() {
+				file, err := os.Open(filename)
+				if err != nil {
+					log.Fatal(err)
+				}
+				defer file.Close()
+				readers = append(readers, file)
+			}
+		}
+		opts.sink = newSink(w)
+		var sorter *span.SortSink
+		if *sortBy != "" {
+			if sorter, err = span.NewSortSink(*sortBy, *sortMaxBytes, opts.sink); err != nil {
+				log.Fatal(err)
+			}
+			var total int64
+			for _, filename := range flag.Args() {
+				fi, err := os.Stat(filename)
+				if err != nil {
+					log.Fatal(err)
+				}
+				total += fi.Size()
+			}
+			if err := sorter.Guard(total); err != nil {
+				log.Fatal(err)
+			}
+			opts.sink = sorter
+		}
+		// line numbers, and with them batches, continue across files
+		var lines int64
+		for _, r := range readers {
+			lr := span.NewLineReader(r, 0)
+			lr.Lines = lines
+			if err := convert(lr, opts, *size, *numWorkers, 0); err != io.EOF {
+				fatal(err)
+			}
+			lines = lr.Lines
+		}
+		if sorter != nil {
+			if err := sorter.Flush(); err != nil {
+				log.Fatal(err)
+			}
+			opts.sink = sorter.Next
 		}
 	}
 
-	b := make([]string, len(batch))
-	copy(b, batch)
-	queue <- b
+	if opts.issnReport != nil {
+		if _, err := opts.issnReport.WriteTo(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	close(queue)
-	wg.Wait()
-	close(out)
-	<-done
+	if opts.coverageReport != nil {
+		switch *coverageFormat {
+		case "json":
+			if err := json.NewEncoder(os.Stdout).Encode(opts.coverageReport); err != nil {
+				log.Fatal(err)
+			}
+		default:
+			if _, err := opts.coverageReport.WriteTo(os.Stdout); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	if opts.schemaCheck != nil {
+		if _, err := opts.schemaCheck.WriteTo(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		if len(opts.schemaCheck.Undeclared()) > 0 {
+			os.Exit(1)
+		}
+	}
+
+	stats := span.Stats{Workers: *numWorkers, Batch: *size}
+	switch sink := opts.sink.(type) {
+	case *span.MultiSink:
+		stats.Records, stats.Bytes = sink.Records(), sink.Bytes()
+		for i, err := range sink.Errors() {
+			if err != nil {
+				span.Warn(span.CodeOutput, fmt.Sprintf("output %d failed: %s", i, err))
+			}
+		}
+		span.Info(span.CodeRecords, fmt.Sprintf("%d records written", sink.Records()),
+			"records", strconv.FormatInt(sink.Records(), 10),
+			"bytes", strconv.FormatInt(sink.Bytes(), 10))
+	case *span.ProducerSink:
+		if err := producer.Close(); err != nil {
+			log.Fatal(err)
+		}
+		stats.Records = sink.Records()
+		span.Info(span.CodeRecords, fmt.Sprintf("%d records sent", sink.Records()),
+			"records", strconv.FormatInt(sink.Records(), 10))
+	}
+	if sampler != nil {
+		stats.Elapsed, stats.Memory = time.Since(started), sampler.Stop()
+		if err := json.NewEncoder(os.Stderr).Encode(stats); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if opts.fixtures != nil {
+		for _, c := range span.Coverages {
+			log.Printf("%s: %d", c, opts.fixtures.Counts()[c])
+		}
+	}
+
+	if solr413.Collections != nil {
+		for sid, count := range solr413.Collections.Unmapped.Counts() {
+			span.Warn(span.CodeUnmappedSource, fmt.Sprintf("unmapped source: %s (%d)", sid, count),
+				"source_id", sid, "count", strconv.Itoa(count))
+		}
+	}
+
+	if lerr != nil {
+		for _, isil := range lerr.ISILs() {
+			span.Warn(span.CodeHoldingsSkipped, fmt.Sprintf("skipped %s, holdings failed to load: %s", isil, lerr.Failed[isil]),
+				"isil", isil)
+		}
+	}
+
+	if opts.completeness != nil && opts.completeness.Dropped() > 0 {
+		span.Info(span.CodeIncomplete, fmt.Sprintf("%d incomplete records dropped", opts.completeness.Dropped()),
+			"count", strconv.FormatInt(opts.completeness.Dropped(), 10))
+	}
+
+	if opts.fieldCap != nil && opts.fieldCap.Count() > 0 {
+		span.Info(span.CodeTruncated, fmt.Sprintf("%d records truncated", opts.fieldCap.Count()),
+			"count", strconv.FormatInt(opts.fieldCap.Count(), 10))
+	}
+
+	if opts.deletions != nil {
+		file, err := os.Create(*deletionsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := opts.deletions.WriteDeletions(file); err != nil {
+			log.Fatal(err)
+		}
+		file.Close()
+	}
+
+	if *unmatchedFile != "" {
+		if err := writeUnmatched(*unmatchedFile, tracked); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
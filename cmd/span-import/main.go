@@ -2,28 +2,65 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"runtime"
 	"runtime/pprof"
+	"strconv"
 	"sync"
 
 	"github.com/miku/span"
+	"github.com/miku/span/arxiv"
+	"github.com/miku/span/base"
+	"github.com/miku/span/bibtex"
+	"github.com/miku/span/core"
 	"github.com/miku/span/crossref"
+	"github.com/miku/span/csl"
+	"github.com/miku/span/datacite"
 	"github.com/miku/span/doaj"
+	"github.com/miku/span/ebsco"
+	"github.com/miku/span/elsevier"
+	"github.com/miku/span/europepmc"
+	"github.com/miku/span/fatcat"
+	"github.com/miku/span/finc"
 	"github.com/miku/span/genios"
+	"github.com/miku/span/hathitrust"
+	"github.com/miku/span/ieee"
+	"github.com/miku/span/invenio"
 	"github.com/miku/span/jats/degruyter"
+	"github.com/miku/span/jats/highwire"
 	"github.com/miku/span/jats/jstor"
+	"github.com/miku/span/jats/thieme"
+	"github.com/miku/span/mab2"
+	"github.com/miku/span/marc"
+	"github.com/miku/span/mods"
+	"github.com/miku/span/oaidc"
+	"github.com/miku/span/onix"
+	"github.com/miku/span/openaire"
+	"github.com/miku/span/openalex"
+	"github.com/miku/span/orcid"
+	"github.com/miku/span/pica"
+	"github.com/miku/span/proquest"
+	"github.com/miku/span/pubmed"
+	"github.com/miku/span/ris"
+	"github.com/miku/span/semanticscholar"
+	"github.com/miku/span/springer"
+	"github.com/miku/span/unpaywall"
+	"github.com/miku/span/wiso"
 )
 
 var (
 	errFormatRequired    = errors.New("input format required")
 	errFormatUnsupported = errors.New("input format not supported")
 	errCannotConvert     = errors.New("cannot convert type")
+	errEmbedRaw          = errors.New("-embed-raw requires line based input")
 )
 
 // Available input formats and their source type.
@@ -33,37 +70,146 @@ var formats = map[string]span.Source{
 	"jstor":     jstor.Jstor{},
 	"doaj":      doaj.DOAJ{},
 	"genios":    genios.Genios{},
+	"pubmed":    pubmed.PubMed{},
+	"arxiv":     arxiv.Arxiv{},
+	"datacite":  datacite.DataCite{},
+	"marc":      marc.MARC{},
+	"marcxml":   marc.MARCXML{},
+	"oaidc":     oaidc.OAIDC{},
+	"ris":       ris.RIS{},
+	"bibtex":    bibtex.BibTeX{},
+	"onix":      onix.ONIX{},
+	"openaire":  openaire.OpenAIRE{},
+	"base":      base.BASE{},
+	"ieee":      ieee.IEEE{},
+	"elsevier":  elsevier.Elsevier{},
+	"springer":  springer.Springer{},
+	"thieme":    thieme.Thieme{},
+	"highwire":  highwire.HighWire{},
+	"wiso":      wiso.WISO{},
+	"proquest":  proquest.ProQuest{},
+	"csl":       csl.CSL{},
+	"mods":      mods.MODS{},
+	"pica":      pica.PICA{},
+	"mab2":      mab2.MAB2{},
+	"openalex":  openalex.OpenAlex{},
+	"s2":        semanticscholar.SemanticScholar{},
+	"unpaywall": unpaywall.Unpaywall{},
+	"orcid":     orcid.ORCID{},
+	"core":      core.CORE{},
+	"fatcat":    fatcat.Fatcat{},
+	"invenio":   invenio.Invenio{},
+	"hathi":     hathitrust.HathiTrust{},
+	"europepmc": europepmc.EuropePMC{},
+	"ebsco":     ebsco.EBSCO{},
 }
 
 type options struct {
-	verbose bool
+	verbose   bool
+	sampler   *span.Sampler
+	embedRaw  bool
+	unpaywall *unpaywall.Index
+	sourceID  string
 }
 
-// batcherWorker iterates over Batcher objects
-func batcherWorker(queue chan span.Batcher, out chan []byte, opts options, wg *sync.WaitGroup) {
+// encoder holds a single encoded record. Encoders are pooled, so buffers
+// can be reused once a record has been written.
+type encoder struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+var encoderPool = sync.Pool{New: func() interface{} {
+	e := new(encoder)
+	e.enc = json.NewEncoder(&e.buf)
+	return e
+}}
+
+// encode serializes a record, followed by a newline. If requested, the raw
+// input is embedded as well.
+func encode(output *finc.IntermediateSchema, item interface{}, opts options) (*encoder, error) {
+	e := encoderPool.Get().(*encoder)
+	e.buf.Reset()
+	if err := e.enc.Encode(output); err != nil {
+		encoderPool.Put(e)
+		return nil, err
+	}
+	if !opts.embedRaw {
+		return e, nil
+	}
+	raw, ok := item.(string)
+	if !ok {
+		encoderPool.Put(e)
+		return nil, errEmbedRaw
+	}
+	b, err := span.EmbedRaw(bytes.TrimSpace(e.buf.Bytes()), []byte(raw))
+	if err != nil {
+		encoderPool.Put(e)
+		return nil, err
+	}
+	e.buf.Reset()
+	e.buf.Write(b)
+	e.buf.WriteByte('\n')
+	return e, nil
+}
+
+// encoderSink writes encoded records and puts the encoders back into the pool.
+func encoderSink(w io.Writer, out chan *encoder, done chan bool) {
+	f := bufio.NewWriter(w)
+	for e := range out {
+		f.Write(e.buf.Bytes())
+		encoderPool.Put(e)
+	}
+	f.Flush()
+	done <- true
+}
+
+// process converts a document and encodes it. Skipped and unsampled records
+// yield a nil encoder.
+func process(doc span.Importer, item interface{}, opts options) (*encoder, error) {
+	output, err := doc.ToIntermediateSchema()
+	if err != nil {
+		if _, ok := err.(span.Skip); !ok {
+			return nil, err
+		}
+		if opts.verbose {
+			log.Println(err)
+		}
+		return nil, nil
+	}
+	if opts.sampler != nil && !opts.sampler.Sample(output.RecordID) {
+		return nil, nil
+	}
+	// applied after conversion, so -source-id wins over per-format source ids
+	if opts.sourceID != "" {
+		output.SetSourceID(opts.sourceID)
+	}
+	if opts.unpaywall != nil {
+		opts.unpaywall.Enrich(output)
+	}
+	return encode(output, item, opts)
+}
+
+// batcherWorker iterates over Batcher objects. Pooled documents are released
+// after conversion, on errors as well.
+func batcherWorker(queue chan span.Batcher, out chan *encoder, opts options, wg *sync.WaitGroup) {
 	defer wg.Done()
 	for batch := range queue {
 		for _, item := range batch.Items {
 			doc, err := batch.Apply(item)
-			if err != nil {
-				log.Fatal(err)
+			var e *encoder
+			if err == nil {
+				e, err = process(doc, item, opts)
 			}
-			output, err := doc.ToIntermediateSchema()
-			if err != nil {
-				switch err.(type) {
-				case span.Skip:
-					if opts.verbose {
-						log.Println(err)
-					}
-				default:
-					log.Fatal(err)
-				}
+			if r, ok := doc.(span.Releaser); ok {
+				r.Release()
 			}
-			b, err := json.Marshal(output)
 			if err != nil {
 				log.Fatal(err)
 			}
-			out <- b
+			if e != nil {
+				out <- e
+			}
 		}
 	}
 }
@@ -72,11 +218,45 @@ func main() {
 	inputFormat := flag.String("i", "", "input format")
 	listFormats := flag.Bool("list", false, "list formats")
 	members := flag.String("members", "", "path to LDJ file, one member per line")
+	strictMembers := flag.Bool("strict-members", false, "fail, if the members file is missing or corrupt")
+	unpaywallFile := flag.String("unpaywall", "", "path to unpaywall snapshot, to add open access status and URL by DOI")
 	numWorkers := flag.Int("w", runtime.NumCPU(), "number of workers")
 	logfile := flag.String("log", "", "if given log to file")
 	showVersion := flag.Bool("v", false, "prints current program version")
 	cpuprofile := flag.String("cpuprofile", "", "write cpu profile to file")
 	verbose := flag.Bool("verbose", false, "more output")
+	sampleRate := flag.Float64("sample-rate", 0, "emit only a stable sample of this fraction of records, 0 means all")
+	sampleSeed := flag.Int64("sample-seed", 0, "seed for -sample-rate")
+	diagFile := flag.String("diag-json", "", "write warnings as JSON objects to this file, e.g. /dev/fd/3")
+	embedRaw := flag.Bool("embed-raw", false, `write {"schema": ..., "raw": ...} objects, which keep the original input, roughly doubles output size`)
+	strictSchema := flag.Bool("strict-schema", false, "fail on crossref input fields, that are not mapped")
+	marcMapping := flag.String("marc-mapping", "", "path to JSON field mapping for marc and marcxml input")
+	geniosCollections := flag.String("genios-collections", "", "path to JSON object mapping genios database codes to collection names")
+	wisoPackages := flag.String("wiso-packages", "", "path to JSON object mapping wiso package identifiers to collection names")
+	coreCollections := flag.String("core-collections", "", "path to JSON object mapping CORE repository ids to collection names")
+	ebscoPackages := flag.String("ebsco-packages", "", "path to JSON object mapping ebsco database short names to collection names")
+	degruyterPackages := flag.String("degruyter-packages", "", "path to JSON object mapping ISSN or ISBN to package name for degruyter input")
+	oaiSourceID := flag.String("oai-source-id", "", "source id for oaidc input")
+	oaiCollection := flag.String("oai-collection", "", "collection name for oaidc input")
+	risSourceID := flag.String("ris-source-id", "", "source id for ris input")
+	risCollection := flag.String("ris-collection", "", "collection name for ris input")
+	bibtexSourceID := flag.String("bibtex-source-id", "", "source id for bibtex input")
+	bibtexCollection := flag.String("bibtex-collection", "", "collection name for bibtex input")
+	onixSourceID := flag.String("onix-source-id", "", "source id for onix input")
+	onixCollection := flag.String("onix-collection", "", "collection name for onix input")
+	cslSourceID := flag.String("csl-source-id", "", "source id for csl input")
+	cslCollection := flag.String("csl-collection", "", "collection name for csl input")
+	modsSourceID := flag.String("mods-source-id", "", "source id for mods input")
+	modsCollection := flag.String("mods-collection", "", "collection name for mods input")
+	picaSourceID := flag.String("pica-source-id", "", "source id for pica input")
+	picaCollection := flag.String("pica-collection", "", "collection name for pica input")
+	mabSourceID := flag.String("mab2-source-id", "", "source id for mab2 input")
+	mabCollection := flag.String("mab2-collection", "", "collection name for mab2 input")
+	mabFormat := flag.String("mab2-format", "", "format for mab2 input, e.g. eBook")
+	invenioSourceID := flag.String("invenio-source-id", "", "source id for invenio input")
+	sourceID := flag.String("source-id", "", "source id for any input format, overrides the built-in id and per-format flags like -ris-source-id")
+	invenioCollection := flag.String("invenio-collection", "", "collection name for invenio input")
+	scan := flag.Bool("scan", false, "only check JSON syntax of line delimited input and exit")
 
 	flag.Parse()
 
@@ -103,6 +283,28 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *scan {
+		if flag.Arg(0) == "" {
+			log.Fatal("input file required")
+		}
+		file, err := os.Open(flag.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		total, bad, examples, err := span.ScanNDJSON(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, e := range examples {
+			log.Println(e)
+		}
+		fmt.Printf("%d lines, %d invalid\n", total, bad)
+		if bad > 0 {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	if *inputFormat == "" {
 		log.Fatal(errFormatRequired)
 	}
@@ -111,11 +313,141 @@ func main() {
 		log.Fatal(errFormatUnsupported)
 	}
 
-	if *members != "" {
-		err := crossref.PopulateMemberNameCache(*members)
+	if *strictSchema {
+		if *inputFormat != "crossref" {
+			log.Fatal("-strict-schema requires crossref input")
+		}
+		formats["crossref"] = crossref.Crossref{Strict: true}
+	}
+
+	if *oaiSourceID != "" || *oaiCollection != "" {
+		formats["oaidc"] = oaidc.OAIDC{SourceID: *oaiSourceID, Collection: *oaiCollection}
+	}
+
+	if *risSourceID != "" || *risCollection != "" {
+		formats["ris"] = ris.RIS{SourceID: *risSourceID, Collection: *risCollection}
+	}
+
+	if *bibtexSourceID != "" || *bibtexCollection != "" {
+		formats["bibtex"] = bibtex.BibTeX{SourceID: *bibtexSourceID, Collection: *bibtexCollection}
+	}
+
+	if *onixSourceID != "" || *onixCollection != "" {
+		formats["onix"] = onix.ONIX{SourceID: *onixSourceID, Collection: *onixCollection}
+	}
+
+	if *cslSourceID != "" || *cslCollection != "" {
+		formats["csl"] = csl.CSL{SourceID: *cslSourceID, Collection: *cslCollection}
+	}
+
+	if *modsSourceID != "" || *modsCollection != "" {
+		formats["mods"] = mods.MODS{SourceID: *modsSourceID, Collection: *modsCollection}
+	}
+
+	if *picaSourceID != "" || *picaCollection != "" {
+		formats["pica"] = pica.PICA{SourceID: *picaSourceID, Collection: *picaCollection}
+	}
+
+	if *mabSourceID != "" || *mabCollection != "" || *mabFormat != "" {
+		formats["mab2"] = mab2.MAB2{SourceID: *mabSourceID, Collection: *mabCollection, Format: *mabFormat}
+	}
+
+	if *invenioSourceID != "" || *invenioCollection != "" {
+		formats["invenio"] = invenio.Invenio{SourceID: *invenioSourceID, Collection: *invenioCollection}
+	}
+
+	if *marcMapping != "" {
+		file, err := os.Open(*marcMapping)
+		if err != nil {
+			log.Fatal(err)
+		}
+		mapping, err := marc.ReadMapping(file)
 		if err != nil {
 			log.Fatal(err)
 		}
+		file.Close()
+		formats["marc"] = marc.MARC{Mapping: mapping}
+		formats["marcxml"] = marc.MARCXML{Mapping: mapping}
+	}
+
+	if *geniosCollections != "" {
+		file, err := os.Open(*geniosCollections)
+		if err != nil {
+			log.Fatal(err)
+		}
+		collections, err := genios.ReadCollections(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		file.Close()
+		formats["genios"] = genios.Genios{Collections: collections}
+	}
+
+	if *wisoPackages != "" {
+		file, err := os.Open(*wisoPackages)
+		if err != nil {
+			log.Fatal(err)
+		}
+		packages, err := wiso.ReadPackages(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		file.Close()
+		formats["wiso"] = wiso.WISO{Packages: packages}
+	}
+
+	if *ebscoPackages != "" {
+		file, err := os.Open(*ebscoPackages)
+		if err != nil {
+			log.Fatal(err)
+		}
+		packages, err := ebsco.ReadPackages(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		file.Close()
+		formats["ebsco"] = ebsco.EBSCO{Packages: packages}
+	}
+
+	if *coreCollections != "" {
+		file, err := os.Open(*coreCollections)
+		if err != nil {
+			log.Fatal(err)
+		}
+		collections, err := core.ReadCollections(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		file.Close()
+		formats["core"] = core.CORE{Collections: collections}
+	}
+
+	if *degruyterPackages != "" {
+		file, err := os.Open(*degruyterPackages)
+		if err != nil {
+			log.Fatal(err)
+		}
+		packages, err := degruyter.ReadPackages(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		file.Close()
+		formats["degruyter"] = degruyter.DeGruyter{Packages: packages}
+	}
+
+	if *diagFile != "" {
+		file, err := os.Create(*diagFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		span.Diagnostics = span.NewJSONEmitter(file)
+	}
+
+	if *members != "" {
+		if err := crossref.LoadMemberNames(*members, *strictMembers); err != nil {
+			log.Fatal(err)
+		}
 	}
 
 	if flag.Arg(0) == "" {
@@ -123,12 +455,28 @@ func main() {
 	}
 
 	queue := make(chan span.Batcher)
-	out := make(chan []byte)
+	out := make(chan *encoder)
 	done := make(chan bool)
-	go span.ByteSink(os.Stdout, out, done)
+	go encoderSink(os.Stdout, out, done)
 
 	var wg sync.WaitGroup
-	opts := options{verbose: *verbose}
+	opts := options{verbose: *verbose, embedRaw: *embedRaw, sourceID: *sourceID}
+	if *sampleRate > 0 {
+		opts.sampler = &span.Sampler{Rate: *sampleRate, Seed: *sampleSeed}
+	}
+	if *unpaywallFile != "" {
+		file, err := os.Open(*unpaywallFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if opts.unpaywall, err = unpaywall.ReadIndex(file); err != nil {
+			log.Fatal(err)
+		}
+		file.Close()
+		if *verbose {
+			log.Printf("unpaywall: %d open access DOIs", opts.unpaywall.Size())
+		}
+	}
 
 	for i := 0; i < *numWorkers; i++ {
 		wg.Add(1)
@@ -159,16 +507,13 @@ func main() {
 	for item := range ch {
 		switch item.(type) {
 		case span.Importer:
-			doc := item.(span.Importer)
-			output, err := doc.ToIntermediateSchema()
+			e, err := process(item.(span.Importer), item, opts)
 			if err != nil {
 				log.Fatal(err)
 			}
-			b, err := json.Marshal(output)
-			if err != nil {
-				log.Fatal(err)
+			if e != nil {
+				out <- e
 			}
-			out <- b
 		case span.Batcher:
 			queue <- item.(span.Batcher)
 		default:
@@ -180,4 +525,13 @@ func main() {
 	wg.Wait()
 	close(out)
 	<-done
+
+	for typ, count := range crossref.UnmappedTypes.Counts() {
+		span.Warn(span.CodeUnmappedType, fmt.Sprintf("unmapped crossref type: %s (%d)", typ, count),
+			"type", typ, "count", strconv.Itoa(count))
+	}
+	for typ, count := range proquest.UnmappedTypes.Counts() {
+		span.Warn(span.CodeUnmappedType, fmt.Sprintf("unmapped proquest type: %s (%d)", typ, count),
+			"type", typ, "count", strconv.Itoa(count))
+	}
 }
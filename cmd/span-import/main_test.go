@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+	"github.com/miku/span/ris"
+)
+
+func TestProcessSourceIDPrecedence(t *testing.T) {
+	var tests = []struct {
+		sourceID string
+		want     string
+	}{
+		{"", "999"},
+		{"1000", "1000"},
+	}
+	for _, tt := range tests {
+		// as with -ris-source-id 999
+		ch, err := ris.RIS{SourceID: "999"}.Iterate(strings.NewReader("TY  - JOUR\nTI  - Title\nPY  - 2015\nER  - \n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		batch := (<-ch).(span.Batcher)
+		for range ch {
+		}
+		doc, err := batch.Apply(batch.Items[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := process(doc, batch.Items[0], options{sourceID: tt.sourceID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var is finc.IntermediateSchema
+		if err := json.Unmarshal(e.buf.Bytes(), &is); err != nil {
+			t.Fatal(err)
+		}
+		if is.SourceID != tt.want || !strings.HasPrefix(is.RecordID, "ai-"+tt.want+"-") {
+			t.Errorf("process(-source-id=%q): got %s %s, want source id %s", tt.sourceID, is.SourceID, is.RecordID, tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Write TSV(ISSN, boundary) from an OVID holdings file, where boundary is the
+// latest publication date covered at the reference date, or "open", if there
+// is no moving wall.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/holdings"
+)
+
+var errInputFileRequired = errors.New("input file required")
+
+func main() {
+
+	showVersion := flag.Bool("v", false, "prints current program version")
+	refDate := flag.String("ref", "", "reference date (YYYY-MM-DD), defaults to today")
+
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Println(span.AppVersion)
+		os.Exit(0)
+	}
+
+	if flag.NArg() < 1 {
+		log.Fatal(errInputFileRequired)
+	}
+
+	ref := time.Now()
+	if *refDate != "" {
+		var err error
+		ref, err = time.Parse("2006-01-02", *refDate)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	handle, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer handle.Close()
+
+	licenses, errs := holdings.ParseHoldings(handle)
+	for _, e := range errs {
+		log.Println(e)
+	}
+
+	var issns []string
+	for issn := range licenses {
+		issns = append(issns, issn)
+	}
+	sort.Strings(issns)
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	for _, issn := range issns {
+		boundary, open := holdings.Boundary(licenses[issn], ref)
+		if open {
+			fmt.Fprintf(w, "%s\topen\n", issn)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\n", issn, boundary.Format("2006-01-02"))
+	}
+}
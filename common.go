@@ -30,6 +30,12 @@ type Importer interface {
 	ToIntermediateSchema() (*finc.IntermediateSchema, error)
 }
 
+// Releaser is implemented by importers, that can be reused after conversion,
+// e.g. because they come from a pool.
+type Releaser interface {
+	Release()
+}
+
 // Source can emit records given a reader. What is actually returned is decided
 // by the source, e.g. it may return Importer or Batcher object.
 // Dealing with the various types is responsibility of the call site.
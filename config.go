@@ -0,0 +1,473 @@
+package span
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/miku/span/container"
+)
+
+// FilterConstructor creates a filter from its JSON configuration.
+type FilterConstructor func(json.RawMessage) (Filter, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]FilterConstructor{
+		"any":           newAnyFromConfig,
+		"source":        newSourceFilterFromConfig,
+		"list":          newListFilterFromConfig,
+		"holdings":      newHoldingFilterFromConfig,
+		"no-issn":       newNoISSNFilterFromConfig,
+		"language":      newLanguageFilterFromConfig,
+		"relation":      newRelationFilterFromConfig,
+		"collection":    newCollectionFilterFromConfig,
+		"access":        newAccessFilterFromConfig,
+		"issn-year":     newISSNYearFilterFromConfig,
+		"doi-publisher": newDOIPublisherFilterFromConfig,
+		"list-db":       newDatabaseListFilterFromConfig,
+	}
+
+	// listDBCache keeps the list filters per database query, while a
+	// configuration is loaded, so a query shared by many ISILs runs once.
+	listDBCache = struct {
+		sync.Mutex
+		m map[listDBKey]map[string]ListFilter
+	}{m: make(map[listDBKey]map[string]ListFilter)}
+)
+
+// listDBKey identifies a list-db query.
+type listDBKey struct {
+	driver, dsn, query string
+}
+
+func init() {
+	// and, at-least and during refer to the registry itself
+	RegisterFilter("and", newAndFilterFromConfig)
+	RegisterFilter("at-least", newAtLeastFilterFromConfig)
+	RegisterFilter("during", newValidDuringFromConfig)
+}
+
+// RegisterFilter makes a filter available by name in tagger configurations.
+// If RegisterFilter is called twice with the same name or if ctor is nil, it
+// panics.
+func RegisterFilter(name string, ctor FilterConstructor) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if ctor == nil {
+		panic("span: RegisterFilter constructor is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic("span: RegisterFilter called twice for filter " + name)
+	}
+	registry[name] = ctor
+}
+
+// Filters returns a sorted list of the names of the registered filters.
+func Filters() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadISILTagger reads a tagger configuration, which maps ISILs to a list of
+// filters. Each filter is given as an object with a single key, the filter
+// name, and the configuration of the filter as value:
+//
+//	{"DE-15": [{"holdings": "/path/to/ovid.xml"}, {"source": "49"}]}
+func LoadISILTagger(r io.Reader) (ISILTagger, error) {
+	var config map[string][]map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&config); err != nil {
+		return nil, err
+	}
+	defer clearListDBCache()
+	tagger := make(ISILTagger)
+	for isil, specs := range config {
+		for _, spec := range specs {
+			f, err := newFilterFromSpec(spec)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", isil, err)
+			}
+			tagger[isil] = append(tagger[isil], f)
+		}
+	}
+	return tagger, nil
+}
+
+// newFilterFromSpec creates a filter from an object with a single key, the
+// filter name, and the filter configuration as value.
+func newFilterFromSpec(spec map[string]json.RawMessage) (Filter, error) {
+	if len(spec) != 1 {
+		return nil, fmt.Errorf("filter must have exactly one key")
+	}
+	for name, value := range spec {
+		registryMu.RLock()
+		ctor, ok := registry[name]
+		registryMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown filter: %s", name)
+		}
+		f, err := ctor(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+		return f, nil
+	}
+	return nil, nil
+}
+
+// filterName returns the name, the type of a filter is registered with.
+func filterName(f Filter) (string, error) {
+	switch f.(type) {
+	case Any:
+		return "any", nil
+	case SourceFilter:
+		return "source", nil
+	case ListFilter:
+		return "list", nil
+	case HoldingFilter:
+		return "holdings", nil
+	case NoISSNFilter:
+		return "no-issn", nil
+	case LanguageFilter:
+		return "language", nil
+	case RelationFilter:
+		return "relation", nil
+	case CollectionFilter:
+		return "collection", nil
+	case AccessFilter:
+		return "access", nil
+	case ISSNYearFilter:
+		return "issn-year", nil
+	case DOIPublisherFilter:
+		return "doi-publisher", nil
+	case AndFilter:
+		return "and", nil
+	case AtLeastFilter:
+		return "at-least", nil
+	case ValidDuring:
+		return "during", nil
+	default:
+		return "", fmt.Errorf("no filter name for %T", f)
+	}
+}
+
+// origin is the configuration, a filter was loaded from. Filters, that are
+// configured with external data, like a path to a file, keep their origin,
+// since their serialization contains the loaded data instead.
+type origin struct {
+	name  string
+	value json.RawMessage
+}
+
+// originOf returns the origin of a filter or nil.
+func originOf(f Filter) *origin {
+	switch g := f.(type) {
+	case ListFilter:
+		return g.origin
+	case HoldingFilter:
+		return g.origin
+	case ISSNYearFilter:
+		return g.origin
+	case DOIPublisherFilter:
+		return g.origin
+	default:
+		return nil
+	}
+}
+
+// filterSpec wraps a filter into an object with a single key, the filter
+// name, so it serializes like a filter in a tagger configuration. Filters
+// loaded with LoadISILTagger are written with their original configuration,
+// so the output can be loaded again.
+func filterSpec(f Filter) (map[string]interface{}, error) {
+	if o := originOf(f); o != nil {
+		return map[string]interface{}{o.name: o.value}, nil
+	}
+	name, err := filterName(f)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{name: f}, nil
+}
+
+func newAnyFromConfig(json.RawMessage) (Filter, error) {
+	return Any{}, nil
+}
+
+func newNoISSNFilterFromConfig(json.RawMessage) (Filter, error) {
+	return NoISSNFilter{}, nil
+}
+
+// newAndFilterFromConfig expects a list of filter specs, all of which must
+// match, e.g. [{"no-issn": null}, {"source": "49"}].
+func newAndFilterFromConfig(b json.RawMessage) (Filter, error) {
+	var specs []map[string]json.RawMessage
+	if err := json.Unmarshal(b, &specs); err != nil {
+		return nil, err
+	}
+	var f AndFilter
+	for _, spec := range specs {
+		g, err := newFilterFromSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		f.Filters = append(f.Filters, g)
+	}
+	return f, nil
+}
+
+// newAtLeastFilterFromConfig expects the number of filters, that must match,
+// and a list of filter specs, e.g. {"n": 2, "filters": [...]}.
+func newAtLeastFilterFromConfig(b json.RawMessage) (Filter, error) {
+	var config struct {
+		N       int                          `json:"n"`
+		Filters []map[string]json.RawMessage `json:"filters"`
+	}
+	if err := json.Unmarshal(b, &config); err != nil {
+		return nil, err
+	}
+	if config.N < 1 || config.N > len(config.Filters) {
+		return nil, fmt.Errorf("n must be between 1 and the number of filters")
+	}
+	f := AtLeastFilter{N: config.N}
+	for _, spec := range config.Filters {
+		g, err := newFilterFromSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		f.Children = append(f.Children, g)
+	}
+	return f, nil
+}
+
+// newValidDuringFromConfig expects a time window and a filter spec, e.g.
+// {"from": "2020-01-01", "to": "2020-12-31", "filter": {"source": "49"}}.
+func newValidDuringFromConfig(b json.RawMessage) (Filter, error) {
+	var config struct {
+		From   string                     `json:"from"`
+		To     string                     `json:"to"`
+		Filter map[string]json.RawMessage `json:"filter"`
+	}
+	if err := json.Unmarshal(b, &config); err != nil {
+		return nil, err
+	}
+	from, err := time.Parse("2006-01-02", config.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := time.Parse("2006-01-02", config.To)
+	if err != nil {
+		return nil, err
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("window ends before it starts")
+	}
+	inner, err := newFilterFromSpec(config.Filter)
+	if err != nil {
+		return nil, err
+	}
+	return NewValidDuring(from, to, inner), nil
+}
+
+// newSourceFilterFromConfig expects a source id as string.
+func newSourceFilterFromConfig(b json.RawMessage) (Filter, error) {
+	var sid string
+	if err := json.Unmarshal(b, &sid); err != nil {
+		return nil, err
+	}
+	return SourceFilter{SourceID: sid}, nil
+}
+
+// newListFilterFromConfig expects a path to a file with one ISSN per line.
+func newListFilterFromConfig(b json.RawMessage) (Filter, error) {
+	var path string
+	if err := json.Unmarshal(b, &path); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	f, err := NewListFilter(file)
+	f.origin = &origin{name: "list", value: b}
+	return f, err
+}
+
+// newLanguageFilterFromConfig expects a list of language codes, e.g. ["deu"].
+func newLanguageFilterFromConfig(b json.RawMessage) (Filter, error) {
+	var codes []string
+	if err := json.Unmarshal(b, &codes); err != nil {
+		return nil, err
+	}
+	return newLanguageFilter(codes)
+}
+
+// newCollectionFilterFromConfig expects a list of collection names, e.g.
+// ["DeGruyter SSH"].
+func newCollectionFilterFromConfig(b json.RawMessage) (Filter, error) {
+	var names []string
+	if err := json.Unmarshal(b, &names); err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no collections")
+	}
+	return CollectionFilter{Collections: container.NewStringSet(names...)}, nil
+}
+
+// newAccessFilterFromConfig expects a list of access levels, e.g. ["open"].
+func newAccessFilterFromConfig(b json.RawMessage) (Filter, error) {
+	var levels []string
+	if err := json.Unmarshal(b, &levels); err != nil {
+		return nil, err
+	}
+	if len(levels) == 0 {
+		return nil, fmt.Errorf("no access levels")
+	}
+	return AccessFilter{Levels: container.NewStringSet(levels...)}, nil
+}
+
+// newRelationFilterFromConfig expects relation types and whether to exclude
+// them, e.g. {"types": ["has-preprint"], "exclude": true}.
+func newRelationFilterFromConfig(b json.RawMessage) (Filter, error) {
+	var config struct {
+		Types   []string `json:"types"`
+		Exclude bool     `json:"exclude"`
+	}
+	if err := json.Unmarshal(b, &config); err != nil {
+		return nil, err
+	}
+	if len(config.Types) == 0 {
+		return nil, fmt.Errorf("no relation types")
+	}
+	return RelationFilter{Types: container.NewStringSet(config.Types...), Exclude: config.Exclude}, nil
+}
+
+// newHoldingFilterFromConfig expects a path to an OVID holdings file.
+func newHoldingFilterFromConfig(b json.RawMessage) (Filter, error) {
+	var path string
+	if err := json.Unmarshal(b, &path); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	f, err := NewHoldingFilter(file)
+	f.origin = &origin{name: "holdings", value: b}
+	return f, err
+}
+
+// newISSNYearFilterFromConfig expects a path to a file with whitespace
+// separated ISSN and year pairs, one per line.
+func newISSNYearFilterFromConfig(b json.RawMessage) (Filter, error) {
+	var path string
+	if err := json.Unmarshal(b, &path); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	f, err := NewISSNYearFilter(file)
+	f.origin = &origin{name: "issn-year", value: b}
+	return f, err
+}
+
+// newDOIPublisherFilterFromConfig expects paths to a prefix to publisher
+// table and to a publisher allow-list, e.g. {"prefixes": "/path/to/prefixes.tsv",
+// "publishers": "/path/to/publishers.txt"}.
+func newDOIPublisherFilterFromConfig(b json.RawMessage) (Filter, error) {
+	var config struct {
+		Prefixes   string `json:"prefixes"`
+		Publishers string `json:"publishers"`
+	}
+	if err := json.Unmarshal(b, &config); err != nil {
+		return nil, err
+	}
+	prefixes, err := os.Open(config.Prefixes)
+	if err != nil {
+		return nil, err
+	}
+	defer prefixes.Close()
+	publishers, err := os.Open(config.Publishers)
+	if err != nil {
+		return nil, err
+	}
+	defer publishers.Close()
+	f, err := NewDOIPublisherFilter(prefixes, publishers)
+	f.origin = &origin{name: "doi-publisher", value: b}
+	return f, err
+}
+
+// newDatabaseListFilterFromConfig expects a database/sql driver name, a data
+// source name, a query returning (isil, issn) rows and the ISIL, whose ISSN
+// should be used, e.g. {"driver": "postgres", "dsn": "...", "query": "SELECT
+// isil, issn FROM assignments", "isil": "DE-15"}. The driver must be linked
+// into the program.
+func newDatabaseListFilterFromConfig(b json.RawMessage) (Filter, error) {
+	var config struct {
+		Driver string `json:"driver"`
+		DSN    string `json:"dsn"`
+		Query  string `json:"query"`
+		ISIL   string `json:"isil"`
+	}
+	if err := json.Unmarshal(b, &config); err != nil {
+		return nil, err
+	}
+	if config.Driver == "" || config.Query == "" || config.ISIL == "" {
+		return nil, fmt.Errorf("driver, query and isil required")
+	}
+	filters, err := loadListFiltersCached(listDBKey{config.Driver, config.DSN, config.Query})
+	if err != nil {
+		return nil, err
+	}
+	f, ok := filters[config.ISIL]
+	if !ok {
+		return nil, fmt.Errorf("no ISSN for %s", config.ISIL)
+	}
+	f.origin = &origin{name: "list-db", value: b}
+	return f, nil
+}
+
+// loadListFiltersCached runs a list-db query, unless its result is cached.
+func loadListFiltersCached(key listDBKey) (map[string]ListFilter, error) {
+	listDBCache.Lock()
+	defer listDBCache.Unlock()
+	if filters, ok := listDBCache.m[key]; ok {
+		return filters, nil
+	}
+	db, err := sql.Open(key.driver, key.dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	filters, err := LoadListFiltersFromDB(db, key.query)
+	if err != nil {
+		return nil, err
+	}
+	listDBCache.m[key] = filters
+	return filters, nil
+}
+
+// clearListDBCache drops cached query results, so the next configuration
+// sees the current database contents.
+func clearListDBCache() {
+	listDBCache.Lock()
+	listDBCache.m = make(map[listDBKey]map[string]ListFilter)
+	listDBCache.Unlock()
+}
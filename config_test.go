@@ -0,0 +1,259 @@
+package span
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miku/span/finc"
+	"github.com/miku/span/holdings"
+)
+
+// journalFilter is a custom filter, matching a journal title.
+type journalFilter struct {
+	Title string
+}
+
+func (f journalFilter) Apply(is finc.IntermediateSchema) bool {
+	return is.JournalTitle == f.Title
+}
+
+func TestLoadISILTagger(t *testing.T) {
+	RegisterFilter("journal", func(b json.RawMessage) (Filter, error) {
+		var f journalFilter
+		err := json.Unmarshal(b, &f.Title)
+		return f, err
+	})
+
+	config := `{
+		"DE-1": [{"source": "49"}, {"journal": "Nature"}],
+		"DE-2": [{"any": null}]
+	}`
+	tagger, err := LoadISILTagger(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ISILTagger{
+		"DE-1": []Filter{SourceFilter{SourceID: "49"}, journalFilter{Title: "Nature"}},
+		"DE-2": []Filter{Any{}},
+	}
+	if !reflect.DeepEqual(tagger, want) {
+		t.Errorf("LoadISILTagger: got %v, want %v", tagger, want)
+	}
+
+	tags := tagger.Tags(finc.IntermediateSchema{SourceID: "28", JournalTitle: "Nature"})
+	if len(tags) != 2 {
+		t.Errorf("ISILTagger.Tags: got %v, want DE-1 and DE-2", tags)
+	}
+
+	tagger, err = LoadISILTagger(strings.NewReader(`{"DE-3": [{"and": [{"no-issn": null}, {"source": "49"}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = ISILTagger{"DE-3": []Filter{AndFilter{Filters: []Filter{NoISSNFilter{}, SourceFilter{SourceID: "49"}}}}}
+	if !reflect.DeepEqual(tagger, want) {
+		t.Errorf("LoadISILTagger: got %v, want %v", tagger, want)
+	}
+	b, err := json.Marshal(want["DE-3"][0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagger, err = LoadISILTagger(strings.NewReader(fmt.Sprintf(`{"DE-3": [{"and": %s}]}`, b)))
+	if err != nil {
+		t.Fatalf("LoadISILTagger(%s): %s", b, err)
+	}
+	if !reflect.DeepEqual(tagger, want) {
+		t.Errorf("LoadISILTagger(%s): got %v, want %v", b, tagger, want)
+	}
+
+	tagger, err = LoadISILTagger(strings.NewReader(`{"DE-4": [{"at-least": {"n": 2, "filters": [{"no-issn": null}, {"source": "49"}, {"any": null}]}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = ISILTagger{"DE-4": []Filter{AtLeastFilter{N: 2, Children: []Filter{NoISSNFilter{}, SourceFilter{SourceID: "49"}, Any{}}}}}
+	if !reflect.DeepEqual(tagger, want) {
+		t.Errorf("LoadISILTagger: got %v, want %v", tagger, want)
+	}
+	b, err = json.Marshal(want["DE-4"][0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagger, err = LoadISILTagger(strings.NewReader(fmt.Sprintf(`{"DE-4": [{"at-least": %s}]}`, b)))
+	if err != nil {
+		t.Fatalf("LoadISILTagger(%s): %s", b, err)
+	}
+	if !reflect.DeepEqual(tagger, want) {
+		t.Errorf("LoadISILTagger(%s): got %v, want %v", b, tagger, want)
+	}
+	if _, err := LoadISILTagger(strings.NewReader(`{"DE-4": [{"at-least": {"n": 3, "filters": [{"any": null}]}}]}`)); err == nil {
+		t.Errorf("LoadISILTagger: got nil, want error for n greater than number of filters")
+	}
+
+	if _, err := LoadISILTagger(strings.NewReader(`{"DE-1": [{"unknown": 1}]}`)); err == nil {
+		t.Errorf("LoadISILTagger: got nil, want error for unknown filter")
+	}
+}
+
+func TestLoadISILTaggerHoldingsEditions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "span-config-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ovid.xml")
+	if err := ioutil.WriteFile(path, []byte(testHolding("2000", "2010", "")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(map[string][]map[string]string{"DE-1": {{"holdings": path}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagger, err := LoadISILTagger(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, ok := tagger["DE-1"][0].(HoldingFilter)
+	if !ok {
+		t.Fatalf("LoadISILTagger: got %T, want HoldingFilter", tagger["DE-1"][0])
+	}
+	date, signature := mustParseDate("2005-01-01"), holdings.CombineDatum("2005", "", "", "")
+	if ok, edition := f.CoveredAndValidEdition(signature, "1234-5678", date); !ok || edition != holdings.Print {
+		t.Errorf("CoveredAndValidEdition: got %v, %s, want true, %s", ok, edition, holdings.Print)
+	}
+}
+
+func TestFilterRegistry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "span-config-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	years := write("years.txt", "1234-5678 2005\n")
+	prefixes := write("prefixes.tsv", "10.1234\tACME\n")
+	publishers := write("publishers.txt", "ACME\n")
+
+	var tests = []struct {
+		config string
+		is     finc.IntermediateSchema
+		result bool
+	}{
+		{fmt.Sprintf(`{"issn-year": %q}`, years),
+			finc.IntermediateSchema{ISSN: []string{"1234-5678"}, Date: mustParseDate("2005-01-01")}, true},
+		{fmt.Sprintf(`{"issn-year": %q}`, years),
+			finc.IntermediateSchema{ISSN: []string{"1234-5678"}, Date: mustParseDate("2006-01-01")}, false},
+		{fmt.Sprintf(`{"doi-publisher": {"prefixes": %q, "publishers": %q}}`, prefixes, publishers),
+			finc.IntermediateSchema{DOI: "10.1234/x"}, true},
+		{fmt.Sprintf(`{"doi-publisher": {"prefixes": %q, "publishers": %q}}`, prefixes, publishers),
+			finc.IntermediateSchema{DOI: "10.2345/x"}, false},
+		{`{"during": {"from": "2000-01-01", "to": "9999-12-31", "filter": {"source": "49"}}}`,
+			finc.IntermediateSchema{SourceID: "49"}, true},
+		{`{"during": {"from": "2000-01-01", "to": "2000-12-31", "filter": {"source": "49"}}}`,
+			finc.IntermediateSchema{SourceID: "49"}, false},
+	}
+	for _, tt := range tests {
+		tagger, err := LoadISILTagger(strings.NewReader(fmt.Sprintf(`{"DE-1": [%s]}`, tt.config)))
+		if err != nil {
+			t.Fatalf("LoadISILTagger(%s): %s", tt.config, err)
+		}
+		if r := tagger["DE-1"][0].Apply(tt.is); r != tt.result {
+			t.Errorf("LoadISILTagger(%s).Apply: got %v, want %v", tt.config, r, tt.result)
+		}
+	}
+
+	names := strings.Join(Filters(), " ")
+	for _, name := range []string{"during", "issn-year", "doi-publisher", "list-db"} {
+		if !strings.Contains(" "+names+" ", " "+name+" ") {
+			t.Errorf("Filters: %s not registered", name)
+		}
+	}
+	tagger, err := LoadISILTagger(strings.NewReader(`{"DE-1": [{"during": {"from": "2020-01-01", "to": "2020-12-31", "filter": {"any": null}}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := tagger["DE-1"][0].(ValidDuring)
+	for ref, want := range map[string]bool{
+		"2020-12-31T00:00:00Z": true,
+		"2020-12-31T23:59:59Z": true,
+		"2021-01-01T00:00:00Z": false,
+	} {
+		f.Ref, _ = time.Parse(time.RFC3339, ref)
+		if r := f.Apply(finc.IntermediateSchema{}); r != want {
+			t.Errorf("during Apply at %s: got %v, want %v", ref, r, want)
+		}
+	}
+	if _, err := LoadISILTagger(strings.NewReader(`{"DE-1": [{"during": {"from": "2001-01-01", "to": "2000-01-01", "filter": {"any": null}}}]}`)); err == nil {
+		t.Errorf("LoadISILTagger: got nil, want error for reversed window")
+	}
+}
+
+func TestFilterSpecRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "span-config-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	list := write("list.txt", "1234-5678\n")
+	ovid := write("ovid.xml", testHolding("2000", "2010", ""))
+	years := write("years.txt", "2345-6789 2005\n")
+	prefixes := write("prefixes.tsv", "10.1234\tACME\n")
+	publishers := write("publishers.txt", "ACME\n")
+
+	children := fmt.Sprintf(`[{"list": %q}, {"holdings": %q}, {"issn-year": %q},
+		{"doi-publisher": {"prefixes": %q, "publishers": %q}}]`, list, ovid, years, prefixes, publishers)
+	for _, config := range []string{
+		fmt.Sprintf(`{"at-least": {"n": 1, "filters": %s}}`, children),
+		fmt.Sprintf(`{"and": %s}`, children),
+		fmt.Sprintf(`{"during": {"from": "2000-01-01", "to": "9999-12-31", "filter": {"list": %q}}}`, list),
+	} {
+		tagger, err := LoadISILTagger(strings.NewReader(fmt.Sprintf(`{"DE-1": [%s]}`, config)))
+		if err != nil {
+			t.Fatalf("LoadISILTagger(%s): %s", config, err)
+		}
+		f := tagger["DE-1"][0]
+		spec, err := filterSpec(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := json.Marshal(map[string][]interface{}{"DE-1": {spec}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(b), strconv.Quote(list)) {
+			t.Errorf("filterSpec(%s): got %s, want the list path", config, b)
+		}
+		reloaded, err := LoadISILTagger(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("LoadISILTagger(%s): %s", b, err)
+		}
+		for _, is := range []finc.IntermediateSchema{
+			{ISSN: []string{"1234-5678"}, Date: mustParseDate("2005-01-01")},
+			{ISSN: []string{"3456-7890"}, Date: mustParseDate("2005-01-01")},
+		} {
+			if got, want := reloaded["DE-1"][0].Apply(is), f.Apply(is); got != want {
+				t.Errorf("reloaded %s: Apply(%v) got %v, want %v", b, is.ISSN, got, want)
+			}
+		}
+	}
+}
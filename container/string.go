@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"sync"
 )
 
 // StringMap provides defaults for string map lookups with defaults.
@@ -109,3 +110,32 @@ func (i *StringSlice) Set(value string) error {
 	*i = append(*i, value)
 	return nil
 }
+
+// StringCounter counts string occurrences, threadsafe.
+type StringCounter struct {
+	mu     *sync.Mutex
+	counts map[string]int
+}
+
+// NewStringCounter creates a new counter.
+func NewStringCounter() StringCounter {
+	return StringCounter{counts: make(map[string]int), mu: new(sync.Mutex)}
+}
+
+// Inc increments the count for a string.
+func (c StringCounter) Inc(s string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[s]++
+}
+
+// Counts returns a copy of the current counts.
+func (c StringCounter) Counts() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m := make(map[string]int, len(c.counts))
+	for k, v := range c.counts {
+		m[k] = v
+	}
+	return m
+}
@@ -0,0 +1,326 @@
+// Package core converts works of the CORE aggregator dataset, one work per
+// line, into the intermediate schema. Both the legacy dump layout and the
+// layout of the current API are read. The repository, a work was harvested
+// from, determines its collection.
+package core
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/container"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// SourceID for internal bookkeeping.
+	SourceID = "167"
+	// Collection is used for works without repository.
+	Collection = "CORE"
+	// BatchSize for grouped channel transport.
+	BatchSize = 10000
+)
+
+// documentTypes maps CORE document types to format, genre and RIS type.
+var documentTypes = map[string][3]string{
+	"research":     {"ElectronicArticle", "article", "JOUR"},
+	"article":      {"ElectronicArticle", "article", "JOUR"},
+	"thesis":       {"ElectronicThesis", "document", "THES"},
+	"book":         {"eBook", "book", "EBOOK"},
+	"book chapter": {"ElectronicBookPart", "bookitem", "ECHAP"},
+	"conference":   {"ElectronicProceeding", "proceeding", "CPAPER"},
+	"preprint":     {"ElectronicPreprint", "preprint", "UNPB"},
+	"report":       {"ElectronicResourceRemoteAccess", "report", "RPRT"},
+}
+
+// CORE source. Collections maps repository ids to collection names, e.g. to
+// group repositories by institution. Repositories without an entry get a
+// collection named after the repository, like "CORE (Example Repository)".
+type CORE struct {
+	Collections map[string]string
+}
+
+// ReadCollections reads a JSON object, that maps repository ids to
+// collection names.
+func ReadCollections(r io.Reader) (map[string]string, error) {
+	collections := make(map[string]string)
+	if err := json.NewDecoder(r).Decode(&collections); err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
+
+// ID is a numeric or string identifier.
+type ID string
+
+// UnmarshalJSON accepts numbers and strings.
+func (id *ID) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	d := json.NewDecoder(strings.NewReader(string(b)))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return err
+	}
+	switch t := v.(type) {
+	case nil:
+		*id = ""
+	case string:
+		*id = ID(t)
+	default:
+		*id = ID(fmt.Sprintf("%v", t))
+	}
+	return nil
+}
+
+// Repository is a data provider, that CORE harvests.
+type Repository struct {
+	ID   ID     `json:"id"`
+	Name string `json:"name"`
+}
+
+// Work is a single work, legacy dump and API fields are merged.
+type Work struct {
+	CoreID        ID                `json:"coreId"`
+	ID            ID                `json:"id"`
+	DOI           string            `json:"doi"`
+	Title         string            `json:"title"`
+	Authors       []json.RawMessage `json:"authors"`
+	Abstract      string            `json:"abstract"`
+	Publisher     string            `json:"publisher"`
+	DocumentType  string            `json:"documentType"`
+	DatePublished string            `json:"datePublished"`
+	PublishedDate string            `json:"publishedDate"`
+	Year          int               `json:"year"`
+	YearPublished json.Number       `json:"yearPublished"`
+	DownloadURL   string            `json:"downloadUrl"`
+	Links         []struct {
+		Type string `json:"type"`
+		URL  string `json:"url"`
+	} `json:"links"`
+	Journals []struct {
+		Title       string   `json:"title"`
+		Identifiers []string `json:"identifiers"`
+	} `json:"journals"`
+	Language *struct {
+		Code string `json:"code"`
+	} `json:"language"`
+	Topics        []string     `json:"topics"`
+	Subjects      []string     `json:"subjects"`
+	Repositories  []Repository `json:"repositories"`
+	DataProviders []Repository `json:"dataProviders"`
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(lines []string, s CORE) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(v interface{}) (span.Importer, error) {
+			doc := &Document{source: s}
+			err := json.Unmarshal([]byte(v.(string)), &doc.Work)
+			return doc, err
+		}, Items: make([]interface{}, len(lines))}
+	for i, line := range lines {
+		batch.Items[i] = line
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via JSON decoding.
+func (s CORE) Iterate(r io.Reader) (<-chan interface{}, error) {
+	ch := make(chan interface{})
+	reader := bufio.NewReader(r)
+	var lines []string
+	go func() {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil && err != io.EOF {
+				log.Fatal(err)
+			}
+			if strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+			if len(lines) == BatchSize {
+				ch <- NewBatch(lines, s)
+				lines = nil
+			}
+			if err == io.EOF {
+				break
+			}
+		}
+		ch <- NewBatch(lines, s)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Document is a work together with the source settings.
+type Document struct {
+	Work
+	source CORE
+}
+
+// Key returns the CORE id.
+func (w *Work) Key() string {
+	if w.CoreID != "" {
+		return string(w.CoreID)
+	}
+	return string(w.ID)
+}
+
+// Repository returns the first repository, the work was harvested from.
+func (w *Work) Repository() (Repository, bool) {
+	for _, repos := range [][]Repository{w.DataProviders, w.Repositories} {
+		for _, r := range repos {
+			if r.ID != "" || r.Name != "" {
+				return r, true
+			}
+		}
+	}
+	return Repository{}, false
+}
+
+// Date returns the publication date, which may be a full timestamp, falling
+// back to the year.
+func (w *Work) Date() (time.Time, error) {
+	for _, v := range []string{w.PublishedDate, w.DatePublished} {
+		if len(v) >= 10 {
+			if t, err := time.Parse("2006-01-02", v[:10]); err == nil {
+				return t, nil
+			}
+		}
+		if len(v) == 4 {
+			if t, err := time.Parse("2006", v); err == nil {
+				return t, nil
+			}
+		}
+	}
+	year := w.Year
+	if y, err := w.YearPublished.Int64(); err == nil && y > 0 {
+		year = int(y)
+	}
+	if year > 0 {
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Time{}, fmt.Errorf("core: %s: no usable date", w.Key())
+}
+
+// AuthorList returns the authors, given as strings or as objects with a
+// name.
+func (w *Work) AuthorList() (authors []finc.Author) {
+	for _, raw := range w.Authors {
+		var name string
+		if err := json.Unmarshal(raw, &name); err != nil {
+			var v struct {
+				Name string `json:"name"`
+			}
+			if json.Unmarshal(raw, &v) != nil {
+				continue
+			}
+			name = v.Name
+		}
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if parts := strings.SplitN(name, ",", 2); len(parts) == 2 {
+			authors = append(authors, finc.Author{LastName: strings.TrimSpace(parts[0]), FirstName: strings.TrimSpace(parts[1])})
+		} else {
+			authors = append(authors, finc.Author{Name: name})
+		}
+	}
+	return authors
+}
+
+// URLs returns the download and display links, without duplicates.
+func (w *Work) URLs() (urls []string) {
+	seen := container.NewStringSet()
+	candidates := []string{w.DownloadURL}
+	for _, l := range w.Links {
+		candidates = append(candidates, l.URL)
+	}
+	for _, u := range candidates {
+		if u = strings.TrimSpace(u); u != "" && seen.Add(u) {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// collection returns the configured collection of the repository or a
+// collection named after the repository.
+func (doc *Document) collection() string {
+	repo, ok := doc.Repository()
+	if !ok {
+		return Collection
+	}
+	if c, ok := doc.source.Collections[string(repo.ID)]; ok {
+		return c
+	}
+	if repo.Name != "" {
+		return fmt.Sprintf("CORE (%s)", repo.Name)
+	}
+	return fmt.Sprintf("CORE (%s)", repo.ID)
+}
+
+// ToIntermediateSchema converts a work. Works without id, title or date are
+// skipped.
+func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	key := doc.Key()
+	if key == "" || strings.TrimSpace(doc.Title) == "" {
+		return output, span.Skip{Reason: fmt.Sprintf("core: %q: missing id or title", key)}
+	}
+	output.Date, err = doc.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(key)))
+	output.SourceID = SourceID
+	output.RecordID = strings.TrimRight(enc, "=")
+	output.MegaCollection = doc.collection()
+	if repo, ok := doc.Repository(); ok {
+		output.DataProvider = repo.Name
+	}
+	kind, ok := documentTypes[strings.ToLower(doc.DocumentType)]
+	if !ok {
+		kind = documentTypes["research"]
+	}
+	output.Format, output.Genre, output.RefType = kind[0], kind[1], kind[2]
+
+	output.ArticleTitle = strings.TrimSpace(doc.Title)
+	output.Authors = doc.AuthorList()
+	output.DOI = strings.TrimSpace(doc.DOI)
+	output.Abstract = strings.TrimSpace(doc.Abstract)
+	output.URL = doc.URLs()
+	if doc.Publisher != "" {
+		output.Publishers = append(output.Publishers, strings.Trim(doc.Publisher, "'\" "))
+	}
+	if doc.Language != nil && doc.Language.Code != "" {
+		output.Languages = append(output.Languages, span.NormalizeLanguage(doc.Language.Code))
+	}
+	seen := container.NewStringSet()
+	for _, v := range append(append([]string{}, doc.Topics...), doc.Subjects...) {
+		if v = strings.TrimSpace(v); v != "" && seen.Add(v) {
+			output.Subjects = append(output.Subjects, v)
+		}
+	}
+	for _, j := range doc.Journals {
+		if output.JournalTitle == "" {
+			output.JournalTitle = strings.TrimSpace(j.Title)
+		}
+		for _, id := range j.Identifiers {
+			if strings.HasPrefix(id, "issn:") {
+				output.ISSN = append(output.ISSN, strings.TrimPrefix(id, "issn:"))
+			}
+		}
+	}
+	return output, nil
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+	"github.com/miku/span/internal/spantest"
+)
+
+const example = `{"coreId": "1001", "doi": "10.1000/core.1", "title": "Harvested", "authors": ["Doe, Jane", "Example Group"], "datePublished": "2017-03-01T00:00:00", "publisher": "'Example Press'", "downloadUrl": "https://core.ac.uk/download/1001.pdf", "journals": [{"title": "Journal of Examples", "identifiers": ["issn:1234-5678", "oai:x"]}], "language": {"code": "en", "name": "English"}, "topics": ["Biology"], "subjects": ["article", "Biology"], "repositories": [{"id": "42", "name": "Example Repository"}]}
+{"id": 2002, "title": "From the API", "authors": [{"name": "Roe, Richard"}], "yearPublished": 2020, "documentType": "thesis", "dataProviders": [{"id": 7, "name": "Other Repository"}], "links": [{"type": "display", "url": "https://core.ac.uk/works/2002"}]}
+{"coreId": "3003", "title": "Undated", "repositories": [{"id": "42"}]}
+`
+
+func TestCORE(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, CORE{Collections: map[string]string{"7": "Theses"}}, strings.NewReader(example))
+	if len(results) != 3 {
+		t.Fatalf("got %d works, want 3", len(results))
+	}
+	if errs[0] != nil || errs[1] != nil {
+		t.Fatalf("unexpected errors: %v, %v", errs[0], errs[1])
+	}
+	if _, ok := errs[2].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for an undated work", errs[2])
+	}
+	is := results[0]
+	if is.MegaCollection != "CORE (Example Repository)" || is.DataProvider != "Example Repository" {
+		t.Errorf("got collection %q, data provider %q", is.MegaCollection, is.DataProvider)
+	}
+	want := []finc.Author{{LastName: "Doe", FirstName: "Jane"}, {Name: "Example Group"}}
+	if !reflect.DeepEqual(is.Authors, want) {
+		t.Errorf("got authors %v, want %v", is.Authors, want)
+	}
+	if is.Date.Format("2006-01-02") != "2017-03-01" || !reflect.DeepEqual(is.ISSN, []string{"1234-5678"}) || is.Publishers[0] != "Example Press" {
+		t.Errorf("got date %v, ISSN %v, publishers %v", is.Date, is.ISSN, is.Publishers)
+	}
+	if !reflect.DeepEqual(is.Subjects, []string{"Biology", "article"}) || !reflect.DeepEqual(is.Languages, []string{"eng"}) {
+		t.Errorf("got subjects %v, languages %v", is.Subjects, is.Languages)
+	}
+	thesis := results[1]
+	if thesis.MegaCollection != "Theses" || thesis.Format != "ElectronicThesis" || thesis.Date.Year() != 2020 {
+		t.Errorf("got collection %q, format %s, date %v", thesis.MegaCollection, thesis.Format, thesis.Date)
+	}
+	if !strings.HasPrefix(thesis.RecordID, "ai-"+SourceID+"-") || thesis.URL[0] != "https://core.ac.uk/works/2002" {
+		t.Errorf("got record id %s, URL %v", thesis.RecordID, thesis.URL)
+	}
+}
@@ -9,6 +9,9 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+
+	"github.com/miku/span"
 )
 
 // Message covers a generic API response.
@@ -86,28 +89,51 @@ func LookupMemberName(id int) (name string, err error) {
 	return name, nil
 }
 
-// PopulateMemberNameCache takes an LDJ filename with one member document per line and populates the cache.
-func PopulateMemberNameCache(filename string) error {
+// PopulateMemberNameCache takes an LDJ filename with one member document per
+// line and populates the cache. Lines, that cannot be parsed are skipped and
+// counted. It is an error, if the file cannot be read.
+func PopulateMemberNameCache(filename string) (skipped int, err error) {
 	handle, err := os.Open(filename)
-	defer handle.Close()
 	if err != nil {
-		return err
+		return 0, err
 	}
-	var member Member
+	defer handle.Close()
 	reader := bufio.NewReader(handle)
 	for {
 		line, err := reader.ReadString('\n')
-		if err == io.EOF {
+		if err == io.EOF && line == "" {
 			break
 		}
-		if err != nil {
-			return err
+		if err != nil && err != io.EOF {
+			return skipped, err
 		}
-		err = json.Unmarshal([]byte(line), &member)
-		if err != nil {
-			return err
+		var member Member
+		if err := json.Unmarshal([]byte(line), &member); err != nil {
+			skipped++
+			continue
 		}
 		cache.Set(member.ID, member.PrimaryName)
 	}
+	return skipped, nil
+}
+
+// LoadMemberNames populates the cache from a members file. In strict mode, a
+// file, that cannot be read or contains invalid lines, is an error. Otherwise
+// these problems are reported as warnings and all valid lines are used.
+func LoadMemberNames(filename string, strict bool) error {
+	skipped, err := PopulateMemberNameCache(filename)
+	if err != nil {
+		if strict {
+			return err
+		}
+		span.Warn(span.CodeMembers, "continuing without member names", "error", err.Error())
+	}
+	if skipped > 0 {
+		if strict {
+			return fmt.Errorf("%d invalid lines in members file", skipped)
+		}
+		span.Warn(span.CodeMembers, fmt.Sprintf("skipped %d invalid lines in members file", skipped),
+			"skipped", strconv.Itoa(skipped))
+	}
 	return nil
 }
@@ -0,0 +1,97 @@
+package crossref
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/miku/span"
+)
+
+func TestPopulateMemberNameCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "span-crossref-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "members.ldj")
+	content := `{"id": 1, "primary-name": "A"}
+{"id": 2, "prim
+{"id": 3, "primary-name": "C"}`
+	if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	skipped, err := PopulateMemberNameCache(filename)
+	if err != nil {
+		t.Fatalf("PopulateMemberNameCache: got %v, want nil", err)
+	}
+	if skipped != 1 {
+		t.Errorf("PopulateMemberNameCache: got %d skipped, want 1", skipped)
+	}
+	for id, want := range map[int]string{1: "A", 3: "C"} {
+		if name := cache.Entries[id]; name != want {
+			t.Errorf("PopulateMemberNameCache: got %q for %d, want %q", name, id, want)
+		}
+	}
+
+	if _, err := PopulateMemberNameCache(filepath.Join(dir, "missing.ldj")); err == nil {
+		t.Errorf("PopulateMemberNameCache: got nil, want error for missing file")
+	}
+}
+
+func TestLoadMemberNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "span-crossref-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	corrupt := filepath.Join(dir, "corrupt.ldj")
+	content := `{"id": 1, "primary-name": "A"}
+{"id": 2, "prim`
+	if err := ioutil.WriteFile(corrupt, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.ldj")
+
+	var buf bytes.Buffer
+	saved := span.Diagnostics
+	span.Diagnostics = span.NewJSONEmitter(&buf)
+	defer func() { span.Diagnostics = saved }()
+
+	var cases = []struct {
+		filename string
+		strict   bool
+		err      bool
+	}{
+		{missing, true, true},
+		{corrupt, true, true},
+		{missing, false, false},
+		{corrupt, false, false},
+	}
+	for _, c := range cases {
+		buf.Reset()
+		err := LoadMemberNames(c.filename, c.strict)
+		if (err != nil) != c.err {
+			t.Errorf("LoadMemberNames(%s, %v): got %v, want error %v", filepath.Base(c.filename), c.strict, err, c.err)
+		}
+		if c.strict {
+			if buf.Len() > 0 {
+				t.Errorf("LoadMemberNames(%s, %v): got warning %q, want none", filepath.Base(c.filename), c.strict, buf.String())
+			}
+			continue
+		}
+		var d span.Diagnostic
+		if err := json.Unmarshal(buf.Bytes(), &d); err != nil || d.Code != span.CodeMembers {
+			t.Errorf("LoadMemberNames(%s, %v): got %q, want a %s warning", filepath.Base(c.filename), c.strict, buf.String(), span.CodeMembers)
+		}
+	}
+	if name := cache.Entries[1]; name != "A" {
+		t.Errorf("LoadMemberNames: got %q for 1, want %q", name, "A")
+	}
+}
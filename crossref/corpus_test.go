@@ -0,0 +1,92 @@
+package crossref
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+)
+
+// corpusPattern matches the conversion corpus. Each file contains a single
+// crossref work; to add a case, drop a new file into the directory. Records
+// should not carry a member, since member names would be fetched from the API.
+const corpusPattern = "testdata/corpus/*.json"
+
+// convertCorpusRecord runs a single raw record through the whole pipeline,
+// from the batch apply function to a marshalled SOLR document. Attachments
+// are left empty, as with a nil tagger. It returns span.Skip, if the record
+// was skipped on purpose.
+func convertCorpusRecord(raw string) (b []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	batch := NewBatch([]string{raw})
+	doc, err := batch.Apply(batch.Items[0])
+	if err != nil {
+		return nil, err
+	}
+	defer doc.(span.Releaser).Release()
+	is, err := doc.ToIntermediateSchema()
+	if err != nil {
+		return nil, err
+	}
+	if is.RecordID == "" || is.SourceID != SourceID {
+		return nil, fmt.Errorf("invalid identifiers: record=%q, source=%q", is.RecordID, is.SourceID)
+	}
+	output := new(finc.Solr413Schema)
+	if err := output.Convert(*is); err != nil {
+		return nil, err
+	}
+	output.Attach(nil)
+	return json.Marshal(output)
+}
+
+func readCorpus(t testing.TB) map[string]string {
+	filenames, err := filepath.Glob(corpusPattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filenames) == 0 {
+		t.Fatalf("no files matching %s", corpusPattern)
+	}
+	corpus := make(map[string]string)
+	for _, fn := range filenames {
+		b, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		corpus[filepath.Base(fn)] = string(b)
+	}
+	return corpus
+}
+
+func TestCorpus(t *testing.T) {
+	for name, raw := range readCorpus(t) {
+		b, err := convertCorpusRecord(raw)
+		if err != nil {
+			if _, ok := err.(span.Skip); !ok {
+				t.Errorf("%s: got %v, want conversion or span.Skip", name, err)
+			}
+			continue
+		}
+		if !json.Valid(b) {
+			t.Errorf("%s: invalid JSON output", name)
+		}
+	}
+}
+
+func BenchmarkCorpus(b *testing.B) {
+	corpus := readCorpus(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, raw := range corpus {
+			convertCorpusRecord(raw)
+		}
+	}
+}
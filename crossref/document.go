@@ -8,12 +8,15 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/miku/span"
 	"github.com/miku/span/assetutil"
+	"github.com/miku/span/container"
 	"github.com/miku/span/finc"
 )
 
@@ -37,20 +40,43 @@ var (
 	Genres   = assetutil.MustLoadStringMap("assets/crossref/genres.json")
 	RefTypes = assetutil.MustLoadStringMap("assets/crossref/reftypes.json")
 
+	// UnmappedTypes counts crossref types without a format mapping.
+	UnmappedTypes = container.NewStringCounter()
+
 	// AuthorReplacer is a special cleaner for author names.
 	AuthorReplacer = strings.NewReplacer("#", "", "--", "", "*", "", "|", "", "&NA;", "", "\u0026NA;", "", "\u0026", "")
 )
 
-// Crossref source.
-type Crossref struct{}
+// documentPool holds documents for reuse, so we do not need to allocate a new
+// document for every line of input.
+var documentPool = sync.Pool{New: func() interface{} { return new(Document) }}
+
+// Crossref source. If Strict is set, fields of the input, that are not part
+// of Document, are errors, e.g. to find new fields in upstream data.
+type Crossref struct {
+	Strict bool
+}
 
-// NewBatch wraps up a new batch for channel com.
+// NewBatch wraps up a new batch for channel com. Documents are taken from a
+// pool, call Release on a document, once it is not needed anymore.
 func NewBatch(lines []string) span.Batcher {
+	return newBatch(lines, false)
+}
+
+// NewStrictBatch works like NewBatch, but unknown fields are errors.
+func NewStrictBatch(lines []string) span.Batcher {
+	return newBatch(lines, true)
+}
+
+func newBatch(lines []string, strict bool) span.Batcher {
 	batch := span.Batcher{
 		Apply: func(s interface{}) (span.Importer, error) {
-			doc := new(Document)
-			err := json.Unmarshal([]byte(s.(string)), doc)
-			if err != nil {
+			doc := documentPool.Get().(*Document)
+			dec := json.NewDecoder(strings.NewReader(s.(string)))
+			if strict {
+				dec.DisallowUnknownFields()
+			}
+			if err := dec.Decode(doc); err != nil {
 				return doc, err
 			}
 			return doc, nil
@@ -81,12 +107,12 @@ func (c Crossref) Iterate(r io.Reader) (<-chan interface{}, error) {
 			i++
 			lines = append(lines, line)
 			if i == BatchSize {
-				ch <- NewBatch(lines)
+				ch <- newBatch(lines, c.Strict)
 				lines = lines[:0]
 				i = 0
 			}
 		}
-		ch <- NewBatch(lines)
+		ch <- newBatch(lines, c.Strict)
 		close(ch)
 	}()
 	return ch, nil
@@ -129,6 +155,20 @@ type DateField struct {
 	Timestamp int64      `json:"timestamp"`
 }
 
+// Relation maps relation types, e.g. has-preprint or is-supplement-to, to the
+// related objects, which are not used.
+type Relation map[string]json.RawMessage
+
+// Types returns the sorted relation types.
+func (r Relation) Types() []string {
+	var types []string
+	for k := range r {
+		types = append(types, k)
+	}
+	sort.Strings(types)
+	return types
+}
+
 // Document is a example 'works' API response.
 type Document struct {
 	Authors        []Author  `json:"author"`
@@ -144,6 +184,7 @@ type Document struct {
 	Prefix         string    `json:"prefix"`
 	Publisher      string    `json:"publisher"`
 	ReferenceCount int       `json:"reference-count"`
+	Relation       Relation  `json:"relation"`
 	Score          float64   `json:"score"`
 	Source         string    `json:"source"`
 	Subjects       []string  `json:"subject"`
@@ -154,6 +195,30 @@ type Document struct {
 	Volume         string    `json:"volume"`
 }
 
+// Reset zeroes all fields, so no values of a previous record survive.
+func (doc *Document) Reset() {
+	*doc = Document{}
+}
+
+// Release resets the document and puts it back into the pool. The document
+// must not be used after it has been released.
+func (doc *Document) Release() {
+	doc.Reset()
+	documentPool.Put(doc)
+}
+
+// Format returns the finc format for a crossref type, as found in the
+// formats table. Types without mapping fall back to DefaultFormat and are
+// counted in UnmappedTypes.
+func Format(typ string) string {
+	format, ok := Formats[typ]
+	if !ok {
+		UnmappedTypes.Inc(typ)
+		return DefaultFormat
+	}
+	return format
+}
+
 // PageInfo holds various page related data.
 type PageInfo struct {
 	RawMessage string
@@ -288,16 +353,16 @@ func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 
 	output.Date, err = doc.Issued.Date()
 	if err != nil {
-		return output, err
+		return output, span.Skip{Reason: err.Error()}
 	}
 
 	if doc.URL == "" {
-		return output, errNoURL
+		return output, span.Skip{Reason: errNoURL.Error()}
 	}
 
 	output.ArticleTitle = doc.CombinedTitle()
 	output.DOI = doc.DOI
-	output.Format = Formats.LookupDefault(doc.Type, DefaultFormat)
+	output.Format = Format(doc.Type)
 	output.Genre = Genres.LookupDefault(doc.Type, "unknown")
 	output.ISSN = doc.ISSN
 	output.Issue = doc.Issue
@@ -305,6 +370,7 @@ func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	output.Publishers = append(output.Publishers, doc.Publisher)
 	output.RecordID = doc.RecordID()
 	output.RefType = RefTypes.LookupDefault(doc.Type, "GEN")
+	output.Relations = doc.Relation.Types()
 	output.SourceID = SourceID
 	output.Subjects = doc.Subjects
 	output.Type = doc.Type
@@ -1,9 +1,14 @@
 package crossref
 
 import (
+	"encoding/json"
 	"log"
+	"reflect"
 	"testing"
 	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/container"
 )
 
 func TestAuthorString(t *testing.T) {
@@ -54,3 +59,106 @@ func TestDateFieldDate(t *testing.T) {
 		}
 	}
 }
+
+func TestBatchApplyRelease(t *testing.T) {
+	batch := NewBatch([]string{
+		`{"DOI": "10.1/a", "ISSN": ["1234-5678"], "title": ["A"], "volume": "1"}`,
+		`{"DOI": "10.1/b"}`,
+	})
+	var docs []Document
+	for _, item := range batch.Items {
+		importer, err := batch.Apply(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc := importer.(*Document)
+		docs = append(docs, *doc)
+		doc.Release()
+	}
+	if docs[1].DOI != "10.1/b" {
+		t.Errorf("Apply: got DOI %v, want %v", docs[1].DOI, "10.1/b")
+	}
+	if len(docs[1].ISSN) > 0 || len(docs[1].Title) > 0 || docs[1].Volume != "" {
+		t.Errorf("Apply: values of a released document leaked: %+v", docs[1])
+	}
+}
+
+const benchmarkDocument = `{"author": [{"family": "Doe", "given": "John"}],
+	"container-title": ["Journal"], "DOI": "10.1/a", "ISSN": ["1234-5678"],
+	"issued": {"date-parts": [[2000, 10, 1]]}, "page": "1-10",
+	"publisher": "Publisher", "title": ["Title"], "type": "journal-article",
+	"URL": "http://dx.doi.org/10.1/a", "volume": "1"}`
+
+func BenchmarkBatchApply(b *testing.B) {
+	batch := NewBatch([]string{benchmarkDocument})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := batch.Apply(batch.Items[0]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBatchApplyRelease(b *testing.B) {
+	batch := NewBatch([]string{benchmarkDocument})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		doc, err := batch.Apply(batch.Items[0])
+		if err != nil {
+			b.Fatal(err)
+		}
+		doc.(span.Releaser).Release()
+	}
+}
+
+func TestFormat(t *testing.T) {
+	var tests = []struct {
+		typ    string
+		format string
+	}{
+		{"journal-article", "ElectronicArticle"},
+		{"proceedings-article", "ElectronicProceeding"},
+		{"book-chapter", "ElectronicBookPart"},
+		{"monograph", "eBook"},
+		{"posted-content", "ElectronicArticle"},
+		{"x-unknown-type", DefaultFormat},
+		{"x-unknown-type", DefaultFormat},
+	}
+	UnmappedTypes = container.NewStringCounter()
+	for _, tt := range tests {
+		if format := Format(tt.typ); format != tt.format {
+			t.Errorf("Format(%s): got %s, want %s", tt.typ, format, tt.format)
+		}
+	}
+	counts := UnmappedTypes.Counts()
+	if counts["x-unknown-type"] != 2 || len(counts) != 1 {
+		t.Errorf("UnmappedTypes: got %v, want only x-unknown-type with 2", counts)
+	}
+}
+
+func TestStrictBatch(t *testing.T) {
+	line := `{"DOI": "10.1000/1", "URL": "http://dx.doi.org/10.1000/1", "x-unexpected": true}`
+	var tests = []struct {
+		batch  span.Batcher
+		failed bool
+	}{
+		{NewBatch([]string{line}), false},
+		{NewStrictBatch([]string{line}), true},
+	}
+	for _, tt := range tests {
+		_, err := tt.batch.Apply(tt.batch.Items[0])
+		if (err != nil) != tt.failed {
+			t.Errorf("Apply: got %v, want error: %v", err, tt.failed)
+		}
+	}
+}
+
+func TestRelationTypes(t *testing.T) {
+	var doc Document
+	if err := json.Unmarshal([]byte(`{"relation": {"has-preprint": [{"id": "10.1101/1"}], "cites": []}}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"cites", "has-preprint"}; !reflect.DeepEqual(doc.Relation.Types(), want) {
+		t.Errorf("Relation.Types: got %v, want %v", doc.Relation.Types(), want)
+	}
+}
@@ -0,0 +1,189 @@
+package crossref
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultWorksEndpoint is the works route of the crossref REST API.
+const DefaultWorksEndpoint = "https://api.crossref.org/works"
+
+// WorkList is the message of a works response.
+type WorkList struct {
+	NextCursor   string            `json:"next-cursor"`
+	TotalResults int               `json:"total-results"`
+	Items        []json.RawMessage `json:"items"`
+}
+
+// Harvester pulls works from the crossref REST API with cursor based deep
+// paging and writes one work per line, as expected by span-import -i
+// crossref.
+//
+// Requests carry a mailto parameter and a User-Agent with contact
+// information, which directs them to the polite pool. The harvester waits at
+// least Interval between requests and slows down further, if the API
+// announces a lower rate limit in its X-Rate-Limit headers.
+type Harvester struct {
+	Endpoint string
+	Client   *http.Client
+	// Filter is a list of crossref filters, e.g. from-index-date:2017-01-01.
+	Filter []string
+	// Rows is the number of works per request, at most 1000.
+	Rows int
+	// Mailto is the contact address sent to the API.
+	Mailto    string
+	UserAgent string
+	Interval  time.Duration
+	// Retries is the number of additional attempts after a failed request,
+	// the wait time doubles with every retry.
+	Retries int
+	Backoff time.Duration
+
+	last time.Time
+}
+
+// NewHarvester returns a harvester with defaults for the polite pool.
+func NewHarvester(mailto string) *Harvester {
+	return &Harvester{
+		Endpoint:  DefaultWorksEndpoint,
+		Client:    &http.Client{Timeout: 60 * time.Second},
+		Rows:      1000,
+		Mailto:    mailto,
+		UserAgent: fmt.Sprintf("span (mailto:%s)", mailto),
+		Interval:  100 * time.Millisecond,
+		Retries:   5,
+		Backoff:   2 * time.Second,
+	}
+}
+
+// pageURL returns the URL for a cursor.
+func (h *Harvester) pageURL(cursor string) string {
+	v := url.Values{}
+	v.Set("cursor", cursor)
+	v.Set("rows", strconv.Itoa(h.Rows))
+	if len(h.Filter) > 0 {
+		v.Set("filter", strings.Join(h.Filter, ","))
+	}
+	if h.Mailto != "" {
+		v.Set("mailto", h.Mailto)
+	}
+	return h.Endpoint + "?" + v.Encode()
+}
+
+// wait keeps requests at least Interval apart.
+func (h *Harvester) wait() {
+	if d := h.Interval - time.Since(h.last); d > 0 {
+		time.Sleep(d)
+	}
+	h.last = time.Now()
+}
+
+// adjustRate lowers the request rate to the limit announced by the API,
+// e.g. X-Rate-Limit-Limit: 50 and X-Rate-Limit-Interval: 1s.
+func (h *Harvester) adjustRate(header http.Header) {
+	limit, err := strconv.Atoi(header.Get("X-Rate-Limit-Limit"))
+	if err != nil || limit <= 0 {
+		return
+	}
+	interval, err := time.ParseDuration(header.Get("X-Rate-Limit-Interval"))
+	if err != nil {
+		return
+	}
+	if d := interval / time.Duration(limit); d > h.Interval {
+		h.Interval = d
+	}
+}
+
+// fetch requests a single page, retrying on network errors, rate limiting
+// and server errors.
+func (h *Harvester) fetch(cursor string) (WorkList, error) {
+	var (
+		list    WorkList
+		err     error
+		backoff = h.Backoff
+	)
+	for i := 0; ; i++ {
+		h.wait()
+		list, err = h.get(h.pageURL(cursor))
+		if err == nil || i >= h.Retries {
+			return list, err
+		}
+		log.Printf("crossref: retrying in %s: %v", backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (h *Harvester) get(link string) (WorkList, error) {
+	var list WorkList
+	req, err := http.NewRequest("GET", link, nil)
+	if err != nil {
+		return list, err
+	}
+	if h.UserAgent != "" {
+		req.Header.Set("User-Agent", h.UserAgent)
+	}
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return list, err
+	}
+	defer resp.Body.Close()
+	h.adjustRate(resp.Header)
+	if resp.StatusCode != http.StatusOK {
+		return list, fmt.Errorf("crossref: %s returned %s", h.Endpoint, resp.Status)
+	}
+	var message Message
+	if err := json.NewDecoder(resp.Body).Decode(&message); err != nil {
+		return list, err
+	}
+	if message.Status != "ok" {
+		return list, fmt.Errorf("message status: %s", message.Status)
+	}
+	if message.Type != "work-list" {
+		return list, fmt.Errorf("invalid message type: %s", message.Type)
+	}
+	err = json.Unmarshal(message.Message, &list)
+	return list, err
+}
+
+// Harvest writes all works matching the filter to w, one per line, and
+// returns the number of works written.
+func (h *Harvester) Harvest(w io.Writer) (int, error) {
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+	var (
+		cursor = "*"
+		n      int
+		buf    bytes.Buffer
+	)
+	for {
+		list, err := h.fetch(cursor)
+		if err != nil {
+			return n, err
+		}
+		for _, item := range list.Items {
+			buf.Reset()
+			if err := json.Compact(&buf, item); err != nil {
+				return n, err
+			}
+			buf.WriteByte('\n')
+			if _, err := bw.Write(buf.Bytes()); err != nil {
+				return n, err
+			}
+			n++
+		}
+		if len(list.Items) == 0 || list.NextCursor == "" {
+			return n, nil
+		}
+		cursor = list.NextCursor
+	}
+}
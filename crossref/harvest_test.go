@@ -0,0 +1,83 @@
+package crossref
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHarvester(t *testing.T) {
+	var (
+		requests int
+		failed   bool
+	)
+	pages := map[string]string{
+		"*":  `{"status": "ok", "message-type": "work-list", "message": {"next-cursor": "c2", "total-results": 3, "items": [{"DOI": "10.1/a"}, {"DOI": "10.1/b"}]}}`,
+		"c2": `{"status": "ok", "message-type": "work-list", "message": {"next-cursor": "c3", "total-results": 3, "items": [{"DOI": "10.1/c"}]}}`,
+		"c3": `{"status": "ok", "message-type": "work-list", "message": {"next-cursor": "c4", "total-results": 3, "items": []}}`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		q := r.URL.Query()
+		if q.Get("filter") != "from-index-date:2017-01-01" || q.Get("mailto") != "ops@example.org" || q.Get("rows") != "2" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		if !strings.Contains(r.Header.Get("User-Agent"), "mailto:ops@example.org") {
+			t.Errorf("got User-Agent %q, want contact", r.Header.Get("User-Agent"))
+		}
+		if q.Get("cursor") == "c2" && !failed {
+			failed = true
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("X-Rate-Limit-Limit", "50")
+		w.Header().Set("X-Rate-Limit-Interval", "1s")
+		fmt.Fprint(w, pages[q.Get("cursor")])
+	}))
+	defer ts.Close()
+
+	h := NewHarvester("ops@example.org")
+	h.Endpoint = ts.URL
+	h.Filter = []string{"from-index-date:2017-01-01"}
+	h.Rows = 2
+	h.Interval = 0
+	h.Backoff = time.Millisecond
+
+	var buf bytes.Buffer
+	n, err := h.Harvest(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("got %d works, want 3", n)
+	}
+	want := "{\"DOI\":\"10.1/a\"}\n{\"DOI\":\"10.1/b\"}\n{\"DOI\":\"10.1/c\"}\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+	if requests != 4 {
+		t.Errorf("got %d requests, want 4, including one retry", requests)
+	}
+	if h.Interval != 20*time.Millisecond {
+		t.Errorf("got interval %s, want 20ms from rate limit headers", h.Interval)
+	}
+}
+
+func TestHarvesterFail(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+	h := NewHarvester("ops@example.org")
+	h.Endpoint = ts.URL
+	h.Interval = 0
+	h.Retries = 1
+	h.Backoff = time.Millisecond
+	if _, err := h.Harvest(&bytes.Buffer{}); err == nil {
+		t.Errorf("got nil, want error")
+	}
+}
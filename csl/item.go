@@ -0,0 +1,373 @@
+// Package csl converts CSL-JSON items, as produced by citeproc processors,
+// Zotero or Pandoc, into the intermediate schema. Input is either a JSON
+// array of items or one item per line.
+package csl
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// DefaultSourceID is used, if no source id is configured.
+	DefaultSourceID = "159"
+	// DefaultCollection is used, if no collection is configured.
+	DefaultCollection = "CSL-JSON"
+	// BatchSize number of items per batch.
+	BatchSize = 2000
+)
+
+// itemTypes maps supported CSL item types to format, genre and RIS type.
+var itemTypes = map[string][3]string{
+	"article-journal":   {"ElectronicArticle", "article", "JOUR"},
+	"article-magazine":  {"ElectronicArticle", "article", "MGZN"},
+	"article-newspaper": {"ElectronicArticle", "article", "NEWS"},
+	"article":           {"ElectronicPreprint", "preprint", "UNPB"},
+	"book":              {"eBook", "book", "EBOOK"},
+	"chapter":           {"ElectronicBookPart", "bookitem", "ECHAP"},
+	"paper-conference":  {"ElectronicProceeding", "proceeding", "CPAPER"},
+	"report":            {"ElectronicResourceRemoteAccess", "report", "RPRT"},
+	"thesis":            {"ElectronicThesis", "document", "THES"},
+	"dataset":           {"ElectronicResourceRemoteAccess", "document", "DATA"},
+}
+
+var (
+	pagesPattern = regexp.MustCompile(`^\s*([^-–\s]+)\s*[-–]+\s*([^-–\s]+)\s*$`)
+	yearPattern  = regexp.MustCompile(`[12][0-9]{3}`)
+)
+
+// CSL source. Source id and collection depend on the provider, empty values
+// fall back to the defaults.
+type CSL struct {
+	SourceID   string
+	Collection string
+}
+
+// Strings is a value, that is either a string or a list of strings. Lists
+// in a single string, separated by comma, are split.
+type Strings []string
+
+// UnmarshalJSON accepts a string or a list of strings.
+func (s *Strings) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	var values []string
+	switch t := v.(type) {
+	case string:
+		values = strings.Split(t, ",")
+	case []interface{}:
+		for _, e := range t {
+			values = append(values, fmt.Sprintf("%v", e))
+		}
+	case nil:
+	default:
+		values = append(values, fmt.Sprintf("%v", t))
+	}
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			*s = append(*s, value)
+		}
+	}
+	return nil
+}
+
+// Text is a value, that may be given as string or number, like volume.
+type Text string
+
+// UnmarshalJSON accepts a string or a number.
+func (t *Text) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch u := v.(type) {
+	case string:
+		*t = Text(strings.TrimSpace(u))
+	case float64:
+		*t = Text(strconv.FormatFloat(u, 'f', -1, 64))
+	case nil:
+	default:
+		return fmt.Errorf("csl: unexpected value: %s", b)
+	}
+	return nil
+}
+
+// Name is a CSL name variable, personal or literal.
+type Name struct {
+	Family              string `json:"family"`
+	Given               string `json:"given"`
+	NonDroppingParticle string `json:"non-dropping-particle"`
+	Literal             string `json:"literal"`
+}
+
+// Date is a CSL date variable.
+type Date struct {
+	DateParts [][]interface{} `json:"date-parts"`
+	Raw       string          `json:"raw"`
+	Literal   string          `json:"literal"`
+}
+
+// Time returns the first date of a date variable. Parts may be numbers or
+// strings, raw and literal dates need to contain a year.
+func (d Date) Time() (time.Time, error) {
+	if len(d.DateParts) > 0 && len(d.DateParts[0]) > 0 {
+		parts := []int{0, 1, 1}
+		for i, p := range d.DateParts[0] {
+			if i > 2 {
+				break
+			}
+			v, err := strconv.Atoi(strings.TrimSpace(fmt.Sprintf("%v", p)))
+			if err != nil {
+				return time.Time{}, fmt.Errorf("csl: invalid date part: %v", p)
+			}
+			parts[i] = v
+		}
+		if parts[0] > 0 && parts[1] >= 1 && parts[1] <= 12 && parts[2] >= 1 && parts[2] <= 31 {
+			return time.Date(parts[0], time.Month(parts[1]), parts[2], 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+	for _, s := range []string{d.Raw, d.Literal} {
+		if len(s) >= 10 {
+			if t, err := time.Parse("2006-01-02", s[:10]); err == nil {
+				return t, nil
+			}
+		}
+		if y := yearPattern.FindString(s); y != "" {
+			return time.Parse("2006", y)
+		}
+	}
+	return time.Time{}, fmt.Errorf("csl: no usable date")
+}
+
+// Item is a CSL-JSON item.
+type Item struct {
+	ID              Text    `json:"id"`
+	Type            string  `json:"type"`
+	Title           string  `json:"title"`
+	ContainerTitle  string  `json:"container-title"`
+	CollectionTitle string  `json:"collection-title"`
+	Author          []Name  `json:"author"`
+	Issued          Date    `json:"issued"`
+	DOI             string  `json:"DOI"`
+	URL             string  `json:"URL"`
+	ISSN            Strings `json:"ISSN"`
+	ISBN            Strings `json:"ISBN"`
+	Volume          Text    `json:"volume"`
+	Issue           Text    `json:"issue"`
+	Page            Text    `json:"page"`
+	Publisher       string  `json:"publisher"`
+	PublisherPlace  string  `json:"publisher-place"`
+	Language        string  `json:"language"`
+	Abstract        string  `json:"abstract"`
+	Keyword         string  `json:"keyword"`
+}
+
+// Document is an item together with the source settings and its raw JSON.
+type Document struct {
+	Item
+	raw    []byte
+	source CSL
+}
+
+// NewBatch wraps up a new batch for channel com. Items are decoded in
+// Apply.
+func NewBatch(items [][]byte, s CSL) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(v interface{}) (span.Importer, error) {
+			doc := &Document{raw: v.([]byte), source: s}
+			err := json.Unmarshal(doc.raw, &doc.Item)
+			return doc, err
+		}, Items: make([]interface{}, len(items))}
+	for i, item := range items {
+		batch.Items[i] = item
+	}
+	return batch
+}
+
+// Iterate emits items from a JSON array or from line delimited JSON. The
+// input format is detected from the first non-whitespace character.
+func (s CSL) Iterate(r io.Reader) (<-chan interface{}, error) {
+	if s.SourceID == "" {
+		s.SourceID = DefaultSourceID
+	}
+	if s.Collection == "" {
+		s.Collection = DefaultCollection
+	}
+	reader := bufio.NewReader(r)
+	if err := skipSpace(reader); err != nil {
+		return nil, err
+	}
+	var dec *json.Decoder
+	if b, err := reader.Peek(1); err == nil && b[0] == '[' {
+		dec = json.NewDecoder(reader)
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	}
+	ch := make(chan interface{})
+	go func() {
+		var items [][]byte
+		emit := func(b []byte) {
+			items = append(items, b)
+			if len(items) == BatchSize {
+				ch <- NewBatch(items, s)
+				items = nil
+			}
+		}
+		if dec != nil {
+			for dec.More() {
+				var raw json.RawMessage
+				if err := dec.Decode(&raw); err != nil {
+					log.Fatal(err)
+				}
+				emit(raw)
+			}
+		} else {
+			for {
+				line, err := reader.ReadBytes('\n')
+				if line = bytes.TrimSpace(line); len(line) > 0 {
+					emit(line)
+				}
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+		}
+		ch <- NewBatch(items, s)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// skipSpace skips leading whitespace and a byte order mark.
+func skipSpace(r *bufio.Reader) error {
+	for {
+		c, _, err := r.ReadRune()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !unicode.IsSpace(c) && c != '\ufeff' {
+			return r.UnreadRune()
+		}
+	}
+}
+
+// Key returns a stable key for the record id: the item id, the DOI or a
+// hash of the item.
+func (doc *Document) Key() string {
+	if doc.ID != "" {
+		return string(doc.ID)
+	}
+	if doc.DOI != "" {
+		return doc.DOI
+	}
+	return fmt.Sprintf("%x", sha1.Sum(doc.raw))
+}
+
+// Authors returns personal and literal names.
+func (doc *Document) Authors() (authors []finc.Author) {
+	for _, name := range doc.Author {
+		if name.Literal != "" {
+			authors = append(authors, finc.Author{Name: name.Literal})
+			continue
+		}
+		last := strings.TrimSpace(strings.Join([]string{name.NonDroppingParticle, name.Family}, " "))
+		if last == "" && name.Given == "" {
+			continue
+		}
+		authors = append(authors, finc.Author{LastName: last, FirstName: name.Given})
+	}
+	return authors
+}
+
+// ToIntermediateSchema converts an item. Unsupported item types and items
+// without a date are skipped.
+func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	types, ok := itemTypes[doc.Type]
+	if !ok {
+		return output, span.Skip{Reason: fmt.Sprintf("csl: %s: unsupported item type %s", doc.Key(), doc.Type)}
+	}
+	output.Date, err = doc.Issued.Time()
+	if err != nil {
+		return output, span.Skip{Reason: fmt.Sprintf("csl: %s: %v", doc.Key(), err)}
+	}
+
+	enc := fmt.Sprintf("ai-%s-%s", doc.source.SourceID, base64.URLEncoding.EncodeToString([]byte(doc.Key())))
+	output.SourceID = doc.source.SourceID
+	output.RecordID = strings.TrimRight(enc, "=")
+	output.MegaCollection = doc.source.Collection
+	output.Format, output.Genre, output.RefType = types[0], types[1], types[2]
+
+	output.ArticleTitle = strings.TrimSpace(doc.Title)
+	output.Authors = doc.Authors()
+	switch output.Genre {
+	case "bookitem", "proceeding":
+		output.BookTitle = strings.TrimSpace(doc.ContainerTitle)
+	case "book":
+		output.BookTitle = output.ArticleTitle
+	default:
+		output.JournalTitle = strings.TrimSpace(doc.ContainerTitle)
+	}
+	output.Series = strings.TrimSpace(doc.CollectionTitle)
+	output.Volume = string(doc.Volume)
+	output.Issue = string(doc.Issue)
+	if m := pagesPattern.FindStringSubmatch(string(doc.Page)); m != nil {
+		output.StartPage, output.EndPage = m[1], m[2]
+		output.Pages = m[1] + "-" + m[2]
+	} else {
+		output.Pages = string(doc.Page)
+	}
+	output.DOI = strings.TrimSpace(doc.DOI)
+	output.ISSN = doc.ISSN
+	output.ISBN = doc.ISBN
+	if doc.URL != "" {
+		output.URL = append(output.URL, doc.URL)
+	} else if output.DOI != "" {
+		output.URL = append(output.URL, "https://doi.org/"+output.DOI)
+	}
+	if doc.Publisher != "" {
+		output.Publishers = append(output.Publishers, doc.Publisher)
+	}
+	if doc.PublisherPlace != "" {
+		output.Places = append(output.Places, doc.PublisherPlace)
+	}
+	if doc.Language != "" {
+		// Language tags like en-US.
+		tag := strings.SplitN(strings.Replace(doc.Language, "_", "-", -1), "-", 2)[0]
+		if code := span.NormalizeLanguage(tag); code != "und" {
+			output.Languages = append(output.Languages, code)
+		}
+	}
+	output.Abstract = strings.TrimSpace(doc.Abstract)
+	for _, kw := range strings.Split(doc.Keyword, ",") {
+		if kw = strings.TrimSpace(kw); kw != "" {
+			output.Subjects = append(output.Subjects, kw)
+		}
+	}
+	return output, nil
+}
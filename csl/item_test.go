@@ -0,0 +1,91 @@
+package csl
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/internal/spantest"
+)
+
+const array = `
+[
+	{
+		"id": "doe2016",
+		"type": "article-journal",
+		"title": "On examples",
+		"container-title": "Journal of Examples",
+		"author": [{"family": "Doe", "given": "Jane"}, {"literal": "Example Consortium"}],
+		"issued": {"date-parts": [["2016", 3]]},
+		"volume": 12,
+		"issue": "3",
+		"page": "101–110",
+		"ISSN": "1234-5678, 2345-6789",
+		"DOI": "10.1000/csl.1",
+		"language": "en-US"
+	},
+	{
+		"id": 2,
+		"type": "chapter",
+		"title": "A chapter",
+		"container-title": "A book",
+		"author": [{"family": "Beethoven", "given": "Ludwig", "non-dropping-particle": "van"}],
+		"issued": {"raw": "2015"},
+		"ISBN": ["978-3-16-148410-0"]
+	},
+	{"id": "x", "type": "song", "title": "Unsupported", "issued": {"date-parts": [[2000]]}}
+]`
+
+const lines = `{"id": "a", "type": "book", "title": "A book", "issued": {"date-parts": [[2001]]}, "publisher": "Example Press"}
+{"id": "b", "type": "report", "title": "Undated"}
+`
+
+func TestArray(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, CSL{}, strings.NewReader(array))
+	if len(results) != 3 {
+		t.Fatalf("got %d items, want 3", len(results))
+	}
+	if errs[0] != nil || errs[1] != nil {
+		t.Fatalf("unexpected errors: %v, %v", errs[0], errs[1])
+	}
+	if _, ok := errs[2].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for unsupported type", errs[2])
+	}
+	article := results[0]
+	if article.JournalTitle != "Journal of Examples" || article.Volume != "12" || article.Pages != "101-110" {
+		t.Errorf("got journal %q, volume %q, pages %q", article.JournalTitle, article.Volume, article.Pages)
+	}
+	if !reflect.DeepEqual(article.ISSN, []string{"1234-5678", "2345-6789"}) || article.Date.Format("2006-01") != "2016-03" {
+		t.Errorf("got ISSN %v, date %v", article.ISSN, article.Date)
+	}
+	if len(article.Authors) != 2 || article.Authors[1].Name != "Example Consortium" {
+		t.Errorf("got authors %v", article.Authors)
+	}
+	if !reflect.DeepEqual(article.Languages, []string{"eng"}) || article.SourceID != DefaultSourceID {
+		t.Errorf("got languages %v, source id %q", article.Languages, article.SourceID)
+	}
+	chapter := results[1]
+	if chapter.BookTitle != "A book" || chapter.Genre != "bookitem" || chapter.Date.Year() != 2015 {
+		t.Errorf("got book title %q, genre %s, date %v", chapter.BookTitle, chapter.Genre, chapter.Date)
+	}
+	if chapter.Authors[0].LastName != "van Beethoven" {
+		t.Errorf("got authors %v", chapter.Authors)
+	}
+}
+
+func TestLines(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, CSL{SourceID: "9", Collection: "Zotero"}, strings.NewReader(lines))
+	if len(results) != 2 {
+		t.Fatalf("got %d items, want 2", len(results))
+	}
+	if errs[0] != nil {
+		t.Fatal(errs[0])
+	}
+	if _, ok := errs[1].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for an undated item", errs[1])
+	}
+	if results[0].SourceID != "9" || results[0].MegaCollection != "Zotero" || results[0].Format != "eBook" {
+		t.Errorf("got source %q, collection %q, format %s", results[0].SourceID, results[0].MegaCollection, results[0].Format)
+	}
+}
@@ -0,0 +1,51 @@
+package span
+
+import (
+	"database/sql"
+
+	"github.com/miku/span/container"
+)
+
+// Rows is the subset of *sql.Rows, that is needed to read ISIL and ISSN
+// assignments. It allows to test loaders without a database.
+type Rows interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+	Close() error
+}
+
+// NewListFiltersFromRows reads (isil, issn) rows and returns one ListFilter
+// per ISIL. The rows are closed afterwards.
+func NewListFiltersFromRows(rows Rows) (map[string]ListFilter, error) {
+	defer rows.Close()
+	filters := make(map[string]ListFilter)
+	for rows.Next() {
+		var isil, issn string
+		if err := rows.Scan(&isil, &issn); err != nil {
+			return filters, err
+		}
+		if _, ok := filters[isil]; !ok {
+			filters[isil] = ListFilter{Set: container.NewStringSet()}
+		}
+		filters[isil].Set.Add(issn)
+	}
+	return filters, rows.Err()
+}
+
+// LoadListFiltersFromDB runs a query, that must return (isil, issn) rows, and
+// returns one ListFilter per ISIL. Meant to be called once at startup.
+func LoadListFiltersFromDB(db *sql.DB, query string, args ...interface{}) (map[string]ListFilter, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return NewListFiltersFromRows(rows)
+}
+
+// AddListFilters appends list filters to the filters of the respective ISIL.
+func (t ISILTagger) AddListFilters(filters map[string]ListFilter) {
+	for isil, f := range filters {
+		t[isil] = append(t[isil], f)
+	}
+}
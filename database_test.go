@@ -0,0 +1,160 @@
+package span
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/miku/span/finc"
+)
+
+// memTables are the tables of the memory driver, by data source name.
+var memTables = map[string][][2]string{
+	"assignments": {
+		{"DE-1", "1234-5678"},
+		{"DE-1", "2345-6789"},
+		{"DE-2", "2345-6789"},
+	},
+}
+
+// memQueries counts the queries run with the memory driver.
+var memQueries int64
+
+func init() {
+	sql.Register("span-mem", memDriver{})
+}
+
+// memDriver is a minimal database/sql driver. A data source name refers to a
+// table of (isil, issn) rows, every query returns all rows of the table.
+type memDriver struct{}
+
+func (memDriver) Open(dsn string) (driver.Conn, error) {
+	rows, ok := memTables[dsn]
+	if !ok {
+		return nil, fmt.Errorf("no such table: %s", dsn)
+	}
+	return memConn(rows), nil
+}
+
+type memConn [][2]string
+
+func (c memConn) Prepare(query string) (driver.Stmt, error) { return memStmt(c), nil }
+func (c memConn) Close() error                              { return nil }
+func (c memConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+type memStmt [][2]string
+
+func (s memStmt) Close() error  { return nil }
+func (s memStmt) NumInput() int { return -1 }
+func (s memStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, driver.ErrSkip
+}
+func (s memStmt) Query(args []driver.Value) (driver.Rows, error) {
+	atomic.AddInt64(&memQueries, 1)
+	return &memRows{rows: s}, nil
+}
+
+type memRows struct {
+	rows [][2]string
+	i    int
+}
+
+func (r *memRows) Columns() []string { return []string{"isil", "issn"} }
+func (r *memRows) Close() error      { return nil }
+func (r *memRows) Next(dest []driver.Value) error {
+	if r.i == len(r.rows) {
+		return io.EOF
+	}
+	dest[0], dest[1] = r.rows[r.i][0], r.rows[r.i][1]
+	r.i++
+	return nil
+}
+
+func TestLoadListFiltersFromDB(t *testing.T) {
+	db, err := sql.Open("span-mem", "assignments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	filters, err := LoadListFiltersFromDB(db, "SELECT isil, issn FROM assignments")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tagger := make(ISILTagger)
+	tagger.AddListFilters(filters)
+
+	var tests = []struct {
+		issn  string
+		isils []string
+	}{
+		{"1234-5678", []string{"DE-1"}},
+		{"2345-6789", []string{"DE-1", "DE-2"}},
+		{"3456-7890", nil},
+	}
+	for _, tt := range tests {
+		isils := tagger.Tags(finc.IntermediateSchema{ISSN: []string{tt.issn}})
+		sort.Strings(isils)
+		if !reflect.DeepEqual(isils, tt.isils) {
+			t.Errorf("Tags(%s): got %v, want %v", tt.issn, isils, tt.isils)
+		}
+	}
+
+	if _, err := LoadListFiltersFromDB(db, "SELECT isil, issn FROM assignments", "x"); err != nil {
+		t.Errorf("LoadListFiltersFromDB: got %v, want nil with query arguments", err)
+	}
+}
+
+func TestLoadISILTaggerListDB(t *testing.T) {
+	config := `{"DE-2": [{"list-db": {"driver": "span-mem", "dsn": "assignments",
+		"query": "SELECT isil, issn FROM assignments", "isil": "DE-2"}}]}`
+	tagger, err := LoadISILTagger(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for issn, want := range map[string]bool{"2345-6789": true, "1234-5678": false} {
+		if r := tagger["DE-2"][0].Apply(finc.IntermediateSchema{ISSN: []string{issn}}); r != want {
+			t.Errorf("list-db Apply(%s): got %v, want %v", issn, r, want)
+		}
+	}
+
+	for _, config := range []string{
+		`{"driver": "span-mem", "dsn": "assignments", "query": "SELECT isil, issn FROM assignments", "isil": "DE-3"}`,
+		`{"driver": "span-mem", "dsn": "missing", "query": "SELECT isil, issn FROM missing", "isil": "DE-1"}`,
+		`{"driver": "unknown", "dsn": "assignments", "query": "SELECT isil, issn FROM assignments", "isil": "DE-1"}`,
+		`{"dsn": "assignments", "isil": "DE-1"}`,
+	} {
+		if _, err := LoadISILTagger(strings.NewReader(`{"DE-1": [{"list-db": ` + config + `}]}`)); err == nil {
+			t.Errorf("LoadISILTagger(%s): got nil, want error", config)
+		}
+	}
+}
+
+func TestLoadISILTaggerListDBQueryOnce(t *testing.T) {
+	spec := func(isil string) string {
+		return `[{"list-db": {"driver": "span-mem", "dsn": "assignments",
+			"query": "SELECT isil, issn FROM assignments", "isil": "` + isil + `"}}]`
+	}
+	config := `{"DE-1": ` + spec("DE-1") + `, "DE-2": ` + spec("DE-2") + `}`
+	for i := 1; i <= 2; i++ {
+		before := atomic.LoadInt64(&memQueries)
+		tagger, err := LoadISILTagger(strings.NewReader(config))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := atomic.LoadInt64(&memQueries) - before; n != 1 {
+			t.Errorf("LoadISILTagger (%d): got %d queries, want 1", i, n)
+		}
+		isils := tagger.Tags(finc.IntermediateSchema{ISSN: []string{"2345-6789"}})
+		sort.Strings(isils)
+		if !reflect.DeepEqual(isils, []string{"DE-1", "DE-2"}) {
+			t.Errorf("LoadISILTagger (%d): got %v, want [DE-1 DE-2]", i, isils)
+		}
+	}
+}
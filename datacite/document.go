@@ -0,0 +1,324 @@
+// Package datacite converts DataCite JSON, as returned by the DataCite REST
+// API, into the intermediate schema. Input is line delimited, one resource
+// per line, either bare or wrapped in a "data" object.
+package datacite
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/miku/span"
+	"github.com/miku/span/assetutil"
+	"github.com/miku/span/container"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// SourceID for internal bookkeeping.
+	SourceID = "144"
+	// Collection name.
+	Collection = "DataCite"
+	// DefaultFormat for resource types without a mapping.
+	DefaultFormat = "ElectronicResourceRemoteAccess"
+	// BatchSize for grouped channel transport.
+	BatchSize = 10000
+)
+
+var (
+	Formats  = assetutil.MustLoadStringMap("assets/datacite/formats.json")
+	Genres   = assetutil.MustLoadStringMap("assets/datacite/genres.json")
+	RefTypes = assetutil.MustLoadStringMap("assets/datacite/reftypes.json")
+
+	// UnmappedTypes counts resourceTypeGeneral values without a format mapping.
+	UnmappedTypes = container.NewStringCounter()
+)
+
+// DataCite source.
+type DataCite struct{}
+
+// Response wraps a single resource, like the DataCite REST API does.
+type Response struct {
+	Data *Document `json:"data"`
+}
+
+// Document is a DataCite resource.
+type Document struct {
+	ID         string `json:"id"`
+	Attributes struct {
+		DOI      string `json:"doi"`
+		URL      string `json:"url"`
+		Creators []struct {
+			Name       string `json:"name"`
+			NameType   string `json:"nameType"`
+			GivenName  string `json:"givenName"`
+			FamilyName string `json:"familyName"`
+		} `json:"creators"`
+		Titles []struct {
+			Title     string `json:"title"`
+			TitleType string `json:"titleType"`
+		} `json:"titles"`
+		Publisher       string      `json:"publisher"`
+		PublicationYear json.Number `json:"publicationYear"`
+		Subjects        []struct {
+			Subject string `json:"subject"`
+		} `json:"subjects"`
+		Dates []struct {
+			Date     string `json:"date"`
+			DateType string `json:"dateType"`
+		} `json:"dates"`
+		Language string `json:"language"`
+		Types    struct {
+			ResourceTypeGeneral string `json:"resourceTypeGeneral"`
+			ResourceType        string `json:"resourceType"`
+		} `json:"types"`
+		RelatedIdentifiers []RelatedIdentifier `json:"relatedIdentifiers"`
+		Descriptions       []struct {
+			Description     string `json:"description"`
+			DescriptionType string `json:"descriptionType"`
+		} `json:"descriptions"`
+	} `json:"attributes"`
+}
+
+// RelatedIdentifier links a resource to another resource.
+type RelatedIdentifier struct {
+	RelatedIdentifier     string `json:"relatedIdentifier"`
+	RelatedIdentifierType string `json:"relatedIdentifierType"`
+	RelationType          string `json:"relationType"`
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(lines []string) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			resp := new(Response)
+			if err := json.Unmarshal([]byte(s.(string)), resp); err != nil {
+				return nil, err
+			}
+			if resp.Data != nil {
+				return resp.Data, nil
+			}
+			doc := new(Document)
+			err := json.Unmarshal([]byte(s.(string)), doc)
+			return doc, err
+		}, Items: make([]interface{}, len(lines))}
+	for i, line := range lines {
+		batch.Items[i] = line
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via JSON decoding.
+func (s DataCite) Iterate(r io.Reader) (<-chan interface{}, error) {
+	ch := make(chan interface{})
+	reader := bufio.NewReader(r)
+	i := 0
+	var lines []string
+	go func() {
+		for {
+			line, err := reader.ReadString('\n')
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+			i++
+			lines = append(lines, line)
+			if i == BatchSize {
+				ch <- NewBatch(lines)
+				lines = lines[:0]
+				i = 0
+			}
+		}
+		ch <- NewBatch(lines)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Format returns the finc format for a resourceTypeGeneral, as found in the
+// formats table. Types without mapping fall back to DefaultFormat and are
+// counted in UnmappedTypes.
+func Format(typ string) string {
+	format, ok := Formats[typ]
+	if !ok {
+		UnmappedTypes.Inc(typ)
+		return DefaultFormat
+	}
+	return format
+}
+
+// DOI returns the DOI, which is also the id of a resource.
+func (doc *Document) DOI() string {
+	if doc.Attributes.DOI != "" {
+		return doc.Attributes.DOI
+	}
+	return doc.ID
+}
+
+// RecordID is of the form <kind>-<source-id>-<id-base64-unpadded>, with the
+// DOI as primary key.
+func (doc *Document) RecordID() string {
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(doc.DOI())))
+	return strings.TrimRight(enc, "=")
+}
+
+// Date returns the issued date, falling back to the publication year.
+func (doc *Document) Date() (time.Time, error) {
+	for _, d := range doc.Attributes.Dates {
+		if d.DateType != "Issued" {
+			continue
+		}
+		for _, layout := range []string{"2006-01-02", "2006-01", "2006"} {
+			if len(d.Date) < len(layout) {
+				continue
+			}
+			if t, err := time.Parse(layout, d.Date[:len(layout)]); err == nil {
+				return t, nil
+			}
+		}
+	}
+	year := doc.Attributes.PublicationYear.String()
+	if _, err := strconv.Atoi(year); err != nil {
+		return time.Time{}, fmt.Errorf("%s: no usable date", doc.DOI())
+	}
+	return time.Parse("2006", year)
+}
+
+// Authors returns the creators. Organizations are kept as corporations.
+func (doc *Document) Authors() (authors []finc.Author) {
+	for _, c := range doc.Attributes.Creators {
+		switch {
+		case c.NameType == "Organizational":
+			authors = append(authors, finc.Author{Corporation: c.Name})
+		case c.FamilyName != "":
+			authors = append(authors, finc.Author{LastName: c.FamilyName, FirstName: c.GivenName})
+		default:
+			authors = append(authors, finc.Author{Name: c.Name})
+		}
+	}
+	return authors
+}
+
+// Title returns the main title, or the first title, if no title is untyped.
+func (doc *Document) Title() (title, subtitle string) {
+	for _, t := range doc.Attributes.Titles {
+		switch t.TitleType {
+		case "":
+			if title == "" {
+				title = t.Title
+			}
+		case "Subtitle":
+			if subtitle == "" {
+				subtitle = t.Title
+			}
+		}
+	}
+	if title == "" && len(doc.Attributes.Titles) > 0 {
+		title = doc.Attributes.Titles[0].Title
+	}
+	return strings.TrimSpace(title), strings.TrimSpace(subtitle)
+}
+
+// Abstract returns the first description of type abstract.
+func (doc *Document) Abstract() string {
+	for _, d := range doc.Attributes.Descriptions {
+		if d.DescriptionType == "Abstract" {
+			return strings.TrimSpace(d.Description)
+		}
+	}
+	return ""
+}
+
+// ISSNs returns the ISSNs of the containing serial, given as related
+// identifiers with a IsPartOf or IsPublishedIn relation.
+func (doc *Document) ISSNs() (issns []string) {
+	for _, r := range doc.Attributes.RelatedIdentifiers {
+		if r.RelatedIdentifierType != "ISSN" {
+			continue
+		}
+		if r.RelationType == "IsPartOf" || r.RelationType == "IsPublishedIn" {
+			issns = append(issns, strings.TrimSpace(r.RelatedIdentifier))
+		}
+	}
+	return issns
+}
+
+// RelationTypes returns the sorted, distinct relation types in the same
+// notation as crossref uses, e.g. IsSupplementTo becomes is-supplement-to, so
+// relation filters work across sources.
+func (doc *Document) RelationTypes() []string {
+	set := container.NewStringSet()
+	for _, r := range doc.Attributes.RelatedIdentifiers {
+		if r.RelationType != "" {
+			set.Add(kebab(r.RelationType))
+		}
+	}
+	return set.SortedValues()
+}
+
+// kebab turns a camel case identifier into lowercase words joined by dashes.
+func kebab(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteRune('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// ToIntermediateSchema converts a DataCite resource. Resources without a
+// usable date are skipped.
+func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	output.Date, err = doc.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+
+	typ := doc.Attributes.Types.ResourceTypeGeneral
+
+	output.SourceID = SourceID
+	output.RecordID = doc.RecordID()
+	output.MegaCollection = Collection
+	output.Format = Format(typ)
+	output.Genre = Genres.LookupDefault(typ, "unknown")
+	output.RefType = RefTypes.LookupDefault(typ, "GEN")
+
+	output.DOI = doc.DOI()
+	if doc.Attributes.URL != "" {
+		output.URL = append(output.URL, doc.Attributes.URL)
+	}
+	output.ArticleTitle, output.ArticleSubtitle = doc.Title()
+	output.Authors = doc.Authors()
+	output.Abstract = doc.Abstract()
+	output.ISSN = doc.ISSNs()
+	output.Relations = doc.RelationTypes()
+
+	if doc.Attributes.Publisher != "" {
+		output.Publishers = append(output.Publishers, doc.Attributes.Publisher)
+	}
+	for _, s := range doc.Attributes.Subjects {
+		output.Subjects = append(output.Subjects, s.Subject)
+	}
+	if doc.Attributes.Language != "" {
+		output.Languages = append(output.Languages, span.NormalizeLanguage(doc.Attributes.Language))
+	}
+
+	return output, nil
+}
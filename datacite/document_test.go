@@ -0,0 +1,108 @@
+package datacite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/container"
+	"github.com/miku/span/finc"
+)
+
+const example = `{"data": {"id": "10.5061/dryad.8515", "type": "dois", "attributes": {
+	"doi": "10.5061/dryad.8515",
+	"url": "http://datadryad.org/stash/dataset/doi:10.5061/dryad.8515",
+	"creators": [
+		{"name": "Ollomo, Benjamin", "nameType": "Personal", "givenName": "Benjamin", "familyName": "Ollomo"},
+		{"name": "Example Consortium", "nameType": "Organizational"}
+	],
+	"titles": [{"title": "Data from: A new malaria agent in African hominids."}],
+	"publisher": "Dryad",
+	"publicationYear": 2011,
+	"subjects": [{"subject": "Plasmodium"}],
+	"dates": [{"date": "2011-02-01T17:22:41Z", "dateType": "Available"}, {"date": "2011", "dateType": "Issued"}],
+	"language": "en",
+	"types": {"resourceTypeGeneral": "Dataset", "resourceType": "Dataset"},
+	"relatedIdentifiers": [
+		{"relatedIdentifier": "10.1371/journal.ppat.1000446", "relatedIdentifierType": "DOI", "relationType": "IsCitedBy"},
+		{"relatedIdentifier": "1553-7374", "relatedIdentifierType": "ISSN", "relationType": "IsPartOf"}
+	],
+	"descriptions": [{"description": "Abstract text.", "descriptionType": "Abstract"}]
+}}}`
+
+func convert(t *testing.T, line string) (*finc.IntermediateSchema, error) {
+	batch := NewBatch([]string{line})
+	doc, err := batch.Apply(batch.Items[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc.ToIntermediateSchema()
+}
+
+func TestToIntermediateSchema(t *testing.T) {
+	is, err := convert(t, example)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if is.Format != "ElectronicResourceRemoteAccess" || is.Genre != "document" || is.RefType != "DATA" {
+		t.Errorf("got %s, %s, %s for a dataset", is.Format, is.Genre, is.RefType)
+	}
+	if is.DOI != "10.5061/dryad.8515" {
+		t.Errorf("got DOI %s", is.DOI)
+	}
+	if is.Date.Year() != 2011 {
+		t.Errorf("got date %v, want 2011", is.Date)
+	}
+	if len(is.Authors) != 2 || is.Authors[0].LastName != "Ollomo" || is.Authors[1].Corporation != "Example Consortium" {
+		t.Errorf("got authors %v", is.Authors)
+	}
+	if !reflect.DeepEqual(is.ISSN, []string{"1553-7374"}) {
+		t.Errorf("got ISSN %v, want the IsPartOf ISSN", is.ISSN)
+	}
+	if !reflect.DeepEqual(is.Relations, []string{"is-cited-by", "is-part-of"}) {
+		t.Errorf("got relations %v", is.Relations)
+	}
+	if !reflect.DeepEqual(is.Languages, []string{"eng"}) {
+		t.Errorf("got languages %v", is.Languages)
+	}
+	if is.Abstract != "Abstract text." {
+		t.Errorf("got abstract %q", is.Abstract)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	UnmappedTypes = container.NewStringCounter()
+	var cases = []struct {
+		typ, format string
+	}{
+		{"JournalArticle", "ElectronicArticle"},
+		{"Preprint", "ElectronicPreprint"},
+		{"Dissertation", "ElectronicThesis"},
+		{"Software", "ElectronicResourceRemoteAccess"},
+		{"Hologram", DefaultFormat},
+	}
+	for _, c := range cases {
+		if got := Format(c.typ); got != c.format {
+			t.Errorf("Format(%s): got %s, want %s", c.typ, got, c.format)
+		}
+	}
+	if UnmappedTypes.Counts()["Hologram"] != 1 {
+		t.Errorf("unmapped type not counted")
+	}
+}
+
+func TestUnwrapped(t *testing.T) {
+	line := `{"id": "10.1/x", "attributes": {"publicationYear": "2020", "types": {"resourceTypeGeneral": "Text"}}}`
+	is, err := convert(t, line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if is.DOI != "10.1/x" || is.Date.Year() != 2020 {
+		t.Errorf("got %s, %v", is.DOI, is.Date)
+	}
+	if _, err := convert(t, `{"id": "10.1/y", "attributes": {}}`); err == nil {
+		t.Errorf("got nil, want skip for missing date")
+	} else if _, ok := err.(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip", err)
+	}
+}
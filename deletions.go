@@ -0,0 +1,103 @@
+package span
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/miku/span/container"
+)
+
+// DeletionTracker finds records, that were attached to at least one ISIL in
+// a prior run, but are not attached to any ISIL anymore, e.g. because a
+// license lapsed. Safe for concurrent use.
+type DeletionTracker struct {
+	mu      sync.Mutex
+	prior   *container.StringSet
+	current *container.StringSet
+}
+
+// NewDeletionTracker reads the output of a prior run, one JSON document per
+// line, and remembers the ids of documents with a non-empty institution field.
+func NewDeletionTracker(r io.Reader) (*DeletionTracker, error) {
+	t := &DeletionTracker{prior: container.NewStringSet(), current: container.NewStringSet()}
+	err := readAttached(r, t.prior)
+	return t, err
+}
+
+// Restore reads output of the current run, that was written before, e.g. up
+// to a checkpoint, and records its attached documents as seen. Without this,
+// records written before a resume would all end up as deletions.
+func (t *DeletionTracker) Restore(r io.Reader) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return readAttached(r, t.current)
+}
+
+// readAttached adds the ids of documents with a non-empty institution field
+// to set, one JSON document per line.
+func readAttached(r io.Reader, set *container.StringSet) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if err == io.EOF && line == "" {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		var doc struct {
+			ID           string   `json:"id"`
+			Institutions []string `json:"institution"`
+		}
+		if err := json.Unmarshal([]byte(line), &doc); err != nil {
+			return err
+		}
+		if doc.ID != "" && len(doc.Institutions) > 0 {
+			set.Add(doc.ID)
+		}
+	}
+	return nil
+}
+
+// Seen records the ISILs attached to a record in the current run.
+func (t *DeletionTracker) Seen(id string, isils []string) {
+	if len(isils) == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.current.Add(id)
+}
+
+// Deletions returns the sorted ids, which were attached before, but are not
+// attached anymore.
+func (t *DeletionTracker) Deletions() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var ids []string
+	for _, id := range t.prior.Values() {
+		if !t.current.Contains(id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// WriteDeletions writes one SOLR delete-by-id document per line.
+func (t *DeletionTracker) WriteDeletions(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, id := range t.Deletions() {
+		doc := map[string]map[string]string{"delete": {"id": id}}
+		b, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		bw.Write(b)
+		bw.Write([]byte("\n"))
+	}
+	return bw.Flush()
+}
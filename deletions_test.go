@@ -0,0 +1,56 @@
+package span
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDeletionTracker(t *testing.T) {
+	prior := `{"id": "a", "institution": ["DE-1"]}
+{"id": "b", "institution": ["DE-1", "DE-2"]}
+{"id": "c"}
+{"id": "d", "institution": ["DE-2"]}
+`
+	tracker, err := NewDeletionTracker(strings.NewReader(prior))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker.Seen("a", []string{"DE-1"})
+	tracker.Seen("b", nil)
+	tracker.Seen("c", nil)
+
+	var buf bytes.Buffer
+	if err := tracker.WriteDeletions(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"delete":{"id":"b"}}
+{"delete":{"id":"d"}}
+`
+	if buf.String() != want {
+		t.Errorf("WriteDeletions: got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestDeletionTrackerRestore(t *testing.T) {
+	prior := `{"id": "a", "institution": ["DE-1"]}
+{"id": "b", "institution": ["DE-1"]}
+{"id": "c", "institution": ["DE-2"]}
+`
+	tracker, err := NewDeletionTracker(strings.NewReader(prior))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// output up to the checkpoint of an interrupted run
+	partial := `{"id": "a", "institution": ["DE-1"]}
+{"id": "b", "institution": ["DE-1"]}
+`
+	if err := tracker.Restore(strings.NewReader(partial)); err != nil {
+		t.Fatal(err)
+	}
+	// the resumed run sees the remaining records only
+	tracker.Seen("c", []string{"DE-2"})
+	if ids := tracker.Deletions(); len(ids) != 0 {
+		t.Errorf("Deletions after resume: got %v, want none", ids)
+	}
+}
@@ -0,0 +1,153 @@
+package span
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/miku/span/holdings"
+)
+
+// Diagnostic levels.
+const (
+	LevelWarning = "warning"
+	LevelError   = "error"
+	LevelInfo    = "info"
+)
+
+// Diagnostic codes are stable identifiers for kinds of diagnostics, that a
+// log processing system can rely on.
+const (
+	CodeHoldingsParse         = "holdings.parse"
+	CodeHoldingsUnknownUnit   = "holdings.unknown_unit"
+	CodeHoldingsUnknownFormat = "holdings.unknown_format"
+	CodeHoldingsDelayMismatch = "holdings.delay_mismatch"
+	CodeHoldingsInvalidYear   = "holdings.invalid_year"
+	CodeHoldingsVolumeTooBig  = "holdings.volume_too_big"
+	CodeHoldingsIssueTooBig   = "holdings.issue_too_big"
+	CodeHoldingsReversedRange = "holdings.reversed_range"
+	CodeHoldingsOther         = "holdings.other"
+	CodeHoldingsSkipped       = "holdings.skipped"
+	CodeMembers               = "crossref.members"
+	CodeUnmappedType          = "crossref.unmapped_type"
+	CodeEmptyFilter           = "filter.empty"
+	CodeTruncated             = "export.truncated"
+	CodeResume                = "export.resume"
+	CodeRecords               = "export.records"
+	CodeOutput                = "export.output"
+	CodeIncomplete            = "export.incomplete"
+	CodeUnmappedSource        = "export.unmapped_source"
+)
+
+// Diagnostic is a single warning, error or statistic.
+type Diagnostic struct {
+	Level   string            `json:"level"`
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Context map[string]string `json:"context,omitempty"`
+}
+
+// Emitter receives diagnostics.
+type Emitter interface {
+	Emit(Diagnostic)
+}
+
+// LogEmitter writes diagnostics as text through the standard logger.
+type LogEmitter struct{}
+
+// Emit logs level, message and context.
+func (LogEmitter) Emit(d Diagnostic) {
+	var keys []string
+	for k := range d.Context {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var kvs []string
+	for _, k := range keys {
+		kvs = append(kvs, k+"="+d.Context[k])
+	}
+	if len(kvs) > 0 {
+		log.Printf("%s: %s (%s)", d.Level, d.Message, strings.Join(kvs, ", "))
+		return
+	}
+	log.Printf("%s: %s", d.Level, d.Message)
+}
+
+// JSONEmitter writes one JSON object per diagnostic and line. Safe for
+// concurrent use.
+type JSONEmitter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONEmitter writes diagnostics to w.
+func NewJSONEmitter(w io.Writer) *JSONEmitter {
+	return &JSONEmitter{enc: json.NewEncoder(w)}
+}
+
+// Emit writes the diagnostic as JSON.
+func (e *JSONEmitter) Emit(d Diagnostic) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.enc.Encode(d); err != nil {
+		log.Println(err)
+	}
+}
+
+// Diagnostics receives all warnings of this package and the commands. Human
+// readable output to stderr by default.
+var Diagnostics Emitter = LogEmitter{}
+
+// Warn emits a warning. The context is a list of key value pairs.
+func Warn(code, message string, context ...string) {
+	Diagnostics.Emit(Diagnostic{Level: LevelWarning, Code: code, Message: message, Context: pairs(context)})
+}
+
+// Info emits an informational diagnostic, e.g. a statistic.
+func Info(code, message string, context ...string) {
+	Diagnostics.Emit(Diagnostic{Level: LevelInfo, Code: code, Message: message, Context: pairs(context)})
+}
+
+// pairs turns a list of key value pairs into a map, a missing last value is
+// left empty.
+func pairs(kvs []string) map[string]string {
+	if len(kvs) == 0 {
+		return nil
+	}
+	m := make(map[string]string)
+	for i := 0; i < len(kvs); i += 2 {
+		if i+1 < len(kvs) {
+			m[kvs[i]] = kvs[i+1]
+		} else {
+			m[kvs[i]] = ""
+		}
+	}
+	return m
+}
+
+// holdingsCode returns the diagnostic code for an error of the holdings package.
+func holdingsCode(err error) string {
+	var codes = []struct {
+		err  error
+		code string
+	}{
+		{holdings.ErrParse, CodeHoldingsParse},
+		{holdings.ErrUnknownUnit, CodeHoldingsUnknownUnit},
+		{holdings.ErrUnknownFormat, CodeHoldingsUnknownFormat},
+		{holdings.ErrDelayMismatch, CodeHoldingsDelayMismatch},
+		{holdings.ErrInvalidYear, CodeHoldingsInvalidYear},
+		{holdings.ErrVolumeTooBig, CodeHoldingsVolumeTooBig},
+		{holdings.ErrIssueTooBig, CodeHoldingsIssueTooBig},
+		{holdings.ErrReversedRange, CodeHoldingsReversedRange},
+	}
+	for _, c := range codes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return CodeHoldingsOther
+}
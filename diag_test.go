@@ -0,0 +1,32 @@
+package span
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestHoldingsWarningJSON(t *testing.T) {
+	var buf bytes.Buffer
+	saved := Diagnostics
+	Diagnostics = NewJSONEmitter(&buf)
+	defer func() { Diagnostics = saved }()
+
+	holding := testHolding("2010", "2000", "")
+	if _, err := NewHoldingFilter(strings.NewReader(holding)); err == nil {
+		t.Fatal("NewHoldingFilter: expected error for reversed range")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d diagnostics, want 1: %q", len(lines), buf.String())
+	}
+	var d Diagnostic
+	if err := json.Unmarshal([]byte(lines[0]), &d); err != nil {
+		t.Fatalf("diagnostic is not valid JSON: %s", err)
+	}
+	if d.Level != LevelWarning || d.Code != CodeHoldingsReversedRange || d.Message == "" {
+		t.Errorf("got %+v, want level %s, code %s and a message", d, LevelWarning, CodeHoldingsReversedRange)
+	}
+}
@@ -107,6 +107,7 @@ type BibJson struct {
 	EndPage    string       `json:"end_page"`
 	Identifier []Identifier `json:"identifier"`
 	Journal    Journal      `json:"journal"`
+	Keywords   []string     `json:"keywords"`
 	Link       []Link       `json:"link"`
 	Month      string       `json:"month"`
 	StartPage  string       `json:"start_page"`
@@ -163,6 +164,26 @@ func (s DOAJ) Iterate(r io.Reader) (<-chan interface{}, error) {
 	return ch, nil
 }
 
+// Identifiers returns print ISSNs, electronic ISSNs and the DOI from the
+// identifier list. If there are no ISSN identifiers, the ISSNs of the index
+// are used as print ISSNs.
+func (doc Document) Identifiers() (issn, eissn []string, doi string) {
+	for _, id := range doc.BibJson.Identifier {
+		switch strings.ToLower(id.Type) {
+		case "pissn":
+			issn = append(issn, id.ID)
+		case "eissn":
+			eissn = append(eissn, id.ID)
+		case "doi":
+			doi = id.ID
+		}
+	}
+	if len(issn) == 0 && len(eissn) == 0 {
+		issn = doc.Index.ISSN
+	}
+	return issn, eissn, doi
+}
+
 // Date return the document date. Journals entries usually have no date, so
 // they will err.
 func (doc Document) Date() (time.Time, error) {
@@ -197,7 +218,8 @@ func (doc Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	output.MegaCollection = Collection
 	output.Format = Format
 
-	output.ISSN = doc.Index.ISSN
+	output.ISSN, output.EISSN, output.DOI = doc.Identifiers()
+	output.Abstract = doc.BibJson.Abstract
 	output.ArticleTitle = doc.BibJson.Title
 	output.JournalTitle = doc.BibJson.Journal.Title
 	output.Volume = doc.BibJson.Journal.Volume
@@ -224,10 +246,14 @@ func (doc Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 			subjects.Add(class)
 		}
 	}
-	if subjects.Size() == 0 {
+	output.Subjects = subjects.SortedValues()
+	for _, kw := range doc.BibJson.Keywords {
+		if kw = strings.TrimSpace(kw); kw != "" {
+			output.Subjects = append(output.Subjects, kw)
+		}
+	}
+	if len(output.Subjects) == 0 {
 		output.Subjects = []string{finc.NOT_ASSIGNED}
-	} else {
-		output.Subjects = subjects.SortedValues()
 	}
 
 	languages := container.NewStringSet()
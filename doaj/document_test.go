@@ -0,0 +1,38 @@
+package doaj
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToIntermediateSchema(t *testing.T) {
+	line := `{"_id": "1", "_type": "article", "_source": {"id": "1", "bibjson": {
+		"title": "Open Access",
+		"abstract": "An abstract.",
+		"year": "2014", "month": "5",
+		"author": [{"name": "Jane Doe"}],
+		"keywords": ["open access", " "],
+		"identifier": [{"type": "pissn", "id": "1234-5678"}, {"type": "eissn", "id": "2345-6789"}, {"type": "doi", "id": "10.1000/1"}],
+		"journal": {"title": "Journal of Openness", "publisher": "Open Press"}}}}`
+	batch := NewBatch([]string{line})
+	doc, err := batch.Apply(batch.Items[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	is, err := doc.ToIntermediateSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(is.ISSN, []string{"1234-5678"}) || !reflect.DeepEqual(is.EISSN, []string{"2345-6789"}) {
+		t.Errorf("ISSN, EISSN: got %v, %v", is.ISSN, is.EISSN)
+	}
+	if is.DOI != "10.1000/1" || is.Abstract != "An abstract." {
+		t.Errorf("DOI, Abstract: got %q, %q", is.DOI, is.Abstract)
+	}
+	if len(is.Authors) != 1 || is.Authors[0].Name != "Jane Doe" {
+		t.Errorf("Authors: got %v", is.Authors)
+	}
+	if !reflect.DeepEqual(is.Subjects, []string{"open access"}) {
+		t.Errorf("Subjects: got %v, want [open access]", is.Subjects)
+	}
+}
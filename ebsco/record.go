@@ -0,0 +1,300 @@
+// Package ebsco converts article metadata delivered by EBSCO, in the record
+// XML of the EBSCOhost API, into the intermediate schema. Each record
+// belongs to a database, which is the unit of licensing. The database
+// becomes the collection of a record, so the existing filters can select
+// single content packages.
+package ebsco
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/doaj"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// SourceID for internal bookkeeping.
+	SourceID = "172"
+	// Collection is the prefix of the collection name of a database.
+	Collection = "EBSCO"
+	// BatchSize number of records per batch.
+	BatchSize = 2000
+)
+
+// docTypes maps document types to format, genre and RIS type.
+var docTypes = map[string][3]string{
+	"article":          {"ElectronicArticle", "article", "JOUR"},
+	"book":             {"eBook", "book", "EBOOK"},
+	"book chapter":     {"ElectronicBookPart", "bookitem", "ECHAP"},
+	"conference paper": {"ElectronicProceeding", "proceeding", "CPAPER"},
+	"dissertation":     {"ElectronicThesis", "document", "THES"},
+	"report":           {"ElectronicResourceRemoteAccess", "report", "RPRT"},
+}
+
+// pubTypes maps publication types, used if the document type is unknown.
+var pubTypes = map[string][3]string{
+	"academic journal":    {"ElectronicArticle", "article", "JOUR"},
+	"periodical":          {"ElectronicArticle", "article", "JOUR"},
+	"trade publication":   {"ElectronicArticle", "article", "JOUR"},
+	"magazine":            {"ElectronicArticle", "article", "MGZN"},
+	"newspaper":           {"ElectronicArticle", "article", "NEWS"},
+	"book":                {"eBook", "book", "EBOOK"},
+	"conference paper":    {"ElectronicProceeding", "proceeding", "CPAPER"},
+	"dissertation/thesis": {"ElectronicThesis", "document", "THES"},
+	"report":              {"ElectronicResourceRemoteAccess", "report", "RPRT"},
+}
+
+// EBSCO source. Packages maps database short names, like "a9h", to
+// collection names. Databases without an entry get a collection named after
+// the database, like "EBSCO (Academic Search Complete)".
+type EBSCO struct {
+	Packages map[string]string
+}
+
+// ReadPackages reads a JSON object, that maps database short names to
+// collection names.
+func ReadPackages(r io.Reader) (map[string]string, error) {
+	packages := make(map[string]string)
+	if err := json.NewDecoder(r).Decode(&packages); err != nil {
+		return nil, err
+	}
+	return packages, nil
+}
+
+// Record is a single rec element.
+type Record struct {
+	XMLName xml.Name `xml:"rec"`
+	Header  struct {
+		ShortDbName string `xml:"shortDbName,attr"`
+		LongDbName  string `xml:"longDbName,attr"`
+		UITerm      string `xml:"uiTerm,attr"`
+		ControlInfo struct {
+			BookInfo struct {
+				Title string   `xml:"btl"`
+				ISBN  []string `xml:"isbn"`
+			} `xml:"bkinfo"`
+			JournalInfo struct {
+				Title string   `xml:"jtl"`
+				ISSN  []string `xml:"issn"`
+			} `xml:"jinfo"`
+			PubInfo struct {
+				Date struct {
+					Year  string `xml:"year,attr"`
+					Month string `xml:"month,attr"`
+					Day   string `xml:"day,attr"`
+				} `xml:"dt"`
+				Volume    string `xml:"vid"`
+				Issue     string `xml:"iid"`
+				Publisher string `xml:"pub"`
+			} `xml:"pubinfo"`
+			ArtInfo struct {
+				IDs []struct {
+					Type  string `xml:"type,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"ui"`
+				Title     string   `xml:"tig>atl"`
+				Authors   []string `xml:"aug>au"`
+				Subjects  []string `xml:"su"`
+				Abstract  string   `xml:"ab"`
+				PubType   string   `xml:"pubtype"`
+				DocType   string   `xml:"doctype"`
+				FirstPage string   `xml:"ppf"`
+				PageCount string   `xml:"ppct"`
+			} `xml:"artinfo"`
+			Language string `xml:"language"`
+		} `xml:"controlInfo"`
+	} `xml:"header"`
+	PLink string `xml:"displayInfo>pLink>url"`
+
+	packages map[string]string
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(records []*Record) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			return s.(span.Importer), nil
+		}, Items: make([]interface{}, len(records))}
+	for i, record := range records {
+		batch.Items[i] = record
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via XML decoding.
+func (s EBSCO) Iterate(r io.Reader) (<-chan interface{}, error) {
+	ch := make(chan interface{})
+	var records []*Record
+	go func() {
+		decoder := xml.NewDecoder(bufio.NewReader(r))
+		for {
+			t, err := decoder.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+			if se, ok := t.(xml.StartElement); ok && se.Name.Local == "rec" {
+				record := &Record{packages: s.Packages}
+				if err := decoder.DecodeElement(record, &se); err != nil {
+					log.Fatal(err)
+				}
+				records = append(records, record)
+				if len(records) == BatchSize {
+					ch <- NewBatch(records)
+					records = nil
+				}
+			}
+		}
+		ch <- NewBatch(records)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Collection returns the collection name of the database of a record.
+func (record *Record) Collection() string {
+	h := record.Header
+	if name, ok := record.packages[h.ShortDbName]; ok {
+		return name
+	}
+	if h.LongDbName != "" {
+		return fmt.Sprintf("%s (%s)", Collection, h.LongDbName)
+	}
+	return fmt.Sprintf("%s (%s)", Collection, h.ShortDbName)
+}
+
+// Date returns the publication date, as precise as given.
+func (record *Record) Date() (time.Time, error) {
+	d := record.Header.ControlInfo.PubInfo.Date
+	if d.Year == "" {
+		return time.Time{}, fmt.Errorf("ebsco: %s: no usable date", record.Header.UITerm)
+	}
+	if d.Month == "" || d.Month == "00" {
+		return time.Parse("2006", d.Year)
+	}
+	if d.Day == "" || d.Day == "00" {
+		return time.Parse("2006-01", d.Year+"-"+d.Month)
+	}
+	return time.Parse("2006-01-02", d.Year+"-"+d.Month+"-"+d.Day)
+}
+
+// DOI returns the DOI of the article.
+func (record *Record) DOI() string {
+	for _, id := range record.Header.ControlInfo.ArtInfo.IDs {
+		if id.Type == "doi" {
+			return strings.TrimSpace(id.Value)
+		}
+	}
+	return ""
+}
+
+// types returns format, genre and RIS type, by document type, then by
+// publication type.
+func (record *Record) types() [3]string {
+	a := record.Header.ControlInfo.ArtInfo
+	if t, ok := docTypes[strings.ToLower(strings.TrimSpace(a.DocType))]; ok {
+		return t
+	}
+	if t, ok := pubTypes[strings.ToLower(strings.TrimSpace(a.PubType))]; ok {
+		return t
+	}
+	return [3]string{"ElectronicArticle", "article", "JOUR"}
+}
+
+// ToIntermediateSchema converts a record. Records without accession number,
+// title or date are skipped.
+func (record *Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	h := record.Header
+	info := h.ControlInfo
+	if h.UITerm == "" || strings.TrimSpace(info.ArtInfo.Title) == "" {
+		return output, span.Skip{Reason: fmt.Sprintf("ebsco: %s: missing accession number or title", h.ShortDbName)}
+	}
+	output.Date, err = record.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+
+	id := fmt.Sprintf("%s__%s", h.ShortDbName, h.UITerm)
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(id)))
+	output.SourceID = SourceID
+	output.RecordID = strings.TrimRight(enc, "=")
+	output.MegaCollection = record.Collection()
+	output.Database = h.LongDbName
+	kind := record.types()
+	output.Format, output.Genre, output.RefType = kind[0], kind[1], kind[2]
+
+	output.ArticleTitle = strings.TrimSpace(info.ArtInfo.Title)
+	for _, name := range info.ArtInfo.Authors {
+		if parts := strings.SplitN(name, ",", 2); len(parts) == 2 {
+			output.Authors = append(output.Authors, finc.Author{LastName: strings.TrimSpace(parts[0]), FirstName: strings.TrimSpace(parts[1])})
+		} else if name = strings.TrimSpace(name); name != "" {
+			output.Authors = append(output.Authors, finc.Author{Name: name})
+		}
+	}
+	output.DOI = record.DOI()
+	output.Abstract = strings.TrimSpace(info.ArtInfo.Abstract)
+	for _, s := range info.ArtInfo.Subjects {
+		if s = strings.TrimSpace(s); s != "" {
+			output.Subjects = append(output.Subjects, s)
+		}
+	}
+	if info.Language != "" {
+		// Languages are given by name, like "English".
+		lang := span.NormalizeLanguage(info.Language)
+		if lang == "und" {
+			lang = doaj.LanguageMap.LookupDefault(strings.TrimSpace(info.Language), "und")
+		}
+		output.Languages = append(output.Languages, lang)
+	}
+	if info.PubInfo.Publisher != "" {
+		output.Publishers = append(output.Publishers, info.PubInfo.Publisher)
+	}
+	if record.PLink != "" {
+		output.URL = append(output.URL, record.PLink)
+	}
+
+	switch output.Genre {
+	case "book", "bookitem", "proceeding":
+		output.BookTitle = strings.TrimSpace(info.BookInfo.Title)
+		if output.BookTitle == "" {
+			output.BookTitle = strings.TrimSpace(info.JournalInfo.Title)
+		}
+	default:
+		output.JournalTitle = strings.TrimSpace(info.JournalInfo.Title)
+	}
+	for _, v := range info.JournalInfo.ISSN {
+		if issn := span.NormalizeISSN(v); issn != "" {
+			output.ISSN = append(output.ISSN, issn)
+		}
+	}
+	for _, v := range info.BookInfo.ISBN {
+		if v = strings.TrimSpace(v); v != "" {
+			output.ISBN = append(output.ISBN, v)
+		}
+	}
+	output.Volume = info.PubInfo.Volume
+	output.Issue = info.PubInfo.Issue
+	output.StartPage = info.ArtInfo.FirstPage
+	output.PageCount = info.ArtInfo.PageCount
+	first, err := strconv.Atoi(output.StartPage)
+	if n, cerr := strconv.Atoi(output.PageCount); err == nil && cerr == nil && n > 0 {
+		output.EndPage = strconv.Itoa(first + n - 1)
+		output.Pages = output.StartPage + "-" + output.EndPage
+	}
+	return output, nil
+}
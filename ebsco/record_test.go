@@ -0,0 +1,102 @@
+package ebsco
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+	"github.com/miku/span/internal/spantest"
+)
+
+const example = `<?xml version="1.0" encoding="utf-8"?>
+<searchResponse>
+  <SearchResults>
+    <records>
+      <rec resultID="1">
+        <header shortDbName="a9h" longDbName="Academic Search Complete" uiTerm="123456">
+          <controlInfo>
+            <jinfo><jtl>Journal of Examples</jtl><issn>12345678</issn></jinfo>
+            <pubinfo><dt year="2018" month="05" day="00">20180500</dt><vid>12</vid><iid>3</iid><pub>Example Press</pub></pubinfo>
+            <artinfo>
+              <ui type="doi">10.1000/ebsco.1</ui>
+              <tig><atl>An Indexed Article</atl></tig>
+              <aug><au>Doe, Jane</au><au>Example Group</au></aug>
+              <su>Cells</su><su>Biology</su>
+              <ab>An abstract.</ab>
+              <pubtype>Academic Journal</pubtype>
+              <doctype>Article</doctype>
+              <ppf>101</ppf><ppct>10</ppct>
+            </artinfo>
+            <language>English</language>
+          </controlInfo>
+        </header>
+        <displayInfo><pLink><url>https://search.ebscohost.com/login.aspx?direct=true&amp;db=a9h&amp;AN=123456</url></pLink></displayInfo>
+      </rec>
+      <rec resultID="2">
+        <header shortDbName="nlebk" longDbName="eBook Collection" uiTerm="777">
+          <controlInfo>
+            <bkinfo><btl>An Example Book</btl><isbn>9780123456786</isbn></bkinfo>
+            <pubinfo><dt year="2015">2015</dt></pubinfo>
+            <artinfo><tig><atl>An Example Book</atl></tig><pubtype>Book</pubtype><doctype>Book</doctype></artinfo>
+          </controlInfo>
+        </header>
+      </rec>
+      <rec resultID="3">
+        <header shortDbName="a9h" longDbName="Academic Search Complete" uiTerm="999">
+          <controlInfo><artinfo><tig><atl>Undated</atl></tig></artinfo></controlInfo>
+        </header>
+      </rec>
+    </records>
+  </SearchResults>
+</searchResponse>
+`
+
+func TestEBSCO(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, EBSCO{Packages: map[string]string{"nlebk": "EBSCO eBooks"}}, strings.NewReader(example))
+	if len(results) != 3 {
+		t.Fatalf("got %d records, want 3", len(results))
+	}
+	if errs[0] != nil || errs[1] != nil {
+		t.Fatalf("unexpected errors: %v, %v", errs[0], errs[1])
+	}
+	if _, ok := errs[2].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for an undated record", errs[2])
+	}
+	is := results[0]
+	if is.MegaCollection != "EBSCO (Academic Search Complete)" || is.Database != "Academic Search Complete" {
+		t.Errorf("got collection %q, database %q", is.MegaCollection, is.Database)
+	}
+	want := []finc.Author{{LastName: "Doe", FirstName: "Jane"}, {Name: "Example Group"}}
+	if !reflect.DeepEqual(is.Authors, want) {
+		t.Errorf("got authors %v, want %v", is.Authors, want)
+	}
+	if !reflect.DeepEqual(is.ISSN, []string{"1234-5678"}) || is.DOI != "10.1000/ebsco.1" || is.Date.Format("2006-01") != "2018-05" {
+		t.Errorf("got ISSN %v, DOI %s, date %v", is.ISSN, is.DOI, is.Date)
+	}
+	if is.StartPage != "101" || is.EndPage != "110" || is.Volume != "12" || is.Issue != "3" {
+		t.Errorf("got pages %s-%s, volume %s, issue %s", is.StartPage, is.EndPage, is.Volume, is.Issue)
+	}
+	if is.URL[0] != "https://search.ebscohost.com/login.aspx?direct=true&db=a9h&AN=123456" || !reflect.DeepEqual(is.Languages, []string{"eng"}) {
+		t.Errorf("got URL %v, languages %v", is.URL, is.Languages)
+	}
+	book := results[1]
+	if book.MegaCollection != "EBSCO eBooks" || book.Format != "eBook" || book.BookTitle != "An Example Book" || book.ISBN[0] != "9780123456786" {
+		t.Errorf("got collection %q, format %s, book title %q, ISBN %v", book.MegaCollection, book.Format, book.BookTitle, book.ISBN)
+	}
+}
+
+func TestEBSCOCollectionFilter(t *testing.T) {
+	tagger, err := span.LoadISILTagger(strings.NewReader(`{"DE-15": [{"collection": ["EBSCO (Academic Search Complete)"]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, _ := spantest.ConvertAll(t, EBSCO{}, strings.NewReader(example))
+	if isils := tagger.Tags(*results[0]); !reflect.DeepEqual(isils, []string{"DE-15"}) {
+		t.Errorf("got %v, want DE-15", isils)
+	}
+	if isils := tagger.Tags(*results[1]); len(isils) != 0 {
+		t.Errorf("got %v, want no ISIL", isils)
+	}
+}
@@ -0,0 +1,172 @@
+package elsevier
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+)
+
+// Article holds the fields of the Elsevier journal article DTD, that are
+// needed for the intermediate schema. It matches full length articles
+// (article) as well as simple and converted articles, whose head element
+// is named differently.
+type Article struct {
+	Lang string `xml:"lang,attr"`
+	PII  string `xml:"item-info>pii"`
+	DOI  string `xml:"item-info>doi"`
+	Head Head   `xml:"head"`
+	// SimpleHead is used by simple-article.
+	SimpleHead Head `xml:"simple-head"`
+}
+
+// Head is the front matter of an article.
+type Head struct {
+	Title struct {
+		Value string `xml:",innerxml"`
+	} `xml:"title"`
+	Authors []struct {
+		GivenName string `xml:"given-name"`
+		Surname   string `xml:"surname"`
+	} `xml:"author-group>author"`
+	Abstracts []struct {
+		Class string `xml:"class,attr"`
+		Paras []struct {
+			Value string `xml:",innerxml"`
+		} `xml:"abstract-sec>simple-para"`
+	} `xml:"abstract"`
+	Keywords []string `xml:"keywords>keyword>text"`
+}
+
+// head returns the front matter, regardless of the article type.
+func (a Article) head() Head {
+	if a.Head.Title.Value != "" {
+		return a.Head
+	}
+	return a.SimpleHead
+}
+
+// Abstract returns the author abstract, or the last abstract, with markup
+// removed.
+func (h Head) Abstract() string {
+	var paras []string
+	for i, abstract := range h.Abstracts {
+		if abstract.Class != "author" && i != len(h.Abstracts)-1 {
+			continue
+		}
+		for _, p := range abstract.Paras {
+			paras = append(paras, stripTags(p.Value))
+		}
+		break
+	}
+	return strings.Join(paras, "\n\n")
+}
+
+// stripTags removes inline markup, like ce:italic, from a fragment.
+func stripTags(s string) string {
+	var (
+		buf strings.Builder
+		dec = xml.NewDecoder(strings.NewReader("<x>" + s + "</x>"))
+	)
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			break
+		}
+		if cd, ok := t.(xml.CharData); ok {
+			buf.Write(cd)
+		}
+	}
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+// Date returns the cover date of the issue, or the online publication date
+// of the item.
+func (doc *Document) Date() (time.Time, error) {
+	if t, err := time.Parse("20060102", doc.Issue.CoverDate); err == nil {
+		return t, nil
+	}
+	if s := doc.Item.Properties.OnlinePublicationDate; len(s) >= 10 {
+		if t, err := time.Parse("2006-01-02", s[:10]); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("elsevier: %s: no cover or publication date", doc.Item.PII)
+}
+
+// ToIntermediateSchema converts a journal item. Items without article XML
+// or date are skipped.
+func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	if len(doc.Article) == 0 {
+		return output, span.Skip{Reason: fmt.Sprintf("elsevier: %s: missing article XML", doc.Item.PII)}
+	}
+	var article Article
+	if err := xml.Unmarshal(doc.Article, &article); err != nil {
+		return output, fmt.Errorf("elsevier: %s: %v", doc.Item.PII, err)
+	}
+	output.Date, err = doc.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+
+	pii := doc.Item.PII
+	if pii == "" {
+		pii = article.PII
+	}
+	if pii == "" {
+		return output, span.Skip{Reason: "elsevier: item without PII"}
+	}
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(pii)))
+	output.SourceID = SourceID
+	output.RecordID = strings.TrimRight(enc, "=")
+	output.MegaCollection = Collection
+	output.Format = Format
+	output.Genre = "article"
+	output.RefType = "JOUR"
+
+	output.DOI = doc.Item.DOI
+	if output.DOI == "" {
+		output.DOI = article.DOI
+	}
+	output.URL = append(output.URL, "https://www.sciencedirect.com/science/article/pii/"+pii)
+	if output.DOI != "" {
+		output.URL = append(output.URL, "https://doi.org/"+output.DOI)
+	}
+
+	head := article.head()
+	output.ArticleTitle = stripTags(head.Title.Value)
+	for _, a := range head.Authors {
+		output.Authors = append(output.Authors, finc.Author{FirstName: a.GivenName, LastName: a.Surname})
+	}
+	output.Abstract = head.Abstract()
+	output.Subjects = head.Keywords
+	if article.Lang != "" {
+		output.Languages = []string{span.NormalizeLanguage(article.Lang)}
+	}
+
+	output.JournalTitle = doc.Item.Properties.CollectionTitle
+	if output.JournalTitle == "" {
+		output.JournalTitle = doc.Issue.CollectionTitle
+	}
+	issn := doc.Item.ISSN
+	if issn == "" {
+		issn = doc.Issue.ISSN
+	}
+	if len(issn) == 8 {
+		issn = issn[:4] + "-" + issn[4:]
+	}
+	if issn != "" {
+		output.ISSN = []string{issn}
+	}
+	output.Volume = doc.Issue.Volume
+	output.Issue = doc.Issue.Issue
+	output.Publishers = []string{"Elsevier"}
+	return output, nil
+}
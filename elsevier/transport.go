@@ -0,0 +1,239 @@
+// Package elsevier converts Elsevier transport deliveries into the
+// intermediate schema. A delivery is a zip or tar archive, optionally gzip
+// compressed, with a dataset.xml manifest, that lists journal issues and
+// journal items. The metadata of an item is read from its article XML
+// (main.xml), the journal, volume and cover date from its issue.
+//
+// Since the manifest may come after the article files in a tar stream, the
+// XML files of a delivery are kept in memory while it is processed.
+package elsevier
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/miku/span"
+)
+
+const (
+	// SourceID for internal bookkeeping.
+	SourceID = "153"
+	// Collection name.
+	Collection = "Elsevier Journals"
+	// Format for intermediate schema.
+	Format = "ElectronicArticle"
+	// BatchSize number of items per batch.
+	BatchSize = 2000
+	// Manifest is the file name of the dataset manifest.
+	Manifest = "dataset.xml"
+)
+
+// Elsevier source.
+type Elsevier struct{}
+
+// Issue is a journal issue of the manifest.
+type Issue struct {
+	ISSN            string   `xml:"journal-issue-properties>issn"`
+	CollectionTitle string   `xml:"journal-issue-properties>collection-title"`
+	Volume          string   `xml:"journal-issue-properties>volume-issue-number>vol-first"`
+	Issue           string   `xml:"journal-issue-properties>volume-issue-number>iss-first"`
+	CoverDate       string   `xml:"journal-issue-properties>cover-date>cover-date-start"`
+	Files           []string `xml:"files-info>ml>pathname"`
+}
+
+// Item is a journal item of the manifest.
+type Item struct {
+	PII        string `xml:"journal-item-unique-ids>pii"`
+	DOI        string `xml:"journal-item-unique-ids>doi"`
+	ISSN       string `xml:"journal-item-unique-ids>jid-aid>issn"`
+	Properties struct {
+		PIT                   string `xml:"pit"`
+		CollectionTitle       string `xml:"collection-title"`
+		OnlinePublicationDate string `xml:"online-publication-date"`
+	} `xml:"journal-item-properties"`
+	Files []string `xml:"files-info>ml>pathname"`
+}
+
+// Dataset is the manifest of a delivery.
+type Dataset struct {
+	XMLName xml.Name `xml:"dataset"`
+	Issues  []Issue  `xml:"dataset-content>journal-issue"`
+	Items   []Item   `xml:"dataset-content>journal-item"`
+}
+
+// Document is a journal item with its issue and the raw article XML.
+type Document struct {
+	Item  Item
+	Issue Issue
+	// Article is the content of main.xml, empty if it was missing.
+	Article []byte
+}
+
+// dir returns the directory of the main XML file of an item or an issue.
+func dir(files []string) string {
+	for _, f := range files {
+		if path.Ext(f) == ".xml" {
+			return path.Dir(f)
+		}
+	}
+	return ""
+}
+
+// MainFile returns the path of the article XML of an item.
+func (item Item) MainFile() string {
+	for _, f := range item.Files {
+		if path.Base(f) == "main.xml" {
+			return f
+		}
+	}
+	return ""
+}
+
+// readArchive reads the XML files of a zip or tar archive, that may be gzip
+// compressed, into memory.
+func readArchive(r io.Reader) (map[string][]byte, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	files := make(map[string][]byte)
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")):
+		b, err := ioutil.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if path.Ext(f.Name) != ".xml" {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			b, err := ioutil.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+			files[strings.TrimPrefix(f.Name, "./")] = b
+		}
+		return files, nil
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		return readTar(gr, files)
+	default:
+		return readTar(br, files)
+	}
+}
+
+func readTar(r io.Reader, files map[string][]byte) (map[string][]byte, error) {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg || path.Ext(hdr.Name) != ".xml" {
+			continue
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[strings.TrimPrefix(hdr.Name, "./")] = b
+	}
+}
+
+// Documents reads the manifests of a delivery and returns its items. A
+// delivery may contain several manifests, one per top level directory.
+// Files listed in a manifest are relative to the directory of the manifest.
+func Documents(r io.Reader) ([]*Document, error) {
+	files, err := readArchive(r)
+	if err != nil {
+		return nil, err
+	}
+	var manifests []string
+	for name := range files {
+		if path.Base(name) == Manifest {
+			manifests = append(manifests, name)
+		}
+	}
+	if len(manifests) == 0 {
+		return nil, fmt.Errorf("elsevier: no %s in delivery", Manifest)
+	}
+	sort.Strings(manifests)
+	var docs []*Document
+	for _, name := range manifests {
+		var ds Dataset
+		if err := xml.Unmarshal(files[name], &ds); err != nil {
+			return nil, fmt.Errorf("elsevier: %s: %v", name, err)
+		}
+		root := path.Dir(name)
+		issues := make(map[string]Issue)
+		for _, issue := range ds.Issues {
+			issues[dir(issue.Files)] = issue
+		}
+		for _, item := range ds.Items {
+			doc := &Document{Item: item}
+			if main := item.MainFile(); main != "" {
+				doc.Article = files[path.Join(root, main)]
+				doc.Issue = issues[path.Dir(path.Dir(main))]
+			}
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(docs []*Document) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			return s.(span.Importer), nil
+		}, Items: make([]interface{}, len(docs))}
+	for i, doc := range docs {
+		batch.Items[i] = doc
+	}
+	return batch
+}
+
+// Iterate reads a single delivery and emits its items.
+func (s Elsevier) Iterate(r io.Reader) (<-chan interface{}, error) {
+	docs, err := Documents(r)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan interface{})
+	go func() {
+		for len(docs) > BatchSize {
+			ch <- NewBatch(docs[:BatchSize])
+			docs = docs[BatchSize:]
+		}
+		ch <- NewBatch(docs)
+		close(ch)
+	}()
+	return ch, nil
+}
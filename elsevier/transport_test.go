@@ -0,0 +1,137 @@
+package elsevier
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/internal/spantest"
+)
+
+var delivery = map[string]string{
+	"OXM10160/dataset.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<dataset>
+<dataset-content>
+	<journal-issue>
+		<journal-issue-properties>
+			<issn>00014575</issn>
+			<collection-title>Accident Analysis &amp; Prevention</collection-title>
+			<volume-issue-number><vol-first>79</vol-first><iss-first>2</iss-first></volume-issue-number>
+			<cover-date><cover-date-start>20150601</cover-date-start></cover-date>
+		</journal-issue-properties>
+		<files-info><ml><pathname>00014575/v79i2/issue.xml</pathname></ml></files-info>
+	</journal-issue>
+	<journal-item>
+		<journal-item-unique-ids>
+			<pii>S0001457515000354</pii>
+			<doi>10.1016/j.aap.2015.01.001</doi>
+			<jid-aid><issn>00014575</issn></jid-aid>
+		</journal-item-unique-ids>
+		<journal-item-properties><pit>FLA</pit><collection-title>Accident Analysis &amp; Prevention</collection-title></journal-item-properties>
+		<files-info><ml><pathname>00014575/v79i2/S0001457515000354/main.xml</pathname></ml></files-info>
+	</journal-item>
+	<journal-item>
+		<journal-item-unique-ids><pii>S0001457515000366</pii></journal-item-unique-ids>
+		<files-info><ml><pathname>00014575/v79i2/S0001457515000366/main.xml</pathname></ml></files-info>
+	</journal-item>
+</dataset-content>
+</dataset>`,
+	"OXM10160/00014575/v79i2/issue.xml": `<issue/>`,
+	"OXM10160/00014575/v79i2/S0001457515000354/main.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<article xmlns="http://www.elsevier.com/xml/ja/dtd" xmlns:ce="http://www.elsevier.com/xml/common/dtd" xml:lang="en">
+<item-info><ce:pii>S0001-4575(15)00035-4</ce:pii><ce:doi>10.1016/j.aap.2015.01.001</ce:doi></item-info>
+<head>
+	<ce:title>Road safety of <ce:italic>examples</ce:italic></ce:title>
+	<ce:author-group>
+		<ce:author><ce:given-name>Jane</ce:given-name><ce:surname>Doe</ce:surname></ce:author>
+	</ce:author-group>
+	<ce:abstract class="graphical"><ce:abstract-sec><ce:simple-para>Graphical.</ce:simple-para></ce:abstract-sec></ce:abstract>
+	<ce:abstract class="author"><ce:abstract-sec><ce:simple-para>An <ce:bold>abstract</ce:bold>.</ce:simple-para></ce:abstract-sec></ce:abstract>
+	<ce:keywords><ce:keyword><ce:text>safety</ce:text></ce:keyword></ce:keywords>
+</head>
+</article>`,
+}
+
+func zipDelivery(t *testing.T) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range delivery {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarGzDelivery(t *testing.T) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range delivery {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestElsevier(t *testing.T) {
+	var cases = []struct {
+		about string
+		data  []byte
+	}{
+		{"zip", zipDelivery(t)},
+		{"tar.gz", tarGzDelivery(t)},
+	}
+	for _, c := range cases {
+		results, errs := spantest.ConvertAll(t, Elsevier{}, bytes.NewReader(c.data))
+		if len(results) != 2 {
+			t.Fatalf("%s: got %d items, want 2", c.about, len(results))
+		}
+		if errs[0] != nil {
+			t.Fatalf("%s: %v", c.about, errs[0])
+		}
+		if _, ok := errs[1].(span.Skip); !ok {
+			t.Errorf("%s: got %v, want span.Skip for missing article XML", c.about, errs[1])
+		}
+		is := results[0]
+		if is.ArticleTitle != "Road safety of examples" || is.Abstract != "An abstract." {
+			t.Errorf("%s: got title %q, abstract %q", c.about, is.ArticleTitle, is.Abstract)
+		}
+		if is.JournalTitle != "Accident Analysis & Prevention" || is.Volume != "79" || is.Issue != "2" {
+			t.Errorf("%s: got journal %q, volume %q, issue %q", c.about, is.JournalTitle, is.Volume, is.Issue)
+		}
+		if len(is.ISSN) != 1 || is.ISSN[0] != "0001-4575" || is.Date.Format("2006-01-02") != "2015-06-01" {
+			t.Errorf("%s: got ISSN %v, date %v", c.about, is.ISSN, is.Date)
+		}
+		if len(is.Authors) != 1 || is.Authors[0].LastName != "Doe" || len(is.Languages) != 1 || is.Languages[0] != "eng" {
+			t.Errorf("%s: got authors %v, languages %v", c.about, is.Authors, is.Languages)
+		}
+	}
+}
+
+func TestNoManifest(t *testing.T) {
+	if _, err := (Elsevier{}).Iterate(bytes.NewReader(nil)); err == nil {
+		t.Error("expected error for delivery without manifest")
+	}
+}
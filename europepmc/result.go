@@ -0,0 +1,391 @@
+// Package europepmc converts article metadata of the Europe PMC REST API
+// into the intermediate schema. Input are search results in the lite or core
+// result type, either as XML or as JSON. JSON may be API responses or one
+// result per line.
+package europepmc
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/miku/span"
+	"github.com/miku/span/container"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// SourceID for internal bookkeeping.
+	SourceID = "171"
+	// Collection name.
+	Collection = "Europe PMC"
+	// BatchSize number of results per batch.
+	BatchSize = 2000
+)
+
+// EuropePMC source.
+type EuropePMC struct{}
+
+// AuthorID is an identifier of an author, e.g. an ORCID iD.
+type AuthorID struct {
+	Type  string `xml:"type,attr" json:"type"`
+	Value string `xml:",chardata" json:"value"`
+}
+
+// Author is an author of the core result type.
+type Author struct {
+	FullName  string   `xml:"fullName" json:"fullName"`
+	FirstName string   `xml:"firstName" json:"firstName"`
+	LastName  string   `xml:"lastName" json:"lastName"`
+	AuthorID  AuthorID `xml:"authorId" json:"authorId"`
+}
+
+// FullTextURL is a link to the full text.
+type FullTextURL struct {
+	Availability  string `xml:"availability" json:"availability"`
+	DocumentStyle string `xml:"documentStyle" json:"documentStyle"`
+	URL           string `xml:"url" json:"url"`
+}
+
+// Result is a single search result. Lite results carry journal and authors
+// as plain strings, core results have structured journal information,
+// authors, abstract and full text links.
+type Result struct {
+	ID                   string `xml:"id" json:"id"`
+	Source               string `xml:"source" json:"source"`
+	PMID                 string `xml:"pmid" json:"pmid"`
+	PMCID                string `xml:"pmcid" json:"pmcid"`
+	DOI                  string `xml:"doi" json:"doi"`
+	Title                string `xml:"title" json:"title"`
+	AuthorString         string `xml:"authorString" json:"authorString"`
+	JournalTitle         string `xml:"journalTitle" json:"journalTitle"`
+	JournalVolume        string `xml:"journalVolume" json:"journalVolume"`
+	Issue                string `xml:"issue" json:"issue"`
+	JournalISSN          string `xml:"journalIssn" json:"journalIssn"`
+	PubYear              string `xml:"pubYear" json:"pubYear"`
+	PageInfo             string `xml:"pageInfo" json:"pageInfo"`
+	PubType              string `xml:"pubType" json:"pubType"`
+	IsOpenAccess         string `xml:"isOpenAccess" json:"isOpenAccess"`
+	InEPMC               string `xml:"inEPMC" json:"inEPMC"`
+	InPMC                string `xml:"inPMC" json:"inPMC"`
+	FirstPublicationDate string `xml:"firstPublicationDate" json:"firstPublicationDate"`
+	AbstractText         string `xml:"abstractText" json:"abstractText"`
+	Language             string `xml:"language" json:"language"`
+	License              string `xml:"license" json:"license"`
+	AuthorList           struct {
+		Author []Author `xml:"author" json:"author"`
+	} `xml:"authorList" json:"authorList"`
+	JournalInfo struct {
+		Volume  string `xml:"volume" json:"volume"`
+		Issue   string `xml:"issue" json:"issue"`
+		Journal struct {
+			Title string `xml:"title" json:"title"`
+			ISSN  string `xml:"ISSN" json:"issn"`
+			ESSN  string `xml:"ESSN" json:"essn"`
+		} `xml:"journal" json:"journal"`
+	} `xml:"journalInfo" json:"journalInfo"`
+	PubTypeList struct {
+		PubType []string `xml:"pubType" json:"pubType"`
+	} `xml:"pubTypeList" json:"pubTypeList"`
+	KeywordList struct {
+		Keyword []string `xml:"keyword" json:"keyword"`
+	} `xml:"keywordList" json:"keywordList"`
+	FullTextURLList struct {
+		FullTextURL []FullTextURL `xml:"fullTextUrl" json:"fullTextUrl"`
+	} `xml:"fullTextUrlList" json:"fullTextUrlList"`
+}
+
+// decodeXML reads all result elements.
+func decodeXML(r io.Reader, f func(*Result)) error {
+	decoder := xml.NewDecoder(r)
+	for {
+		t, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if se, ok := t.(xml.StartElement); ok && se.Name.Local == "result" {
+			result := new(Result)
+			if err := decoder.DecodeElement(result, &se); err != nil {
+				return err
+			}
+			f(result)
+		}
+	}
+}
+
+// decodeJSON reads a stream of JSON objects, which are either API responses
+// with a result list or single results.
+func decodeJSON(r io.Reader, f func(*Result)) error {
+	dec := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		var page struct {
+			ResultList *struct {
+				Result []*Result `json:"result"`
+			} `json:"resultList"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return err
+		}
+		if page.ResultList != nil {
+			for _, result := range page.ResultList.Result {
+				f(result)
+			}
+			continue
+		}
+		result := new(Result)
+		if err := json.Unmarshal(raw, result); err != nil {
+			return err
+		}
+		f(result)
+	}
+}
+
+// Decode reads results from XML or JSON, detected from the first
+// non-whitespace character, and calls f for each of them.
+func Decode(r io.Reader, f func(*Result)) error {
+	br := bufio.NewReader(r)
+	for {
+		c, _, err := br.ReadRune()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if unicode.IsSpace(c) || c == '\ufeff' {
+			continue
+		}
+		if err := br.UnreadRune(); err != nil {
+			return err
+		}
+		if c == '<' {
+			return decodeXML(br, f)
+		}
+		return decodeJSON(br, f)
+	}
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(results []*Result) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			return s.(span.Importer), nil
+		}, Items: make([]interface{}, len(results))}
+	for i, result := range results {
+		batch.Items[i] = result
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via XML or JSON decoding.
+func (s EuropePMC) Iterate(r io.Reader) (<-chan interface{}, error) {
+	ch := make(chan interface{})
+	var results []*Result
+	go func() {
+		err := Decode(r, func(result *Result) {
+			results = append(results, result)
+			if len(results) == BatchSize {
+				ch <- NewBatch(results)
+				results = nil
+			}
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		ch <- NewBatch(results)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Key returns the Europe PMC identifier, which is unique together with the
+// source, e.g. MED/12345 or PPR/PPR123.
+func (r *Result) Key() string {
+	return r.Source + "/" + r.ID
+}
+
+// Date returns the first publication date, falling back to the year.
+func (r *Result) Date() (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", r.FirstPublicationDate); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006", r.PubYear); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("europepmc: %s: no usable date", r.Key())
+}
+
+// IsOA returns true, if the article is open access.
+func (r *Result) IsOA() bool {
+	return r.IsOpenAccess == "Y"
+}
+
+// PubTypes returns the lowercased publication types.
+func (r *Result) PubTypes() (types []string) {
+	for _, t := range append([]string{r.PubType}, r.PubTypeList.PubType...) {
+		for _, v := range strings.Split(t, ";") {
+			if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+				types = append(types, v)
+			}
+		}
+	}
+	return types
+}
+
+// types returns format, genre and RIS type. Preprints come from the PPR
+// source, books from the NBK source.
+func (r *Result) types() [3]string {
+	if r.Source == "PPR" {
+		return [3]string{"ElectronicPreprint", "preprint", "UNPB"}
+	}
+	for _, t := range r.PubTypes() {
+		switch t {
+		case "preprint":
+			return [3]string{"ElectronicPreprint", "preprint", "UNPB"}
+		case "book":
+			return [3]string{"eBook", "book", "EBOOK"}
+		}
+	}
+	if r.Source == "NBK" {
+		return [3]string{"eBook", "book", "EBOOK"}
+	}
+	return [3]string{"ElectronicArticle", "article", "JOUR"}
+}
+
+// Authors returns the structured authors with ORCID iDs or, for lite
+// results, the names of the author string.
+func (r *Result) Authors() (authors []finc.Author) {
+	for _, a := range r.AuthorList.Author {
+		var orcid string
+		if a.AuthorID.Type == "ORCID" {
+			orcid = strings.TrimSpace(a.AuthorID.Value)
+		}
+		switch {
+		case a.LastName != "":
+			authors = append(authors, finc.Author{LastName: a.LastName, FirstName: a.FirstName, ORCID: orcid})
+		case a.FullName != "":
+			authors = append(authors, finc.Author{Name: a.FullName, ORCID: orcid})
+		}
+	}
+	if len(authors) > 0 {
+		return authors
+	}
+	for _, name := range strings.Split(strings.TrimSuffix(r.AuthorString, "."), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			authors = append(authors, finc.Author{Name: name})
+		}
+	}
+	return authors
+}
+
+// ISSNs returns print and electronic ISSN. The lite journal ISSN field lists
+// all ISSNs, separated by semicolon.
+func (r *Result) ISSNs() (issn, eissn []string) {
+	j := r.JournalInfo.Journal
+	if v := span.NormalizeISSN(j.ISSN); v != "" {
+		issn = append(issn, v)
+	}
+	if v := span.NormalizeISSN(j.ESSN); v != "" {
+		eissn = append(eissn, v)
+	}
+	if len(issn) > 0 || len(eissn) > 0 {
+		return issn, eissn
+	}
+	for _, s := range strings.Split(r.JournalISSN, ";") {
+		if v := span.NormalizeISSN(s); v != "" {
+			issn = append(issn, v)
+		}
+	}
+	return issn, eissn
+}
+
+// Links returns the Europe PMC page, links by PMID and PMCID, and free full
+// text links, without duplicates.
+func (r *Result) Links() (links []string) {
+	seen := container.NewStringSet()
+	add := func(u string) {
+		if u != "" && seen.Add(u) {
+			links = append(links, u)
+		}
+	}
+	add(fmt.Sprintf("https://europepmc.org/article/%s/%s", r.Source, r.ID))
+	if r.PMID != "" {
+		add("https://pubmed.ncbi.nlm.nih.gov/" + r.PMID)
+	}
+	if r.PMCID != "" {
+		add("https://www.ncbi.nlm.nih.gov/pmc/articles/" + r.PMCID)
+	}
+	for _, u := range r.FullTextURLList.FullTextURL {
+		if u.Availability == "Open access" || u.Availability == "Free" {
+			add(u.URL)
+		}
+	}
+	return links
+}
+
+// ToIntermediateSchema converts a result. Results without id, title or date
+// are skipped.
+func (r *Result) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	if r.ID == "" || r.Source == "" || strings.TrimSpace(r.Title) == "" {
+		return output, span.Skip{Reason: fmt.Sprintf("europepmc: %s: missing id or title", r.Key())}
+	}
+	output.Date, err = r.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(r.Key())))
+	output.SourceID = SourceID
+	output.RecordID = strings.TrimRight(enc, "=")
+	output.MegaCollection = Collection
+	kind := r.types()
+	output.Format, output.Genre, output.RefType = kind[0], kind[1], kind[2]
+
+	output.ArticleTitle = strings.TrimRight(strings.TrimSpace(r.Title), ".")
+	output.Authors = r.Authors()
+	output.DOI = strings.TrimSpace(r.DOI)
+	output.Abstract = strings.TrimSpace(r.AbstractText)
+	output.OpenAccess = r.IsOA()
+	output.URL = r.Links()
+	output.Subjects = r.KeywordList.Keyword
+	if r.Language != "" {
+		output.Languages = append(output.Languages, span.NormalizeLanguage(r.Language))
+	}
+
+	output.JournalTitle = r.JournalInfo.Journal.Title
+	if output.JournalTitle == "" {
+		output.JournalTitle = r.JournalTitle
+	}
+	output.ISSN, output.EISSN = r.ISSNs()
+	output.Volume, output.Issue = r.JournalInfo.Volume, r.JournalInfo.Issue
+	if output.Volume == "" {
+		output.Volume = r.JournalVolume
+	}
+	if output.Issue == "" {
+		output.Issue = r.Issue
+	}
+	output.Pages = r.PageInfo
+	if parts := strings.SplitN(r.PageInfo, "-", 2); len(parts) == 2 {
+		output.StartPage, output.EndPage = parts[0], parts[1]
+	}
+	return output, nil
+}
@@ -0,0 +1,114 @@
+package europepmc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+	"github.com/miku/span/internal/spantest"
+)
+
+const exampleXML = `<?xml version="1.0" encoding="UTF-8"?>
+<responseWrapper>
+  <version>6.9</version>
+  <hitCount>2</hitCount>
+  <resultList>
+    <result>
+      <id>12345</id>
+      <source>MED</source>
+      <pmid>12345</pmid>
+      <pmcid>PMC678</pmcid>
+      <doi>10.1000/epmc.1</doi>
+      <title>A core result.</title>
+      <authorList>
+        <author><fullName>Doe J</fullName><firstName>Jane</firstName><lastName>Doe</lastName><authorId type="ORCID">0000-0002-1825-0097</authorId></author>
+        <author><fullName>Example Consortium</fullName></author>
+      </authorList>
+      <journalInfo>
+        <issue>2</issue>
+        <volume>7</volume>
+        <journal><title>Journal of Examples</title><ISSN>1234-5678</ISSN><ESSN>8765-4321</ESSN></journal>
+      </journalInfo>
+      <pubYear>2019</pubYear>
+      <pageInfo>10-20</pageInfo>
+      <abstractText>An abstract.</abstractText>
+      <language>eng</language>
+      <pubTypeList><pubType>research-article</pubType><pubType>Journal Article</pubType></pubTypeList>
+      <keywordList><keyword>Cells</keyword></keywordList>
+      <fullTextUrlList>
+        <fullTextUrl><availability>Open access</availability><documentStyle>pdf</documentStyle><url>https://europepmc.org/articles/PMC678?pdf=render</url></fullTextUrl>
+        <fullTextUrl><availability>Subscription required</availability><documentStyle>doi</documentStyle><url>https://doi.org/10.1000/epmc.1</url></fullTextUrl>
+      </fullTextUrlList>
+      <isOpenAccess>Y</isOpenAccess>
+      <firstPublicationDate>2019-03-04</firstPublicationDate>
+    </result>
+    <result>
+      <id>99</id>
+      <source>MED</source>
+      <pmid>99</pmid>
+    </result>
+  </resultList>
+</responseWrapper>
+`
+
+const exampleJSON = `{"version": "6.9", "hitCount": 1, "resultList": {"result": [{"id": "PPR100", "source": "PPR", "doi": "10.1101/2020.01.01.000001", "title": "A preprint", "authorString": "Doe J, Roe R.", "pubYear": "2020", "pubType": "preprint", "isOpenAccess": "N"}]}}
+{"id": "777", "source": "MED", "pmid": "777", "title": "A lite line", "authorString": "Roe R.", "journalTitle": "Nature", "journalVolume": "500", "issue": "1", "journalIssn": "0028-0836; 1476-4687", "pubYear": "2013", "isOpenAccess": "N"}
+`
+
+func TestEuropePMCXML(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, EuropePMC{}, strings.NewReader(exampleXML))
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if errs[0] != nil {
+		t.Fatalf("unexpected error: %v", errs[0])
+	}
+	if _, ok := errs[1].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for a result without title", errs[1])
+	}
+	is := results[0]
+	if is.ArticleTitle != "A core result" || is.DOI != "10.1000/epmc.1" || !is.OpenAccess || is.Format != "ElectronicArticle" {
+		t.Errorf("got title %q, DOI %s, open access %v, format %s", is.ArticleTitle, is.DOI, is.OpenAccess, is.Format)
+	}
+	want := []finc.Author{{LastName: "Doe", FirstName: "Jane", ORCID: "0000-0002-1825-0097"}, {Name: "Example Consortium"}}
+	if !reflect.DeepEqual(is.Authors, want) {
+		t.Errorf("got authors %v, want %v", is.Authors, want)
+	}
+	if !reflect.DeepEqual(is.ISSN, []string{"1234-5678"}) || !reflect.DeepEqual(is.EISSN, []string{"8765-4321"}) {
+		t.Errorf("got ISSN %v, EISSN %v", is.ISSN, is.EISSN)
+	}
+	if is.Volume != "7" || is.Issue != "2" || is.StartPage != "10" || is.Date.Format("2006-01-02") != "2019-03-04" {
+		t.Errorf("got volume %s, issue %s, start page %s, date %v", is.Volume, is.Issue, is.StartPage, is.Date)
+	}
+	urls := []string{
+		"https://europepmc.org/article/MED/12345",
+		"https://pubmed.ncbi.nlm.nih.gov/12345",
+		"https://www.ncbi.nlm.nih.gov/pmc/articles/PMC678",
+		"https://europepmc.org/articles/PMC678?pdf=render",
+	}
+	if !reflect.DeepEqual(is.URL, urls) {
+		t.Errorf("got URL %v, want %v", is.URL, urls)
+	}
+}
+
+func TestEuropePMCJSON(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, EuropePMC{}, strings.NewReader(exampleJSON))
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	preprint := results[0]
+	if preprint.Format != "ElectronicPreprint" || preprint.OpenAccess || len(preprint.Authors) != 2 || preprint.Authors[1].Name != "Roe R" {
+		t.Errorf("got format %s, open access %v, authors %v", preprint.Format, preprint.OpenAccess, preprint.Authors)
+	}
+	lite := results[1]
+	if lite.JournalTitle != "Nature" || lite.Volume != "500" || !reflect.DeepEqual(lite.ISSN, []string{"0028-0836", "1476-4687"}) {
+		t.Errorf("got journal %q, volume %s, ISSN %v", lite.JournalTitle, lite.Volume, lite.ISSN)
+	}
+}
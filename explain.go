@@ -0,0 +1,161 @@
+package span
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miku/span/finc"
+)
+
+// Explainer is implemented by filters, that can tell, why they did not match
+// a record.
+type Explainer interface {
+	// Explain returns the reason, why the filter does not match the record,
+	// or the empty string, if it matches.
+	Explain(is finc.IntermediateSchema) string
+}
+
+// explain returns the reason, why a filter does not match a record, with a
+// generic reason for filters, that are no Explainers.
+func explain(f Filter, is finc.IntermediateSchema) string {
+	if e, ok := f.(Explainer); ok {
+		return e.Explain(is)
+	}
+	if f.Apply(is) {
+		return ""
+	}
+	return fmt.Sprintf("%T did not match", f)
+}
+
+// Explain runs every filter of an ISIL and reports the result and, for
+// filters, that did not match, the reason.
+func (t ISILTagger) Explain(is finc.IntermediateSchema, isil string) []FilterTrace {
+	var traces []FilterTrace
+	for _, f := range t[isil] {
+		reason := explain(f, is)
+		traces = append(traces, FilterTrace{
+			Filter: fmt.Sprintf("%T", f),
+			Result: reason == "",
+			Reason: reason,
+		})
+	}
+	return traces
+}
+
+// Explain filter.
+func (f SourceFilter) Explain(is finc.IntermediateSchema) string {
+	if f.Apply(is) {
+		return ""
+	}
+	return fmt.Sprintf("wrong source: got %s, want %s", is.SourceID, f.SourceID)
+}
+
+// Explain filter.
+func (f NoISSNFilter) Explain(is finc.IntermediateSchema) string {
+	if f.Apply(is) {
+		return ""
+	}
+	return fmt.Sprintf("record has ISSN: %s", strings.Join(is.ISSNList(), ", "))
+}
+
+// Explain returns the reason of the first filter, that did not match.
+func (f AndFilter) Explain(is finc.IntermediateSchema) string {
+	if len(f.Filters) == 0 {
+		return "no filters"
+	}
+	for _, g := range f.Filters {
+		if reason := explain(g, is); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// Explain reports for each ISSN, whether it is missing from the holdings,
+// not covered by a license or behind the moving wall.
+func (f HoldingFilter) Explain(is finc.IntermediateSchema) string {
+	e := f.evaluate(is, true)
+	if e.Covered {
+		return ""
+	}
+	if len(e.ISSNs) == 0 {
+		return "record has no ISSN"
+	}
+	if e.Dropped {
+		return fmt.Sprintf("future-dated record dropped: date=%s, ref=%s",
+			is.Date.Format("2006-01-02"), f.Ref.Format("2006-01-02"))
+	}
+	var reasons []string
+	for _, r := range e.ISSNs {
+		switch r.Coverage {
+		case NotInHoldings:
+			reasons = append(reasons, fmt.Sprintf("ISSN %s not in holdings", r.ISSN))
+		case NotCovered:
+			reasons = append(reasons, fmt.Sprintf("ISSN %s present but not covered: year=%d, volume=%q, issue=%q",
+				r.ISSN, e.Date.Year(), is.Volume, is.Issue))
+		case Walled:
+			var walls []string
+			for _, w := range r.Walls {
+				walls = append(walls, w.Format("2006-01-02"))
+			}
+			reasons = append(reasons, fmt.Sprintf("ISSN %s present but wall not cleared: date=%s, wall=%s, ref=%s",
+				r.ISSN, e.Date.Format("2006-01-02"), strings.Join(walls, ", "), f.Ref.Format("2006-01-02")))
+		}
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// Explain filter.
+func (f ListFilter) Explain(is finc.IntermediateSchema) string {
+	if f.Apply(is) {
+		return ""
+	}
+	issns := f.ISSNSource.ISSNs(is)
+	if len(issns) == 0 {
+		return "record has no ISSN"
+	}
+	return fmt.Sprintf("ISSN not in list: %s", strings.Join(issns, ", "))
+}
+
+// Explain filter.
+func (f ISSNYearFilter) Explain(is finc.IntermediateSchema) string {
+	if f.Apply(is) {
+		return ""
+	}
+	issns := f.ISSNSource.ISSNs(is)
+	if len(issns) == 0 {
+		return "record has no ISSN"
+	}
+	return fmt.Sprintf("ISSN and year %d not listed: %s", is.Date.Year(), strings.Join(issns, ", "))
+}
+
+// Explain filter.
+func (f LanguageFilter) Explain(is finc.IntermediateSchema) string {
+	if f.Apply(is) {
+		return ""
+	}
+	if len(is.Languages) == 0 {
+		return "record has no language"
+	}
+	return fmt.Sprintf("language not allowed: %s", strings.Join(is.Languages, ", "))
+}
+
+// Explain filter.
+func (f RelationFilter) Explain(is finc.IntermediateSchema) string {
+	if f.Apply(is) {
+		return ""
+	}
+	if f.Exclude {
+		return fmt.Sprintf("excluded relation: %s", strings.Join(is.Relations, ", "))
+	}
+	return fmt.Sprintf("no relation of type: %s", strings.Join(f.Types.SortedValues(), ", "))
+}
+
+// Explain filter.
+func (f ValidDuring) Explain(is finc.IntermediateSchema) string {
+	if f.Ref.Before(f.From) || f.Ref.After(f.To) {
+		return fmt.Sprintf("outside of window: ref=%s, window=%s to %s", f.Ref.Format("2006-01-02"),
+			f.From.Format("2006-01-02"), f.To.Format("2006-01-02"))
+	}
+	return explain(f.Inner, is)
+}
@@ -0,0 +1,89 @@
+package span
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/miku/span/finc"
+	"github.com/miku/span/holdings"
+)
+
+func TestISILTaggerExplain(t *testing.T) {
+	holding := testHolding("2000", "", "-1Y")
+	table, errs := holdings.ParseHoldings(strings.NewReader(holding))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	tagger := ISILTagger{"DE-1": []Filter{
+		NewHoldingFilterFromLicenses(table, mustParseDate("2022-06-15")),
+		SourceFilter{SourceID: "49"},
+	}}
+	var tests = []struct {
+		is      finc.IntermediateSchema
+		reasons []string
+	}{
+		{
+			finc.IntermediateSchema{SourceID: "28", ISSN: []string{"1234-5678"}, Date: mustParseDate("2022-01-01")},
+			[]string{"ISSN 1234-5678 present but wall not cleared: date=2022-01-01, wall=2021-06-15, ref=2022-06-15",
+				"wrong source: got 28, want 49"},
+		},
+		{
+			finc.IntermediateSchema{SourceID: "49", ISSN: []string{"2345-6789"}, Date: mustParseDate("2010-01-01")},
+			[]string{"ISSN 2345-6789 not in holdings", ""},
+		},
+		{
+			finc.IntermediateSchema{SourceID: "49", ISSN: []string{"1234-5678"}, Date: mustParseDate("2010-01-01")},
+			[]string{"", ""},
+		},
+	}
+	for _, tt := range tests {
+		traces := tagger.Explain(tt.is, "DE-1")
+		if len(traces) != len(tt.reasons) {
+			t.Fatalf("ISILTagger.Explain: got %d traces, want %d", len(traces), len(tt.reasons))
+		}
+		for i, trace := range traces {
+			if trace.Reason != tt.reasons[i] || trace.Result != (tt.reasons[i] == "") {
+				t.Errorf("ISILTagger.Explain: got %s, want reason %q", fmt.Sprintf("%+v", trace), tt.reasons[i])
+			}
+		}
+	}
+	if traces := tagger.Explain(finc.IntermediateSchema{}, "DE-2"); len(traces) != 0 {
+		t.Errorf("ISILTagger.Explain: got %v for unknown ISIL, want no traces", traces)
+	}
+}
+
+func TestHoldingFilterExplainPolicies(t *testing.T) {
+	table := holdings.Licenses{
+		"1234-5678": []holdings.License{"2000000000000000:ZZZZZZZZZZZZZZZZ:-1Y"},
+		"2345-6789": []holdings.License{"2000000000000000:ZZZZZZZZZZZZZZZZ:-1Y:free"},
+	}
+	ref := mustParseDate("2022-06-15")
+	var tests = []struct {
+		f      HoldingFilter
+		is     finc.IntermediateSchema
+		reason string
+	}{
+		{HoldingFilter{Ref: ref, Table: table, FreeIgnoresWall: true},
+			finc.IntermediateSchema{ISSN: []string{"2345-6789"}, Date: mustParseDate("2022-01-01")}, ""},
+		{HoldingFilter{Ref: ref, Table: table},
+			finc.IntermediateSchema{ISSN: []string{"2345-6789"}, Date: mustParseDate("2022-01-01")},
+			"ISSN 2345-6789 present but wall not cleared: date=2022-01-01, wall=2021-06-15, ref=2022-06-15"},
+		{HoldingFilter{Ref: ref, Table: table, FuturePolicy: FutureAttach},
+			finc.IntermediateSchema{ISSN: []string{"1234-5678"}, Date: mustParseDate("2023-01-01")}, ""},
+		{HoldingFilter{Ref: ref, Table: table, FuturePolicy: FutureDrop},
+			finc.IntermediateSchema{ISSN: []string{"1234-5678"}, Date: mustParseDate("2023-01-01")},
+			"future-dated record dropped: date=2023-01-01, ref=2022-06-15"},
+		{HoldingFilter{Ref: ref, Table: table, FuturePolicy: FutureClamp},
+			finc.IntermediateSchema{ISSN: []string{"1234-5678"}, Date: mustParseDate("2023-01-01")},
+			"ISSN 1234-5678 present but wall not cleared: date=2022-06-15, wall=2021-06-15, ref=2022-06-15"},
+	}
+	for _, tt := range tests {
+		if reason := tt.f.Explain(tt.is); reason != tt.reason {
+			t.Errorf("HoldingFilter.Explain: got %q, want %q", reason, tt.reason)
+		}
+		if r := tt.f.Apply(tt.is); r != (tt.reason == "") {
+			t.Errorf("HoldingFilter.Apply: got %v, want %v", r, tt.reason == "")
+		}
+	}
+}
@@ -0,0 +1,280 @@
+// Package fatcat converts release entities of the Fatcat catalog, as found
+// in the bulk release exports, one release per line, into the intermediate
+// schema.
+package fatcat
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/container"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// SourceID for internal bookkeeping.
+	SourceID = "168"
+	// Collection name.
+	Collection = "Fatcat"
+	// BatchSize for grouped channel transport.
+	BatchSize = 10000
+)
+
+// releaseTypes maps release types, which follow CSL, to format, genre and
+// RIS type.
+var releaseTypes = map[string][3]string{
+	"article-journal":  {"ElectronicArticle", "article", "JOUR"},
+	"article-magazine": {"ElectronicArticle", "article", "MGZN"},
+	"article":          {"ElectronicArticle", "article", "JOUR"},
+	"book":             {"eBook", "book", "EBOOK"},
+	"chapter":          {"ElectronicBookPart", "bookitem", "ECHAP"},
+	"paper-conference": {"ElectronicProceeding", "proceeding", "CPAPER"},
+	"thesis":           {"ElectronicThesis", "document", "THES"},
+	"report":           {"ElectronicResourceRemoteAccess", "report", "RPRT"},
+	"dataset":          {"ElectronicResourceRemoteAccess", "document", "DATA"},
+}
+
+// preprintStages are release stages of works, that are not published yet.
+var preprintStages = map[string]bool{
+	"draft":     true,
+	"submitted": true,
+}
+
+// Fatcat source.
+type Fatcat struct{}
+
+// ExtIDs are the external identifiers of a release.
+type ExtIDs struct {
+	DOI         string `json:"doi"`
+	PMID        string `json:"pmid"`
+	PMCID       string `json:"pmcid"`
+	WikidataQID string `json:"wikidata_qid"`
+	ArXiv       string `json:"arxiv"`
+	JSTOR       string `json:"jstor"`
+	ISBN13      string `json:"isbn13"`
+	HDL         string `json:"hdl"`
+	CORE        string `json:"core"`
+}
+
+// Container is the journal, conference or book series of a release.
+type Container struct {
+	Ident         string `json:"ident"`
+	Name          string `json:"name"`
+	ISSNL         string `json:"issnl"`
+	ISSNE         string `json:"issne"`
+	ISSNP         string `json:"issnp"`
+	Publisher     string `json:"publisher"`
+	ContainerType string `json:"container_type"`
+}
+
+// Release is a single release entity.
+type Release struct {
+	Ident           string     `json:"ident"`
+	State           string     `json:"state"`
+	Title           string     `json:"title"`
+	Subtitle        string     `json:"subtitle"`
+	ReleaseType     string     `json:"release_type"`
+	ReleaseStage    string     `json:"release_stage"`
+	WithdrawnStatus string     `json:"withdrawn_status"`
+	ReleaseDate     string     `json:"release_date"`
+	ReleaseYear     int        `json:"release_year"`
+	ExtIDs          ExtIDs     `json:"ext_ids"`
+	Volume          string     `json:"volume"`
+	Issue           string     `json:"issue"`
+	Pages           string     `json:"pages"`
+	Publisher       string     `json:"publisher"`
+	Language        string     `json:"language"`
+	Container       *Container `json:"container"`
+	Contribs        []struct {
+		RawName   string `json:"raw_name"`
+		GivenName string `json:"given_name"`
+		Surname   string `json:"surname"`
+		Role      string `json:"role"`
+	} `json:"contribs"`
+	Abstracts []struct {
+		Content  string `json:"content"`
+		Mimetype string `json:"mimetype"`
+		Lang     string `json:"lang"`
+	} `json:"abstracts"`
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(lines []string) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			release := new(Release)
+			err := json.Unmarshal([]byte(s.(string)), release)
+			return release, err
+		}, Items: make([]interface{}, len(lines))}
+	for i, line := range lines {
+		batch.Items[i] = line
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via JSON decoding.
+func (s Fatcat) Iterate(r io.Reader) (<-chan interface{}, error) {
+	ch := make(chan interface{})
+	reader := bufio.NewReader(r)
+	var lines []string
+	go func() {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil && err != io.EOF {
+				log.Fatal(err)
+			}
+			if strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+			if len(lines) == BatchSize {
+				ch <- NewBatch(lines)
+				lines = nil
+			}
+			if err == io.EOF {
+				break
+			}
+		}
+		ch <- NewBatch(lines)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Date returns the release date, or the first day of the release year.
+func (r *Release) Date() (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", r.ReleaseDate); err == nil {
+		return t, nil
+	}
+	if r.ReleaseYear > 0 {
+		return time.Date(r.ReleaseYear, time.January, 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Time{}, fmt.Errorf("fatcat: %s: no usable date", r.Ident)
+}
+
+// ISSNs returns the linking ISSN first, followed by print and electronic
+// ISSN of the container, without duplicates.
+func (r *Release) ISSNs() (issns []string) {
+	if r.Container == nil {
+		return nil
+	}
+	seen := container.NewStringSet()
+	for _, v := range []string{r.Container.ISSNL, r.Container.ISSNP, r.Container.ISSNE} {
+		if v != "" && seen.Add(v) {
+			issns = append(issns, v)
+		}
+	}
+	return issns
+}
+
+// Links returns the Fatcat page and links derived from external identifiers.
+func (r *Release) Links() []string {
+	links := []string{"https://fatcat.wiki/release/" + r.Ident}
+	x := r.ExtIDs
+	for _, v := range [][2]string{
+		{"https://arxiv.org/abs/", x.ArXiv},
+		{"https://pubmed.ncbi.nlm.nih.gov/", x.PMID},
+		{"https://www.ncbi.nlm.nih.gov/pmc/articles/", x.PMCID},
+		{"https://www.jstor.org/stable/", x.JSTOR},
+		{"https://hdl.handle.net/", x.HDL},
+		{"https://www.wikidata.org/wiki/", x.WikidataQID},
+	} {
+		if v[1] != "" {
+			links = append(links, v[0]+v[1])
+		}
+	}
+	return links
+}
+
+// types returns format, genre and RIS type. Drafts and submitted versions
+// are preprints, regardless of the release type.
+func (r *Release) types() [3]string {
+	if preprintStages[r.ReleaseStage] {
+		return [3]string{"ElectronicPreprint", "preprint", "UNPB"}
+	}
+	if t, ok := releaseTypes[r.ReleaseType]; ok {
+		return t
+	}
+	return [3]string{"ElectronicResourceRemoteAccess", "unknown", "GEN"}
+}
+
+// ToIntermediateSchema converts a release. Releases, that are not active,
+// withdrawn, retraction notices or undated, are skipped.
+func (r *Release) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	if r.State != "" && r.State != "active" {
+		return output, span.Skip{Reason: fmt.Sprintf("fatcat: %s: state %s", r.Ident, r.State)}
+	}
+	if r.WithdrawnStatus != "" || r.ReleaseStage == "retraction" {
+		return output, span.Skip{Reason: fmt.Sprintf("fatcat: %s: withdrawn or retraction", r.Ident)}
+	}
+	output.Date, err = r.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(r.Ident)))
+	output.SourceID = SourceID
+	output.RecordID = strings.TrimRight(enc, "=")
+	output.MegaCollection = Collection
+	types := r.types()
+	output.Format, output.Genre, output.RefType = types[0], types[1], types[2]
+	output.Type = r.ReleaseType
+
+	output.DOI = r.ExtIDs.DOI
+	output.ArticleTitle = strings.TrimSpace(r.Title)
+	output.ArticleSubtitle = strings.TrimSpace(r.Subtitle)
+	for _, c := range r.Contribs {
+		if c.Role != "" && c.Role != "author" {
+			continue
+		}
+		switch {
+		case c.Surname != "":
+			output.Authors = append(output.Authors, finc.Author{LastName: c.Surname, FirstName: c.GivenName})
+		case c.RawName != "":
+			output.Authors = append(output.Authors, finc.Author{Name: c.RawName})
+		}
+	}
+	for _, a := range r.Abstracts {
+		if a.Mimetype == "text/plain" || output.Abstract == "" {
+			output.Abstract = strings.TrimSpace(a.Content)
+		}
+	}
+	if r.ExtIDs.ISBN13 != "" {
+		output.ISBN = append(output.ISBN, r.ExtIDs.ISBN13)
+	}
+	output.URL = r.Links()
+	output.Volume, output.Issue, output.Pages = r.Volume, r.Issue, r.Pages
+	if parts := strings.SplitN(r.Pages, "-", 2); len(parts) == 2 {
+		output.StartPage, output.EndPage = parts[0], parts[1]
+	}
+	if r.Language != "" {
+		output.Languages = append(output.Languages, span.NormalizeLanguage(r.Language))
+	}
+
+	publisher := r.Publisher
+	if c := r.Container; c != nil {
+		output.ISSN = r.ISSNs()
+		switch output.Genre {
+		case "bookitem", "proceeding":
+			output.BookTitle = c.Name
+		default:
+			output.JournalTitle = c.Name
+		}
+		if publisher == "" {
+			publisher = c.Publisher
+		}
+	}
+	if publisher != "" {
+		output.Publishers = append(output.Publishers, publisher)
+	}
+	return output, nil
+}
@@ -0,0 +1,62 @@
+package fatcat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+	"github.com/miku/span/internal/spantest"
+)
+
+const example = `{"ident": "aaaaaaaaaaaaarceaaaaaaaaai", "state": "active", "title": "A Release", "release_type": "article-journal", "release_stage": "published", "release_date": "2019-04-02", "release_year": 2019, "ext_ids": {"doi": "10.1000/fatcat.1", "pmid": "123", "wikidata_qid": "Q42"}, "volume": "3", "issue": "1", "pages": "10-20", "language": "en", "container": {"name": "Journal of Examples", "issnl": "1234-5678", "issnp": "1234-5678", "issne": "8765-4321", "publisher": "Example Press"}, "contribs": [{"given_name": "Jane", "surname": "Doe", "role": "author"}, {"raw_name": "R. Roe"}, {"raw_name": "E. Editor", "role": "editor"}], "abstracts": [{"content": "<p>Markup</p>", "mimetype": "application/xml+jats"}, {"content": "Plain.", "mimetype": "text/plain"}]}
+
+{"ident": "aaaaaaaaaaaaarceaaaaaaaaam", "state": "active", "title": "Draft", "release_type": "article-journal", "release_stage": "submitted", "release_year": 2020, "ext_ids": {"arxiv": "2001.00001v1"}}
+{"ident": "aaaaaaaaaaaaarceaaaaaaaaaq", "state": "active", "title": "Gone", "release_year": 2018, "withdrawn_status": "retracted", "ext_ids": {}}
+{"ident": "aaaaaaaaaaaaarceaaaaaaaaau", "state": "active", "title": "Undated", "ext_ids": {}}
+`
+
+func TestFatcat(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, Fatcat{}, strings.NewReader(example))
+	if len(results) != 4 {
+		t.Fatalf("got %d releases, want 4", len(results))
+	}
+	if errs[0] != nil || errs[1] != nil {
+		t.Fatalf("unexpected errors: %v, %v", errs[0], errs[1])
+	}
+	for _, err := range errs[2:] {
+		if _, ok := err.(span.Skip); !ok {
+			t.Errorf("got %v, want span.Skip", err)
+		}
+	}
+	is := results[0]
+	if !reflect.DeepEqual(is.ISSN, []string{"1234-5678", "8765-4321"}) {
+		t.Errorf("got ISSN %v, want ISSN-L first and no duplicates", is.ISSN)
+	}
+	if is.Format != "ElectronicArticle" || is.Type != "article-journal" || is.DOI != "10.1000/fatcat.1" {
+		t.Errorf("got format %s, type %s, DOI %s", is.Format, is.Type, is.DOI)
+	}
+	want := []finc.Author{{LastName: "Doe", FirstName: "Jane"}, {Name: "R. Roe"}}
+	if !reflect.DeepEqual(is.Authors, want) {
+		t.Errorf("got authors %v, want %v", is.Authors, want)
+	}
+	if is.Abstract != "Plain." || is.JournalTitle != "Journal of Examples" || is.Publishers[0] != "Example Press" {
+		t.Errorf("got abstract %q, journal %q, publishers %v", is.Abstract, is.JournalTitle, is.Publishers)
+	}
+	if is.StartPage != "10" || is.EndPage != "20" || !reflect.DeepEqual(is.Languages, []string{"eng"}) {
+		t.Errorf("got pages %s-%s, languages %v", is.StartPage, is.EndPage, is.Languages)
+	}
+	urls := []string{
+		"https://fatcat.wiki/release/aaaaaaaaaaaaarceaaaaaaaaai",
+		"https://pubmed.ncbi.nlm.nih.gov/123",
+		"https://www.wikidata.org/wiki/Q42",
+	}
+	if !reflect.DeepEqual(is.URL, urls) {
+		t.Errorf("got URL %v, want %v", is.URL, urls)
+	}
+	draft := results[1]
+	if draft.Format != "ElectronicPreprint" || draft.Date.Year() != 2020 || draft.URL[1] != "https://arxiv.org/abs/2001.00001v1" {
+		t.Errorf("got format %s, date %v, URL %v", draft.Format, draft.Date, draft.URL)
+	}
+}
@@ -5,8 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/miku/span/container"
@@ -20,6 +21,85 @@ type Filter interface {
 	Apply(finc.IntermediateSchema) bool
 }
 
+// ISSNSource selects, which ISSNs of a record ISSN based filters consider.
+type ISSNSource int
+
+const (
+	// BothISSN considers print and electronic ISSNs.
+	BothISSN ISSNSource = iota
+	// PrintISSN considers print ISSNs only.
+	PrintISSN
+	// ElectronicISSN considers electronic ISSNs only.
+	ElectronicISSN
+)
+
+// ParseISSNSource parses both, pissn or eissn into an ISSNSource.
+func ParseISSNSource(s string) (ISSNSource, error) {
+	switch s {
+	case "both":
+		return BothISSN, nil
+	case "pissn":
+		return PrintISSN, nil
+	case "eissn":
+		return ElectronicISSN, nil
+	default:
+		return BothISSN, fmt.Errorf("unknown ISSN source: %s", s)
+	}
+}
+
+// FuturePolicy decides, how HoldingFilter treats records with a publication
+// date after the reference date, e.g. ahead-of-print articles or bad metadata.
+type FuturePolicy int
+
+const (
+	// FutureAsIs evaluates the date as is. A future date lies after every
+	// moving wall, so such records only match free entitlements, that ignore
+	// walls.
+	FutureAsIs FuturePolicy = iota
+	// FutureAttach skips the moving wall check for future dates, so a record
+	// matches, if a license covers it.
+	FutureAttach
+	// FutureDrop never matches future-dated records.
+	FutureDrop
+	// FutureClamp evaluates future-dated records as if published on the day
+	// of the reference date.
+	FutureClamp
+)
+
+// ParseFuturePolicy parses as-is, attach, drop or clamp into a FuturePolicy.
+func ParseFuturePolicy(s string) (FuturePolicy, error) {
+	switch s {
+	case "as-is":
+		return FutureAsIs, nil
+	case "attach":
+		return FutureAttach, nil
+	case "drop":
+		return FutureDrop, nil
+	case "clamp":
+		return FutureClamp, nil
+	default:
+		return FutureAsIs, fmt.Errorf("unknown future policy: %s", s)
+	}
+}
+
+// ISSNs returns the ISSNs of a record, that should be considered.
+func (s ISSNSource) ISSNs(is finc.IntermediateSchema) []string {
+	switch s {
+	case PrintISSN:
+		return is.ISSN
+	case ElectronicISSN:
+		return is.EISSN
+	default:
+		return append(is.ISSN, is.EISSN...)
+	}
+}
+
+// Emptier is implemented by filters, that are based on data, that might be
+// missing, e.g. because a list file is empty.
+type Emptier interface {
+	Empty() bool
+}
+
 // Any always returns true.
 type Any struct{}
 
@@ -41,89 +121,430 @@ func (f SourceFilter) MarshalJSON() ([]byte, error) {
 	return json.Marshal(f.SourceID)
 }
 
+// CollectionFilter attaches records of the given collections, e.g. single
+// packages of a source, that sets the package name as collection.
+type CollectionFilter struct {
+	Collections *container.StringSet
+}
+
+// MarshalJSON provides custom serialization.
+func (f CollectionFilter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.Collections.SortedValues())
+}
+
+// Apply filter.
+func (f CollectionFilter) Apply(is finc.IntermediateSchema) bool {
+	return f.Collections.Contains(is.MegaCollection)
+}
+
+// AccessFilter attaches records with one of the given access levels, e.g.
+// only records, that can be viewed from anywhere.
+type AccessFilter struct {
+	Levels *container.StringSet
+}
+
+// MarshalJSON provides custom serialization.
+func (f AccessFilter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.Levels.SortedValues())
+}
+
+// Apply filter.
+func (f AccessFilter) Apply(is finc.IntermediateSchema) bool {
+	return f.Levels.Contains(is.Access)
+}
+
+// NoISSNFilter matches records without any ISSN, which never match ISSN based
+// filters, e.g. book chapters or datasets. Usually combined with other
+// filters in an AndFilter.
+type NoISSNFilter struct{}
+
+// Apply filter.
+func (f NoISSNFilter) Apply(is finc.IntermediateSchema) bool {
+	return len(is.ISSN) == 0 && len(is.EISSN) == 0
+}
+
+// AndFilter matches, if all of its filters match. An AndFilter without
+// filters matches nothing.
+type AndFilter struct {
+	Filters []Filter
+}
+
+// MarshalJSON provides custom serialization. Filters are serialized as
+// filter specs, see filterSpec.
+func (f AndFilter) MarshalJSON() ([]byte, error) {
+	specs := make([]map[string]interface{}, 0, len(f.Filters))
+	for _, g := range f.Filters {
+		spec, err := filterSpec(g)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return json.Marshal(specs)
+}
+
+// Apply filter.
+func (f AndFilter) Apply(is finc.IntermediateSchema) bool {
+	if len(f.Filters) == 0 {
+		return false
+	}
+	for _, g := range f.Filters {
+		if !g.Apply(is) {
+			return false
+		}
+	}
+	return true
+}
+
+// AtLeastFilter matches, if at least N of its children match, e.g. for
+// agreements, that require two out of three criteria.
+type AtLeastFilter struct {
+	N        int
+	Children []Filter
+}
+
+// Apply filter.
+func (f AtLeastFilter) Apply(is finc.IntermediateSchema) bool {
+	if f.N > len(f.Children) {
+		return false
+	}
+	var matched int
+	for i, g := range f.Children {
+		if g.Apply(is) {
+			matched++
+		}
+		if matched >= f.N {
+			return true
+		}
+		// not enough children left to reach N
+		if matched+len(f.Children)-i-1 < f.N {
+			return false
+		}
+	}
+	return matched >= f.N
+}
+
+// MarshalJSON provides custom serialization. Children are serialized as
+// filter specs, see filterSpec.
+func (f AtLeastFilter) MarshalJSON() ([]byte, error) {
+	var specs []map[string]interface{}
+	for _, g := range f.Children {
+		spec, err := filterSpec(g)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return json.Marshal(map[string]interface{}{
+		"n":       f.N,
+		"filters": specs,
+	})
+}
+
 // HoldingFilter decides ISIL-attachment by looking at licensing information
 // from OVID files. Ref is the reference date for moving wall calculations and
-// Table contains a map from ISSNs to licenses.
+// Table contains a map from ISSNs to licenses. If Store is set, the licenses
+// and editions for ISIL are read from the store instead. If FreeIgnoresWall is
+// set, licenses from free entitlements are not subject to moving walls.
+// FuturePolicy applies to records dated after Ref. Editions, if set, records
+// whether an ISSN was listed as print or electronic ISSN.
 type HoldingFilter struct {
-	Ref   time.Time
-	Table holdings.Licenses
+	Ref             time.Time
+	Table           holdings.Licenses
+	Editions        holdings.Editions
+	Store           *HoldingStore
+	ISIL            string
+	FreeIgnoresWall bool
+	ISSNSource      ISSNSource
+	FuturePolicy    FuturePolicy
+
+	// matched records ISSNs, that were covered and valid for some record; only
+	// used, if tracking is enabled
+	mu      *sync.Mutex
+	matched *container.StringSet
+
+	origin *origin
 }
 
 // NewHoldingFilter loads the holdings information for a single institution.
-// Returns a single error, if errors has been encountered. The single errors
-// will be logger to stderr.
-func NewHoldingFilter(r io.Reader) (HoldingFilter, error) {
-	licenses, errs := holdings.ParseHoldings(r)
+// If more than one reader is given, e.g. for holdings files from different
+// vendors, the licenses are merged per ISSN. Returns a single error, if errors
+// has been encountered. The single errors are emitted as Diagnostics.
+func NewHoldingFilter(readers ...io.Reader) (HoldingFilter, error) {
+	licenses, editions := make(holdings.Licenses), make(holdings.Editions)
+	var errs []error
+	for _, r := range readers {
+		l, ed, e := holdings.ParseHoldingsEditions(r)
+		licenses.Merge(l)
+		editions.Merge(ed)
+		errs = append(errs, e...)
+	}
+	f := NewHoldingFilterFromLicenses(licenses, time.Now())
+	f.Editions = editions
 	if len(errs) > 0 {
 		for _, e := range errs {
-			log.Println(e)
+			Warn(holdingsCode(e), e.Error())
 		}
-		err := fmt.Errorf("%d errors in holdings file", len(errs))
-		return HoldingFilter{Ref: time.Now(), Table: licenses}, err
+		return f, fmt.Errorf("%d errors in holdings file, first: %w", len(errs), errs[0])
+	}
+	return f, nil
+}
+
+// NewHoldingFilterFromLicenses creates a filter from an already parsed table,
+// e.g. to share a table between ISILs. Moving walls are relative to ref.
+func NewHoldingFilterFromLicenses(t holdings.Licenses, ref time.Time) HoldingFilter {
+	return HoldingFilter{Ref: ref, Table: t}
+}
+
+// NewStoreHoldingFilter returns a filter, that reads the licenses of an ISIL
+// through a store, so reloads take effect immediately.
+func NewStoreHoldingFilter(store *HoldingStore, isil string) HoldingFilter {
+	return HoldingFilter{Ref: time.Now(), Store: store, ISIL: isil}
+}
+
+// licenses returns the current license table.
+func (f HoldingFilter) licenses() holdings.Licenses {
+	if f.Store != nil {
+		return f.Store.Licenses(f.ISIL)
+	}
+	return f.Table
+}
+
+// editions returns the current editions.
+func (f HoldingFilter) editions() holdings.Editions {
+	if f.Store != nil {
+		return f.Store.Editions(f.ISIL)
 	}
-	return HoldingFilter{Ref: time.Now(), Table: licenses}, nil
+	return f.Editions
+}
+
+// Empty returns true, if there are no licenses.
+func (f HoldingFilter) Empty() bool {
+	return len(f.licenses()) == 0
 }
 
 // MarshalJSON provides custom serialization.
 func (f HoldingFilter) MarshalJSON() ([]byte, error) {
-	return json.Marshal(f.Table)
+	return json.Marshal(f.licenses())
+}
+
+// Track enables the recording of matched ISSNs, so unmatched ISSNs can be
+// reported after a run, see Unmatched.
+func (f *HoldingFilter) Track() {
+	f.mu = new(sync.Mutex)
+	f.matched = container.NewStringSet()
+}
+
+// Unmatched returns the sorted ISSNs of the holdings table, which have not
+// matched any record so far. Returns nil, if tracking is not enabled.
+func (f HoldingFilter) Unmatched() []string {
+	if f.matched == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var issns []string
+	for issn := range f.licenses() {
+		if !f.matched.Contains(issn) {
+			issns = append(issns, issn)
+		}
+	}
+	sort.Strings(issns)
+	return issns
 }
 
-// CoveredAndValid checks coverage and moving wall. If there is no entry for
+// CoveredAndValid checks coverage and moving wall, e.g. a record published
+// at a given date must not be newer than the wall. If there is no entry for
 // an ISSN in the holdings file, we assume, there exists no valid license.
-func (f HoldingFilter) CoveredAndValid(signature, issn string) bool {
-	licenses, ok := f.Table[issn]
+func (f HoldingFilter) CoveredAndValid(signature, issn string, date time.Time) bool {
+	c, _ := f.check(signature, issn, date, false, false)
+	if c == Covered {
+		f.track(issn)
+	}
+	return c == Covered
+}
+
+// CoveredAndValidEdition works like CoveredAndValid, but also returns, whether
+// the ISSN was listed as print or electronic ISSN, for edition-specific
+// licensing decisions. The edition is unknown, if Editions is not set.
+func (f HoldingFilter) CoveredAndValidEdition(signature, issn string, date time.Time) (bool, holdings.Edition) {
+	if !f.CoveredAndValid(signature, issn, date) {
+		return false, 0
+	}
+	return true, f.editions()[issn]
+}
+
+// track records a matched ISSN, if tracking is enabled.
+func (f HoldingFilter) track(issn string) {
+	if f.matched == nil {
+		return
+	}
+	f.mu.Lock()
+	f.matched.Add(issn)
+	f.mu.Unlock()
+}
+
+// check tells, whether the licenses of an ISSN cover the issue and, unless
+// ignoreWall is set or the license is free and FreeIgnoresWall is set,
+// whether the date clears the moving wall. If walls is set, the walls of
+// covering licenses, which were not cleared, are returned as well.
+func (f HoldingFilter) check(signature, issn string, date time.Time, ignoreWall, walls bool) (Coverage, []time.Time) {
+	licenses, ok := f.licenses()[issn]
 	if !ok {
-		return false
+		return NotInHoldings, nil
 	}
+	var (
+		c      = NotCovered
+		closed []time.Time
+	)
 	for _, license := range licenses {
 		if !license.Covers(signature) {
 			continue
 		}
-		if f.Ref.After(license.Wall(f.Ref)) {
-			return true
+		if ignoreWall || (f.FreeIgnoresWall && license.Free()) {
+			return Covered, nil
+		}
+		wall := license.Wall(f.Ref)
+		if !date.After(wall) {
+			return Covered, nil
+		}
+		c = Walled
+		if walls {
+			closed = append(closed, wall)
 		}
 	}
-	return false
+	return c, closed
+}
+
+// issnEvaluation is the outcome for a single ISSN of a record.
+type issnEvaluation struct {
+	ISSN     string
+	Coverage Coverage
+	// Walls are the moving walls of covering licenses, that were not cleared.
+	Walls []time.Time
+}
+
+// holdingEvaluation is the outcome of checking a record against the
+// holdings, see HoldingFilter.evaluate.
+type holdingEvaluation struct {
+	// Date is the record date after applying the future policy.
+	Date time.Time
+	// Dropped is true, if the future policy dropped the record.
+	Dropped bool
+	Covered bool
+	ISSNs   []issnEvaluation
+}
+
+// coverageRank orders coverages from least to most favorable.
+var coverageRank = map[Coverage]int{NotInHoldings: 0, NotCovered: 1, Walled: 2, Covered: 3}
+
+// Coverage returns the most favorable coverage of all ISSNs.
+func (e holdingEvaluation) Coverage() Coverage {
+	if len(e.ISSNs) == 0 {
+		return NoISSN
+	}
+	c := NotInHoldings
+	for _, r := range e.ISSNs {
+		if coverageRank[r.Coverage] > coverageRank[c] {
+			c = r.Coverage
+		}
+	}
+	return c
+}
+
+// evaluate checks a record against the holdings, applying the future
+// policy, free entitlements and moving walls. Apply, Explain and Classify
+// share this evaluation. Unless all is set, evaluate stops at the first
+// covered ISSN and does not collect results per ISSN; with tracking enabled,
+// all ISSNs are checked, so none of a matched record is reported as
+// unmatched. Covering licenses of a dropped record count as walled.
+func (f HoldingFilter) evaluate(is finc.IntermediateSchema, all bool) holdingEvaluation {
+	e := holdingEvaluation{Date: is.Date}
+	var ignoreWall bool
+	if e.Date.After(f.Ref) {
+		switch f.FuturePolicy {
+		case FutureAttach:
+			ignoreWall = true
+		case FutureDrop:
+			e.Dropped = true
+		case FutureClamp:
+			e.Date = time.Date(f.Ref.Year(), f.Ref.Month(), f.Ref.Day(), 0, 0, 0, 0, f.Ref.Location())
+		}
+	}
+	if e.Dropped && !all {
+		return e
+	}
+	signature := holdings.CombineDatum(fmt.Sprintf("%d", e.Date.Year()), is.Volume, is.Issue, "")
+	for _, issn := range f.ISSNSource.ISSNs(is) {
+		c, walls := f.check(signature, issn, e.Date, ignoreWall, all)
+		if c == Covered && e.Dropped {
+			c = Walled
+		}
+		if c == Covered {
+			f.track(issn)
+			e.Covered = true
+		}
+		if !all {
+			if e.Covered && f.matched == nil {
+				return e
+			}
+			continue
+		}
+		e.ISSNs = append(e.ISSNs, issnEvaluation{ISSN: issn, Coverage: c, Walls: walls})
+	}
+	return e
 }
 
 // HoldingFilter compares the (year, volume, issue) of the
 // record with license information, including possible moving walls.
 func (f HoldingFilter) Apply(is finc.IntermediateSchema) bool {
-	signature := holdings.CombineDatum(fmt.Sprintf("%d", is.Date.Year()), is.Volume, is.Issue, "")
-	for _, issn := range append(is.ISSN, is.EISSN...) {
-		if f.CoveredAndValid(signature, issn) {
-			return true
-		}
-	}
-	return false
+	return f.evaluate(is, false).Covered
 }
 
 // ListFilter will include records, whose ISSN is contained in a given set.
 type ListFilter struct {
-	Set *container.StringSet
+	Set        *container.StringSet
+	ISSNSource ISSNSource
+
+	origin *origin
 }
 
-// NewAttachByList reads one record per line from reader.
-func NewListFilter(r io.Reader) (ListFilter, error) {
+// readLines returns the trimmed, non-empty lines from a reader.
+func readLines(r io.Reader) ([]string, error) {
+	var lines []string
 	br := bufio.NewReader(r)
-	f := ListFilter{Set: container.NewStringSet()}
 	for {
 		line, err := br.ReadString('\n')
-		if err == io.EOF {
+		if err == io.EOF && line == "" {
 			break
 		}
+		if err != nil && err != io.EOF {
+			return lines, err
+		}
 		if strings.TrimSpace(line) == "" {
 			continue
 		}
-		if err != nil {
-			return f, err
-		}
-		f.Set.Add(strings.TrimSpace(line))
+		lines = append(lines, strings.TrimSpace(line))
 	}
+	return lines, nil
+}
+
+// NewAttachByList reads one record per line from reader.
+func NewListFilter(r io.Reader) (ListFilter, error) {
+	f := ListFilter{Set: container.NewStringSet()}
+	lines, err := readLines(r)
+	if err != nil {
+		return f, err
+	}
+	f.Set.AddAll(lines...)
 	return f, nil
 }
 
+// Empty returns true, if the list has no entries.
+func (f ListFilter) Empty() bool {
+	return f.Set.Size() == 0
+}
+
 // MarshalJSON provides custom serialization.
 func (f ListFilter) MarshalJSON() ([]byte, error) {
 	return json.Marshal(f.Set.Values())
@@ -131,7 +552,7 @@ func (f ListFilter) MarshalJSON() ([]byte, error) {
 
 // Apply filter.
 func (f ListFilter) Apply(is finc.IntermediateSchema) bool {
-	for _, issn := range append(is.ISSN, is.EISSN...) {
+	for _, issn := range f.ISSNSource.ISSNs(is) {
 		if f.Set.Contains(issn) {
 			return true
 		}
@@ -139,6 +560,311 @@ func (f ListFilter) Apply(is finc.IntermediateSchema) bool {
 	return false
 }
 
+// LanguageFilter attaches records in one of the allowed languages, given as
+// ISO 639-3 codes.
+type LanguageFilter struct {
+	Allowed *container.StringSet
+}
+
+// NewLanguageFilter reads one language code per line, codes are normalized.
+func NewLanguageFilter(r io.Reader) (LanguageFilter, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return LanguageFilter{Allowed: container.NewStringSet()}, err
+	}
+	return newLanguageFilter(lines)
+}
+
+// newLanguageFilter normalizes the given codes. Unknown codes are an error,
+// since they would normalize to "und" and let through all records of unknown
+// language. Use "und" explicitly to allow these.
+func newLanguageFilter(codes []string) (LanguageFilter, error) {
+	f := LanguageFilter{Allowed: container.NewStringSet()}
+	for _, s := range codes {
+		code, ok := lookupLanguage(s)
+		if !ok {
+			return f, fmt.Errorf("unknown language code: %q", s)
+		}
+		f.Allowed.Add(code)
+	}
+	return f, nil
+}
+
+// Empty returns true, if no language is allowed.
+func (f LanguageFilter) Empty() bool {
+	return f.Allowed.Size() == 0
+}
+
+// MarshalJSON provides custom serialization.
+func (f LanguageFilter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.Allowed.SortedValues())
+}
+
+// Apply filter.
+func (f LanguageFilter) Apply(is finc.IntermediateSchema) bool {
+	for _, lang := range is.Languages {
+		if f.Allowed.Contains(NormalizeLanguage(lang)) {
+			return true
+		}
+	}
+	return false
+}
+
+// RelationFilter attaches records, that have one of the given relation
+// types, e.g. has-preprint. If Exclude is set, it attaches records, that
+// have none of the types, e.g. to exclude preprints.
+type RelationFilter struct {
+	Types   *container.StringSet
+	Exclude bool
+}
+
+// NewRelationFilter reads one relation type per line.
+func NewRelationFilter(r io.Reader, exclude bool) (RelationFilter, error) {
+	f := RelationFilter{Types: container.NewStringSet(), Exclude: exclude}
+	lines, err := readLines(r)
+	if err != nil {
+		return f, err
+	}
+	f.Types.AddAll(lines...)
+	return f, nil
+}
+
+// MarshalJSON provides custom serialization.
+func (f RelationFilter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"types":   f.Types.SortedValues(),
+		"exclude": f.Exclude,
+	})
+}
+
+// Apply filter.
+func (f RelationFilter) Apply(is finc.IntermediateSchema) bool {
+	for _, rel := range is.Relations {
+		if f.Types.Contains(rel) {
+			return !f.Exclude
+		}
+	}
+	return f.Exclude
+}
+
+// ISSNYearFilter attaches records, if both ISSN and publication year are
+// listed, e.g. for agreements, that cover a few specific years only.
+type ISSNYearFilter struct {
+	Table      map[string]*container.StringSet
+	ISSNSource ISSNSource
+
+	origin *origin
+}
+
+// NewISSNYearFilter reads whitespace separated ISSN and year pairs, one per line.
+func NewISSNYearFilter(r io.Reader) (ISSNYearFilter, error) {
+	f := ISSNYearFilter{Table: make(map[string]*container.StringSet)}
+	lines, err := readLines(r)
+	if err != nil {
+		return f, err
+	}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return f, fmt.Errorf("invalid ISSN year line: %s", line)
+		}
+		issn, year := fields[0], fields[1]
+		if _, ok := f.Table[issn]; !ok {
+			f.Table[issn] = container.NewStringSet()
+		}
+		f.Table[issn].Add(year)
+	}
+	return f, nil
+}
+
+// Empty returns true, if there are no entries.
+func (f ISSNYearFilter) Empty() bool {
+	return len(f.Table) == 0
+}
+
+// MarshalJSON provides custom serialization.
+func (f ISSNYearFilter) MarshalJSON() ([]byte, error) {
+	m := make(map[string][]string)
+	for issn, years := range f.Table {
+		m[issn] = years.SortedValues()
+	}
+	return json.Marshal(m)
+}
+
+// Apply filter.
+func (f ISSNYearFilter) Apply(is finc.IntermediateSchema) bool {
+	year := fmt.Sprintf("%d", is.Date.Year())
+	for _, issn := range f.ISSNSource.ISSNs(is) {
+		if years, ok := f.Table[issn]; ok && years.Contains(year) {
+			return true
+		}
+	}
+	return false
+}
+
+// DOIPublisherFilter attaches records, whose publisher is contained in an
+// allow-list. Besides the publishers of the record, the publisher is derived
+// from the DOI prefix, since the publisher field is often empty.
+type DOIPublisherFilter struct {
+	Prefixes   container.StringMap
+	Publishers *container.StringSet
+
+	origin *origin
+}
+
+// NewDOIPublisherFilter reads a tab separated prefix to publisher table and an
+// allow-list with one publisher per line.
+func NewDOIPublisherFilter(prefixes, publishers io.Reader) (DOIPublisherFilter, error) {
+	f := DOIPublisherFilter{Prefixes: make(container.StringMap), Publishers: container.NewStringSet()}
+	lines, err := readLines(prefixes)
+	if err != nil {
+		return f, err
+	}
+	for _, line := range lines {
+		fields := strings.SplitN(line, "\t", 2)
+		if len(fields) != 2 {
+			return f, fmt.Errorf("invalid prefix table line: %s", line)
+		}
+		f.Prefixes[strings.TrimSpace(fields[0])] = strings.TrimSpace(fields[1])
+	}
+	lines, err = readLines(publishers)
+	if err != nil {
+		return f, err
+	}
+	f.Publishers.AddAll(lines...)
+	return f, nil
+}
+
+// Empty returns true, if no publisher is allowed.
+func (f DOIPublisherFilter) Empty() bool {
+	return f.Publishers.Size() == 0
+}
+
+// MarshalJSON provides custom serialization.
+func (f DOIPublisherFilter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.Publishers.SortedValues())
+}
+
+// Apply filter.
+func (f DOIPublisherFilter) Apply(is finc.IntermediateSchema) bool {
+	for _, publisher := range is.Publishers {
+		if f.Publishers.Contains(strings.TrimSpace(publisher)) {
+			return true
+		}
+	}
+	prefix := strings.SplitN(is.DOI, "/", 2)[0]
+	if publisher, ok := f.Prefixes[prefix]; ok {
+		return f.Publishers.Contains(publisher)
+	}
+	return false
+}
+
+// ValidDuring makes an inner filter effective only, if the reference date Ref
+// lies within the window given by From and To (inclusive), e.g. for trial
+// access or a past subscription year. Outside the window, Apply returns false.
+type ValidDuring struct {
+	From  time.Time
+	To    time.Time
+	Ref   time.Time
+	Inner Filter
+}
+
+// NewValidDuring wraps a filter with a time window, using the current time as
+// reference date. The window ends with the last moment of the day of to, so
+// the last day is included entirely.
+func NewValidDuring(from, to time.Time, inner Filter) ValidDuring {
+	y, m, d := to.Date()
+	to = time.Date(y, m, d, 0, 0, 0, 0, to.Location()).Add(24*time.Hour - time.Nanosecond)
+	return ValidDuring{From: from, To: to, Ref: time.Now(), Inner: inner}
+}
+
+// Apply filter.
+func (f ValidDuring) Apply(is finc.IntermediateSchema) bool {
+	if f.Ref.Before(f.From) || f.Ref.After(f.To) {
+		return false
+	}
+	return f.Inner.Apply(is)
+}
+
+// Empty returns true, if the inner filter is empty.
+func (f ValidDuring) Empty() bool {
+	e, ok := f.Inner.(Emptier)
+	return ok && e.Empty()
+}
+
+// MarshalJSON provides custom serialization. The inner filter is serialized
+// as a filter spec, see filterSpec.
+func (f ValidDuring) MarshalJSON() ([]byte, error) {
+	spec, err := filterSpec(f.Inner)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]interface{}{
+		"from":   f.From.Format("2006-01-02"),
+		"to":     f.To.Format("2006-01-02"),
+		"filter": spec,
+	})
+}
+
+// MapFilters replaces each filter with the result of fn, including the
+// children of and, at-least and during filters, e.g. to apply options to
+// filters, regardless of how they were configured. Children are passed to fn
+// before their parent.
+func (t ISILTagger) MapFilters(fn func(isil string, f Filter) Filter) {
+	for isil, filters := range t {
+		for i, f := range filters {
+			filters[i] = mapFilter(f, func(g Filter) Filter { return fn(isil, g) })
+		}
+	}
+}
+
+// mapFilter applies fn to f and its children.
+func mapFilter(f Filter, fn func(Filter) Filter) Filter {
+	switch g := f.(type) {
+	case AndFilter:
+		children := make([]Filter, len(g.Filters))
+		for i, c := range g.Filters {
+			children[i] = mapFilter(c, fn)
+		}
+		g.Filters = children
+		f = g
+	case AtLeastFilter:
+		children := make([]Filter, len(g.Children))
+		for i, c := range g.Children {
+			children[i] = mapFilter(c, fn)
+		}
+		g.Children = children
+		f = g
+	case ValidDuring:
+		g.Inner = mapFilter(g.Inner, fn)
+		f = g
+	}
+	return fn(f)
+}
+
+// Validate returns warnings about ISILs, that have no filters or filters,
+// that cannot match anything, e.g. because a list file was empty.
+func (t ISILTagger) Validate() []string {
+	var warnings []string
+	for isil, filters := range t {
+		if len(filters) == 0 {
+			warnings = append(warnings, fmt.Sprintf("%s: no filters", isil))
+		}
+		for i, f := range filters {
+			if e, ok := f.(Emptier); ok && e.Empty() {
+				warnings = append(warnings, fmt.Sprintf("%s: filter %d (%T) is empty", isil, i, f))
+			}
+		}
+	}
+	sort.Strings(warnings)
+	return warnings
+}
+
+// Tagger decides, which ISILs should be attached to a record.
+type Tagger interface {
+	Tags(finc.IntermediateSchema) []string
+}
+
 // ISILTagger maps an ISIL to one or more Filters. If any of these filters
 // return true, the ISIL shall be attached (therefore order of the filters
 // does not matter).
@@ -156,3 +882,89 @@ func (t ISILTagger) Tags(is finc.IntermediateSchema) []string {
 	}
 	return isils.Values()
 }
+
+// Attachment records, which filter caused an ISIL to be attached. Index is
+// the position of the filter in the list of filters for the ISIL.
+type Attachment struct {
+	ISIL   string `json:"isil"`
+	Index  int    `json:"index"`
+	Filter string `json:"filter"`
+}
+
+// PrecedenceTagger maps an ISIL to an ordered list of filters. Per ISIL, the
+// first matching filter wins and later filters are not evaluated at all, e.g.
+// to express "prefer holdings, fall back to source".
+type PrecedenceTagger map[string][]Filter
+
+// Attachments returns, sorted by ISIL, the ISILs, that can be attached to a
+// given record, together with the filter, that applied.
+func (t PrecedenceTagger) Attachments(is finc.IntermediateSchema) []Attachment {
+	var attachments []Attachment
+	for isil, filters := range t {
+		for i, f := range filters {
+			if f.Apply(is) {
+				attachments = append(attachments, Attachment{
+					ISIL:   isil,
+					Index:  i,
+					Filter: fmt.Sprintf("%T", f),
+				})
+				break
+			}
+		}
+	}
+	sort.Slice(attachments, func(i, j int) bool {
+		return attachments[i].ISIL < attachments[j].ISIL
+	})
+	return attachments
+}
+
+// Tags returns all ISILs that can be attached to a given intermediate schema record.
+func (t PrecedenceTagger) Tags(is finc.IntermediateSchema) []string {
+	var isils []string
+	for _, a := range t.Attachments(is) {
+		isils = append(isils, a.ISIL)
+	}
+	return isils
+}
+
+// FilterTrace records the outcome of a single filter.
+type FilterTrace struct {
+	Filter string `json:"filter"`
+	Result bool   `json:"result"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Trace describes how an attachment decision for a record came about.
+type Trace struct {
+	SourceID string                   `json:"source_id"`
+	ISSN     []string                 `json:"issn"`
+	Filters  map[string][]FilterTrace `json:"filters"`
+	Tags     []string                 `json:"tags"`
+}
+
+// Trace evaluates every filter for every ISIL, without short-circuiting, and
+// reports the individual results. Meant for debugging.
+func (t ISILTagger) Trace(is finc.IntermediateSchema) Trace {
+	trace := Trace{
+		SourceID: is.SourceID,
+		ISSN:     is.ISSNList(),
+		Filters:  make(map[string][]FilterTrace),
+	}
+	sort.Strings(trace.ISSN)
+	isils := container.NewStringSet()
+	for isil, filters := range t {
+		for _, f := range filters {
+			result := f.Apply(is)
+			if result {
+				isils.Add(isil)
+			}
+			ft := FilterTrace{Filter: fmt.Sprintf("%T", f), Result: result}
+			if !result {
+				ft.Reason = explain(f, is)
+			}
+			trace.Filters[isil] = append(trace.Filters[isil], ft)
+		}
+	}
+	trace.Tags = isils.SortedValues()
+	return trace
+}
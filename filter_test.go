@@ -0,0 +1,622 @@
+package span
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miku/span/finc"
+	"github.com/miku/span/holdings"
+)
+
+func mustParseDate(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestValidDuring(t *testing.T) {
+	var tests = []struct {
+		ref    time.Time
+		result bool
+	}{
+		{ref: mustParseDate("2022-01-01"), result: true},
+		{ref: mustParseDate("2022-06-15"), result: true},
+		{ref: mustParseDate("2022-12-31"), result: true},
+		{ref: mustParseDate("2021-12-31"), result: false},
+		{ref: mustParseDate("2023-06-15"), result: false},
+	}
+	for _, tt := range tests {
+		f := ValidDuring{
+			From:  mustParseDate("2022-01-01"),
+			To:    mustParseDate("2022-12-31"),
+			Ref:   tt.ref,
+			Inner: Any{},
+		}
+		if r := f.Apply(finc.IntermediateSchema{}); r != tt.result {
+			t.Errorf("ValidDuring.Apply at %s: got %v, want %v", tt.ref, r, tt.result)
+		}
+	}
+
+	f := ValidDuring{From: mustParseDate("2022-01-01"), To: mustParseDate("2022-12-31"),
+		Inner: SourceFilter{SourceID: "49"}}
+	b, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"filter":{"source":"49"},"from":"2022-01-01","to":"2022-12-31"}`; string(b) != want {
+		t.Errorf("ValidDuring.MarshalJSON: got %s, want %s", b, want)
+	}
+	if _, err := LoadISILTagger(strings.NewReader(fmt.Sprintf(`{"DE-1": [{"during": %s}]}`, b))); err != nil {
+		t.Errorf("LoadISILTagger(%s): %s", b, err)
+	}
+}
+
+func TestHoldingFilterUnmatched(t *testing.T) {
+	f := HoldingFilter{Ref: time.Now(), Table: holdings.Licenses{
+		"1234-5678": []holdings.License{"0000000000000000:ZZZZZZZZZZZZZZZZ:0"},
+		"2345-6789": []holdings.License{"0000000000000000:ZZZZZZZZZZZZZZZZ:0"},
+		"3456-7890": []holdings.License{"0000000000000000:ZZZZZZZZZZZZZZZZ:0"},
+	}}
+	f.Track()
+
+	records := []finc.IntermediateSchema{
+		{ISSN: []string{"1234-5678"}, Date: mustParseDate("2000-01-01")},
+		{EISSN: []string{"3456-7890"}, Date: mustParseDate("2000-01-01")},
+		{ISSN: []string{"0000-0000"}, Date: mustParseDate("2000-01-01")},
+	}
+	for _, is := range records {
+		f.Apply(is)
+	}
+
+	want := []string{"2345-6789"}
+	if got := f.Unmatched(); !reflect.DeepEqual(got, want) {
+		t.Errorf("HoldingFilter.Unmatched: got %v, want %v", got, want)
+	}
+
+	// all ISSNs of a matched record count as matched
+	f.Apply(finc.IntermediateSchema{ISSN: []string{"1234-5678"}, EISSN: []string{"2345-6789"},
+		Date: mustParseDate("2000-01-01")})
+	want = nil
+	if got := f.Unmatched(); !reflect.DeepEqual(got, want) {
+		t.Errorf("HoldingFilter.Unmatched: got %v, want %v", got, want)
+	}
+}
+
+func TestHoldingFilterEdition(t *testing.T) {
+	f, err := NewHoldingFilter(strings.NewReader(`<holding ezb_id="1">
+  <EZBIssns><p-issn>1234-5678</p-issn><e-issn>2345-6789</e-issn></EZBIssns>
+  <entitlements>
+    <entitlement status="subscribed">
+      <begin><year>2000</year></begin>
+      <end><year>2010</year></end>
+    </entitlement>
+  </entitlements>
+</holding>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		issn    string
+		ok      bool
+		edition holdings.Edition
+	}{
+		{"1234-5678", true, holdings.Print},
+		{"2345-6789", true, holdings.Online},
+		{"3456-7890", false, 0},
+	}
+	date, signature := mustParseDate("2005-01-01"), holdings.CombineDatum("2005", "", "", "")
+	for _, tt := range tests {
+		ok, edition := f.CoveredAndValidEdition(signature, tt.issn, date)
+		if ok != tt.ok || edition != tt.edition {
+			t.Errorf("CoveredAndValidEdition(%s): got %v, %s, want %v, %s", tt.issn, ok, edition, tt.ok, tt.edition)
+		}
+		if f.CoveredAndValid(signature, tt.issn, date) != tt.ok {
+			t.Errorf("CoveredAndValid(%s): got %v, want %v", tt.issn, !tt.ok, tt.ok)
+		}
+	}
+}
+
+func TestISILTaggerTrace(t *testing.T) {
+	tagger := ISILTagger{
+		"DE-1": []Filter{Any{}},
+		"DE-2": []Filter{SourceFilter{SourceID: "49"}, SourceFilter{SourceID: "28"}},
+	}
+	is := finc.IntermediateSchema{SourceID: "28", ISSN: []string{"1234-5678"}}
+	trace := tagger.Trace(is)
+	want := Trace{
+		SourceID: "28",
+		ISSN:     []string{"1234-5678"},
+		Filters: map[string][]FilterTrace{
+			"DE-1": []FilterTrace{{Filter: "span.Any", Result: true}},
+			"DE-2": []FilterTrace{
+				{Filter: "span.SourceFilter", Result: false, Reason: "wrong source: got 28, want 49"},
+				{Filter: "span.SourceFilter", Result: true},
+			},
+		},
+		Tags: []string{"DE-1", "DE-2"},
+	}
+	if !reflect.DeepEqual(trace, want) {
+		t.Errorf("ISILTagger.Trace: got %+v, want %+v", trace, want)
+	}
+}
+
+func TestDOIPublisherFilter(t *testing.T) {
+	f, err := NewDOIPublisherFilter(
+		strings.NewReader("10.1007\tSpringer\n10.1016\tElsevier\n"),
+		strings.NewReader("Springer\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		is     finc.IntermediateSchema
+		result bool
+	}{
+		{finc.IntermediateSchema{DOI: "10.1007/s00894-015-2669-1"}, true},
+		{finc.IntermediateSchema{DOI: "10.1016/j.cell.2015.01.001"}, false},
+		{finc.IntermediateSchema{DOI: "10.9999/x"}, false},
+		{finc.IntermediateSchema{Publishers: []string{"Springer"}}, true},
+		{finc.IntermediateSchema{}, false},
+	}
+	for _, tt := range tests {
+		if r := f.Apply(tt.is); r != tt.result {
+			t.Errorf("DOIPublisherFilter.Apply(%v): got %v, want %v", tt.is.DOI, r, tt.result)
+		}
+	}
+}
+
+// countingFilter always matches and counts its invocations.
+type countingFilter struct {
+	calls *int
+}
+
+func (f countingFilter) Apply(is finc.IntermediateSchema) bool {
+	*f.calls++
+	return true
+}
+
+func TestPrecedenceTagger(t *testing.T) {
+	var orCalls, precedenceCalls int
+
+	or := ISILTagger{"DE-1": []Filter{countingFilter{&orCalls}, countingFilter{&orCalls}}}
+	if tags := or.Tags(finc.IntermediateSchema{}); !reflect.DeepEqual(tags, []string{"DE-1"}) {
+		t.Errorf("ISILTagger.Tags: got %v, want [DE-1]", tags)
+	}
+	if orCalls != 2 {
+		t.Errorf("ISILTagger.Tags: got %d filter calls, want 2", orCalls)
+	}
+
+	precedence := PrecedenceTagger{"DE-1": []Filter{countingFilter{&precedenceCalls}, countingFilter{&precedenceCalls}}}
+	want := []Attachment{{ISIL: "DE-1", Index: 0, Filter: "span.countingFilter"}}
+	if as := precedence.Attachments(finc.IntermediateSchema{}); !reflect.DeepEqual(as, want) {
+		t.Errorf("PrecedenceTagger.Attachments: got %v, want %v", as, want)
+	}
+	if precedenceCalls != 1 {
+		t.Errorf("PrecedenceTagger.Attachments: got %d filter calls, want 1", precedenceCalls)
+	}
+
+	fallback := PrecedenceTagger{"DE-1": []Filter{SourceFilter{SourceID: "49"}, Any{}}}
+	want = []Attachment{{ISIL: "DE-1", Index: 1, Filter: "span.Any"}}
+	if as := fallback.Attachments(finc.IntermediateSchema{SourceID: "28"}); !reflect.DeepEqual(as, want) {
+		t.Errorf("PrecedenceTagger.Attachments: got %v, want %v", as, want)
+	}
+}
+
+func TestISSNYearFilter(t *testing.T) {
+	f, err := NewISSNYearFilter(strings.NewReader("1234-5678 2019\n1234-5678\t2021\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		is     finc.IntermediateSchema
+		result bool
+	}{
+		{finc.IntermediateSchema{ISSN: []string{"1234-5678"}, Date: mustParseDate("2019-05-01")}, true},
+		{finc.IntermediateSchema{ISSN: []string{"1234-5678"}, Date: mustParseDate("2020-05-01")}, false},
+		{finc.IntermediateSchema{EISSN: []string{"1234-5678"}, Date: mustParseDate("2021-05-01")}, true},
+		{finc.IntermediateSchema{ISSN: []string{"2345-6789"}, Date: mustParseDate("2019-05-01")}, false},
+	}
+	for _, tt := range tests {
+		if r := f.Apply(tt.is); r != tt.result {
+			t.Errorf("ISSNYearFilter.Apply(%v, %d): got %v, want %v", tt.is.ISSNList(), tt.is.Date.Year(), r, tt.result)
+		}
+	}
+}
+
+func TestISILTaggerValidate(t *testing.T) {
+	empty, err := NewListFilter(strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := NewListFilter(strings.NewReader("1234-5678\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagger := ISILTagger{
+		"DE-1": []Filter{full},
+		"DE-2": []Filter{full, empty},
+		"DE-3": []Filter{},
+		"DE-4": []Filter{Any{}},
+	}
+	want := []string{
+		"DE-2: filter 1 (span.ListFilter) is empty",
+		"DE-3: no filters",
+	}
+	if warnings := tagger.Validate(); !reflect.DeepEqual(warnings, want) {
+		t.Errorf("ISILTagger.Validate: got %v, want %v", warnings, want)
+	}
+}
+
+func TestHoldingFilterWall(t *testing.T) {
+	ref := mustParseDate("2020-06-01")
+	table := holdings.Licenses{
+		"1234-5678": []holdings.License{"0000000000000000:ZZZZZZZZZZZZZZZZ:0"},
+		"2345-6789": []holdings.License{"0000000000000000:ZZZZZZZZZZZZZZZZ:-1Y"},
+	}
+	var tests = []struct {
+		issn   string
+		date   time.Time
+		result bool
+	}{
+		{"1234-5678", mustParseDate("2019-01-01"), true},
+		{"1234-5678", mustParseDate("2020-06-01"), true},
+		{"2345-6789", mustParseDate("2019-01-01"), true},
+		{"2345-6789", mustParseDate("2019-06-01"), true},
+		{"2345-6789", mustParseDate("2019-06-02"), false},
+		{"2345-6789", mustParseDate("2020-01-01"), false},
+	}
+	for _, tt := range tests {
+		f := HoldingFilter{Ref: ref, Table: table}
+		is := finc.IntermediateSchema{ISSN: []string{tt.issn}, Date: tt.date}
+		if r := f.Apply(is); r != tt.result {
+			t.Errorf("HoldingFilter.Apply(%s, %s): got %v, want %v",
+				tt.issn, tt.date.Format("2006-01-02"), r, tt.result)
+		}
+	}
+}
+
+func TestHoldingFilterFree(t *testing.T) {
+	ref := mustParseDate("2020-06-01")
+	table := holdings.Licenses{
+		"1234-5678": []holdings.License{"0000000000000000:ZZZZZZZZZZZZZZZZ:-1Y"},
+		"2345-6789": []holdings.License{"0000000000000000:ZZZZZZZZZZZZZZZZ:-1Y:free"},
+	}
+	var tests = []struct {
+		issn            string
+		date            time.Time
+		freeIgnoresWall bool
+		result          bool
+	}{
+		{"1234-5678", mustParseDate("2019-01-01"), true, true},
+		{"1234-5678", mustParseDate("2020-01-01"), true, false},
+		{"2345-6789", mustParseDate("2020-01-01"), true, true},
+		{"2345-6789", mustParseDate("2020-01-01"), false, false},
+		{"2345-6789", mustParseDate("2019-01-01"), false, true},
+	}
+	for _, tt := range tests {
+		f := HoldingFilter{Ref: ref, Table: table, FreeIgnoresWall: tt.freeIgnoresWall}
+		is := finc.IntermediateSchema{ISSN: []string{tt.issn}, Date: tt.date}
+		if r := f.Apply(is); r != tt.result {
+			t.Errorf("HoldingFilter.Apply(%s, %s, free ignores wall %v): got %v, want %v",
+				tt.issn, tt.date.Format("2006-01-02"), tt.freeIgnoresWall, r, tt.result)
+		}
+	}
+}
+
+func TestListFilterISSNSource(t *testing.T) {
+	f, err := NewListFilter(strings.NewReader("1234-5678\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pissn := finc.IntermediateSchema{ISSN: []string{"1234-5678"}}
+	eissn := finc.IntermediateSchema{EISSN: []string{"1234-5678"}}
+	var tests = []struct {
+		source ISSNSource
+		is     finc.IntermediateSchema
+		result bool
+	}{
+		{BothISSN, pissn, true},
+		{BothISSN, eissn, true},
+		{PrintISSN, pissn, true},
+		{PrintISSN, eissn, false},
+		{ElectronicISSN, pissn, false},
+		{ElectronicISSN, eissn, true},
+	}
+	for _, tt := range tests {
+		f.ISSNSource = tt.source
+		if r := f.Apply(tt.is); r != tt.result {
+			t.Errorf("ListFilter.Apply(%v, %v): got %v, want %v", tt.source, tt.is.ISSNList(), r, tt.result)
+		}
+	}
+}
+
+func TestNewHoldingFilterMerge(t *testing.T) {
+	f, err := NewHoldingFilter(
+		strings.NewReader(testHolding("2000", "2004", "")),
+		strings.NewReader(testHolding("2010", "2014", "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		date   string
+		result bool
+	}{
+		{"2002-01-01", true},
+		{"2007-01-01", false},
+		{"2012-01-01", true},
+	}
+	for _, tt := range tests {
+		is := finc.IntermediateSchema{ISSN: []string{"1234-5678"}, Date: mustParseDate(tt.date)}
+		if r := f.Apply(is); r != tt.result {
+			t.Errorf("HoldingFilter.Apply(%s): got %v, want %v", tt.date, r, tt.result)
+		}
+	}
+}
+
+func TestNewHoldingFilterFromLicenses(t *testing.T) {
+	holding := testHolding("2000", "", "-1Y")
+	table, errs := holdings.ParseHoldings(strings.NewReader(holding))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	g, err := NewHoldingFilter(strings.NewReader(holding))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := NewHoldingFilterFromLicenses(table, g.Ref)
+	now := time.Now()
+	for _, date := range []time.Time{
+		mustParseDate("1999-12-31"),
+		mustParseDate("2000-01-01"),
+		now.AddDate(-2, 0, 0),
+		now.AddDate(0, -6, 0),
+		now,
+	} {
+		is := finc.IntermediateSchema{ISSN: []string{"1234-5678"}, Date: date}
+		if f.Apply(is) != g.Apply(is) {
+			t.Errorf("HoldingFilter.Apply(%s): got %v from table, %v from reader", date, f.Apply(is), g.Apply(is))
+		}
+	}
+	if !f.Apply(finc.IntermediateSchema{ISSN: []string{"1234-5678"}, Date: mustParseDate("2001-01-01")}) {
+		t.Errorf("HoldingFilter.Apply: expected record within license to be covered")
+	}
+}
+
+func TestNoISSNFilter(t *testing.T) {
+	var tests = []struct {
+		is     finc.IntermediateSchema
+		result bool
+	}{
+		{finc.IntermediateSchema{}, true},
+		{finc.IntermediateSchema{ISSN: []string{"1234-5678"}}, false},
+		{finc.IntermediateSchema{EISSN: []string{"1234-5678"}}, false},
+	}
+	for _, tt := range tests {
+		if r := (NoISSNFilter{}).Apply(tt.is); r != tt.result {
+			t.Errorf("NoISSNFilter.Apply(%v): got %v, want %v", tt.is, r, tt.result)
+		}
+	}
+
+	f := AndFilter{Filters: []Filter{NoISSNFilter{}, SourceFilter{SourceID: "49"}}}
+	tests = []struct {
+		is     finc.IntermediateSchema
+		result bool
+	}{
+		{finc.IntermediateSchema{SourceID: "49"}, true},
+		{finc.IntermediateSchema{SourceID: "28"}, false},
+		{finc.IntermediateSchema{SourceID: "49", ISSN: []string{"1234-5678"}}, false},
+	}
+	for _, tt := range tests {
+		if r := f.Apply(tt.is); r != tt.result {
+			t.Errorf("AndFilter.Apply(%v): got %v, want %v", tt.is, r, tt.result)
+		}
+	}
+}
+
+func TestLanguageFilter(t *testing.T) {
+	f, err := NewLanguageFilter(strings.NewReader("de\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagger := ISILTagger{"DE-15": []Filter{AndFilter{Filters: []Filter{f, SourceFilter{SourceID: "49"}}}}}
+	var tests = []struct {
+		is    finc.IntermediateSchema
+		isils []string
+	}{
+		{finc.IntermediateSchema{SourceID: "49", Languages: []string{"deu"}}, []string{"DE-15"}},
+		{finc.IntermediateSchema{SourceID: "49", Languages: []string{"eng", "ger"}}, []string{"DE-15"}},
+		{finc.IntermediateSchema{SourceID: "49", Languages: []string{"eng"}}, nil},
+		{finc.IntermediateSchema{SourceID: "49"}, nil},
+		{finc.IntermediateSchema{SourceID: "28", Languages: []string{"deu"}}, nil},
+	}
+	for _, tt := range tests {
+		if isils := tagger.Tags(tt.is); !reflect.DeepEqual(isils, tt.isils) {
+			t.Errorf("Tags(%v): got %v, want %v", tt.is.Languages, isils, tt.isils)
+		}
+	}
+
+	if _, err := NewLanguageFilter(strings.NewReader("de\ndeutsch\n")); err == nil {
+		t.Errorf("NewLanguageFilter: got nil, want error for unknown code")
+	}
+	if _, err := LoadISILTagger(strings.NewReader(`{"DE-15": [{"language": ["deu", "dxu"]}]}`)); err == nil {
+		t.Errorf("LoadISILTagger: got nil, want error for unknown language code")
+	}
+	tagger, err = LoadISILTagger(strings.NewReader(`{"DE-15": [{"language": ["und"]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isils := tagger.Tags(finc.IntermediateSchema{Languages: []string{"xx"}}); len(isils) != 1 {
+		t.Errorf("Tags: got %v, want DE-15 for unknown language with und allowed", isils)
+	}
+}
+
+func TestRelationFilter(t *testing.T) {
+	preprint := finc.IntermediateSchema{Relations: []string{"has-preprint", "is-supplement-to"}}
+	plain := finc.IntermediateSchema{}
+	var tests = []struct {
+		exclude bool
+		is      finc.IntermediateSchema
+		result  bool
+	}{
+		{false, preprint, true},
+		{false, plain, false},
+		{true, preprint, false},
+		{true, plain, true},
+	}
+	for _, tt := range tests {
+		f, err := NewRelationFilter(strings.NewReader("has-preprint\n"), tt.exclude)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r := f.Apply(tt.is); r != tt.result {
+			t.Errorf("RelationFilter.Apply(%v), exclude=%v: got %v, want %v", tt.is.Relations, tt.exclude, r, tt.result)
+		}
+	}
+}
+
+func TestCollectionFilter(t *testing.T) {
+	tagger, err := LoadISILTagger(strings.NewReader(`{"DE-15": [{"collection": ["DeGruyter Mathematics"]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		is    finc.IntermediateSchema
+		isils []string
+	}{
+		{finc.IntermediateSchema{MegaCollection: "DeGruyter Mathematics"}, []string{"DE-15"}},
+		{finc.IntermediateSchema{MegaCollection: "DeGruyter SSH"}, nil},
+		{finc.IntermediateSchema{}, nil},
+	}
+	for _, tt := range tests {
+		if isils := tagger.Tags(tt.is); !reflect.DeepEqual(isils, tt.isils) {
+			t.Errorf("Tags(%q): got %v, want %v", tt.is.MegaCollection, isils, tt.isils)
+		}
+	}
+	if _, err := LoadISILTagger(strings.NewReader(`{"DE-15": [{"collection": []}]}`)); err == nil {
+		t.Errorf("LoadISILTagger: got nil, want error for empty collection list")
+	}
+}
+
+func TestHoldingFilterFuturePolicy(t *testing.T) {
+	ref := mustParseDate("2020-06-15")
+	is := finc.IntermediateSchema{ISSN: []string{"1234-5678"}, Date: ref.AddDate(2, 0, 0)}
+	var tests = []struct {
+		delay  string
+		policy FuturePolicy
+		result bool
+	}{
+		{"-0M", FutureAsIs, false},
+		{"-0M", FutureAttach, true},
+		{"-0M", FutureDrop, false},
+		{"-0M", FutureClamp, true},
+		{"-1Y", FutureAttach, true},
+		{"-1Y", FutureClamp, false},
+	}
+	for _, tt := range tests {
+		table, errs := holdings.ParseHoldings(strings.NewReader(testHolding("2000", "", tt.delay)))
+		if len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		f := NewHoldingFilterFromLicenses(table, ref)
+		f.FuturePolicy = tt.policy
+		if r := f.Apply(is); r != tt.result {
+			t.Errorf("HoldingFilter.Apply with delay %s, policy %d: got %v, want %v", tt.delay, tt.policy, r, tt.result)
+		}
+	}
+}
+
+func TestAtLeastFilter(t *testing.T) {
+	f := AtLeastFilter{N: 2, Children: []Filter{
+		SourceFilter{SourceID: "49"},
+		NoISSNFilter{},
+		ValidDuring{From: mustParseDate("2000-01-01"), To: mustParseDate("2001-01-01"),
+			Ref: mustParseDate("2000-06-01"), Inner: Any{}},
+	}}
+	var tests = []struct {
+		is     finc.IntermediateSchema
+		result bool
+	}{
+		{finc.IntermediateSchema{SourceID: "49"}, true},
+		{finc.IntermediateSchema{SourceID: "49", ISSN: []string{"1234-5678"}}, true},
+		{finc.IntermediateSchema{SourceID: "28"}, true},
+		{finc.IntermediateSchema{SourceID: "28", ISSN: []string{"1234-5678"}}, false},
+	}
+	for _, tt := range tests {
+		if r := f.Apply(tt.is); r != tt.result {
+			t.Errorf("AtLeastFilter.Apply(%v): got %v, want %v", tt.is, r, tt.result)
+		}
+	}
+
+	f.Children[2] = SourceFilter{SourceID: "28"}
+	if r := f.Apply(finc.IntermediateSchema{SourceID: "49", ISSN: []string{"1234-5678"}}); r {
+		t.Errorf("AtLeastFilter.Apply: got true for one of three, want false")
+	}
+	if r := (AtLeastFilter{N: 4, Children: f.Children}).Apply(finc.IntermediateSchema{}); r {
+		t.Errorf("AtLeastFilter.Apply: got true for N greater than number of children")
+	}
+
+	b, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"filters":[{"source":"49"},{"no-issn":{}},{"source":"28"}],"n":2}`; string(b) != want {
+		t.Errorf("AtLeastFilter.MarshalJSON: got %s, want %s", b, want)
+	}
+}
+
+func TestAccessFilter(t *testing.T) {
+	tagger, err := LoadISILTagger(strings.NewReader(`{"DE-15": [{"access": ["open", "us"]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		is    finc.IntermediateSchema
+		isils []string
+	}{
+		{finc.IntermediateSchema{Access: "open"}, []string{"DE-15"}},
+		{finc.IntermediateSchema{Access: "us"}, []string{"DE-15"}},
+		{finc.IntermediateSchema{Access: "restricted"}, nil},
+		{finc.IntermediateSchema{}, nil},
+	}
+	for _, tt := range tests {
+		if isils := tagger.Tags(tt.is); !reflect.DeepEqual(isils, tt.isils) {
+			t.Errorf("Tags(%q): got %v, want %v", tt.is.Access, isils, tt.isils)
+		}
+	}
+	if _, err := LoadISILTagger(strings.NewReader(`{"DE-15": [{"access": []}]}`)); err == nil {
+		t.Errorf("LoadISILTagger: got nil, want error for empty access list")
+	}
+}
+
+func TestISILTaggerMapFilters(t *testing.T) {
+	tagger := ISILTagger{
+		"DE-1": []Filter{SourceFilter{SourceID: "1"}},
+		"DE-2": []Filter{AndFilter{Filters: []Filter{
+			SourceFilter{SourceID: "1"},
+			AtLeastFilter{N: 1, Children: []Filter{
+				ValidDuring{From: mustParseDate("2000-01-01"), To: mustParseDate("2001-01-01"),
+					Ref: mustParseDate("2000-06-01"), Inner: SourceFilter{SourceID: "1"}},
+			}},
+		}}},
+	}
+	var count int
+	tagger.MapFilters(func(isil string, f Filter) Filter {
+		if _, ok := f.(SourceFilter); ok {
+			count++
+			return SourceFilter{SourceID: "2"}
+		}
+		return f
+	})
+	if count != 3 {
+		t.Errorf("MapFilters: got %d source filters, want 3", count)
+	}
+	is := finc.IntermediateSchema{SourceID: "2"}
+	if got := tagger.Tags(is); !reflect.DeepEqual(got, []string{"DE-1", "DE-2"}) {
+		t.Errorf("MapFilters: got %v, want [DE-1 DE-2]", got)
+	}
+}
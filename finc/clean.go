@@ -0,0 +1,36 @@
+package finc
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CleanString collapses runs of whitespace, including tabs and newlines, to
+// a single space, trims the result and drops other control characters, like
+// NUL, which break TSV output and some SOLR analyzers.
+func CleanString(s string) string {
+	return strings.Join(strings.Fields(stripControl(s)), " ")
+}
+
+// stripControl drops C0 and C1 control characters, except whitespace. HTML
+// sanitizing replaces NUL with U+FFFD, so this must run before it.
+func stripControl(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && !unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// cleanStrings applies CleanString to a copy of a slice.
+func cleanStrings(ss []string) []string {
+	if ss == nil {
+		return nil
+	}
+	result := make([]string, len(ss))
+	for i, s := range ss {
+		result[i] = CleanString(s)
+	}
+	return result
+}
@@ -0,0 +1,47 @@
+package finc
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/miku/span/container"
+)
+
+// DefaultCollection is the mega collection of records from unmapped sources,
+// that do not carry a mega collection themselves.
+const DefaultCollection = "Unknown Collection"
+
+// CollectionMap assigns a mega collection to each source id. Sources without
+// mapping keep the mega collection of the record, or get DefaultCollection,
+// and are counted in Unmapped. Safe for concurrent use.
+type CollectionMap struct {
+	Table    map[string]string
+	Unmapped container.StringCounter
+}
+
+// NewCollectionMap creates a map from a table.
+func NewCollectionMap(table map[string]string) *CollectionMap {
+	return &CollectionMap{Table: table, Unmapped: container.NewStringCounter()}
+}
+
+// ReadCollectionMap reads a JSON object mapping source ids to collections,
+// e.g. {"49": "CrossRef", "28": "DOAJ"}.
+func ReadCollectionMap(r io.Reader) (*CollectionMap, error) {
+	table := make(map[string]string)
+	if err := json.NewDecoder(r).Decode(&table); err != nil {
+		return nil, err
+	}
+	return NewCollectionMap(table), nil
+}
+
+// Collection returns the mega collection for a record.
+func (m *CollectionMap) Collection(is IntermediateSchema) string {
+	if c, ok := m.Table[is.SourceID]; ok {
+		return c
+	}
+	m.Unmapped.Inc(is.SourceID)
+	if is.MegaCollection != "" {
+		return is.MegaCollection
+	}
+	return DefaultCollection
+}
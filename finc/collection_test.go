@@ -0,0 +1,36 @@
+package finc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCollectionMap(t *testing.T) {
+	m, err := ReadCollectionMap(strings.NewReader(`{"49": "CrossRef", "28": "DOAJ"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		is         IntermediateSchema
+		collection string
+	}{
+		{IntermediateSchema{SourceID: "49", MegaCollection: "Springer (CrossRef)"}, "CrossRef"},
+		{IntermediateSchema{SourceID: "28"}, "DOAJ"},
+		{IntermediateSchema{SourceID: "55", MegaCollection: "JSTOR Arts"}, "JSTOR Arts"},
+		{IntermediateSchema{SourceID: "55"}, DefaultCollection},
+		{IntermediateSchema{SourceID: "60"}, DefaultCollection},
+	}
+	for _, tt := range tests {
+		s := Solr413Schema{Collections: m}
+		if err := s.Convert(tt.is); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(s.MegaCollections, []string{tt.collection}) {
+			t.Errorf("MegaCollections (%s): got %v, want [%s]", tt.is.SourceID, s.MegaCollections, tt.collection)
+		}
+	}
+	if want := map[string]int{"55": 2, "60": 1}; !reflect.DeepEqual(m.Unmapped.Counts(), want) {
+		t.Errorf("CollectionMap.Unmapped: got %v, want %v", m.Unmapped.Counts(), want)
+	}
+}
@@ -0,0 +1,76 @@
+package finc
+
+import "sync/atomic"
+
+// Weights of populated fields for the completeness score. Title, authors and
+// ISSN identify a record best, so they count most. A record with all fields
+// populated scores MaxCompleteness.
+const (
+	WeightTitle     = 3 // article title or book title
+	WeightAuthors   = 2 // at least one author
+	WeightISSN      = 2 // print or electronic ISSN, or ISBN
+	WeightJournal   = 1 // journal title
+	WeightDate      = 1 // publication date
+	WeightLocator   = 1 // DOI or URL
+	WeightPublisher = 1 // at least one publisher
+	WeightAbstract  = 1 // abstract
+	MaxCompleteness = WeightTitle + WeightAuthors + WeightISSN + WeightJournal + WeightDate + WeightLocator + WeightPublisher + WeightAbstract
+)
+
+// Completeness returns a weighted count of populated key fields, between zero
+// and MaxCompleteness.
+func (is *IntermediateSchema) Completeness() int {
+	var score int
+	if is.ArticleTitle != "" || is.BookTitle != "" {
+		score += WeightTitle
+	}
+	for _, author := range is.Authors {
+		if author.String() != "" {
+			score += WeightAuthors
+			break
+		}
+	}
+	if len(is.ISSN) > 0 || len(is.EISSN) > 0 || len(is.ISBN) > 0 || len(is.EISBN) > 0 {
+		score += WeightISSN
+	}
+	if is.JournalTitle != "" {
+		score += WeightJournal
+	}
+	if !is.Date.IsZero() {
+		score += WeightDate
+	}
+	if is.DOI != "" || len(is.URL) > 0 {
+		score += WeightLocator
+	}
+	for _, p := range is.Publishers {
+		if p != "" {
+			score += WeightPublisher
+			break
+		}
+	}
+	if is.Abstract != "" {
+		score += WeightAbstract
+	}
+	return score
+}
+
+// CompletenessGate drops records with a completeness score below Min and
+// counts them. Safe for concurrent use.
+type CompletenessGate struct {
+	dropped int64 // first, so it is 64-bit aligned for atomic access on 32-bit platforms
+	Min     int
+}
+
+// Keep returns true, if the record scores at least Min.
+func (g *CompletenessGate) Keep(is IntermediateSchema) bool {
+	if is.Completeness() < g.Min {
+		atomic.AddInt64(&g.dropped, 1)
+		return false
+	}
+	return true
+}
+
+// Dropped returns the number of dropped records so far.
+func (g *CompletenessGate) Dropped() int64 {
+	return atomic.LoadInt64(&g.dropped)
+}
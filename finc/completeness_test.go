@@ -0,0 +1,40 @@
+package finc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompletenessGate(t *testing.T) {
+	var tests = []struct {
+		is    IntermediateSchema
+		score int
+		keep  bool
+	}{
+		{IntermediateSchema{}, 0, false},
+		{IntermediateSchema{Publishers: []string{""}, Authors: []Author{{}}}, 0, false},
+		{IntermediateSchema{ArticleTitle: "On Sparse Records", Date: time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)}, 4, false},
+		{IntermediateSchema{
+			ArticleTitle: "On Rich Records",
+			Authors:      []Author{{FirstName: "Jane", LastName: "Doe"}},
+			ISSN:         []string{"1234-5678"},
+			JournalTitle: "Journal of Records",
+			Date:         time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC),
+			DOI:          "10.1000/1",
+			Publishers:   []string{"Record Press"},
+			Abstract:     "Rich records are rich.",
+		}, MaxCompleteness, true},
+	}
+	gate := &CompletenessGate{Min: 7}
+	for _, tt := range tests {
+		if score := tt.is.Completeness(); score != tt.score {
+			t.Errorf("Completeness: got %d, want %d", score, tt.score)
+		}
+		if keep := gate.Keep(tt.is); keep != tt.keep {
+			t.Errorf("CompletenessGate.Keep (score %d): got %v, want %v", tt.score, keep, tt.keep)
+		}
+	}
+	if gate.Dropped() != 3 {
+		t.Errorf("CompletenessGate.Dropped: got %d, want 3", gate.Dropped())
+	}
+}
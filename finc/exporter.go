@@ -1,6 +1,7 @@
 package finc
 
 import (
+	"crypto/sha1"
 	"fmt"
 
 	"github.com/kennygrant/sanitize"
@@ -20,6 +21,42 @@ type ExportSchema interface {
 	Attach([]string)
 }
 
+// IdentifierFunc derives the id of an exported document from an intermediate
+// schema record.
+type IdentifierFunc func(IntermediateSchema) string
+
+// IdentifierFuncs holds the available strategies for generating ids. All of
+// them are stable, e.g. they will yield the same value for the same record.
+var IdentifierFuncs = map[string]IdentifierFunc{
+	"record_id": RecordIdentifier,
+	"doi":       DOIIdentifier,
+	"hash":      HashIdentifier,
+	"composite": CompositeIdentifier,
+}
+
+// RecordIdentifier uses the record id as is. This is the default.
+func RecordIdentifier(is IntermediateSchema) string {
+	return is.RecordID
+}
+
+// DOIIdentifier uses the DOI and falls back to the record id, if there is no DOI.
+func DOIIdentifier(is IntermediateSchema) string {
+	if is.DOI == "" {
+		return is.RecordID
+	}
+	return is.DOI
+}
+
+// HashIdentifier uses the hex encoded SHA1 of source and record id.
+func HashIdentifier(is IntermediateSchema) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(is.SourceID+":"+is.RecordID)))
+}
+
+// CompositeIdentifier prefixes the record id with the source id, e.g. ai-49-<record id>.
+func CompositeIdentifier(is IntermediateSchema) string {
+	return fmt.Sprintf("ai-%s-%s", is.SourceID, is.RecordID)
+}
+
 // DummySchema is an example export schema, that only has one field.
 type DummySchema struct {
 	Title string `json:"title"`
@@ -39,6 +76,7 @@ func (d *DummySchema) Convert(is IntermediateSchema) error {
 type Solr413Schema struct {
 	AccessFacet          string   `json:"access_facet,omitempty"`
 	AuthorFacet          []string `json:"author_facet"`
+	AuthorORCID          []string `json:"author_orcid,omitempty"`
 	Allfields            string   `json:"allfields,omitempty"`
 	Author               string   `json:"author,omitempty"`
 	FincClassFacet       []string `json:"finc_class_facet,omitempty"`
@@ -66,6 +104,21 @@ type Solr413Schema struct {
 	Topics               []string `json:"topic,omitempty"`
 	URL                  []string `json:"url,omitempty"`
 	FormatDe15           []string `json:"format_de15"`
+
+	// IdentifierFunc generates the id, defaults to RecordIdentifier.
+	IdentifierFunc IdentifierFunc `json:"-"`
+	// AuthorOrder, if set to LastFirst or FirstLast, normalizes and
+	// deduplicates author names.
+	AuthorOrder string `json:"-"`
+	// SortAuthors sorts secondary authors for a stable output, requires AuthorOrder.
+	SortAuthors bool `json:"-"`
+	// RawText keeps whitespace and control characters in titles, publishers
+	// and allfields, which are cleaned by default, see CleanString.
+	RawText bool `json:"-"`
+	// URLBuilder optionally derives URLs from the DOI.
+	URLBuilder URLBuilder `json:"-"`
+	// Collections, if set, assigns the mega collection by source id.
+	Collections *CollectionMap `json:"-"`
 }
 
 // Attach attaches the ISILs to a record.
@@ -73,17 +126,33 @@ func (s *Solr413Schema) Attach(isils []string) {
 	s.Institutions = isils
 }
 
+// Truncate caps allfields and fulltext, which contain the abstract, at max bytes.
+func (s *Solr413Schema) Truncate(max int) bool {
+	var a, b bool
+	s.Allfields, a = TruncateString(s.Allfields, max)
+	s.Fulltext, b = TruncateString(s.Fulltext, max)
+	return a || b
+}
+
 // Export method from intermediate schema to solr 4/13 schema.
 func (s *Solr413Schema) Convert(is IntermediateSchema) error {
 	s.Allfields = is.Allfields()
 	s.Formats = append(s.Formats, is.Format)
 	s.Fullrecord = "blob:" + is.RecordID
 	s.Fulltext = is.Fulltext
-	s.HierarchyParentTitle = append(s.HierarchyParentTitle, is.JournalTitle)
-	s.ID = is.RecordID
+	s.HierarchyParentTitle = append(s.HierarchyParentTitle, is.ParentTitle())
+	if s.IdentifierFunc != nil {
+		s.ID = s.IdentifierFunc(is)
+	} else {
+		s.ID = is.RecordID
+	}
 	s.Imprint = is.Imprint()
 	s.ISSN = is.ISSNList()
-	s.MegaCollections = append(s.MegaCollections, is.MegaCollection)
+	if s.Collections != nil {
+		s.MegaCollections = append(s.MegaCollections, s.Collections.Collection(is))
+	} else {
+		s.MegaCollections = append(s.MegaCollections, is.MegaCollection)
+	}
 	s.PublishDateSort = is.Date.Year()
 	s.Publishers = is.Publishers
 	s.RecordType = AIRecordType
@@ -92,7 +161,7 @@ func (s *Solr413Schema) Convert(is IntermediateSchema) error {
 	s.Subtitle = is.ArticleSubtitle
 	s.TitleSort = is.SortableTitle()
 	s.Topics = is.Subjects
-	s.URL = is.URL
+	s.URL = s.URLBuilder.URLs(is)
 
 	classes := container.NewStringSet()
 	for _, s := range is.Subjects {
@@ -102,24 +171,52 @@ func (s *Solr413Schema) Convert(is IntermediateSchema) error {
 	}
 	s.FincClassFacet = classes.Values()
 
-	sanitized := sanitize.HTML(is.ArticleTitle)
+	title := is.ArticleTitle
+	if !s.RawText {
+		title = stripControl(title)
+	}
+	sanitized := sanitize.HTML(title)
 	s.Title, s.TitleFull, s.TitleShort = sanitized, sanitized, sanitized
 
 	for _, lang := range is.Languages {
 		s.Languages = append(s.Languages, LanguageMap.LookupDefault(lang, lang))
 	}
 
-	for _, author := range is.Authors {
-		s.SecondaryAuthors = append(s.SecondaryAuthors, author.String())
-		s.AuthorFacet = append(s.AuthorFacet, author.String())
+	if s.AuthorOrder != "" {
+		names := CanonicalAuthors(is.Authors, s.AuthorOrder, false)
+		if len(names) > 0 {
+			s.Author = names[0]
+		}
+		if s.SortAuthors {
+			names = CanonicalAuthors(is.Authors, s.AuthorOrder, true)
+		}
+		s.SecondaryAuthors, s.AuthorFacet = names, names
+	} else {
+		for _, author := range is.Authors {
+			s.SecondaryAuthors = append(s.SecondaryAuthors, author.String())
+			s.AuthorFacet = append(s.AuthorFacet, author.String())
+		}
+		if len(s.SecondaryAuthors) > 0 {
+			s.Author = s.SecondaryAuthors[0]
+		}
 	}
 
-	if len(s.SecondaryAuthors) > 0 {
-		s.Author = s.SecondaryAuthors[0]
+	orcids := container.NewStringSet()
+	for _, author := range is.Authors {
+		if author.ORCID != "" && orcids.Add(author.ORCID) {
+			s.AuthorORCID = append(s.AuthorORCID, author.ORCID)
+		}
 	}
 
 	s.AccessFacet = AIAccessFacet
 	s.FormatDe15 = []string{FormatSite.LookupDefault(is.Format, "")}
 
+	if !s.RawText {
+		s.Title = CleanString(s.Title)
+		s.TitleFull, s.TitleShort = s.Title, s.Title
+		s.Publishers = cleanStrings(s.Publishers)
+		s.Allfields = CleanString(s.Allfields)
+	}
+
 	return nil
 }
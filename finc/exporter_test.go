@@ -0,0 +1,165 @@
+package finc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSolr413SchemaIdentifier(t *testing.T) {
+	is := IntermediateSchema{RecordID: "abc", SourceID: "49", DOI: "10.1/x"}
+	var tests = []struct {
+		f  IdentifierFunc
+		id string
+	}{
+		{f: nil, id: "abc"},
+		{f: RecordIdentifier, id: "abc"},
+		{f: DOIIdentifier, id: "10.1/x"},
+		{f: CompositeIdentifier, id: "ai-49-abc"},
+		{f: HashIdentifier, id: "e81f365a03a0c11692ee045729349ea225da7a68"},
+	}
+	for _, tt := range tests {
+		s := Solr413Schema{IdentifierFunc: tt.f}
+		if err := s.Convert(is); err != nil {
+			t.Fatal(err)
+		}
+		if s.ID != tt.id {
+			t.Errorf("Solr413Schema.Convert: got id %q, want %q", s.ID, tt.id)
+		}
+	}
+}
+
+func TestSolr413SchemaParentTitle(t *testing.T) {
+	var tests = []struct {
+		is   IntermediateSchema
+		want string
+	}{
+		{IntermediateSchema{JournalTitle: "Journal", BookTitle: "Book"}, "Journal"},
+		{IntermediateSchema{BookTitle: "Book"}, "Book"},
+		{IntermediateSchema{}, ""},
+	}
+	for _, tt := range tests {
+		var s Solr413Schema
+		if err := s.Convert(tt.is); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(s.HierarchyParentTitle, []string{tt.want}) {
+			t.Errorf("Solr413Schema.Convert: got parent title %q, want %q", s.HierarchyParentTitle, tt.want)
+		}
+	}
+}
+
+func TestTruncateString(t *testing.T) {
+	var tests = []struct {
+		s         string
+		max       int
+		result    string
+		truncated bool
+	}{
+		{"Hello", 10, "Hello", false},
+		{"Hello", 5, "Hello", false},
+		{"Hello World", 8, "Hello…", true},
+		{"Grüße", 6, "Gr…", true},
+		{"Grüße!", 7, "Grü…", true},
+		{"Hello", 2, "He", true},
+		{"Grüße", 2, "Gr", true},
+		{"üü", 1, "", true},
+		{"Hello", 0, "", true},
+	}
+	for _, tt := range tests {
+		r, truncated := TruncateString(tt.s, tt.max)
+		if r != tt.result || truncated != tt.truncated {
+			t.Errorf("TruncateString(%q, %d): got %q, %v, want %q, %v", tt.s, tt.max, r, truncated, tt.result, tt.truncated)
+		}
+	}
+}
+
+func TestFieldCap(t *testing.T) {
+	c := FieldCap{Max: 8}
+	for _, s := range []string{"short", "a little longer", "much, much longer"} {
+		c.Apply(&Solr413Schema{Allfields: s})
+	}
+	if c.Count() != 2 {
+		t.Errorf("FieldCap.Count: got %d, want 2", c.Count())
+	}
+}
+
+func TestSolr413SchemaAuthors(t *testing.T) {
+	is := IntermediateSchema{Authors: []Author{
+		{Name: "Roe,  Jane "},
+		{LastName: "Doe", FirstName: "John"},
+		{Name: "John  Doe"},
+		{Name: "Anna Maria Smith"},
+		{Corporation: "ACME"},
+	}}
+	var tests = []struct {
+		order  string
+		sorted bool
+		author string
+		names  []string
+	}{
+		{LastFirst, false, "Roe, Jane", []string{"Roe, Jane", "Doe, John", "Smith, Anna Maria", "ACME"}},
+		{LastFirst, true, "Roe, Jane", []string{"ACME", "Doe, John", "Roe, Jane", "Smith, Anna Maria"}},
+		{FirstLast, true, "Jane Roe", []string{"ACME", "Anna Maria Smith", "Jane Roe", "John Doe"}},
+	}
+	for _, tt := range tests {
+		s := Solr413Schema{AuthorOrder: tt.order, SortAuthors: tt.sorted}
+		if err := s.Convert(is); err != nil {
+			t.Fatal(err)
+		}
+		if s.Author != tt.author {
+			t.Errorf("Convert(%s, %v): got author %q, want %q", tt.order, tt.sorted, s.Author, tt.author)
+		}
+		if !reflect.DeepEqual(s.SecondaryAuthors, tt.names) {
+			t.Errorf("Convert(%s, %v): got author2 %q, want %q", tt.order, tt.sorted, s.SecondaryAuthors, tt.names)
+		}
+	}
+}
+
+func TestSolr413SchemaCleanText(t *testing.T) {
+	is := IntermediateSchema{
+		ArticleTitle: "A\ttitle\nwith \x00control  chars – ünïcode",
+		Publishers:   []string{" ACME\t\tPress "},
+	}
+	s := Solr413Schema{}
+	if err := s.Convert(is); err != nil {
+		t.Fatal(err)
+	}
+	want := "A title with control chars – ünïcode"
+	if s.Title != want || s.TitleFull != want || s.TitleShort != want {
+		t.Errorf("Solr413Schema.Convert: got title %q, want %q", s.Title, want)
+	}
+	if !reflect.DeepEqual(s.Publishers, []string{"ACME Press"}) {
+		t.Errorf("Solr413Schema.Convert: got publishers %q", s.Publishers)
+	}
+	if is.Publishers[0] != " ACME\t\tPress " {
+		t.Errorf("Solr413Schema.Convert: modified intermediate schema publishers")
+	}
+	for _, r := range s.Allfields {
+		if r == '\t' || r == '\n' || r == 0 {
+			t.Errorf("Solr413Schema.Convert: got control character in allfields %q", s.Allfields)
+		}
+	}
+
+	s = Solr413Schema{RawText: true}
+	if err := s.Convert(is); err != nil {
+		t.Fatal(err)
+	}
+	if s.Publishers[0] != " ACME\t\tPress " {
+		t.Errorf("Solr413Schema.Convert with RawText: got publishers %q", s.Publishers)
+	}
+}
+
+func TestSolr413SchemaAuthorORCID(t *testing.T) {
+	is := IntermediateSchema{Authors: []Author{
+		{Name: "Jane Doe", ORCID: "0000-0002-1825-0097"},
+		{Name: "John Doe"},
+		{Name: "J. Doe", ORCID: "0000-0002-1825-0097"},
+	}}
+	s := Solr413Schema{}
+	if err := s.Convert(is); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s.AuthorORCID, []string{"0000-0002-1825-0097"}) {
+		t.Errorf("got author_orcid %v", s.AuthorORCID)
+	}
+}
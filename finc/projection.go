@@ -0,0 +1,90 @@
+package finc
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/miku/span/container"
+)
+
+// FieldNames returns the sorted JSON field names of an export schema, as
+// given by the struct tags. Fields tagged with "-" are not included.
+func FieldNames(s ExportSchema) []string {
+	t := reflect.TypeOf(s)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Projection restricts marshalled export schemas to a subset of fields,
+// e.g. to save transferring heavy fields like allfields, that an index does
+// not need. Either the fields to keep or the fields to drop are given.
+type Projection struct {
+	Include *container.StringSet
+	Exclude *container.StringSet
+}
+
+// NewProjection creates a projection for an export schema. Returns an error,
+// if both include and exclude are given or if a field name does not exist.
+func NewProjection(s ExportSchema, include, exclude []string) (*Projection, error) {
+	if len(include) > 0 && len(exclude) > 0 {
+		return nil, fmt.Errorf("cannot both include and exclude fields")
+	}
+	known := container.NewStringSet(FieldNames(s)...)
+	for _, name := range append(include, exclude...) {
+		if !known.Contains(name) {
+			return nil, fmt.Errorf("unknown field: %s", name)
+		}
+	}
+	return &Projection{
+		Include: container.NewStringSet(include...),
+		Exclude: container.NewStringSet(exclude...),
+	}, nil
+}
+
+// keep returns true, if the named field should be part of the output.
+func (p *Projection) keep(name string) bool {
+	if p.Include.Size() > 0 {
+		return p.Include.Contains(name)
+	}
+	return !p.Exclude.Contains(name)
+}
+
+// Marshal returns the JSON encoding of the export schema, restricted to the
+// fields of the projection.
+func (p *Projection) Marshal(s ExportSchema) ([]byte, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	for name := range doc {
+		if !p.keep(name) {
+			delete(doc, name)
+		}
+	}
+	return json.Marshal(doc)
+}
@@ -0,0 +1,68 @@
+package finc
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// keys returns the sorted top level keys of a JSON object.
+func keys(t *testing.T, b []byte) []string {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for name := range doc {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestProjection(t *testing.T) {
+	s := &Solr413Schema{
+		ID:        "ai-49-abc",
+		Title:     "A title",
+		Allfields: "A title and much more",
+		Fulltext:  "An abstract",
+		ISSN:      []string{"1234-5678"},
+	}
+
+	p, err := NewProjection(s, []string{"id", "title"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := p.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := keys(t, b), []string{"id", "title"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Projection.Marshal with include: got %v, want %v", got, want)
+	}
+
+	p, err = NewProjection(s, nil, []string{"allfields", "fulltext"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = p.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range keys(t, b) {
+		if name == "allfields" || name == "fulltext" {
+			t.Errorf("Projection.Marshal with exclude: %s present", name)
+		}
+	}
+	if got := keys(t, b); len(got) == 0 {
+		t.Errorf("Projection.Marshal with exclude: got no fields")
+	}
+
+	if _, err := NewProjection(s, []string{"no_such_field"}, nil); err == nil {
+		t.Errorf("NewProjection: expected error for unknown field")
+	}
+	if _, err := NewProjection(s, []string{"id"}, []string{"title"}); err == nil {
+		t.Errorf("NewProjection: expected error for include and exclude")
+	}
+}
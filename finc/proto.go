@@ -0,0 +1,201 @@
+package finc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Protocol buffer wire types, that are used here.
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+var errTruncated = errors.New("truncated protobuf message")
+
+// ProtoMarshaler is implemented by export schemas, that can be serialized as
+// protocol buffer message.
+type ProtoMarshaler interface {
+	MarshalProto() ([]byte, error)
+}
+
+// appendUvarint appends a base 128 varint.
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+// appendKey appends a field key, which is the field number and the wire type.
+func appendKey(b []byte, num, wire int) []byte {
+	return appendUvarint(b, uint64(num<<3|wire))
+}
+
+// appendString appends a string field, empty strings are omitted.
+func appendString(b []byte, num int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	return appendBytes(b, num, s)
+}
+
+// appendBytes appends a length-delimited field.
+func appendBytes(b []byte, num int, s string) []byte {
+	b = appendKey(b, num, wireBytes)
+	b = appendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// appendStrings appends a repeated string field, all values are kept.
+func appendStrings(b []byte, num int, ss []string) []byte {
+	for _, s := range ss {
+		b = appendBytes(b, num, s)
+	}
+	return b
+}
+
+// appendInt appends an int64 field, zero is omitted.
+func appendInt(b []byte, num int, v int) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendKey(b, num, wireVarint)
+	return appendUvarint(b, uint64(int64(v)))
+}
+
+// MarshalProto serializes the record as protocol buffer message, as defined in
+// solr413.proto.
+func (s *Solr413Schema) MarshalProto() ([]byte, error) {
+	var b []byte
+	b = appendString(b, 1, s.AccessFacet)
+	b = appendStrings(b, 2, s.AuthorFacet)
+	b = appendString(b, 3, s.Allfields)
+	b = appendString(b, 4, s.Author)
+	b = appendStrings(b, 5, s.FincClassFacet)
+	b = appendStrings(b, 6, s.Formats)
+	b = appendString(b, 7, s.Fullrecord)
+	b = appendString(b, 8, s.Fulltext)
+	b = appendStrings(b, 9, s.HierarchyParentTitle)
+	b = appendString(b, 10, s.ID)
+	b = appendStrings(b, 11, s.Institutions)
+	b = appendString(b, 12, s.Imprint)
+	b = appendStrings(b, 13, s.ISSN)
+	b = appendStrings(b, 14, s.Languages)
+	b = appendStrings(b, 15, s.MegaCollections)
+	b = appendInt(b, 16, s.PublishDateSort)
+	b = appendStrings(b, 17, s.Publishers)
+	b = appendString(b, 18, s.RecordType)
+	b = appendStrings(b, 19, s.Series)
+	b = appendStrings(b, 20, s.SecondaryAuthors)
+	b = appendString(b, 21, s.SourceID)
+	b = appendString(b, 22, s.Subtitle)
+	b = appendString(b, 23, s.Title)
+	b = appendString(b, 24, s.TitleFull)
+	b = appendString(b, 25, s.TitleShort)
+	b = appendString(b, 26, s.TitleSort)
+	b = appendStrings(b, 27, s.Topics)
+	b = appendStrings(b, 28, s.URL)
+	b = appendStrings(b, 29, s.FormatDe15)
+	b = appendStrings(b, 30, s.AuthorORCID)
+	return b, nil
+}
+
+// UnmarshalProto parses a protocol buffer message, as defined in
+// solr413.proto. Unknown fields are skipped.
+func (s *Solr413Schema) UnmarshalProto(b []byte) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errTruncated
+		}
+		b = b[n:]
+		num, wire := int(key>>3), int(key&7)
+		switch wire {
+		case wireVarint:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return errTruncated
+			}
+			b = b[n:]
+			if num == 16 {
+				s.PublishDateSort = int(int64(v))
+			}
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < size {
+				return errTruncated
+			}
+			v := string(b[n : n+int(size)])
+			b = b[n+int(size):]
+			s.setProtoField(num, v)
+		default:
+			return fmt.Errorf("unsupported wire type %d for field %d", wire, num)
+		}
+	}
+	return nil
+}
+
+// setProtoField sets a string valued field given by its number.
+func (s *Solr413Schema) setProtoField(num int, v string) {
+	switch num {
+	case 1:
+		s.AccessFacet = v
+	case 2:
+		s.AuthorFacet = append(s.AuthorFacet, v)
+	case 3:
+		s.Allfields = v
+	case 4:
+		s.Author = v
+	case 5:
+		s.FincClassFacet = append(s.FincClassFacet, v)
+	case 6:
+		s.Formats = append(s.Formats, v)
+	case 7:
+		s.Fullrecord = v
+	case 8:
+		s.Fulltext = v
+	case 9:
+		s.HierarchyParentTitle = append(s.HierarchyParentTitle, v)
+	case 10:
+		s.ID = v
+	case 11:
+		s.Institutions = append(s.Institutions, v)
+	case 12:
+		s.Imprint = v
+	case 13:
+		s.ISSN = append(s.ISSN, v)
+	case 14:
+		s.Languages = append(s.Languages, v)
+	case 15:
+		s.MegaCollections = append(s.MegaCollections, v)
+	case 17:
+		s.Publishers = append(s.Publishers, v)
+	case 18:
+		s.RecordType = v
+	case 19:
+		s.Series = append(s.Series, v)
+	case 20:
+		s.SecondaryAuthors = append(s.SecondaryAuthors, v)
+	case 21:
+		s.SourceID = v
+	case 22:
+		s.Subtitle = v
+	case 23:
+		s.Title = v
+	case 24:
+		s.TitleFull = v
+	case 25:
+		s.TitleShort = v
+	case 26:
+		s.TitleSort = v
+	case 27:
+		s.Topics = append(s.Topics, v)
+	case 28:
+		s.URL = append(s.URL, v)
+	case 29:
+		s.FormatDe15 = append(s.FormatDe15, v)
+	case 30:
+		s.AuthorORCID = append(s.AuthorORCID, v)
+	}
+}
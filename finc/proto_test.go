@@ -0,0 +1,194 @@
+package finc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSolr413SchemaProto(t *testing.T) {
+	records := []IntermediateSchema{
+		{
+			RecordID:     "ai-49-1",
+			SourceID:     "49",
+			ArticleTitle: "Title",
+			JournalTitle: "Journal",
+			ISSN:         []string{"1234-5678"},
+			Authors:      []Author{{LastName: "Doe", FirstName: "John"}, {Name: "Roe"}},
+			Date:         time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC),
+			Languages:    []string{"eng"},
+			URL:          []string{"http://example.com"},
+		},
+		{RecordID: "ai-28-2", SourceID: "28", Format: "ElectronicArticle"},
+	}
+
+	// encode a length-delimited stream and remember the JSON output
+	var stream bytes.Buffer
+	var want [][]byte
+	for _, is := range records {
+		s := new(Solr413Schema)
+		if err := s.Convert(is); err != nil {
+			t.Fatal(err)
+		}
+		s.Attach([]string{"DE-15", "DE-14"})
+		b, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, b)
+		p, err := s.MarshalProto()
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, binary.MaxVarintLen64)
+		stream.Write(buf[:binary.PutUvarint(buf, uint64(len(p)))])
+		stream.Write(p)
+	}
+
+	// decode the stream again and compare JSON
+	var i int
+	for {
+		size, err := binary.ReadUvarint(&stream)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := make([]byte, size)
+		if _, err := io.ReadFull(&stream, p); err != nil {
+			t.Fatal(err)
+		}
+		s := new(Solr413Schema)
+		if err := s.UnmarshalProto(p); err != nil {
+			t.Fatal(err)
+		}
+		b, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, want[i]) {
+			t.Errorf("UnmarshalProto: got %s, want %s", b, want[i])
+		}
+		i++
+	}
+	if i != len(records) {
+		t.Errorf("UnmarshalProto: got %d records, want %d", i, len(records))
+	}
+}
+
+// protoField is a field declaration of solr413.proto.
+type protoField struct {
+	Name     string
+	Type     string
+	Repeated bool
+}
+
+// readProtoFields parses the field declarations of the single message in
+// solr413.proto, keyed by field number.
+func readProtoFields(t *testing.T) map[uint64]protoField {
+	b, err := ioutil.ReadFile("solr413.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decl := regexp.MustCompile(`^\s*(repeated\s+)?(\w+)\s+(\w+)\s*=\s*(\d+);`)
+	fields := make(map[uint64]protoField)
+	for _, line := range strings.Split(string(b), "\n") {
+		m := decl.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		num, err := strconv.ParseUint(m[4], 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields[num] = protoField{Name: m[3], Type: m[2], Repeated: m[1] != ""}
+	}
+	return fields
+}
+
+// TestSolr413SchemaProtoDefinition decodes the output of MarshalProto with
+// the field numbers and types from solr413.proto, so the encoder and the
+// definition cannot drift apart. Proto field names are the JSON names.
+func TestSolr413SchemaProtoDefinition(t *testing.T) {
+	fields := readProtoFields(t)
+
+	// fill every serialized field with distinct values
+	s := new(Solr413Schema)
+	want, repeated := make(map[string][]string), make(map[string]bool)
+	v := reflect.ValueOf(s).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		switch f := v.Field(i); f.Kind() {
+		case reflect.String:
+			f.SetString("v-" + name)
+			want[name] = []string{"v-" + name}
+		case reflect.Slice:
+			f.Set(reflect.ValueOf([]string{"a-" + name, "b-" + name}))
+			want[name] = []string{"a-" + name, "b-" + name}
+			repeated[name] = true
+		case reflect.Int:
+			f.SetInt(int64(1000 + i))
+			want[name] = []string{strconv.Itoa(1000 + i)}
+		default:
+			t.Fatalf("unexpected kind %s of field %s", f.Kind(), name)
+		}
+	}
+	for _, pf := range fields {
+		if _, ok := want[pf.Name]; !ok {
+			t.Errorf("solr413.proto: field %s is not a JSON field of Solr413Schema", pf.Name)
+		}
+		if pf.Repeated != repeated[pf.Name] {
+			t.Errorf("solr413.proto: field %s: repeated is %v, want %v", pf.Name, pf.Repeated, repeated[pf.Name])
+		}
+	}
+
+	b, err := s.MarshalProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string][]string)
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatal("truncated key")
+		}
+		b = b[n:]
+		pf, ok := fields[key>>3]
+		if !ok {
+			t.Fatalf("field %d not in solr413.proto", key>>3)
+		}
+		switch wire := key & 7; {
+		case pf.Type == "string" && wire == 2:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < size {
+				t.Fatal("truncated value")
+			}
+			got[pf.Name] = append(got[pf.Name], string(b[n:n+int(size)]))
+			b = b[n+int(size):]
+		case pf.Type == "int64" && wire == 0:
+			x, n := binary.Uvarint(b)
+			if n <= 0 {
+				t.Fatal("truncated value")
+			}
+			got[pf.Name] = append(got[pf.Name], strconv.FormatInt(int64(x), 10))
+			b = b[n:]
+		default:
+			t.Fatalf("field %s: wire type %d does not match %s", pf.Name, wire, pf.Type)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MarshalProto: decoded %v, want %v", got, want)
+	}
+}
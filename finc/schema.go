@@ -4,13 +4,22 @@ package finc
 import (
 	"bytes"
 	"fmt"
+	"reflect"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/miku/span/assetutil"
 )
 
+const (
+	// LastFirst formats author names as "Doe, John".
+	LastFirst = "last-first"
+	// FirstLast formats author names as "John Doe".
+	FirstLast = "first-last"
+)
+
 const (
 	AIRecordType              = "ai"
 	AIAccessFacet             = "Electronic Resources"
@@ -28,6 +37,7 @@ var (
 // Author representes an author, "inspired" by OpenURL.
 type Author struct {
 	ID           string `json:"x.id,omitempty"`
+	ORCID        string `json:"x.orcid,omitempty"`
 	Name         string `json:"rft.au,omitempty"`
 	LastName     string `json:"rft.aulast,omitempty"`
 	FirstName    string `json:"rft.aufirst,omitempty"`
@@ -53,6 +63,66 @@ func (author *Author) String() string {
 	return author.ID
 }
 
+// normalizeSpace trims and collapses whitespace.
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// splitName splits a name given as "Last, First" or "First Last".
+func splitName(name string) (last, first string) {
+	if i := strings.Index(name, ","); i >= 0 {
+		return normalizeSpace(name[:i]), normalizeSpace(name[i+1:])
+	}
+	fields := strings.Fields(name)
+	if len(fields) < 2 {
+		return normalizeSpace(name), ""
+	}
+	return fields[len(fields)-1], strings.Join(fields[:len(fields)-1], " ")
+}
+
+// Canonical returns the author name in a given order, LastFirst or
+// FirstLast, regardless of how the name was given originally.
+func (author *Author) Canonical(order string) string {
+	var last, first string
+	switch {
+	case author.LastName != "":
+		last, first = normalizeSpace(author.LastName), normalizeSpace(author.FirstName)
+	case author.Name != "":
+		last, first = splitName(author.Name)
+	case author.Corporation != "":
+		return normalizeSpace(author.Corporation)
+	default:
+		return normalizeSpace(author.ID)
+	}
+	if first == "" {
+		return last
+	}
+	if order == FirstLast {
+		return fmt.Sprintf("%s %s", first, last)
+	}
+	return fmt.Sprintf("%s, %s", last, first)
+}
+
+// CanonicalAuthors returns the canonical names of authors in a given order,
+// without empty names and duplicates. Order of appearance is kept, unless
+// sorted is true.
+func CanonicalAuthors(authors []Author, order string, sorted bool) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, author := range authors {
+		name := author.Canonical(order)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		names = append(names, name)
+	}
+	if sorted {
+		sort.Strings(names)
+	}
+	return names
+}
+
 // IntermediateSchema abstract and collects the values of various input formats.
 // Goal is to simplify further processing by using a single format, from which
 // the next artifacts can be derived, e.g. records for solr indices.
@@ -81,7 +151,7 @@ type IntermediateSchema struct {
 	BookTitle    string    `json:"rft.btitle,omitempty"`
 	Chronology   string    `json:"rft.chron,omitempty"`
 	Edition      string    `json:"rft.edition,omitempty"`
-	EISBN        []string  `json:"rft.isbn,omitempty"`
+	EISBN        []string  `json:"rft.eisbn,omitempty"`
 	EISSN        []string  `json:"rft.eissn,omitempty"`
 	EndPage      string    `json:"rft.epage,omitempty"`
 	Genre        string    `json:"rft.genre,omitempty"`
@@ -110,8 +180,14 @@ type IntermediateSchema struct {
 	Version   string   `json:"version,omitempty"`
 
 	ArticleSubtitle string   `json:"x.subtitle,omitempty"`
+	Access          string   `json:"x.access,omitempty"`
 	Fulltext        string   `json:"x.fulltext,omitempty"`
+	Funders         []string `json:"x.funders,omitempty"`
 	Headings        []string `json:"x.headings,omitempty"`
+	OpenAccess      bool     `json:"x.oa,omitempty"`
+	OAStatus        string   `json:"x.oa_status,omitempty"`
+	Projects        []string `json:"x.projects,omitempty"`
+	Relations       []string `json:"x.relations,omitempty"`
 	Subjects        []string `json:"x.subjects,omitempty"`
 	Type            string   `json:"x.type,omitempty"`
 }
@@ -120,6 +196,54 @@ func NewIntermediateSchema() *IntermediateSchema {
 	return &IntermediateSchema{Version: IntermediateSchemaVersion}
 }
 
+// Merge fills in values from another record. The receiver takes precedence:
+// Strings and dates are only taken from other, if they are empty in the
+// receiver. Slices are combined, values from the receiver come first and
+// values from other are appended, if they are not already present.
+func (is *IntermediateSchema) Merge(other IntermediateSchema) {
+	v, w := reflect.ValueOf(is).Elem(), reflect.ValueOf(other)
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Field(i), w.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			if field.Len() == 0 {
+				field.Set(value)
+			}
+		case reflect.Slice:
+			for j := 0; j < value.Len(); j++ {
+				if !containsValue(field, value.Index(j)) {
+					field.Set(reflect.Append(field, value.Index(j)))
+				}
+			}
+		default:
+			if field.IsZero() {
+				field.Set(value)
+			}
+		}
+	}
+}
+
+// containsValue returns true, if a slice contains a value.
+func containsValue(slice, value reflect.Value) bool {
+	for i := 0; i < slice.Len(); i++ {
+		if reflect.DeepEqual(slice.Index(i).Interface(), value.Interface()) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetSourceID assigns a different source id to a record. The record id
+// carries the source id as well, "ai-<sid>-<id>", its prefix is rewritten
+// accordingly.
+func (is *IntermediateSchema) SetSourceID(sid string) {
+	prefix := fmt.Sprintf("ai-%s-", is.SourceID)
+	if strings.HasPrefix(is.RecordID, prefix) {
+		is.RecordID = fmt.Sprintf("ai-%s-%s", sid, strings.TrimPrefix(is.RecordID, prefix))
+	}
+	is.SourceID = sid
+}
+
 // ISSNList returns a deduplicated list of all ISSN and EISSN.
 func (is *IntermediateSchema) ISSNList() []string {
 	set := make(map[string]struct{})
@@ -133,6 +257,15 @@ func (is *IntermediateSchema) ISSNList() []string {
 	return issns
 }
 
+// ParentTitle returns the title of the containing work, the journal title
+// for articles, the book title for chapters and conference papers.
+func (is *IntermediateSchema) ParentTitle() string {
+	if is.JournalTitle != "" {
+		return is.JournalTitle
+	}
+	return is.BookTitle
+}
+
 // Allfields returns a combination of various fields.
 func (is *IntermediateSchema) Allfields() string {
 	var authors []string
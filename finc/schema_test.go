@@ -0,0 +1,55 @@
+package finc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIntermediateSchemaMerge(t *testing.T) {
+	date := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	is := IntermediateSchema{
+		DOI:      "10.1/x",
+		ISSN:     []string{"1234-5678"},
+		Subjects: []string{"Physics"},
+		Authors:  []Author{{LastName: "Doe"}},
+	}
+	other := IntermediateSchema{
+		DOI:          "10.1/y",
+		ArticleTitle: "Title",
+		Date:         date,
+		ISSN:         []string{"2345-6789", "1234-5678"},
+		Subjects:     []string{"Physics", "Chemistry"},
+		Authors:      []Author{{LastName: "Doe"}, {LastName: "Roe"}},
+	}
+	is.Merge(other)
+
+	want := IntermediateSchema{
+		DOI:          "10.1/x",
+		ArticleTitle: "Title",
+		Date:         date,
+		ISSN:         []string{"1234-5678", "2345-6789"},
+		Subjects:     []string{"Physics", "Chemistry"},
+		Authors:      []Author{{LastName: "Doe"}, {LastName: "Roe"}},
+	}
+	if !reflect.DeepEqual(is, want) {
+		t.Errorf("Merge: got %+v, want %+v", is, want)
+	}
+}
+
+func TestIntermediateSchemaSetSourceID(t *testing.T) {
+	var cases = []struct {
+		sid, rid, want string
+	}{
+		{"172", "ai-172-YTloX18xMjM", "ai-900-YTloX18xMjM"},
+		{"172", "custom-id", "custom-id"},
+		{"", "ai--x", "ai-900-x"},
+	}
+	for _, c := range cases {
+		is := IntermediateSchema{SourceID: c.sid, RecordID: c.rid}
+		is.SetSourceID("900")
+		if is.SourceID != "900" || is.RecordID != c.want {
+			t.Errorf("SetSourceID: got %s %s, want 900 %s", is.SourceID, is.RecordID, c.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package finc
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/miku/span/container"
+)
+
+// SolrSchema holds the field names declared in a SOLR schema.xml or
+// managed-schema, to check exported documents before indexing.
+type SolrSchema struct {
+	Fields *container.StringSet
+	// Dynamic holds dynamic field patterns, like *_txt or attr_*.
+	Dynamic []string
+}
+
+// ReadSolrSchema reads the field and dynamic field declarations from a SOLR
+// schema file. Fields may be declared at the top level or within a fields
+// element, as in older schemas.
+func ReadSolrSchema(r io.Reader) (SolrSchema, error) {
+	s := SolrSchema{Fields: container.NewStringSet()}
+	decoder := xml.NewDecoder(r)
+	for {
+		t, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return s, err
+		}
+		se, ok := t.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var name string
+		for _, attr := range se.Attr {
+			if attr.Name.Local == "name" {
+				name = attr.Value
+			}
+		}
+		switch se.Name.Local {
+		case "field":
+			s.Fields.Add(name)
+		case "dynamicField":
+			s.Dynamic = append(s.Dynamic, name)
+		}
+	}
+	return s, nil
+}
+
+// Declared returns true, if the field is declared or matches a dynamic
+// field. As in SOLR, the pattern may start or end with an asterisk.
+func (s SolrSchema) Declared(name string) bool {
+	if s.Fields.Contains(name) {
+		return true
+	}
+	for _, p := range s.Dynamic {
+		switch {
+		case strings.HasPrefix(p, "*") && strings.HasSuffix(name, p[1:]):
+			return true
+		case strings.HasSuffix(p, "*") && strings.HasPrefix(name, p[:len(p)-1]):
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package finc
+
+import (
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// Ellipsis marks truncated values.
+const Ellipsis = "…"
+
+// Truncater is implemented by export schemas, whose long text fields can be
+// capped, e.g. to prevent oversized SOLR documents.
+type Truncater interface {
+	// Truncate caps fields at max bytes and returns true, if any field was
+	// truncated.
+	Truncate(max int) bool
+}
+
+// TruncateString shortens a string to at most max bytes, including the
+// ellipsis, without splitting a rune. If the ellipsis does not fit, the
+// string is cut without it. Returns true, if the string was truncated.
+func TruncateString(s string, max int) (string, bool) {
+	if len(s) <= max {
+		return s, false
+	}
+	ellipsis, cut := Ellipsis, max-len(Ellipsis)
+	if cut < 0 {
+		ellipsis, cut = "", max
+	}
+	if cut < 0 {
+		cut = 0
+	}
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + ellipsis, true
+}
+
+// FieldCap truncates long fields of export schemas and counts the number of
+// affected records. Safe for concurrent use.
+type FieldCap struct {
+	count int64 // first, so it is 64-bit aligned for atomic access on 32-bit platforms
+	Max   int
+}
+
+// Apply truncates the fields of a schema, if it supports truncation.
+func (c *FieldCap) Apply(s ExportSchema) {
+	t, ok := s.(Truncater)
+	if !ok {
+		return
+	}
+	if t.Truncate(c.Max) {
+		atomic.AddInt64(&c.count, 1)
+	}
+}
+
+// Count returns the number of truncated records so far.
+func (c *FieldCap) Count() int64 {
+	return atomic.LoadInt64(&c.count)
+}
@@ -0,0 +1,80 @@
+package finc
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// URLRaw keeps the URLs of the intermediate schema.
+	URLRaw = "raw"
+	// URLDOIResolver links to the DOI resolver, e.g. https://doi.org/10.1000/1.
+	URLDOIResolver = "doi-resolver"
+	// URLProxyDOIResolver links to the DOI resolver through a proxy, e.g.
+	// http://proxy.example.com/login?url=https://doi.org/10.1000/1.
+	URLProxyDOIResolver = "proxy-prefixed-doi-resolver"
+)
+
+// DOIResolver is the prefix for canonical DOI URLs.
+const DOIResolver = "https://doi.org/"
+
+// doiPrefixes are removed from a DOI, before it is appended to the resolver.
+var doiPrefixes = []string{"https://doi.org/", "http://doi.org/", "http://dx.doi.org/", "https://dx.doi.org/", "doi:"}
+
+// URLBuilder constructs record URLs from the DOI. Records without DOI keep
+// their URLs. If Keep is set, DOI URLs are only used for records without any
+// URL, otherwise they replace existing URLs.
+type URLBuilder struct {
+	Mode  string
+	Proxy string
+	Keep  bool
+}
+
+// NewURLBuilder validates mode and proxy and returns a new URLBuilder.
+func NewURLBuilder(mode, proxy string, keep bool) (URLBuilder, error) {
+	b := URLBuilder{Mode: mode, Proxy: proxy, Keep: keep}
+	switch mode {
+	case "", URLRaw, URLDOIResolver:
+	case URLProxyDOIResolver:
+		if proxy == "" {
+			return b, fmt.Errorf("url mode %s requires a proxy prefix", mode)
+		}
+	default:
+		return b, fmt.Errorf("unknown url mode: %s", mode)
+	}
+	return b, nil
+}
+
+// DOIURL returns the URL for a DOI, according to the mode. It returns the
+// empty string for raw mode or an empty DOI.
+func (b URLBuilder) DOIURL(doi string) string {
+	doi = strings.TrimSpace(doi)
+	for _, prefix := range doiPrefixes {
+		if strings.HasPrefix(strings.ToLower(doi), prefix) {
+			doi = doi[len(prefix):]
+			break
+		}
+	}
+	if doi == "" {
+		return ""
+	}
+	switch b.Mode {
+	case URLDOIResolver:
+		return DOIResolver + doi
+	case URLProxyDOIResolver:
+		return b.Proxy + DOIResolver + doi
+	default:
+		return ""
+	}
+}
+
+// URLs returns the URLs for a record.
+func (b URLBuilder) URLs(is IntermediateSchema) []string {
+	if b.Keep && len(is.URL) > 0 {
+		return is.URL
+	}
+	if u := b.DOIURL(is.DOI); u != "" {
+		return []string{u}
+	}
+	return is.URL
+}
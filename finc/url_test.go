@@ -0,0 +1,60 @@
+package finc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestURLBuilder(t *testing.T) {
+	var tests = []struct {
+		mode  string
+		proxy string
+		keep  bool
+		is    IntermediateSchema
+		urls  []string
+	}{
+		{URLRaw, "", false,
+			IntermediateSchema{DOI: "10.1000/1", URL: []string{"http://example.com/1"}},
+			[]string{"http://example.com/1"}},
+		{URLDOIResolver, "", false,
+			IntermediateSchema{DOI: "10.1000/1", URL: []string{"http://example.com/1"}},
+			[]string{"https://doi.org/10.1000/1"}},
+		{URLDOIResolver, "", false,
+			IntermediateSchema{DOI: "http://dx.doi.org/10.1000/1"},
+			[]string{"https://doi.org/10.1000/1"}},
+		{URLProxyDOIResolver, "http://proxy.example.com/login?url=", false,
+			IntermediateSchema{DOI: "10.1000/1", URL: []string{"http://example.com/1"}},
+			[]string{"http://proxy.example.com/login?url=https://doi.org/10.1000/1"}},
+		{URLDOIResolver, "", true,
+			IntermediateSchema{DOI: "10.1000/1", URL: []string{"http://example.com/1"}},
+			[]string{"http://example.com/1"}},
+		{URLDOIResolver, "", true,
+			IntermediateSchema{DOI: "10.1000/1"},
+			[]string{"https://doi.org/10.1000/1"}},
+		{URLDOIResolver, "", false,
+			IntermediateSchema{URL: []string{"http://example.com/1"}},
+			[]string{"http://example.com/1"}},
+	}
+	for _, tt := range tests {
+		b, err := NewURLBuilder(tt.mode, tt.proxy, tt.keep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := Solr413Schema{URLBuilder: b}
+		if err := s.Convert(tt.is); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(s.URL, tt.urls) {
+			t.Errorf("URL (%s, keep=%v): got %v, want %v", tt.mode, tt.keep, s.URL, tt.urls)
+		}
+	}
+}
+
+func TestNewURLBuilder(t *testing.T) {
+	if _, err := NewURLBuilder(URLProxyDOIResolver, "", false); err == nil {
+		t.Errorf("NewURLBuilder: got nil, want error for missing proxy")
+	}
+	if _, err := NewURLBuilder("x-unknown", "", false); err == nil {
+		t.Errorf("NewURLBuilder: got nil, want error for unknown mode")
+	}
+}
@@ -0,0 +1,71 @@
+package span
+
+import (
+	"sync"
+
+	"github.com/miku/span/finc"
+)
+
+// Coverage classifies a record with respect to holdings.
+type Coverage string
+
+// Coverage categories, see HoldingFilter.Classify.
+const (
+	Covered       Coverage = "covered"
+	Walled        Coverage = "walled"
+	NotCovered    Coverage = "not-covered"
+	NotInHoldings Coverage = "not-in-holdings"
+	NoISSN        Coverage = "no-issn"
+)
+
+// Coverages lists all categories.
+var Coverages = []Coverage{Covered, Walled, NotCovered, NotInHoldings, NoISSN}
+
+// Classify tells, why a record is or is not attached by the filter: it is
+// covered, behind the moving wall, its ISSN is listed but the license does
+// not cover the issue, its ISSN is not listed at all or it has no ISSN. The
+// most favorable category of all ISSNs of the record is returned.
+func (f HoldingFilter) Classify(is finc.IntermediateSchema) Coverage {
+	return f.evaluate(is, true).Coverage()
+}
+
+// FixtureSampler selects up to K records per coverage category, e.g. to
+// build a small, representative corpus for regression tests from a large
+// dump. Safe for concurrent use.
+type FixtureSampler struct {
+	Filter HoldingFilter
+	K      int
+
+	mu     sync.Mutex
+	counts map[Coverage]int
+}
+
+// NewFixtureSampler creates a sampler, that classifies records with the
+// given holding filter.
+func NewFixtureSampler(f HoldingFilter, k int) *FixtureSampler {
+	return &FixtureSampler{Filter: f, K: k, counts: make(map[Coverage]int)}
+}
+
+// Keep returns true, if the record should be part of the sample, which is
+// the case, if fewer than K records of its category have been kept.
+func (s *FixtureSampler) Keep(is finc.IntermediateSchema) bool {
+	c := s.Filter.Classify(is)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.counts[c] >= s.K {
+		return false
+	}
+	s.counts[c]++
+	return true
+}
+
+// Counts returns the number of kept records per category.
+func (s *FixtureSampler) Counts() map[Coverage]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[Coverage]int)
+	for k, v := range s.counts {
+		counts[k] = v
+	}
+	return counts
+}
@@ -0,0 +1,80 @@
+package span
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/miku/span/finc"
+	"github.com/miku/span/holdings"
+)
+
+func TestFixtureSampler(t *testing.T) {
+	holding := testHolding("2000", "2020", "-1Y")
+	table, errs := holdings.ParseHoldings(strings.NewReader(holding))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	f := NewHoldingFilterFromLicenses(table, mustParseDate("2020-06-15"))
+
+	records := []struct {
+		is       finc.IntermediateSchema
+		coverage Coverage
+	}{
+		{finc.IntermediateSchema{RecordID: "1", ISSN: []string{"1234-5678"}, Date: mustParseDate("2010-01-01")}, Covered},
+		{finc.IntermediateSchema{RecordID: "2", ISSN: []string{"1234-5678"}, Date: mustParseDate("2011-01-01")}, Covered},
+		{finc.IntermediateSchema{RecordID: "3", ISSN: []string{"1234-5678"}, Date: mustParseDate("2020-01-01")}, Walled},
+		{finc.IntermediateSchema{RecordID: "4", ISSN: []string{"1234-5678"}, Date: mustParseDate("1990-01-01")}, NotCovered},
+		{finc.IntermediateSchema{RecordID: "5", ISSN: []string{"2345-6789"}, Date: mustParseDate("2010-01-01")}, NotInHoldings},
+		{finc.IntermediateSchema{RecordID: "6", Date: mustParseDate("2010-01-01")}, NoISSN},
+		{finc.IntermediateSchema{RecordID: "7"}, NoISSN},
+	}
+	s := NewFixtureSampler(f, 1)
+	var kept []string
+	for _, r := range records {
+		if c := f.Classify(r.is); c != r.coverage {
+			t.Errorf("HoldingFilter.Classify(%s): got %s, want %s", r.is.RecordID, c, r.coverage)
+		}
+		if s.Keep(r.is) {
+			kept = append(kept, r.is.RecordID)
+		}
+	}
+	if got, want := strings.Join(kept, ","), "1,3,4,5,6"; got != want {
+		t.Errorf("FixtureSampler.Keep: kept %s, want %s", got, want)
+	}
+	for _, c := range Coverages {
+		if s.Counts()[c] != 1 {
+			t.Errorf("FixtureSampler.Counts: got %d for %s, want 1", s.Counts()[c], c)
+		}
+	}
+}
+
+func TestHoldingFilterClassifyPolicies(t *testing.T) {
+	table := holdings.Licenses{
+		"1234-5678": []holdings.License{"2000000000000000:ZZZZZZZZZZZZZZZZ:-1Y"},
+		"2345-6789": []holdings.License{"2000000000000000:ZZZZZZZZZZZZZZZZ:-1Y:free"},
+	}
+	ref := mustParseDate("2022-06-15")
+	var tests = []struct {
+		f        HoldingFilter
+		is       finc.IntermediateSchema
+		coverage Coverage
+	}{
+		{HoldingFilter{Ref: ref, Table: table, FreeIgnoresWall: true},
+			finc.IntermediateSchema{ISSN: []string{"2345-6789"}, Date: mustParseDate("2022-01-01")}, Covered},
+		{HoldingFilter{Ref: ref, Table: table},
+			finc.IntermediateSchema{ISSN: []string{"2345-6789"}, Date: mustParseDate("2022-01-01")}, Walled},
+		{HoldingFilter{Ref: ref, Table: table, FuturePolicy: FutureAttach},
+			finc.IntermediateSchema{ISSN: []string{"1234-5678"}, Date: mustParseDate("2023-01-01")}, Covered},
+		{HoldingFilter{Ref: ref, Table: table, FuturePolicy: FutureDrop},
+			finc.IntermediateSchema{ISSN: []string{"1234-5678"}, Date: mustParseDate("2023-01-01")}, Walled},
+		{HoldingFilter{Ref: ref, Table: table, FuturePolicy: FutureClamp},
+			finc.IntermediateSchema{ISSN: []string{"1234-5678"}, Date: mustParseDate("2023-01-01")}, Walled},
+		{HoldingFilter{Ref: ref, Table: table},
+			finc.IntermediateSchema{ISSN: []string{"3456-7890", "1234-5678"}, Date: mustParseDate("2010-01-01")}, Covered},
+	}
+	for _, tt := range tests {
+		if c := tt.f.Classify(tt.is); c != tt.coverage {
+			t.Errorf("HoldingFilter.Classify(%v, %s): got %s, want %s", tt.is.ISSN, tt.is.Date.Format("2006-01-02"), c, tt.coverage)
+		}
+	}
+}
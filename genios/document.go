@@ -1,9 +1,18 @@
+// Package genios converts Genios (GBI) XML dumps, one Document element per
+// record, into the intermediate schema.
+//
+// The dumps are usually encoded in ISO-8859-1 and contain HTML character
+// entities, sometimes escaped twice. Each Genios database gets its own
+// collection, so filters can select single databases.
 package genios
 
 import (
 	"bufio"
+	"encoding/base64"
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"html"
 	"io"
 	"log"
 	"strings"
@@ -16,8 +25,25 @@ import (
 const (
 	SourceID  = "48"
 	BatchSize = 2000
+	// Collection is the prefix of the collection name of a database.
+	Collection = "Genios"
+	// Format for intermediate schema.
+	Format = "ElectronicArticle"
 )
 
+// cp1252 maps the bytes 0x80 to 0x9f of Windows-1252, which latin-1 labeled
+// files often contain, to runes. Undefined bytes map to themselves.
+var cp1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8d, 'Ž', 0x8f,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9d, 'ž', 'Ÿ',
+}
+
+// languages maps the language names used in the dumps to ISO 639-3.
+var languages = map[string]string{
+	"deutsch": "deu", "german": "deu", "englisch": "eng", "english": "eng",
+	"französisch": "fra", "french": "fra",
+}
+
 type Document struct {
 	ID               string   `xml:"ID,attr"`
 	ISSN             string   `xml:"ISSN"`
@@ -31,11 +57,90 @@ type Document struct {
 	RawAuthors       []string `xml:"Authors>Author"`
 	Language         string   `xml:"Language"`
 	Abstract         string   `xml:"Abstract"`
+
+	collections map[string]string
 }
 
 var RawDateReplacer = strings.NewReplacer(`"`, "", "\n", "", "\t", "")
 
-type Genios struct{}
+// Genios source. Collections maps database codes to collection names, e.g.
+// to group databases into packages. Databases without an entry get a
+// collection named after their code, like "Genios (ZECH)".
+type Genios struct {
+	Collections map[string]string
+}
+
+// ReadCollections reads a JSON object, that maps database codes to
+// collection names.
+func ReadCollections(r io.Reader) (map[string]string, error) {
+	collections := make(map[string]string)
+	if err := json.NewDecoder(r).Decode(&collections); err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
+
+// latin1Reader decodes ISO-8859-1, with Windows-1252 characters in the
+// range, that ISO-8859-1 reserves for control characters.
+type latin1Reader struct {
+	r   io.ByteReader
+	buf []byte
+}
+
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	var n int
+	for n < len(p) {
+		if len(l.buf) > 0 {
+			c := copy(p[n:], l.buf)
+			n += c
+			l.buf = l.buf[c:]
+			continue
+		}
+		b, err := l.r.ReadByte()
+		if err != nil {
+			return n, err
+		}
+		r := rune(b)
+		if b >= 0x80 && b < 0xa0 {
+			r = cp1252[b-0x80]
+		}
+		if r < 0x80 {
+			p[n] = b
+			n++
+			continue
+		}
+		l.buf = []byte(string(r))
+	}
+	return n, nil
+}
+
+// CharsetReader handles the latin-1 encodings of the dumps.
+func CharsetReader(label string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(label) {
+	case "iso-8859-1", "iso8859-1", "latin1", "latin-1", "windows-1252", "cp1252":
+		return &latin1Reader{r: bufio.NewReader(input)}, nil
+	}
+	return nil, fmt.Errorf("genios: unsupported charset: %s", label)
+}
+
+// NewDecoder returns a decoder, that understands latin-1 and HTML character
+// entities.
+func NewDecoder(r io.Reader) *xml.Decoder {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = CharsetReader
+	decoder.Entity = xml.HTMLEntity
+	return decoder
+}
+
+// clean trims a value and resolves entities, that were escaped twice, like
+// &amp;uuml;.
+func clean(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "&") {
+		s = html.UnescapeString(s)
+	}
+	return s
+}
 
 // NewBatch wraps up a new batch for channel com.
 func NewBatch(docs []*Document) span.Batcher {
@@ -56,7 +161,7 @@ func (s Genios) Iterate(r io.Reader) (<-chan interface{}, error) {
 	i := 0
 	var docs []*Document
 	go func() {
-		decoder := xml.NewDecoder(bufio.NewReader(r))
+		decoder := NewDecoder(bufio.NewReader(r))
 		for {
 			t, _ := decoder.Token()
 			if t == nil {
@@ -65,7 +170,7 @@ func (s Genios) Iterate(r io.Reader) (<-chan interface{}, error) {
 			switch se := t.(type) {
 			case xml.StartElement:
 				if se.Name.Local == "Document" {
-					doc := new(Document)
+					doc := &Document{collections: s.Collections}
 					err := decoder.DecodeElement(&doc, &se)
 					if err != nil {
 						log.Fatal(err)
@@ -98,6 +203,37 @@ func (doc Document) URL() string {
 	return fmt.Sprintf("https://www.genios.de/document/%s__%s/", strings.TrimSpace(doc.Source), strings.TrimSpace(doc.ID))
 }
 
+// Collection returns the collection name of the database of a document.
+func (doc Document) Collection() string {
+	code := strings.TrimSpace(doc.Source)
+	if name, ok := doc.collections[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", Collection, code)
+}
+
+// RecordID returns the record id, derived from database code and id.
+func (doc Document) RecordID() string {
+	id := fmt.Sprintf("%s__%s", strings.TrimSpace(doc.Source), strings.TrimSpace(doc.ID))
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(id)))
+	return strings.TrimRight(enc, "=")
+}
+
+// Languages returns the language of the document as ISO 639-3 code.
+func (doc Document) Languages() []string {
+	v := strings.ToLower(strings.TrimSpace(doc.Language))
+	if v == "" || IsNN(v) {
+		return nil
+	}
+	if code, ok := languages[v]; ok {
+		return []string{code}
+	}
+	if code := span.NormalizeLanguage(v); code != "und" {
+		return []string{code}
+	}
+	return nil
+}
+
 func IsNN(s string) bool {
 	return strings.ToLower(strings.TrimSpace(s)) == "n.n."
 }
@@ -107,8 +243,8 @@ func (doc Document) Authors() []string {
 	for _, v := range doc.RawAuthors {
 		fields := strings.Split(v, ";")
 		for _, f := range fields {
-			if !IsNN(f) {
-				authors = append(authors, strings.TrimSpace(f))
+			if f = clean(f); f != "" && !IsNN(f) {
+				authors = append(authors, f)
 			}
 		}
 	}
@@ -119,6 +255,18 @@ func (doc Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	var err error
 	output := finc.NewIntermediateSchema()
 
+	if strings.TrimSpace(doc.ID) == "" || strings.TrimSpace(doc.Source) == "" {
+		return output, span.Skip{Reason: "genios: document without database or id"}
+	}
+	output.SourceID = SourceID
+	output.RecordID = doc.RecordID()
+	output.MegaCollection = doc.Collection()
+	output.Database = strings.TrimSpace(doc.Source)
+	output.Format = Format
+	output.Genre = "article"
+	output.RefType = "JOUR"
+	output.Languages = doc.Languages()
+
 	for _, author := range doc.Authors() {
 		output.Authors = append(output.Authors, finc.Author{Name: author})
 	}
@@ -126,11 +274,11 @@ func (doc Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	output.URL = append(output.URL, doc.URL())
 
 	if !IsNN(doc.Abstract) {
-		output.Abstract = strings.TrimSpace(doc.Abstract)
+		output.Abstract = clean(doc.Abstract)
 	}
 
 	if !IsNN(doc.Title) {
-		output.ArticleTitle = strings.TrimSpace(doc.Title)
+		output.ArticleTitle = clean(doc.Title)
 	}
 
 	if !IsNN(doc.ISSN) {
@@ -142,7 +290,7 @@ func (doc Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	}
 
 	if !IsNN(doc.PublicationTitle) {
-		output.JournalTitle = strings.TrimSpace(doc.PublicationTitle)
+		output.JournalTitle = clean(doc.PublicationTitle)
 	}
 
 	if !IsNN(doc.Volume) {
@@ -0,0 +1,74 @@
+package genios
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+	"github.com/miku/span/internal/spantest"
+)
+
+// example is encoded in latin-1 and contains an HTML entity, a twice
+// escaped entity and a Windows-1252 dash.
+var example = []byte("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n" +
+	"<Documents>\n" +
+	"<Document ID=\"zech_0001\">\n" +
+	"<Source>ZECH</Source>\n" +
+	"<ISSN>0000-0019</ISSN>\n" +
+	"<Publication-Title>Zeitschrift f\xfcr Beispiele</Publication-Title>\n" +
+	"<Title>Gr&uuml;nde und Stra&amp;szlig;en \x96 ein \xdcberblick</Title>\n" +
+	"<Year>2016</Year>\n" +
+	"<Date>\"20160302\"</Date>\n" +
+	"<Volume>12</Volume>\n" +
+	"<Issue>n.n.</Issue>\n" +
+	"<Authors><Author>M\xfcller, Hans; n.n.</Author></Authors>\n" +
+	"<Language>Deutsch</Language>\n" +
+	"<Abstract>n.n.</Abstract>\n" +
+	"</Document>\n" +
+	"<Document ID=\"wuw_0002\">\n" +
+	"<Source>WUW</Source>\n" +
+	"<Title>Ohne Datum</Title>\n" +
+	"</Document>\n" +
+	"</Documents>\n")
+
+func TestGenios(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, Genios{}, bytes.NewReader(example))
+	if len(results) != 2 {
+		t.Fatalf("got %d records, want 2", len(results))
+	}
+	if errs[0] != nil {
+		t.Fatal(errs[0])
+	}
+	if _, ok := errs[1].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for a document without date", errs[1])
+	}
+	is := results[0]
+	if is.ArticleTitle != "Gründe und Straßen – ein Überblick" {
+		t.Errorf("got title %q", is.ArticleTitle)
+	}
+	if is.JournalTitle != "Zeitschrift für Beispiele" || is.Issue != "" || is.Abstract != "" {
+		t.Errorf("got journal %q, issue %q, abstract %q", is.JournalTitle, is.Issue, is.Abstract)
+	}
+	if !reflect.DeepEqual(is.Authors, []finc.Author{{Name: "Müller, Hans"}}) {
+		t.Errorf("got authors %v", is.Authors)
+	}
+	if is.MegaCollection != "Genios (ZECH)" || is.Database != "ZECH" || is.SourceID != SourceID || is.RecordID == "" {
+		t.Errorf("got collection %q, database %q, source %q, record id %q", is.MegaCollection, is.Database, is.SourceID, is.RecordID)
+	}
+	if !reflect.DeepEqual(is.Languages, []string{"deu"}) || is.Date.Format("2006-01-02") != "2016-03-02" {
+		t.Errorf("got languages %v, date %v", is.Languages, is.Date)
+	}
+}
+
+func TestCollections(t *testing.T) {
+	collections, err := ReadCollections(bytes.NewReader([]byte(`{"ZECH": "Genios (Recht)"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, _ := spantest.ConvertAll(t, Genios{Collections: collections}, bytes.NewReader(example))
+	if results[0].MegaCollection != "Genios (Recht)" {
+		t.Errorf("got collection %q, want Genios (Recht)", results[0].MegaCollection)
+	}
+}
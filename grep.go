@@ -0,0 +1,55 @@
+package span
+
+import (
+	"bytes"
+
+	"github.com/miku/span/container"
+	"github.com/miku/span/finc"
+)
+
+// Grep selects records by ISSN or DOI, e.g. to look at a single journal in a
+// large dump. A record is selected, if any of its ISSNs or its DOI is given.
+type Grep struct {
+	ISSN *container.StringSet
+	DOI  *container.StringSet
+
+	// needles are the ISSNs and DOIs as bytes, for MayMatch
+	needles [][]byte
+}
+
+// NewGrep creates a selection for the given ISSNs and DOIs.
+func NewGrep(issns, dois []string) Grep {
+	g := Grep{ISSN: container.NewStringSet(issns...), DOI: container.NewStringSet(dois...)}
+	for _, v := range append(g.ISSN.Values(), g.DOI.Values()...) {
+		g.needles = append(g.needles, []byte(v))
+	}
+	return g
+}
+
+// MayMatch is a cheap check on the serialized record. If it returns false,
+// the record cannot match and need not be decoded. Does not allocate. A Grep,
+// that was not created with NewGrep, may match any record.
+func (g Grep) MayMatch(line []byte) bool {
+	if g.needles == nil {
+		return true
+	}
+	for _, v := range g.needles {
+		if bytes.Contains(line, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns true, if the record has one of the ISSNs or DOIs.
+func (g Grep) Match(is finc.IntermediateSchema) bool {
+	if g.DOI.Contains(is.DOI) {
+		return true
+	}
+	for _, issn := range is.ISSNList() {
+		if g.ISSN.Contains(issn) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package span
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/miku/span/finc"
+)
+
+func TestGrep(t *testing.T) {
+	lines := []string{
+		`{"finc.record_id": "1", "rft.issn": ["1234-5678"]}`,
+		`{"finc.record_id": "2", "rft.eissn": ["1234-5678"]}`,
+		`{"finc.record_id": "3", "rft.issn": ["2345-6789"]}`,
+		`{"finc.record_id": "4", "doi": "10.1/abc"}`,
+		`{"finc.record_id": "5", "doi": "10.1/abcd"}`,
+		`{"finc.record_id": "6", "rft.atitle": "On 1234-5678"}`,
+	}
+	g := NewGrep([]string{"1234-5678"}, []string{"10.1/abc"})
+	var ids []string
+	for _, line := range lines {
+		if !g.MayMatch([]byte(line)) {
+			continue
+		}
+		var is finc.IntermediateSchema
+		if err := json.Unmarshal([]byte(line), &is); err != nil {
+			t.Fatal(err)
+		}
+		if g.Match(is) {
+			ids = append(ids, is.RecordID)
+		}
+	}
+	if want := []string{"1", "2", "4"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Grep: got %v, want %v", ids, want)
+	}
+	line := []byte(lines[2])
+	if g.MayMatch(line) {
+		t.Errorf("Grep.MayMatch: got true for %s", lines[2])
+	}
+	if n := testing.AllocsPerRun(100, func() { g.MayMatch(line) }); n > 0 {
+		t.Errorf("Grep.MayMatch: got %v allocations, want none", n)
+	}
+}
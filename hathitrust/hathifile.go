@@ -0,0 +1,271 @@
+// Package hathitrust converts the tab separated Hathifiles, one digitized
+// volume per line, into the intermediate schema. The rights code of a volume
+// is mapped to an access level, which can be used with the access filter.
+package hathitrust
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// SourceID for internal bookkeeping.
+	SourceID = "170"
+	// Collection name.
+	Collection = "HathiTrust"
+	// BatchSize for grouped channel transport.
+	BatchSize = 10000
+)
+
+// Access levels of a volume.
+const (
+	// AccessOpen volumes can be viewed from anywhere.
+	AccessOpen = "open"
+	// AccessUS volumes can be viewed from the United States only.
+	AccessUS = "us"
+	// AccessNonUS volumes can be viewed from outside the United States only.
+	AccessNonUS = "non-us"
+	// AccessRestricted volumes are search only or not available at all.
+	AccessRestricted = "restricted"
+)
+
+// rightsAccess maps rights codes to access levels. Creative Commons codes
+// are open, all other codes are restricted.
+var rightsAccess = map[string]string{
+	"pd":        AccessOpen,
+	"pdworld":   AccessOpen,
+	"ic-world":  AccessOpen,
+	"und-world": AccessOpen,
+	"pdus":      AccessUS,
+	"icus":      AccessNonUS,
+}
+
+// bibFormats maps the bibliographic format of the catalog record to format,
+// genre and RIS type.
+var bibFormats = map[string][3]string{
+	"BK": {"eBook", "book", "EBOOK"},
+	"SE": {"ElectronicResourceRemoteAccess", "document", "SER"},
+	"MP": {"ElectronicResourceRemoteAccess", "document", "MAP"},
+	"MU": {"ElectronicResourceRemoteAccess", "document", "MUSIC"},
+}
+
+var (
+	// lifeDates matches trailing life dates of a name, e.g. ", 1835-1910.".
+	lifeDates = regexp.MustCompile(`,?\s*(b\.\s*)?[0-9]{4}-?([0-9]{4})?\??\.?$`)
+	// isbnToken matches the leading ISBN of a value like "0123456789 (pbk.)".
+	isbnToken = regexp.MustCompile(`^[0-9X-]+`)
+)
+
+// HathiTrust source.
+type HathiTrust struct{}
+
+// Volume is a single line of a Hathifile. Newer files have more columns,
+// missing columns are empty.
+type Volume struct {
+	HTID        string
+	Access      string
+	Rights      string
+	BibKey      string
+	Description string
+	ISBN        string
+	ISSN        string
+	Title       string
+	Imprint     string
+	RightsDate  string
+	PubPlace    string
+	Language    string
+	BibFormat   string
+	Author      string
+}
+
+// ParseVolume parses a tab separated line.
+func ParseVolume(line string) *Volume {
+	fields := strings.Split(strings.TrimRight(line, "\r\n"), "\t")
+	field := func(i int) string {
+		if i < len(fields) {
+			return strings.TrimSpace(fields[i])
+		}
+		return ""
+	}
+	return &Volume{
+		HTID:        field(0),
+		Access:      field(1),
+		Rights:      field(2),
+		BibKey:      field(3),
+		Description: field(4),
+		ISBN:        field(8),
+		ISSN:        field(9),
+		Title:       field(11),
+		Imprint:     field(12),
+		RightsDate:  field(16),
+		PubPlace:    field(17),
+		Language:    field(18),
+		BibFormat:   field(19),
+		Author:      field(25),
+	}
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(lines []string) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			return ParseVolume(s.(string)), nil
+		}, Items: make([]interface{}, len(lines))}
+	for i, line := range lines {
+		batch.Items[i] = line
+	}
+	return batch
+}
+
+// Iterate emits Converter elements, one per line.
+func (s HathiTrust) Iterate(r io.Reader) (<-chan interface{}, error) {
+	ch := make(chan interface{})
+	reader := bufio.NewReader(r)
+	var lines []string
+	go func() {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil && err != io.EOF {
+				log.Fatal(err)
+			}
+			if strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+			if len(lines) == BatchSize {
+				ch <- NewBatch(lines)
+				lines = nil
+			}
+			if err == io.EOF {
+				break
+			}
+		}
+		ch <- NewBatch(lines)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// AccessLevel returns the access level of the rights code.
+func (v *Volume) AccessLevel() string {
+	if level, ok := rightsAccess[v.Rights]; ok {
+		return level
+	}
+	if strings.HasPrefix(v.Rights, "cc-") {
+		return AccessOpen
+	}
+	return AccessRestricted
+}
+
+// Date returns the first day of the year, that was used to determine the
+// rights. Unknown years are given as 9999.
+func (v *Volume) Date() (time.Time, error) {
+	if v.RightsDate == "" || v.RightsDate == "9999" {
+		return time.Time{}, fmt.Errorf("hathitrust: %s: no usable date", v.HTID)
+	}
+	return time.Parse("2006", v.RightsDate)
+}
+
+// CleanTitle returns the title without statement of responsibility.
+func (v *Volume) CleanTitle() string {
+	title := v.Title
+	if i := strings.Index(title, " / "); i > 0 {
+		title = title[:i]
+	}
+	return strings.TrimSpace(strings.TrimRight(title, " ./:;,"))
+}
+
+// Publisher returns the publisher from the imprint, like "New York :
+// Macmillan, 1923.".
+func (v *Volume) Publisher() string {
+	parts := strings.SplitN(v.Imprint, " : ", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	publisher := strings.TrimSuffix(strings.TrimSpace(parts[1]), ".")
+	if i := strings.LastIndex(publisher, ","); i > 0 {
+		publisher = publisher[:i]
+	}
+	return strings.Trim(publisher, "[] ")
+}
+
+// AuthorValue returns the main entry without life dates.
+func (v *Volume) AuthorValue() (finc.Author, bool) {
+	name := strings.TrimSpace(lifeDates.ReplaceAllString(v.Author, ""))
+	name = strings.TrimRight(name, " ,.")
+	if name == "" {
+		return finc.Author{}, false
+	}
+	if parts := strings.SplitN(name, ",", 2); len(parts) == 2 {
+		return finc.Author{LastName: strings.TrimSpace(parts[0]), FirstName: strings.TrimSpace(parts[1])}, true
+	}
+	return finc.Author{Name: name}, true
+}
+
+// ToIntermediateSchema converts a volume. Volumes without id, title or year
+// are skipped. Restricted volumes are kept, but marked as such.
+func (v *Volume) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	if v.HTID == "" || v.Title == "" {
+		return output, span.Skip{Reason: fmt.Sprintf("hathitrust: %q: missing id or title", v.HTID)}
+	}
+	output.Date, err = v.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(v.HTID)))
+	output.SourceID = SourceID
+	output.RecordID = strings.TrimRight(enc, "=")
+	output.MegaCollection = Collection
+	kind, ok := bibFormats[v.BibFormat]
+	if !ok {
+		kind = [3]string{"ElectronicResourceRemoteAccess", "unknown", "GEN"}
+	}
+	output.Format, output.Genre, output.RefType = kind[0], kind[1], kind[2]
+
+	output.Access = v.AccessLevel()
+	output.OpenAccess = output.Access == AccessOpen
+	output.ArticleTitle = v.CleanTitle()
+	if output.Genre == "book" {
+		output.BookTitle = output.ArticleTitle
+	}
+	if a, ok := v.AuthorValue(); ok {
+		output.Authors = append(output.Authors, a)
+	}
+	output.Volume = v.Description
+	if p := v.Publisher(); p != "" {
+		output.Publishers = append(output.Publishers, p)
+	}
+	if parts := strings.SplitN(v.Imprint, " : ", 2); len(parts) == 2 {
+		output.Places = append(output.Places, strings.Trim(parts[0], "[] "))
+	}
+	if v.Language != "" {
+		output.Languages = append(output.Languages, span.NormalizeLanguage(v.Language))
+	}
+	for _, s := range strings.Split(v.ISBN, ",") {
+		if isbn := isbnToken.FindString(strings.TrimSpace(s)); isbn != "" {
+			output.ISBN = append(output.ISBN, isbn)
+		}
+	}
+	for _, s := range strings.Split(v.ISSN, ",") {
+		if issn := span.NormalizeISSN(s); issn != "" {
+			output.ISSN = append(output.ISSN, issn)
+		}
+	}
+	output.URL = append(output.URL, "https://hdl.handle.net/2027/"+v.HTID)
+	if v.BibKey != "" {
+		output.URL = append(output.URL, "https://catalog.hathitrust.org/Record/"+v.BibKey)
+	}
+	return output, nil
+}
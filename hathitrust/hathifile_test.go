@@ -0,0 +1,96 @@
+package hathitrust
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+	"github.com/miku/span/internal/spantest"
+)
+
+// examples are Hathifile lines, split into columns.
+var examples = [][]string{
+	{"mdp.39015000000001", "allow", "pd", "001000001", "v.2", "MIU", "990000001", "12345", "0123456789 (pbk.),9780123456786", "", "a12345",
+		"The adventures of Tom Sawyer / by Mark Twain.", "New York : Harper, 1903.", "bib", "2008-06-01 12:00:00", "0", "1903", "nyu", "eng", "BK",
+		"MIU", "umich", "umich", "google", "google", "Twain, Mark, 1835-1910."},
+	{"uc1.b0000000002", "deny", "pdus", "002000002", "", "CU", "990000002", "", "", "00280836", "",
+		"Nature.", "London : Macmillan.", "bib", "2010-01-01 12:00:00", "0", "1925", "enk", "eng", "SE"},
+	{"coo.31924000000003", "deny", "ic", "003000003", "", "COO", "990000003", "", "", "", "",
+		"A modern book.", "Ithaca : Cornell, 1990.", "bib", "2012-01-01 12:00:00", "0", "1990", "nyu", "eng", "BK",
+		"COO", "cornell", "cornell", "google", "google", "Example Society."},
+	{"nyp.33433000000004", "allow", "cc-by-4.0", "004000004", "", "NYP", "990000004", "", "", "", "",
+		"Undated.", "", "bib", "2012-01-01 12:00:00", "0", "9999", "nyu", "eng", "BK"},
+}
+
+// hathifile returns the examples as tab separated lines.
+func hathifile() string {
+	var lines []string
+	for _, fields := range examples {
+		lines = append(lines, strings.Join(fields, "\t"))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestHathiTrust(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, HathiTrust{}, strings.NewReader(hathifile()))
+	if len(results) != 4 {
+		t.Fatalf("got %d volumes, want 4", len(results))
+	}
+	for _, err := range errs[:3] {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, ok := errs[3].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for an undated volume", errs[3])
+	}
+	is := results[0]
+	if is.ArticleTitle != "The adventures of Tom Sawyer" || is.Volume != "v.2" || is.Date.Year() != 1903 {
+		t.Errorf("got title %q, volume %q, date %v", is.ArticleTitle, is.Volume, is.Date)
+	}
+	if want := []finc.Author{{LastName: "Twain", FirstName: "Mark"}}; !reflect.DeepEqual(is.Authors, want) {
+		t.Errorf("got authors %v, want %v", is.Authors, want)
+	}
+	if !reflect.DeepEqual(is.ISBN, []string{"0123456789", "9780123456786"}) || is.Publishers[0] != "Harper" || is.Places[0] != "New York" {
+		t.Errorf("got ISBN %v, publishers %v, places %v", is.ISBN, is.Publishers, is.Places)
+	}
+	if is.URL[0] != "https://hdl.handle.net/2027/mdp.39015000000001" || !strings.HasPrefix(is.RecordID, "ai-"+SourceID+"-") {
+		t.Errorf("got URL %v, record id %s", is.URL, is.RecordID)
+	}
+	serial := results[1]
+	if serial.RefType != "SER" || !reflect.DeepEqual(serial.ISSN, []string{"0028-0836"}) || serial.Publishers[0] != "Macmillan" {
+		t.Errorf("got RIS type %s, ISSN %v, publishers %v", serial.RefType, serial.ISSN, serial.Publishers)
+	}
+	if results[2].Authors[0].Name != "Example Society" {
+		t.Errorf("got authors %v, want corporate name", results[2].Authors)
+	}
+	for i, want := range []string{AccessOpen, AccessUS, AccessRestricted} {
+		if results[i].Access != want || results[i].OpenAccess != (want == AccessOpen) {
+			t.Errorf("%d: got access %q, open access %v, want %q", i, results[i].Access, results[i].OpenAccess, want)
+		}
+	}
+}
+
+func TestAccessLevel(t *testing.T) {
+	var tests = []struct {
+		rights string
+		access string
+	}{
+		{"pd", AccessOpen},
+		{"cc-by-nc-sa-4.0", AccessOpen},
+		{"ic-world", AccessOpen},
+		{"pdus", AccessUS},
+		{"icus", AccessNonUS},
+		{"ic", AccessRestricted},
+		{"und", AccessRestricted},
+		{"", AccessRestricted},
+	}
+	for _, tt := range tests {
+		v := Volume{Rights: tt.rights}
+		if access := v.AccessLevel(); access != tt.access {
+			t.Errorf("AccessLevel(%q): got %q, want %q", tt.rights, access, tt.access)
+		}
+	}
+}
@@ -2,8 +2,14 @@ package span
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
 	"html"
 	"io"
+	"math"
 	"strings"
 )
 
@@ -12,14 +18,200 @@ func UnescapeTrim(s string) string {
 	return strings.TrimSpace(html.UnescapeString(s))
 }
 
-// ByteSink is a fan in writer for a byte channel.
-// A newline is appended after each object.
-func ByteSink(w io.Writer, out chan []byte, done chan bool) {
-	f := bufio.NewWriter(w)
+// SinkPolicy decides, what happens, if one of the writers of a MultiSink
+// fails, e.g. with a broken pipe.
+type SinkPolicy int
+
+const (
+	// AbortOnError stops writing to all writers after the first error.
+	AbortOnError SinkPolicy = iota
+	// DropOnError stops writing to the failing writer only.
+	DropOnError
+)
+
+// MultiSink is a Sink, that copies each object to a number of writers, e.g.
+// stdout and a file, and counts the objects. Each writer is buffered and
+// flushed separately. Objects are newline terminated or, if Delimited is set,
+// prefixed with their length as varint.
+type MultiSink struct {
+	Delimited bool
+	Policy    SinkPolicy
+
+	writers []*bufio.Writer
+	errs    []error
+	records int64
+	bytes   int64
+}
+
+// NewMultiSink creates a sink for the given writers.
+func NewMultiSink(writers ...io.Writer) *MultiSink {
+	s := &MultiSink{errs: make([]error, len(writers))}
+	for _, w := range writers {
+		s.writers = append(s.writers, bufio.NewWriter(w))
+	}
+	return s
+}
+
+// write writes a single object to a writer.
+func (s *MultiSink) write(w *bufio.Writer, b []byte) error {
+	if s.Delimited {
+		buf := make([]byte, binary.MaxVarintLen64)
+		n := binary.PutUvarint(buf, uint64(len(b)))
+		if _, err := w.Write(buf[:n]); err != nil {
+			return err
+		}
+		_, err := w.Write(b)
+		return err
+	}
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	return w.WriteByte('\n')
+}
+
+// Run writes all objects from out and flushes the writers. Can be called
+// again with a new channel, counts accumulate. After an error, the channel is
+// still drained, so producers do not block.
+func (s *MultiSink) Run(out chan []byte, done chan bool) {
 	for b := range out {
-		f.Write(b[:])
-		f.Write([]byte("\n"))
+		s.records++
+		s.bytes += int64(len(b))
+		for i, w := range s.writers {
+			if s.Err() != nil && s.Policy == AbortOnError {
+				break
+			}
+			if s.errs[i] != nil {
+				continue
+			}
+			s.errs[i] = s.write(w, b)
+		}
+	}
+	for i, w := range s.writers {
+		if s.errs[i] == nil {
+			s.errs[i] = w.Flush()
+		}
 	}
-	f.Flush()
 	done <- true
 }
+
+// Errors returns the error of each writer, nil for writers without errors.
+func (s *MultiSink) Errors() []error {
+	return s.errs
+}
+
+// Err returns the first error of any writer, if the policy is AbortOnError,
+// otherwise only, if all writers failed.
+func (s *MultiSink) Err() error {
+	var first error
+	var failed int
+	for _, err := range s.errs {
+		if err != nil {
+			failed++
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	if s.Policy == AbortOnError || failed == len(s.errs) {
+		return first
+	}
+	return nil
+}
+
+// Records returns the number of objects received.
+func (s *MultiSink) Records() int64 {
+	return s.records
+}
+
+// Bytes returns the number of bytes received, without framing.
+func (s *MultiSink) Bytes() int64 {
+	return s.bytes
+}
+
+// Dispatch assigns lines to batches and batches to workers by a stable
+// function of the zero-based line number, so a given line is always
+// processed in the same batch by the same worker.
+type Dispatch struct {
+	BatchSize int
+	Workers   int
+}
+
+// Batch returns the batch index of a line.
+func (d Dispatch) Batch(line int) int {
+	return line / d.BatchSize
+}
+
+// Worker returns the worker index for a batch.
+func (d Dispatch) Worker(batch int) int {
+	return batch % d.Workers
+}
+
+// MaxLineErrors is the number of examples ScanNDJSON keeps.
+const MaxLineErrors = 10
+
+// LineError describes an invalid line.
+type LineError struct {
+	Line int
+	Text string
+}
+
+// Error returns the line number and the beginning of the line.
+func (e LineError) Error() string {
+	text := e.Text
+	if len(text) > 80 {
+		text = text[:80] + "..."
+	}
+	return fmt.Sprintf("line %d: invalid JSON: %s", e.Line, text)
+}
+
+// ScanNDJSON checks the syntax of newline delimited JSON, without decoding
+// into any type. Returns the number of non-empty lines, the number of invalid
+// lines and up to MaxLineErrors of the first invalid lines.
+func ScanNDJSON(r io.Reader) (total, bad int, examples []LineError, err error) {
+	br := bufio.NewReader(r)
+	var i int
+	for {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return total, bad, examples, err
+		}
+		i++
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		total++
+		if !json.Valid(line) {
+			bad++
+			if len(examples) < MaxLineErrors {
+				examples = append(examples, LineError{Line: i, Text: string(bytes.TrimSpace(line))})
+			}
+		}
+	}
+	return total, bad, examples, nil
+}
+
+// Sampler decides, whether a record is part of a sample. The decision only
+// depends on seed and record id, so samples are stable across runs and
+// independent of the order, in which records are processed.
+type Sampler struct {
+	Rate float64
+	Seed int64
+}
+
+// Sample returns true, if the record with the given id should be emitted.
+func (s Sampler) Sample(id string) bool {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d:%s", s.Seed, id)
+	return float64(mix64(h.Sum64()))/float64(math.MaxUint64) < s.Rate
+}
+
+// mix64 spreads the bits of a hash value (splitmix64 finalizer), since FNV
+// alone is not uniform enough for similar inputs.
+func mix64(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
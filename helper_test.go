@@ -1,6 +1,13 @@
 package span
 
-import "testing"
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
 
 func TestUnescapeTrim(t *testing.T) {
 	var tests = []struct {
@@ -18,3 +25,114 @@ func TestUnescapeTrim(t *testing.T) {
 		}
 	}
 }
+
+func TestScanNDJSON(t *testing.T) {
+	input := `{"a": 1}
+{"a": 2
+
+[1, 2, 3]
+not json
+{"a": "b"}`
+	total, bad, examples, err := ScanNDJSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 5 || bad != 2 {
+		t.Errorf("ScanNDJSON: got %d total, %d bad, want 5, 2", total, bad)
+	}
+	if len(examples) != 2 || examples[0].Line != 2 || examples[1].Line != 5 {
+		t.Errorf("ScanNDJSON: got examples %v, want lines 2 and 5", examples)
+	}
+}
+
+func TestSampler(t *testing.T) {
+	sample := func(s Sampler) (ids []string) {
+		for i := 0; i < 10000; i++ {
+			id := fmt.Sprintf("ai-49-%d", i)
+			if s.Sample(id) {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+	first := sample(Sampler{Rate: 0.1, Seed: 42})
+	second := sample(Sampler{Rate: 0.1, Seed: 42})
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Sampler: samples with the same seed differ")
+	}
+	if len(first) < 900 || len(first) > 1100 {
+		t.Errorf("Sampler: got %d of 10000 records, want about 1000", len(first))
+	}
+	if other := sample(Sampler{Rate: 0.1, Seed: 43}); reflect.DeepEqual(first, other) {
+		t.Errorf("Sampler: samples with different seeds are equal")
+	}
+}
+
+// failingWriter fails on every write.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestMultiSink(t *testing.T) {
+	run := func(s *MultiSink, records ...string) {
+		out := make(chan []byte)
+		done := make(chan bool)
+		go s.Run(out, done)
+		for _, r := range records {
+			out <- []byte(r)
+		}
+		close(out)
+		<-done
+	}
+
+	var a, b bytes.Buffer
+	s := NewMultiSink(&a, &b)
+	run(s, `{"id": 1}`, `{"id": 2}`)
+	run(s, `{"id": 3}`)
+	if a.String() != "{\"id\": 1}\n{\"id\": 2}\n{\"id\": 3}\n" || a.String() != b.String() {
+		t.Errorf("MultiSink: got %q and %q, want identical output", a.String(), b.String())
+	}
+	if s.Records() != 3 || s.Bytes() != 27 || s.Err() != nil {
+		t.Errorf("MultiSink: got %d records, %d bytes, err %v, want 3, 27, nil", s.Records(), s.Bytes(), s.Err())
+	}
+
+	var c bytes.Buffer
+	s = NewMultiSink(failingWriter{}, &c)
+	s.Policy = DropOnError
+	run(s, "a", "b")
+	if c.String() != "a\nb\n" || s.Err() != nil || s.Errors()[0] == nil {
+		t.Errorf("MultiSink with DropOnError: got %q, err %v, errors %v", c.String(), s.Err(), s.Errors())
+	}
+
+	s = NewMultiSink(failingWriter{}, &c)
+	run(s, "a", "b")
+	if s.Err() == nil || s.Records() != 2 {
+		t.Errorf("MultiSink with AbortOnError: got err %v, %d records, want error and 2 records", s.Err(), s.Records())
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	d := Dispatch{BatchSize: 100, Workers: 4}
+	var tests = []struct {
+		line   int
+		batch  int
+		worker int
+	}{
+		{0, 0, 0},
+		{99, 0, 0},
+		{100, 1, 1},
+		{450, 4, 0},
+		{12345, 123, 3},
+	}
+	for run := 0; run < 3; run++ {
+		for _, tt := range tests {
+			batch := d.Batch(tt.line)
+			if batch != tt.batch || d.Worker(batch) != tt.worker {
+				t.Errorf("Dispatch(%d): got batch %d, worker %d, want %d, %d",
+					tt.line, batch, d.Worker(batch), tt.batch, tt.worker)
+			}
+		}
+	}
+}
@@ -2,6 +2,7 @@ package holdings
 
 import (
 	"bufio"
+	"crypto/sha1"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -14,11 +15,6 @@ import (
 )
 
 const (
-	// Inaccurate but sufficient helpers for unwrapping delay values.
-	day   = 24 * time.Hour
-	month = 30 * day
-	year  = 12 * month
-
 	// LowDatum16 represents a lowest datum for unspecified start dates.
 	// The format is YYYYvvvvvviiiiii (year-volume-issue, zero-padded).
 	LowDatum16 = "0000000000000000"
@@ -36,6 +32,10 @@ const (
 	// MaxVolume is the largest issue number we can sensibly handle.
 	MaxIssue = "999999"
 
+	// StatusFree marks entitlements, that grant access independent of a
+	// subscription, e.g. open access.
+	StatusFree = "free"
+
 	// emptyLicense
 	emptyLicense = License("")
 )
@@ -43,14 +43,28 @@ const (
 // delayPattern is how moving walls are expressed in OVID.
 var delayPattern = regexp.MustCompile(`^([-+]\d+)(M|Y)$`)
 
+// Errors returned by this package. Errors with more context wrap one of
+// these, so they can be distinguished with errors.Is.
 var (
-	errUnknownUnit   = errors.New("unknown unit")
-	errUnknownFormat = errors.New("unknown format")
-	errDelayMismatch = errors.New("delay mismatch")
-	errInvalidYear   = errors.New("invalid year")
-	errVolumeTooBig  = errors.New("volume number too big")
-	errIssueTooBig   = errors.New("issue number too big")
-	errInvalidRange  = errors.New("invalid range in holdings file")
+	// ErrUnknownUnit is returned for delays with units other than M and Y.
+	ErrUnknownUnit = errors.New("unknown unit")
+	// ErrUnknownFormat is returned for malformed delays.
+	ErrUnknownFormat = errors.New("unknown format")
+	// ErrDelayMismatch describes differing start and end delays of an
+	// entitlement. Entitlements are not rejected for it, the start delay is used.
+	ErrDelayMismatch = errors.New("delay mismatch")
+	// ErrInvalidYear is returned for years with more than four digits.
+	ErrInvalidYear = errors.New("invalid year")
+	// ErrVolumeTooBig is returned for volume numbers longer than MaxVolume.
+	ErrVolumeTooBig = errors.New("volume number too big")
+	// ErrIssueTooBig is returned for issue numbers longer than MaxIssue.
+	ErrIssueTooBig = errors.New("issue number too big")
+	// ErrReversedRange is returned, if the end of an entitlement lies before its start.
+	ErrReversedRange = errors.New("invalid range in holdings file")
+	// ErrParse is returned, if the holdings file is not well-formed.
+	ErrParse = errors.New("cannot parse holdings file")
+	// ErrInvalidLicense is returned for license strings not of the form from:to:delay.
+	ErrInvalidLicense = errors.New("invalid license")
 )
 
 // ISSNPattern is the canonical form of an ISSN.
@@ -66,6 +80,20 @@ type Holding struct {
 	Entitlements []Entitlement `xml:"entitlements>entitlement" json:"entitlements"`
 }
 
+// Key returns a stable identity for a holding, that does not depend on its
+// ISSNs, which can change between file versions. The EZB id is used, if
+// present, otherwise a hash of the normalized title and publishers.
+func (h Holding) Key() string {
+	if h.EZBID != 0 {
+		return fmt.Sprintf("ezb:%d", h.EZBID)
+	}
+	normalize := func(s string) string {
+		return strings.ToLower(strings.Join(strings.Fields(s), " "))
+	}
+	sum := sha1.Sum([]byte(normalize(h.Title) + "\x00" + normalize(h.Publishers)))
+	return fmt.Sprintf("sha1:%x", sum)
+}
+
 // Entitlement holds a single OVID entitlement.
 type Entitlement struct {
 	Status     string `xml:"status,attr" json:"status"`
@@ -81,13 +109,45 @@ type Entitlement struct {
 	ToDelay    string `xml:"end>delay" json:"to-delay"`
 }
 
+// Delay is a moving wall expressed in calendar units.
+type Delay struct {
+	Years  int
+	Months int
+}
+
+// String returns the delay in OVID notation, e.g. -2Y or -6M, or 0 for no delay.
+func (d Delay) String() string {
+	switch {
+	case d.Years == 0 && d.Months == 0:
+		return "0"
+	case d.Months == 0:
+		return fmt.Sprintf("%dY", d.Years)
+	default:
+		return fmt.Sprintf("%dM", 12*d.Years+d.Months)
+	}
+}
+
+// Shift moves a date by the delay. If the day does not exist in the target
+// month, the last day of that month is used, e.g. -1Y from 2024-02-29 yields
+// 2023-02-28.
+func (d Delay) Shift(t time.Time) time.Time {
+	first := time.Date(t.Year(), t.Month(), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	first = first.AddDate(d.Years, d.Months, 0)
+	last := first.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
 // License represents a span of time, which a license covers, expressed as a
 // string of the form `from:to:delay`. Both `from` and `to` are expressed as
 // `YYYYvvvvvviiiiii` (year-volume-issue, zero-padded). Since we resort to
 // string comparisons, `0000000000000000` and `ZZZZZZZZZZZZZZZZ` are valid
-// values for unbounded start and end points in time. The delay must be
-// expressed in nanoseconds, e.g. -2Y would be expressed as
-// `-62208000000000000`.
+// values for unbounded start and end points in time. The delay is expressed
+// in OVID notation, e.g. `-2Y` or `-6M`, or `0` for no delay. Licenses from
+// free entitlements carry an additional `:free` suffix.
 type License string
 
 // From returns the start of the license range.
@@ -102,29 +162,137 @@ func (l License) To() string {
 	return parts[1]
 }
 
+// Free returns true, if the license stems from a free entitlement.
+func (l License) Free() bool {
+	parts := strings.Split(string(l), ":")
+	return len(parts) > 3 && parts[3] == StatusFree
+}
+
 // Covers returns true, if the given signature falls between the start and end
 // of the license. Moving wall does not play a role here.
 func (l License) Covers(signature string) bool {
 	return signature >= l.From() && l.To() >= signature
 }
 
-// Delay returns the delay as a duration. This function will halt the world if
-// the license has not passed basic sanity checks. Always use
-// `NewLicenseFromEntitlement` to build a license.
-func (l License) Delay() time.Duration {
+// Validate checks, whether the license is well-formed, e.g. when it does not
+// stem from NewLicenseFromEntitlement.
+func (l License) Validate() error {
+	parts := strings.Split(string(l), ":")
+	if len(parts) < 3 || len(parts) > 4 || (len(parts) == 4 && parts[3] != StatusFree) {
+		return fmt.Errorf("%w: %s", ErrInvalidLicense, l)
+	}
+	if len(parts[0]) != len(LowDatum16) || len(parts[1]) != len(HighDatum16) {
+		return fmt.Errorf("%w: %s", ErrInvalidLicense, l)
+	}
+	if parts[1] < parts[0] {
+		return fmt.Errorf("%w: %s", ErrReversedRange, l)
+	}
+	if parts[2] == "0" {
+		return nil
+	}
+	_, err := ParseDelay(parts[2])
+	return err
+}
+
+// Delay returns the delay. This function will halt the world if the license
+// has not passed basic sanity checks. Always use `NewLicenseFromEntitlement`
+// to build a license.
+func (l License) Delay() Delay {
 	parts := strings.Split(string(l), ":")
-	v, err := strconv.Atoi(parts[2])
+	if parts[2] == "0" {
+		return Delay{}
+	}
+	d, err := ParseDelay(parts[2])
 	if err != nil {
 		log.Fatal(err)
 	}
-	return time.Duration(v)
+	return d
 }
 
-// Wall returns the licence wall truncated to day. The moving wall calculation
-// is based on the system current time.
+// Wall returns the licence wall truncated to day. The moving wall is
+// calculated with calendar arithmetic relative to the reference date.
 func (l License) Wall(ref time.Time) time.Time {
-	d := time.Duration(-ref.Hour()) * time.Hour
-	return ref.Truncate(time.Hour).Add(d + l.Delay())
+	day := time.Date(ref.Year(), ref.Month(), ref.Day(), 0, 0, 0, 0, ref.Location())
+	return l.Delay().Shift(day)
+}
+
+// year returns the year of a datum, false for the open ends LowDatum16 and
+// HighDatum16.
+func year(datum string) (int, bool) {
+	if len(datum) < 4 {
+		return 0, false
+	}
+	y, err := strconv.Atoi(datum[:4])
+	if err != nil || y == 0 {
+		return 0, false
+	}
+	return y, true
+}
+
+// Boundary returns the latest publication date, that any of the licenses
+// allows at ref. For a single license, this is the moving wall or the end of
+// its coverage, whichever is earlier; a license, whose coverage starts after
+// its wall, allows nothing. Open is true, if a license has neither moving
+// wall nor end.
+func Boundary(licenses []License, ref time.Time) (boundary time.Time, open bool) {
+	for _, l := range licenses {
+		walled := l.Delay() != (Delay{})
+		to, ended := year(l.To())
+		var latest time.Time
+		switch {
+		case !walled && !ended:
+			return time.Time{}, true
+		case !ended:
+			latest = l.Wall(ref)
+		default:
+			latest = time.Date(to, time.December, 31, 0, 0, 0, 0, ref.Location())
+			if wall := l.Wall(ref); walled && wall.Before(latest) {
+				latest = wall
+			}
+		}
+		if from, ok := year(l.From()); ok && latest.Year() < from {
+			continue
+		}
+		if latest.After(boundary) {
+			boundary = latest
+		}
+	}
+	return boundary, false
+}
+
+// Edition records, whether an ISSN was listed as print or electronic ISSN of
+// a holding. An ISSN can be listed as both. The zero value means unknown.
+type Edition int
+
+const (
+	// Print marks an ISSN listed as p-issn.
+	Print Edition = 1 << iota
+	// Online marks an ISSN listed as e-issn.
+	Online
+)
+
+// String returns print, online, print+online or unknown.
+func (e Edition) String() string {
+	switch e {
+	case Print:
+		return "print"
+	case Online:
+		return "online"
+	case Print | Online:
+		return "print+online"
+	default:
+		return "unknown"
+	}
+}
+
+// Editions maps ISSNs to the edition they were listed as.
+type Editions map[string]Edition
+
+// Merge adds all entries from another map.
+func (m Editions) Merge(other Editions) {
+	for issn, e := range other {
+		m[issn] |= e
+	}
 }
 
 // Licenses holds the license ranges for an ISSN.
@@ -140,35 +308,62 @@ func (t Licenses) Add(issn string, license License) {
 	t[issn] = append(t[issn], license)
 }
 
+// Remove removes a license from a given ISSN. Returns true, if the license
+// was present.
+func (t Licenses) Remove(issn string, license License) bool {
+	for i, v := range t[issn] {
+		if v == license {
+			t[issn] = append(t[issn][:i:i], t[issn][i+1:]...)
+			if len(t[issn]) == 0 {
+				delete(t, issn)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Merge adds all licenses from another table. Dups are ignored.
+func (t Licenses) Merge(other Licenses) {
+	for issn, licenses := range other {
+		for _, license := range licenses {
+			t.Add(issn, license)
+		}
+	}
+}
+
 // NewLicenseFromEntitlement creates a simple License string from the more
 // complex Entitlement structure. If error is nil, the License passed the
 // sanity checks.
 func NewLicenseFromEntitlement(e Entitlement) (License, error) {
 	if len(e.FromYear) > len(MaxYear) || len(e.ToYear) > len(MaxYear) {
-		return emptyLicense, errInvalidYear
+		return emptyLicense, fmt.Errorf("%w: %s-%s", ErrInvalidYear, e.FromYear, e.ToYear)
 	}
 	if len(e.FromVolume) > len(MaxVolume) || len(e.ToVolume) > len(MaxVolume) {
-		return emptyLicense, errVolumeTooBig
+		return emptyLicense, fmt.Errorf("%w: %s-%s", ErrVolumeTooBig, e.FromVolume, e.ToVolume)
 	}
 	if len(e.FromIssue) > len(MaxIssue) || len(e.ToIssue) > len(MaxIssue) {
-		return emptyLicense, errIssueTooBig
+		return emptyLicense, fmt.Errorf("%w: %s-%s", ErrIssueTooBig, e.FromIssue, e.ToIssue)
 	}
 
 	from := CombineDatum(e.FromYear, e.FromVolume, e.FromIssue, LowDatum16)
 	to := CombineDatum(e.ToYear, e.ToVolume, e.ToIssue, HighDatum16)
 
 	if to < from {
-		return emptyLicense, errInvalidRange
+		return emptyLicense, fmt.Errorf("%w: %s-%s", ErrReversedRange, from, to)
 	}
 
 	delay := firstNonemptyString(e.FromDelay, e.ToDelay, "-0M")
 
-	dur, err := parseDelay(delay)
+	d, err := ParseDelay(delay)
 	if err != nil {
 		return emptyLicense, err
 	}
 
-	return License(fmt.Sprintf("%s:%s:%d", from, to, dur.Nanoseconds())), nil
+	if e.Status == StatusFree {
+		return License(fmt.Sprintf("%s:%s:%s:%s", from, to, d, StatusFree)), nil
+	}
+	return License(fmt.Sprintf("%s:%s:%s", from, to, d)), nil
 }
 
 // CombineDatum combines year, volume and issue into a single value,
@@ -181,30 +376,29 @@ func CombineDatum(year, volume, issue string, empty string) string {
 	return fmt.Sprintf("%04s%06s%06s", year, volume, issue)
 }
 
-// parseDelay parses delay strings like '-1M', '-3Y', ... into a time.Duration.
+// ParseDelay parses delay strings like '-1M', '-3Y', ... into a Delay.
 // Will fail on on units other that M and Y.
-func parseDelay(s string) (time.Duration, error) {
-	var d time.Duration
+func ParseDelay(s string) (Delay, error) {
+	var d Delay
 	if s == "" {
 		return d, nil
 	}
 	ms := delayPattern.FindStringSubmatch(s)
 	if len(ms) != 3 {
-		return d, errUnknownFormat
+		return d, fmt.Errorf("%w: %q", ErrUnknownFormat, s)
 	}
 	value, err := strconv.Atoi(ms[1])
 	if err != nil {
-		return d, err
+		return d, fmt.Errorf("%w: %q: %s", ErrUnknownFormat, s, err)
 	}
-	switch {
-	case ms[2] == "Y":
-		return time.Duration(time.Duration(value) * year), nil
-	case ms[2] == "M":
-		return time.Duration(time.Duration(value) * month), nil
+	switch ms[2] {
+	case "Y":
+		return Delay{Years: value}, nil
+	case "M":
+		return Delay{Months: value}, nil
 	default:
-		return d, errUnknownUnit
+		return d, fmt.Errorf("%w: %q", ErrUnknownUnit, s)
 	}
-	return d, nil
 }
 
 // firstNonemptyString returns the first value that is not the empty string.
@@ -220,16 +414,24 @@ func firstNonemptyString(v ...string) string {
 // ParseHoldings takes a reader and will try to return Licenses, which is just
 // a map from ISSN to []License. Errors are collected and returned as slice.
 func ParseHoldings(r io.Reader) (Licenses, []error) {
+	lmap, _, errors := ParseHoldingsEditions(r)
+	return lmap, errors
+}
+
+// ParseHoldingsEditions works like ParseHoldings, but additionally records,
+// whether an ISSN was listed as print or electronic ISSN.
+func ParseHoldingsEditions(r io.Reader) (Licenses, Editions, []error) {
 	decoder := xml.NewDecoder(bufio.NewReader(r))
 	lmap := make(Licenses)
+	editions := make(Editions)
 	var errors []error
 	var tag string
 
 	for {
 		t, err := decoder.Token()
 		if err != nil && err != io.EOF {
-			errors = append(errors, err)
-			return lmap, errors
+			errors = append(errors, fmt.Errorf("%w: %s", ErrParse, err))
+			return lmap, editions, errors
 		}
 		if t == nil {
 			break
@@ -244,7 +446,7 @@ func ParseHoldings(r io.Reader) (Licenses, []error) {
 				for _, e := range item.Entitlements {
 					l, err := NewLicenseFromEntitlement(e)
 					if err != nil {
-						errors = append(errors, fmt.Errorf("%d => %s", item.EZBID, err))
+						errors = append(errors, fmt.Errorf("%d => %w", item.EZBID, err))
 					} else {
 						hls = append(hls, l)
 					}
@@ -255,8 +457,14 @@ func ParseHoldings(r io.Reader) (Licenses, []error) {
 						lmap.Add(issn, l)
 					}
 				}
+				for _, issn := range item.PISSN {
+					editions[issn] |= Print
+				}
+				for _, issn := range item.EISSN {
+					editions[issn] |= Online
+				}
 			}
 		}
 	}
-	return lmap, errors
+	return lmap, editions, errors
 }
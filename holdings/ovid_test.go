@@ -1,6 +1,7 @@
 package holdings
 
 import (
+	"errors"
 	"io"
 	"reflect"
 	"strings"
@@ -19,34 +20,83 @@ func mustParse(s string) time.Time {
 func TestParseDelay(t *testing.T) {
 	var tests = []struct {
 		s   string
-		d   time.Duration
+		d   Delay
 		err error
 	}{
-		{"-0M", time.Duration(0), nil},
-		{"-1M", time.Duration(-1) * month, nil},
-		{"-2M", time.Duration(-2) * month, nil},
-		{"-1Y", time.Duration(-1) * year, nil},
-		{"-1D", time.Duration(0), errUnknownFormat},
-		{"-1", time.Duration(0), errUnknownFormat},
-		{"129", time.Duration(0), errUnknownFormat},
-		{"AB", time.Duration(0), errUnknownFormat},
-		{"-111m", time.Duration(0), errUnknownFormat},
-		{"0.1M", time.Duration(0), errUnknownFormat},
+		{"-0M", Delay{}, nil},
+		{"-1M", Delay{Months: -1}, nil},
+		{"-2M", Delay{Months: -2}, nil},
+		{"-1Y", Delay{Years: -1}, nil},
+		{"-1D", Delay{}, ErrUnknownFormat},
+		{"-1", Delay{}, ErrUnknownFormat},
+		{"129", Delay{}, ErrUnknownFormat},
+		{"AB", Delay{}, ErrUnknownFormat},
+		{"-111m", Delay{}, ErrUnknownFormat},
+		{"0.1M", Delay{}, ErrUnknownFormat},
+		{"-99999999999999999999M", Delay{}, ErrUnknownFormat},
 	}
 
 	for _, tt := range tests {
-		d, err := parseDelay(tt.s)
-		if d != tt.d {
-			t.Errorf("parseDelay(%s) => %v, %v, want %v, %v", tt.s, d, err, tt.d, tt.err)
+		d, err := ParseDelay(tt.s)
+		if d != tt.d || !errors.Is(err, tt.err) {
+			t.Errorf("ParseDelay(%s) => %v, %v, want %v, %v", tt.s, d, err, tt.d, tt.err)
 		}
-		if err != nil {
-			if tt.err != nil {
-				if err.Error() != tt.err.Error() {
-					t.Errorf("parseDelay(%s) => %v, %v, want %v, %v", tt.s, d, err, tt.d, tt.err)
-				}
-			} else {
-				t.Errorf("parseDelay(%s) => %v, %v, want %v, %v", tt.s, d, err, tt.d, tt.err)
-			}
+	}
+}
+
+func TestNewLicenseFromEntitlementErrors(t *testing.T) {
+	var tests = []struct {
+		e   Entitlement
+		err error
+	}{
+		{Entitlement{FromYear: "2000", ToYear: "2010"}, nil},
+		{Entitlement{FromYear: "20000"}, ErrInvalidYear},
+		{Entitlement{FromVolume: "1234567"}, ErrVolumeTooBig},
+		{Entitlement{ToIssue: "1234567"}, ErrIssueTooBig},
+		{Entitlement{FromYear: "2010", ToYear: "2000"}, ErrReversedRange},
+		{Entitlement{FromDelay: "-1D"}, ErrUnknownFormat},
+		{Entitlement{FromDelay: "-1Y", ToDelay: "-2Y"}, nil},
+		{Entitlement{FromDelay: "-1Y", ToDelay: "-12M"}, nil},
+		{Entitlement{FromDelay: "-1Y", ToDelay: "-1Y"}, nil},
+	}
+	for _, tt := range tests {
+		_, err := NewLicenseFromEntitlement(tt.e)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("NewLicenseFromEntitlement(%+v) => %v, want %v", tt.e, err, tt.err)
+		}
+	}
+}
+
+func TestParseHoldingsErrors(t *testing.T) {
+	_, errs := ParseHoldings(strings.NewReader(`<holdings><holding ezb_id="1">`))
+	if len(errs) != 1 || !errors.Is(errs[0], ErrParse) {
+		t.Errorf("ParseHoldings: got %v, want %v", errs, ErrParse)
+	}
+	doc := `<holdings><holding ezb_id="1"><EZBIssns><p-issn>1234-5678</p-issn></EZBIssns>
+	<entitlements><entitlement><begin><year>2010</year></begin><end><year>2000</year></end></entitlement></entitlements>
+	</holding></holdings>`
+	_, errs = ParseHoldings(strings.NewReader(doc))
+	if len(errs) != 1 || !errors.Is(errs[0], ErrReversedRange) {
+		t.Errorf("ParseHoldings: got %v, want %v", errs, ErrReversedRange)
+	}
+}
+
+func TestDelayShift(t *testing.T) {
+	var tests = []struct {
+		d      Delay
+		t      time.Time
+		result time.Time
+	}{
+		{Delay{Years: -1}, mustParse("2024-02-29"), mustParse("2023-02-28")},
+		{Delay{Years: -4}, mustParse("2024-02-29"), mustParse("2020-02-29")},
+		{Delay{Years: -1}, mustParse("2024-03-01"), mustParse("2023-03-01")},
+		{Delay{Months: -6}, mustParse("2024-08-31"), mustParse("2024-02-29")},
+		{Delay{Months: -1}, mustParse("2024-01-15"), mustParse("2023-12-15")},
+		{Delay{}, mustParse("2024-01-15"), mustParse("2024-01-15")},
+	}
+	for _, tt := range tests {
+		if r := tt.d.Shift(tt.t); !r.Equal(tt.result) {
+			t.Errorf("Delay(%s).Shift(%s) => %s, want %s", tt.d, tt.t, r, tt.result)
 		}
 	}
 }
@@ -175,10 +225,10 @@ func TestParseHoldings(t *testing.T) {
   </entitlements>
 </holding>`), Licenses{
 				"0140-525X": []License{
-					License("2012000035000000:ZZZZZZZZZZZZZZZZ:-62208000000000000"),
+					License("2012000035000000:ZZZZZZZZZZZZZZZZ:-2Y"),
 					License("1997000020000001:2004000027000006:0")},
 				"1469-1825": []License{
-					License("2012000035000000:ZZZZZZZZZZZZZZZZ:-62208000000000000"),
+					License("2012000035000000:ZZZZZZZZZZZZZZZZ:-2Y"),
 					License("1997000020000001:2004000027000006:0")}},
 		},
 	}
@@ -199,11 +249,11 @@ func TestCovers(t *testing.T) {
 		signature string
 		result    bool
 	}{
-		{License("2012000035000000:ZZZZZZZZZZZZZZZZ:-62208000000000000"), "2013000035000000", true},
-		{License("2012000035000000:ZZZZZZZZZZZZZZZZ:-62208000000000000"), "2012000035000000", true},
-		{License("2012000035000000:ZZZZZZZZZZZZZZZZ:-62208000000000000"), "2018000034000000", true},
-		{License("2012000035000000:ZZZZZZZZZZZZZZZZ:-62208000000000000"), "2012000034000000", false},
-		{License("2012000035000000:ZZZZZZZZZZZZZZZZ:-62208000000000000"), "2011000035000000", false},
+		{License("2012000035000000:ZZZZZZZZZZZZZZZZ:-2Y"), "2013000035000000", true},
+		{License("2012000035000000:ZZZZZZZZZZZZZZZZ:-2Y"), "2012000035000000", true},
+		{License("2012000035000000:ZZZZZZZZZZZZZZZZ:-2Y"), "2018000034000000", true},
+		{License("2012000035000000:ZZZZZZZZZZZZZZZZ:-2Y"), "2012000034000000", false},
+		{License("2012000035000000:ZZZZZZZZZZZZZZZZ:-2Y"), "2011000035000000", false},
 	}
 	for _, c := range cases {
 		r := c.license.Covers(c.signature)
@@ -222,9 +272,9 @@ func TestWall(t *testing.T) {
 	}{
 		{License("2012000035000000:ZZZZZZZZZZZZZZZZ:0"), mustParse("2012-02-02"), false},
 		{License("2012000035000000:ZZZZZZZZZZZZZZZZ:0"), mustParse("2011-02-02"), false},
-		{License("2012000035000000:ZZZZZZZZZZZZZZZZ:-62208000000000000"), mustParse("2014-02-02"), true},
-		{License("2012000035000000:ZZZZZZZZZZZZZZZZ:-62208000000000000"), mustParse("2013-02-02"), true},
-		{License("2012000035000000:ZZZZZZZZZZZZZZZZ:-62208000000000000"), mustParse("2018-09-02"), true},
+		{License("2012000035000000:ZZZZZZZZZZZZZZZZ:-2Y"), mustParse("2014-02-02"), true},
+		{License("2012000035000000:ZZZZZZZZZZZZZZZZ:-2Y"), mustParse("2013-02-02"), true},
+		{License("2012000035000000:ZZZZZZZZZZZZZZZZ:-2Y"), mustParse("2018-09-02"), true},
 	}
 	for _, c := range cases {
 		r := c.t.After(c.license.Wall(c.t))
@@ -233,3 +283,92 @@ func TestWall(t *testing.T) {
 		}
 	}
 }
+
+func TestNewLicenseFromEntitlement(t *testing.T) {
+	var tests = []struct {
+		e       Entitlement
+		license License
+		free    bool
+	}{
+		{Entitlement{Status: "subscribed", FromYear: "2000", FromDelay: "-1Y"}, License("2000000000000000:ZZZZZZZZZZZZZZZZ:-1Y"), false},
+		{Entitlement{Status: "free", FromYear: "2000", FromDelay: "-1Y"}, License("2000000000000000:ZZZZZZZZZZZZZZZZ:-1Y:free"), true},
+	}
+	for _, tt := range tests {
+		l, err := NewLicenseFromEntitlement(tt.e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l != tt.license || l.Free() != tt.free {
+			t.Errorf("NewLicenseFromEntitlement(%+v) => %s (free %v), want %s (free %v)", tt.e, l, l.Free(), tt.license, tt.free)
+		}
+	}
+}
+
+func TestHoldingKey(t *testing.T) {
+	a := Holding{EZBID: 1, Title: "Journal", PISSN: []string{"1234-5678"}}
+	b := Holding{EZBID: 1, Title: "Journal", PISSN: []string{"2345-6789"}, EISSN: []string{"1234-5678"}}
+	if a.Key() != b.Key() {
+		t.Errorf("Holding.Key: got %s and %s for same EZB id", a.Key(), b.Key())
+	}
+	c := Holding{EZBID: 2, Title: "Journal", PISSN: []string{"1234-5678"}}
+	if a.Key() == c.Key() {
+		t.Errorf("Holding.Key: got %s for different EZB ids", a.Key())
+	}
+	d := Holding{Title: "The  Journal", Publishers: "ACME", PISSN: []string{"1234-5678"}}
+	e := Holding{Title: "the journal", Publishers: "ACME"}
+	f := Holding{Title: "The Journal", Publishers: "Other"}
+	if d.Key() != e.Key() {
+		t.Errorf("Holding.Key: got %s and %s for same title and publisher", d.Key(), e.Key())
+	}
+	if d.Key() == f.Key() {
+		t.Errorf("Holding.Key: got %s for different publishers", d.Key())
+	}
+}
+
+func TestParseHoldingsEditions(t *testing.T) {
+	_, editions, errs := ParseHoldingsEditions(strings.NewReader(`<holdings>
+<holding ezb_id="1">
+  <EZBIssns><p-issn>1234-5678</p-issn><e-issn>2345-6789</e-issn></EZBIssns>
+</holding>
+<holding ezb_id="2">
+  <EZBIssns><e-issn>1234-5678</e-issn></EZBIssns>
+</holding>
+</holdings>`))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	want := Editions{"1234-5678": Print | Online, "2345-6789": Online}
+	if !reflect.DeepEqual(editions, want) {
+		t.Errorf("ParseHoldingsEditions: got %v, want %v", editions, want)
+	}
+}
+
+func TestBoundary(t *testing.T) {
+	ref := mustParse("2023-07-31")
+	var tests = []struct {
+		licenses []License
+		boundary time.Time
+		open     bool
+	}{
+		{[]License{"0000000000000000:ZZZZZZZZZZZZZZZZ:-6M"}, ref.AddDate(0, -6, 0), false},
+		{[]License{"0000000000000000:ZZZZZZZZZZZZZZZZ:-1Y", "0000000000000000:ZZZZZZZZZZZZZZZZ:-6M"}, mustParse("2023-01-31"), false},
+		{[]License{"0000000000000000:ZZZZZZZZZZZZZZZZ:-6M", "0000000000000000:ZZZZZZZZZZZZZZZZ:0"}, time.Time{}, true},
+		{[]License{"0000000000000000:ZZZZZZZZZZZZZZZZ:-5M"}, mustParse("2023-02-28"), false},
+		// ended licenses
+		{[]License{"2000000000000000:2010000000000000:0"}, mustParse("2010-12-31"), false},
+		{[]License{"2000000000000000:2010000000000000:-1Y"}, mustParse("2010-12-31"), false},
+		{[]License{"2000000000000000:2023000000000000:-1Y"}, mustParse("2022-07-31"), false},
+		{[]License{"2000000000000000:2010000000000000:0", "0000000000000000:ZZZZZZZZZZZZZZZZ:-1Y"}, mustParse("2022-07-31"), false},
+		// partially overlapping licenses
+		{[]License{"2000000000000000:2015000000000000:0", "2010000000000000:ZZZZZZZZZZZZZZZZ:-10Y"}, mustParse("2015-12-31"), false},
+		{[]License{"2000000000000000:2015000000000000:0", "2010000000000000:ZZZZZZZZZZZZZZZZ:-6M"}, mustParse("2023-01-31"), false},
+		// coverage starts after the wall
+		{[]License{"2000000000000000:2005000000000000:0", "2023000000000000:ZZZZZZZZZZZZZZZZ:-1Y"}, mustParse("2005-12-31"), false},
+	}
+	for _, tt := range tests {
+		boundary, open := Boundary(tt.licenses, ref)
+		if !boundary.Equal(tt.boundary) || open != tt.open {
+			t.Errorf("Boundary(%v): got %v, %v, want %v, %v", tt.licenses, boundary, open, tt.boundary, tt.open)
+		}
+	}
+}
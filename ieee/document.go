@@ -0,0 +1,288 @@
+// Package ieee converts IEEE Xplore publication XML into the intermediate
+// schema. A delivery contains publications, each with a volume and its
+// articles; every article becomes a record, carrying the publication and
+// volume metadata.
+package ieee
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// SourceID for internal bookkeeping.
+	SourceID = "152"
+	// Collection name.
+	Collection = "IEEE Xplore"
+	// BatchSize number of articles per batch.
+	BatchSize = 2000
+)
+
+// pubTypes maps IEEE publication types to format, genre and RIS type.
+// Conference papers and journal articles end up as different formats.
+var pubTypes = map[string][3]string{
+	"journal":      {"ElectronicArticle", "article", "JOUR"},
+	"magazine":     {"ElectronicArticle", "article", "MGZN"},
+	"early access": {"ElectronicArticle", "article", "JOUR"},
+	"conference":   {"ElectronicProceeding", "proceeding", "CPAPER"},
+	"standard":     {"ElectronicResourceRemoteAccess", "document", "STAND"},
+}
+
+// IEEE source.
+type IEEE struct{}
+
+// Date is a date with possibly textual month, like "Jan." or "March".
+type Date struct {
+	Type  string `xml:"datetype,attr"`
+	Year  string `xml:"year"`
+	Month string `xml:"month"`
+	Day   string `xml:"day"`
+}
+
+// Time parses a date. Months may be numeric or names, ranges like
+// "Jan.-Feb." use the first month.
+func (d Date) Time() (time.Time, error) {
+	year, err := strconv.Atoi(strings.TrimSpace(d.Year))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("ieee: invalid year: %q", d.Year)
+	}
+	month, day := time.January, 1
+	m := strings.ToLower(strings.TrimSpace(d.Month))
+	if i := strings.IndexAny(m, "-/"); i > 0 {
+		m = m[:i]
+	}
+	m = strings.TrimSuffix(m, ".")
+	if v, err := strconv.Atoi(m); err == nil && v >= 1 && v <= 12 {
+		month = time.Month(v)
+	} else if len(m) >= 3 {
+		for i := time.January; i <= time.December; i++ {
+			if strings.HasPrefix(strings.ToLower(i.String()), m[:3]) {
+				month = i
+				break
+			}
+		}
+	}
+	if v, err := strconv.Atoi(strings.TrimSpace(d.Day)); err == nil && v >= 1 && v <= 31 {
+		day = v
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC), nil
+}
+
+// Article is a single article of a volume.
+type Article struct {
+	Title       string `xml:"title"`
+	ArticleInfo struct {
+		DOI           string `xml:"articledoi"`
+		ArticleNumber string `xml:"articlenumber"`
+		Authors       []struct {
+			FirstName  string `xml:"firstname"`
+			Surname    string `xml:"surname"`
+			Normalized string `xml:"normname"`
+		} `xml:"authorgroup>author"`
+		StartPage   string `xml:"pagination>startpage"`
+		EndPage     string `xml:"pagination>endpage"`
+		Abstract    string `xml:"abstract"`
+		KeywordSets []struct {
+			Type  string   `xml:"keywordtype,attr"`
+			Terms []string `xml:"keyword>term"`
+		} `xml:"keywordset"`
+		Dates []Date `xml:"date"`
+	} `xml:"articleinfo"`
+}
+
+// Publication is a journal, magazine, conference or standard with a volume.
+type Publication struct {
+	Title           string `xml:"title"`
+	PublicationInfo struct {
+		ISSN []struct {
+			MediaType string `xml:"mediatype,attr"`
+			Value     string `xml:",chardata"`
+		} `xml:"issn"`
+		ISBN []struct {
+			MediaType string `xml:"mediatype,attr"`
+			Value     string `xml:",chardata"`
+		} `xml:"isbn"`
+		PubType   string `xml:"pubtype"`
+		Publisher string `xml:"publisher>publishername"`
+	} `xml:"publicationinfo"`
+	Volume struct {
+		VolumeInfo struct {
+			Year   string `xml:"year"`
+			Volume string `xml:"volumenum"`
+			Issue  string `xml:"issue>issuenum"`
+		} `xml:"volumeinfo"`
+		Articles []Article `xml:"article"`
+	} `xml:"volume"`
+}
+
+// Document is an article together with its publication.
+type Document struct {
+	Article
+	Publication *Publication
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(docs []*Document) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			return s.(span.Importer), nil
+		}, Items: make([]interface{}, len(docs))}
+	for i, doc := range docs {
+		batch.Items[i] = doc
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via XML decoding. Publications are
+// decoded one at a time and split into their articles.
+func (s IEEE) Iterate(r io.Reader) (<-chan interface{}, error) {
+	ch := make(chan interface{})
+	var docs []*Document
+	go func() {
+		decoder := xml.NewDecoder(bufio.NewReader(r))
+		for {
+			t, _ := decoder.Token()
+			if t == nil {
+				break
+			}
+			switch se := t.(type) {
+			case xml.StartElement:
+				if se.Name.Local == "publication" {
+					pub := new(Publication)
+					if err := decoder.DecodeElement(pub, &se); err != nil {
+						log.Fatal(err)
+					}
+					for _, article := range pub.Volume.Articles {
+						docs = append(docs, &Document{Article: article, Publication: pub})
+						if len(docs) == BatchSize {
+							ch <- NewBatch(docs)
+							docs = nil
+						}
+					}
+				}
+			}
+		}
+		ch <- NewBatch(docs)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Date returns the original publication date of the article and falls back
+// to any other article date and the volume year.
+func (doc *Document) Date() (time.Time, error) {
+	dates := doc.ArticleInfo.Dates
+	for _, d := range dates {
+		if d.Type == "OriginalPub" {
+			if t, err := d.Time(); err == nil {
+				return t, nil
+			}
+		}
+	}
+	for _, d := range dates {
+		if t, err := d.Time(); err == nil {
+			return t, nil
+		}
+	}
+	return Date{Year: doc.Publication.Volume.VolumeInfo.Year}.Time()
+}
+
+// types returns format, genre and RIS type of the publication.
+func (doc *Document) types() ([3]string, bool) {
+	v, ok := pubTypes[strings.ToLower(strings.TrimSpace(doc.Publication.PublicationInfo.PubType))]
+	return v, ok
+}
+
+// ToIntermediateSchema converts an article. Articles of unsupported
+// publication types and articles without an identifier or date are skipped.
+func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	types, ok := doc.types()
+	if !ok {
+		return output, span.Skip{Reason: fmt.Sprintf("ieee: unsupported publication type: %s", doc.Publication.PublicationInfo.PubType)}
+	}
+	id := doc.ArticleInfo.ArticleNumber
+	if id == "" {
+		id = doc.ArticleInfo.DOI
+	}
+	if id == "" {
+		return output, span.Skip{Reason: "ieee: article without article number or DOI"}
+	}
+	output.Date, err = doc.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(id)))
+	output.SourceID = SourceID
+	output.RecordID = strings.TrimRight(enc, "=")
+	output.MegaCollection = Collection
+	output.Format, output.Genre, output.RefType = types[0], types[1], types[2]
+
+	output.ArticleTitle = strings.TrimSpace(doc.Title)
+	output.DOI = strings.TrimSpace(doc.ArticleInfo.DOI)
+	if doc.ArticleInfo.ArticleNumber != "" {
+		output.URL = append(output.URL, "https://ieeexplore.ieee.org/document/"+doc.ArticleInfo.ArticleNumber)
+	} else if output.DOI != "" {
+		output.URL = append(output.URL, "https://doi.org/"+output.DOI)
+	}
+	output.Abstract = strings.TrimSpace(doc.ArticleInfo.Abstract)
+	for _, a := range doc.ArticleInfo.Authors {
+		if a.Surname == "" && a.Normalized != "" {
+			output.Authors = append(output.Authors, finc.Author{Name: a.Normalized})
+			continue
+		}
+		output.Authors = append(output.Authors, finc.Author{FirstName: a.FirstName, LastName: a.Surname})
+	}
+	for _, ks := range doc.ArticleInfo.KeywordSets {
+		for _, term := range ks.Terms {
+			if term = strings.TrimSpace(term); term != "" {
+				output.Subjects = append(output.Subjects, term)
+			}
+		}
+	}
+
+	pub := doc.Publication
+	if output.Genre == "proceeding" {
+		output.BookTitle = strings.TrimSpace(pub.Title)
+	} else {
+		output.JournalTitle = strings.TrimSpace(pub.Title)
+	}
+	for _, issn := range pub.PublicationInfo.ISSN {
+		if issn.MediaType == "online" || issn.MediaType == "electronic" {
+			output.EISSN = append(output.EISSN, issn.Value)
+		} else {
+			output.ISSN = append(output.ISSN, issn.Value)
+		}
+	}
+	for _, isbn := range pub.PublicationInfo.ISBN {
+		if isbn.MediaType == "online" || isbn.MediaType == "electronic" {
+			output.EISBN = append(output.EISBN, isbn.Value)
+		} else {
+			output.ISBN = append(output.ISBN, isbn.Value)
+		}
+	}
+	if pub.PublicationInfo.Publisher != "" {
+		output.Publishers = append(output.Publishers, pub.PublicationInfo.Publisher)
+	}
+	output.Volume = pub.Volume.VolumeInfo.Volume
+	output.Issue = pub.Volume.VolumeInfo.Issue
+	output.StartPage = doc.ArticleInfo.StartPage
+	output.EndPage = doc.ArticleInfo.EndPage
+	if output.StartPage != "" && output.EndPage != "" {
+		output.Pages = fmt.Sprintf("%s-%s", output.StartPage, output.EndPage)
+	}
+	return output, nil
+}
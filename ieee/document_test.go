@@ -0,0 +1,116 @@
+package ieee
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/internal/spantest"
+)
+
+const example = `<?xml version="1.0" encoding="UTF-8"?>
+<publications>
+<publication>
+	<title>IEEE Transactions on Examples</title>
+	<publicationinfo>
+		<issn mediatype="print">1234-5678</issn>
+		<issn mediatype="online">2345-6789</issn>
+		<pubtype>Journal</pubtype>
+		<publisher><publishername>IEEE</publishername></publisher>
+	</publicationinfo>
+	<volume>
+		<volumeinfo><year>2016</year><volumenum>12</volumenum><issue><issuenum>3</issuenum></issue></volumeinfo>
+		<article>
+			<title>Journal article</title>
+			<articleinfo>
+				<articledoi>10.1109/TEX.2016.1</articledoi>
+				<articlenumber>7400001</articlenumber>
+				<authorgroup><author><firstname>Jane</firstname><surname>Doe</surname></author></authorgroup>
+				<pagination><startpage>10</startpage><endpage>20</endpage></pagination>
+				<keywordset keywordtype="IEEEFree"><keyword><term>examples</term></keyword></keywordset>
+				<date datetype="OriginalPub"><year>2016</year><month>Mar.-Apr.</month></date>
+			</articleinfo>
+		</article>
+		<article>
+			<title>Without identifier</title>
+			<articleinfo></articleinfo>
+		</article>
+	</volume>
+</publication>
+<publication>
+	<title>Proceedings of the Example Conference</title>
+	<publicationinfo>
+		<isbn mediatype="electronic">978-1-0000-0000-0</isbn>
+		<pubtype>Conference</pubtype>
+	</publicationinfo>
+	<volume>
+		<volumeinfo><year>2015</year></volumeinfo>
+		<article>
+			<title>Conference paper</title>
+			<articleinfo><articlenumber>7300001</articlenumber></articleinfo>
+		</article>
+	</volume>
+</publication>
+</publications>`
+
+func TestIEEE(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, IEEE{}, strings.NewReader(example))
+	if len(results) != 3 {
+		t.Fatalf("got %d records, want 3", len(results))
+	}
+	if errs[0] != nil || errs[2] != nil {
+		t.Fatalf("unexpected errors: %v, %v", errs[0], errs[2])
+	}
+	if _, ok := errs[1].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for an article without identifier", errs[1])
+	}
+
+	article := results[0]
+	if article.Format != "ElectronicArticle" || article.Genre != "article" {
+		t.Errorf("got format %s, genre %s", article.Format, article.Genre)
+	}
+	if article.JournalTitle != "IEEE Transactions on Examples" || article.Volume != "12" || article.Issue != "3" {
+		t.Errorf("got journal %q, volume %q, issue %q", article.JournalTitle, article.Volume, article.Issue)
+	}
+	if article.Date.Format("2006-01-02") != "2016-03-01" {
+		t.Errorf("got date %s", article.Date.Format("2006-01-02"))
+	}
+	if len(article.ISSN) != 1 || len(article.EISSN) != 1 || article.EISSN[0] != "2345-6789" {
+		t.Errorf("got ISSN %v, EISSN %v", article.ISSN, article.EISSN)
+	}
+	if article.Pages != "10-20" || len(article.URL) != 1 || article.URL[0] != "https://ieeexplore.ieee.org/document/7400001" {
+		t.Errorf("got pages %q, URL %v", article.Pages, article.URL)
+	}
+
+	paper := results[2]
+	if paper.Format != "ElectronicProceeding" || paper.Genre != "proceeding" || paper.RefType != "CPAPER" {
+		t.Errorf("got format %s, genre %s, type %s", paper.Format, paper.Genre, paper.RefType)
+	}
+	if paper.BookTitle != "Proceedings of the Example Conference" || paper.JournalTitle != "" {
+		t.Errorf("got book title %q, journal title %q", paper.BookTitle, paper.JournalTitle)
+	}
+	if paper.Date.Year() != 2015 || len(paper.EISBN) != 1 {
+		t.Errorf("got date %v, EISBN %v", paper.Date, paper.EISBN)
+	}
+}
+
+func TestDateTime(t *testing.T) {
+	var cases = []struct {
+		date Date
+		want string
+	}{
+		{Date{Year: "2016", Month: "3", Day: "7"}, "2016-03-07"},
+		{Date{Year: "2016", Month: "Sept."}, "2016-09-01"},
+		{Date{Year: "2016", Month: "Jan/Feb"}, "2016-01-01"},
+		{Date{Year: "2016"}, "2016-01-01"},
+	}
+	for _, c := range cases {
+		got, err := c.date.Time()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Format("2006-01-02") != c.want {
+			t.Errorf("%v: got %s, want %s", c.date, got.Format("2006-01-02"), c.want)
+		}
+	}
+}
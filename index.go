@@ -0,0 +1,102 @@
+package span
+
+import (
+	"encoding/gob"
+	"io"
+
+	"github.com/miku/span/container"
+	"github.com/miku/span/finc"
+)
+
+// ISSNIndex maps ISSNs to the ISILs, whose filters mention that ISSN. It is
+// built from the ISSN-based filters of a tagger, HoldingFilter, ListFilter
+// and ISSNYearFilter. The index only tells, which ISILs may be attached to a
+// record: holdings and year filters still need to check the date of a record.
+//
+// ISILs with any other filter, e.g. a source or a combined filter, can attach
+// records regardless of their ISSN. They are kept in Unindexed and need to be
+// evaluated for every record.
+type ISSNIndex struct {
+	ISILs     map[string][]string
+	Unindexed []string
+}
+
+// issnKeys returns the ISSNs, that an ISSN-based filter can match. The
+// second return value is false for other filters.
+func issnKeys(f Filter) ([]string, bool) {
+	var issns []string
+	switch f := f.(type) {
+	case HoldingFilter:
+		for issn := range f.licenses() {
+			issns = append(issns, issn)
+		}
+	case ListFilter:
+		issns = f.Set.Values()
+	case ISSNYearFilter:
+		for issn := range f.Table {
+			issns = append(issns, issn)
+		}
+	default:
+		return nil, false
+	}
+	return issns, true
+}
+
+// BuildISSNIndex materializes the inverted index for the ISSN-based filters
+// of a tagger.
+func BuildISSNIndex(tagger ISILTagger) ISSNIndex {
+	sets := make(map[string]*container.StringSet)
+	unindexed := container.NewStringSet()
+	for isil, filters := range tagger {
+		for _, f := range filters {
+			issns, ok := issnKeys(f)
+			if !ok {
+				unindexed.Add(isil)
+				continue
+			}
+			for _, issn := range issns {
+				if _, ok := sets[issn]; !ok {
+					sets[issn] = container.NewStringSet()
+				}
+				sets[issn].Add(isil)
+			}
+		}
+	}
+	index := ISSNIndex{ISILs: make(map[string][]string), Unindexed: unindexed.SortedValues()}
+	for issn, set := range sets {
+		index.ISILs[issn] = set.SortedValues()
+	}
+	return index
+}
+
+// Lookup returns the sorted ISILs for an ISSN.
+func (x ISSNIndex) Lookup(issn string) []string {
+	return x.ISILs[issn]
+}
+
+// Candidates returns the sorted ISILs, that may be attached to a record: the
+// ISILs of all ISSNs of the record and all unindexed ISILs.
+func (x ISSNIndex) Candidates(is finc.IntermediateSchema) []string {
+	set := container.NewStringSet(x.Unindexed...)
+	for _, issn := range is.ISSNList() {
+		set.AddAll(x.ISILs[issn]...)
+	}
+	return set.SortedValues()
+}
+
+// Save writes the index gob encoded.
+func (x ISSNIndex) Save(w io.Writer) error {
+	return gob.NewEncoder(w).Encode(x)
+}
+
+// LoadISSNIndex reads an index written by Save.
+func LoadISSNIndex(r io.Reader) (ISSNIndex, error) {
+	var x ISSNIndex
+	err := gob.NewDecoder(r).Decode(&x)
+	return x, err
+}
+
+// Size returns the number of indexed ISSNs.
+func (x ISSNIndex) Size() int {
+	return len(x.ISILs)
+}
@@ -0,0 +1,59 @@
+package span
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/miku/span/container"
+	"github.com/miku/span/finc"
+	"github.com/miku/span/holdings"
+)
+
+func TestISSNIndex(t *testing.T) {
+	tagger := ISILTagger{
+		"DE-14": []Filter{HoldingFilter{Table: holdings.Licenses{
+			"1234-5678": []holdings.License{"0000000000000000:ZZZZZZZZZZZZZZZZ:0"},
+		}}},
+		"DE-15": []Filter{ListFilter{Set: container.NewStringSet("1234-5678", "2345-6789")}},
+		"DE-Ch1": []Filter{
+			ISSNYearFilter{Table: map[string]*container.StringSet{"3456-7890": container.NewStringSet("2001")}},
+			SourceFilter{SourceID: "28"},
+		},
+	}
+	index := BuildISSNIndex(tagger)
+
+	var buf bytes.Buffer
+	if err := index.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadISSNIndex(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, index) {
+		t.Errorf("LoadISSNIndex: got %v, want %v", loaded, index)
+	}
+
+	var tests = []struct {
+		issn  string
+		isils []string
+	}{
+		{"1234-5678", []string{"DE-14", "DE-15"}},
+		{"2345-6789", []string{"DE-15"}},
+		{"3456-7890", []string{"DE-Ch1"}},
+		{"0000-0000", nil},
+	}
+	for _, tt := range tests {
+		if isils := loaded.Lookup(tt.issn); !reflect.DeepEqual(isils, tt.isils) {
+			t.Errorf("ISSNIndex.Lookup(%s): got %v, want %v", tt.issn, isils, tt.isils)
+		}
+	}
+	if want := []string{"DE-Ch1"}; !reflect.DeepEqual(loaded.Unindexed, want) {
+		t.Errorf("ISSNIndex.Unindexed: got %v, want %v", loaded.Unindexed, want)
+	}
+	is := finc.IntermediateSchema{EISSN: []string{"2345-6789"}}
+	if want := []string{"DE-15", "DE-Ch1"}; !reflect.DeepEqual(loaded.Candidates(is), want) {
+		t.Errorf("ISSNIndex.Candidates: got %v, want %v", loaded.Candidates(is), want)
+	}
+}
@@ -0,0 +1,52 @@
+// Package spantest provides helpers for testing sources.
+package spantest
+
+import (
+	"io"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+)
+
+// ConvertAll reads r with source s, applies every batch and converts each
+// item into the intermediate schema. Errors from iteration or batch apply
+// fail the test. Conversion errors, e.g. span.Skip, are returned alongside
+// the records, one per item.
+func ConvertAll(t testing.TB, s span.Source, r io.Reader) ([]*finc.IntermediateSchema, []error) {
+	t.Helper()
+	ch, err := s.Iterate(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		results []*finc.IntermediateSchema
+		errs    []error
+	)
+	for v := range ch {
+		batch := v.(span.Batcher)
+		for _, item := range batch.Items {
+			doc, err := batch.Apply(item)
+			if err != nil {
+				t.Fatal(err)
+			}
+			is, err := doc.ToIntermediateSchema()
+			results = append(results, is)
+			errs = append(errs, err)
+		}
+	}
+	return results, errs
+}
+
+// MustConvertAll works like ConvertAll, but fails the test on the first
+// conversion error.
+func MustConvertAll(t testing.TB, s span.Source, r io.Reader) []*finc.IntermediateSchema {
+	t.Helper()
+	results, errs := ConvertAll(t, s, r)
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return results
+}
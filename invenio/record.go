@@ -0,0 +1,390 @@
+// Package invenio converts records of Invenio based repositories, like
+// Zenodo, into the intermediate schema. Input is one record per line, as
+// returned by the records API. Both the legacy Zenodo layout and the
+// InvenioRDM layout are read.
+package invenio
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/kennygrant/sanitize"
+	"github.com/miku/span"
+	"github.com/miku/span/container"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// DefaultSourceID is used, if no source id is configured.
+	DefaultSourceID = "169"
+	// DefaultCollection is used, if no collection is configured.
+	DefaultCollection = "Zenodo"
+	// BatchSize for grouped channel transport.
+	BatchSize = 10000
+)
+
+// resourceTypes maps resource types, as "type-subtype", to format, genre
+// and RIS type.
+var resourceTypes = map[string][3]string{
+	"publication-article":         {"ElectronicArticle", "article", "JOUR"},
+	"publication-book":            {"eBook", "book", "EBOOK"},
+	"publication-section":         {"ElectronicBookPart", "bookitem", "ECHAP"},
+	"publication-conferencepaper": {"ElectronicProceeding", "proceeding", "CPAPER"},
+	"publication-thesis":          {"ElectronicThesis", "document", "THES"},
+	"publication-preprint":        {"ElectronicPreprint", "preprint", "UNPB"},
+	"publication-report":          {"ElectronicResourceRemoteAccess", "report", "RPRT"},
+	"publication-workingpaper":    {"ElectronicResourceRemoteAccess", "report", "RPRT"},
+	"publication-technicalnote":   {"ElectronicResourceRemoteAccess", "report", "RPRT"},
+	"dataset":                     {"ElectronicResourceRemoteAccess", "document", "DATA"},
+}
+
+// Invenio source. Source id and collection depend on the repository, empty
+// values fall back to the defaults.
+type Invenio struct {
+	SourceID   string
+	Collection string
+}
+
+// ID is a numeric or string record identifier.
+type ID string
+
+// UnmarshalJSON accepts numbers and strings.
+func (id *ID) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	d := json.NewDecoder(strings.NewReader(string(b)))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return err
+	}
+	switch t := v.(type) {
+	case nil:
+		*id = ""
+	case string:
+		*id = ID(t)
+	default:
+		*id = ID(fmt.Sprintf("%v", t))
+	}
+	return nil
+}
+
+// Creator is an author. Zenodo uses name and orcid, InvenioRDM nests the
+// name in person_or_org.
+type Creator struct {
+	Name        string `json:"name"`
+	ORCID       string `json:"orcid"`
+	PersonOrOrg struct {
+		Type        string `json:"type"`
+		Name        string `json:"name"`
+		GivenName   string `json:"given_name"`
+		FamilyName  string `json:"family_name"`
+		Identifiers []struct {
+			Scheme     string `json:"scheme"`
+			Identifier string `json:"identifier"`
+		} `json:"identifiers"`
+	} `json:"person_or_org"`
+}
+
+// Author returns the creator as author, organizations as name only.
+func (c Creator) Author() finc.Author {
+	if c.Name != "" {
+		if parts := strings.SplitN(c.Name, ",", 2); len(parts) == 2 {
+			return finc.Author{LastName: strings.TrimSpace(parts[0]), FirstName: strings.TrimSpace(parts[1]), ORCID: c.ORCID}
+		}
+		return finc.Author{Name: strings.TrimSpace(c.Name), ORCID: c.ORCID}
+	}
+	p := c.PersonOrOrg
+	var orcid string
+	for _, id := range p.Identifiers {
+		if id.Scheme == "orcid" {
+			orcid = id.Identifier
+		}
+	}
+	if p.Type == "personal" && p.FamilyName != "" {
+		return finc.Author{LastName: p.FamilyName, FirstName: p.GivenName, ORCID: orcid}
+	}
+	return finc.Author{Name: strings.TrimSpace(p.Name), ORCID: orcid}
+}
+
+// ResourceType is a type and an optional subtype (Zenodo) or a combined id
+// (InvenioRDM), like "publication-article".
+type ResourceType struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Subtype string `json:"subtype"`
+}
+
+// Key returns the type as "type-subtype".
+func (t ResourceType) Key() string {
+	if t.ID != "" {
+		return t.ID
+	}
+	if t.Subtype != "" {
+		return t.Type + "-" + t.Subtype
+	}
+	return t.Type
+}
+
+// Journal holds the journal information of an article.
+type Journal struct {
+	Title  string `json:"title"`
+	Volume string `json:"volume"`
+	Issue  string `json:"issue"`
+	Pages  string `json:"pages"`
+	ISSN   string `json:"issn"`
+}
+
+// Record is a single record, legacy Zenodo and InvenioRDM fields are merged.
+type Record struct {
+	ID    ID     `json:"id"`
+	DOI   string `json:"doi"`
+	Links struct {
+		HTML     string `json:"html"`
+		SelfHTML string `json:"self_html"`
+	} `json:"links"`
+	PIDs struct {
+		DOI struct {
+			Identifier string `json:"identifier"`
+		} `json:"doi"`
+	} `json:"pids"`
+	Access struct {
+		Record string `json:"record"`
+		Files  string `json:"files"`
+	} `json:"access"`
+	Metadata struct {
+		Title           string       `json:"title"`
+		DOI             string       `json:"doi"`
+		PublicationDate string       `json:"publication_date"`
+		Description     string       `json:"description"`
+		ResourceType    ResourceType `json:"resource_type"`
+		Creators        []Creator    `json:"creators"`
+		Keywords        []string     `json:"keywords"`
+		Subjects        []struct {
+			Subject string `json:"subject"`
+			Term    string `json:"term"`
+		} `json:"subjects"`
+		Language  string `json:"language"`
+		Languages []struct {
+			ID string `json:"id"`
+		} `json:"languages"`
+		AccessRight string  `json:"access_right"`
+		Publisher   string  `json:"publisher"`
+		Journal     Journal `json:"journal"`
+		Imprint     struct {
+			Publisher string `json:"publisher"`
+			ISBN      string `json:"isbn"`
+			Place     string `json:"place"`
+		} `json:"imprint"`
+		PartOf struct {
+			Title string `json:"title"`
+			Pages string `json:"pages"`
+		} `json:"part_of"`
+	} `json:"metadata"`
+	CustomFields struct {
+		Journal Journal `json:"journal:journal"`
+	} `json:"custom_fields"`
+}
+
+// Document is a record together with the source settings.
+type Document struct {
+	Record
+	source Invenio
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(lines []string, s Invenio) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(v interface{}) (span.Importer, error) {
+			doc := &Document{source: s}
+			err := json.Unmarshal([]byte(v.(string)), &doc.Record)
+			return doc, err
+		}, Items: make([]interface{}, len(lines))}
+	for i, line := range lines {
+		batch.Items[i] = line
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via JSON decoding.
+func (s Invenio) Iterate(r io.Reader) (<-chan interface{}, error) {
+	if s.SourceID == "" {
+		s.SourceID = DefaultSourceID
+	}
+	if s.Collection == "" {
+		s.Collection = DefaultCollection
+	}
+	ch := make(chan interface{})
+	reader := bufio.NewReader(r)
+	var lines []string
+	go func() {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil && err != io.EOF {
+				log.Fatal(err)
+			}
+			if strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+			if len(lines) == BatchSize {
+				ch <- NewBatch(lines, s)
+				lines = nil
+			}
+			if err == io.EOF {
+				break
+			}
+		}
+		ch <- NewBatch(lines, s)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Date returns the publication date, which may be reduced to year and month
+// or to the year.
+func (r *Record) Date() (time.Time, error) {
+	v := strings.TrimSpace(r.Metadata.PublicationDate)
+	for _, layout := range []string{"2006-01-02", "2006-01", "2006"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	// Ranges like 2019-01/2019-06 start with the first date.
+	if i := strings.Index(v, "/"); i > 0 {
+		for _, layout := range []string{"2006-01-02", "2006-01", "2006"} {
+			if t, err := time.Parse(layout, v[:i]); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invenio: %s: no usable date: %q", r.ID, v)
+}
+
+// DOIValue returns the DOI, from the persistent identifiers or the metadata.
+func (r *Record) DOIValue() string {
+	for _, v := range []string{r.PIDs.DOI.Identifier, r.Metadata.DOI, r.DOI} {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// IsOpen returns true, if the files of the record are public.
+func (r *Record) IsOpen() bool {
+	if r.Metadata.AccessRight != "" {
+		return r.Metadata.AccessRight == "open"
+	}
+	return r.Access.Files == "public"
+}
+
+// journal returns the journal, from custom fields or the metadata.
+func (r *Record) journal() Journal {
+	if r.CustomFields.Journal.Title != "" {
+		return r.CustomFields.Journal
+	}
+	return r.Metadata.Journal
+}
+
+// ToIntermediateSchema converts a record. Restricted records, records of
+// unsupported types, like software or images, and records without id or
+// date are skipped.
+func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	md := doc.Metadata
+	if doc.ID == "" || strings.TrimSpace(md.Title) == "" {
+		return output, span.Skip{Reason: fmt.Sprintf("invenio: %q: missing id or title", doc.ID)}
+	}
+	if doc.Access.Record != "" && doc.Access.Record != "public" {
+		return output, span.Skip{Reason: fmt.Sprintf("invenio: %s: restricted record", doc.ID)}
+	}
+	kind, ok := resourceTypes[md.ResourceType.Key()]
+	if !ok {
+		return output, span.Skip{Reason: fmt.Sprintf("invenio: %s: unsupported resource type: %s", doc.ID, md.ResourceType.Key())}
+	}
+	output.Date, err = doc.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+
+	enc := fmt.Sprintf("ai-%s-%s", doc.source.SourceID, base64.URLEncoding.EncodeToString([]byte(doc.ID)))
+	output.SourceID = doc.source.SourceID
+	output.RecordID = strings.TrimRight(enc, "=")
+	output.MegaCollection = doc.source.Collection
+	output.Format, output.Genre, output.RefType = kind[0], kind[1], kind[2]
+
+	output.ArticleTitle = strings.TrimSpace(md.Title)
+	for _, c := range md.Creators {
+		if a := c.Author(); a != (finc.Author{}) {
+			output.Authors = append(output.Authors, a)
+		}
+	}
+	output.DOI = doc.DOIValue()
+	output.Abstract = strings.Join(strings.Fields(sanitize.HTML(md.Description)), " ")
+	output.OpenAccess = doc.IsOpen()
+	for _, u := range []string{doc.Links.HTML, doc.Links.SelfHTML} {
+		if u != "" {
+			output.URL = append(output.URL, u)
+			break
+		}
+	}
+
+	subjects := container.NewStringSet()
+	for _, v := range md.Keywords {
+		if v = strings.TrimSpace(v); v != "" && subjects.Add(v) {
+			output.Subjects = append(output.Subjects, v)
+		}
+	}
+	for _, s := range md.Subjects {
+		for _, v := range []string{s.Subject, s.Term} {
+			if v = strings.TrimSpace(v); v != "" && subjects.Add(v) {
+				output.Subjects = append(output.Subjects, v)
+			}
+		}
+	}
+	if md.Language != "" {
+		output.Languages = append(output.Languages, span.NormalizeLanguage(md.Language))
+	}
+	for _, l := range md.Languages {
+		output.Languages = append(output.Languages, span.NormalizeLanguage(l.ID))
+	}
+
+	for _, v := range []string{md.Publisher, md.Imprint.Publisher} {
+		if v = strings.TrimSpace(v); v != "" {
+			output.Publishers = append(output.Publishers, v)
+			break
+		}
+	}
+	if md.Imprint.Place != "" {
+		output.Places = append(output.Places, md.Imprint.Place)
+	}
+	if md.Imprint.ISBN != "" {
+		output.ISBN = append(output.ISBN, md.Imprint.ISBN)
+	}
+
+	pages := md.PartOf.Pages
+	switch output.Genre {
+	case "bookitem", "proceeding":
+		output.BookTitle = strings.TrimSpace(md.PartOf.Title)
+	case "book":
+		output.BookTitle = output.ArticleTitle
+	default:
+		j := doc.journal()
+		output.JournalTitle = strings.TrimSpace(j.Title)
+		output.Volume, output.Issue, pages = j.Volume, j.Issue, j.Pages
+		if j.ISSN != "" {
+			output.ISSN = append(output.ISSN, j.ISSN)
+		}
+	}
+	output.Pages = pages
+	if parts := strings.SplitN(pages, "-", 2); len(parts) == 2 {
+		output.StartPage, output.EndPage = strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	}
+	return output, nil
+}
@@ -0,0 +1,62 @@
+package invenio
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+	"github.com/miku/span/internal/spantest"
+)
+
+const example = `{"id": 1234, "doi": "10.5281/zenodo.1234", "links": {"html": "https://zenodo.org/record/1234"}, "metadata": {"title": "Zenodo Article", "doi": "10.5281/zenodo.1234", "publication_date": "2019-05-06", "description": "<p>An <em>abstract</em>.</p>", "resource_type": {"type": "publication", "subtype": "article", "title": "Journal article"}, "creators": [{"name": "Doe, Jane", "orcid": "0000-0002-1825-0097"}, {"name": "Example Consortium"}], "keywords": ["biology", "biology", "cells"], "language": "eng", "access_right": "open", "journal": {"title": "Journal of Examples", "volume": "4", "issue": "2", "pages": "11-19"}}}
+{"id": "abcd-1234", "pids": {"doi": {"identifier": "10.1000/rdm.1"}}, "links": {"self_html": "https://repo.example.org/records/abcd-1234"}, "access": {"record": "public", "files": "restricted"}, "metadata": {"title": "RDM Thesis", "publication_date": "2021-03", "resource_type": {"id": "publication-thesis"}, "creators": [{"person_or_org": {"type": "personal", "given_name": "Richard", "family_name": "Roe", "identifiers": [{"scheme": "orcid", "identifier": "0000-0001-5109-3700"}]}}], "publisher": "Example University", "languages": [{"id": "deu"}], "subjects": [{"subject": "History"}]}}
+
+{"id": 5678, "metadata": {"title": "Some Code", "publication_date": "2020-01-01", "resource_type": {"type": "software"}}}
+{"id": "efgh-5678", "access": {"record": "restricted"}, "metadata": {"title": "Hidden", "publication_date": "2020", "resource_type": {"id": "dataset"}}}
+`
+
+func TestInvenio(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, Invenio{}, strings.NewReader(example))
+	if len(results) != 4 {
+		t.Fatalf("got %d records, want 4", len(results))
+	}
+	if errs[0] != nil || errs[1] != nil {
+		t.Fatalf("unexpected errors: %v, %v", errs[0], errs[1])
+	}
+	for _, err := range errs[2:] {
+		if _, ok := err.(span.Skip); !ok {
+			t.Errorf("got %v, want span.Skip", err)
+		}
+	}
+	is := results[0]
+	if is.SourceID != DefaultSourceID || is.MegaCollection != DefaultCollection || !is.OpenAccess {
+		t.Errorf("got source id %s, collection %s, open access %v", is.SourceID, is.MegaCollection, is.OpenAccess)
+	}
+	want := []finc.Author{{LastName: "Doe", FirstName: "Jane", ORCID: "0000-0002-1825-0097"}, {Name: "Example Consortium"}}
+	if !reflect.DeepEqual(is.Authors, want) {
+		t.Errorf("got authors %v, want %v", is.Authors, want)
+	}
+	if is.Abstract != "An abstract." || is.JournalTitle != "Journal of Examples" || is.StartPage != "11" || is.EndPage != "19" {
+		t.Errorf("got abstract %q, journal %q, pages %s-%s", is.Abstract, is.JournalTitle, is.StartPage, is.EndPage)
+	}
+	if !reflect.DeepEqual(is.Subjects, []string{"biology", "cells"}) || is.URL[0] != "https://zenodo.org/record/1234" {
+		t.Errorf("got subjects %v, URL %v", is.Subjects, is.URL)
+	}
+	thesis := results[1]
+	if thesis.Format != "ElectronicThesis" || thesis.DOI != "10.1000/rdm.1" || thesis.OpenAccess {
+		t.Errorf("got format %s, DOI %s, open access %v", thesis.Format, thesis.DOI, thesis.OpenAccess)
+	}
+	if thesis.Date.Format("2006-01") != "2021-03" || thesis.Authors[0].ORCID != "0000-0001-5109-3700" || thesis.Publishers[0] != "Example University" {
+		t.Errorf("got date %v, authors %v, publishers %v", thesis.Date, thesis.Authors, thesis.Publishers)
+	}
+}
+
+func TestInvenioSettings(t *testing.T) {
+	results, _ := spantest.ConvertAll(t, Invenio{SourceID: "900", Collection: "Institutional Repository"}, strings.NewReader(example))
+	is := results[0]
+	if is.SourceID != "900" || is.MegaCollection != "Institutional Repository" || !strings.HasPrefix(is.RecordID, "ai-900-") {
+		t.Errorf("got source id %s, collection %s, record id %s", is.SourceID, is.MegaCollection, is.RecordID)
+	}
+}
@@ -0,0 +1,88 @@
+package span
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/miku/span/finc"
+)
+
+// issnCandidate matches ISSN shaped tokens, with or without hyphen.
+var issnCandidate = regexp.MustCompile(`\b\d{4}-?\d{3}[\dXx]\b`)
+
+// NormalizeISSN returns an ISSN in the canonical form 1234-567X, or the empty
+// string, if s does not look like an ISSN. The checksum is not verified.
+func NormalizeISSN(s string) string {
+	s = strings.ToUpper(strings.Replace(strings.TrimSpace(s), "-", "", -1))
+	if len(s) != 8 {
+		return ""
+	}
+	for i, c := range s {
+		if (c < '0' || c > '9') && !(i == 7 && c == 'X') {
+			return ""
+		}
+	}
+	return s[:4] + "-" + s[4:]
+}
+
+// ValidISSN returns true, if s is an ISSN with a valid check digit.
+func ValidISSN(s string) bool {
+	s = NormalizeISSN(s)
+	if s == "" {
+		return false
+	}
+	digits := strings.Replace(s, "-", "", 1)
+	var sum int
+	for i := 0; i < 7; i++ {
+		sum += int(digits[i]-'0') * (8 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return digits[7] == 'X'
+	}
+	return int(digits[7]-'0') == check
+}
+
+// ISSNExtractorFields lists the text fields, ISSNs can be extracted from.
+var ISSNExtractorFields = map[string]func(finc.IntermediateSchema) string{
+	"allfields": func(is finc.IntermediateSchema) string { return is.Allfields() },
+	"abstract":  func(is finc.IntermediateSchema) string { return is.Abstract },
+	"fulltext":  func(is finc.IntermediateSchema) string { return is.Fulltext },
+}
+
+// ISSNExtractor adds ISSNs, that only appear in a text field of a record, to
+// its ISSN list. Since this is a heuristic, only tokens with a valid check
+// digit are considered.
+type ISSNExtractor struct {
+	text func(finc.IntermediateSchema) string
+}
+
+// NewISSNExtractor creates an extractor for a field in ISSNExtractorFields.
+func NewISSNExtractor(field string) (ISSNExtractor, error) {
+	f, ok := ISSNExtractorFields[field]
+	if !ok {
+		return ISSNExtractor{}, fmt.Errorf("cannot extract ISSN from field: %s", field)
+	}
+	return ISSNExtractor{text: f}, nil
+}
+
+// Apply adds the extracted ISSNs, that are not yet known, to the ISSN list
+// and returns the number of added ISSNs.
+func (e ISSNExtractor) Apply(is *finc.IntermediateSchema) int {
+	known := make(map[string]bool)
+	for _, issn := range is.ISSNList() {
+		known[NormalizeISSN(issn)] = true
+	}
+	var n int
+	for _, token := range issnCandidate.FindAllString(e.text(*is), -1) {
+		issn := NormalizeISSN(token)
+		if known[issn] || !ValidISSN(issn) {
+			continue
+		}
+		known[issn] = true
+		is.ISSN = append(is.ISSN, issn)
+		n++
+	}
+	return n
+}
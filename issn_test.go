@@ -0,0 +1,61 @@
+package span
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span/finc"
+)
+
+func TestValidISSN(t *testing.T) {
+	var tests = []struct {
+		s     string
+		valid bool
+	}{
+		{"0028-0836", true},
+		{"00280836", true},
+		{"0317-8471", true},
+		{"2434-561x", true},
+		{"2434-561X", true},
+		{"0028-0837", false},
+		{"1234-5678", false},
+		{"0028-08361", false},
+		{"abcd-efgh", false},
+	}
+	for _, tt := range tests {
+		if r := ValidISSN(tt.s); r != tt.valid {
+			t.Errorf("ValidISSN(%s): got %v, want %v", tt.s, r, tt.valid)
+		}
+	}
+}
+
+func TestISSNExtractor(t *testing.T) {
+	is := finc.IntermediateSchema{
+		ISSN:     []string{"0317-8471"},
+		Abstract: "Published in Nature (ISSN 00280836), see also 0317-8471 and 1234-5678, call 2021-2022.",
+	}
+	f, err := NewListFilter(strings.NewReader("0028-0836\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Apply(is) {
+		t.Fatalf("ListFilter.Apply: got true before extraction, want false")
+	}
+	e, err := NewISSNExtractor("allfields")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := e.Apply(&is); n != 1 {
+		t.Errorf("ISSNExtractor.Apply: got %d, want 1 added ISSN", n)
+	}
+	if want := []string{"0317-8471", "0028-0836"}; !reflect.DeepEqual(is.ISSN, want) {
+		t.Errorf("ISSNExtractor.Apply: got %v, want %v", is.ISSN, want)
+	}
+	if !f.Apply(is) {
+		t.Errorf("ListFilter.Apply: got false after extraction, want true")
+	}
+	if _, err := NewISSNExtractor("title"); err == nil {
+		t.Errorf("NewISSNExtractor: got nil, want error for unsupported field")
+	}
+}
@@ -49,9 +49,12 @@ var (
 )
 
 // PubDate represents a publication date. Typical type values are ppub and epub.
+// JATS 1.0 and later use date type and publication format attributes instead.
 type PubDate struct {
-	Type  string `xml:"pub-type,attr"`
-	Month struct {
+	Type              string `xml:"pub-type,attr"`
+	DateType          string `xml:"date-type,attr"`
+	PublicationFormat string `xml:"publication-format,attr"`
+	Month             struct {
 		XMLName xml.Name `xml:"month"`
 		Value   string   `xml:",chardata"`
 	}
@@ -63,6 +66,10 @@ type PubDate struct {
 		XMLName xml.Name `xml:"day"`
 		Value   string   `xml:",chardata"`
 	}
+	StringDate struct {
+		XMLName xml.Name `xml:"string-date"`
+		Value   string   `xml:",chardata"`
+	}
 }
 
 // Article mirrors a JATS article element.
@@ -199,6 +206,7 @@ type Article struct {
 			}
 			TranslatedAbstract struct {
 				XMLName xml.Name `xml:"trans-abstract"`
+				Value   string   `xml:",innerxml"`
 				Lang    string   `xml:"lang,attr"`
 				Title   struct {
 					XMLName xml.Name `xml:"title"`
@@ -354,9 +362,10 @@ func (article *Article) parsePubDate(pd PubDate) (t time.Time) {
 	var s string
 	if pd.Day.Value == "" {
 		if pd.Month.Value == "" {
-			s = fmt.Sprintf("%s", pd.Year.Value)
+			s = pd.Year.Value
+		} else {
+			s = fmt.Sprintf("%s-%s", pd.Year.Value, pd.Month.Value)
 		}
-		s = fmt.Sprintf("%s-%s", pd.Year.Value, pd.Month.Value)
 	} else {
 		s = fmt.Sprintf("%s-%s-%s", pd.Year.Value, pd.Month.Value, pd.Day.Value)
 	}
@@ -432,6 +441,7 @@ func (article *Article) ToIntermediateSchema() (*finc.IntermediateSchema, error)
 	output := finc.NewIntermediateSchema()
 
 	output.Date = article.Date()
+	output.DOI, _ = article.DOI()
 
 	output.Abstract = string(article.Front.Article.Abstract.Value)
 	output.ArticleTitle = article.CombinedTitle()
@@ -457,7 +467,9 @@ func (article *Article) ToIntermediateSchema() (*finc.IntermediateSchema, error)
 	output.StartPage = article.Front.Article.FirstPage.Value
 	output.EndPage = article.Front.Article.LastPage.Value
 	output.PageCount = article.PageCount()
-	output.Pages = fmt.Sprintf("%s-%s", output.StartPage, output.EndPage)
+	if output.StartPage != "" && output.EndPage != "" {
+		output.Pages = fmt.Sprintf("%s-%s", output.StartPage, output.EndPage)
+	}
 
 	return output, nil
 }
@@ -83,3 +83,35 @@ func TestSchema(t *testing.T) {
 	}
 
 }
+
+func TestToIntermediateSchema(t *testing.T) {
+	var article Article
+	if err := xml.Unmarshal([]byte(example), &article); err != nil {
+		t.Fatal(err)
+	}
+	is, err := article.ToIntermediateSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if is.DOI != "10.7788/akg.1969.51.2.183" {
+		t.Errorf("got %s, want %s", is.DOI, "10.7788/akg.1969.51.2.183")
+	}
+	if is.Date.Format("2006-01-02") != "1969-12-01" {
+		t.Errorf("got %s, want %s", is.Date.Format("2006-01-02"), "1969-12-01")
+	}
+	if is.Volume != "51" || is.Issue != "2" || is.Pages != "183-209" {
+		t.Errorf("got %s/%s/%s, want 51/2/183-209", is.Volume, is.Issue, is.Pages)
+	}
+	if len(is.Authors) != 1 || is.Authors[0].LastName != "Flaskamp" {
+		t.Errorf("got %v, want Flaskamp", is.Authors)
+	}
+}
+
+func TestParsePubDateYearOnly(t *testing.T) {
+	var article Article
+	pd := PubDate{}
+	pd.Year.Value = "1969"
+	if got := article.parsePubDate(pd); got.Year() != 1969 {
+		t.Errorf("got %v, want 1969", got)
+	}
+}
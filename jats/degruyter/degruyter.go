@@ -1,13 +1,21 @@
+// Package degruyter converts De Gruyter XML deliveries, JATS journal articles
+// and BITS book chapters, into the intermediate schema.
+//
+// De Gruyter sells journals and books in packages. With a package mapping,
+// the package name of a record becomes its collection, so taggers can
+// select single packages with a collection filter.
 package degruyter
 
 import (
 	"bufio"
 	"encoding/base64"
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/miku/span"
 	"github.com/miku/span/finc"
@@ -21,20 +29,48 @@ const (
 	SourceName = "DeGruyter SSH"
 	// Format for intermediate schema.
 	Format = "ElectronicArticle"
+	// ChapterFormat for book chapters.
+	ChapterFormat = "ElectronicBookPart"
 	// Batchsize number of documents per batch.
 	BatchSize = 2000
 )
 
-// DeGruyter source.
-type DeGruyter struct{}
+// DeGruyter source. Packages maps ISSN and ISBN to package names, which
+// are used as collection name. Records without a package keep SourceName.
+type DeGruyter struct {
+	Packages map[string]string
+}
+
+// ReadPackages reads a package mapping, a JSON object with ISSN or ISBN as
+// keys and package names as values.
+func ReadPackages(r io.Reader) (map[string]string, error) {
+	packages := make(map[string]string)
+	if err := json.NewDecoder(r).Decode(&packages); err != nil {
+		return nil, err
+	}
+	return packages, nil
+}
+
+// collection returns the package name for the first known identifier.
+func collection(packages map[string]string, ids ...[]string) string {
+	for _, list := range ids {
+		for _, id := range list {
+			if name, ok := packages[strings.TrimSpace(id)]; ok {
+				return name
+			}
+		}
+	}
+	return SourceName
+}
 
 // Article with extras for this source.
 type Article struct {
 	jats.Article
+	packages map[string]string
 }
 
 // NewBatch wraps up a new batch for channel com.
-func NewBatch(docs []*Article) span.Batcher {
+func NewBatch(docs []span.Importer) span.Batcher {
 	batch := span.Batcher{
 		Apply: func(s interface{}) (span.Importer, error) {
 			return s.(span.Importer), nil
@@ -49,7 +85,16 @@ func NewBatch(docs []*Article) span.Batcher {
 func (s DeGruyter) Iterate(r io.Reader) (<-chan interface{}, error) {
 	ch := make(chan interface{})
 	i := 0
-	var docs []*Article
+	var docs []span.Importer
+	emit := func(doc span.Importer) {
+		i++
+		docs = append(docs, doc)
+		if i == BatchSize {
+			ch <- NewBatch(docs)
+			docs = nil
+			i = 0
+		}
+	}
 	go func() {
 		decoder := xml.NewDecoder(bufio.NewReader(r))
 		for {
@@ -59,18 +104,23 @@ func (s DeGruyter) Iterate(r io.Reader) (<-chan interface{}, error) {
 			}
 			switch se := t.(type) {
 			case xml.StartElement:
-				if se.Name.Local == "article" {
-					doc := new(Article)
+				switch se.Name.Local {
+				case "article":
+					doc := &Article{packages: s.Packages}
 					err := decoder.DecodeElement(&doc, &se)
 					if err != nil {
 						log.Fatal(err)
 					}
-					i++
-					docs = append(docs, doc)
-					if i == BatchSize {
-						ch <- NewBatch(docs)
-						docs = docs[:0]
-						i = 0
+					emit(doc)
+				case "book", "book-part-wrapper":
+					book := new(Book)
+					if err := decoder.DecodeElement(book, &se); err != nil {
+						log.Fatal(err)
+					}
+					for _, parts := range [][]BookPart{book.Parts, book.BodyParts} {
+						for i := range parts {
+							emit(&Chapter{BookPart: &parts[i], Book: book, packages: s.Packages})
+						}
 					}
 				}
 			}
@@ -111,8 +161,151 @@ func (article *Article) ToIntermediateSchema() (*finc.IntermediateSchema, error)
 	output.URL = append(output.URL, ids.URL)
 
 	output.Format = Format
-	output.MegaCollection = SourceName
+	output.MegaCollection = collection(article.packages, output.ISSN)
+	output.SourceID = SourceID
+
+	return output, nil
+}
+
+// Name is a contributor name.
+type Name struct {
+	Surname    string `xml:"surname"`
+	GivenNames string `xml:"given-names"`
+}
+
+// BookPart is a chapter of a BITS book.
+type BookPart struct {
+	Type string `xml:"book-part-type,attr"`
+	Meta struct {
+		IDs []struct {
+			Type  string `xml:"pub-id-type,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"book-part-id"`
+		Title    string `xml:"title-group>title"`
+		Subtitle string `xml:"title-group>subtitle"`
+		Contribs []struct {
+			Type string `xml:"contrib-type,attr"`
+			Name Name   `xml:"name"`
+		} `xml:"contrib-group>contrib"`
+		FirstPage string `xml:"fpage"`
+		LastPage  string `xml:"lpage"`
+		Abstract  string `xml:"abstract"`
+	} `xml:"book-part-meta"`
+}
+
+// Book is a BITS book or book part wrapper with its chapters.
+type Book struct {
+	Meta struct {
+		IDs []struct {
+			Type  string `xml:"pub-id-type,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"book-id"`
+		Title string `xml:"book-title-group>book-title"`
+		ISBN  []struct {
+			Format string `xml:"publication-format,attr"`
+			Type   string `xml:"pub-type,attr"`
+			Value  string `xml:",chardata"`
+		} `xml:"isbn"`
+		PubDates  []jats.PubDate `xml:"pub-date"`
+		Publisher string         `xml:"publisher>publisher-name"`
+		Series    string         `xml:"book-series-meta>book-series-title"`
+	} `xml:"book-meta"`
+	Parts     []BookPart `xml:"book-part"`
+	BodyParts []BookPart `xml:"book-body>book-part"`
+}
+
+// Date returns the first parseable publication date of the book.
+func (book *Book) Date() (time.Time, error) {
+	for _, pd := range book.Meta.PubDates {
+		y, m, d := strings.TrimSpace(pd.Year.Value), strings.TrimSpace(pd.Month.Value), strings.TrimSpace(pd.Day.Value)
+		for _, v := range []struct{ layout, value string }{
+			{"2006-1-2", fmt.Sprintf("%s-%s-%s", y, m, d)},
+			{"2006-1", fmt.Sprintf("%s-%s", y, m)},
+			{"2006", y},
+		} {
+			if t, err := time.Parse(v.layout, v.value); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("degruyter: book without publication date")
+}
+
+// ISBN returns print and electronic ISBN.
+func (book *Book) ISBN() (isbn, eisbn []string) {
+	for _, v := range book.Meta.ISBN {
+		if v.Format == "electronic" || v.Type == "epub" {
+			eisbn = append(eisbn, strings.TrimSpace(v.Value))
+		} else {
+			isbn = append(isbn, strings.TrimSpace(v.Value))
+		}
+	}
+	return isbn, eisbn
+}
+
+// Chapter is a book part with its book.
+type Chapter struct {
+	*BookPart
+	Book     *Book
+	packages map[string]string
+}
+
+// DOI returns the DOI of the chapter.
+func (c *Chapter) DOI() string {
+	for _, id := range c.Meta.IDs {
+		if id.Type == "doi" {
+			return strings.TrimSpace(id.Value)
+		}
+	}
+	return ""
+}
+
+// ToIntermediateSchema converts a book chapter. Chapters without DOI or
+// date are skipped.
+func (c *Chapter) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	doi := c.DOI()
+	if doi == "" {
+		return output, span.Skip{Reason: "degruyter: chapter without DOI"}
+	}
+	output.Date, err = c.Book.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+	locator := fmt.Sprintf("http://dx.doi.org/%s", doi)
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(locator)))
+	output.RecordID = strings.TrimRight(enc, "=")
+	output.DOI = doi
+	output.URL = append(output.URL, locator)
 	output.SourceID = SourceID
 
+	output.Format = ChapterFormat
+	output.Genre = "bookitem"
+	output.RefType = "ECHAP"
+	output.ArticleTitle = strings.TrimSpace(c.Meta.Title)
+	output.ArticleSubtitle = strings.TrimSpace(c.Meta.Subtitle)
+	output.Abstract = strings.TrimSpace(c.Meta.Abstract)
+	for _, contrib := range c.Meta.Contribs {
+		if contrib.Type != "" && contrib.Type != "author" {
+			continue
+		}
+		output.Authors = append(output.Authors, finc.Author{
+			LastName:  strings.TrimSpace(contrib.Name.Surname),
+			FirstName: strings.TrimSpace(contrib.Name.GivenNames)})
+	}
+	output.StartPage, output.EndPage = c.Meta.FirstPage, c.Meta.LastPage
+	if output.StartPage != "" && output.EndPage != "" {
+		output.Pages = fmt.Sprintf("%s-%s", output.StartPage, output.EndPage)
+	}
+
+	output.BookTitle = strings.TrimSpace(c.Book.Meta.Title)
+	output.Series = strings.TrimSpace(c.Book.Meta.Series)
+	output.ISBN, output.EISBN = c.Book.ISBN()
+	if c.Book.Meta.Publisher != "" {
+		output.Publishers = append(output.Publishers, c.Book.Meta.Publisher)
+	}
+	output.MegaCollection = collection(c.packages, output.ISBN, output.EISBN)
 	return output, nil
 }
@@ -0,0 +1,86 @@
+package degruyter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/miku/span/internal/spantest"
+)
+
+const example = `<deliveries>
+<article>
+<front>
+<journal-meta>
+	<journal-title-group><journal-title>Zeitschrift für Beispiele</journal-title></journal-title-group>
+	<issn pub-type="ppub">0044-0000</issn>
+</journal-meta>
+<article-meta>
+	<article-id pub-id-type="doi">10.1515/zfb-2016-0001</article-id>
+	<title-group><article-title>Ein Artikel</article-title></title-group>
+	<pub-date pub-type="ppub"><year>2016</year></pub-date>
+</article-meta>
+</front>
+</article>
+<book-part-wrapper>
+	<book-meta>
+		<book-id pub-id-type="doi">10.1515/9783110000000</book-id>
+		<book-title-group><book-title>Handbuch der Beispiele</book-title></book-title-group>
+		<isbn publication-format="print">9783110000000</isbn>
+		<isbn publication-format="electronic">9783110000017</isbn>
+		<pub-date><year>2015</year><month>6</month></pub-date>
+		<publisher><publisher-name>De Gruyter</publisher-name></publisher>
+	</book-meta>
+	<book-part book-part-type="chapter">
+		<book-part-meta>
+			<book-part-id pub-id-type="doi">10.1515/9783110000017-001</book-part-id>
+			<title-group><title>Einleitung</title></title-group>
+			<contrib-group><contrib contrib-type="author"><name><surname>Muster</surname><given-names>Erika</given-names></name></contrib></contrib-group>
+			<fpage>1</fpage>
+			<lpage>12</lpage>
+		</book-part-meta>
+	</book-part>
+</book-part-wrapper>
+</deliveries>`
+
+func TestDeGruyter(t *testing.T) {
+	results := spantest.MustConvertAll(t, DeGruyter{}, strings.NewReader(example))
+	if len(results) != 2 {
+		t.Fatalf("got %d records, want 2", len(results))
+	}
+	article, chapter := results[0], results[1]
+	if article.Format != Format || article.MegaCollection != SourceName {
+		t.Errorf("got format %s, collection %s", article.Format, article.MegaCollection)
+	}
+	if chapter.Format != ChapterFormat || chapter.Genre != "bookitem" || chapter.BookTitle != "Handbuch der Beispiele" {
+		t.Errorf("got format %s, genre %s, book title %q", chapter.Format, chapter.Genre, chapter.BookTitle)
+	}
+	if chapter.DOI != "10.1515/9783110000017-001" || chapter.Pages != "1-12" || chapter.Date.Format("2006-01") != "2015-06" {
+		t.Errorf("got DOI %s, pages %s, date %v", chapter.DOI, chapter.Pages, chapter.Date)
+	}
+	if len(chapter.ISBN) != 1 || len(chapter.EISBN) != 1 || chapter.EISBN[0] != "9783110000017" {
+		t.Errorf("got ISBN %v, EISBN %v", chapter.ISBN, chapter.EISBN)
+	}
+	if len(chapter.Authors) != 1 || chapter.Authors[0].LastName != "Muster" {
+		t.Errorf("got authors %v", chapter.Authors)
+	}
+}
+
+func TestPackages(t *testing.T) {
+	packages, err := ReadPackages(strings.NewReader(`{
+		"0044-0000": "DeGruyter Journals Linguistics",
+		"9783110000017": "DeGruyter eBooks Humanities"
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := spantest.MustConvertAll(t, DeGruyter{Packages: packages}, strings.NewReader(example))
+	if len(results) != 2 {
+		t.Fatalf("got %d records, want 2", len(results))
+	}
+	if results[0].MegaCollection != "DeGruyter Journals Linguistics" {
+		t.Errorf("got article collection %q", results[0].MegaCollection)
+	}
+	if results[1].MegaCollection != "DeGruyter eBooks Humanities" {
+		t.Errorf("got chapter collection %q", results[1].MegaCollection)
+	}
+}
@@ -0,0 +1,205 @@
+// Package highwire converts HighWire hosted article headers into the
+// intermediate schema. Backfiles of society presses come as SGML derived
+// from the NLM article DTD: tag names may be upper case, end tags may be
+// missing and character entities are not declared. The headers are
+// normalized into well-formed JATS before conversion.
+package highwire
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+	"github.com/miku/span/jats"
+)
+
+const (
+	// SourceID for internal bookkeeping.
+	SourceID = "156"
+	// SourceName for finc.mega_collection.
+	SourceName = "HighWire"
+	// Format for intermediate schema.
+	Format = "ElectronicArticle"
+	// Batchsize number of documents per batch.
+	BatchSize = 2000
+)
+
+// HighWire source.
+type HighWire struct{}
+
+// Article with extras for this source.
+type Article struct {
+	jats.Article
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(docs []*Article) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			return s.(span.Importer), nil
+		}, Items: make([]interface{}, len(docs))}
+	for i, doc := range docs {
+		batch.Items[i] = doc
+	}
+	return batch
+}
+
+// NewDecoder returns a lenient decoder for SGML headers, that knows the
+// HTML character entities.
+func NewDecoder(r io.Reader) *xml.Decoder {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+	return decoder
+}
+
+// normalize lower cases element and attribute names and drops namespaces.
+func normalize(t xml.Token) xml.Token {
+	switch v := t.(type) {
+	case xml.StartElement:
+		v = v.Copy()
+		v.Name = xml.Name{Local: strings.ToLower(v.Name.Local)}
+		var attrs []xml.Attr
+		for _, attr := range v.Attr {
+			if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+				continue
+			}
+			attrs = append(attrs, xml.Attr{Name: xml.Name{Local: strings.ToLower(attr.Name.Local)}, Value: attr.Value})
+		}
+		v.Attr = attrs
+		return v
+	case xml.EndElement:
+		return xml.EndElement{Name: xml.Name{Local: strings.ToLower(v.Name.Local)}}
+	case xml.CharData:
+		return v.Copy()
+	}
+	return nil
+}
+
+// readArticle reads the tokens of an article, which has just started, and
+// returns them as well-formed XML.
+func readArticle(decoder *xml.Decoder, start xml.StartElement) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	if err := enc.EncodeToken(normalize(start)); err != nil {
+		return nil, err
+	}
+	depth := 1
+	for depth > 0 {
+		t, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+		if n := normalize(t); n != nil {
+			if err := enc.EncodeToken(n); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Iterate emits Converter elements via lenient XML decoding.
+func (s HighWire) Iterate(r io.Reader) (<-chan interface{}, error) {
+	ch := make(chan interface{})
+	var docs []*Article
+	go func() {
+		decoder := NewDecoder(bufio.NewReader(r))
+		for {
+			t, _ := decoder.Token()
+			if t == nil {
+				break
+			}
+			switch se := t.(type) {
+			case xml.StartElement:
+				if strings.ToLower(se.Name.Local) == "article" {
+					b, err := readArticle(decoder, se)
+					if err != nil {
+						log.Fatal(err)
+					}
+					doc := new(Article)
+					if err := xml.Unmarshal(b, doc); err != nil {
+						log.Fatal(err)
+					}
+					docs = append(docs, doc)
+					if len(docs) == BatchSize {
+						ch <- NewBatch(docs)
+						docs = nil
+					}
+				}
+			}
+		}
+		ch <- NewBatch(docs)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Identifiers returns the doi and the dependent url and recordID in a struct.
+// Backfiles often lack a DOI, the publisher id of an article, qualified by
+// the journal id, is used instead.
+func (article *Article) Identifiers() (jats.Identifiers, error) {
+	var ids jats.Identifiers
+	doi, _ := article.DOI()
+	key, locator := doi, article.Front.Article.SelfURI.Value
+	if doi != "" {
+		locator = fmt.Sprintf("https://doi.org/%s", doi)
+	} else {
+		for _, id := range article.Front.Article.ID {
+			if id.Type == "publisher-id" && strings.TrimSpace(id.Value) != "" {
+				key = fmt.Sprintf("%s/%s", article.Front.Journal.ID.Value, strings.TrimSpace(id.Value))
+				break
+			}
+		}
+	}
+	if key == "" {
+		return ids, fmt.Errorf("highwire: article without DOI or publisher id")
+	}
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(key)))
+	ids.DOI, ids.URL, ids.RecordID = doi, locator, strings.TrimRight(enc, "=")
+	return ids, nil
+}
+
+// ToInternalSchema converts a jats article into an internal schema.
+func (article *Article) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	output, err := article.Article.ToIntermediateSchema()
+	if err != nil {
+		return output, err
+	}
+
+	ids, err := article.Identifiers()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+	if output.Date.IsZero() {
+		return output, span.Skip{Reason: fmt.Sprintf("highwire: %s: no publication date", ids.RecordID)}
+	}
+	output.DOI = ids.DOI
+	output.RecordID = ids.RecordID
+	if ids.URL != "" {
+		output.URL = append(output.URL, ids.URL)
+	}
+
+	output.Format = Format
+	output.MegaCollection = SourceName
+	output.SourceID = SourceID
+
+	return output, nil
+}
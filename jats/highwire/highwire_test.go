@@ -0,0 +1,65 @@
+package highwire
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/internal/spantest"
+)
+
+// example has upper case tags, an undeclared entity and a paragraph
+// without end tag, like HighWire SGML headers.
+const example = `<!DOCTYPE ARTICLE SYSTEM "hwp-article.dtd">
+<ARTICLE>
+<FRONT>
+<JOURNAL-META>
+	<JOURNAL-ID journal-id-type="hwp">socpress</JOURNAL-ID>
+	<JOURNAL-TITLE-GROUP><JOURNAL-TITLE>Bulletin of the Society</JOURNAL-TITLE></JOURNAL-TITLE-GROUP>
+	<ISSN pub-type="ppub">0000-0019</ISSN>
+</JOURNAL-META>
+<ARTICLE-META>
+	<ARTICLE-ID pub-id-type="publisher-id">12/3/45</ARTICLE-ID>
+	<TITLE-GROUP><ARTICLE-TITLE>Caf&eacute; society &mdash; a study</ARTICLE-TITLE></TITLE-GROUP>
+	<CONTRIB-GROUP><CONTRIB contrib-type="author"><NAME><SURNAME>Doe</SURNAME><GIVEN-NAMES>J.</GIVEN-NAMES></NAME></CONTRIB></CONTRIB-GROUP>
+	<PUB-DATE pub-type="ppub"><MONTH>4</MONTH><YEAR>1962</YEAR></PUB-DATE>
+	<VOLUME>12</VOLUME>
+	<ISSUE>3</ISSUE>
+	<FPAGE>45</FPAGE>
+	<LPAGE>60</LPAGE>
+	<ABSTRACT><P>First paragraph<P>Second paragraph</ABSTRACT>
+</ARTICLE-META>
+</FRONT>
+</ARTICLE>
+<ARTICLE>
+<FRONT><ARTICLE-META><ARTICLE-ID pub-id-type="doi">10.1000/hw.2</ARTICLE-ID><TITLE-GROUP><ARTICLE-TITLE>Undated</ARTICLE-TITLE></TITLE-GROUP></ARTICLE-META></FRONT>
+</ARTICLE>`
+
+func TestHighWire(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, HighWire{}, strings.NewReader(example))
+	if len(results) != 2 {
+		t.Fatalf("got %d records, want 2", len(results))
+	}
+	if errs[0] != nil {
+		t.Fatal(errs[0])
+	}
+	if _, ok := errs[1].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for an undated article", errs[1])
+	}
+	is := results[0]
+	if is.ArticleTitle != "Café society — a study" {
+		t.Errorf("got title %q", is.ArticleTitle)
+	}
+	if is.JournalTitle != "Bulletin of the Society" || is.Volume != "12" || is.Pages != "45-60" {
+		t.Errorf("got journal %q, volume %q, pages %q", is.JournalTitle, is.Volume, is.Pages)
+	}
+	if is.Date.Format("2006-01") != "1962-04" || len(is.Authors) != 1 || is.Authors[0].LastName != "Doe" {
+		t.Errorf("got date %v, authors %v", is.Date, is.Authors)
+	}
+	if is.DOI != "" || is.RecordID == "" || is.SourceID != SourceID {
+		t.Errorf("got DOI %q, record id %q, source id %q", is.DOI, is.RecordID, is.SourceID)
+	}
+	if !strings.Contains(is.Abstract, "Second paragraph") {
+		t.Errorf("got abstract %q", is.Abstract)
+	}
+}
@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/miku/span"
 	"github.com/miku/span/container"
@@ -25,6 +28,22 @@ const (
 	Format = "ElectronicArticle"
 	// Batchsize number of documents per batch.
 	BatchSize = 2000
+	// StableURLPrefix is the prefix of JSTOR stable URLs.
+	StableURLPrefix = "https://www.jstor.org/stable/"
+	// DOIPrefix is the DOI prefix, JSTOR assigns to its own items.
+	DOIPrefix = "10.2307/"
+)
+
+var (
+	// seasons maps seasons, as used by quarterlies, to their first month.
+	seasons = map[string]time.Month{
+		"spring": time.March,
+		"summer": time.June,
+		"autumn": time.September,
+		"fall":   time.September,
+		"winter": time.December,
+	}
+	yearPattern = regexp.MustCompile(`\b(1[5-9]|20)[0-9]{2}\b`)
 )
 
 // Jstor source.
@@ -83,16 +102,95 @@ func (s Jstor) Iterate(r io.Reader) (<-chan interface{}, error) {
 	return ch, nil
 }
 
+// StableURL returns the stable URL of an article. It is built from the
+// jstor article id or a JSTOR DOI, and falls back to the self URI.
+func (article *Article) StableURL() string {
+	for _, id := range article.Front.Article.ID {
+		if id.Type == "jstor" && strings.TrimSpace(id.Value) != "" {
+			return StableURLPrefix + strings.TrimSpace(id.Value)
+		}
+	}
+	if doi, err := article.DOI(); err == nil && strings.HasPrefix(doi, DOIPrefix) {
+		return StableURLPrefix + strings.TrimPrefix(doi, DOIPrefix)
+	}
+	return article.Front.Article.SelfURI.Value
+}
+
 // Identifiers returns the doi and the dependent url and recordID in a struct.
-// Records from this source do not need a DOI necessarily.
+// Records from this source do not need a DOI necessarily. The record id is
+// derived from the self URI, if present, to keep ids of existing records.
 func (article *Article) Identifiers() (jats.Identifiers, error) {
 	doi, _ := article.DOI()
-	locator := article.Front.Article.SelfURI.Value
-	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(locator)))
+	locator := article.StableURL()
+	if locator == "" {
+		return jats.Identifiers{}, fmt.Errorf("jstor: article without locator")
+	}
+	id := article.Front.Article.SelfURI.Value
+	if id == "" {
+		id = locator
+	}
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(id)))
 	recordID := strings.TrimRight(enc, "=")
 	return jats.Identifiers{DOI: doi, URL: locator, RecordID: recordID}, nil
 }
 
+// isPrint returns true, if a date is the date of the print issue.
+func isPrint(pd jats.PubDate) bool {
+	return pd.Type == "ppub" || pd.PublicationFormat == "print"
+}
+
+// parsePubDate parses a date with numeric or season month. If only a string
+// date is given, the year is taken from it.
+func parsePubDate(pd jats.PubDate) (time.Time, error) {
+	year, err := strconv.Atoi(strings.TrimSpace(pd.Year.Value))
+	if err != nil {
+		match := yearPattern.FindString(pd.StringDate.Value)
+		if match == "" {
+			return time.Time{}, fmt.Errorf("jstor: no year in date")
+		}
+		year, _ = strconv.Atoi(match)
+	}
+	month, day := time.January, 1
+	m := strings.ToLower(strings.TrimSpace(pd.Month.Value))
+	if v, err := strconv.Atoi(m); err == nil && v >= 1 && v <= 12 {
+		month = time.Month(v)
+	} else if v, ok := seasons[m]; ok {
+		month = v
+	}
+	if v, err := strconv.Atoi(strings.TrimSpace(pd.Day.Value)); err == nil && v >= 1 && v <= 31 {
+		day = v
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC), nil
+}
+
+// Date returns the publication date of the print issue. Moving walls count
+// from the issue date, while the electronic date on JSTOR is often the date
+// of digitization, years later. The electronic date is only used, if there
+// is no other date.
+func (article *Article) Date() (time.Time, error) {
+	dates := article.Front.Article.PubDates
+	for _, pd := range dates {
+		if isPrint(pd) {
+			if t, err := parsePubDate(pd); err == nil {
+				return t, nil
+			}
+		}
+	}
+	for _, pd := range dates {
+		if pd.Type != "epub" && pd.PublicationFormat != "electronic" {
+			if t, err := parsePubDate(pd); err == nil {
+				return t, nil
+			}
+		}
+	}
+	for _, pd := range dates {
+		if t, err := parsePubDate(pd); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("jstor: no usable publication date")
+}
+
 // Authors returns the authors as slice.
 func (article *Article) Authors() []finc.Author {
 	var authors []finc.Author
@@ -128,10 +226,14 @@ func (article *Article) ToIntermediateSchema() (*finc.IntermediateSchema, error)
 	if err != nil {
 		return output, err
 	}
+	output.Date, err = article.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
 
 	ids, err := article.Identifiers()
 	if err != nil {
-		return output, err
+		return output, span.Skip{Reason: err.Error()}
 	}
 	output.DOI = ids.DOI
 	output.RecordID = ids.RecordID
@@ -0,0 +1,115 @@
+package jstor
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/miku/span"
+)
+
+const example = `<article xmlns:xlink="http://www.w3.org/1999/xlink">
+<front>
+<journal-meta>
+	<journal-title-group><journal-title>Journal of Examples</journal-title></journal-title-group>
+	<issn pub-type="ppub">12345678</issn>
+	<publisher><publisher-name>Example Press</publisher-name></publisher>
+</journal-meta>
+<article-meta>
+	<article-id pub-id-type="doi">10.2307/1234567</article-id>
+	<article-id pub-id-type="jstor">1234567</article-id>
+	<title-group><article-title>On Examples</article-title></title-group>
+	<pub-date pub-type="epub"><day>12</day><month>05</month><year>2009</year></pub-date>
+	<pub-date pub-type="ppub"><month>Autumn</month><year>1987</year></pub-date>
+	<volume>12</volume>
+	<issue>3</issue>
+	<fpage>101</fpage>
+	<lpage>120</lpage>
+</article-meta>
+</front>
+</article>`
+
+func TestToIntermediateSchema(t *testing.T) {
+	var article Article
+	if err := xml.Unmarshal([]byte(example), &article); err != nil {
+		t.Fatal(err)
+	}
+	is, err := article.ToIntermediateSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := is.Date.Format("2006-01-02"); got != "1987-09-01" {
+		t.Errorf("got date %s, want print date 1987-09-01", got)
+	}
+	if len(is.URL) != 1 || is.URL[0] != "https://www.jstor.org/stable/1234567" {
+		t.Errorf("got URL %v", is.URL)
+	}
+	if len(is.ISSN) != 1 || is.ISSN[0] != "1234-5678" {
+		t.Errorf("got ISSN %v", is.ISSN)
+	}
+}
+
+func TestStableURL(t *testing.T) {
+	var cases = []struct {
+		about string
+		xml   string
+		want  string
+	}{
+		{"jstor id", `<article><front><article-meta><article-id pub-id-type="jstor">42</article-id></article-meta></front></article>`,
+			"https://www.jstor.org/stable/42"},
+		{"jstor doi", `<article><front><article-meta><article-id pub-id-type="doi">10.2307/42</article-id></article-meta></front></article>`,
+			"https://www.jstor.org/stable/42"},
+		{"other doi, self uri", `<article xmlns:xlink="http://www.w3.org/1999/xlink"><front><article-meta><article-id pub-id-type="doi">10.1000/x</article-id><self-uri xlink:href="http://www.jstor.org/stable/10.1000/x"/></article-meta></front></article>`,
+			"http://www.jstor.org/stable/10.1000/x"},
+	}
+	for _, c := range cases {
+		var article Article
+		if err := xml.Unmarshal([]byte(c.xml), &article); err != nil {
+			t.Fatal(err)
+		}
+		if got := article.StableURL(); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.about, got, c.want)
+		}
+	}
+}
+
+func TestDate(t *testing.T) {
+	var cases = []struct {
+		xml  string
+		want string
+		err  bool
+	}{
+		{`<article><front><article-meta><pub-date date-type="pub" publication-format="electronic"><year>2010</year></pub-date><pub-date date-type="pub" publication-format="print"><month>3</month><year>1950</year></pub-date></article-meta></front></article>`,
+			"1950-03-01", false},
+		{`<article><front><article-meta><pub-date pub-type="ppub"><string-date>Winter 1899-1900</string-date></pub-date></article-meta></front></article>`,
+			"1899-01-01", false},
+		{`<article><front><article-meta><pub-date pub-type="epub"><year>2001</year></pub-date></article-meta></front></article>`,
+			"2001-01-01", false},
+		{`<article><front><article-meta></article-meta></front></article>`, "", true},
+	}
+	for _, c := range cases {
+		var article Article
+		if err := xml.Unmarshal([]byte(c.xml), &article); err != nil {
+			t.Fatal(err)
+		}
+		got, err := article.Date()
+		if (err != nil) != c.err {
+			t.Errorf("got error %v, want error %v", err, c.err)
+			continue
+		}
+		if err == nil && got.Format("2006-01-02") != c.want {
+			t.Errorf("got %s, want %s", got.Format("2006-01-02"), c.want)
+		}
+	}
+}
+
+func TestMissingDateSkips(t *testing.T) {
+	var article Article
+	if err := xml.Unmarshal([]byte(`<article><front><article-meta><article-id pub-id-type="jstor">1</article-id></article-meta></front></article>`), &article); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := article.ToIntermediateSchema(); err == nil {
+		t.Fatal("expected error")
+	} else if _, ok := err.(span.Skip); !ok {
+		t.Errorf("got %T, want span.Skip", err)
+	}
+}
@@ -0,0 +1,174 @@
+package thieme
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/kennygrant/sanitize"
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+	"github.com/miku/span/jats"
+)
+
+const (
+	// SourceID for internal bookkeeping.
+	SourceID = "155"
+	// SourceName for finc.mega_collection.
+	SourceName = "Thieme E-Journals"
+	// Format for intermediate schema.
+	Format = "ElectronicArticle"
+	// Batchsize number of documents per batch.
+	BatchSize = 2000
+)
+
+// abstractTitle matches the heading of an abstract, like Zusammenfassung.
+var abstractTitle = regexp.MustCompile(`(?s)<title[^>]*>.*?</title>`)
+
+// Thieme source.
+type Thieme struct{}
+
+// Article with extras for this source. Thieme uses the NLM journal
+// publishing DTD and sets the language of an article on the root element.
+type Article struct {
+	jats.Article
+	Lang string `xml:"lang,attr"`
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(docs []*Article) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			return s.(span.Importer), nil
+		}, Items: make([]interface{}, len(docs))}
+	for i, doc := range docs {
+		batch.Items[i] = doc
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via XML decoding.
+func (s Thieme) Iterate(r io.Reader) (<-chan interface{}, error) {
+	ch := make(chan interface{})
+	var docs []*Article
+	go func() {
+		decoder := xml.NewDecoder(bufio.NewReader(r))
+		for {
+			t, _ := decoder.Token()
+			if t == nil {
+				break
+			}
+			switch se := t.(type) {
+			case xml.StartElement:
+				if se.Name.Local == "article" {
+					doc := new(Article)
+					if err := decoder.DecodeElement(doc, &se); err != nil {
+						log.Fatal(err)
+					}
+					docs = append(docs, doc)
+					if len(docs) == BatchSize {
+						ch <- NewBatch(docs)
+						docs = nil
+					}
+				}
+			}
+		}
+		ch <- NewBatch(docs)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Identifiers returns the doi and the dependent url and recordID in a struct.
+// It is an error, if there is no DOI.
+func (article *Article) Identifiers() (jats.Identifiers, error) {
+	var ids jats.Identifiers
+	doi, err := article.DOI()
+	if err != nil {
+		return ids, err
+	}
+	locator := fmt.Sprintf("https://doi.org/%s", doi)
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(doi)))
+	recordID := strings.TrimRight(enc, "=")
+	return jats.Identifiers{DOI: doi, URL: locator, RecordID: recordID}, nil
+}
+
+// ISSN returns print and electronic ISSN separately.
+func (article *Article) ISSN() (issn, eissn []string) {
+	for _, v := range article.Front.Journal.ISSN {
+		if v.Type == "epub" {
+			eissn = append(eissn, strings.TrimSpace(v.Value))
+		} else {
+			issn = append(issn, strings.TrimSpace(v.Value))
+		}
+	}
+	return issn, eissn
+}
+
+// abstractText removes the heading and markup of an abstract.
+func abstractText(s string) string {
+	s = abstractTitle.ReplaceAllString(s, "")
+	return strings.Join(strings.Fields(sanitize.HTML(s)), " ")
+}
+
+// Abstract returns the abstract in the language of the article. Many
+// articles have a German abstract and an English translated abstract, or
+// the other way around; the main abstract is used, if the languages do not
+// tell.
+func (article *Article) Abstract() string {
+	meta := article.Front.Article
+	lang := span.NormalizeLanguage(article.Lang)
+	if meta.TranslatedAbstract.Value != "" && lang != "und" &&
+		span.NormalizeLanguage(meta.TranslatedAbstract.Lang) == lang &&
+		span.NormalizeLanguage(meta.Abstract.Lang) != lang {
+		return abstractText(meta.TranslatedAbstract.Value)
+	}
+	if meta.Abstract.Value != "" {
+		return abstractText(meta.Abstract.Value)
+	}
+	return abstractText(meta.TranslatedAbstract.Value)
+}
+
+// Languages returns the language of the article, or the language of the
+// main abstract.
+func (article *Article) Languages() []string {
+	for _, v := range []string{article.Lang, article.Front.Article.Abstract.Lang} {
+		if lang := span.NormalizeLanguage(v); lang != "und" {
+			return []string{lang}
+		}
+	}
+	return nil
+}
+
+// ToInternalSchema converts a jats article into an internal schema.
+func (article *Article) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	output, err := article.Article.ToIntermediateSchema()
+	if err != nil {
+		return output, err
+	}
+
+	ids, err := article.Identifiers()
+	if err != nil {
+		return output, span.Skip{Reason: fmt.Sprintf("thieme: %v", err)}
+	}
+	if output.Date.IsZero() {
+		return output, span.Skip{Reason: fmt.Sprintf("thieme: %s: no publication date", ids.DOI)}
+	}
+	output.DOI = ids.DOI
+	output.RecordID = ids.RecordID
+	output.URL = append(output.URL, ids.URL)
+
+	output.Abstract = article.Abstract()
+	output.ISSN, output.EISSN = article.ISSN()
+	output.Languages = article.Languages()
+	output.Format = Format
+	output.MegaCollection = SourceName
+	output.SourceID = SourceID
+
+	return output, nil
+}
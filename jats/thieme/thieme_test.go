@@ -0,0 +1,91 @@
+package thieme
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/miku/span"
+)
+
+const example = `<article xml:lang="de" article-type="research-article">
+<front>
+<journal-meta>
+	<journal-title-group><journal-title>Das Gesundheitswesen</journal-title></journal-title-group>
+	<issn pub-type="ppub">0941-3790</issn>
+	<issn pub-type="epub">1439-4421</issn>
+	<publisher><publisher-name>Georg Thieme Verlag KG</publisher-name></publisher>
+</journal-meta>
+<article-meta>
+	<article-id pub-id-type="doi">10.1055/s-0035-1549999</article-id>
+	<title-group><article-title>Versorgung im Beispiel</article-title></title-group>
+	<pub-date pub-type="ppub"><month>05</month><year>2016</year></pub-date>
+	<volume>78</volume>
+	<issue>5</issue>
+	<fpage>301</fpage>
+	<lpage>306</lpage>
+	<abstract xml:lang="en"><title>Abstract</title><p>An <italic>English</italic> abstract.</p></abstract>
+	<trans-abstract xml:lang="de"><title>Zusammenfassung</title><p>Eine deutsche Zusammenfassung.</p></trans-abstract>
+</article-meta>
+</front>
+</article>`
+
+func TestToIntermediateSchema(t *testing.T) {
+	var article Article
+	if err := xml.Unmarshal([]byte(example), &article); err != nil {
+		t.Fatal(err)
+	}
+	is, err := article.ToIntermediateSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if is.DOI != "10.1055/s-0035-1549999" || is.URL[0] != "https://doi.org/10.1055/s-0035-1549999" {
+		t.Errorf("got DOI %q, URL %v", is.DOI, is.URL)
+	}
+	if len(is.ISSN) != 1 || is.ISSN[0] != "0941-3790" || len(is.EISSN) != 1 || is.EISSN[0] != "1439-4421" {
+		t.Errorf("got ISSN %v, EISSN %v", is.ISSN, is.EISSN)
+	}
+	if is.Abstract != "Eine deutsche Zusammenfassung." {
+		t.Errorf("got abstract %q, want the German abstract", is.Abstract)
+	}
+	if len(is.Languages) != 1 || is.Languages[0] != "deu" {
+		t.Errorf("got languages %v", is.Languages)
+	}
+	if is.Date.Format("2006-01") != "2016-05" || is.Pages != "301-306" {
+		t.Errorf("got date %v, pages %q", is.Date, is.Pages)
+	}
+}
+
+func TestAbstract(t *testing.T) {
+	var cases = []struct {
+		xml  string
+		want string
+	}{
+		{`<article xml:lang="en"><front><article-meta><abstract xml:lang="en"><title>Abstract</title><p>Only one.</p></abstract></article-meta></front></article>`,
+			"Only one."},
+		{`<article><front><article-meta><abstract><p>Main.</p></abstract><trans-abstract xml:lang="de"><p>Übersetzt.</p></trans-abstract></article-meta></front></article>`,
+			"Main."},
+		{`<article><front><article-meta><trans-abstract xml:lang="de"><p>Nur übersetzt.</p></trans-abstract></article-meta></front></article>`,
+			"Nur übersetzt."},
+	}
+	for _, c := range cases {
+		var article Article
+		if err := xml.Unmarshal([]byte(c.xml), &article); err != nil {
+			t.Fatal(err)
+		}
+		if got := article.Abstract(); got != c.want {
+			t.Errorf("got %q, want %q", got, c.want)
+		}
+	}
+}
+
+func TestMissingDOISkips(t *testing.T) {
+	var article Article
+	if err := xml.Unmarshal([]byte(`<article><front><article-meta></article-meta></front></article>`), &article); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := article.ToIntermediateSchema(); err == nil {
+		t.Fatal("expected error")
+	} else if _, ok := err.(span.Skip); !ok {
+		t.Errorf("got %T, want span.Skip", err)
+	}
+}
@@ -0,0 +1,79 @@
+package span
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Sink consumes the objects of a byte channel, e.g. writes them to files or
+// sends them to a message queue.
+type Sink interface {
+	// Run consumes all objects from out and signals done, when finished.
+	Run(out chan []byte, done chan bool)
+	// Err returns an error, that made the sink stop.
+	Err() error
+}
+
+// Producer sends keyed messages, e.g. to a Kafka topic. Produce may block,
+// if the producer cannot keep up. Close flushes pending messages.
+type Producer interface {
+	Produce(key, value []byte) error
+	Close() error
+}
+
+// KeyFunc derives a message key from an object.
+type KeyFunc func([]byte) ([]byte, error)
+
+// JSONIDKey uses the id field of a JSON document as key, so all versions of
+// a document end up in the same partition.
+func JSONIDKey(b []byte) ([]byte, error) {
+	var doc struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	if doc.ID == "" {
+		return nil, errors.New("document without id")
+	}
+	return []byte(doc.ID), nil
+}
+
+// ProducerSink sends each object as a single message. After the first error
+// remaining objects are drained, but not sent.
+type ProducerSink struct {
+	Producer Producer
+	Key      KeyFunc
+
+	err     error
+	records int64
+}
+
+// Run sends all objects from out. The producer is not closed, so Run can be
+// called again with a new channel.
+func (s *ProducerSink) Run(out chan []byte, done chan bool) {
+	for b := range out {
+		if s.err != nil {
+			continue
+		}
+		key, err := s.Key(b)
+		if err != nil {
+			s.err = err
+			continue
+		}
+		if s.err = s.Producer.Produce(key, b); s.err == nil {
+			s.records++
+		}
+	}
+	done <- true
+}
+
+// Err returns the first error.
+func (s *ProducerSink) Err() error {
+	return s.err
+}
+
+// Records returns the number of messages sent.
+func (s *ProducerSink) Records() int64 {
+	return s.records
+}
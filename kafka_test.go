@@ -0,0 +1,60 @@
+package span
+
+import (
+	"reflect"
+	"testing"
+)
+
+// fakeProducer records messages.
+type fakeProducer struct {
+	keys   []string
+	values []string
+	closed bool
+}
+
+func (p *fakeProducer) Produce(key, value []byte) error {
+	p.keys = append(p.keys, string(key))
+	p.values = append(p.values, string(value))
+	return nil
+}
+
+func (p *fakeProducer) Close() error {
+	p.closed = true
+	return nil
+}
+
+func TestProducerSink(t *testing.T) {
+	p := &fakeProducer{}
+	s := &ProducerSink{Producer: p, Key: JSONIDKey}
+	docs := []string{`{"id": "ai-49-a", "title": "A"}`, `{"id": "ai-49-b"}`, `{"id": "ai-28-c"}`}
+
+	out := make(chan []byte)
+	done := make(chan bool)
+	go s.Run(out, done)
+	for _, doc := range docs {
+		out <- []byte(doc)
+	}
+	close(out)
+	<-done
+
+	if s.Err() != nil {
+		t.Fatal(s.Err())
+	}
+	if want := []string{"ai-49-a", "ai-49-b", "ai-28-c"}; !reflect.DeepEqual(p.keys, want) {
+		t.Errorf("ProducerSink: got keys %v, want %v", p.keys, want)
+	}
+	if !reflect.DeepEqual(p.values, docs) || s.Records() != 3 {
+		t.Errorf("ProducerSink: got %d records %v, want %v", s.Records(), p.values, docs)
+	}
+
+	s = &ProducerSink{Producer: p, Key: JSONIDKey}
+	out = make(chan []byte)
+	go s.Run(out, done)
+	out <- []byte(`{"title": "no id"}`)
+	out <- []byte(`{"id": "ai-49-d"}`)
+	close(out)
+	<-done
+	if s.Err() == nil || s.Records() != 0 {
+		t.Errorf("ProducerSink: got %v, %d records, want error and no records", s.Err(), s.Records())
+	}
+}
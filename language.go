@@ -0,0 +1,39 @@
+package span
+
+import (
+	"strings"
+
+	"github.com/miku/span/finc"
+)
+
+// languageAliases maps ISO 639-1 and ISO 639-2/B codes to ISO 639-3.
+var languageAliases = map[string]string{
+	"ar": "ara", "cs": "ces", "cze": "ces", "da": "dan", "de": "deu",
+	"ger": "deu", "el": "ell", "gre": "ell", "en": "eng", "es": "spa",
+	"fi": "fin", "fr": "fra", "fre": "fra", "hu": "hun", "it": "ita",
+	"ja": "jpn", "la": "lat", "nl": "nld", "dut": "nld", "no": "nor",
+	"pl": "pol", "pt": "por", "ru": "rus", "sv": "swe", "tr": "tur",
+	"zh": "zho", "chi": "zho",
+}
+
+// NormalizeLanguage returns the ISO 639-3 code for a language code, e.g. deu
+// for de, DE or ger, or "und", if the code is unknown.
+func NormalizeLanguage(s string) string {
+	if code, ok := lookupLanguage(s); ok {
+		return code
+	}
+	return "und"
+}
+
+// lookupLanguage returns the ISO 639-3 code for a language code and whether
+// the code is known.
+func lookupLanguage(s string) (string, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if code, ok := languageAliases[s]; ok {
+		return code, true
+	}
+	if _, ok := finc.LanguageMap[s]; ok {
+		return s, true
+	}
+	return "", false
+}
@@ -0,0 +1,23 @@
+package span
+
+import "testing"
+
+func TestNormalizeLanguage(t *testing.T) {
+	var tests = []struct {
+		s    string
+		code string
+	}{
+		{"deu", "deu"},
+		{"DE", "deu"},
+		{" ger ", "deu"},
+		{"en", "eng"},
+		{"fra", "fra"},
+		{"xx", "und"},
+		{"", "und"},
+	}
+	for _, tt := range tests {
+		if code := NormalizeLanguage(tt.s); code != tt.code {
+			t.Errorf("NormalizeLanguage(%q): got %s, want %s", tt.s, code, tt.code)
+		}
+	}
+}
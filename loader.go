@@ -0,0 +1,155 @@
+package span
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/miku/span/holdings"
+)
+
+// DefaultMaxOpenFiles is the default number of holdings files, a
+// HoldingsLoader keeps open at once.
+const DefaultMaxOpenFiles = 64
+
+// HoldingsLoader parses holdings files of many institutions concurrently.
+// At most MaxOpen files are open at any time, so large configurations do not
+// run out of file descriptors.
+type HoldingsLoader struct {
+	MaxOpen int
+	// Open opens a file, defaults to os.Open.
+	Open func(name string) (io.ReadCloser, error)
+	// Strict makes a single file, that cannot be opened or parsed, fail the
+	// whole load, otherwise only the affected ISIL is left out.
+	Strict bool
+}
+
+// LoadError reports problems with holdings files. The ISILs in Failed had a
+// file, that could not be opened or parsed, and are missing from the loaded
+// tables. Invalid counts the invalid entries in all other files.
+type LoadError struct {
+	Failed  map[string]error
+	Invalid int
+}
+
+// ISILs returns the sorted ISILs, that failed to load.
+func (e *LoadError) ISILs() []string {
+	var isils []string
+	for isil := range e.Failed {
+		isils = append(isils, isil)
+	}
+	sort.Strings(isils)
+	return isils
+}
+
+func (e *LoadError) Error() string {
+	var parts []string
+	if len(e.Failed) > 0 {
+		parts = append(parts, fmt.Sprintf("holdings of %d ISILs failed to load: %s",
+			len(e.Failed), strings.Join(e.ISILs(), ", ")))
+	}
+	if e.Invalid > 0 {
+		parts = append(parts, fmt.Sprintf("%d errors in holdings files", e.Invalid))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Load parses the holdings files given per ISIL. Licenses from several files
+// of an ISIL are merged. Errors in holdings files are emitted as diagnostics
+// and counted in the returned *LoadError, while the valid licenses are kept.
+// If a file cannot be opened or parsed, the ISIL is left out and reported in
+// the *LoadError, or, if Strict is set, no tables are returned.
+func (l HoldingsLoader) Load(files map[string][]string) (map[string]holdings.Licenses, error) {
+	tables, _, err := l.LoadEditions(files)
+	return tables, err
+}
+
+// LoadEditions works like Load, but additionally returns, per ISIL, whether
+// an ISSN was listed as print or electronic ISSN.
+func (l HoldingsLoader) LoadEditions(files map[string][]string) (map[string]holdings.Licenses, map[string]holdings.Editions, error) {
+	open := l.Open
+	if open == nil {
+		open = func(name string) (io.ReadCloser, error) { return os.Open(name) }
+	}
+	max := l.MaxOpen
+	if max < 1 {
+		max = DefaultMaxOpenFiles
+	}
+
+	type result struct {
+		licenses holdings.Licenses
+		editions holdings.Editions
+		errs     []error
+		err      error
+	}
+	results := make(map[string][]result)
+	for isil, names := range files {
+		results[isil] = make([]result, len(names))
+	}
+
+	sem := make(chan struct{}, max)
+	var wg sync.WaitGroup
+	for isil, names := range files {
+		for i, name := range names {
+			wg.Add(1)
+			go func(r *result, name string) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				rc, err := open(name)
+				if err != nil {
+					r.err = err
+					return
+				}
+				defer rc.Close()
+				r.licenses, r.editions, r.errs = holdings.ParseHoldingsEditions(rc)
+			}(&results[isil][i], name)
+		}
+	}
+	wg.Wait()
+
+	tables := make(map[string]holdings.Licenses)
+	editions := make(map[string]holdings.Editions)
+	lerr := &LoadError{Failed: make(map[string]error)}
+	for isil, rs := range results {
+		licenses, eds := make(holdings.Licenses), make(holdings.Editions)
+		var invalid int
+		for _, r := range rs {
+			if r.err == nil {
+				// a file, that is not well-formed, is not worth using
+				for _, e := range r.errs {
+					if errors.Is(e, holdings.ErrParse) {
+						r.err = e
+						break
+					}
+				}
+			}
+			if r.err != nil {
+				if l.Strict {
+					return nil, nil, fmt.Errorf("%s: %w", isil, r.err)
+				}
+				lerr.Failed[isil] = r.err
+				break
+			}
+			for _, e := range r.errs {
+				Warn(holdingsCode(e), e.Error(), "isil", isil)
+			}
+			invalid += len(r.errs)
+			licenses.Merge(r.licenses)
+			eds.Merge(r.editions)
+		}
+		if _, ok := lerr.Failed[isil]; ok {
+			continue
+		}
+		lerr.Invalid += invalid
+		tables[isil], editions[isil] = licenses, eds
+	}
+	if len(lerr.Failed) > 0 || lerr.Invalid > 0 {
+		return tables, editions, lerr
+	}
+	return tables, editions, nil
+}
@@ -0,0 +1,130 @@
+package span
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miku/span/finc"
+	"github.com/miku/span/holdings"
+)
+
+// countingOpener serves holdings from memory and records the maximum number
+// of simultaneously open files.
+type countingOpener struct {
+	mu      sync.Mutex
+	open    int
+	maxOpen int
+	files   map[string]string
+}
+
+type countingFile struct {
+	io.Reader
+	o *countingOpener
+}
+
+func (f countingFile) Close() error {
+	f.o.mu.Lock()
+	defer f.o.mu.Unlock()
+	f.o.open--
+	return nil
+}
+
+func (o *countingOpener) Open(name string) (io.ReadCloser, error) {
+	content, ok := o.files[name]
+	if !ok {
+		return nil, errors.New("no such file: " + name)
+	}
+	o.mu.Lock()
+	o.open++
+	if o.open > o.maxOpen {
+		o.maxOpen = o.open
+	}
+	o.mu.Unlock()
+	// keep the file open for a while, so loads overlap
+	time.Sleep(time.Millisecond)
+	return countingFile{Reader: strings.NewReader(content), o: o}, nil
+}
+
+func TestHoldingsLoaderMaxOpen(t *testing.T) {
+	o := &countingOpener{files: make(map[string]string)}
+	files := make(map[string][]string)
+	for i := 0; i < 20; i++ {
+		isil := fmt.Sprintf("DE-%d", i)
+		for _, end := range []string{"2001", "2010"} {
+			name := fmt.Sprintf("%s-%s.xml", isil, end)
+			o.files[name] = testHolding("2000", end, "")
+			files[isil] = append(files[isil], name)
+		}
+	}
+	loader := HoldingsLoader{MaxOpen: 3, Open: o.Open}
+	tables, err := loader.Load(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.maxOpen > 3 {
+		t.Errorf("HoldingsLoader.Load: %d files open at once, want at most 3", o.maxOpen)
+	}
+	if o.open != 0 {
+		t.Errorf("HoldingsLoader.Load: %d files not closed", o.open)
+	}
+	if len(tables) != 20 || len(tables["DE-7"]["1234-5678"]) != 2 {
+		t.Errorf("HoldingsLoader.Load: got %d tables, %v for DE-7", len(tables), tables["DE-7"])
+	}
+
+	files["DE-0"] = append(files["DE-0"], "missing.xml")
+	if _, err := loader.Load(files); err == nil {
+		t.Errorf("HoldingsLoader.Load: got nil, want error for missing file")
+	}
+}
+
+func TestHoldingsLoaderCorrupt(t *testing.T) {
+	o := &countingOpener{files: map[string]string{
+		"good.xml":    testHolding("2000", "2010", ""),
+		"corrupt.xml": `<holding ezb_id="2"><EZBIssns><p-issn>2345-6789</p-issn>`,
+	}}
+	files := map[string][]string{"DE-1": {"good.xml"}, "DE-2": {"corrupt.xml"}}
+
+	tables, err := HoldingsLoader{Open: o.Open}.Load(files)
+	var lerr *LoadError
+	if !errors.As(err, &lerr) {
+		t.Fatalf("HoldingsLoader.Load: got %v, want *LoadError", err)
+	}
+	if want := []string{"DE-2"}; !reflect.DeepEqual(lerr.ISILs(), want) {
+		t.Errorf("LoadError.ISILs: got %v, want %v", lerr.ISILs(), want)
+	}
+	if _, ok := tables["DE-2"]; ok {
+		t.Errorf("HoldingsLoader.Load: got table for corrupt DE-2")
+	}
+	tagger := make(ISILTagger)
+	for isil, table := range tables {
+		tagger[isil] = []Filter{NewHoldingFilterFromLicenses(table, time.Now())}
+	}
+	is := finc.IntermediateSchema{ISSN: []string{"1234-5678"}, Date: mustParseDate("2005-01-01")}
+	if isils := tagger.Tags(is); !reflect.DeepEqual(isils, []string{"DE-1"}) {
+		t.Errorf("Tags: got %v, want [DE-1]", isils)
+	}
+
+	if tables, err := (HoldingsLoader{Open: o.Open, Strict: true}).Load(files); err == nil || tables != nil {
+		t.Errorf("HoldingsLoader.Load (strict): got %v, %v, want no tables and an error", tables, err)
+	}
+}
+
+func TestHoldingsLoaderEditions(t *testing.T) {
+	o := &countingOpener{files: map[string]string{"a.xml": testHolding("2000", "2010", "")}}
+	tables, editions, err := HoldingsLoader{Open: o.Open}.LoadEditions(map[string][]string{"DE-1": {"a.xml"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := NewHoldingFilterFromLicenses(tables["DE-1"], time.Now())
+	f.Editions = editions["DE-1"]
+	date, signature := mustParseDate("2005-01-01"), holdings.CombineDatum("2005", "", "", "")
+	if ok, edition := f.CoveredAndValidEdition(signature, "1234-5678", date); !ok || edition != holdings.Print {
+		t.Errorf("CoveredAndValidEdition: got %v, %s, want true, %s", ok, edition, holdings.Print)
+	}
+}
@@ -0,0 +1,293 @@
+package mab2
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/container"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// DefaultSourceID is used, if no source id is configured.
+	DefaultSourceID = "162"
+	// DefaultCollection is used, if no collection is configured.
+	DefaultCollection = "MAB2"
+	// DefaultFormat is used, if no format is configured.
+	DefaultFormat = "eBook"
+	// BatchSize number of records per batch.
+	BatchSize = 2000
+)
+
+var (
+	yearPattern = regexp.MustCompile(`[12][0-9]{3}`)
+	// nonSorting marks articles excluded from sorting, "<<Die>> Welt" or
+	// "¬Die¬ Welt" in older data.
+	nonSorting = strings.NewReplacer("<<", "", ">>", "", "¬", "")
+)
+
+// MAB2 source. Since MAB2 data comes from different catalogs, source id,
+// collection and format can be set, empty values fall back to the defaults.
+type MAB2 struct {
+	SourceID   string
+	Collection string
+	Format     string
+}
+
+// Document is a raw record together with the source settings.
+type Document struct {
+	*Record
+	source MAB2
+}
+
+// NewBatch wraps up raw records for channel com, they are parsed in Apply.
+func NewBatch(records [][]byte, s MAB2) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(v interface{}) (span.Importer, error) {
+			record, err := ParseRecord(v.([]byte))
+			if err != nil {
+				return nil, err
+			}
+			return &Document{Record: record, source: s}, nil
+		}, Items: make([]interface{}, len(records))}
+	for i, record := range records {
+		batch.Items[i] = record
+	}
+	return batch
+}
+
+// Iterate splits the input into records. The format is detected from the
+// first line: diskette format starts with "###".
+func (s MAB2) Iterate(r io.Reader) (<-chan interface{}, error) {
+	if s.SourceID == "" {
+		s.SourceID = DefaultSourceID
+	}
+	if s.Collection == "" {
+		s.Collection = DefaultCollection
+	}
+	if s.Format == "" {
+		s.Format = DefaultFormat
+	}
+	reader := bufio.NewReader(r)
+	diskette := false
+	if b, err := reader.Peek(3); err == nil && string(b) == "###" {
+		diskette = true
+	}
+	ch := make(chan interface{})
+	var records [][]byte
+	add := func(b []byte) {
+		if len(bytes.TrimSpace(b)) == 0 {
+			return
+		}
+		records = append(records, b)
+		if len(records) == BatchSize {
+			ch <- NewBatch(records, s)
+			records = nil
+		}
+	}
+	go func() {
+		var buf bytes.Buffer
+		for {
+			var (
+				b   []byte
+				err error
+			)
+			if diskette {
+				b, err = reader.ReadBytes('\n')
+			} else {
+				b, err = reader.ReadBytes(RecordTerminator)
+			}
+			if err != nil && err != io.EOF {
+				log.Fatal(err)
+			}
+			if !diskette {
+				add(b)
+			} else {
+				// A record ends at an empty line or the label of the next one.
+				if bytes.HasPrefix(b, []byte("###")) || len(bytes.TrimSpace(b)) == 0 {
+					add(append([]byte(nil), buf.Bytes()...))
+					buf.Reset()
+				}
+				buf.Write(b)
+			}
+			if err == io.EOF {
+				break
+			}
+		}
+		add(buf.Bytes())
+		ch <- NewBatch(records, s)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// ID returns the identification number from field 001.
+func (r *Record) ID() string {
+	return r.First("001", "")
+}
+
+// Title returns the main title from 331, without non-sorting marks.
+func (r *Record) Title() string {
+	return nonSorting.Replace(r.First("331", ""))
+}
+
+// person converts a name of the form "Doe, Jane", or the $p subfield, if
+// names are given with subfields.
+func person(f Field) finc.Author {
+	name := f.text()
+	if len(f.Subfields) > 0 {
+		if v := f.Get("p"); v != "" {
+			name = v
+		} else if v := f.Get("a"); v != "" {
+			name = v
+		}
+	}
+	parts := strings.SplitN(name, ",", 2)
+	if len(parts) == 1 {
+		return finc.Author{Name: strings.TrimSpace(name)}
+	}
+	return finc.Author{
+		LastName:  strings.TrimSpace(parts[0]),
+		FirstName: strings.TrimSpace(parts[1]),
+	}
+}
+
+// Authors returns persons from the fields 100 to 196, in steps of four, with
+// a blank or "a" indicator, which mark authors. Other indicators mark other
+// roles, e.g. editors. Corporate bodies from 200 to 296 follow.
+func (r *Record) Authors() (authors []finc.Author) {
+	for i := 100; i <= 196; i += 4 {
+		tag := fmt.Sprintf("%03d", i)
+		for _, f := range r.Fields {
+			if f.Tag != tag || (f.Indicator != " " && f.Indicator != "a") {
+				continue
+			}
+			if a := person(f); a != (finc.Author{}) {
+				authors = append(authors, a)
+			}
+		}
+	}
+	for i := 200; i <= 296; i += 4 {
+		for _, v := range r.Values(fmt.Sprintf("%03d", i), " a") {
+			authors = append(authors, finc.Author{Corporation: v})
+		}
+	}
+	return authors
+}
+
+// Date returns the year of publication from 425.
+func (r *Record) Date() (time.Time, error) {
+	for _, v := range r.Values("425", " a") {
+		if year := yearPattern.FindString(v); year != "" {
+			return time.Parse("2006", year)
+		}
+	}
+	return time.Time{}, fmt.Errorf("%s: no usable date", r.ID())
+}
+
+// Subjects returns subject headings from 710 and the subject chains in 902
+// to 947, without duplicates.
+func (r *Record) Subjects() (subjects []string) {
+	seen := container.NewStringSet()
+	add := func(v string) {
+		if v = nonSorting.Replace(v); v != "" && !seen.Contains(v) {
+			seen.Add(v)
+			subjects = append(subjects, v)
+		}
+	}
+	for _, v := range r.Values("710", "") {
+		add(v)
+	}
+	for i := 902; i <= 947; i += 5 {
+		for _, f := range r.Fields {
+			if f.Tag != fmt.Sprintf("%03d", i) {
+				continue
+			}
+			if v := f.Get("s"); v != "" {
+				add(v)
+			} else if len(f.Subfields) == 0 {
+				add(f.text())
+			}
+		}
+	}
+	return subjects
+}
+
+// URLs returns the links from 655, subfield u.
+func (r *Record) URLs() (urls []string) {
+	for _, f := range r.Fields {
+		if f.Tag != "655" {
+			continue
+		}
+		if v := f.Get("u"); v != "" {
+			urls = append(urls, v)
+		}
+	}
+	return urls
+}
+
+// RecordID is of the form <kind>-<source-id>-<id-base64-unpadded>.
+func (r *Record) RecordID(sourceID string) string {
+	enc := fmt.Sprintf("ai-%s-%s", sourceID, base64.URLEncoding.EncodeToString([]byte(r.ID())))
+	return strings.TrimRight(enc, "=")
+}
+
+// Convert maps the record. Deleted records, marked with status "d" in the
+// record label, and records without identification number or year are
+// skipped.
+func (r *Record) Convert(sourceID string) (*finc.IntermediateSchema, error) {
+	output := finc.NewIntermediateSchema()
+
+	id := r.ID()
+	if id == "" {
+		return output, span.Skip{Reason: "mab2: record without identification number"}
+	}
+	if len(r.Leader) > 5 && r.Leader[5] == 'd' {
+		return output, span.Skip{Reason: fmt.Sprintf("%s: deleted", id)}
+	}
+	date, err := r.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+
+	output.Date = date
+	output.SourceID = sourceID
+	output.RecordID = r.RecordID(sourceID)
+	output.ArticleTitle = r.Title()
+	output.ArticleSubtitle = nonSorting.Replace(r.First("335", ""))
+	output.Authors = r.Authors()
+	output.Edition = r.First("403", "")
+	output.Places = r.Values("410", "")
+	output.Publishers = r.Values("412", "")
+	output.PageCount = r.First("433", "")
+	output.Series = nonSorting.Replace(r.First("451", ""))
+	output.ISBN = r.Values("540", " ab")
+	output.ISSN = r.Values("542", " a")
+	output.DOI = r.First("552", "a")
+	output.URL = r.URLs()
+	output.Abstract = r.First("750", "")
+	output.Subjects = r.Subjects()
+	for _, lang := range r.Values("037", "b") {
+		output.Languages = append(output.Languages, span.NormalizeLanguage(lang))
+	}
+	return output, nil
+}
+
+// ToIntermediateSchema converts the record with the settings of the source.
+func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	output, err := doc.Record.Convert(doc.source.SourceID)
+	if err != nil {
+		return output, err
+	}
+	output.MegaCollection = doc.source.Collection
+	output.Format = doc.source.Format
+	return output, nil
+}
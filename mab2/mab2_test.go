@@ -0,0 +1,103 @@
+package mab2
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+	"github.com/miku/span/internal/spantest"
+)
+
+// band joins fields to a record in band format.
+func band(leader string, fields ...string) string {
+	return leader + strings.Join(fields, "\x1e") + "\x1e\x1d"
+}
+
+var example = band("01234nM2.01200024      h",
+	"001 100000001",
+	"037b"+"ger",
+	"100 Doe, Jane",
+	"100bRoe, Richard",
+	"104aMustermann, Max",
+	"200 Example Society",
+	"331 <<Die>> Welt der Beispiele",
+	"335 eine Einführung",
+	"410 Berlin",
+	"412 Example Press",
+	"425a2015",
+	"540a978-3-16-148410-0",
+	"655e\x1fuhttps://example.org/1\x1fxVolltext",
+	"902 \x1fsBeispiel",
+) + band("01234dM2.01200024      h", "001 100000002", "425a2001") + `
+### 01234nM2.01200024      h
+001 100000003
+331 A diskette record
+425 ca. 1999
+
+### 01234nM2.01200024      h
+001 100000004
+331 Undated
+`
+
+func TestBand(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, MAB2{}, strings.NewReader(example[:strings.Index(example, "\n###")]))
+	if len(results) != 2 {
+		t.Fatalf("got %d records, want 2", len(results))
+	}
+	if errs[0] != nil {
+		t.Fatal(errs[0])
+	}
+	if _, ok := errs[1].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for a deleted record", errs[1])
+	}
+	is := results[0]
+	if is.ArticleTitle != "Die Welt der Beispiele" || is.ArticleSubtitle != "eine Einführung" {
+		t.Errorf("got title %q, subtitle %q", is.ArticleTitle, is.ArticleSubtitle)
+	}
+	want := []finc.Author{
+		{LastName: "Doe", FirstName: "Jane"},
+		{LastName: "Mustermann", FirstName: "Max"},
+		{Corporation: "Example Society"},
+	}
+	if !reflect.DeepEqual(is.Authors, want) {
+		t.Errorf("got authors %v, want %v", is.Authors, want)
+	}
+	if is.Date.Year() != 2015 || !reflect.DeepEqual(is.ISBN, []string{"978-3-16-148410-0"}) {
+		t.Errorf("got date %v, ISBN %v", is.Date, is.ISBN)
+	}
+	if !reflect.DeepEqual(is.URL, []string{"https://example.org/1"}) || !reflect.DeepEqual(is.Subjects, []string{"Beispiel"}) {
+		t.Errorf("got URL %v, subjects %v", is.URL, is.Subjects)
+	}
+	if !reflect.DeepEqual(is.Languages, []string{"deu"}) || is.Format != DefaultFormat || is.SourceID != DefaultSourceID {
+		t.Errorf("got languages %v, format %s, source id %s", is.Languages, is.Format, is.SourceID)
+	}
+}
+
+func TestDiskette(t *testing.T) {
+	input := example[strings.Index(example, "###"):]
+	results, errs := spantest.ConvertAll(t, MAB2{SourceID: "300", Format: "ElectronicThesis"}, strings.NewReader(input))
+	if len(results) != 2 {
+		t.Fatalf("got %d records, want 2", len(results))
+	}
+	if errs[0] != nil {
+		t.Fatal(errs[0])
+	}
+	if _, ok := errs[1].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for an undated record", errs[1])
+	}
+	is := results[0]
+	if is.ArticleTitle != "A diskette record" || is.Date.Year() != 1999 {
+		t.Errorf("got title %q, date %v", is.ArticleTitle, is.Date)
+	}
+	if is.Format != "ElectronicThesis" || !strings.HasPrefix(is.RecordID, "ai-300-") {
+		t.Errorf("got format %s, record id %s", is.Format, is.RecordID)
+	}
+}
+
+func TestParseBand(t *testing.T) {
+	if _, err := ParseBand([]byte("00010")); err != errShortRecord {
+		t.Errorf("got %v, want %v", err, errShortRecord)
+	}
+}
@@ -0,0 +1,167 @@
+// Package mab2 converts MAB2 title records, the exchange format of German
+// library networks before MARC21, into the intermediate schema. Records are
+// read in band format, with fields terminated by 0x1E and records by 0x1D,
+// or in diskette format, with one field per line, a "###" line starting
+// each record. Values are expected to be UTF-8.
+package mab2
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Delimiters of the band format, subfields are delimited the same way in
+// both formats.
+const (
+	RecordTerminator = 0x1D
+	FieldTerminator  = 0x1E
+	SubfieldDelim    = 0x1F
+)
+
+const leaderLength = 24
+
+var errShortRecord = errors.New("mab2: record too short")
+
+// Subfield is a coded part of a field.
+type Subfield struct {
+	Code  string
+	Value string
+}
+
+// Field has a three digit tag and a one character indicator. Fields without
+// subfields only have a value.
+type Field struct {
+	Tag       string
+	Indicator string
+	Value     string
+	Subfields []Subfield
+}
+
+// Record is a MAB2 record with its record label.
+type Record struct {
+	Leader string
+	Fields []Field
+}
+
+// parseField splits tag, indicator and data.
+func parseField(s string) (Field, error) {
+	if len(s) < 4 {
+		return Field{}, fmt.Errorf("mab2: invalid field: %q", s)
+	}
+	field := Field{Tag: s[:3], Indicator: s[3:4]}
+	data := s[4:]
+	if !strings.Contains(data, string(rune(SubfieldDelim))) {
+		field.Value = data
+		return field, nil
+	}
+	for _, p := range strings.Split(data, string(rune(SubfieldDelim)))[1:] {
+		if p == "" {
+			continue
+		}
+		field.Subfields = append(field.Subfields, Subfield{Code: p[:1], Value: p[1:]})
+	}
+	return field, nil
+}
+
+// ParseBand decodes a record in band format: a 24 character record label,
+// followed by fields, each terminated by 0x1E.
+func ParseBand(b []byte) (*Record, error) {
+	b = bytes.TrimLeft(bytes.TrimRight(b, "\x1d"), "\r\n")
+	if len(b) < leaderLength {
+		return nil, errShortRecord
+	}
+	record := &Record{Leader: string(b[:leaderLength])}
+	for _, data := range bytes.Split(b[leaderLength:], []byte{FieldTerminator}) {
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+		field, err := parseField(string(data))
+		if err != nil {
+			return nil, err
+		}
+		record.Fields = append(record.Fields, field)
+	}
+	return record, nil
+}
+
+// ParseDiskette decodes a record in diskette format: a "### " line with the
+// record label, followed by one field per line.
+func ParseDiskette(b []byte) (*Record, error) {
+	record := new(Record)
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "###") {
+			record.Leader = strings.TrimSpace(strings.TrimPrefix(line, "###"))
+			continue
+		}
+		field, err := parseField(line)
+		if err != nil {
+			return nil, err
+		}
+		record.Fields = append(record.Fields, field)
+	}
+	if len(record.Fields) == 0 {
+		return nil, errShortRecord
+	}
+	return record, nil
+}
+
+// ParseRecord decodes a single record, band or diskette format.
+func ParseRecord(b []byte) (*Record, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("###")) {
+		return ParseDiskette(b)
+	}
+	return ParseBand(b)
+}
+
+// text returns the value of a field, subfields joined by space.
+func (f Field) text() string {
+	if len(f.Subfields) == 0 {
+		return strings.TrimSpace(f.Value)
+	}
+	var parts []string
+	for _, sf := range f.Subfields {
+		if v := strings.TrimSpace(sf.Value); v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Get returns the first value of a subfield code.
+func (f Field) Get(code string) string {
+	for _, sf := range f.Subfields {
+		if sf.Code == code {
+			return strings.TrimSpace(sf.Value)
+		}
+	}
+	return ""
+}
+
+// Values returns the values of all fields with a tag and one of the given
+// indicators, e.g. Values("425", " a"). An empty indicator list matches any
+// indicator.
+func (r *Record) Values(tag, indicators string) (values []string) {
+	for _, f := range r.Fields {
+		if f.Tag != tag || (indicators != "" && !strings.Contains(indicators, f.Indicator)) {
+			continue
+		}
+		if v := f.text(); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// First returns the first value of Values or the empty string.
+func (r *Record) First(tag, indicators string) string {
+	if values := r.Values(tag, indicators); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,162 @@
+package marc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+)
+
+// Targets of a mapping, i.e. the supported intermediate schema fields.
+const (
+	TargetTitle     = "title"
+	TargetSubtitle  = "subtitle"
+	TargetAuthors   = "authors"
+	TargetPublisher = "publisher"
+	TargetPlace     = "place"
+	TargetDate      = "date"
+	TargetISSN      = "issn"
+	TargetISBN      = "isbn"
+	TargetURL       = "url"
+	TargetSubjects  = "subjects"
+	TargetLanguages = "languages"
+)
+
+var targets = map[string]bool{
+	TargetTitle: true, TargetSubtitle: true, TargetAuthors: true, TargetPublisher: true,
+	TargetPlace: true, TargetDate: true, TargetISSN: true, TargetISBN: true,
+	TargetURL: true, TargetSubjects: true, TargetLanguages: true,
+}
+
+var (
+	specPattern = regexp.MustCompile(`^[0-9]{3}[0-9a-z]*$`)
+	yearPattern = regexp.MustCompile(`[12][0-9]{3}`)
+	// isbdPunctuation trails values in cataloging, e.g. "Title /".
+	isbdPunctuation = " /:;,=."
+)
+
+// Mapping configures the conversion of MARC records. Fields maps a target to
+// a list of field specifications: a tag followed by subfield codes, e.g.
+// "245ab" or "001" for a control field. All specifications of a target are
+// used, in order; single valued targets take the first value.
+type Mapping struct {
+	SourceID   string              `json:"source_id"`
+	Collection string              `json:"collection"`
+	Format     string              `json:"format"`
+	Fields     map[string][]string `json:"fields"`
+}
+
+// DefaultMapping covers title, statement of publication, ISSN and persons.
+var DefaultMapping = Mapping{
+	SourceID:   "145",
+	Collection: "MARC",
+	Format:     "eBook",
+	Fields: map[string][]string{
+		TargetTitle:     {"245a"},
+		TargetSubtitle:  {"245b"},
+		TargetAuthors:   {"100a", "700a"},
+		TargetPublisher: {"260b", "264b"},
+		TargetPlace:     {"260a", "264a"},
+		TargetDate:      {"260c", "264c"},
+		TargetISSN:      {"022a"},
+		TargetISBN:      {"020a"},
+		TargetURL:       {"856u"},
+		TargetSubjects:  {"650a"},
+		TargetLanguages: {"041a"},
+	},
+}
+
+// ReadMapping reads a JSON mapping and checks targets and specifications.
+func ReadMapping(r io.Reader) (Mapping, error) {
+	var m Mapping
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return m, err
+	}
+	return m, m.Validate()
+}
+
+// Validate returns an error for unknown targets or malformed specifications.
+func (m Mapping) Validate() error {
+	if m.SourceID == "" {
+		return fmt.Errorf("marc: mapping needs a source_id")
+	}
+	for target, specs := range m.Fields {
+		if !targets[target] {
+			return fmt.Errorf("marc: unknown target: %s", target)
+		}
+		for _, spec := range specs {
+			if !specPattern.MatchString(spec) {
+				return fmt.Errorf("marc: invalid field specification: %s", spec)
+			}
+		}
+	}
+	return nil
+}
+
+// values returns the cleaned values of all specifications of a target.
+func (m Mapping) values(r *Record, target string) (values []string) {
+	for _, spec := range m.Fields[target] {
+		if len(spec) < 3 {
+			continue
+		}
+		for _, v := range r.Values(spec[:3], spec[3:]) {
+			if v = strings.TrimRight(v, isbdPunctuation); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+func (m Mapping) first(r *Record, target string) string {
+	if values := m.values(r, target); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Convert maps a record. The record id is taken from the control number in
+// 001. Records without control number or year are skipped.
+func (m Mapping) Convert(r *Record) (*finc.IntermediateSchema, error) {
+	output := finc.NewIntermediateSchema()
+
+	id := strings.TrimSpace(r.ControlField("001"))
+	if id == "" {
+		return output, span.Skip{Reason: "marc: record without control number"}
+	}
+	year := yearPattern.FindString(m.first(r, TargetDate))
+	if year == "" {
+		return output, span.Skip{Reason: fmt.Sprintf("marc: %s: no usable date", id)}
+	}
+	date, err := time.Parse("2006", year)
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+
+	output.Date = date
+	output.SourceID = m.SourceID
+	output.RecordID = fmt.Sprintf("ai-%s-%s", m.SourceID, id)
+	output.MegaCollection = m.Collection
+	output.Format = m.Format
+
+	output.ArticleTitle = m.first(r, TargetTitle)
+	output.ArticleSubtitle = m.first(r, TargetSubtitle)
+	for _, name := range m.values(r, TargetAuthors) {
+		output.Authors = append(output.Authors, finc.Author{Name: name})
+	}
+	output.Publishers = m.values(r, TargetPublisher)
+	output.Places = m.values(r, TargetPlace)
+	output.ISSN = m.values(r, TargetISSN)
+	output.ISBN = m.values(r, TargetISBN)
+	output.URL = m.values(r, TargetURL)
+	output.Subjects = m.values(r, TargetSubjects)
+	for _, lang := range m.values(r, TargetLanguages) {
+		output.Languages = append(output.Languages, span.NormalizeLanguage(lang))
+	}
+	return output, nil
+}
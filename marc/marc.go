@@ -0,0 +1,83 @@
+package marc
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+)
+
+// BatchSize number of records per batch.
+const BatchSize = 2000
+
+// MARC source for binary ISO 2709 records. Without a mapping, DefaultMapping
+// is used.
+type MARC struct {
+	Mapping Mapping
+}
+
+// Document is a record together with the mapping used for conversion.
+type Document struct {
+	Record  *Record
+	Mapping Mapping
+}
+
+// ToIntermediateSchema converts the record according to the mapping.
+func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	return doc.Mapping.Convert(doc.Record)
+}
+
+func (s MARC) mapping() Mapping {
+	if s.Mapping.SourceID == "" {
+		return DefaultMapping
+	}
+	return s.Mapping
+}
+
+// NewBatch wraps up raw records for channel com, they are parsed in Apply.
+func NewBatch(records [][]byte, m Mapping) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			record, err := ParseRecord(s.([]byte))
+			if err != nil {
+				return nil, err
+			}
+			return &Document{Record: record, Mapping: m}, nil
+		}, Items: make([]interface{}, len(records))}
+	for i, record := range records {
+		batch.Items[i] = record
+	}
+	return batch
+}
+
+// Iterate splits the input at record terminators.
+func (s MARC) Iterate(r io.Reader) (<-chan interface{}, error) {
+	ch := make(chan interface{})
+	reader := bufio.NewReader(r)
+	m := s.mapping()
+	var records [][]byte
+	go func() {
+		for {
+			b, err := reader.ReadBytes(RecordTerminator)
+			if err != nil && err != io.EOF {
+				log.Fatal(err)
+			}
+			if len(bytes.TrimSpace(b)) > 0 {
+				records = append(records, b)
+			}
+			if len(records) == BatchSize {
+				ch <- NewBatch(records, m)
+				records = nil
+			}
+			if err == io.EOF {
+				break
+			}
+		}
+		ch <- NewBatch(records, m)
+		close(ch)
+	}()
+	return ch, nil
+}
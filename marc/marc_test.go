@@ -0,0 +1,134 @@
+package marc
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/internal/spantest"
+)
+
+// encode writes a binary ISO 2709 record for testing. Data fields are given
+// as indicators followed by subfields, separated by "$", e.g. "10$aTitle".
+func encode(fields [][2]string) []byte {
+	var directory, data bytes.Buffer
+	for _, f := range fields {
+		value := strings.Replace(f[1], "$", string(rune(SubfieldDelim)), -1) + string(rune(FieldTerminator))
+		fmt.Fprintf(&directory, "%s%04d%05d", f[0], len(value), data.Len())
+		data.WriteString(value)
+	}
+	directory.WriteByte(FieldTerminator)
+	base := leaderLength + directory.Len()
+	length := base + data.Len() + 1
+	leader := fmt.Sprintf("%05dnam a22%05d   4500", length, base)
+	var b bytes.Buffer
+	b.WriteString(leader)
+	b.Write(directory.Bytes())
+	b.Write(data.Bytes())
+	b.WriteByte(RecordTerminator)
+	return b.Bytes()
+}
+
+var example = encode([][2]string{
+	{"001", "000123"},
+	{"020", "  $a9783161484100"},
+	{"022", "  $a1234-5678"},
+	{"100", "1 $aDoe, Jane,$d1970-"},
+	{"245", "10$aOn examples :$bthe second edition /$cJane Doe."},
+	{"260", "  $aBerlin :$bExample Press,$cc2015."},
+	{"700", "1 $aRoe, Richard."},
+	{"856", "40$uhttps://example.org/000123"},
+})
+
+func TestParseRecord(t *testing.T) {
+	r, err := ParseRecord(example)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ControlField("001") != "000123" {
+		t.Errorf("got %q, want 000123", r.ControlField("001"))
+	}
+	if got := r.Values("245", "ab"); !reflect.DeepEqual(got, []string{"On examples : the second edition /"}) {
+		t.Errorf("got %q", got)
+	}
+	if got := r.Values("100", "a"); !reflect.DeepEqual(got, []string{"Doe, Jane,"}) {
+		t.Errorf("got %q", got)
+	}
+	if _, err := ParseRecord([]byte("00010")); err == nil {
+		t.Errorf("got nil, want error for short record")
+	}
+}
+
+func TestDefaultMapping(t *testing.T) {
+	undated := encode([][2]string{{"001", "000124"}, {"245", "10$aUndated"}})
+	input := append(append(append([]byte{}, example...), undated...), '\n')
+	results, errs := spantest.ConvertAll(t, MARC{}, bytes.NewReader(input))
+	if len(results) != 2 {
+		t.Fatalf("got %d records, want 2", len(results))
+	}
+	if errs[0] != nil {
+		t.Fatal(errs[0])
+	}
+	if _, ok := errs[1].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for a record without date", errs[1])
+	}
+	is := results[0]
+	if is.ArticleTitle != "On examples" || is.ArticleSubtitle != "the second edition" {
+		t.Errorf("got title %q, subtitle %q", is.ArticleTitle, is.ArticleSubtitle)
+	}
+	var names []string
+	for _, a := range is.Authors {
+		names = append(names, a.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"Doe, Jane", "Roe, Richard"}) {
+		t.Errorf("got authors %q", names)
+	}
+	if !reflect.DeepEqual(is.Publishers, []string{"Example Press"}) || !reflect.DeepEqual(is.Places, []string{"Berlin"}) {
+		t.Errorf("got publishers %q, places %q", is.Publishers, is.Places)
+	}
+	if is.Date.Year() != 2015 {
+		t.Errorf("got date %v, want 2015", is.Date)
+	}
+	if !reflect.DeepEqual(is.ISSN, []string{"1234-5678"}) || !reflect.DeepEqual(is.ISBN, []string{"9783161484100"}) {
+		t.Errorf("got ISSN %q, ISBN %q", is.ISSN, is.ISBN)
+	}
+	if is.RecordID != "ai-145-000123" {
+		t.Errorf("got record id %s", is.RecordID)
+	}
+}
+
+func TestReadMapping(t *testing.T) {
+	m, err := ReadMapping(strings.NewReader(`{"source_id": "200", "format": "ElectronicArticle",
+		"fields": {"title": ["245ab"], "date": ["264c"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, errs := spantest.ConvertAll(t, MARC{Mapping: m}, bytes.NewReader(example))
+	if errs[0] == nil {
+		t.Fatalf("got nil, want skip, date is mapped to 264 only")
+	}
+	m.Fields[TargetDate] = []string{"260c"}
+	results, errs = spantest.ConvertAll(t, MARC{Mapping: m}, bytes.NewReader(example))
+	if errs[0] != nil {
+		t.Fatal(errs[0])
+	}
+	if results[0].ArticleTitle != "On examples : the second edition" || len(results[0].Authors) != 0 {
+		t.Errorf("got title %q, authors %v", results[0].ArticleTitle, results[0].Authors)
+	}
+	if results[0].SourceID != "200" {
+		t.Errorf("got source %s, want 200", results[0].SourceID)
+	}
+
+	for _, s := range []string{
+		`{"source_id": "1", "fields": {"colour": ["245a"]}}`,
+		`{"source_id": "1", "fields": {"title": ["24a"]}}`,
+		`{"fields": {"title": ["245a"]}}`,
+	} {
+		if _, err := ReadMapping(strings.NewReader(s)); err == nil {
+			t.Errorf("ReadMapping(%s): got nil, want error", s)
+		}
+	}
+}
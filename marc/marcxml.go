@@ -0,0 +1,95 @@
+package marc
+
+import (
+	"bufio"
+	"encoding/xml"
+	"io"
+	"log"
+
+	"github.com/miku/span"
+)
+
+// MARCXML source, which decodes one record element at a time, so large
+// collections are not buffered. Without a mapping, DefaultMapping is used.
+type MARCXML struct {
+	Mapping Mapping
+}
+
+// xmlRecord mirrors a MARCXML record element.
+type xmlRecord struct {
+	Leader        string `xml:"leader"`
+	ControlFields []struct {
+		Tag   string `xml:"tag,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"controlfield"`
+	DataFields []struct {
+		Tag       string `xml:"tag,attr"`
+		Ind1      string `xml:"ind1,attr"`
+		Ind2      string `xml:"ind2,attr"`
+		Subfields []struct {
+			Code  string `xml:"code,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"subfield"`
+	} `xml:"datafield"`
+}
+
+// record converts the XML representation into a Record.
+func (x *xmlRecord) record() *Record {
+	r := &Record{Leader: x.Leader}
+	for _, cf := range x.ControlFields {
+		r.Fields = append(r.Fields, Field{Tag: cf.Tag, Value: cf.Value})
+	}
+	for _, df := range x.DataFields {
+		f := Field{Tag: df.Tag, Ind1: df.Ind1, Ind2: df.Ind2}
+		for _, sf := range df.Subfields {
+			f.Subfields = append(f.Subfields, Subfield{Code: sf.Code, Value: sf.Value})
+		}
+		r.Fields = append(r.Fields, f)
+	}
+	return r
+}
+
+// NewXMLBatch wraps up decoded records for channel com.
+func NewXMLBatch(docs []*Document) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			return s.(span.Importer), nil
+		}, Items: make([]interface{}, len(docs))}
+	for i, doc := range docs {
+		batch.Items[i] = doc
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via XML decoding.
+func (s MARCXML) Iterate(r io.Reader) (<-chan interface{}, error) {
+	ch := make(chan interface{})
+	m := MARC{Mapping: s.Mapping}.mapping()
+	var docs []*Document
+	go func() {
+		decoder := xml.NewDecoder(bufio.NewReader(r))
+		for {
+			t, _ := decoder.Token()
+			if t == nil {
+				break
+			}
+			switch se := t.(type) {
+			case xml.StartElement:
+				if se.Name.Local == "record" {
+					x := new(xmlRecord)
+					if err := decoder.DecodeElement(x, &se); err != nil {
+						log.Fatal(err)
+					}
+					docs = append(docs, &Document{Record: x.record(), Mapping: m})
+					if len(docs) == BatchSize {
+						ch <- NewXMLBatch(docs)
+						docs = nil
+					}
+				}
+			}
+		}
+		ch <- NewXMLBatch(docs)
+		close(ch)
+	}()
+	return ch, nil
+}
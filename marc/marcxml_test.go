@@ -0,0 +1,61 @@
+package marc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/internal/spantest"
+)
+
+const exampleXML = `<?xml version="1.0" encoding="UTF-8"?>
+<collection xmlns="http://www.loc.gov/MARC21/slim">
+<record>
+	<leader>00000nam a2200000   4500</leader>
+	<controlfield tag="001">000123</controlfield>
+	<datafield tag="022" ind1=" " ind2=" "><subfield code="a">1234-5678</subfield></datafield>
+	<datafield tag="100" ind1="1" ind2=" "><subfield code="a">Doe, Jane,</subfield></datafield>
+	<datafield tag="245" ind1="1" ind2="0">
+		<subfield code="a">On examples :</subfield>
+		<subfield code="b">the second edition /</subfield>
+	</datafield>
+	<datafield tag="264" ind1=" " ind2="1">
+		<subfield code="a">Berlin :</subfield>
+		<subfield code="b">Example Press,</subfield>
+		<subfield code="c">2015.</subfield>
+	</datafield>
+	<datafield tag="700" ind1="1" ind2=" "><subfield code="a">Roe, Richard.</subfield></datafield>
+</record>
+<record>
+	<leader>00000nam a2200000   4500</leader>
+	<datafield tag="245" ind1="1" ind2="0"><subfield code="a">No control number</subfield></datafield>
+</record>
+</collection>`
+
+func TestMARCXML(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, MARCXML{}, strings.NewReader(exampleXML))
+	for i, is := range results {
+		if errs[i] != nil {
+			continue
+		}
+		if is.ArticleTitle != "On examples" || is.Date.Year() != 2015 {
+			t.Errorf("got title %q, date %v", is.ArticleTitle, is.Date)
+		}
+		if len(is.Authors) != 2 || is.Authors[1].Name != "Roe, Richard" {
+			t.Errorf("got authors %v", is.Authors)
+		}
+		if !reflect.DeepEqual(is.ISSN, []string{"1234-5678"}) || !reflect.DeepEqual(is.Publishers, []string{"Example Press"}) {
+			t.Errorf("got ISSN %q, publishers %q", is.ISSN, is.Publishers)
+		}
+	}
+	if len(errs) != 2 {
+		t.Fatalf("got %d records, want 2", len(errs))
+	}
+	if errs[0] != nil {
+		t.Error(errs[0])
+	}
+	if _, ok := errs[1].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for a record without control number", errs[1])
+	}
+}
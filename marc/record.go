@@ -0,0 +1,143 @@
+// Package marc converts MARC21 records, binary ISO 2709 or MARCXML, into the
+// intermediate schema, using a configurable field mapping.
+package marc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Delimiters of the ISO 2709 format.
+const (
+	RecordTerminator = 0x1D
+	FieldTerminator  = 0x1E
+	SubfieldDelim    = 0x1F
+)
+
+const (
+	leaderLength   = 24
+	directoryEntry = 12
+)
+
+var errShortRecord = errors.New("marc: record too short")
+
+// Subfield is a coded part of a data field.
+type Subfield struct {
+	Code  string
+	Value string
+}
+
+// Field is a control field (tags 001 to 009), which only has a value, or a
+// data field with indicators and subfields.
+type Field struct {
+	Tag       string
+	Ind1      string
+	Ind2      string
+	Value     string
+	Subfields []Subfield
+}
+
+// IsControl returns true for control fields.
+func (f Field) IsControl() bool {
+	return strings.HasPrefix(f.Tag, "00")
+}
+
+// Record is a MARC record.
+type Record struct {
+	Leader string
+	Fields []Field
+}
+
+// ParseRecord decodes a single binary ISO 2709 record, with or without the
+// record terminator. The character encoding is not converted, so only UTF-8
+// records (leader position 09 is "a") yield readable values.
+func ParseRecord(b []byte) (*Record, error) {
+	if len(b) < leaderLength {
+		return nil, errShortRecord
+	}
+	base, err := strconv.Atoi(string(b[12:17]))
+	if err != nil {
+		return nil, fmt.Errorf("marc: invalid base address: %q", b[12:17])
+	}
+	if base > len(b) || base < leaderLength+1 {
+		return nil, fmt.Errorf("marc: base address %d out of range", base)
+	}
+	record := &Record{Leader: string(b[:leaderLength])}
+	directory := b[leaderLength : base-1]
+	for i := 0; i+directoryEntry <= len(directory); i += directoryEntry {
+		entry := directory[i : i+directoryEntry]
+		length, err := strconv.Atoi(string(entry[3:7]))
+		if err != nil {
+			return nil, fmt.Errorf("marc: invalid field length: %q", entry[3:7])
+		}
+		start, err := strconv.Atoi(string(entry[7:12]))
+		if err != nil {
+			return nil, fmt.Errorf("marc: invalid field start: %q", entry[7:12])
+		}
+		if base+start+length > len(b) {
+			return nil, fmt.Errorf("marc: field %s out of range", entry[:3])
+		}
+		data := strings.TrimRight(string(b[base+start:base+start+length]), "\x1e")
+		record.Fields = append(record.Fields, parseField(string(entry[:3]), data))
+	}
+	return record, nil
+}
+
+// parseField splits the data of a field into indicators and subfields.
+func parseField(tag, data string) Field {
+	field := Field{Tag: tag}
+	if field.IsControl() {
+		field.Value = data
+		return field
+	}
+	parts := strings.Split(data, string(rune(SubfieldDelim)))
+	if len(parts[0]) >= 2 {
+		field.Ind1, field.Ind2 = parts[0][:1], parts[0][1:2]
+	}
+	for _, p := range parts[1:] {
+		if p == "" {
+			continue
+		}
+		field.Subfields = append(field.Subfields, Subfield{Code: p[:1], Value: p[1:]})
+	}
+	return field
+}
+
+// ControlField returns the value of the first control field with a tag.
+func (r *Record) ControlField(tag string) string {
+	for _, f := range r.Fields {
+		if f.Tag == tag && f.IsControl() {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Values returns one value per field with a given tag, which joins the
+// subfields with the given codes in record order, e.g. Values("245", "ab").
+// Fields without any of the subfields are left out.
+func (r *Record) Values(tag, codes string) (values []string) {
+	for _, f := range r.Fields {
+		if f.Tag != tag {
+			continue
+		}
+		if f.IsControl() {
+			values = append(values, f.Value)
+			continue
+		}
+		var parts []string
+		for _, sf := range f.Subfields {
+			if strings.Contains(codes, sf.Code) {
+				if v := strings.TrimSpace(sf.Value); v != "" {
+					parts = append(parts, v)
+				}
+			}
+		}
+		if len(parts) > 0 {
+			values = append(values, strings.Join(parts, " "))
+		}
+	}
+	return values
+}
@@ -0,0 +1,626 @@
+// Package mods converts MODS records, as exported by institutional
+// repositories and digitization projects, into the intermediate schema.
+// Records can be wrapped in a modsCollection or in OAI-PMH responses.
+package mods
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/container"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// DefaultSourceID is used, if no source id is configured.
+	DefaultSourceID = "160"
+	// DefaultCollection is used, if no collection is configured.
+	DefaultCollection = "MODS"
+	// BatchSize number of records per batch.
+	BatchSize = 2000
+)
+
+// genres maps lowercased MODS genre terms, including the DINI and COAR terms
+// common in German repositories, to format, genre and RIS type.
+var genres = map[string][3]string{
+	"article":                  {"ElectronicArticle", "article", "JOUR"},
+	"journal article":          {"ElectronicArticle", "article", "JOUR"},
+	"contributiontoperiodical": {"ElectronicArticle", "article", "JOUR"},
+	"book":                     {"eBook", "book", "EBOOK"},
+	"monograph":                {"eBook", "book", "EBOOK"},
+	"bookpart":                 {"ElectronicBookPart", "bookitem", "ECHAP"},
+	"book part":                {"ElectronicBookPart", "bookitem", "ECHAP"},
+	"book chapter":             {"ElectronicBookPart", "bookitem", "ECHAP"},
+	"conferenceobject":         {"ElectronicProceeding", "proceeding", "CPAPER"},
+	"conference paper":         {"ElectronicProceeding", "proceeding", "CPAPER"},
+	"conference object":        {"ElectronicProceeding", "proceeding", "CPAPER"},
+	"doctoralthesis":           {"ElectronicThesis", "document", "THES"},
+	"masterthesis":             {"ElectronicThesis", "document", "THES"},
+	"bachelorthesis":           {"ElectronicThesis", "document", "THES"},
+	"thesis":                   {"ElectronicThesis", "document", "THES"},
+	"report":                   {"ElectronicResourceRemoteAccess", "report", "RPRT"},
+	"workingpaper":             {"ElectronicResourceRemoteAccess", "report", "RPRT"},
+	"preprint":                 {"ElectronicPreprint", "preprint", "UNPB"},
+}
+
+var (
+	yearPattern = regexp.MustCompile(`[12][0-9]{3}`)
+	datePattern = regexp.MustCompile(`^[12][0-9]{3}(-[0-9]{2}(-[0-9]{2})?)?`)
+)
+
+// MODS source. Source id and collection depend on the repository, empty
+// values fall back to the defaults.
+type MODS struct {
+	SourceID   string
+	Collection string
+}
+
+// TitleInfo is a title, type is empty for the main title.
+type TitleInfo struct {
+	Type       string `xml:"type,attr"`
+	Lang       string `xml:"lang,attr"`
+	NonSort    string `xml:"nonSort"`
+	Title      string `xml:"title"`
+	SubTitle   string `xml:"subTitle"`
+	PartNumber string `xml:"partNumber"`
+	PartName   string `xml:"partName"`
+}
+
+// NamePart is a part of a name, type is family, given, date or empty.
+type NamePart struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Name is a personal or corporate name with roles.
+type Name struct {
+	Type        string     `xml:"type,attr"`
+	NameParts   []NamePart `xml:"namePart"`
+	DisplayForm string     `xml:"displayForm"`
+	RoleTerms   []string   `xml:"role>roleTerm"`
+}
+
+// Date is a date element of originInfo or part.
+type Date struct {
+	Encoding string `xml:"encoding,attr"`
+	KeyDate  string `xml:"keyDate,attr"`
+	Point    string `xml:"point,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// OriginInfo holds publication data.
+type OriginInfo struct {
+	EventType     string   `xml:"eventType,attr"`
+	Places        []string `xml:"place>placeTerm"`
+	Publishers    []string `xml:"publisher"`
+	DateIssued    []Date   `xml:"dateIssued"`
+	DateCreated   []Date   `xml:"dateCreated"`
+	CopyrightDate []Date   `xml:"copyrightDate"`
+	Edition       string   `xml:"edition"`
+}
+
+// Identifier is a typed identifier, e.g. doi, isbn, issn, urn or uri.
+type Identifier struct {
+	Type    string `xml:"type,attr"`
+	Invalid string `xml:"invalid,attr"`
+	Value   string `xml:",chardata"`
+}
+
+// Detail is a volume, issue or other numbered part.
+type Detail struct {
+	Type   string `xml:"type,attr"`
+	Number string `xml:"number"`
+}
+
+// Extent is a page range.
+type Extent struct {
+	Unit  string `xml:"unit,attr"`
+	Start string `xml:"start"`
+	End   string `xml:"end"`
+	Total string `xml:"total"`
+	List  string `xml:"list"`
+}
+
+// Part locates an item within its host.
+type Part struct {
+	Details []Detail `xml:"detail"`
+	Extents []Extent `xml:"extent"`
+	Dates   []Date   `xml:"date"`
+}
+
+// RelatedItem is a host or series of a record.
+type RelatedItem struct {
+	Type        string       `xml:"type,attr"`
+	TitleInfo   []TitleInfo  `xml:"titleInfo"`
+	Identifiers []Identifier `xml:"identifier"`
+	Parts       []Part       `xml:"part"`
+	OriginInfo  []OriginInfo `xml:"originInfo"`
+}
+
+// Record is a single MODS record.
+type Record struct {
+	XMLName          xml.Name      `xml:"mods"`
+	TitleInfo        []TitleInfo   `xml:"titleInfo"`
+	Names            []Name        `xml:"name"`
+	TypeOfResource   []string      `xml:"typeOfResource"`
+	Genres           []string      `xml:"genre"`
+	OriginInfo       []OriginInfo  `xml:"originInfo"`
+	Languages        []string      `xml:"language>languageTerm"`
+	Abstracts        []string      `xml:"abstract"`
+	Topics           []string      `xml:"subject>topic"`
+	Classification   []string      `xml:"classification"`
+	RelatedItems     []RelatedItem `xml:"relatedItem"`
+	Identifiers      []Identifier  `xml:"identifier"`
+	URLs             []string      `xml:"location>url"`
+	Parts            []Part        `xml:"part"`
+	AccessConditions []struct {
+		Type  string `xml:"type,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"accessCondition"`
+	RecordIdentifier string `xml:"recordInfo>recordIdentifier"`
+}
+
+// Decode reads all mods elements and calls f for each of them. Only a single
+// record is held in memory at a time.
+func Decode(r io.Reader, f func(*Record)) error {
+	decoder := xml.NewDecoder(bufio.NewReader(r))
+	for {
+		t, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if se, ok := t.(xml.StartElement); ok && se.Name.Local == "mods" {
+			record := new(Record)
+			if err := decoder.DecodeElement(record, &se); err != nil {
+				return err
+			}
+			f(record)
+		}
+	}
+}
+
+// Document is a record together with the source settings.
+type Document struct {
+	*Record
+	source MODS
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(docs []*Document) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			return s.(span.Importer), nil
+		}, Items: make([]interface{}, len(docs))}
+	for i, doc := range docs {
+		batch.Items[i] = doc
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via XML decoding.
+func (s MODS) Iterate(r io.Reader) (<-chan interface{}, error) {
+	if s.SourceID == "" {
+		s.SourceID = DefaultSourceID
+	}
+	if s.Collection == "" {
+		s.Collection = DefaultCollection
+	}
+	ch := make(chan interface{})
+	var docs []*Document
+	go func() {
+		err := Decode(r, func(record *Record) {
+			docs = append(docs, &Document{Record: record, source: s})
+			if len(docs) == BatchSize {
+				ch <- NewBatch(docs)
+				docs = nil
+			}
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		ch <- NewBatch(docs)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// clean collapses whitespace.
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// mainTitle returns the title info without type, or the first one.
+func mainTitle(infos []TitleInfo) (TitleInfo, bool) {
+	for _, ti := range infos {
+		if ti.Type == "" {
+			return ti, true
+		}
+	}
+	if len(infos) > 0 {
+		return infos[0], true
+	}
+	return TitleInfo{}, false
+}
+
+// Title returns the main title, with the non-sorting prefix and part number
+// or name, if any.
+func (r *Record) Title() string {
+	ti, ok := mainTitle(r.TitleInfo)
+	if !ok {
+		return ""
+	}
+	title := clean(ti.Title)
+	if ti.NonSort != "" {
+		nonSort := clean(ti.NonSort)
+		if !strings.HasSuffix(nonSort, "'") {
+			nonSort += " "
+		}
+		title = nonSort + title
+	}
+	for _, v := range []string{ti.PartNumber, ti.PartName} {
+		if v = clean(v); v != "" {
+			title = title + ". " + v
+		}
+	}
+	return title
+}
+
+// Subtitle returns the subtitle of the main title.
+func (r *Record) Subtitle() string {
+	ti, _ := mainTitle(r.TitleInfo)
+	return clean(ti.SubTitle)
+}
+
+// author reports, whether a name is an author, names without role count as
+// authors.
+func (n Name) author() bool {
+	if len(n.RoleTerms) == 0 {
+		return true
+	}
+	for _, term := range n.RoleTerms {
+		switch strings.ToLower(clean(term)) {
+		case "aut", "author", "cre", "creator":
+			return true
+		}
+	}
+	return false
+}
+
+// Author converts a name, preferring family and given name parts over the
+// display form. Corporate names are kept as corporation.
+func (n Name) Author() finc.Author {
+	var author finc.Author
+	var rest []string
+	for _, p := range n.NameParts {
+		v := clean(p.Value)
+		switch p.Type {
+		case "family":
+			author.LastName = v
+		case "given":
+			author.FirstName = strings.TrimSpace(author.FirstName + " " + v)
+		case "date", "termsOfAddress":
+		default:
+			if v != "" {
+				rest = append(rest, v)
+			}
+		}
+	}
+	if author.LastName != "" || author.FirstName != "" {
+		return author
+	}
+	name := clean(n.DisplayForm)
+	if name == "" {
+		name = strings.Join(rest, ", ")
+	}
+	if n.Type == "corporate" {
+		return finc.Author{Corporation: name}
+	}
+	return finc.Author{Name: name}
+}
+
+// Authors returns the authors and creators.
+func (r *Record) Authors() (authors []finc.Author) {
+	for _, n := range r.Names {
+		if !n.author() {
+			continue
+		}
+		if a := n.Author(); a != (finc.Author{}) {
+			authors = append(authors, a)
+		}
+	}
+	return authors
+}
+
+// parseDate parses w3cdtf-like dates and falls back to the first year in the
+// value, e.g. for "[ca. 1900]" or "Sommer 2001".
+func parseDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	var t time.Time
+	var err error
+	switch v := datePattern.FindString(s); len(v) {
+	case 4:
+		t, err = time.Parse("2006", v)
+	case 7:
+		t, err = time.Parse("2006-01", v)
+	case 10:
+		t, err = time.Parse("2006-01-02", v)
+	default:
+		y := yearPattern.FindString(s)
+		if y == "" {
+			return t, false
+		}
+		t, err = time.Parse("2006", y)
+	}
+	return t, err == nil
+}
+
+// firstDate returns the first parseable date, dates marked as key date first.
+func firstDate(dates []Date) (time.Time, bool) {
+	for _, d := range dates {
+		if d.KeyDate == "yes" {
+			if t, ok := parseDate(d.Value); ok {
+				return t, true
+			}
+		}
+	}
+	for _, d := range dates {
+		if d.Point == "end" {
+			continue
+		}
+		if t, ok := parseDate(d.Value); ok {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Date returns the issue date, falling back to creation and copyright date,
+// and the dates of the host item.
+func (r *Record) Date() (time.Time, error) {
+	var candidates [][]Date
+	for _, oi := range r.OriginInfo {
+		candidates = append(candidates, oi.DateIssued)
+	}
+	for _, oi := range r.OriginInfo {
+		candidates = append(candidates, oi.DateCreated, oi.CopyrightDate)
+	}
+	for _, p := range r.Parts {
+		candidates = append(candidates, p.Dates)
+	}
+	if host, ok := r.Host(); ok {
+		for _, p := range host.Parts {
+			candidates = append(candidates, p.Dates)
+		}
+		for _, oi := range host.OriginInfo {
+			candidates = append(candidates, oi.DateIssued)
+		}
+	}
+	for _, dates := range candidates {
+		if t, ok := firstDate(dates); ok {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%s: no usable date", r.ID())
+}
+
+// Host returns the host item, e.g. the journal of an article.
+func (r *Record) Host() (RelatedItem, bool) {
+	for _, item := range r.RelatedItems {
+		if item.Type == "host" {
+			return item, true
+		}
+	}
+	return RelatedItem{}, false
+}
+
+// Series returns the title of the series, if any.
+func (r *Record) Series() string {
+	for _, item := range r.RelatedItems {
+		if item.Type != "series" {
+			continue
+		}
+		if ti, ok := mainTitle(item.TitleInfo); ok {
+			return clean(ti.Title)
+		}
+	}
+	return ""
+}
+
+// identifiers returns the valid values of a given identifier type.
+func identifiers(ids []Identifier, kind string) (values []string) {
+	for _, id := range ids {
+		if strings.EqualFold(id.Type, kind) && id.Invalid != "yes" {
+			if v := strings.TrimSpace(id.Value); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// DOI returns the first DOI, without resolver prefix.
+func (r *Record) DOI() string {
+	for _, v := range identifiers(r.Identifiers, "doi") {
+		for _, prefix := range []string{"doi:", "https://doi.org/", "http://dx.doi.org/", "https://dx.doi.org/"} {
+			v = strings.TrimPrefix(v, prefix)
+		}
+		return v
+	}
+	return ""
+}
+
+// ID returns the record identifier, falling back to URN, URI, handle or DOI.
+func (r *Record) ID() string {
+	if v := strings.TrimSpace(r.RecordIdentifier); v != "" {
+		return v
+	}
+	for _, kind := range []string{"urn", "uri", "hdl", "handle", "doi"} {
+		if ids := identifiers(r.Identifiers, kind); len(ids) > 0 {
+			return ids[0]
+		}
+	}
+	return ""
+}
+
+// Kind returns format, genre and RIS type from the first known genre term.
+// Records without a known genre are articles, if they have a host with an
+// ISSN, and documents otherwise.
+func (r *Record) Kind() [3]string {
+	for _, g := range r.Genres {
+		if kind, ok := genres[strings.ToLower(clean(g))]; ok {
+			return kind
+		}
+	}
+	if host, ok := r.Host(); ok && len(identifiers(host.Identifiers, "issn")) > 0 {
+		return genres["article"]
+	}
+	return [3]string{"ElectronicResourceRemoteAccess", "document", "GEN"}
+}
+
+// Links returns location URLs and URI identifiers, without duplicates.
+func (r *Record) Links() []string {
+	var links []string
+	seen := container.NewStringSet()
+	for _, v := range append(append([]string{}, r.URLs...), identifiers(r.Identifiers, "uri")...) {
+		v = strings.TrimSpace(v)
+		if !strings.HasPrefix(v, "http://") && !strings.HasPrefix(v, "https://") {
+			continue
+		}
+		if seen.Contains(v) {
+			continue
+		}
+		seen.Add(v)
+		links = append(links, v)
+	}
+	return links
+}
+
+// applyPart sets volume, issue and pages from part elements.
+func applyPart(output *finc.IntermediateSchema, parts []Part) {
+	for _, p := range parts {
+		for _, d := range p.Details {
+			switch strings.ToLower(d.Type) {
+			case "volume":
+				output.Volume = clean(d.Number)
+			case "issue", "number":
+				output.Issue = clean(d.Number)
+			}
+		}
+		for _, e := range p.Extents {
+			if e.Unit != "" && e.Unit != "page" && e.Unit != "pages" {
+				continue
+			}
+			output.StartPage, output.EndPage = clean(e.Start), clean(e.End)
+			switch {
+			case output.StartPage != "" && output.EndPage != "":
+				output.Pages = output.StartPage + "-" + output.EndPage
+			case e.List != "":
+				output.Pages = clean(e.List)
+			}
+			if e.Total != "" {
+				output.PageCount = clean(e.Total)
+			}
+		}
+	}
+}
+
+// RecordID is of the form <kind>-<source-id>-<id-base64-unpadded>.
+func (r *Record) RecordID(sourceID string) string {
+	enc := fmt.Sprintf("ai-%s-%s", sourceID, base64.URLEncoding.EncodeToString([]byte(r.ID())))
+	return strings.TrimRight(enc, "=")
+}
+
+// Convert maps the record. Records without id or usable date are skipped.
+func (r *Record) Convert(sourceID string) (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	if r.ID() == "" {
+		return output, span.Skip{Reason: "record without identifier"}
+	}
+	output.Date, err = r.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+	output.SourceID = sourceID
+	output.RecordID = r.RecordID(sourceID)
+
+	kind := r.Kind()
+	output.Format, output.Genre, output.RefType = kind[0], kind[1], kind[2]
+
+	output.ArticleTitle = r.Title()
+	output.ArticleSubtitle = r.Subtitle()
+	output.Authors = r.Authors()
+	output.DOI = r.DOI()
+	output.URL = r.Links()
+	output.ISBN = identifiers(r.Identifiers, "isbn")
+	output.ISSN = identifiers(r.Identifiers, "issn")
+	output.Series = r.Series()
+	if len(r.Abstracts) > 0 {
+		output.Abstract = clean(r.Abstracts[0])
+	}
+	for _, oi := range r.OriginInfo {
+		for _, p := range oi.Publishers {
+			output.Publishers = append(output.Publishers, clean(p))
+		}
+		for _, p := range oi.Places {
+			output.Places = append(output.Places, clean(p))
+		}
+		if oi.Edition != "" {
+			output.Edition = clean(oi.Edition)
+		}
+	}
+	seen := container.NewStringSet()
+	for _, v := range r.Topics {
+		if v = clean(v); v != "" && !seen.Contains(v) {
+			seen.Add(v)
+			output.Subjects = append(output.Subjects, v)
+		}
+	}
+	for _, lang := range r.Languages {
+		output.Languages = append(output.Languages, span.NormalizeLanguage(clean(lang)))
+	}
+	for _, ac := range r.AccessConditions {
+		if strings.Contains(strings.ToLower(ac.Value), "open access") {
+			output.OpenAccess = true
+		}
+	}
+
+	if host, ok := r.Host(); ok {
+		var hostTitle string
+		if ti, ok := mainTitle(host.TitleInfo); ok {
+			hostTitle = clean(ti.Title)
+		}
+		if output.Genre == "article" {
+			output.JournalTitle = hostTitle
+			output.ISSN = append(output.ISSN, identifiers(host.Identifiers, "issn")...)
+		} else {
+			output.BookTitle = hostTitle
+			output.ISBN = append(output.ISBN, identifiers(host.Identifiers, "isbn")...)
+		}
+		applyPart(output, host.Parts)
+	}
+	applyPart(output, r.Parts)
+	return output, nil
+}
+
+// ToIntermediateSchema converts the record with the settings of the source.
+func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	output, err := doc.Record.Convert(doc.source.SourceID)
+	if err != nil {
+		return output, err
+	}
+	output.MegaCollection = doc.source.Collection
+	return output, nil
+}
@@ -0,0 +1,104 @@
+package mods
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+	"github.com/miku/span/internal/spantest"
+)
+
+const example = `<?xml version="1.0" encoding="UTF-8"?>
+<modsCollection xmlns="http://www.loc.gov/mods/v3">
+<mods version="3.7">
+	<titleInfo>
+		<nonSort>The</nonSort>
+		<title>Example   article</title>
+		<subTitle>A subtitle</subTitle>
+	</titleInfo>
+	<titleInfo type="translated" lang="ger"><title>Ein Beispiel</title></titleInfo>
+	<name type="personal">
+		<namePart type="family">Doe</namePart>
+		<namePart type="given">Jane</namePart>
+		<role><roleTerm type="code" authority="marcrelator">aut</roleTerm></role>
+	</name>
+	<name type="personal">
+		<displayForm>Editor, Ed</displayForm>
+		<role><roleTerm type="code" authority="marcrelator">edt</roleTerm></role>
+	</name>
+	<name type="corporate"><namePart>Example Consortium</namePart></name>
+	<genre authority="dini">article</genre>
+	<originInfo>
+		<dateIssued encoding="w3cdtf" keyDate="yes">2016-04</dateIssued>
+		<publisher>Example Press</publisher>
+	</originInfo>
+	<language><languageTerm authority="iso639-2b" type="code">ger</languageTerm></language>
+	<abstract>An abstract.</abstract>
+	<subject><topic>Biology</topic></subject>
+	<relatedItem type="host">
+		<titleInfo><title>Journal of Examples</title></titleInfo>
+		<identifier type="issn">1234-5678</identifier>
+		<part>
+			<detail type="volume"><number>12</number></detail>
+			<detail type="issue"><number>3</number></detail>
+			<extent unit="page"><start>101</start><end>110</end></extent>
+		</part>
+	</relatedItem>
+	<identifier type="doi">https://doi.org/10.1000/mods.1</identifier>
+	<identifier type="urn">urn:nbn:de:example-1</identifier>
+	<location><url>https://repo.example.org/1</url></location>
+	<recordInfo><recordIdentifier>repo-1</recordIdentifier></recordInfo>
+</mods>
+<mods version="3.7">
+	<titleInfo><title>A thesis</title></titleInfo>
+	<genre>doctoralThesis</genre>
+	<originInfo><dateCreated>[ca. 1999]</dateCreated></originInfo>
+	<identifier type="urn">urn:nbn:de:example-2</identifier>
+</mods>
+<mods version="3.7">
+	<titleInfo><title>Undated</title></titleInfo>
+	<recordInfo><recordIdentifier>repo-3</recordIdentifier></recordInfo>
+</mods>
+</modsCollection>`
+
+func TestMODS(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, MODS{}, strings.NewReader(example))
+	if len(results) != 3 {
+		t.Fatalf("got %d records, want 3", len(results))
+	}
+	if errs[0] != nil || errs[1] != nil {
+		t.Fatalf("unexpected errors: %v, %v", errs[0], errs[1])
+	}
+	if _, ok := errs[2].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for an undated record", errs[2])
+	}
+	is := results[0]
+	if is.ArticleTitle != "The Example article" || is.ArticleSubtitle != "A subtitle" {
+		t.Errorf("got title %q, subtitle %q", is.ArticleTitle, is.ArticleSubtitle)
+	}
+	want := []finc.Author{{LastName: "Doe", FirstName: "Jane"}, {Corporation: "Example Consortium"}}
+	if !reflect.DeepEqual(is.Authors, want) {
+		t.Errorf("got authors %v, want %v", is.Authors, want)
+	}
+	if is.Format != "ElectronicArticle" || is.JournalTitle != "Journal of Examples" || is.Date.Format("2006-01") != "2016-04" {
+		t.Errorf("got format %s, journal %q, date %v", is.Format, is.JournalTitle, is.Date)
+	}
+	if is.Volume != "12" || is.Issue != "3" || is.Pages != "101-110" || !reflect.DeepEqual(is.ISSN, []string{"1234-5678"}) {
+		t.Errorf("got volume %s, issue %s, pages %s, ISSN %v", is.Volume, is.Issue, is.Pages, is.ISSN)
+	}
+	if is.DOI != "10.1000/mods.1" || !reflect.DeepEqual(is.URL, []string{"https://repo.example.org/1"}) {
+		t.Errorf("got DOI %s, URL %v", is.DOI, is.URL)
+	}
+	if !reflect.DeepEqual(is.Languages, []string{"deu"}) || is.MegaCollection != DefaultCollection {
+		t.Errorf("got languages %v, collection %s", is.Languages, is.MegaCollection)
+	}
+	thesis := results[1]
+	if thesis.Format != "ElectronicThesis" || thesis.Date.Year() != 1999 {
+		t.Errorf("got format %s, date %v", thesis.Format, thesis.Date)
+	}
+	if thesis.RecordID != (&Record{RecordIdentifier: "urn:nbn:de:example-2"}).RecordID(DefaultSourceID) {
+		t.Errorf("got record id %s, want the URN as key", thesis.RecordID)
+	}
+}
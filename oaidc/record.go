@@ -0,0 +1,284 @@
+// Package oaidc converts OAI-PMH records in the oai_dc metadata format, as
+// found in harvested ListRecords responses, into the intermediate schema.
+package oaidc
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/container"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// DefaultSourceID is used, if no source id is configured.
+	DefaultSourceID = "146"
+	// DefaultCollection is used, if no collection is configured.
+	DefaultCollection = "OAI-PMH"
+	// DefaultFormat is used, if no format is configured.
+	DefaultFormat = "ElectronicArticle"
+	// BatchSize number of records per batch.
+	BatchSize = 2000
+)
+
+var datePattern = regexp.MustCompile(`^[12][0-9]{3}(-[0-9]{2}(-[0-9]{2})?)?`)
+
+// OAIDC source. Since the source of harvested records depends on the
+// repository, source id, collection and format can be set, empty values
+// fall back to the defaults.
+type OAIDC struct {
+	SourceID   string
+	Collection string
+	Format     string
+}
+
+// Header is the OAI-PMH record header.
+type Header struct {
+	Status     string   `xml:"status,attr"`
+	Identifier string   `xml:"identifier"`
+	Datestamp  string   `xml:"datestamp"`
+	SetSpec    []string `xml:"setSpec"`
+}
+
+// DC holds the fifteen Dublin Core elements.
+type DC struct {
+	Title       []string `xml:"title"`
+	Creator     []string `xml:"creator"`
+	Subject     []string `xml:"subject"`
+	Description []string `xml:"description"`
+	Publisher   []string `xml:"publisher"`
+	Contributor []string `xml:"contributor"`
+	Date        []string `xml:"date"`
+	Type        []string `xml:"type"`
+	Format      []string `xml:"format"`
+	Identifier  []string `xml:"identifier"`
+	Source      []string `xml:"source"`
+	Language    []string `xml:"language"`
+	Relation    []string `xml:"relation"`
+	Coverage    []string `xml:"coverage"`
+	Rights      []string `xml:"rights"`
+}
+
+// Record is a single OAI-PMH record with Dublin Core metadata.
+type Record struct {
+	XMLName  xml.Name `xml:"record"`
+	Header   Header   `xml:"header"`
+	Metadata struct {
+		DC DC `xml:"dc"`
+	} `xml:"metadata"`
+}
+
+// Deleted returns true, if the header marks the record as deleted.
+func (r *Record) Deleted() bool {
+	return r.Header.Status == "deleted"
+}
+
+// Decode reads all record elements of one or more concatenated OAI-PMH
+// responses and calls f for each of them. Only a single record is held in
+// memory at a time.
+func Decode(r io.Reader, f func(*Record)) error {
+	decoder := xml.NewDecoder(bufio.NewReader(r))
+	for {
+		t, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if se, ok := t.(xml.StartElement); ok && se.Name.Local == "record" {
+			record := new(Record)
+			if err := decoder.DecodeElement(record, &se); err != nil {
+				return err
+			}
+			f(record)
+		}
+	}
+}
+
+// Document is a record together with the source settings.
+type Document struct {
+	*Record
+	source OAIDC
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(docs []*Document) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			return s.(span.Importer), nil
+		}, Items: make([]interface{}, len(docs))}
+	for i, doc := range docs {
+		batch.Items[i] = doc
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via XML decoding.
+func (s OAIDC) Iterate(r io.Reader) (<-chan interface{}, error) {
+	if s.SourceID == "" {
+		s.SourceID = DefaultSourceID
+	}
+	if s.Collection == "" {
+		s.Collection = DefaultCollection
+	}
+	if s.Format == "" {
+		s.Format = DefaultFormat
+	}
+	ch := make(chan interface{})
+	var docs []*Document
+	go func() {
+		err := Decode(r, func(record *Record) {
+			docs = append(docs, &Document{Record: record, source: s})
+			if len(docs) == BatchSize {
+				ch <- NewBatch(docs)
+				docs = nil
+			}
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		ch <- NewBatch(docs)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Date returns the first parseable dc:date, year, year and month or full
+// dates are accepted.
+func (r *Record) Date() (time.Time, error) {
+	for _, v := range r.Metadata.DC.Date {
+		s := datePattern.FindString(strings.TrimSpace(v))
+		switch len(s) {
+		case 4:
+			return time.Parse("2006", s)
+		case 7:
+			return time.Parse("2006-01", s)
+		case 10:
+			return time.Parse("2006-01-02", s)
+		}
+	}
+	return time.Time{}, fmt.Errorf("%s: no usable date", r.Header.Identifier)
+}
+
+// URL returns the first identifier, that is a link.
+func (r *Record) URL() string {
+	for _, id := range r.Metadata.DC.Identifier {
+		id = strings.TrimSpace(id)
+		if strings.HasPrefix(id, "http://") || strings.HasPrefix(id, "https://") {
+			return id
+		}
+	}
+	return ""
+}
+
+// DOI returns the first identifier, that looks like a DOI.
+func (r *Record) DOI() string {
+	for _, id := range r.Metadata.DC.Identifier {
+		id = strings.TrimSpace(id)
+		for _, prefix := range []string{"doi:", "info:doi/", "https://doi.org/", "http://dx.doi.org/"} {
+			if strings.HasPrefix(id, prefix) {
+				return strings.TrimPrefix(id, prefix)
+			}
+		}
+	}
+	return ""
+}
+
+// Authors returns the creators. Names with a comma are taken as "Last, First".
+func (r *Record) Authors() (authors []finc.Author) {
+	for _, creator := range r.Metadata.DC.Creator {
+		parts := strings.SplitN(creator, ",", 2)
+		if len(parts) == 1 {
+			authors = append(authors, finc.Author{Name: strings.TrimSpace(creator)})
+			continue
+		}
+		authors = append(authors, finc.Author{
+			LastName:  strings.TrimSpace(parts[0]),
+			FirstName: strings.TrimSpace(parts[1]),
+		})
+	}
+	return authors
+}
+
+// Title returns the first title, with whitespace collapsed.
+func (r *Record) Title() string {
+	if len(r.Metadata.DC.Title) == 0 {
+		return ""
+	}
+	return strings.Join(strings.Fields(r.Metadata.DC.Title[0]), " ")
+}
+
+// Topics returns subjects and set specs, without duplicates.
+func (r *Record) Topics() []string {
+	var topics []string
+	seen := container.NewStringSet()
+	for _, v := range append(append([]string{}, r.Metadata.DC.Subject...), r.Header.SetSpec...) {
+		v = strings.TrimSpace(v)
+		if v == "" || seen.Contains(v) {
+			continue
+		}
+		seen.Add(v)
+		topics = append(topics, v)
+	}
+	return topics
+}
+
+// RecordID is of the form <kind>-<source-id>-<id-base64-unpadded>, with the
+// OAI identifier as primary key.
+func (r *Record) RecordID(sourceID string) string {
+	enc := fmt.Sprintf("ai-%s-%s", sourceID, base64.URLEncoding.EncodeToString([]byte(r.Header.Identifier)))
+	return strings.TrimRight(enc, "=")
+}
+
+// Convert maps the record. Deleted records and records without a usable
+// date are skipped, so they show up in the skip counters.
+func (r *Record) Convert(sourceID string) (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	if r.Deleted() {
+		return output, span.Skip{Reason: fmt.Sprintf("%s: deleted", r.Header.Identifier)}
+	}
+	output.Date, err = r.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+
+	output.SourceID = sourceID
+	output.RecordID = r.RecordID(sourceID)
+	output.ArticleTitle = r.Title()
+	output.Authors = r.Authors()
+	output.DOI = r.DOI()
+	if u := r.URL(); u != "" {
+		output.URL = append(output.URL, u)
+	}
+	if len(r.Metadata.DC.Description) > 0 {
+		output.Abstract = strings.TrimSpace(r.Metadata.DC.Description[0])
+	}
+	output.Publishers = r.Metadata.DC.Publisher
+	output.Subjects = r.Topics()
+	for _, lang := range r.Metadata.DC.Language {
+		output.Languages = append(output.Languages, span.NormalizeLanguage(lang))
+	}
+	return output, nil
+}
+
+// ToIntermediateSchema converts the record with the settings of the source.
+func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	output, err := doc.Record.Convert(doc.source.SourceID)
+	if err != nil {
+		return output, err
+	}
+	output.MegaCollection = doc.source.Collection
+	output.Format = doc.source.Format
+	return output, nil
+}
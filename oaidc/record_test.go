@@ -0,0 +1,93 @@
+package oaidc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/internal/spantest"
+)
+
+// Two concatenated responses, as written by harvesters.
+const example = `<?xml version="1.0" encoding="UTF-8"?>
+<OAI-PMH xmlns="http://www.openarchives.org/OAI/2.0/">
+<ListRecords>
+<record>
+	<header>
+		<identifier>oai:repo.example.org:1</identifier>
+		<datestamp>2017-01-02</datestamp>
+		<setSpec>ddc:500</setSpec>
+	</header>
+	<metadata>
+		<oai_dc:dc xmlns:oai_dc="http://www.openarchives.org/OAI/2.0/oai_dc/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+			<dc:title>A   repository
+				item</dc:title>
+			<dc:creator>Doe, Jane</dc:creator>
+			<dc:subject>Biology</dc:subject>
+			<dc:description>Abstract.</dc:description>
+			<dc:publisher>Example University</dc:publisher>
+			<dc:date>2016-11</dc:date>
+			<dc:identifier>https://repo.example.org/1</dc:identifier>
+			<dc:identifier>info:doi/10.1000/1</dc:identifier>
+			<dc:language>ger</dc:language>
+		</oai_dc:dc>
+	</metadata>
+</record>
+<resumptionToken>abc</resumptionToken>
+</ListRecords>
+</OAI-PMH>
+<?xml version="1.0" encoding="UTF-8"?>
+<OAI-PMH xmlns="http://www.openarchives.org/OAI/2.0/">
+<ListRecords>
+<record>
+	<header status="deleted">
+		<identifier>oai:repo.example.org:2</identifier>
+		<datestamp>2017-01-03</datestamp>
+	</header>
+</record>
+</ListRecords>
+</OAI-PMH>`
+
+func TestOAIDC(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, OAIDC{}, strings.NewReader(example))
+	if len(results) != 2 {
+		t.Fatalf("got %d records, want 2", len(results))
+	}
+	if errs[0] != nil {
+		t.Fatal(errs[0])
+	}
+	if _, ok := errs[1].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for a deleted record", errs[1])
+	}
+	is := results[0]
+	if is.SourceID != DefaultSourceID || is.MegaCollection != DefaultCollection || is.Format != DefaultFormat {
+		t.Errorf("got %s, %s, %s, want defaults", is.SourceID, is.MegaCollection, is.Format)
+	}
+	if is.ArticleTitle != "A repository item" {
+		t.Errorf("got title %q", is.ArticleTitle)
+	}
+	if is.Date.Format("2006-01") != "2016-11" {
+		t.Errorf("got date %v", is.Date)
+	}
+	if is.DOI != "10.1000/1" || !reflect.DeepEqual(is.URL, []string{"https://repo.example.org/1"}) {
+		t.Errorf("got DOI %s, URL %v", is.DOI, is.URL)
+	}
+	if !reflect.DeepEqual(is.Subjects, []string{"Biology", "ddc:500"}) {
+		t.Errorf("got subjects %v", is.Subjects)
+	}
+	if !reflect.DeepEqual(is.Languages, []string{"deu"}) {
+		t.Errorf("got languages %v", is.Languages)
+	}
+}
+
+func TestOAIDCSettings(t *testing.T) {
+	results, _ := spantest.ConvertAll(t, OAIDC{SourceID: "300", Collection: "Example Repository"}, strings.NewReader(example))
+	is := results[0]
+	if is.SourceID != "300" || is.MegaCollection != "Example Repository" {
+		t.Errorf("got %s, %s", is.SourceID, is.MegaCollection)
+	}
+	if !strings.HasPrefix(is.RecordID, "ai-300-") {
+		t.Errorf("got record id %s", is.RecordID)
+	}
+}
@@ -0,0 +1,361 @@
+// Package onix converts ONIX for Books 3.0 product records, as delivered
+// with e-book packages, into the intermediate schema. Only the reference tag
+// names are supported, not the short tags.
+package onix
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// DefaultSourceID is used, if no source id is configured.
+	DefaultSourceID = "149"
+	// DefaultCollection is used, if no collection is configured.
+	DefaultCollection = "ONIX"
+	// Format for all records.
+	Format = "eBook"
+	// BatchSize number of products per batch.
+	BatchSize = 2000
+)
+
+// Code list values used in the conversion.
+const (
+	notificationDelete  = "05" // list 1
+	productIDISBN13     = "15" // list 5
+	productIDGTIN13     = "03"
+	titleTypeDistinct   = "01" // list 15
+	titleLevelProduct   = "01" // list 149
+	contributorAuthor   = "A01"
+	contributorEditor   = "B01"
+	languageOfText      = "01" // list 22
+	textTypeDescription = "03" // list 153
+	textTypeShort       = "02"
+	publishingDatePub   = "01" // list 163
+	relationPrintBased  = "13" // list 51, epublication based on
+	relationAlternative = "06"
+)
+
+// ONIX source. Source id and collection depend on the provider, empty values
+// fall back to the defaults.
+type ONIX struct {
+	SourceID   string
+	Collection string
+}
+
+type identifier struct {
+	Type  string `xml:"ProductIDType"`
+	Value string `xml:"IDValue"`
+}
+
+// Product is a single ONIX 3.0 product record.
+type Product struct {
+	XMLName           xml.Name     `xml:"Product"`
+	RecordReference   string       `xml:"RecordReference"`
+	NotificationType  string       `xml:"NotificationType"`
+	ProductIdentifier []identifier `xml:"ProductIdentifier"`
+	DescriptiveDetail struct {
+		ProductForm string `xml:"ProductForm"`
+		TitleDetail []struct {
+			TitleType    string `xml:"TitleType"`
+			TitleElement []struct {
+				Level              string `xml:"TitleElementLevel"`
+				TitleText          string `xml:"TitleText"`
+				TitlePrefix        string `xml:"TitlePrefix"`
+				TitleWithoutPrefix string `xml:"TitleWithoutPrefix"`
+				Subtitle           string `xml:"Subtitle"`
+			} `xml:"TitleElement"`
+		} `xml:"TitleDetail"`
+		Contributor []struct {
+			SequenceNumber     string   `xml:"SequenceNumber"`
+			ContributorRole    []string `xml:"ContributorRole"`
+			PersonName         string   `xml:"PersonName"`
+			NamesBeforeKey     string   `xml:"NamesBeforeKey"`
+			KeyNames           string   `xml:"KeyNames"`
+			CorporateName      string   `xml:"CorporateName"`
+			PersonNameInverted string   `xml:"PersonNameInverted"`
+		} `xml:"Contributor"`
+		Language []struct {
+			Role string `xml:"LanguageRole"`
+			Code string `xml:"LanguageCode"`
+		} `xml:"Language"`
+		Subject []struct {
+			Code    string `xml:"SubjectCode"`
+			Heading string `xml:"SubjectHeadingText"`
+		} `xml:"Subject"`
+	} `xml:"DescriptiveDetail"`
+	CollateralDetail struct {
+		TextContent []struct {
+			TextType string `xml:"TextType"`
+			Text     string `xml:"Text"`
+		} `xml:"TextContent"`
+	} `xml:"CollateralDetail"`
+	PublishingDetail struct {
+		Publisher []struct {
+			Name string `xml:"PublisherName"`
+		} `xml:"Publisher"`
+		CityOfPublication []string `xml:"CityOfPublication"`
+		PublishingDate    []struct {
+			Role string `xml:"PublishingDateRole"`
+			Date struct {
+				Format string `xml:"dateformat,attr"`
+				Value  string `xml:",chardata"`
+			} `xml:"Date"`
+		} `xml:"PublishingDate"`
+	} `xml:"PublishingDetail"`
+	RelatedMaterial struct {
+		RelatedProduct []struct {
+			RelationCode      []string     `xml:"ProductRelationCode"`
+			ProductIdentifier []identifier `xml:"ProductIdentifier"`
+		} `xml:"RelatedProduct"`
+	} `xml:"RelatedMaterial"`
+}
+
+// Document is a product together with the source settings.
+type Document struct {
+	*Product
+	source ONIX
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(docs []*Document) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			return s.(span.Importer), nil
+		}, Items: make([]interface{}, len(docs))}
+	for i, doc := range docs {
+		batch.Items[i] = doc
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via XML decoding, one product at a time.
+func (s ONIX) Iterate(r io.Reader) (<-chan interface{}, error) {
+	if s.SourceID == "" {
+		s.SourceID = DefaultSourceID
+	}
+	if s.Collection == "" {
+		s.Collection = DefaultCollection
+	}
+	ch := make(chan interface{})
+	var docs []*Document
+	go func() {
+		decoder := xml.NewDecoder(bufio.NewReader(r))
+		for {
+			t, _ := decoder.Token()
+			if t == nil {
+				break
+			}
+			switch se := t.(type) {
+			case xml.StartElement:
+				if se.Name.Local == "Product" {
+					p := new(Product)
+					if err := decoder.DecodeElement(p, &se); err != nil {
+						log.Fatal(err)
+					}
+					docs = append(docs, &Document{Product: p, source: s})
+					if len(docs) == BatchSize {
+						ch <- NewBatch(docs)
+						docs = nil
+					}
+				}
+			}
+		}
+		ch <- NewBatch(docs)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// isbn returns the ISBN-13 of a list of identifiers, GTIN-13 are accepted,
+// if they have a book prefix.
+func isbn(ids []identifier) string {
+	for _, id := range ids {
+		v := strings.TrimSpace(id.Value)
+		switch {
+		case id.Type == productIDISBN13:
+			return v
+		case id.Type == productIDGTIN13 && (strings.HasPrefix(v, "978") || strings.HasPrefix(v, "979")):
+			return v
+		}
+	}
+	return ""
+}
+
+// ISBN returns the ISBN of the product itself.
+func (p *Product) ISBN() string {
+	return isbn(p.ProductIdentifier)
+}
+
+// PrintISBNs returns the ISBNs of the print editions, the e-book is based on
+// or an alternative format of.
+func (p *Product) PrintISBNs() (isbns []string) {
+	for _, rp := range p.RelatedMaterial.RelatedProduct {
+		for _, code := range rp.RelationCode {
+			if code != relationPrintBased && code != relationAlternative {
+				continue
+			}
+			if v := isbn(rp.ProductIdentifier); v != "" {
+				isbns = append(isbns, v)
+			}
+			break
+		}
+	}
+	return isbns
+}
+
+// IsDigital returns true for digital product forms, codes starting with E
+// (digital content) or D (digital on physical carrier).
+func (p *Product) IsDigital() bool {
+	form := p.DescriptiveDetail.ProductForm
+	return strings.HasPrefix(form, "E") || strings.HasPrefix(form, "D")
+}
+
+// Title returns title and subtitle of the product level distinctive title.
+func (p *Product) Title() (title, subtitle string) {
+	for _, td := range p.DescriptiveDetail.TitleDetail {
+		if td.TitleType != titleTypeDistinct {
+			continue
+		}
+		for _, te := range td.TitleElement {
+			if te.Level != titleLevelProduct {
+				continue
+			}
+			title = te.TitleText
+			if title == "" {
+				title = strings.TrimSpace(te.TitlePrefix + " " + te.TitleWithoutPrefix)
+			}
+			return strings.TrimSpace(title), strings.TrimSpace(te.Subtitle)
+		}
+	}
+	return "", ""
+}
+
+// Authors returns authors and editors in sequence order.
+func (p *Product) Authors() (authors []finc.Author) {
+	contributors := p.DescriptiveDetail.Contributor
+	sort.SliceStable(contributors, func(i, j int) bool {
+		a, _ := strconv.Atoi(contributors[i].SequenceNumber)
+		b, _ := strconv.Atoi(contributors[j].SequenceNumber)
+		return a < b
+	})
+	for _, c := range contributors {
+		var relevant bool
+		for _, role := range c.ContributorRole {
+			if role == contributorAuthor || role == contributorEditor {
+				relevant = true
+			}
+		}
+		if !relevant {
+			continue
+		}
+		switch {
+		case c.KeyNames != "":
+			authors = append(authors, finc.Author{LastName: c.KeyNames, FirstName: c.NamesBeforeKey})
+		case c.PersonNameInverted != "":
+			authors = append(authors, finc.Author{Name: c.PersonNameInverted})
+		case c.PersonName != "":
+			authors = append(authors, finc.Author{Name: c.PersonName})
+		case c.CorporateName != "":
+			authors = append(authors, finc.Author{Corporation: c.CorporateName})
+		}
+	}
+	return authors
+}
+
+// Date returns the publication date. Dates are YYYYMMDD by default, the
+// dateformat attribute allows year and month (01) or year only (05).
+func (p *Product) Date() (time.Time, error) {
+	for _, pd := range p.PublishingDetail.PublishingDate {
+		if pd.Role != publishingDatePub {
+			continue
+		}
+		v := strings.TrimSpace(pd.Date.Value)
+		switch {
+		case pd.Date.Format == "05" || len(v) == 4:
+			return time.Parse("2006", v)
+		case pd.Date.Format == "01" || len(v) == 6:
+			return time.Parse("200601", v)
+		default:
+			return time.Parse("20060102", v)
+		}
+	}
+	return time.Time{}, fmt.Errorf("onix: %s: publication date is missing", p.RecordReference)
+}
+
+// Abstract returns the description, or the short description.
+func (p *Product) Abstract() string {
+	var short string
+	for _, tc := range p.CollateralDetail.TextContent {
+		switch tc.TextType {
+		case textTypeDescription:
+			return strings.TrimSpace(tc.Text)
+		case textTypeShort:
+			short = strings.TrimSpace(tc.Text)
+		}
+	}
+	return short
+}
+
+// ToIntermediateSchema converts a product. Delete notifications and products
+// without publication date are skipped.
+func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	if doc.NotificationType == notificationDelete {
+		return output, span.Skip{Reason: fmt.Sprintf("onix: %s: deleted", doc.RecordReference)}
+	}
+	output.Date, err = doc.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+
+	enc := fmt.Sprintf("ai-%s-%s", doc.source.SourceID, base64.URLEncoding.EncodeToString([]byte(doc.RecordReference)))
+	output.SourceID = doc.source.SourceID
+	output.RecordID = strings.TrimRight(enc, "=")
+	output.MegaCollection = doc.source.Collection
+	output.Format = Format
+	output.Genre = "book"
+	output.RefType = "EBOOK"
+
+	if v := doc.ISBN(); v != "" {
+		if doc.IsDigital() {
+			output.EISBN = append(output.EISBN, v)
+		} else {
+			output.ISBN = append(output.ISBN, v)
+		}
+	}
+	output.ISBN = append(output.ISBN, doc.PrintISBNs()...)
+
+	output.BookTitle, output.ArticleSubtitle = doc.Title()
+	output.ArticleTitle = output.BookTitle
+	output.Authors = doc.Authors()
+	output.Abstract = doc.Abstract()
+	for _, p := range doc.PublishingDetail.Publisher {
+		output.Publishers = append(output.Publishers, p.Name)
+	}
+	output.Places = doc.PublishingDetail.CityOfPublication
+	for _, l := range doc.DescriptiveDetail.Language {
+		if l.Role == languageOfText {
+			output.Languages = append(output.Languages, span.NormalizeLanguage(l.Code))
+		}
+	}
+	for _, s := range doc.DescriptiveDetail.Subject {
+		if s.Heading != "" {
+			output.Subjects = append(output.Subjects, s.Heading)
+		}
+	}
+	return output, nil
+}
@@ -0,0 +1,127 @@
+package onix
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+	"github.com/miku/span/internal/spantest"
+)
+
+const example = `<?xml version="1.0" encoding="UTF-8"?>
+<ONIXMessage release="3.0" xmlns="http://ns.editeur.org/onix/3.0/reference">
+<Header><Sender><SenderName>Example Press</SenderName></Sender></Header>
+<Product>
+	<RecordReference>com.example.9783161484100</RecordReference>
+	<NotificationType>03</NotificationType>
+	<ProductIdentifier><ProductIDType>01</ProductIDType><IDValue>EX-1</IDValue></ProductIdentifier>
+	<ProductIdentifier><ProductIDType>15</ProductIDType><IDValue>9783161484100</IDValue></ProductIdentifier>
+	<DescriptiveDetail>
+		<ProductComposition>00</ProductComposition>
+		<ProductForm>ED</ProductForm>
+		<TitleDetail>
+			<TitleType>01</TitleType>
+			<TitleElement>
+				<TitleElementLevel>01</TitleElementLevel>
+				<TitlePrefix>The</TitlePrefix>
+				<TitleWithoutPrefix>Book of Examples</TitleWithoutPrefix>
+				<Subtitle>A Guide</Subtitle>
+			</TitleElement>
+		</TitleDetail>
+		<Contributor>
+			<SequenceNumber>2</SequenceNumber>
+			<ContributorRole>B01</ContributorRole>
+			<PersonName>Richard Roe</PersonName>
+		</Contributor>
+		<Contributor>
+			<SequenceNumber>1</SequenceNumber>
+			<ContributorRole>A01</ContributorRole>
+			<NamesBeforeKey>Jane</NamesBeforeKey>
+			<KeyNames>Doe</KeyNames>
+		</Contributor>
+		<Contributor>
+			<SequenceNumber>3</SequenceNumber>
+			<ContributorRole>A36</ContributorRole>
+			<PersonName>Cover Designer</PersonName>
+		</Contributor>
+		<Language><LanguageRole>01</LanguageRole><LanguageCode>ger</LanguageCode></Language>
+		<Subject><SubjectSchemeIdentifier>20</SubjectSchemeIdentifier><SubjectHeadingText>Examples</SubjectHeadingText></Subject>
+	</DescriptiveDetail>
+	<CollateralDetail>
+		<TextContent><TextType>02</TextType><ContentAudience>00</ContentAudience><Text>Short.</Text></TextContent>
+		<TextContent><TextType>03</TextType><ContentAudience>00</ContentAudience><Text>A longer description.</Text></TextContent>
+	</CollateralDetail>
+	<PublishingDetail>
+		<Publisher><PublishingRole>01</PublishingRole><PublisherName>Example Press</PublisherName></Publisher>
+		<CityOfPublication>Berlin</CityOfPublication>
+		<PublishingDate><PublishingDateRole>01</PublishingDateRole><Date>20150501</Date></PublishingDate>
+	</PublishingDetail>
+	<RelatedMaterial>
+		<RelatedProduct>
+			<ProductRelationCode>13</ProductRelationCode>
+			<ProductIdentifier><ProductIDType>03</ProductIDType><IDValue>9780306406157</IDValue></ProductIdentifier>
+		</RelatedProduct>
+	</RelatedMaterial>
+</Product>
+<Product>
+	<RecordReference>com.example.deleted</RecordReference>
+	<NotificationType>05</NotificationType>
+</Product>
+<Product>
+	<RecordReference>com.example.year</RecordReference>
+	<NotificationType>03</NotificationType>
+	<PublishingDetail>
+		<PublishingDate><PublishingDateRole>01</PublishingDateRole><Date dateformat="05">2014</Date></PublishingDate>
+	</PublishingDetail>
+</Product>
+</ONIXMessage>`
+
+func TestONIX(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, ONIX{}, strings.NewReader(example))
+	if len(results) != 3 {
+		t.Fatalf("got %d records, want 3", len(results))
+	}
+	if errs[0] != nil || errs[2] != nil {
+		t.Fatalf("got %v, %v, want no errors", errs[0], errs[2])
+	}
+	if _, ok := errs[1].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for a delete notification", errs[1])
+	}
+
+	is := results[0]
+	if !reflect.DeepEqual(is.EISBN, []string{"9783161484100"}) || !reflect.DeepEqual(is.ISBN, []string{"9780306406157"}) {
+		t.Errorf("got EISBN %v, ISBN %v", is.EISBN, is.ISBN)
+	}
+	if is.BookTitle != "The Book of Examples" || is.ArticleSubtitle != "A Guide" {
+		t.Errorf("got title %q, subtitle %q", is.BookTitle, is.ArticleSubtitle)
+	}
+	want := []finc.Author{{LastName: "Doe", FirstName: "Jane"}, {Name: "Richard Roe"}}
+	if !reflect.DeepEqual(is.Authors, want) {
+		t.Errorf("got authors %+v, want %+v", is.Authors, want)
+	}
+	if is.Date.Format("2006-01-02") != "2015-05-01" {
+		t.Errorf("got date %v", is.Date)
+	}
+	if is.Abstract != "A longer description." || !reflect.DeepEqual(is.Languages, []string{"deu"}) {
+		t.Errorf("got abstract %q, languages %v", is.Abstract, is.Languages)
+	}
+	if results[2].Date.Year() != 2014 {
+		t.Errorf("got date %v, want 2014", results[2].Date)
+	}
+
+	// Print and electronic ISBN must both survive serialization.
+	b, err := json.Marshal(is)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["rft.isbn"] == nil || m["rft.eisbn"] == nil {
+		t.Errorf("got %s, want rft.isbn and rft.eisbn", b)
+	}
+}
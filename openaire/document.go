@@ -0,0 +1,290 @@
+// Package openaire converts result records of the OpenAIRE Research Graph
+// JSON dump, one result per line, into the intermediate schema.
+package openaire
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/container"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// SourceID for internal bookkeeping.
+	SourceID = "150"
+	// Collection name.
+	Collection = "OpenAIRE"
+	// DefaultFormat for instance types without a mapping.
+	DefaultFormat = "ElectronicArticle"
+	// BatchSize for grouped channel transport.
+	BatchSize = 10000
+	// openAccessCode is the COAR access right for open access.
+	openAccessCode = "c_abf2"
+)
+
+// instanceTypes maps OpenAIRE instance types to format, genre and RIS type.
+var instanceTypes = map[string][3]string{
+	"Article":                         {"ElectronicArticle", "article", "JOUR"},
+	"Book":                            {"eBook", "book", "EBOOK"},
+	"Part of book or chapter of book": {"ElectronicBookPart", "bookitem", "ECHAP"},
+	"Conference object":               {"ElectronicProceeding", "proceeding", "CPAPER"},
+	"Doctoral thesis":                 {"ElectronicThesis", "document", "THES"},
+	"Master thesis":                   {"ElectronicThesis", "document", "THES"},
+	"Preprint":                        {"ElectronicPreprint", "preprint", "UNPB"},
+	"Report":                          {"ElectronicResourceRemoteAccess", "report", "RPRT"},
+	"Dataset":                         {"ElectronicResourceRemoteAccess", "document", "DATA"},
+	"Software":                        {"ElectronicResourceRemoteAccess", "document", "COMP"},
+}
+
+// OpenAIRE source.
+type OpenAIRE struct{}
+
+// AccessRight is a COAR access right.
+type AccessRight struct {
+	Code  string `json:"code"`
+	Label string `json:"label"`
+}
+
+// IsOpen returns true for open access.
+func (a AccessRight) IsOpen() bool {
+	return a.Code == openAccessCode || strings.EqualFold(a.Label, "OPEN")
+}
+
+// Project is a project, that funded a result.
+type Project struct {
+	Code    string `json:"code"`
+	Acronym string `json:"acronym"`
+	Title   string `json:"title"`
+	Funder  struct {
+		ShortName     string `json:"shortName"`
+		Name          string `json:"name"`
+		FundingStream string `json:"fundingStream"`
+	} `json:"funder"`
+}
+
+// Document is a result of the research graph.
+type Document struct {
+	ID  string `json:"id"`
+	PID []struct {
+		Scheme string `json:"scheme"`
+		Value  string `json:"value"`
+	} `json:"pid"`
+	Type      string `json:"type"`
+	MainTitle string `json:"mainTitle"`
+	Subtitle  string `json:"subtitle"`
+	Author    []struct {
+		FullName string `json:"fullName"`
+		Name     string `json:"name"`
+		Surname  string `json:"surname"`
+	} `json:"author"`
+	BestAccessRight AccessRight `json:"bestAccessRight"`
+	PublicationDate string      `json:"publicationDate"`
+	Publisher       string      `json:"publisher"`
+	Language        struct {
+		Code string `json:"code"`
+	} `json:"language"`
+	Description []string `json:"description"`
+	Subjects    []struct {
+		Subject struct {
+			Scheme string `json:"scheme"`
+			Value  string `json:"value"`
+		} `json:"subject"`
+	} `json:"subjects"`
+	Container struct {
+		Name        string `json:"name"`
+		ISSNPrinted string `json:"issnPrinted"`
+		ISSNOnline  string `json:"issnOnline"`
+		Vol         string `json:"vol"`
+		Iss         string `json:"iss"`
+		SP          string `json:"sp"`
+		EP          string `json:"ep"`
+	} `json:"container"`
+	Instances []struct {
+		Type        string      `json:"type"`
+		URLs        []string    `json:"urls"`
+		AccessRight AccessRight `json:"accessRight"`
+	} `json:"instances"`
+	Projects []Project `json:"projects"`
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(lines []string) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			doc := new(Document)
+			err := json.Unmarshal([]byte(s.(string)), doc)
+			return doc, err
+		}, Items: make([]interface{}, len(lines))}
+	for i, line := range lines {
+		batch.Items[i] = line
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via JSON decoding.
+func (s OpenAIRE) Iterate(r io.Reader) (<-chan interface{}, error) {
+	ch := make(chan interface{})
+	reader := bufio.NewReader(r)
+	i := 0
+	var lines []string
+	go func() {
+		for {
+			line, err := reader.ReadString('\n')
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+			i++
+			lines = append(lines, line)
+			if i == BatchSize {
+				ch <- NewBatch(lines)
+				lines = lines[:0]
+				i = 0
+			}
+		}
+		ch <- NewBatch(lines)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// DOI returns the first DOI among the persistent identifiers.
+func (doc *Document) DOI() string {
+	for _, pid := range doc.PID {
+		if strings.EqualFold(pid.Scheme, "doi") {
+			return pid.Value
+		}
+	}
+	return ""
+}
+
+// OpenAccess returns true, if the best access right or any instance is
+// open access.
+func (doc *Document) OpenAccess() bool {
+	if doc.BestAccessRight.IsOpen() {
+		return true
+	}
+	for _, instance := range doc.Instances {
+		if instance.AccessRight.IsOpen() {
+			return true
+		}
+	}
+	return false
+}
+
+// Funding returns the distinct funder names and project references of the
+// form <funder>/<project code>, e.g. EC/101000001.
+func (doc *Document) Funding() (funders, projects []string) {
+	fs, ps := container.NewStringSet(), container.NewStringSet()
+	for _, p := range doc.Projects {
+		if p.Funder.Name != "" {
+			fs.Add(p.Funder.Name)
+		}
+		funder := p.Funder.ShortName
+		if funder == "" {
+			funder = p.Funder.Name
+		}
+		if p.Code != "" {
+			ps.Add(funder + "/" + p.Code)
+		}
+	}
+	return fs.SortedValues(), ps.SortedValues()
+}
+
+// types returns format, genre and RIS type for the first known instance
+// type.
+func (doc *Document) types() [3]string {
+	for _, instance := range doc.Instances {
+		if t, ok := instanceTypes[instance.Type]; ok {
+			return t
+		}
+	}
+	switch doc.Type {
+	case "dataset":
+		return instanceTypes["Dataset"]
+	case "software":
+		return instanceTypes["Software"]
+	}
+	return [3]string{DefaultFormat, "unknown", "GEN"}
+}
+
+// ToIntermediateSchema converts a result. Results without publication date
+// are skipped.
+func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	output.Date, err = time.Parse("2006-01-02", doc.PublicationDate)
+	if err != nil {
+		return output, span.Skip{Reason: fmt.Sprintf("openaire: %s: no usable date", doc.ID)}
+	}
+
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(doc.ID)))
+	output.SourceID = SourceID
+	output.RecordID = strings.TrimRight(enc, "=")
+	output.MegaCollection = Collection
+	types := doc.types()
+	output.Format, output.Genre, output.RefType = types[0], types[1], types[2]
+
+	output.DOI = doc.DOI()
+	output.ArticleTitle = strings.TrimSpace(doc.MainTitle)
+	output.ArticleSubtitle = strings.TrimSpace(doc.Subtitle)
+	for _, a := range doc.Author {
+		if a.Surname != "" {
+			output.Authors = append(output.Authors, finc.Author{LastName: a.Surname, FirstName: a.Name})
+		} else if a.FullName != "" {
+			output.Authors = append(output.Authors, finc.Author{Name: a.FullName})
+		}
+	}
+	if len(doc.Description) > 0 {
+		output.Abstract = strings.TrimSpace(doc.Description[0])
+	}
+	if doc.Publisher != "" {
+		output.Publishers = append(output.Publishers, doc.Publisher)
+	}
+	if doc.Language.Code != "" && doc.Language.Code != "und" {
+		output.Languages = append(output.Languages, span.NormalizeLanguage(doc.Language.Code))
+	}
+	for _, s := range doc.Subjects {
+		if s.Subject.Value != "" {
+			output.Subjects = append(output.Subjects, s.Subject.Value)
+		}
+	}
+
+	c := doc.Container
+	output.JournalTitle = c.Name
+	if c.ISSNPrinted != "" {
+		output.ISSN = append(output.ISSN, c.ISSNPrinted)
+	}
+	if c.ISSNOnline != "" {
+		output.EISSN = append(output.EISSN, c.ISSNOnline)
+	}
+	output.Volume, output.Issue = c.Vol, c.Iss
+	output.StartPage, output.EndPage = c.SP, c.EP
+	if c.SP != "" && c.EP != "" {
+		output.Pages = c.SP + "-" + c.EP
+	}
+
+	urls := container.NewStringSet()
+	for _, instance := range doc.Instances {
+		for _, u := range instance.URLs {
+			if urls.Add(u) {
+				output.URL = append(output.URL, u)
+			}
+		}
+	}
+
+	output.OpenAccess = doc.OpenAccess()
+	output.Funders, output.Projects = doc.Funding()
+	return output, nil
+}
@@ -0,0 +1,94 @@
+package openaire
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/miku/span"
+)
+
+const example = `{"id": "50|doi_________::abc", "type": "publication",
+	"pid": [{"scheme": "doi", "value": "10.1000/oa"}],
+	"mainTitle": "Open results", "subtitle": "A study",
+	"author": [{"fullName": "Jane Doe", "name": "Jane", "surname": "Doe"}, {"fullName": "Example Consortium"}],
+	"bestAccessRight": {"code": "c_abf2", "label": "OPEN", "scheme": "http://vocabularies.coar-repositories.org/documentation/access_rights/"},
+	"publicationDate": "2019-05-01",
+	"publisher": "Example Press",
+	"language": {"code": "eng", "label": "English"},
+	"description": ["An abstract."],
+	"subjects": [{"subject": {"scheme": "keyword", "value": "open science"}}],
+	"container": {"name": "Journal of Openness", "issnPrinted": "1234-5678", "issnOnline": "2345-6789", "vol": "4", "iss": "2", "sp": "10", "ep": "20"},
+	"instances": [{"type": "Article", "urls": ["https://example.org/a", "https://example.org/a"], "accessRight": {"code": "c_abf2", "label": "OPEN"}}],
+	"projects": [
+		{"code": "101000001", "acronym": "OPEN", "title": "Open Project", "funder": {"shortName": "EC", "name": "European Commission", "fundingStream": "H2020"}},
+		{"code": "123", "acronym": "X", "funder": {"shortName": "DFG", "name": "Deutsche Forschungsgemeinschaft"}}
+	]}`
+
+func decode(t *testing.T, line string) *Document {
+	batch := NewBatch([]string{line})
+	doc, err := batch.Apply(batch.Items[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc.(*Document)
+}
+
+func TestToIntermediateSchema(t *testing.T) {
+	doc := decode(t, example)
+	is, err := doc.ToIntermediateSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !is.OpenAccess {
+		t.Errorf("got closed, want open access")
+	}
+	if !reflect.DeepEqual(is.Funders, []string{"Deutsche Forschungsgemeinschaft", "European Commission"}) {
+		t.Errorf("got funders %v", is.Funders)
+	}
+	if !reflect.DeepEqual(is.Projects, []string{"DFG/123", "EC/101000001"}) {
+		t.Errorf("got projects %v", is.Projects)
+	}
+	if is.DOI != "10.1000/oa" || is.Format != "ElectronicArticle" || is.Genre != "article" {
+		t.Errorf("got DOI %s, format %s, genre %s", is.DOI, is.Format, is.Genre)
+	}
+	if !reflect.DeepEqual(is.ISSN, []string{"1234-5678"}) || !reflect.DeepEqual(is.EISSN, []string{"2345-6789"}) {
+		t.Errorf("got ISSN %v, EISSN %v", is.ISSN, is.EISSN)
+	}
+	if len(is.Authors) != 2 || is.Authors[0].LastName != "Doe" || is.Authors[1].Name != "Example Consortium" {
+		t.Errorf("got authors %v", is.Authors)
+	}
+	if !reflect.DeepEqual(is.URL, []string{"https://example.org/a"}) {
+		t.Errorf("got URL %v", is.URL)
+	}
+
+	b, err := json.Marshal(is)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["x.oa"] != true || m["x.projects"] == nil {
+		t.Errorf("got %s, want x.oa and x.projects", b)
+	}
+}
+
+func TestClosedAndUndated(t *testing.T) {
+	doc := decode(t, `{"id": "50|x", "publicationDate": "2020-01-01",
+		"bestAccessRight": {"code": "c_14cb", "label": "CLOSED"}, "instances": [{"type": "Dataset"}]}`)
+	is, err := doc.ToIntermediateSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if is.OpenAccess || is.Format != "ElectronicResourceRemoteAccess" {
+		t.Errorf("got open access %v, format %s", is.OpenAccess, is.Format)
+	}
+	doc = decode(t, `{"id": "50|y"}`)
+	if _, err := doc.ToIntermediateSchema(); err == nil {
+		t.Errorf("got nil, want skip")
+	} else if _, ok := err.(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip", err)
+	}
+}
@@ -0,0 +1,295 @@
+// Package openalex converts works of the OpenAlex snapshot, one work per
+// line, into the intermediate schema.
+package openalex
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/container"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// SourceID for internal bookkeeping.
+	SourceID = "163"
+	// Collection name.
+	Collection = "OpenAlex"
+	// BatchSize for grouped channel transport.
+	BatchSize = 10000
+	// MinConceptScore is the lowest score of a concept, that is kept as a
+	// subject. Low scoring concepts are mostly noise.
+	MinConceptScore = 0.3
+)
+
+// workTypes maps OpenAlex work types to format, genre and RIS type.
+var workTypes = map[string][3]string{
+	"article":             {"ElectronicArticle", "article", "JOUR"},
+	"journal-article":     {"ElectronicArticle", "article", "JOUR"},
+	"review":              {"ElectronicArticle", "article", "JOUR"},
+	"letter":              {"ElectronicArticle", "article", "JOUR"},
+	"editorial":           {"ElectronicArticle", "article", "JOUR"},
+	"book":                {"eBook", "book", "EBOOK"},
+	"monograph":           {"eBook", "book", "EBOOK"},
+	"book-chapter":        {"ElectronicBookPart", "bookitem", "ECHAP"},
+	"proceedings-article": {"ElectronicProceeding", "proceeding", "CPAPER"},
+	"dissertation":        {"ElectronicThesis", "document", "THES"},
+	"preprint":            {"ElectronicPreprint", "preprint", "UNPB"},
+	"posted-content":      {"ElectronicPreprint", "preprint", "UNPB"},
+	"report":              {"ElectronicResourceRemoteAccess", "report", "RPRT"},
+	"dataset":             {"ElectronicResourceRemoteAccess", "document", "DATA"},
+}
+
+// OpenAlex source.
+type OpenAlex struct{}
+
+// Source is a journal, repository or conference, where a work is hosted.
+type Source struct {
+	ID                      string   `json:"id"`
+	DisplayName             string   `json:"display_name"`
+	ISSNL                   string   `json:"issn_l"`
+	ISSN                    []string `json:"issn"`
+	Type                    string   `json:"type"`
+	HostOrganizationName    string   `json:"host_organization_name"`
+	HostOrganizationLineage []string `json:"host_organization_lineage"`
+}
+
+// Location is a place, where a work can be found.
+type Location struct {
+	IsOA           bool    `json:"is_oa"`
+	LandingPageURL string  `json:"landing_page_url"`
+	PDFURL         string  `json:"pdf_url"`
+	Source         *Source `json:"source"`
+}
+
+// Work is a single work of the snapshot.
+type Work struct {
+	ID              string `json:"id"`
+	DOI             string `json:"doi"`
+	Title           string `json:"title"`
+	DisplayName     string `json:"display_name"`
+	PublicationDate string `json:"publication_date"`
+	PublicationYear int    `json:"publication_year"`
+	Type            string `json:"type"`
+	TypeCrossref    string `json:"type_crossref"`
+	Language        string `json:"language"`
+	IsParatext      bool   `json:"is_paratext"`
+	IsRetracted     bool   `json:"is_retracted"`
+	Authorships     []struct {
+		Author struct {
+			ID          string `json:"id"`
+			DisplayName string `json:"display_name"`
+			ORCID       string `json:"orcid"`
+		} `json:"author"`
+		RawAuthorName string `json:"raw_author_name"`
+	} `json:"authorships"`
+	PrimaryLocation Location   `json:"primary_location"`
+	Locations       []Location `json:"locations"`
+	Biblio          struct {
+		Volume    string `json:"volume"`
+		Issue     string `json:"issue"`
+		FirstPage string `json:"first_page"`
+		LastPage  string `json:"last_page"`
+	} `json:"biblio"`
+	OpenAccess struct {
+		IsOA     bool   `json:"is_oa"`
+		OAStatus string `json:"oa_status"`
+		OAURL    string `json:"oa_url"`
+	} `json:"open_access"`
+	AbstractInvertedIndex map[string][]int `json:"abstract_inverted_index"`
+	Concepts              []struct {
+		DisplayName string  `json:"display_name"`
+		Level       int     `json:"level"`
+		Score       float64 `json:"score"`
+	} `json:"concepts"`
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(lines []string) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			work := new(Work)
+			err := json.Unmarshal([]byte(s.(string)), work)
+			return work, err
+		}, Items: make([]interface{}, len(lines))}
+	for i, line := range lines {
+		batch.Items[i] = line
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via JSON decoding.
+func (s OpenAlex) Iterate(r io.Reader) (<-chan interface{}, error) {
+	ch := make(chan interface{})
+	reader := bufio.NewReader(r)
+	var lines []string
+	go func() {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil && err != io.EOF {
+				log.Fatal(err)
+			}
+			if strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+			if len(lines) == BatchSize {
+				ch <- NewBatch(lines)
+				lines = nil
+			}
+			if err == io.EOF {
+				break
+			}
+		}
+		ch <- NewBatch(lines)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Abstract reconstructs the abstract from the inverted index, which maps
+// each word to its positions.
+func (w *Work) Abstract() string {
+	if len(w.AbstractInvertedIndex) == 0 {
+		return ""
+	}
+	type token struct {
+		pos  int
+		word string
+	}
+	var tokens []token
+	for word, positions := range w.AbstractInvertedIndex {
+		for _, pos := range positions {
+			tokens = append(tokens, token{pos, word})
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].pos < tokens[j].pos })
+	words := make([]string, len(tokens))
+	for i, t := range tokens {
+		words[i] = t.word
+	}
+	return strings.Join(words, " ")
+}
+
+// Date returns the publication date, or the first day of the publication
+// year.
+func (w *Work) Date() (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", w.PublicationDate); err == nil {
+		return t, nil
+	}
+	if w.PublicationYear > 0 {
+		return time.Date(w.PublicationYear, time.January, 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Time{}, fmt.Errorf("openalex: %s: no usable date", w.ID)
+}
+
+// ShortID returns the work id without the URL prefix, e.g. W2741809807.
+func (w *Work) ShortID() string {
+	return strings.TrimPrefix(w.ID, "https://openalex.org/")
+}
+
+// Topics returns the names of the concepts with a score of at least
+// MinConceptScore, most relevant first.
+func (w *Work) Topics() (topics []string) {
+	concepts := w.Concepts
+	sort.SliceStable(concepts, func(i, j int) bool { return concepts[i].Score > concepts[j].Score })
+	for _, c := range concepts {
+		if c.Score >= MinConceptScore && c.DisplayName != "" {
+			topics = append(topics, c.DisplayName)
+		}
+	}
+	return topics
+}
+
+// types returns format, genre and RIS type of the work, the more specific
+// Crossref type first.
+func (w *Work) types() [3]string {
+	if t, ok := workTypes[w.TypeCrossref]; ok {
+		return t
+	}
+	if t, ok := workTypes[w.Type]; ok {
+		return t
+	}
+	return [3]string{"ElectronicResourceRemoteAccess", "unknown", "GEN"}
+}
+
+// ToIntermediateSchema converts a work. Paratext, e.g. tables of contents,
+// retracted works and works without date are skipped.
+func (w *Work) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	if w.IsParatext || w.IsRetracted {
+		return output, span.Skip{Reason: fmt.Sprintf("openalex: %s: paratext or retracted", w.ShortID())}
+	}
+	output.Date, err = w.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(w.ShortID())))
+	output.SourceID = SourceID
+	output.RecordID = strings.TrimRight(enc, "=")
+	output.MegaCollection = Collection
+	types := w.types()
+	output.Format, output.Genre, output.RefType = types[0], types[1], types[2]
+
+	output.DOI = strings.TrimPrefix(w.DOI, "https://doi.org/")
+	output.ArticleTitle = strings.TrimSpace(w.Title)
+	if output.ArticleTitle == "" {
+		output.ArticleTitle = strings.TrimSpace(w.DisplayName)
+	}
+	for _, a := range w.Authorships {
+		name := a.Author.DisplayName
+		if name == "" {
+			name = a.RawAuthorName
+		}
+		if name == "" {
+			continue
+		}
+		output.Authors = append(output.Authors, finc.Author{
+			Name:  name,
+			ORCID: strings.TrimPrefix(a.Author.ORCID, "https://orcid.org/"),
+		})
+	}
+	output.Abstract = w.Abstract()
+	if w.Language != "" {
+		output.Languages = append(output.Languages, span.NormalizeLanguage(w.Language))
+	}
+	output.Subjects = w.Topics()
+
+	if src := w.PrimaryLocation.Source; src != nil {
+		switch output.Genre {
+		case "bookitem", "proceeding":
+			output.BookTitle = src.DisplayName
+		default:
+			output.JournalTitle = src.DisplayName
+		}
+		output.ISSN = src.ISSN
+		if src.HostOrganizationName != "" {
+			output.Publishers = append(output.Publishers, src.HostOrganizationName)
+		}
+	}
+	b := w.Biblio
+	output.Volume, output.Issue = b.Volume, b.Issue
+	output.StartPage, output.EndPage = b.FirstPage, b.LastPage
+	if b.FirstPage != "" && b.LastPage != "" {
+		output.Pages = b.FirstPage + "-" + b.LastPage
+	}
+
+	urls := container.NewStringSet()
+	for _, loc := range append([]Location{w.PrimaryLocation}, w.Locations...) {
+		if loc.LandingPageURL != "" && urls.Add(loc.LandingPageURL) {
+			output.URL = append(output.URL, loc.LandingPageURL)
+		}
+	}
+	output.OpenAccess = w.OpenAccess.IsOA
+	return output, nil
+}
@@ -0,0 +1,74 @@
+package openalex
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/miku/span"
+)
+
+const example = `{"id": "https://openalex.org/W100", "doi": "https://doi.org/10.1000/oa.1",
+	"title": "Inverted abstracts", "publication_date": "2021-06-15", "publication_year": 2021,
+	"type": "article", "type_crossref": "journal-article", "language": "en",
+	"authorships": [{"author": {"id": "https://openalex.org/A1", "display_name": "Jane Doe", "orcid": "https://orcid.org/0000-0002-1825-0097"}, "raw_author_name": "J. Doe"}],
+	"primary_location": {"is_oa": true, "landing_page_url": "https://example.org/1",
+		"source": {"display_name": "Journal of Examples", "issn_l": "1234-5678", "issn": ["1234-5678", "2345-6789"], "host_organization_name": "Example Press"}},
+	"locations": [{"landing_page_url": "https://example.org/1"}, {"landing_page_url": "https://repo.example.org/1"}],
+	"biblio": {"volume": "7", "issue": "2", "first_page": "1", "last_page": "9"},
+	"open_access": {"is_oa": true, "oa_status": "gold", "oa_url": "https://example.org/1"},
+	"abstract_inverted_index": {"words": [1, 4], "Some": [0], "and": [2], "more": [3]},
+	"concepts": [{"display_name": "Noise", "level": 2, "score": 0.1}, {"display_name": "Biology", "level": 0, "score": 0.5}, {"display_name": "Genetics", "level": 1, "score": 0.9}]}`
+
+func decode(t *testing.T, line string) *Work {
+	batch := NewBatch([]string{line})
+	doc, err := batch.Apply(batch.Items[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc.(*Work)
+}
+
+func TestToIntermediateSchema(t *testing.T) {
+	is, err := decode(t, example).ToIntermediateSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if is.Abstract != "Some words and more words" {
+		t.Errorf("got abstract %q", is.Abstract)
+	}
+	if !reflect.DeepEqual(is.Subjects, []string{"Genetics", "Biology"}) {
+		t.Errorf("got subjects %v", is.Subjects)
+	}
+	if is.DOI != "10.1000/oa.1" || is.Format != "ElectronicArticle" || is.JournalTitle != "Journal of Examples" {
+		t.Errorf("got DOI %s, format %s, journal %q", is.DOI, is.Format, is.JournalTitle)
+	}
+	if len(is.Authors) != 1 || is.Authors[0].ORCID != "0000-0002-1825-0097" {
+		t.Errorf("got authors %v", is.Authors)
+	}
+	if !reflect.DeepEqual(is.URL, []string{"https://example.org/1", "https://repo.example.org/1"}) || !is.OpenAccess {
+		t.Errorf("got URL %v, open access %v", is.URL, is.OpenAccess)
+	}
+	if is.Pages != "1-9" || !reflect.DeepEqual(is.Languages, []string{"eng"}) {
+		t.Errorf("got pages %s, languages %v", is.Pages, is.Languages)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	for _, line := range []string{
+		`{"id": "https://openalex.org/W1", "publication_year": 2020, "is_paratext": true}`,
+		`{"id": "https://openalex.org/W2"}`,
+	} {
+		if _, err := decode(t, line).ToIntermediateSchema(); err == nil {
+			t.Errorf("%s: want error", line)
+		} else if _, ok := err.(span.Skip); !ok {
+			t.Errorf("got %v, want span.Skip", err)
+		}
+	}
+	is, err := decode(t, `{"id": "https://openalex.org/W3", "publication_year": 2019, "type": "dataset"}`).ToIntermediateSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if is.Date.Year() != 2019 || is.Genre != "document" {
+		t.Errorf("got date %v, genre %s", is.Date, is.Genre)
+	}
+}
@@ -0,0 +1,295 @@
+// Package orcid converts works of the ORCID public data file into the
+// intermediate schema. Input is the activities archive, a tar file, that may
+// be gzip compressed, or concatenated work XML files. ORCID iDs of
+// contributors are kept with the authors.
+package orcid
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// SourceID for internal bookkeeping.
+	SourceID = "166"
+	// Collection name.
+	Collection = "ORCID"
+	// BatchSize number of works per batch.
+	BatchSize = 2000
+)
+
+// workTypes maps ORCID work types to format, genre and RIS type.
+var workTypes = map[string][3]string{
+	"journal-article":     {"ElectronicArticle", "article", "JOUR"},
+	"magazine-article":    {"ElectronicArticle", "article", "MGZN"},
+	"book":                {"eBook", "book", "EBOOK"},
+	"edited-book":         {"eBook", "book", "EBOOK"},
+	"book-chapter":        {"ElectronicBookPart", "bookitem", "ECHAP"},
+	"conference-paper":    {"ElectronicProceeding", "proceeding", "CPAPER"},
+	"dissertation":        {"ElectronicThesis", "document", "THES"},
+	"dissertation-thesis": {"ElectronicThesis", "document", "THES"},
+	"preprint":            {"ElectronicPreprint", "preprint", "UNPB"},
+	"report":              {"ElectronicResourceRemoteAccess", "report", "RPRT"},
+	"working-paper":       {"ElectronicResourceRemoteAccess", "report", "RPRT"},
+	"data-set":            {"ElectronicResourceRemoteAccess", "document", "DATA"},
+}
+
+// ORCID source.
+type ORCID struct{}
+
+// Contributor is a person credited for a work.
+type Contributor struct {
+	ORCID      string `xml:"contributor-orcid>path"`
+	CreditName string `xml:"credit-name"`
+	Role       string `xml:"contributor-attributes>contributor-role"`
+}
+
+// ExternalID is an identifier of a work, e.g. a DOI.
+type ExternalID struct {
+	Type         string `xml:"external-id-type"`
+	Value        string `xml:"external-id-value"`
+	Relationship string `xml:"external-id-relationship"`
+}
+
+// Work is a single work of an ORCID record.
+type Work struct {
+	XMLName          xml.Name `xml:"work"`
+	PutCode          string   `xml:"put-code,attr"`
+	Path             string   `xml:"path,attr"`
+	SourceORCID      string   `xml:"source>source-orcid>path"`
+	Title            string   `xml:"title>title"`
+	Subtitle         string   `xml:"title>subtitle"`
+	JournalTitle     string   `xml:"journal-title"`
+	ShortDescription string   `xml:"short-description"`
+	Type             string   `xml:"type"`
+	PublicationDate  struct {
+		Year  string `xml:"year"`
+		Month string `xml:"month"`
+		Day   string `xml:"day"`
+	} `xml:"publication-date"`
+	ExternalIDs  []ExternalID  `xml:"external-ids>external-id"`
+	URL          string        `xml:"url"`
+	Contributors []Contributor `xml:"contributors>contributor"`
+	LanguageCode string        `xml:"language-code"`
+}
+
+// decodeWorks reads all work elements of concatenated XML documents.
+func decodeWorks(r io.Reader, f func(*Work)) error {
+	decoder := xml.NewDecoder(r)
+	for {
+		t, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if se, ok := t.(xml.StartElement); ok && se.Name.Local == "work" {
+			work := new(Work)
+			if err := decoder.DecodeElement(work, &se); err != nil {
+				return err
+			}
+			f(work)
+		}
+	}
+}
+
+// Decode reads works from a tar archive, optionally gzip compressed, or from
+// plain XML, and calls f for each of them. Only a single work is held in
+// memory at a time.
+func Decode(r io.Reader, f func(*Work)) error {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		br = bufio.NewReader(gr)
+	}
+	if magic, _ := br.Peek(262); len(magic) < 262 || string(magic[257:262]) != "ustar" {
+		return decodeWorks(br, f)
+	}
+	tr := tar.NewReader(br)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg || path.Ext(hdr.Name) != ".xml" {
+			continue
+		}
+		if err := decodeWorks(tr, f); err != nil {
+			return fmt.Errorf("%s: %v", hdr.Name, err)
+		}
+	}
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(works []*Work) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			return s.(span.Importer), nil
+		}, Items: make([]interface{}, len(works))}
+	for i, work := range works {
+		batch.Items[i] = work
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via XML decoding.
+func (s ORCID) Iterate(r io.Reader) (<-chan interface{}, error) {
+	ch := make(chan interface{})
+	var works []*Work
+	go func() {
+		err := Decode(r, func(work *Work) {
+			works = append(works, work)
+			if len(works) == BatchSize {
+				ch <- NewBatch(works)
+				works = nil
+			}
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		ch <- NewBatch(works)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Owner returns the ORCID iD of the record, the work belongs to, taken from
+// the path, e.g. "/0000-0002-1825-0097/work/123", or the source.
+func (w *Work) Owner() string {
+	if parts := strings.Split(strings.Trim(w.Path, "/"), "/"); len(parts) > 0 && parts[0] != "" {
+		return parts[0]
+	}
+	return w.SourceORCID
+}
+
+// DOI returns the DOI of the work itself, not of a part or a version.
+func (w *Work) DOI() string {
+	for _, id := range w.ExternalIDs {
+		if id.Type == "doi" && (id.Relationship == "" || id.Relationship == "self") {
+			v := strings.TrimSpace(id.Value)
+			for _, prefix := range []string{"https://doi.org/", "http://dx.doi.org/", "doi:"} {
+				v = strings.TrimPrefix(v, prefix)
+			}
+			return v
+		}
+	}
+	return ""
+}
+
+// Date returns the publication date, as precise as given.
+func (w *Work) Date() (time.Time, error) {
+	d := w.PublicationDate
+	if d.Year == "" {
+		return time.Time{}, fmt.Errorf("orcid: %s: no usable date", w.Path)
+	}
+	if d.Month == "" {
+		return time.Parse("2006", d.Year)
+	}
+	if d.Day == "" {
+		return time.Parse("2006-01", d.Year+"-"+d.Month)
+	}
+	return time.Parse("2006-01-02", d.Year+"-"+d.Month+"-"+d.Day)
+}
+
+// Authors returns the contributors with their ORCID iDs. The owner of the
+// record is a contributor, but often listed without iD. If so, and only a
+// single contributor has no iD, the owner iD is attached to it.
+func (w *Work) Authors() (authors []finc.Author) {
+	owner, ownerListed, withoutID := w.Owner(), false, -1
+	for _, c := range w.Contributors {
+		name := strings.TrimSpace(c.CreditName)
+		if name == "" {
+			continue
+		}
+		if c.Role != "" && c.Role != "author" {
+			continue
+		}
+		if c.ORCID == owner {
+			ownerListed = true
+		}
+		if c.ORCID == "" {
+			if withoutID == -1 {
+				withoutID = len(authors)
+			} else {
+				withoutID = -2
+			}
+		}
+		authors = append(authors, finc.Author{Name: name, ORCID: c.ORCID})
+	}
+	if owner != "" && !ownerListed && withoutID >= 0 {
+		authors[withoutID].ORCID = owner
+	}
+	return authors
+}
+
+// RecordID is of the form <kind>-<source-id>-<id-base64-unpadded>, with
+// owner and put code as primary key.
+func (w *Work) RecordID() string {
+	id := w.Owner() + "/" + w.PutCode
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(id)))
+	return strings.TrimRight(enc, "=")
+}
+
+// ToIntermediateSchema converts a work. Works without put code, title or
+// year and works of unsupported types are skipped.
+func (w *Work) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	if w.PutCode == "" || strings.TrimSpace(w.Title) == "" {
+		return output, span.Skip{Reason: fmt.Sprintf("orcid: %s: missing put code or title", w.Path)}
+	}
+	kind, ok := workTypes[w.Type]
+	if !ok {
+		return output, span.Skip{Reason: fmt.Sprintf("orcid: %s: unsupported type: %s", w.Path, w.Type)}
+	}
+	output.Date, err = w.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+
+	output.SourceID = SourceID
+	output.RecordID = w.RecordID()
+	output.MegaCollection = Collection
+	output.Format, output.Genre, output.RefType = kind[0], kind[1], kind[2]
+
+	output.ArticleTitle = strings.TrimSpace(w.Title)
+	output.ArticleSubtitle = strings.TrimSpace(w.Subtitle)
+	output.Authors = w.Authors()
+	output.DOI = w.DOI()
+	output.Abstract = strings.TrimSpace(w.ShortDescription)
+	if u := strings.TrimSpace(w.URL); u != "" {
+		output.URL = append(output.URL, u)
+	}
+	if w.LanguageCode != "" {
+		output.Languages = append(output.Languages, span.NormalizeLanguage(w.LanguageCode))
+	}
+	switch output.Genre {
+	case "bookitem", "proceeding":
+		output.BookTitle = strings.TrimSpace(w.JournalTitle)
+	default:
+		output.JournalTitle = strings.TrimSpace(w.JournalTitle)
+	}
+	return output, nil
+}
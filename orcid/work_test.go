@@ -0,0 +1,91 @@
+package orcid
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+	"github.com/miku/span/internal/spantest"
+)
+
+const work = `<?xml version="1.0" encoding="UTF-8"?>
+<work:work xmlns:common="http://www.orcid.org/ns/common" xmlns:work="http://www.orcid.org/ns/work" put-code="123" path="/0000-0002-1825-0097/work/123">
+	<common:source><common:source-orcid><common:path>0000-0002-1825-0097</common:path></common:source-orcid></common:source>
+	<work:title><common:title>Identifiers for people</common:title></work:title>
+	<work:journal-title>Journal of Examples</work:journal-title>
+	<work:type>journal-article</work:type>
+	<common:publication-date><common:year>2019</common:year><common:month>05</common:month></common:publication-date>
+	<common:external-ids>
+		<common:external-id><common:external-id-type>issn</common:external-id-type><common:external-id-value>1234-5678</common:external-id-value><common:external-id-relationship>part-of</common:external-id-relationship></common:external-id>
+		<common:external-id><common:external-id-type>doi</common:external-id-type><common:external-id-value>https://doi.org/10.1000/orcid.1</common:external-id-value><common:external-id-relationship>self</common:external-id-relationship></common:external-id>
+	</common:external-ids>
+	<work:contributors>
+		<work:contributor><work:credit-name>Josiah Carberry</work:credit-name><work:contributor-attributes><work:contributor-role>author</work:contributor-role></work:contributor-attributes></work:contributor>
+		<work:contributor><common:contributor-orcid><common:path>0000-0001-5109-3700</common:path></common:contributor-orcid><work:credit-name>Jane Doe</work:credit-name></work:contributor>
+		<work:contributor><work:credit-name>Ed Itor</work:credit-name><work:contributor-attributes><work:contributor-role>editor</work:contributor-role></work:contributor-attributes></work:contributor>
+	</work:contributors>
+</work:work>`
+
+const other = `<?xml version="1.0" encoding="UTF-8"?>
+<work:work xmlns:common="http://www.orcid.org/ns/common" xmlns:work="http://www.orcid.org/ns/work" put-code="124" path="/0000-0002-1825-0097/work/124">
+	<work:title><common:title>A lecture</common:title></work:title>
+	<work:type>lecture-speech</work:type>
+	<common:publication-date><common:year>2020</common:year></common:publication-date>
+</work:work>`
+
+// archive returns a gzip compressed tar archive with the works.
+func archive(t *testing.T) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for _, f := range [][2]string{
+		{"ORCID_2023_activites_7/097/0000-0002-1825-0097/works/0000-0002-1825-0097_123.xml", work},
+		{"ORCID_2023_activites_7/097/0000-0002-1825-0097/works/0000-0002-1825-0097_124.xml", other},
+		{"ORCID_2023_activites_7/README.txt", "not a work"},
+	} {
+		name, content := f[0], f[1]
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	gw.Close()
+	return buf.Bytes()
+}
+
+func TestIterate(t *testing.T) {
+	for _, input := range [][]byte{[]byte(work + "\n" + other), archive(t)} {
+		results, errs := spantest.ConvertAll(t, ORCID{}, bytes.NewReader(input))
+		if len(results) != 2 {
+			t.Fatalf("got %d works, want 2", len(results))
+		}
+		var is *finc.IntermediateSchema
+		for i, err := range errs {
+			if err == nil {
+				is = results[i]
+			} else if _, ok := err.(span.Skip); !ok {
+				t.Errorf("got %v, want span.Skip for an unsupported type", err)
+			}
+		}
+		if is == nil {
+			t.Fatal("want one converted work")
+		}
+		want := []finc.Author{
+			{Name: "Josiah Carberry", ORCID: "0000-0002-1825-0097"},
+			{Name: "Jane Doe", ORCID: "0000-0001-5109-3700"},
+		}
+		if !reflect.DeepEqual(is.Authors, want) {
+			t.Errorf("got authors %v, want %v", is.Authors, want)
+		}
+		if is.DOI != "10.1000/orcid.1" || is.JournalTitle != "Journal of Examples" || is.Date.Format("2006-01") != "2019-05" {
+			t.Errorf("got DOI %s, journal %q, date %v", is.DOI, is.JournalTitle, is.Date)
+		}
+	}
+}
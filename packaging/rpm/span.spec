@@ -31,6 +31,8 @@ mkdir -p $RPM_BUILD_ROOT/usr/local/sbin
 install -m 755 span-export $RPM_BUILD_ROOT/usr/local/sbin
 install -m 755 span-gh-dump $RPM_BUILD_ROOT/usr/local/sbin
 install -m 755 span-import $RPM_BUILD_ROOT/usr/local/sbin
+install -m 755 span-walls $RPM_BUILD_ROOT/usr/local/sbin
+install -m 755 span-crossref-harvest $RPM_BUILD_ROOT/usr/local/sbin
 
 
 %post
@@ -48,6 +50,8 @@ rm -rf %{_topdir}/BUILD/%{name}
 /usr/local/sbin/span-export
 /usr/local/sbin/span-gh-dump
 /usr/local/sbin/span-import
+/usr/local/sbin/span-walls
+/usr/local/sbin/span-crossref-harvest
 
 
 %changelog
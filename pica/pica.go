@@ -0,0 +1,289 @@
+package pica
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/container"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// DefaultSourceID is used, if no source id is configured.
+	DefaultSourceID = "161"
+	// DefaultCollection is used, if no collection is configured.
+	DefaultCollection = "K10plus"
+	// BatchSize number of records per batch.
+	BatchSize = 2000
+	// maxRecordSize limits the size of a single normalized record.
+	maxRecordSize = 16 << 20
+)
+
+// bibTypes maps the second position of the bibliographic type in 002@, the
+// bibliographic level, to format, genre and RIS type.
+var bibTypes = map[byte][3]string{
+	'a': {"eBook", "book", "EBOOK"},
+	'f': {"eBook", "book", "EBOOK"},
+	'F': {"eBook", "book", "EBOOK"},
+	'v': {"eBook", "book", "EBOOK"},
+	's': {"ElectronicArticle", "article", "JOUR"},
+}
+
+var yearPattern = regexp.MustCompile(`[12][0-9]{3}`)
+
+// PICA source. Source id and collection depend on the catalog, empty values
+// fall back to the defaults.
+type PICA struct {
+	SourceID   string
+	Collection string
+}
+
+// Document is a raw record together with the source settings.
+type Document struct {
+	*Record
+	source PICA
+}
+
+// NewBatch wraps up raw records for channel com, they are parsed in Apply.
+func NewBatch(records [][]byte, s PICA) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(v interface{}) (span.Importer, error) {
+			record, err := ParseRecord(v.([]byte))
+			if err != nil {
+				return nil, err
+			}
+			return &Document{Record: record, source: s}, nil
+		}, Items: make([]interface{}, len(records))}
+	for i, record := range records {
+		batch.Items[i] = record
+	}
+	return batch
+}
+
+// splitRecords is a bufio.SplitFunc, which splits at newlines and record
+// terminators.
+func splitRecords(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\n\x1d"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// Iterate reads normalized records, which are single lines, and plain
+// records, which span lines up to an empty line.
+func (s PICA) Iterate(r io.Reader) (<-chan interface{}, error) {
+	if s.SourceID == "" {
+		s.SourceID = DefaultSourceID
+	}
+	if s.Collection == "" {
+		s.Collection = DefaultCollection
+	}
+	ch := make(chan interface{})
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+	scanner.Split(splitRecords)
+	var (
+		records [][]byte
+		plain   bytes.Buffer
+	)
+	add := func(b []byte) {
+		records = append(records, b)
+		if len(records) == BatchSize {
+			ch <- NewBatch(records, s)
+			records = nil
+		}
+	}
+	flushPlain := func() {
+		if plain.Len() > 0 {
+			add(append([]byte(nil), plain.Bytes()...))
+			plain.Reset()
+		}
+	}
+	go func() {
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			switch {
+			case IsNormalized(line):
+				flushPlain()
+				add(append([]byte(nil), line...))
+			case len(bytes.TrimSpace(line)) == 0:
+				flushPlain()
+			default:
+				plain.Write(line)
+				plain.WriteByte('\n')
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Fatal(err)
+		}
+		flushPlain()
+		ch <- NewBatch(records, s)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// PPN returns the record identifier from 003@.
+func (r *Record) PPN() string {
+	return r.First("003@", "0")
+}
+
+// BibType returns the bibliographic type from 002@, e.g. "Oaxn".
+func (r *Record) BibType() string {
+	return r.First("002@", "0")
+}
+
+// Title returns the title from 021A, without the "@" marking the start of
+// the sorting title.
+func (r *Record) Title() string {
+	return strings.Replace(r.First("021A", "a"), "@", "", 1)
+}
+
+// name converts a person field, with family and given name in $a and $d,
+// in $A and $D, a personal name in $P or an expansion in $8.
+func name(f Field) finc.Author {
+	family, given := f.Get("a"), f.Get("d")
+	if family == "" {
+		family, given = f.Get("A"), f.Get("D")
+	}
+	if family != "" {
+		return finc.Author{LastName: family, FirstName: given}
+	}
+	for _, code := range []string{"P", "8"} {
+		if v := f.Get(code); v != "" {
+			return finc.Author{Name: v}
+		}
+	}
+	return finc.Author{}
+}
+
+// Authors returns the primary and further authors from 028A and 028B and
+// corporate authors from 029A.
+func (r *Record) Authors() (authors []finc.Author) {
+	for _, tag := range []string{"028A", "028B"} {
+		for _, f := range r.FieldsByTag(tag) {
+			if a := name(f); a != (finc.Author{}) {
+				authors = append(authors, a)
+			}
+		}
+	}
+	for _, v := range r.Values("029A", "a") {
+		authors = append(authors, finc.Author{Corporation: v})
+	}
+	return authors
+}
+
+// Date returns the year of publication from 011@.
+func (r *Record) Date() (time.Time, error) {
+	year := yearPattern.FindString(r.First("011@", "a"))
+	if year == "" {
+		return time.Time{}, fmt.Errorf("%s: no usable date", r.PPN())
+	}
+	return time.Parse("2006", year)
+}
+
+// Subjects returns keywords and subject headings, without duplicates.
+func (r *Record) Subjects() (subjects []string) {
+	seen := container.NewStringSet()
+	for _, tag := range []string{"044K", "044A", "044N"} {
+		for _, v := range r.Values(tag, "a") {
+			if !seen.Contains(v) {
+				seen.Add(v)
+				subjects = append(subjects, v)
+			}
+		}
+	}
+	return subjects
+}
+
+// RecordID is of the form <kind>-<source-id>-<id-base64-unpadded>, with the
+// PPN as primary key.
+func (r *Record) RecordID(sourceID string) string {
+	enc := fmt.Sprintf("ai-%s-%s", sourceID, base64.URLEncoding.EncodeToString([]byte(r.PPN())))
+	return strings.TrimRight(enc, "=")
+}
+
+// Convert maps the record. Records without PPN or year, print records and
+// bibliographic levels other than monographs and articles are skipped.
+func (r *Record) Convert(sourceID string) (*finc.IntermediateSchema, error) {
+	output := finc.NewIntermediateSchema()
+
+	ppn := r.PPN()
+	if ppn == "" {
+		return output, span.Skip{Reason: "pica: record without PPN"}
+	}
+	bibType := r.BibType()
+	if len(bibType) < 2 || bibType[0] != 'O' {
+		return output, span.Skip{Reason: fmt.Sprintf("%s: not an online resource: %q", ppn, bibType)}
+	}
+	kind, ok := bibTypes[bibType[1]]
+	if !ok {
+		return output, span.Skip{Reason: fmt.Sprintf("%s: unsupported bibliographic type: %s", ppn, bibType)}
+	}
+	date, err := r.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+
+	output.Date = date
+	output.SourceID = sourceID
+	output.RecordID = r.RecordID(sourceID)
+	output.Format, output.Genre, output.RefType = kind[0], kind[1], kind[2]
+
+	output.ArticleTitle = r.Title()
+	output.ArticleSubtitle = r.First("021A", "d")
+	output.Authors = r.Authors()
+	output.Places = r.Values("033A", "p")
+	output.Publishers = r.Values("033A", "n")
+	output.Edition = r.First("032@", "a")
+	output.Series = r.First("036E", "a")
+	output.PageCount = r.First("034D", "a")
+	output.ISBN = r.Values("004A", "0")
+	output.ISSN = r.Values("005A", "0")
+	output.DOI = r.First("004V", "0")
+	output.URL = r.Values("017C", "u")
+	output.Abstract = r.First("047I", "a")
+	output.Subjects = r.Subjects()
+	for _, lang := range r.Values("010@", "a") {
+		output.Languages = append(output.Languages, span.NormalizeLanguage(lang))
+	}
+
+	if output.Genre == "article" {
+		host := r.First("039B", "t")
+		if host == "" {
+			host = r.First("039B", "8")
+		}
+		output.JournalTitle = host
+		for _, f := range r.FieldsByTag("031A") {
+			output.Volume, output.Issue, output.Pages = f.Get("d"), f.Get("e"), f.Get("h")
+		}
+		if parts := strings.SplitN(output.Pages, "-", 2); len(parts) == 2 {
+			output.StartPage, output.EndPage = strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		}
+	}
+	return output, nil
+}
+
+// ToIntermediateSchema converts the record with the settings of the source.
+func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	output, err := doc.Record.Convert(doc.source.SourceID)
+	if err != nil {
+		return output, err
+	}
+	output.MegaCollection = doc.source.Collection
+	return output, nil
+}
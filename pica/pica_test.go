@@ -0,0 +1,64 @@
+package pica
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+	"github.com/miku/span/internal/spantest"
+)
+
+// A normalized article, a plain e-book and a plain print record.
+const example = "002@ \x1f0Osx\x1e003@ \x1f0100000001\x1e011@ \x1fa2016\x1e021A \x1faAn @article\x1e" +
+	"028A \x1fdJane\x1faDoe\x1e039B \x1ftJournal of Examples\x1e031A \x1fd12\x1fe3\x1fh101-110\x1e" +
+	"005A \x1f01234-5678\x1e004V \x1f010.1000/pica.1\x1e010@ \x1faeng\x1e\n" +
+	`002@ $0Oaxn
+003@ $0100000002
+011@ $a[2015]
+021A $aAn e-book$dwith a subtitle
+028A $PExample Author
+033A $pBerlin$nExample Press
+017C $uhttps://example.org/2
+044K $aBiologie
+044K $aBiologie
+
+002@ $0Aau
+003@ $0100000003
+011@ $a1999
+021A $aA print book
+`
+
+func TestPICA(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, PICA{}, strings.NewReader(example))
+	if len(results) != 3 {
+		t.Fatalf("got %d records, want 3", len(results))
+	}
+	if errs[0] != nil || errs[1] != nil {
+		t.Fatalf("unexpected errors: %v, %v", errs[0], errs[1])
+	}
+	if _, ok := errs[2].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for a print record", errs[2])
+	}
+	article := results[0]
+	if article.Format != "ElectronicArticle" || article.ArticleTitle != "An article" || article.JournalTitle != "Journal of Examples" {
+		t.Errorf("got format %s, title %q, journal %q", article.Format, article.ArticleTitle, article.JournalTitle)
+	}
+	if article.Volume != "12" || article.Issue != "3" || article.StartPage != "101" || article.EndPage != "110" {
+		t.Errorf("got volume %s, issue %s, pages %s-%s", article.Volume, article.Issue, article.StartPage, article.EndPage)
+	}
+	if !reflect.DeepEqual(article.Authors, []finc.Author{{LastName: "Doe", FirstName: "Jane"}}) || article.DOI != "10.1000/pica.1" {
+		t.Errorf("got authors %v, DOI %s", article.Authors, article.DOI)
+	}
+	book := results[1]
+	if book.Format != "eBook" || book.Date.Year() != 2015 || book.ArticleSubtitle != "with a subtitle" {
+		t.Errorf("got format %s, date %v, subtitle %q", book.Format, book.Date, book.ArticleSubtitle)
+	}
+	if !reflect.DeepEqual(book.Subjects, []string{"Biologie"}) || !reflect.DeepEqual(book.Publishers, []string{"Example Press"}) {
+		t.Errorf("got subjects %v, publishers %v", book.Subjects, book.Publishers)
+	}
+	if book.MegaCollection != DefaultCollection || !strings.HasPrefix(book.RecordID, "ai-"+DefaultSourceID+"-") {
+		t.Errorf("got collection %s, record id %s", book.MegaCollection, book.RecordID)
+	}
+}
@@ -0,0 +1,195 @@
+// Package pica converts PICA+ records, as exported from the GBV and K10plus
+// union catalogs, into the intermediate schema. Both normalized PICA+, one
+// record per line or terminated by 0x1D, and PICA plain, one field per line
+// and records separated by empty lines, are read.
+package pica
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Delimiters of normalized PICA+.
+const (
+	RecordTerminator = 0x1D
+	FieldTerminator  = 0x1E
+	SubfieldDelim    = 0x1F
+)
+
+var errEmptyRecord = errors.New("pica: empty record")
+
+// Subfield is a coded part of a field.
+type Subfield struct {
+	Code  string
+	Value string
+}
+
+// Field has a tag, e.g. "021A", an optional occurrence, e.g. "01" for
+// "047A/01", and subfields.
+type Field struct {
+	Tag        string
+	Occurrence string
+	Subfields  []Subfield
+}
+
+// Record is a PICA+ record.
+type Record struct {
+	Fields []Field
+}
+
+// IsNormalized reports, whether raw record data is normalized PICA+.
+func IsNormalized(b []byte) bool {
+	return bytes.IndexByte(b, FieldTerminator) >= 0
+}
+
+// ParseRecord decodes a single record, normalized or plain.
+func ParseRecord(b []byte) (*Record, error) {
+	if IsNormalized(b) {
+		return ParseNormalized(b)
+	}
+	return ParsePlain(b)
+}
+
+// parseTag splits the head of a field into tag and occurrence.
+func parseTag(head string) (Field, error) {
+	head = strings.TrimSpace(head)
+	var field Field
+	if i := strings.Index(head, "/"); i >= 0 {
+		head, field.Occurrence = head[:i], head[i+1:]
+	}
+	if len(head) != 4 {
+		return field, fmt.Errorf("pica: invalid tag: %q", head)
+	}
+	field.Tag = head
+	return field, nil
+}
+
+// ParseNormalized decodes a normalized record: fields end with 0x1E, each
+// subfield starts with 0x1F and the tag is separated from the subfields by a
+// space.
+func ParseNormalized(b []byte) (*Record, error) {
+	b = bytes.TrimRight(b, "\n\r\x1d")
+	record := new(Record)
+	for _, data := range bytes.Split(b, []byte{FieldTerminator}) {
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+		parts := strings.Split(string(data), string(rune(SubfieldDelim)))
+		field, err := parseTag(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range parts[1:] {
+			if p == "" {
+				continue
+			}
+			field.Subfields = append(field.Subfields, Subfield{Code: p[:1], Value: p[1:]})
+		}
+		record.Fields = append(record.Fields, field)
+	}
+	if len(record.Fields) == 0 {
+		return nil, errEmptyRecord
+	}
+	return record, nil
+}
+
+// ParsePlain decodes a record in PICA plain, where each line is a field and
+// subfields start with "$". A literal dollar sign is written as "$$".
+func ParsePlain(b []byte) (*Record, error) {
+	record := new(Record)
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		i := strings.Index(line, "$")
+		if i < 0 {
+			return nil, fmt.Errorf("pica: field without subfields: %q", line)
+		}
+		field, err := parseTag(line[:i])
+		if err != nil {
+			return nil, err
+		}
+		rest := line[i+1:]
+		for len(rest) > 0 {
+			var value strings.Builder
+			code := rest[:1]
+			j := 1
+			for j < len(rest) {
+				if rest[j] == '$' {
+					if j+1 < len(rest) && rest[j+1] == '$' {
+						value.WriteByte('$')
+						j += 2
+						continue
+					}
+					break
+				}
+				value.WriteByte(rest[j])
+				j++
+			}
+			field.Subfields = append(field.Subfields, Subfield{Code: code, Value: value.String()})
+			if j >= len(rest) {
+				break
+			}
+			rest = rest[j+1:]
+		}
+		record.Fields = append(record.Fields, field)
+	}
+	if len(record.Fields) == 0 {
+		return nil, errEmptyRecord
+	}
+	return record, nil
+}
+
+// Values returns one value per field with a given tag, which joins the
+// subfields with the given codes in record order, e.g. Values("021A", "ad").
+// Fields without any of the subfields are left out.
+func (r *Record) Values(tag, codes string) (values []string) {
+	for _, f := range r.Fields {
+		if f.Tag != tag {
+			continue
+		}
+		var parts []string
+		for _, sf := range f.Subfields {
+			if strings.Contains(codes, sf.Code) {
+				if v := strings.TrimSpace(sf.Value); v != "" {
+					parts = append(parts, v)
+				}
+			}
+		}
+		if len(parts) > 0 {
+			values = append(values, strings.Join(parts, " "))
+		}
+	}
+	return values
+}
+
+// First returns the first value of Values or the empty string.
+func (r *Record) First(tag, codes string) string {
+	if values := r.Values(tag, codes); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// FieldsByTag returns all fields with a given tag.
+func (r *Record) FieldsByTag(tag string) (fields []Field) {
+	for _, f := range r.Fields {
+		if f.Tag == tag {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// Get returns the first value of a subfield code in a field.
+func (f Field) Get(code string) string {
+	for _, sf := range f.Subfields {
+		if sf.Code == code {
+			return strings.TrimSpace(sf.Value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,39 @@
+package pica
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePlain(t *testing.T) {
+	r, err := ParsePlain([]byte("003@ $0123456789\n047A/03 $aPrice: 10 $$ $bnote\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.PPN() != "123456789" {
+		t.Errorf("got PPN %q", r.PPN())
+	}
+	want := Field{Tag: "047A", Occurrence: "03", Subfields: []Subfield{{"a", "Price: 10 $ "}, {"b", "note"}}}
+	if !reflect.DeepEqual(r.Fields[1], want) {
+		t.Errorf("got %v, want %v", r.Fields[1], want)
+	}
+	if _, err := ParsePlain([]byte("003@\n")); err == nil {
+		t.Error("want error for a field without subfields")
+	}
+}
+
+func TestParseNormalized(t *testing.T) {
+	r, err := ParseRecord([]byte("003@ \x1f0123\x1e021A \x1faThe @example\x1fdsubtitle\x1e\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Values("021A", "ad"); !reflect.DeepEqual(got, []string{"The @example subtitle"}) {
+		t.Errorf("got %q", got)
+	}
+	if r.Title() != "The example" {
+		t.Errorf("got title %q", r.Title())
+	}
+	if _, err := ParseNormalized([]byte("\x1e\n")); err != errEmptyRecord {
+		t.Errorf("got %v, want %v", err, errEmptyRecord)
+	}
+}
@@ -0,0 +1,290 @@
+// Package proquest converts ProQuest XML exports, one RECORD element per
+// document, into the intermediate schema. Document metadata lives in the Obj
+// element, the serial in DFS, with publication (PubFrosting) and issue
+// (GroupFrosting) level data.
+package proquest
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kennygrant/sanitize"
+	"github.com/miku/span"
+	"github.com/miku/span/container"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// SourceID for internal bookkeeping.
+	SourceID = "158"
+	// Collection name.
+	Collection = "ProQuest"
+	// BatchSize number of records per batch.
+	BatchSize = 2000
+)
+
+var (
+	// objectTypes maps ProQuest object types to format, genre and RIS type.
+	objectTypes = map[string][3]string{
+		"article":               {"ElectronicArticle", "article", "JOUR"},
+		"journal article":       {"ElectronicArticle", "article", "JOUR"},
+		"feature":               {"ElectronicArticle", "article", "JOUR"},
+		"review":                {"ElectronicArticle", "article", "JOUR"},
+		"book review":           {"ElectronicArticle", "article", "JOUR"},
+		"editorial":             {"ElectronicArticle", "article", "JOUR"},
+		"news":                  {"ElectronicArticle", "article", "NEWS"},
+		"newspaper article":     {"ElectronicArticle", "article", "NEWS"},
+		"dissertation/thesis":   {"ElectronicThesis", "document", "THES"},
+		"conference paper":      {"ElectronicProceeding", "proceeding", "CPAPER"},
+		"conference proceeding": {"ElectronicProceeding", "proceeding", "CPAPER"},
+		"book":                  {"eBook", "book", "EBOOK"},
+		"book chapter":          {"ElectronicBookPart", "bookitem", "ECHAP"},
+		"report":                {"ElectronicResourceRemoteAccess", "report", "RPRT"},
+	}
+	// defaultType is used for object types without mapping.
+	defaultType = [3]string{"ElectronicResourceRemoteAccess", "unknown", "GEN"}
+	// UnmappedTypes counts object types without mapping.
+	UnmappedTypes = container.NewStringCounter()
+
+	// languages maps the language names of the exports to ISO 639-3.
+	languages = map[string]string{
+		"english": "eng", "german": "deu", "french": "fra", "spanish": "spa",
+		"italian": "ita", "portuguese": "por", "russian": "rus", "dutch": "nld",
+		"chinese": "zho", "japanese": "jpn",
+	}
+
+	issnPattern = regexp.MustCompile(`^([0-9]{4})-?([0-9]{3}[0-9X])$`)
+)
+
+// ProQuest source.
+type ProQuest struct{}
+
+// Document is a single record of an export.
+type Document struct {
+	Obj struct {
+		IDs []struct {
+			Type  string `xml:"IDType,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"ObjectIDs>ObjectID"`
+		Title        string   `xml:"TitleAtt>Title"`
+		ObjectTypes  []string `xml:"ObjectTypes>mstar"`
+		Contributors []struct {
+			Role      string `xml:"ContribRole,attr"`
+			LastName  string `xml:"LastName"`
+			FirstName string `xml:"FirstName"`
+			Name      string `xml:"OriginalForm"`
+		} `xml:"Contributors>Contributor"`
+		NumericDate string   `xml:"NumericDate"`
+		StartPage   string   `xml:"StartPage"`
+		EndPage     string   `xml:"EndPage"`
+		PageRange   string   `xml:"PageRange"`
+		Languages   []string `xml:"Language>RawLang"`
+		Abstract    struct {
+			Value string `xml:",innerxml"`
+		} `xml:"Abstract>Medium>AbsText"`
+		Terms []string `xml:"Terms>FlexTerm>FlexTermValue"`
+	} `xml:"Obj"`
+	DFS struct {
+		Pub struct {
+			Title      string   `xml:"Title"`
+			SourceType string   `xml:"SourceType"`
+			ISSN       []string `xml:"ISSN"`
+			EISSN      []string `xml:"EISSN"`
+			ISBN       []string `xml:"ISBN"`
+			Publisher  string   `xml:"PublisherName"`
+		} `xml:"PubFrosting"`
+		Group struct {
+			Volume string `xml:"Volume"`
+			Issue  string `xml:"Issue"`
+		} `xml:"GroupFrosting"`
+	} `xml:"DFS"`
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(docs []*Document) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			return s.(span.Importer), nil
+		}, Items: make([]interface{}, len(docs))}
+	for i, doc := range docs {
+		batch.Items[i] = doc
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via XML decoding.
+func (s ProQuest) Iterate(r io.Reader) (<-chan interface{}, error) {
+	ch := make(chan interface{})
+	var docs []*Document
+	go func() {
+		decoder := xml.NewDecoder(bufio.NewReader(r))
+		for {
+			t, _ := decoder.Token()
+			if t == nil {
+				break
+			}
+			switch se := t.(type) {
+			case xml.StartElement:
+				if se.Name.Local == "RECORD" {
+					doc := new(Document)
+					if err := decoder.DecodeElement(doc, &se); err != nil {
+						log.Fatal(err)
+					}
+					docs = append(docs, doc)
+					if len(docs) == BatchSize {
+						ch <- NewBatch(docs)
+						docs = nil
+					}
+				}
+			}
+		}
+		ch <- NewBatch(docs)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// ID returns the ProQuest document id.
+func (doc *Document) ID() string {
+	for _, id := range doc.Obj.IDs {
+		if id.Type == "DOCID" {
+			return strings.TrimSpace(id.Value)
+		}
+	}
+	return ""
+}
+
+// DOI returns the DOI, if any.
+func (doc *Document) DOI() string {
+	for _, id := range doc.Obj.IDs {
+		if id.Type == "DOI" {
+			return strings.TrimSpace(id.Value)
+		}
+	}
+	return ""
+}
+
+// Types returns format, genre and RIS type of the first mapped object type.
+func (doc *Document) Types() [3]string {
+	for _, t := range doc.Obj.ObjectTypes {
+		if v, ok := objectTypes[strings.ToLower(strings.TrimSpace(t))]; ok {
+			return v
+		}
+	}
+	for _, t := range doc.Obj.ObjectTypes {
+		UnmappedTypes.Inc(t)
+	}
+	return defaultType
+}
+
+// normalizeISSNs hyphenates ISSNs, drops invalid values and duplicates.
+// Serials often list the ISSNs of earlier titles as well, so there may be
+// several.
+func normalizeISSNs(values []string, seen map[string]bool) (issns []string) {
+	for _, v := range values {
+		m := issnPattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(v)))
+		if m == nil {
+			continue
+		}
+		issn := m[1] + "-" + m[2]
+		if seen[issn] {
+			continue
+		}
+		seen[issn] = true
+		issns = append(issns, issn)
+	}
+	return issns
+}
+
+// ISSN returns the print and electronic ISSNs of the serial.
+func (doc *Document) ISSN() (issn, eissn []string) {
+	seen := make(map[string]bool)
+	issn = normalizeISSNs(doc.DFS.Pub.ISSN, seen)
+	eissn = normalizeISSNs(doc.DFS.Pub.EISSN, seen)
+	return issn, eissn
+}
+
+// Date returns the numeric date.
+func (doc *Document) Date() (time.Time, error) {
+	v := strings.TrimSpace(doc.Obj.NumericDate)
+	for _, layout := range []string{"2006-01-02", "20060102", "2006-01", "2006"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("proquest: invalid date: %q", v)
+}
+
+// ToIntermediateSchema converts a record. Records without id or date are
+// skipped.
+func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	id := doc.ID()
+	if id == "" {
+		return output, span.Skip{Reason: "proquest: record without DOCID"}
+	}
+	output.Date, err = doc.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(id)))
+	output.SourceID = SourceID
+	output.RecordID = strings.TrimRight(enc, "=")
+	output.MegaCollection = Collection
+	types := doc.Types()
+	output.Format, output.Genre, output.RefType = types[0], types[1], types[2]
+
+	output.DOI = doc.DOI()
+	output.URL = append(output.URL, fmt.Sprintf("https://www.proquest.com/docview/%s", id))
+	output.ArticleTitle = strings.TrimSpace(doc.Obj.Title)
+	for _, c := range doc.Obj.Contributors {
+		if c.Role != "" && c.Role != "Author" {
+			continue
+		}
+		if c.LastName == "" {
+			output.Authors = append(output.Authors, finc.Author{Name: strings.TrimSpace(c.Name)})
+			continue
+		}
+		output.Authors = append(output.Authors, finc.Author{
+			LastName: strings.TrimSpace(c.LastName), FirstName: strings.TrimSpace(c.FirstName)})
+	}
+	output.Abstract = strings.Join(strings.Fields(sanitize.HTML(doc.Obj.Abstract.Value)), " ")
+	output.Subjects = doc.Obj.Terms
+	for _, lang := range doc.Obj.Languages {
+		if code, ok := languages[strings.ToLower(strings.TrimSpace(lang))]; ok {
+			output.Languages = append(output.Languages, code)
+		} else if code := span.NormalizeLanguage(lang); code != "und" {
+			output.Languages = append(output.Languages, code)
+		}
+	}
+
+	pub := doc.DFS.Pub
+	if output.Genre == "proceeding" || output.Genre == "bookitem" {
+		output.BookTitle = strings.TrimSpace(pub.Title)
+	} else {
+		output.JournalTitle = strings.TrimSpace(pub.Title)
+	}
+	output.ISSN, output.EISSN = doc.ISSN()
+	output.ISBN = pub.ISBN
+	if pub.Publisher != "" {
+		output.Publishers = append(output.Publishers, strings.TrimSpace(pub.Publisher))
+	}
+	output.Volume = strings.TrimSpace(doc.DFS.Group.Volume)
+	output.Issue = strings.TrimSpace(doc.DFS.Group.Issue)
+	output.StartPage = strings.TrimSpace(doc.Obj.StartPage)
+	output.EndPage = strings.TrimSpace(doc.Obj.EndPage)
+	output.Pages = strings.TrimSpace(doc.Obj.PageRange)
+	if output.Pages == "" && output.StartPage != "" && output.EndPage != "" {
+		output.Pages = fmt.Sprintf("%s-%s", output.StartPage, output.EndPage)
+	}
+	return output, nil
+}
@@ -0,0 +1,79 @@
+package proquest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span/internal/spantest"
+)
+
+const example = `<?xml version="1.0" encoding="UTF-8"?>
+<Records>
+<RECORD>
+	<Obj>
+		<ObjectIDs>
+			<ObjectID IDType="DOCID">1234567890</ObjectID>
+			<ObjectID IDType="DOI">10.1000/pq.1</ObjectID>
+		</ObjectIDs>
+		<TitleAtt><Title>A scholarly article</Title></TitleAtt>
+		<ObjectTypes><mstar>Journal Article</mstar></ObjectTypes>
+		<Contributors>
+			<Contributor ContribRole="Author"><LastName>Doe</LastName><FirstName>Jane</FirstName></Contributor>
+			<Contributor ContribRole="Editor"><LastName>Roe</LastName></Contributor>
+		</Contributors>
+		<NumericDate>2016-03-01</NumericDate>
+		<StartPage>5</StartPage>
+		<PageRange>5-19</PageRange>
+		<Language><RawLang>English</RawLang></Language>
+		<Abstract><Medium><AbsText><p>An abstract.</p></AbsText></Medium></Abstract>
+	</Obj>
+	<DFS>
+		<PubFrosting>
+			<Title>Journal of Examples</Title>
+			<SourceType>Scholarly Journals</SourceType>
+			<ISSN>00000019</ISSN>
+			<ISSN>0000-0019</ISSN>
+			<ISSN>1234567x</ISSN>
+			<EISSN>2345-6789</EISSN>
+			<PublisherName>Example Press</PublisherName>
+		</PubFrosting>
+		<GroupFrosting><Volume>12</Volume><Issue>3</Issue></GroupFrosting>
+	</DFS>
+</RECORD>
+<RECORD>
+	<Obj>
+		<ObjectIDs><ObjectID IDType="DOCID">2</ObjectID></ObjectIDs>
+		<ObjectTypes><mstar>Obituary</mstar></ObjectTypes>
+		<NumericDate>20150101</NumericDate>
+	</Obj>
+</RECORD>
+</Records>`
+
+func TestProQuest(t *testing.T) {
+	results := spantest.MustConvertAll(t, ProQuest{}, strings.NewReader(example))
+	if len(results) != 2 {
+		t.Fatalf("got %d records, want 2", len(results))
+	}
+	is := results[0]
+	if !reflect.DeepEqual(is.ISSN, []string{"0000-0019", "1234-567X"}) || !reflect.DeepEqual(is.EISSN, []string{"2345-6789"}) {
+		t.Errorf("got ISSN %v, EISSN %v", is.ISSN, is.EISSN)
+	}
+	if is.Format != "ElectronicArticle" || is.Genre != "article" || is.JournalTitle != "Journal of Examples" {
+		t.Errorf("got format %s, genre %s, journal %q", is.Format, is.Genre, is.JournalTitle)
+	}
+	if len(is.Authors) != 1 || is.Authors[0].LastName != "Doe" || is.Abstract != "An abstract." {
+		t.Errorf("got authors %v, abstract %q", is.Authors, is.Abstract)
+	}
+	if !reflect.DeepEqual(is.Languages, []string{"eng"}) || is.Pages != "5-19" || is.DOI != "10.1000/pq.1" {
+		t.Errorf("got languages %v, pages %q, DOI %q", is.Languages, is.Pages, is.DOI)
+	}
+
+	other := results[1]
+	if other.Format != "ElectronicResourceRemoteAccess" || other.Genre != "unknown" {
+		t.Errorf("got format %s, genre %s for an unmapped type", other.Format, other.Genre)
+	}
+	if UnmappedTypes.Counts()["Obituary"] != 1 {
+		t.Errorf("got unmapped types %v", UnmappedTypes.Counts())
+	}
+}
@@ -0,0 +1,341 @@
+// Package pubmed converts PubMed/MEDLINE XML, as found in the baseline and
+// update files, into the intermediate schema.
+package pubmed
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/container"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// SourceID for internal bookkeeping.
+	SourceID = "142"
+	// Collection name.
+	Collection = "PubMed"
+	// Format for all records.
+	Format = "ElectronicArticle"
+	// BatchSize number of documents per batch.
+	BatchSize = 2000
+)
+
+// yearPattern finds the first year in a free-form MedlineDate, e.g. "1998 Dec-1999 Jan".
+var yearPattern = regexp.MustCompile(`[12][0-9]{3}`)
+
+// PubMed source.
+type PubMed struct{}
+
+// Document is a single PubmedArticle element of a PubmedArticleSet.
+type Document struct {
+	XMLName         xml.Name `xml:"PubmedArticle"`
+	MedlineCitation struct {
+		PMID    string `xml:"PMID"`
+		Article struct {
+			Journal struct {
+				ISSN []struct {
+					Type  string `xml:"IssnType,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"ISSN"`
+				JournalIssue struct {
+					Volume  string `xml:"Volume"`
+					Issue   string `xml:"Issue"`
+					PubDate struct {
+						Year        string `xml:"Year"`
+						Month       string `xml:"Month"`
+						Day         string `xml:"Day"`
+						MedlineDate string `xml:"MedlineDate"`
+					} `xml:"PubDate"`
+				} `xml:"JournalIssue"`
+				Title           string `xml:"Title"`
+				ISOAbbreviation string `xml:"ISOAbbreviation"`
+			} `xml:"Journal"`
+			ArticleTitle string `xml:"ArticleTitle"`
+			Pagination   struct {
+				MedlinePgn string `xml:"MedlinePgn"`
+			} `xml:"Pagination"`
+			ELocationID []struct {
+				Type  string `xml:"EIdType,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"ELocationID"`
+			Abstract struct {
+				Text []struct {
+					Label string `xml:"Label,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"AbstractText"`
+			} `xml:"Abstract"`
+			AuthorList struct {
+				Author []struct {
+					LastName       string `xml:"LastName"`
+					ForeName       string `xml:"ForeName"`
+					Initials       string `xml:"Initials"`
+					CollectiveName string `xml:"CollectiveName"`
+				} `xml:"Author"`
+			} `xml:"AuthorList"`
+			Language []string `xml:"Language"`
+		} `xml:"Article"`
+		MedlineJournalInfo struct {
+			Country string `xml:"Country"`
+		} `xml:"MedlineJournalInfo"`
+		MeshHeadingList struct {
+			MeshHeading []struct {
+				DescriptorName string   `xml:"DescriptorName"`
+				QualifierName  []string `xml:"QualifierName"`
+			} `xml:"MeshHeading"`
+		} `xml:"MeshHeadingList"`
+	} `xml:"MedlineCitation"`
+	PubmedData struct {
+		ArticleIdList struct {
+			ArticleId []struct {
+				Type  string `xml:"IdType,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"ArticleId"`
+		} `xml:"ArticleIdList"`
+	} `xml:"PubmedData"`
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(docs []*Document) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			return s.(span.Importer), nil
+		}, Items: make([]interface{}, len(docs))}
+	for i, doc := range docs {
+		batch.Items[i] = doc
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via XML decoding. Only a single article
+// is decoded at a time, so this works on baseline files of any size.
+func (s PubMed) Iterate(r io.Reader) (<-chan interface{}, error) {
+	ch := make(chan interface{})
+	i := 0
+	var docs []*Document
+	go func() {
+		decoder := xml.NewDecoder(bufio.NewReader(r))
+		for {
+			t, _ := decoder.Token()
+			if t == nil {
+				break
+			}
+			switch se := t.(type) {
+			case xml.StartElement:
+				if se.Name.Local == "PubmedArticle" {
+					doc := new(Document)
+					err := decoder.DecodeElement(&doc, &se)
+					if err != nil {
+						log.Fatal(err)
+					}
+					i++
+					docs = append(docs, doc)
+					if i == BatchSize {
+						ch <- NewBatch(docs)
+						docs = nil
+						i = 0
+					}
+				}
+			}
+		}
+		ch <- NewBatch(docs)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// PMID returns the PubMed identifier.
+func (doc *Document) PMID() string {
+	return strings.TrimSpace(doc.MedlineCitation.PMID)
+}
+
+// URL returns the PubMed landing page.
+func (doc *Document) URL() string {
+	return fmt.Sprintf("https://pubmed.ncbi.nlm.nih.gov/%s/", doc.PMID())
+}
+
+// RecordID is of the form <kind>-<source-id>-<id-base64-unpadded>, like
+// in the other sources, with the landing page as primary key.
+func (doc *Document) RecordID() string {
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(doc.URL())))
+	return strings.TrimRight(enc, "=")
+}
+
+// DOI returns the DOI from the article id list or the electronic location.
+func (doc *Document) DOI() string {
+	for _, id := range doc.PubmedData.ArticleIdList.ArticleId {
+		if id.Type == "doi" {
+			return strings.TrimSpace(id.Value)
+		}
+	}
+	for _, id := range doc.MedlineCitation.Article.ELocationID {
+		if id.Type == "doi" {
+			return strings.TrimSpace(id.Value)
+		}
+	}
+	return ""
+}
+
+// ISSNs returns print and electronic ISSNs. Linking ISSNs are not part of
+// the article, only of the journal info, so they are ignored.
+func (doc *Document) ISSNs() (issn, eissn []string) {
+	for _, v := range doc.MedlineCitation.Article.Journal.ISSN {
+		switch v.Type {
+		case "Electronic":
+			eissn = append(eissn, strings.TrimSpace(v.Value))
+		default:
+			issn = append(issn, strings.TrimSpace(v.Value))
+		}
+	}
+	return issn, eissn
+}
+
+// Date returns the publication date. Month and day are optional, the month
+// may be numeric or abbreviated. For free-form MedlineDate values, only the
+// first year is used.
+func (doc *Document) Date() (time.Time, error) {
+	pd := doc.MedlineCitation.Article.Journal.JournalIssue.PubDate
+	if pd.Year == "" {
+		year := yearPattern.FindString(pd.MedlineDate)
+		if year == "" {
+			return time.Time{}, fmt.Errorf("no usable date: %q", pd.MedlineDate)
+		}
+		return time.Parse("2006", year)
+	}
+	s, layouts := pd.Year, []string{"2006"}
+	if pd.Month != "" {
+		s = s + "-" + pd.Month
+		layouts = []string{"2006-Jan", "2006-1", "2006-01"}
+		if pd.Day != "" {
+			s = s + "-" + pd.Day
+			layouts = []string{"2006-Jan-2", "2006-1-2", "2006-01-02"}
+		}
+	}
+	var err error
+	for _, layout := range layouts {
+		var t time.Time
+		if t, err = time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// Abstract joins the abstract sections, keeping their labels.
+func (doc *Document) Abstract() string {
+	var parts []string
+	for _, text := range doc.MedlineCitation.Article.Abstract.Text {
+		v := strings.TrimSpace(text.Value)
+		if v == "" {
+			continue
+		}
+		if text.Label != "" {
+			v = text.Label + ": " + v
+		}
+		parts = append(parts, v)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Authors returns persons and group authors.
+func (doc *Document) Authors() (authors []finc.Author) {
+	for _, a := range doc.MedlineCitation.Article.AuthorList.Author {
+		if a.CollectiveName != "" {
+			authors = append(authors, finc.Author{Corporation: a.CollectiveName})
+			continue
+		}
+		authors = append(authors, finc.Author{
+			LastName:  a.LastName,
+			FirstName: a.ForeName,
+			Initial:   a.Initials,
+		})
+	}
+	return authors
+}
+
+// pageRange splits a MEDLINE page specification, which abbreviates the last
+// page, "123-9" means pages 123 to 129.
+func pageRange(s string) (start, end string) {
+	parts := strings.SplitN(s, "-", 2)
+	start = strings.TrimSpace(parts[0])
+	if len(parts) < 2 {
+		return start, ""
+	}
+	end = strings.TrimSpace(parts[1])
+	if _, err := strconv.Atoi(start); err != nil {
+		return start, end
+	}
+	if _, err := strconv.Atoi(end); err != nil {
+		return start, end
+	}
+	if len(end) < len(start) {
+		end = start[:len(start)-len(end)] + end
+	}
+	return start, end
+}
+
+// MeshHeadings returns the distinct MeSH descriptor names in document order.
+func (doc *Document) MeshHeadings() (headings []string) {
+	seen := container.NewStringSet()
+	for _, h := range doc.MedlineCitation.MeshHeadingList.MeshHeading {
+		name := strings.TrimSpace(h.DescriptorName)
+		if name == "" || seen.Contains(name) {
+			continue
+		}
+		seen.Add(name)
+		headings = append(headings, name)
+	}
+	return headings
+}
+
+// ToIntermediateSchema converts a PubMed article. Records without a usable
+// date are skipped.
+func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	output.Date, err = doc.Date()
+	if err != nil {
+		return output, span.Skip{Reason: fmt.Sprintf("PMID %s: %s", doc.PMID(), err)}
+	}
+
+	article := doc.MedlineCitation.Article
+
+	output.SourceID = SourceID
+	output.RecordID = doc.RecordID()
+	output.MegaCollection = Collection
+	output.Format = Format
+	output.Genre = "article"
+	output.RefType = "JOUR"
+
+	output.DOI = doc.DOI()
+	output.URL = append(output.URL, doc.URL())
+	output.ISSN, output.EISSN = doc.ISSNs()
+
+	output.ArticleTitle = strings.TrimSpace(article.ArticleTitle)
+	output.JournalTitle = strings.TrimSpace(article.Journal.Title)
+	output.Volume = article.Journal.JournalIssue.Volume
+	output.Issue = article.Journal.JournalIssue.Issue
+	output.Abstract = doc.Abstract()
+	output.Authors = doc.Authors()
+	output.Languages = article.Language
+
+	if pages := strings.TrimSpace(article.Pagination.MedlinePgn); pages != "" {
+		output.Pages = pages
+		output.StartPage, output.EndPage = pageRange(pages)
+	}
+
+	// MeSH headings end up in the topic field of the export.
+	output.Subjects = doc.MeshHeadings()
+
+	return output, nil
+}
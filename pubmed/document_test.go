@@ -0,0 +1,137 @@
+package pubmed
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/internal/spantest"
+)
+
+const example = `<?xml version="1.0" encoding="utf-8"?>
+<PubmedArticleSet>
+<PubmedArticle>
+	<MedlineCitation Status="MEDLINE" Owner="NLM">
+		<PMID Version="1">10000001</PMID>
+		<Article PubModel="Print">
+			<Journal>
+				<ISSN IssnType="Print">0000-0019</ISSN>
+				<JournalIssue CitedMedium="Print">
+					<Volume>12</Volume>
+					<Issue>3</Issue>
+					<PubDate><Year>1998</Year><Month>Dec</Month></PubDate>
+				</JournalIssue>
+				<Title>Journal of Examples</Title>
+			</Journal>
+			<ArticleTitle>On examples.</ArticleTitle>
+			<Pagination><MedlinePgn>123-9</MedlinePgn></Pagination>
+			<Abstract>
+				<AbstractText Label="BACKGROUND">Some background.</AbstractText>
+				<AbstractText Label="RESULTS">Some results.</AbstractText>
+			</Abstract>
+			<AuthorList>
+				<Author><LastName>Doe</LastName><ForeName>Jane</ForeName><Initials>J</Initials></Author>
+				<Author><CollectiveName>Example Study Group</CollectiveName></Author>
+			</AuthorList>
+			<Language>eng</Language>
+		</Article>
+		<MeshHeadingList>
+			<MeshHeading><DescriptorName UI="D006801" MajorTopicYN="N">Humans</DescriptorName></MeshHeading>
+			<MeshHeading><DescriptorName UI="D001769" MajorTopicYN="Y">Blood</DescriptorName><QualifierName UI="Q000097">blood</QualifierName></MeshHeading>
+			<MeshHeading><DescriptorName UI="D006801" MajorTopicYN="N">Humans</DescriptorName></MeshHeading>
+		</MeshHeadingList>
+	</MedlineCitation>
+	<PubmedData>
+		<ArticleIdList>
+			<ArticleId IdType="pubmed">10000001</ArticleId>
+			<ArticleId IdType="doi">10.1000/example</ArticleId>
+		</ArticleIdList>
+	</PubmedData>
+</PubmedArticle>
+<PubmedArticle>
+	<MedlineCitation>
+		<PMID Version="1">10000002</PMID>
+		<Article>
+			<Journal>
+				<ISSN IssnType="Electronic">0000-0027</ISSN>
+				<JournalIssue><PubDate><MedlineDate>1998 Dec-1999 Jan</MedlineDate></PubDate></JournalIssue>
+			</Journal>
+		</Article>
+	</MedlineCitation>
+</PubmedArticle>
+<PubmedArticle>
+	<MedlineCitation>
+		<PMID Version="1">10000003</PMID>
+		<Article><Journal><JournalIssue><PubDate><MedlineDate>Spring</MedlineDate></PubDate></JournalIssue></Journal></Article>
+	</MedlineCitation>
+</PubmedArticle>
+</PubmedArticleSet>`
+
+func TestIterate(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, PubMed{}, strings.NewReader(example))
+	if len(results) != 3 {
+		t.Fatalf("got %d records, want 3", len(results))
+	}
+	if errs[0] != nil || errs[1] != nil {
+		t.Fatalf("got %v, %v, want no errors", errs[0], errs[1])
+	}
+	if _, ok := errs[2].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for a record without a usable date", errs[2])
+	}
+}
+
+func TestToIntermediateSchema(t *testing.T) {
+	results, _ := spantest.ConvertAll(t, PubMed{}, strings.NewReader(example))
+	is := results[0]
+	if is.DOI != "10.1000/example" {
+		t.Errorf("DOI: got %s, want 10.1000/example", is.DOI)
+	}
+	if is.Date.Format("2006-01-02") != "1998-12-01" {
+		t.Errorf("Date: got %s, want 1998-12-01", is.Date.Format("2006-01-02"))
+	}
+	if !reflect.DeepEqual(is.ISSN, []string{"0000-0019"}) {
+		t.Errorf("ISSN: got %v", is.ISSN)
+	}
+	if !reflect.DeepEqual(is.Subjects, []string{"Humans", "Blood"}) {
+		t.Errorf("Subjects: got %v, want MeSH descriptors [Humans Blood]", is.Subjects)
+	}
+	if len(is.Authors) != 2 || is.Authors[0].LastName != "Doe" || is.Authors[1].Corporation != "Example Study Group" {
+		t.Errorf("Authors: got %v", is.Authors)
+	}
+	if is.Abstract != "BACKGROUND: Some background.\nRESULTS: Some results." {
+		t.Errorf("Abstract: got %q", is.Abstract)
+	}
+	if is.StartPage != "123" || is.EndPage != "129" {
+		t.Errorf("Pages: got %s-%s, want 123-129", is.StartPage, is.EndPage)
+	}
+	if is.SourceID != SourceID || !strings.HasPrefix(is.RecordID, "ai-"+SourceID+"-") {
+		t.Errorf("got source %s, record %s", is.SourceID, is.RecordID)
+	}
+
+	is = results[1]
+	if is.Date.Year() != 1998 {
+		t.Errorf("MedlineDate: got %v, want 1998", is.Date)
+	}
+	if !reflect.DeepEqual(is.EISSN, []string{"0000-0027"}) {
+		t.Errorf("EISSN: got %v", is.EISSN)
+	}
+}
+
+func TestPageRange(t *testing.T) {
+	var cases = []struct {
+		s, start, end string
+	}{
+		{"123-9", "123", "129"},
+		{"123-45", "123", "145"},
+		{"12-345", "12", "345"},
+		{"e123", "e123", ""},
+		{"S12-S19", "S12", "S19"},
+	}
+	for _, c := range cases {
+		start, end := pageRange(c.s)
+		if start != c.start || end != c.end {
+			t.Errorf("pageRange(%q): got %s, %s, want %s, %s", c.s, start, end, c.start, c.end)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package span
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// RawRecord is a converted record together with the original input. With
+// json.RawMessage fields, decoding keeps Raw byte for byte.
+type RawRecord struct {
+	Schema json.RawMessage `json:"schema"`
+	Raw    json.RawMessage `json:"raw"`
+}
+
+// EmbedRaw nests an encoded record and the original input into a single JSON
+// object, {"schema": {...}, "raw": {...}}. The input is embedded unchanged,
+// except for surrounding whitespace, if it is valid JSON, e.g. a line of a
+// crossref dump, otherwise as a JSON string, e.g. for XML. The output is
+// about as large as input and record combined.
+func EmbedRaw(schema, raw []byte) ([]byte, error) {
+	raw = bytes.TrimSpace(raw)
+	if !json.Valid(raw) {
+		var sbuf bytes.Buffer
+		enc := json.NewEncoder(&sbuf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(string(raw)); err != nil {
+			return nil, err
+		}
+		raw = bytes.TrimSpace(sbuf.Bytes())
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(schema) + len(raw) + 20)
+	buf.WriteString(`{"schema":`)
+	buf.Write(schema)
+	buf.WriteString(`,"raw":`)
+	buf.Write(raw)
+	buf.WriteString(`}`)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,33 @@
+package span
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEmbedRaw(t *testing.T) {
+	var tests = []struct {
+		raw  string
+		want string
+	}{
+		{`{"DOI": "10.1000/1",  "title": ["Über Ränder"], "x": 1.50}`, `{"DOI": "10.1000/1",  "title": ["Über Ränder"], "x": 1.50}`},
+		{"{\"DOI\": \"10.1000/1\"}\n", `{"DOI": "10.1000/1"}`},
+		{`<article><title>A</title></article>`, `"<article><title>A</title></article>"`},
+	}
+	for _, tt := range tests {
+		b, err := EmbedRaw([]byte(`{"finc.record_id":"ai-49-1"}`), []byte(tt.raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r RawRecord
+		if err := json.Unmarshal(b, &r); err != nil {
+			t.Fatalf("EmbedRaw: invalid JSON %s: %s", b, err)
+		}
+		if string(r.Raw) != tt.want {
+			t.Errorf("EmbedRaw: got raw %s, want %s", r.Raw, tt.want)
+		}
+		if string(r.Schema) != `{"finc.record_id":"ai-49-1"}` {
+			t.Errorf("EmbedRaw: got schema %s", r.Schema)
+		}
+	}
+}
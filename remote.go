@@ -0,0 +1,164 @@
+package span
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miku/span/finc"
+)
+
+// Prefetcher is implemented by taggers, that can look up the tags of many
+// records at once, before Tags is called for each record.
+type Prefetcher interface {
+	Prefetch([]finc.IntermediateSchema) error
+}
+
+// RemoteTagger asks an external service for the ISILs of a record, instead
+// of evaluating local filters. The decision of the service must only depend
+// on the ISSNs of a record, since answers are cached per ISSN set.
+//
+// The service receives a JSON POST request with one ISSN list per record,
+// {"issns": [["1234-5678"], ["2345-6789", "3456-789X"]]}, and responds with
+// one ISIL list per ISSN list, in the same order, {"isils": [["DE-15"], []]}.
+type RemoteTagger struct {
+	Endpoint string
+	Client   *http.Client
+	// Retries is the number of additional attempts after a failed request.
+	Retries int
+	// Backoff is the wait time before the first retry, it doubles with every
+	// further retry.
+	Backoff time.Duration
+
+	mu    sync.Mutex
+	cache map[string][]string
+}
+
+// NewRemoteTagger creates a tagger for an endpoint with a request timeout.
+func NewRemoteTagger(endpoint string, timeout time.Duration, retries int) *RemoteTagger {
+	return &RemoteTagger{
+		Endpoint: endpoint,
+		Client:   &http.Client{Timeout: timeout},
+		Retries:  retries,
+		Backoff:  500 * time.Millisecond,
+		cache:    make(map[string][]string),
+	}
+}
+
+type remoteRequest struct {
+	ISSNs [][]string `json:"issns"`
+}
+
+type remoteResponse struct {
+	ISILs [][]string `json:"isils"`
+}
+
+// issnSet returns the sorted, deduplicated ISSNs of a record and the cache
+// key derived from them.
+func issnSet(is finc.IntermediateSchema) ([]string, string) {
+	issns := is.ISSNList()
+	if issns == nil {
+		issns = []string{}
+	}
+	sort.Strings(issns)
+	return issns, strings.Join(issns, ",")
+}
+
+// Prefetch looks up all records, whose ISSN set is not cached yet, with a
+// single request.
+func (t *RemoteTagger) Prefetch(records []finc.IntermediateSchema) error {
+	var (
+		req  remoteRequest
+		keys []string
+		seen = make(map[string]bool)
+	)
+	t.mu.Lock()
+	for _, is := range records {
+		issns, key := issnSet(is)
+		if _, ok := t.cache[key]; ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+		req.ISSNs = append(req.ISSNs, issns)
+	}
+	t.mu.Unlock()
+	if len(keys) == 0 {
+		return nil
+	}
+	resp, err := t.fetch(req)
+	if err != nil {
+		return err
+	}
+	if len(resp.ISILs) != len(keys) {
+		return fmt.Errorf("remote tagger: got %d results for %d requests", len(resp.ISILs), len(keys))
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, key := range keys {
+		t.cache[key] = resp.ISILs[i]
+	}
+	return nil
+}
+
+// fetch sends a request, retrying on network errors and server errors.
+func (t *RemoteTagger) fetch(req remoteRequest) (remoteResponse, error) {
+	var resp remoteResponse
+	b, err := json.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+	backoff := t.Backoff
+	for i := 0; ; i++ {
+		err = t.post(b, &resp)
+		if err == nil || i >= t.Retries {
+			return resp, err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (t *RemoteTagger) post(b []byte, v interface{}) error {
+	resp, err := t.Client.Post(t.Endpoint, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("remote tagger: %s returned %s", t.Endpoint, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Tags returns the ISILs for a record, from the cache or the service. Since
+// a record would silently lose its ISILs otherwise, Tags exits the program,
+// if the service cannot be reached; call Prefetch to handle errors.
+func (t *RemoteTagger) Tags(is finc.IntermediateSchema) []string {
+	_, key := issnSet(is)
+	t.mu.Lock()
+	isils, ok := t.cache[key]
+	t.mu.Unlock()
+	if ok {
+		return isils
+	}
+	if err := t.Prefetch([]finc.IntermediateSchema{is}); err != nil {
+		log.Fatal(err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cache[key]
+}
+
+// CacheSize returns the number of cached ISSN sets.
+func (t *RemoteTagger) CacheSize() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.cache)
+}
@@ -0,0 +1,87 @@
+package span
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miku/span/finc"
+)
+
+// fakeRules attaches DE-15 to records with ISSN 1234-5678 and counts requests.
+func fakeRules(requests *int64, failures int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(requests, 1) <= failures {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var req remoteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := remoteResponse{ISILs: make([][]string, len(req.ISSNs))}
+		for i, issns := range req.ISSNs {
+			resp.ISILs[i] = []string{}
+			for _, issn := range issns {
+				if issn == "1234-5678" {
+					resp.ISILs[i] = []string{"DE-15"}
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestRemoteTagger(t *testing.T) {
+	var requests int64
+	server := fakeRules(&requests, 0)
+	defer server.Close()
+
+	tagger := NewRemoteTagger(server.URL, time.Second, 0)
+	records := []finc.IntermediateSchema{
+		{ISSN: []string{"1234-5678"}},
+		{EISSN: []string{"1234-5678"}},
+		{ISSN: []string{"2345-6789"}},
+		{},
+	}
+	if err := tagger.Prefetch(records); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"DE-15"}, {"DE-15"}, {}, {}}
+	for i, is := range records {
+		if isils := tagger.Tags(is); !reflect.DeepEqual(isils, want[i]) {
+			t.Errorf("RemoteTagger.Tags(%d): got %v, want %v", i, isils, want[i])
+		}
+	}
+	if n := atomic.LoadInt64(&requests); n != 1 {
+		t.Errorf("RemoteTagger requests: got %d, want 1", n)
+	}
+	if tagger.CacheSize() != 3 {
+		t.Errorf("RemoteTagger.CacheSize: got %d, want 3", tagger.CacheSize())
+	}
+}
+
+func TestRemoteTaggerRetry(t *testing.T) {
+	var requests int64
+	server := fakeRules(&requests, 2)
+	defer server.Close()
+
+	tagger := NewRemoteTagger(server.URL, time.Second, 1)
+	tagger.Backoff = time.Millisecond
+	records := []finc.IntermediateSchema{{ISSN: []string{"1234-5678"}}}
+	if err := tagger.Prefetch(records); err == nil {
+		t.Errorf("RemoteTagger.Prefetch: got nil, want error after exhausted retries")
+	}
+	tagger.Retries = 2
+	if err := tagger.Prefetch(records); err != nil {
+		t.Errorf("RemoteTagger.Prefetch: got %v, want nil", err)
+	}
+	if isils := tagger.Tags(records[0]); !reflect.DeepEqual(isils, []string{"DE-15"}) {
+		t.Errorf("RemoteTagger.Tags: got %v, want [DE-15]", isils)
+	}
+}
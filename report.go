@@ -0,0 +1,231 @@
+package span
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/miku/span/container"
+	"github.com/miku/span/finc"
+)
+
+// ISSNReport counts the records per ISSN (print and electronic) in a
+// corpus. Safe for concurrent use.
+type ISSNReport struct {
+	counter container.StringCounter
+}
+
+// NewISSNReport creates an empty report.
+func NewISSNReport() *ISSNReport {
+	return &ISSNReport{counter: container.NewStringCounter()}
+}
+
+// Add counts the ISSNs of a record, each ISSN at most once per record. ISSNs
+// are normalized, values, that are no ISSN, are counted as they are.
+func (r *ISSNReport) Add(is finc.IntermediateSchema) {
+	seen := container.NewStringSet()
+	for _, issn := range is.ISSNList() {
+		if v := NormalizeISSN(issn); v != "" {
+			issn = v
+		}
+		if seen.Contains(issn) {
+			continue
+		}
+		seen.Add(issn)
+		r.counter.Inc(issn)
+	}
+}
+
+// WriteTo writes ISSN and count tab separated, most frequent first, ties
+// are sorted by ISSN.
+func (r *ISSNReport) WriteTo(w io.Writer) (int64, error) {
+	counts := r.counter.Counts()
+	var issns []string
+	for issn := range counts {
+		issns = append(issns, issn)
+	}
+	sort.Slice(issns, func(i, j int) bool {
+		if counts[issns[i]] != counts[issns[j]] {
+			return counts[issns[i]] > counts[issns[j]]
+		}
+		return issns[i] < issns[j]
+	})
+	bw := bufio.NewWriter(w)
+	var written int64
+	for _, issn := range issns {
+		n, err := fmt.Fprintf(bw, "%s\t%d\n", issn, counts[issn])
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, bw.Flush()
+}
+
+// MaxSchemaExamples is the number of example record ids kept per undeclared
+// field.
+const MaxSchemaExamples = 5
+
+// SchemaCheck counts fields of exported JSON documents, that are not declared
+// in a SOLR schema, and keeps a few example ids. Safe for concurrent use.
+type SchemaCheck struct {
+	Schema finc.SolrSchema
+
+	mu       sync.Mutex
+	counts   map[string]int
+	examples map[string][]string
+}
+
+// NewSchemaCheck creates a check against a schema.
+func NewSchemaCheck(schema finc.SolrSchema) *SchemaCheck {
+	return &SchemaCheck{Schema: schema, counts: make(map[string]int), examples: make(map[string][]string)}
+}
+
+// Check records the undeclared fields of a JSON document.
+func (c *SchemaCheck) Check(b []byte) error {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	var id string
+	json.Unmarshal(doc["id"], &id)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name := range doc {
+		if c.Schema.Declared(name) {
+			continue
+		}
+		c.counts[name]++
+		if len(c.examples[name]) < MaxSchemaExamples {
+			c.examples[name] = append(c.examples[name], id)
+		}
+	}
+	return nil
+}
+
+// Undeclared returns the number of documents per undeclared field.
+func (c *SchemaCheck) Undeclared() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := make(map[string]int)
+	for k, v := range c.counts {
+		counts[k] = v
+	}
+	return counts
+}
+
+// WriteTo writes field, number of documents and example ids tab separated,
+// sorted by field.
+func (c *SchemaCheck) WriteTo(w io.Writer) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var names []string
+	for name := range c.counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	bw := bufio.NewWriter(w)
+	var written int64
+	for _, name := range names {
+		n, err := fmt.Fprintf(bw, "%s\t%d\t%s\n", name, c.counts[name], strings.Join(c.examples[name], ", "))
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, bw.Flush()
+}
+
+// CoverageCell holds the number of records of a source and publication year,
+// that were attached to an ISIL or not.
+type CoverageCell struct {
+	SourceID   string `json:"source_id"`
+	Year       int    `json:"year"`
+	Covered    int    `json:"covered"`
+	NotCovered int    `json:"not_covered"`
+}
+
+// CoverageReport counts covered and uncovered records per source and year for
+// a single ISIL, based on the tags of a record. Safe for concurrent use.
+type CoverageReport struct {
+	ISIL string
+
+	mu    sync.Mutex
+	cells map[coverageKey]*CoverageCell
+}
+
+type coverageKey struct {
+	sourceID string
+	year     int
+}
+
+// NewCoverageReport creates an empty report for an ISIL.
+func NewCoverageReport(isil string) *CoverageReport {
+	return &CoverageReport{ISIL: isil, cells: make(map[coverageKey]*CoverageCell)}
+}
+
+// Add counts a record, which is covered, if isils contains the ISIL of the report.
+func (r *CoverageReport) Add(is finc.IntermediateSchema, isils []string) {
+	k := coverageKey{sourceID: is.SourceID, year: is.Date.Year()}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cell, ok := r.cells[k]
+	if !ok {
+		cell = &CoverageCell{SourceID: k.sourceID, Year: k.year}
+		r.cells[k] = cell
+	}
+	for _, isil := range isils {
+		if isil == r.ISIL {
+			cell.Covered++
+			return
+		}
+	}
+	cell.NotCovered++
+}
+
+// Cells returns a copy of all cells, sorted by source and year.
+func (r *CoverageReport) Cells() []CoverageCell {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var cells []CoverageCell
+	for _, cell := range r.cells {
+		cells = append(cells, *cell)
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].SourceID != cells[j].SourceID {
+			return cells[i].SourceID < cells[j].SourceID
+		}
+		return cells[i].Year < cells[j].Year
+	})
+	return cells
+}
+
+// WriteTo writes source, year, covered and not covered counts tab separated,
+// with a header, sorted by source and year.
+func (r *CoverageReport) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	written, err := fmt.Fprintf(bw, "source_id\tyear\tcovered\tnot_covered\n")
+	if err != nil {
+		return int64(written), err
+	}
+	for _, cell := range r.Cells() {
+		n, err := fmt.Fprintf(bw, "%s\t%d\t%d\t%d\n", cell.SourceID, cell.Year, cell.Covered, cell.NotCovered)
+		written += n
+		if err != nil {
+			return int64(written), err
+		}
+	}
+	return int64(written), bw.Flush()
+}
+
+// MarshalJSON encodes the ISIL and the sorted cells.
+func (r *CoverageReport) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ISIL  string         `json:"isil"`
+		Cells []CoverageCell `json:"cells"`
+	}{r.ISIL, r.Cells()})
+}
@@ -0,0 +1,100 @@
+package span
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span/finc"
+)
+
+func TestISSNReport(t *testing.T) {
+	report := NewISSNReport()
+	for _, is := range []finc.IntermediateSchema{
+		{ISSN: []string{"1234-5678"}, EISSN: []string{"2345-6789"}},
+		{ISSN: []string{"1234-5678"}, EISSN: []string{"1234-5678"}},
+		{EISSN: []string{"2345-6789"}},
+		{ISSN: []string{"1234-5678", "0000-0000"}},
+		{ISSN: []string{"12345678"}, EISSN: []string{"1234-5678"}},
+		{EISSN: []string{"2345678x"}, ISSN: []string{"n/a"}},
+		{},
+	} {
+		report.Add(is)
+	}
+	var buf bytes.Buffer
+	if _, err := report.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "1234-5678\t4\n2345-6789\t2\n0000-0000\t1\n2345-678X\t1\nn/a\t1\n"
+	if buf.String() != want {
+		t.Errorf("ISSNReport.WriteTo: got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestSchemaCheck(t *testing.T) {
+	schema := `<?xml version="1.0" encoding="UTF-8" ?>
+<schema name="test" version="1.5">
+  <fields>
+    <field name="id" type="string" indexed="true" stored="true" required="true"/>
+    <field name="title" type="text" indexed="true" stored="true"/>
+    <dynamicField name="*_facet" type="string" indexed="true" stored="false"/>
+  </fields>
+</schema>`
+	s, err := finc.ReadSolrSchema(strings.NewReader(schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewSchemaCheck(s)
+	for _, doc := range []string{
+		`{"id": "ai-1", "title": "A", "author_facet": ["X"], "allfields": "A X"}`,
+		`{"id": "ai-2", "title": "B", "allfields": "B"}`,
+		`{"id": "ai-3", "title": "C"}`,
+	} {
+		if err := c.Check([]byte(doc)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := map[string]int{"allfields": 2}; !reflect.DeepEqual(c.Undeclared(), want) {
+		t.Errorf("SchemaCheck.Undeclared: got %v, want %v", c.Undeclared(), want)
+	}
+	var buf bytes.Buffer
+	if _, err := c.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "allfields\t2\tai-1, ai-2\n"; buf.String() != want {
+		t.Errorf("SchemaCheck.WriteTo: got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestCoverageReport(t *testing.T) {
+	report := NewCoverageReport("DE-15")
+	for _, r := range []struct {
+		is    finc.IntermediateSchema
+		isils []string
+	}{
+		{finc.IntermediateSchema{SourceID: "49", Date: mustParseDate("2001-01-01")}, []string{"DE-14", "DE-15"}},
+		{finc.IntermediateSchema{SourceID: "49", Date: mustParseDate("2001-06-01")}, []string{"DE-14"}},
+		{finc.IntermediateSchema{SourceID: "49", Date: mustParseDate("2001-12-31")}, nil},
+		{finc.IntermediateSchema{SourceID: "49", Date: mustParseDate("1999-01-01")}, []string{"DE-15"}},
+		{finc.IntermediateSchema{SourceID: "28", Date: mustParseDate("2001-01-01")}, []string{"DE-15"}},
+	} {
+		report.Add(r.is, r.isils)
+	}
+	want := []CoverageCell{
+		{SourceID: "28", Year: 2001, Covered: 1},
+		{SourceID: "49", Year: 1999, Covered: 1},
+		{SourceID: "49", Year: 2001, Covered: 1, NotCovered: 2},
+	}
+	if !reflect.DeepEqual(report.Cells(), want) {
+		t.Errorf("CoverageReport.Cells: got %v, want %v", report.Cells(), want)
+	}
+	var buf bytes.Buffer
+	if _, err := report.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	tsv := "source_id\tyear\tcovered\tnot_covered\n28\t2001\t1\t0\n49\t1999\t1\t0\n49\t2001\t1\t2\n"
+	if buf.String() != tsv {
+		t.Errorf("CoverageReport.WriteTo: got %q, want %q", buf.String(), tsv)
+	}
+}
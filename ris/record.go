@@ -0,0 +1,265 @@
+// Package ris converts RIS tagged records into the intermediate schema.
+package ris
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/assetutil"
+	"github.com/miku/span/container"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// DefaultSourceID is used, if no source id is configured.
+	DefaultSourceID = "147"
+	// DefaultCollection is used, if no collection is configured.
+	DefaultCollection = "RIS"
+	// DefaultFormat for reference types without a mapping.
+	DefaultFormat = "ElectronicArticle"
+	// BatchSize number of records per batch.
+	BatchSize = 2000
+)
+
+var (
+	Formats = assetutil.MustLoadStringMap("assets/ris/formats.json")
+	Genres  = assetutil.MustLoadStringMap("assets/ris/genres.json")
+
+	// UnmappedTypes counts reference types without a format mapping.
+	UnmappedTypes = container.NewStringCounter()
+
+	// tagPattern matches a tagged line, e.g. "TY  - JOUR". Some exports drop
+	// the trailing space after the dash for empty values, e.g. "ER  -".
+	tagPattern  = regexp.MustCompile(`^([A-Z][A-Z0-9])  -( (.*))?$`)
+	issnPattern = regexp.MustCompile(`^[0-9]{4}-?[0-9]{3}[0-9xX]$`)
+	datePattern = regexp.MustCompile(`^([12][0-9]{3})(/([0-9]{1,2})?(/([0-9]{1,2})?)?)?`)
+)
+
+// RIS source. Source id and collection depend on the provider, empty values
+// fall back to the defaults.
+type RIS struct {
+	SourceID   string
+	Collection string
+}
+
+// Record maps tags to their values, in input order. Raw keeps the record
+// text, it is used to derive a stable id for records without identifier.
+type Record struct {
+	Fields map[string][]string
+	Raw    string
+}
+
+// Get returns the first value of the first tag, that has a value.
+func (r *Record) Get(tags ...string) string {
+	for _, tag := range tags {
+		if vs := r.Fields[tag]; len(vs) > 0 {
+			return vs[0]
+		}
+	}
+	return ""
+}
+
+// All returns all values of the given tags.
+func (r *Record) All(tags ...string) (values []string) {
+	for _, tag := range tags {
+		values = append(values, r.Fields[tag]...)
+	}
+	return values
+}
+
+// Decode reads records delimited by TY and ER and calls f for each of them.
+// Untagged lines continue the value of the previous tag.
+func Decode(r io.Reader, f func(*Record)) error {
+	var (
+		scanner = bufio.NewScanner(r)
+		record  *Record
+		last    string
+		raw     strings.Builder
+	)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(strings.TrimPrefix(scanner.Text(), "\ufeff"), "\r")
+		m := tagPattern.FindStringSubmatch(line)
+		if m == nil {
+			if record != nil && last != "" && strings.TrimSpace(line) != "" {
+				vs := record.Fields[last]
+				vs[len(vs)-1] = strings.TrimSpace(vs[len(vs)-1] + " " + strings.TrimSpace(line))
+				raw.WriteString(line + "\n")
+			}
+			continue
+		}
+		tag, value := m[1], strings.TrimSpace(m[3])
+		switch {
+		case tag == "TY":
+			record = &Record{Fields: map[string][]string{"TY": {value}}}
+			raw.Reset()
+			raw.WriteString(line + "\n")
+			last = tag
+		case record == nil:
+			continue
+		case tag == "ER":
+			record.Raw = raw.String()
+			f(record)
+			record, last = nil, ""
+		default:
+			record.Fields[tag] = append(record.Fields[tag], value)
+			raw.WriteString(line + "\n")
+			last = tag
+		}
+	}
+	return scanner.Err()
+}
+
+// Document is a record together with the source settings.
+type Document struct {
+	*Record
+	source RIS
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(docs []*Document) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			return s.(span.Importer), nil
+		}, Items: make([]interface{}, len(docs))}
+	for i, doc := range docs {
+		batch.Items[i] = doc
+	}
+	return batch
+}
+
+// Iterate emits Converter elements.
+func (s RIS) Iterate(r io.Reader) (<-chan interface{}, error) {
+	if s.SourceID == "" {
+		s.SourceID = DefaultSourceID
+	}
+	if s.Collection == "" {
+		s.Collection = DefaultCollection
+	}
+	ch := make(chan interface{})
+	var docs []*Document
+	go func() {
+		err := Decode(r, func(record *Record) {
+			docs = append(docs, &Document{Record: record, source: s})
+			if len(docs) == BatchSize {
+				ch <- NewBatch(docs)
+				docs = nil
+			}
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		ch <- NewBatch(docs)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Format returns the finc format for a reference type. Types without mapping
+// fall back to DefaultFormat and are counted in UnmappedTypes.
+func Format(typ string) string {
+	format, ok := Formats[typ]
+	if !ok {
+		UnmappedTypes.Inc(typ)
+		return DefaultFormat
+	}
+	return format
+}
+
+// Date parses PY, Y1 or DA values like 2015, 2015/05 or 2015/05/01/.
+func (r *Record) Date() (time.Time, error) {
+	for _, v := range r.All("PY", "Y1", "DA") {
+		m := datePattern.FindStringSubmatch(strings.TrimSpace(v))
+		if m == nil {
+			continue
+		}
+		s, layout := m[1], "2006"
+		if m[3] != "" {
+			s, layout = s+"-"+m[3], layout+"-1"
+			if m[5] != "" {
+				s, layout = s+"-"+m[5], layout+"-2"
+			}
+		}
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("ris: no usable date")
+}
+
+// Identifiers splits SN values into ISSNs and ISBNs.
+func (r *Record) Identifiers() (issns, isbns []string) {
+	for _, v := range r.All("SN") {
+		for _, s := range strings.FieldsFunc(v, func(r rune) bool { return r == ';' || r == ',' || r == ' ' }) {
+			if issnPattern.MatchString(s) {
+				issns = append(issns, s)
+			} else if s != "" {
+				isbns = append(isbns, s)
+			}
+		}
+	}
+	return issns, isbns
+}
+
+// ID returns a primary key for the record: the ID tag, the DOI or the URL.
+// Records without any of them get a hash of their text.
+func (r *Record) ID() string {
+	if id := r.Get("ID", "DO", "UR"); id != "" {
+		return id
+	}
+	return fmt.Sprintf("%x", sha1.Sum([]byte(r.Raw)))
+}
+
+// ToIntermediateSchema converts a record. Records without a usable date are
+// skipped.
+func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	output.Date, err = doc.Date()
+	if err != nil {
+		return output, span.Skip{Reason: fmt.Sprintf("%s: %s", doc.ID(), err)}
+	}
+
+	typ := doc.Get("TY")
+	enc := fmt.Sprintf("ai-%s-%s", doc.source.SourceID, base64.URLEncoding.EncodeToString([]byte(doc.ID())))
+
+	output.SourceID = doc.source.SourceID
+	output.RecordID = strings.TrimRight(enc, "=")
+	output.MegaCollection = doc.source.Collection
+	output.Format = Format(typ)
+	output.Genre = Genres.LookupDefault(typ, "unknown")
+	output.RefType = typ
+
+	output.ArticleTitle = doc.Get("TI", "T1")
+	output.JournalTitle = doc.Get("JO", "JF", "T2", "JA", "J2")
+	for _, name := range doc.All("AU", "A1") {
+		output.Authors = append(output.Authors, finc.Author{Name: name})
+	}
+	output.ISSN, output.ISBN = doc.Identifiers()
+	output.Volume = doc.Get("VL")
+	output.Issue = doc.Get("IS")
+	output.StartPage = doc.Get("SP")
+	output.EndPage = doc.Get("EP")
+	if output.StartPage != "" && output.EndPage != "" {
+		output.Pages = output.StartPage + "-" + output.EndPage
+	}
+	output.DOI = doc.Get("DO")
+	output.URL = doc.All("UR")
+	output.Abstract = doc.Get("AB", "N2")
+	output.Publishers = doc.All("PB")
+	output.Places = doc.All("CY")
+	output.Subjects = doc.All("KW")
+	for _, lang := range doc.All("LA") {
+		output.Languages = append(output.Languages, span.NormalizeLanguage(lang))
+	}
+	return output, nil
+}
@@ -0,0 +1,86 @@
+package ris
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/internal/spantest"
+)
+
+const example = "\ufeffTY  - JOUR\r\n" + `AU  - Doe, Jane
+AU  - Roe, Richard
+TI  - On examples in a title,
+      which continues on the next line
+JO  - Journal of Examples
+SN  - 1234-5678
+VL  - 12
+IS  - 3
+SP  - 101
+EP  - 110
+PY  - 2015/05/01/
+DO  - 10.1000/example
+KW  - examples
+LA  - de
+ER  -
+
+TY  - BOOK
+TI  - A book without a date
+SN  - 978-3-16-148410-0
+ER  - 
+TY  - NONE
+TI  - Unusual type
+PY  - 2001
+ER  - 
+`
+
+func TestRIS(t *testing.T) {
+	results, errs := spantest.ConvertAll(t, RIS{}, strings.NewReader(example))
+	if len(results) != 3 {
+		t.Fatalf("got %d records, want 3", len(results))
+	}
+	if errs[0] != nil || errs[2] != nil {
+		t.Fatalf("got %v, %v, want no errors", errs[0], errs[2])
+	}
+	if _, ok := errs[1].(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip for a record without date", errs[1])
+	}
+
+	is := results[0]
+	if is.ArticleTitle != "On examples in a title, which continues on the next line" {
+		t.Errorf("got title %q", is.ArticleTitle)
+	}
+	if len(is.Authors) != 2 || is.Authors[1].Name != "Roe, Richard" {
+		t.Errorf("got authors %v", is.Authors)
+	}
+	if is.JournalTitle != "Journal of Examples" || !reflect.DeepEqual(is.ISSN, []string{"1234-5678"}) {
+		t.Errorf("got journal %q, ISSN %v", is.JournalTitle, is.ISSN)
+	}
+	if is.Date.Format("2006-01-02") != "2015-05-01" {
+		t.Errorf("got date %v", is.Date)
+	}
+	if is.Pages != "101-110" || is.Volume != "12" || is.Issue != "3" {
+		t.Errorf("got pages %s, volume %s, issue %s", is.Pages, is.Volume, is.Issue)
+	}
+	if is.Format != "ElectronicArticle" || is.Genre != "article" || is.RefType != "JOUR" {
+		t.Errorf("got %s, %s, %s", is.Format, is.Genre, is.RefType)
+	}
+	if !reflect.DeepEqual(is.Languages, []string{"deu"}) {
+		t.Errorf("got languages %v", is.Languages)
+	}
+	if results[2].Format != DefaultFormat || UnmappedTypes.Counts()["NONE"] != 1 {
+		t.Errorf("got %s for an unmapped type", results[2].Format)
+	}
+	if results[2].RecordID == "" {
+		t.Errorf("got empty record id for a record without identifiers")
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	r := &Record{Fields: map[string][]string{"SN": {"1234-567X; 9783161484100"}}}
+	issns, isbns := r.Identifiers()
+	if !reflect.DeepEqual(issns, []string{"1234-567X"}) || !reflect.DeepEqual(isbns, []string{"9783161484100"}) {
+		t.Errorf("got %v, %v", issns, isbns)
+	}
+}
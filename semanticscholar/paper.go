@@ -0,0 +1,248 @@
+// Package semanticscholar converts papers of the Semantic Scholar Academic
+// Graph (S2AG) papers dataset, one paper per line, into the intermediate
+// schema. Keys are matched case insensitively, so papers from the API, with
+// camel case keys, are read as well.
+package semanticscholar
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/container"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// SourceID for internal bookkeeping.
+	SourceID = "164"
+	// Collection name.
+	Collection = "Semantic Scholar"
+	// BatchSize for grouped channel transport.
+	BatchSize = 10000
+)
+
+// publicationTypes maps S2 publication types to format, genre and RIS type,
+// in order of preference.
+var publicationTypes = []struct {
+	Name string
+	Kind [3]string
+}{
+	{"Conference", [3]string{"ElectronicProceeding", "proceeding", "CPAPER"}},
+	{"Book", [3]string{"eBook", "book", "EBOOK"}},
+	{"BookSection", [3]string{"ElectronicBookPart", "bookitem", "ECHAP"}},
+	{"Dataset", [3]string{"ElectronicResourceRemoteAccess", "document", "DATA"}},
+	{"JournalArticle", [3]string{"ElectronicArticle", "article", "JOUR"}},
+	{"Review", [3]string{"ElectronicArticle", "article", "JOUR"}},
+	{"Editorial", [3]string{"ElectronicArticle", "article", "JOUR"}},
+	{"LettersAndComments", [3]string{"ElectronicArticle", "article", "JOUR"}},
+}
+
+// SemanticScholar source.
+type SemanticScholar struct{}
+
+// Paper is a single paper of the papers dataset.
+type Paper struct {
+	CorpusID    json.Number `json:"corpusid"`
+	PaperID     string      `json:"paperid"`
+	ExternalIDs struct {
+		DOI           string      `json:"doi"`
+		ArXiv         string      `json:"arxiv"`
+		PubMed        string      `json:"pubmed"`
+		PubMedCentral string      `json:"pubmedcentral"`
+		MAG           string      `json:"mag"`
+		DBLP          string      `json:"dblp"`
+		ACL           string      `json:"acl"`
+		CorpusID      json.Number `json:"corpusid"`
+	} `json:"externalids"`
+	URL     string `json:"url"`
+	Title   string `json:"title"`
+	Authors []struct {
+		AuthorID string `json:"authorid"`
+		Name     string `json:"name"`
+	} `json:"authors"`
+	Venue           string `json:"venue"`
+	Year            int    `json:"year"`
+	PublicationDate string `json:"publicationdate"`
+	IsOpenAccess    bool   `json:"isopenaccess"`
+	FieldsOfStudy   []struct {
+		Category string `json:"category"`
+		Source   string `json:"source"`
+	} `json:"s2fieldsofstudy"`
+	PublicationTypes []string `json:"publicationtypes"`
+	Journal          *struct {
+		Name   string `json:"name"`
+		Volume string `json:"volume"`
+		Pages  string `json:"pages"`
+	} `json:"journal"`
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(lines []string) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			paper := new(Paper)
+			err := json.Unmarshal([]byte(s.(string)), paper)
+			return paper, err
+		}, Items: make([]interface{}, len(lines))}
+	for i, line := range lines {
+		batch.Items[i] = line
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via JSON decoding.
+func (s SemanticScholar) Iterate(r io.Reader) (<-chan interface{}, error) {
+	ch := make(chan interface{})
+	reader := bufio.NewReader(r)
+	var lines []string
+	go func() {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil && err != io.EOF {
+				log.Fatal(err)
+			}
+			if strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+			if len(lines) == BatchSize {
+				ch <- NewBatch(lines)
+				lines = nil
+			}
+			if err == io.EOF {
+				break
+			}
+		}
+		ch <- NewBatch(lines)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// ID returns the corpus id, which is stable across releases, or the paper
+// id for API responses.
+func (p *Paper) ID() string {
+	if v := p.CorpusID.String(); v != "" {
+		return v
+	}
+	if v := p.ExternalIDs.CorpusID.String(); v != "" {
+		return v
+	}
+	return p.PaperID
+}
+
+// Date returns the publication date, or the first day of the year.
+func (p *Paper) Date() (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", p.PublicationDate); err == nil {
+		return t, nil
+	}
+	if p.Year > 0 {
+		return time.Date(p.Year, time.January, 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Time{}, fmt.Errorf("semanticscholar: %s: no usable date", p.ID())
+}
+
+// Subjects returns the distinct fields of study, in order.
+func (p *Paper) Subjects() (subjects []string) {
+	seen := container.NewStringSet()
+	for _, f := range p.FieldsOfStudy {
+		if f.Category != "" && seen.Add(f.Category) {
+			subjects = append(subjects, f.Category)
+		}
+	}
+	return subjects
+}
+
+// Links returns the S2 page and links derived from the external identifiers
+// for arXiv, PubMed and PubMed Central.
+func (p *Paper) Links() (links []string) {
+	if p.URL != "" {
+		links = append(links, p.URL)
+	}
+	x := p.ExternalIDs
+	if x.ArXiv != "" {
+		links = append(links, "https://arxiv.org/abs/"+x.ArXiv)
+	}
+	if x.PubMed != "" {
+		links = append(links, "https://pubmed.ncbi.nlm.nih.gov/"+x.PubMed+"/")
+	}
+	if x.PubMedCentral != "" {
+		links = append(links, "https://www.ncbi.nlm.nih.gov/pmc/articles/PMC"+strings.TrimPrefix(x.PubMedCentral, "PMC")+"/")
+	}
+	return links
+}
+
+// types returns format, genre and RIS type for the publication types.
+func (p *Paper) types() [3]string {
+	for _, t := range publicationTypes {
+		for _, name := range p.PublicationTypes {
+			if name == t.Name {
+				return t.Kind
+			}
+		}
+	}
+	if p.Journal != nil && p.Journal.Name != "" {
+		return [3]string{"ElectronicArticle", "article", "JOUR"}
+	}
+	return [3]string{"ElectronicResourceRemoteAccess", "unknown", "GEN"}
+}
+
+// ToIntermediateSchema converts a paper. Papers without id, title or date
+// are skipped.
+func (p *Paper) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	id := p.ID()
+	if id == "" || strings.TrimSpace(p.Title) == "" {
+		return output, span.Skip{Reason: fmt.Sprintf("semanticscholar: %q: missing id or title", id)}
+	}
+	output.Date, err = p.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(id)))
+	output.SourceID = SourceID
+	output.RecordID = strings.TrimRight(enc, "=")
+	output.MegaCollection = Collection
+	types := p.types()
+	output.Format, output.Genre, output.RefType = types[0], types[1], types[2]
+
+	output.DOI = p.ExternalIDs.DOI
+	output.ArticleTitle = strings.TrimSpace(p.Title)
+	for _, a := range p.Authors {
+		if a.Name != "" {
+			output.Authors = append(output.Authors, finc.Author{Name: a.Name})
+		}
+	}
+	output.Subjects = p.Subjects()
+	output.URL = p.Links()
+	output.OpenAccess = p.IsOpenAccess
+
+	var containerTitle string
+	if p.Journal != nil {
+		containerTitle = strings.TrimSpace(p.Journal.Name)
+		output.Volume = strings.TrimSpace(p.Journal.Volume)
+		output.Pages = strings.Join(strings.Fields(p.Journal.Pages), "")
+		if parts := strings.SplitN(output.Pages, "-", 2); len(parts) == 2 {
+			output.StartPage, output.EndPage = parts[0], parts[1]
+		}
+	}
+	if containerTitle == "" {
+		containerTitle = strings.TrimSpace(p.Venue)
+	}
+	switch output.Genre {
+	case "proceeding", "bookitem":
+		output.BookTitle = containerTitle
+	default:
+		output.JournalTitle = containerTitle
+	}
+	return output, nil
+}
@@ -0,0 +1,65 @@
+package semanticscholar
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/miku/span"
+)
+
+const example = `{"corpusid": 12345, "externalids": {"DOI": "10.1000/s2.1", "ArXiv": "2101.00001", "PubMed": "111", "PubMedCentral": "222", "CorpusId": "12345"},
+	"url": "https://www.semanticscholar.org/paper/abc", "title": "Graphs of papers",
+	"authors": [{"authorId": "1", "name": "Jane Doe"}], "venue": "J. Ex.", "year": 2020, "publicationdate": "2020-02-03",
+	"isopenaccess": true, "s2fieldsofstudy": [{"category": "Biology", "source": "external"}, {"category": "Biology", "source": "s2-fos-model"}, {"category": "Medicine", "source": "s2-fos-model"}],
+	"publicationtypes": ["JournalArticle", "Review"], "journal": {"name": "Journal of Examples", "volume": "3", "pages": " 10 - 20"}}`
+
+func decode(t *testing.T, line string) *Paper {
+	batch := NewBatch([]string{line})
+	doc, err := batch.Apply(batch.Items[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc.(*Paper)
+}
+
+func TestToIntermediateSchema(t *testing.T) {
+	is, err := decode(t, example).ToIntermediateSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if is.DOI != "10.1000/s2.1" || is.Format != "ElectronicArticle" || is.JournalTitle != "Journal of Examples" {
+		t.Errorf("got DOI %s, format %s, journal %q", is.DOI, is.Format, is.JournalTitle)
+	}
+	if !reflect.DeepEqual(is.Subjects, []string{"Biology", "Medicine"}) {
+		t.Errorf("got subjects %v", is.Subjects)
+	}
+	want := []string{
+		"https://www.semanticscholar.org/paper/abc",
+		"https://arxiv.org/abs/2101.00001",
+		"https://pubmed.ncbi.nlm.nih.gov/111/",
+		"https://www.ncbi.nlm.nih.gov/pmc/articles/PMC222/",
+	}
+	if !reflect.DeepEqual(is.URL, want) {
+		t.Errorf("got URL %v, want %v", is.URL, want)
+	}
+	if is.StartPage != "10" || is.EndPage != "20" || !is.OpenAccess || is.Date.Format("2006-01-02") != "2020-02-03" {
+		t.Errorf("got pages %s-%s, open access %v, date %v", is.StartPage, is.EndPage, is.OpenAccess, is.Date)
+	}
+}
+
+// Papers from the API use camel case keys and a paper id.
+func TestAPIPaper(t *testing.T) {
+	p := decode(t, `{"paperId": "abc", "title": "A talk", "year": 2018, "venue": "Example Conference", "publicationTypes": ["Conference"]}`)
+	is, err := p.ToIntermediateSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ID() != "abc" || is.Genre != "proceeding" || is.BookTitle != "Example Conference" || is.Date.Year() != 2018 {
+		t.Errorf("got id %s, genre %s, book title %q, date %v", p.ID(), is.Genre, is.BookTitle, is.Date)
+	}
+	if _, err := decode(t, `{"corpusid": 1, "title": "Undated"}`).ToIntermediateSchema(); err == nil {
+		t.Error("want error for an undated paper")
+	} else if _, ok := err.(span.Skip); !ok {
+		t.Errorf("got %v, want span.Skip", err)
+	}
+}
@@ -0,0 +1,132 @@
+package span
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrSortLimit is returned, if the input is too large to be sorted in memory.
+var ErrSortLimit = errors.New("input too large to sort in memory")
+
+// SortKeys are the supported sort keys of SortSink, mapped to the field of
+// the exported JSON document.
+var SortKeys = map[string]string{
+	"id":   "id",
+	"date": "publishDateSort",
+	"issn": "issn",
+}
+
+// DefaultSortMaxBytes is the default limit for sorting in memory.
+const DefaultSortMaxBytes = 100 << 20
+
+// SortSink keeps all JSON documents in memory and passes them, sorted by a
+// key, to the next sink on Flush. Ties are broken by id. To avoid running
+// out of memory, the sink fails with ErrSortLimit, once the documents exceed
+// MaxBytes.
+type SortSink struct {
+	Key      string
+	MaxBytes int64
+	Next     Sink
+
+	docs  []sortDoc
+	bytes int64
+	err   error
+}
+
+type sortDoc struct {
+	key  string
+	num  float64
+	id   string
+	blob []byte
+}
+
+// NewSortSink returns a sink sorting by key, which must be one of SortKeys.
+func NewSortSink(key string, maxBytes int64, next Sink) (*SortSink, error) {
+	if _, ok := SortKeys[key]; !ok {
+		return nil, fmt.Errorf("unknown sort key: %s", key)
+	}
+	return &SortSink{Key: key, MaxBytes: maxBytes, Next: next}, nil
+}
+
+// Guard returns ErrSortLimit, if an input of a given size cannot be sorted.
+func (s *SortSink) Guard(size int64) error {
+	if size > s.MaxBytes {
+		return fmt.Errorf("%w: %d bytes, limit is %d", ErrSortLimit, size, s.MaxBytes)
+	}
+	return nil
+}
+
+// add parses and keeps a single document.
+func (s *SortSink) add(b []byte) error {
+	s.bytes += int64(len(b))
+	if err := s.Guard(s.bytes); err != nil {
+		return err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	d := sortDoc{blob: b}
+	d.id, _ = doc["id"].(string)
+	switch v := doc[SortKeys[s.Key]].(type) {
+	case string:
+		d.key = v
+	case float64:
+		d.num = v
+	case []interface{}:
+		if len(v) > 0 {
+			d.key, _ = v[0].(string)
+		}
+	}
+	s.docs = append(s.docs, d)
+	return nil
+}
+
+// Run keeps all documents from out. It can be called repeatedly, documents
+// accumulate until Flush is called. After an error, the channel is still
+// drained, so producers do not block.
+func (s *SortSink) Run(out chan []byte, done chan bool) {
+	for b := range out {
+		if s.err != nil {
+			continue
+		}
+		s.err = s.add(b)
+	}
+	done <- true
+}
+
+// Err returns the first error of this sink or the next sink.
+func (s *SortSink) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.Next.Err()
+}
+
+// Flush sorts all documents and passes them to the next sink.
+func (s *SortSink) Flush() error {
+	if s.err != nil {
+		return s.err
+	}
+	sort.SliceStable(s.docs, func(i, j int) bool {
+		a, b := s.docs[i], s.docs[j]
+		if a.key != b.key {
+			return a.key < b.key
+		}
+		if a.num != b.num {
+			return a.num < b.num
+		}
+		return a.id < b.id
+	})
+	out, done := make(chan []byte), make(chan bool)
+	go s.Next.Run(out, done)
+	for _, d := range s.docs {
+		out <- d.blob
+	}
+	close(out)
+	<-done
+	s.docs = nil
+	return s.Next.Err()
+}
@@ -0,0 +1,69 @@
+package span
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// runSortSink sends documents through a sort sink and returns the output.
+func runSortSink(key string, maxBytes int64, docs []string) (string, error) {
+	var buf bytes.Buffer
+	s, err := NewSortSink(key, maxBytes, NewMultiSink(&buf))
+	if err != nil {
+		return "", err
+	}
+	out, done := make(chan []byte), make(chan bool)
+	go s.Run(out, done)
+	for _, doc := range docs {
+		out <- []byte(doc)
+	}
+	close(out)
+	<-done
+	if err := s.Flush(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func TestSortSink(t *testing.T) {
+	docs := []string{
+		`{"id":"ai-3","publishDateSort":2001,"issn":["2345-6789"]}`,
+		`{"id":"ai-1","publishDateSort":2003}`,
+		`{"id":"ai-2","publishDateSort":1999,"issn":["1234-5678"]}`,
+	}
+	var tests = []struct {
+		key  string
+		want string
+	}{
+		{"id", docs[1] + "\n" + docs[2] + "\n" + docs[0] + "\n"},
+		{"date", docs[2] + "\n" + docs[0] + "\n" + docs[1] + "\n"},
+		{"issn", docs[1] + "\n" + docs[2] + "\n" + docs[0] + "\n"},
+	}
+	for _, tt := range tests {
+		got, err := runSortSink(tt.key, DefaultSortMaxBytes, docs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("SortSink(%s): got %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestSortSinkLimit(t *testing.T) {
+	docs := []string{`{"id":"ai-2"}`, `{"id":"ai-1"}`}
+	if _, err := runSortSink("id", 20, docs); !errors.Is(err, ErrSortLimit) {
+		t.Errorf("SortSink: got %v, want %v", err, ErrSortLimit)
+	}
+	s, err := NewSortSink("id", 20, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Guard(21); !errors.Is(err, ErrSortLimit) {
+		t.Errorf("SortSink.Guard: got %v, want %v", err, ErrSortLimit)
+	}
+	if _, err := NewSortSink("title", 20, nil); err == nil {
+		t.Errorf("NewSortSink: got nil, want error for unknown key")
+	}
+}
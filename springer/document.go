@@ -0,0 +1,370 @@
+// Package springer converts Springer A++ XML deliveries into the
+// intermediate schema. A delivery contains journals with volumes, issues and
+// articles, or books, possibly within a series, with chapters. Articles and
+// chapters become records, their journal or book title is the parent title.
+package springer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kennygrant/sanitize"
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// SourceID for internal bookkeeping.
+	SourceID = "154"
+	// Collection name.
+	Collection = "Springer"
+	// BatchSize number of records per batch.
+	BatchSize = 2000
+)
+
+// Springer source.
+type Springer struct{}
+
+// Date is a date, month and day may be missing.
+type Date struct {
+	Year  string `xml:"Year"`
+	Month string `xml:"Month"`
+	Day   string `xml:"Day"`
+}
+
+// Time parses a date, missing month and day default to the first.
+func (d Date) Time() (time.Time, error) {
+	year, err := strconv.Atoi(strings.TrimSpace(d.Year))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("springer: invalid year: %q", d.Year)
+	}
+	month, day := 1, 1
+	if v, err := strconv.Atoi(strings.TrimSpace(d.Month)); err == nil && v >= 1 && v <= 12 {
+		month = v
+	}
+	if v, err := strconv.Atoi(strings.TrimSpace(d.Day)); err == nil && v >= 1 && v <= 31 {
+		day = v
+	}
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC), nil
+}
+
+// Markup is an element with inline markup, like Emphasis.
+type Markup struct {
+	Value string `xml:",innerxml"`
+}
+
+// Text returns the text without markup.
+func (m Markup) Text() string {
+	return strings.Join(strings.Fields(sanitize.HTML(m.Value)), " ")
+}
+
+// Header is the article or chapter header.
+type Header struct {
+	Authors []struct {
+		GivenNames []string `xml:"AuthorName>GivenName"`
+		FamilyName string   `xml:"AuthorName>FamilyName"`
+	} `xml:"AuthorGroup>Author"`
+	Abstracts []struct {
+		Language string   `xml:"Language,attr"`
+		Paras    []Markup `xml:"Para"`
+		Sections []Markup `xml:"AbstractSection>Para"`
+	} `xml:"Abstract"`
+	Keywords []string `xml:"KeywordGroup>Keyword"`
+}
+
+// Article is a journal article.
+type Article struct {
+	Info struct {
+		Language  string `xml:"Language,attr"`
+		Type      string `xml:"ArticleType,attr"`
+		DOI       string `xml:"ArticleDOI"`
+		Title     Markup `xml:"ArticleTitle"`
+		FirstPage string `xml:"ArticleFirstPage"`
+		LastPage  string `xml:"ArticleLastPage"`
+		Online    Date   `xml:"ArticleHistory>OnlineDate"`
+	} `xml:"ArticleInfo"`
+	Header Header `xml:"ArticleHeader"`
+}
+
+// Issue is a journal issue.
+type Issue struct {
+	Issue     string    `xml:"IssueInfo>IssueIDStart"`
+	CoverDate Date      `xml:"IssueInfo>IssueHistory>CoverDate"`
+	Articles  []Article `xml:"Article"`
+}
+
+// Journal is a journal with its volumes.
+type Journal struct {
+	Info struct {
+		Title string `xml:"JournalTitle"`
+		PISSN string `xml:"JournalPrintISSN"`
+		EISSN string `xml:"JournalElectronicISSN"`
+	} `xml:"JournalInfo"`
+	Volumes []struct {
+		Volume string  `xml:"VolumeInfo>VolumeIDStart"`
+		Issues []Issue `xml:"Issue"`
+	} `xml:"Volume"`
+}
+
+// Chapter is a book chapter.
+type Chapter struct {
+	Info struct {
+		Language  string `xml:"Language,attr"`
+		DOI       string `xml:"ChapterDOI"`
+		Title     Markup `xml:"ChapterTitle"`
+		FirstPage string `xml:"ChapterFirstPage"`
+		LastPage  string `xml:"ChapterLastPage"`
+		Online    Date   `xml:"ChapterHistory>OnlineDate"`
+	} `xml:"ChapterInfo"`
+	Header Header `xml:"ChapterHeader"`
+}
+
+// SeriesInfo describes a book series.
+type SeriesInfo struct {
+	Title string `xml:"SeriesTitle"`
+	PISSN string `xml:"SeriesPrintISSN"`
+	EISSN string `xml:"SeriesElectronicISSN"`
+}
+
+// Book is a book with chapters, directly or within parts.
+type Book struct {
+	Info struct {
+		DOI           string `xml:"BookDOI"`
+		Title         string `xml:"BookTitle"`
+		PISBN         string `xml:"BookPrintISBN"`
+		EISBN         string `xml:"BookElectronicISBN"`
+		Volume        string `xml:"BookVolumeNumber"`
+		CopyrightYear string `xml:"BookCopyright>CopyrightYear"`
+	} `xml:"BookInfo"`
+	Chapters     []Chapter `xml:"Chapter"`
+	PartChapters []Chapter `xml:"Part>Chapter"`
+}
+
+// Document is an article or a chapter with its context.
+type Document struct {
+	Publisher string
+	// Journal article.
+	Article *Article
+	Journal *Journal
+	Volume  string
+	Issue   *Issue
+	// Book chapter.
+	Chapter *Chapter
+	Book    *Book
+	Series  *SeriesInfo
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(docs []*Document) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			return s.(span.Importer), nil
+		}, Items: make([]interface{}, len(docs))}
+	for i, doc := range docs {
+		batch.Items[i] = doc
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via XML decoding. Journals and books are
+// decoded one at a time. The series info of a book and the publisher name
+// are remembered while their enclosing element is open.
+func (s Springer) Iterate(r io.Reader) (<-chan interface{}, error) {
+	ch := make(chan interface{})
+	var docs []*Document
+	emit := func(doc *Document) {
+		docs = append(docs, doc)
+		if len(docs) == BatchSize {
+			ch <- NewBatch(docs)
+			docs = nil
+		}
+	}
+	go func() {
+		var (
+			decoder   = xml.NewDecoder(bufio.NewReader(r))
+			series    *SeriesInfo
+			publisher string
+		)
+		for {
+			t, _ := decoder.Token()
+			if t == nil {
+				break
+			}
+			switch se := t.(type) {
+			case xml.StartElement:
+				switch se.Name.Local {
+				case "PublisherName":
+					var name string
+					if err := decoder.DecodeElement(&name, &se); err != nil {
+						log.Fatal(err)
+					}
+					publisher = strings.TrimSpace(name)
+				case "SeriesInfo":
+					series = new(SeriesInfo)
+					if err := decoder.DecodeElement(series, &se); err != nil {
+						log.Fatal(err)
+					}
+				case "Journal":
+					journal := new(Journal)
+					if err := decoder.DecodeElement(journal, &se); err != nil {
+						log.Fatal(err)
+					}
+					for _, volume := range journal.Volumes {
+						for i := range volume.Issues {
+							issue := &volume.Issues[i]
+							for j := range issue.Articles {
+								emit(&Document{Publisher: publisher, Article: &issue.Articles[j],
+									Journal: journal, Volume: volume.Volume, Issue: issue})
+							}
+						}
+					}
+				case "Book":
+					book := new(Book)
+					if err := decoder.DecodeElement(book, &se); err != nil {
+						log.Fatal(err)
+					}
+					for _, chapters := range [][]Chapter{book.Chapters, book.PartChapters} {
+						for i := range chapters {
+							emit(&Document{Publisher: publisher, Chapter: &chapters[i], Book: book, Series: series})
+						}
+					}
+				}
+			case xml.EndElement:
+				if se.Name.Local == "Series" {
+					series = nil
+				}
+			}
+		}
+		ch <- NewBatch(docs)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// abstract returns the first abstract as plain text.
+func (h Header) abstract() string {
+	if len(h.Abstracts) == 0 {
+		return ""
+	}
+	var paras []string
+	for _, p := range append(h.Abstracts[0].Paras, h.Abstracts[0].Sections...) {
+		paras = append(paras, p.Text())
+	}
+	return strings.Join(paras, "\n\n")
+}
+
+// authors returns the authors of a header.
+func (h Header) authors() (authors []finc.Author) {
+	for _, a := range h.Authors {
+		authors = append(authors, finc.Author{
+			FirstName: strings.Join(a.GivenNames, " "),
+			LastName:  a.FamilyName,
+		})
+	}
+	return authors
+}
+
+// Date returns the cover date of the issue for articles, the online date
+// for chapters. Online date and copyright year are fallbacks.
+func (doc *Document) Date() (time.Time, error) {
+	if doc.Article != nil {
+		if t, err := doc.Issue.CoverDate.Time(); err == nil {
+			return t, nil
+		}
+		return doc.Article.Info.Online.Time()
+	}
+	if t, err := doc.Chapter.Info.Online.Time(); err == nil {
+		return t, nil
+	}
+	return Date{Year: doc.Book.Info.CopyrightYear}.Time()
+}
+
+// ToIntermediateSchema converts an article or chapter. Records without DOI
+// or date are skipped.
+func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	output.Date, err = doc.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+
+	var (
+		header      Header
+		language    string
+		first, last string
+	)
+	if doc.Article != nil {
+		info := doc.Article.Info
+		header, language = doc.Article.Header, info.Language
+		first, last = info.FirstPage, info.LastPage
+		output.DOI = info.DOI
+		output.ArticleTitle = info.Title.Text()
+		output.Format, output.Genre, output.RefType = "ElectronicArticle", "article", "JOUR"
+		output.JournalTitle = doc.Journal.Info.Title
+		if v := doc.Journal.Info.PISSN; v != "" {
+			output.ISSN = append(output.ISSN, v)
+		}
+		if v := doc.Journal.Info.EISSN; v != "" {
+			output.EISSN = append(output.EISSN, v)
+		}
+		output.Volume = doc.Volume
+		output.Issue = doc.Issue.Issue
+	} else {
+		info := doc.Chapter.Info
+		header, language = doc.Chapter.Header, info.Language
+		first, last = info.FirstPage, info.LastPage
+		output.DOI = info.DOI
+		output.ArticleTitle = info.Title.Text()
+		output.Format, output.Genre, output.RefType = "ElectronicBookPart", "bookitem", "ECHAP"
+		output.BookTitle = doc.Book.Info.Title
+		if v := doc.Book.Info.PISBN; v != "" {
+			output.ISBN = append(output.ISBN, v)
+		}
+		if v := doc.Book.Info.EISBN; v != "" {
+			output.EISBN = append(output.EISBN, v)
+		}
+		output.Volume = doc.Book.Info.Volume
+		if doc.Series != nil {
+			output.Series = doc.Series.Title
+			if v := doc.Series.PISSN; v != "" {
+				output.ISSN = append(output.ISSN, v)
+			}
+			if v := doc.Series.EISSN; v != "" {
+				output.EISSN = append(output.EISSN, v)
+			}
+		}
+	}
+
+	if output.DOI == "" {
+		return output, span.Skip{Reason: "springer: record without DOI"}
+	}
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(output.DOI)))
+	output.SourceID = SourceID
+	output.RecordID = strings.TrimRight(enc, "=")
+	output.MegaCollection = Collection
+	output.URL = append(output.URL, "https://doi.org/"+output.DOI)
+
+	output.Authors = header.authors()
+	output.Abstract = header.abstract()
+	output.Subjects = header.Keywords
+	if language != "" {
+		output.Languages = []string{span.NormalizeLanguage(language)}
+	}
+	if doc.Publisher != "" {
+		output.Publishers = append(output.Publishers, doc.Publisher)
+	}
+	output.StartPage, output.EndPage = first, last
+	if first != "" && last != "" {
+		output.Pages = fmt.Sprintf("%s-%s", first, last)
+	}
+	return output, nil
+}
@@ -0,0 +1,97 @@
+package springer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/miku/span/internal/spantest"
+)
+
+const example = `<?xml version="1.0" encoding="UTF-8"?>
+<Publisher>
+<PublisherInfo><PublisherName>Springer Berlin Heidelberg</PublisherName></PublisherInfo>
+<Journal>
+	<JournalInfo>
+		<JournalTitle>Journal of Examples</JournalTitle>
+		<JournalPrintISSN>1234-5678</JournalPrintISSN>
+		<JournalElectronicISSN>2345-6789</JournalElectronicISSN>
+	</JournalInfo>
+	<Volume>
+		<VolumeInfo><VolumeIDStart>12</VolumeIDStart></VolumeInfo>
+		<Issue>
+			<IssueInfo><IssueIDStart>3</IssueIDStart><IssueHistory><CoverDate><Year>2016</Year><Month>3</Month></CoverDate></IssueHistory></IssueInfo>
+			<Article>
+				<ArticleInfo Language="En" ArticleType="OriginalPaper">
+					<ArticleDOI>10.1007/s00001-016-0001-1</ArticleDOI>
+					<ArticleTitle Language="En">On <Emphasis Type="Italic">examples</Emphasis></ArticleTitle>
+					<ArticleFirstPage>101</ArticleFirstPage>
+					<ArticleLastPage>110</ArticleLastPage>
+				</ArticleInfo>
+				<ArticleHeader>
+					<AuthorGroup><Author><AuthorName><GivenName>Jane</GivenName><GivenName>M.</GivenName><FamilyName>Doe</FamilyName></AuthorName></Author></AuthorGroup>
+					<Abstract Language="En"><Heading>Abstract</Heading><Para>An abstract.</Para></Abstract>
+					<KeywordGroup><Keyword>examples</Keyword></KeywordGroup>
+				</ArticleHeader>
+			</Article>
+		</Issue>
+	</Volume>
+</Journal>
+<Series>
+	<SeriesInfo><SeriesTitle>Lecture Notes in Examples</SeriesTitle><SeriesPrintISSN>0302-9743</SeriesPrintISSN></SeriesInfo>
+	<Book>
+		<BookInfo>
+			<BookDOI>10.1007/978-3-000-00000-0</BookDOI>
+			<BookTitle>Advances in Examples</BookTitle>
+			<BookPrintISBN>978-3-000-00000-0</BookPrintISBN>
+			<BookElectronicISBN>978-3-000-00001-7</BookElectronicISBN>
+			<BookCopyright><CopyrightYear>2015</CopyrightYear></BookCopyright>
+		</BookInfo>
+		<Part>
+			<Chapter>
+				<ChapterInfo Language="De">
+					<ChapterDOI>10.1007/978-3-000-00001-7_1</ChapterDOI>
+					<ChapterTitle>Beispiele</ChapterTitle>
+				</ChapterInfo>
+			</Chapter>
+		</Part>
+	</Book>
+</Series>
+</Publisher>`
+
+func TestSpringer(t *testing.T) {
+	results := spantest.MustConvertAll(t, Springer{}, strings.NewReader(example))
+	if len(results) != 2 {
+		t.Fatalf("got %d records, want 2", len(results))
+	}
+
+	article := results[0]
+	if article.ArticleTitle != "On examples" || article.Abstract != "An abstract." {
+		t.Errorf("got title %q, abstract %q", article.ArticleTitle, article.Abstract)
+	}
+	if article.ParentTitle() != "Journal of Examples" || article.Format != "ElectronicArticle" {
+		t.Errorf("got parent title %q, format %s", article.ParentTitle(), article.Format)
+	}
+	if article.Date.Format("2006-01-02") != "2016-03-01" || article.Pages != "101-110" {
+		t.Errorf("got date %v, pages %q", article.Date, article.Pages)
+	}
+	if len(article.Authors) != 1 || article.Authors[0].FirstName != "Jane M." {
+		t.Errorf("got authors %v", article.Authors)
+	}
+	if len(article.Publishers) != 1 || article.Publishers[0] != "Springer Berlin Heidelberg" {
+		t.Errorf("got publishers %v", article.Publishers)
+	}
+
+	chapter := results[1]
+	if chapter.ParentTitle() != "Advances in Examples" || chapter.JournalTitle != "" {
+		t.Errorf("got parent title %q, journal title %q", chapter.ParentTitle(), chapter.JournalTitle)
+	}
+	if chapter.Format != "ElectronicBookPart" || chapter.Genre != "bookitem" || chapter.Series != "Lecture Notes in Examples" {
+		t.Errorf("got format %s, genre %s, series %q", chapter.Format, chapter.Genre, chapter.Series)
+	}
+	if chapter.Date.Year() != 2015 || len(chapter.ISBN) != 1 || len(chapter.EISBN) != 1 || len(chapter.ISSN) != 1 {
+		t.Errorf("got date %v, ISBN %v, EISBN %v, ISSN %v", chapter.Date, chapter.ISBN, chapter.EISBN, chapter.ISSN)
+	}
+	if len(chapter.Languages) != 1 || chapter.Languages[0] != "deu" {
+		t.Errorf("got languages %v", chapter.Languages)
+	}
+}
@@ -0,0 +1,88 @@
+package span
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// MemStats summarizes memory usage and garbage collection of a run.
+type MemStats struct {
+	// PeakHeapInuse is the largest sampled number of bytes in in-use spans.
+	PeakHeapInuse uint64 `json:"peak_heap_inuse"`
+	// TotalAlloc is the cumulative number of bytes allocated for heap objects.
+	TotalAlloc uint64 `json:"total_alloc"`
+	// NumGC is the number of completed GC cycles.
+	NumGC uint32 `json:"num_gc"`
+	// PauseTotal is the cumulative stop-the-world pause time.
+	PauseTotal time.Duration `json:"gc_pause_total_ns"`
+	// Samples is the number of samples taken.
+	Samples int `json:"samples"`
+}
+
+// Stats is the summary of a run.
+type Stats struct {
+	Records int64         `json:"records"`
+	Bytes   int64         `json:"bytes"`
+	Elapsed time.Duration `json:"elapsed_ns"`
+	Workers int           `json:"workers"`
+	Batch   int           `json:"batch_size"`
+	Memory  MemStats      `json:"memory"`
+}
+
+// MemSampler records the peak heap usage in a background goroutine. Reading
+// memory statistics stops the world briefly, so the interval should not be
+// too short, about a second works well.
+type MemSampler struct {
+	Interval time.Duration
+
+	mu    sync.Mutex
+	stats MemStats
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewMemSampler starts a sampler with a given interval.
+func NewMemSampler(interval time.Duration) *MemSampler {
+	s := &MemSampler{Interval: interval, done: make(chan struct{})}
+	s.sample()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.sample()
+			case <-s.done:
+				return
+			}
+		}
+	}()
+	return s
+}
+
+func (s *MemSampler) sample() {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m.HeapInuse > s.stats.PeakHeapInuse {
+		s.stats.PeakHeapInuse = m.HeapInuse
+	}
+	s.stats.TotalAlloc = m.TotalAlloc
+	s.stats.NumGC = m.NumGC
+	s.stats.PauseTotal = time.Duration(m.PauseTotalNs)
+	s.stats.Samples++
+}
+
+// Stop takes a final sample, stops the sampler and returns the statistics.
+func (s *MemSampler) Stop() MemStats {
+	close(s.done)
+	s.wg.Wait()
+	s.sample()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
@@ -0,0 +1,37 @@
+package span
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// allocated keeps allocations alive, so they show up in the heap.
+var allocated [][]byte
+
+func TestMemSampler(t *testing.T) {
+	sampler := NewMemSampler(time.Millisecond)
+	for i := 0; i < 1000; i++ {
+		allocated = append(allocated, make([]byte, 4096))
+	}
+	time.Sleep(5 * time.Millisecond)
+	stats := Stats{Records: 1000, Memory: sampler.Stop()}
+	allocated = nil
+
+	if stats.Memory.PeakHeapInuse < 4096*1000 {
+		t.Errorf("PeakHeapInuse: got %d, want at least %d", stats.Memory.PeakHeapInuse, 4096*1000)
+	}
+	if stats.Memory.TotalAlloc == 0 || stats.Memory.Samples < 2 {
+		t.Errorf("MemStats: got %+v, want non-zero allocations and samples", stats.Memory)
+	}
+	b, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"peak_heap_inuse":`, `"total_alloc":`, `"num_gc":`, `"gc_pause_total_ns":`} {
+		if !strings.Contains(string(b), field) {
+			t.Errorf("Stats JSON: got %s, want field %s", b, field)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package span
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/miku/span/holdings"
+)
+
+// HoldingStore keeps the licenses of a number of institutions and allows to
+// replace them while the store is in use, e.g. in a long running service,
+// where holdings files change. Readers are never blocked for the duration of
+// a reload, only for the swap.
+type HoldingStore struct {
+	mu       sync.RWMutex
+	table    map[string]holdings.Licenses
+	editions map[string]holdings.Editions
+}
+
+// NewHoldingStore returns an empty store.
+func NewHoldingStore() *HoldingStore {
+	return &HoldingStore{
+		table:    make(map[string]holdings.Licenses),
+		editions: make(map[string]holdings.Editions),
+	}
+}
+
+// Licenses returns the current licenses for an ISIL.
+func (s *HoldingStore) Licenses(isil string) holdings.Licenses {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.table[isil]
+}
+
+// Editions returns, whether ISSNs of an ISIL were listed as print or
+// electronic ISSN in the holdings files of the last reload.
+func (s *HoldingStore) Editions(isil string) holdings.Editions {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.editions[isil]
+}
+
+// Reload parses the holdings files given as a map from ISIL to path and
+// replaces the current holdings at once. If any file cannot be parsed, the
+// current holdings are kept and an error is returned.
+func (s *HoldingStore) Reload(paths map[string]string) error {
+	table := make(map[string]holdings.Licenses)
+	editions := make(map[string]holdings.Editions)
+	for isil, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		licenses, eds, errs := holdings.ParseHoldingsEditions(file)
+		file.Close()
+		if len(errs) > 0 {
+			return fmt.Errorf("%d errors in holdings file %s, first: %s", len(errs), path, errs[0])
+		}
+		table[isil], editions[isil] = licenses, eds
+	}
+	s.mu.Lock()
+	s.table, s.editions = table, editions
+	s.mu.Unlock()
+	return nil
+}
+
+// Mutation operations.
+const (
+	// OpAdd adds License to ISSN for ISIL. Adding a present license is a noop.
+	OpAdd = "add"
+	// OpRemove removes License from ISSN for ISIL. Removing an absent license
+	// is a noop.
+	OpRemove = "remove"
+	// OpRemoveISSN removes all licenses of ISSN for ISIL.
+	OpRemoveISSN = "remove-issn"
+)
+
+// Mutation is a small change to the holdings of an institution, e.g.
+// {"op": "add", "isil": "DE-15", "issn": "1234-5678", "license": "..."}.
+type Mutation struct {
+	Op      string           `json:"op"`
+	ISIL    string           `json:"isil"`
+	ISSN    string           `json:"issn"`
+	License holdings.License `json:"license,omitempty"`
+}
+
+// Validate checks, whether the mutation is complete and well-formed.
+func (m Mutation) Validate() error {
+	if m.ISIL == "" || m.ISSN == "" {
+		return errors.New("mutation requires isil and issn")
+	}
+	switch m.Op {
+	case OpAdd, OpRemove:
+		return m.License.Validate()
+	case OpRemoveISSN:
+		return nil
+	default:
+		return fmt.Errorf("unknown mutation: %s", m.Op)
+	}
+}
+
+// Update applies a number of mutations at once: readers see either none or
+// all of them. If any mutation is invalid, nothing is applied. Licenses of
+// affected institutions are copied, so tables returned by Licenses earlier
+// stay unchanged. Editions are kept as of the last reload.
+func (s *HoldingStore) Update(mutations ...Mutation) error {
+	for _, m := range mutations {
+		if err := m.Validate(); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	table := make(map[string]holdings.Licenses, len(s.table))
+	for isil, licenses := range s.table {
+		table[isil] = licenses
+	}
+	copied := make(map[string]bool)
+	for _, m := range mutations {
+		if !copied[m.ISIL] {
+			licenses := make(holdings.Licenses)
+			for issn, ls := range table[m.ISIL] {
+				licenses[issn] = ls
+			}
+			table[m.ISIL] = licenses
+			copied[m.ISIL] = true
+		}
+		licenses := table[m.ISIL]
+		switch m.Op {
+		case OpAdd:
+			licenses.Add(m.ISSN, m.License)
+		case OpRemove:
+			licenses.Remove(m.ISSN, m.License)
+		case OpRemoveISSN:
+			delete(licenses, m.ISSN)
+		}
+	}
+	s.table = table
+	return nil
+}
+
+// UpdateFrom reads mutations as line delimited JSON and applies each line
+// as soon as it is read, see Update. Blank lines are skipped. Returns the
+// number of applied mutations.
+func (s *HoldingStore) UpdateFrom(r io.Reader) (int, error) {
+	var n int
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if err == io.EOF && line == "" {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return n, err
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var m Mutation
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			return n, err
+		}
+		if err := s.Update(m); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,170 @@
+package span
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miku/span/finc"
+	"github.com/miku/span/holdings"
+)
+
+// testHolding returns a holding for ISSN 1234-5678 with a single subscribed
+// entitlement, empty begin, end or delay are left out.
+func testHolding(begin, end, delay string) string {
+	var b strings.Builder
+	b.WriteString("<holding ezb_id=\"1\">\n  <EZBIssns><p-issn>1234-5678</p-issn></EZBIssns>\n")
+	b.WriteString("  <entitlements>\n    <entitlement status=\"subscribed\">\n      <begin>")
+	if begin != "" {
+		fmt.Fprintf(&b, "<year>%s</year>", begin)
+	}
+	if delay != "" {
+		fmt.Fprintf(&b, "<delay>%s</delay>", delay)
+	}
+	b.WriteString("</begin>\n")
+	if end != "" {
+		fmt.Fprintf(&b, "      <end><year>%s</year></end>\n", end)
+	}
+	b.WriteString("    </entitlement>\n  </entitlements>\n</holding>")
+	return b.String()
+}
+
+func TestHoldingStoreReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "span-store-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, end string) string {
+		path := filepath.Join(dir, name)
+		content := []byte(testHolding("2000", end, ""))
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	store := NewHoldingStore()
+	if err := store.Reload(map[string]string{"DE-1": write("a.xml", "2001")}); err != nil {
+		t.Fatal(err)
+	}
+	f := NewStoreHoldingFilter(store, "DE-1")
+	is := finc.IntermediateSchema{ISSN: []string{"1234-5678"}, Date: mustParseDate("2005-01-01")}
+
+	if f.Apply(is) {
+		t.Errorf("HoldingFilter.Apply: got true, want false before reload")
+	}
+	if err := store.Reload(map[string]string{"DE-1": write("b.xml", "2010")}); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Apply(is) {
+		t.Errorf("HoldingFilter.Apply: got false, want true after reload")
+	}
+	signature := holdings.CombineDatum("2005", "", "", "")
+	if _, edition := f.CoveredAndValidEdition(signature, "1234-5678", is.Date); edition != holdings.Print {
+		t.Errorf("CoveredAndValidEdition: got %s, want %s after reload", edition, holdings.Print)
+	}
+	if err := store.Reload(map[string]string{"DE-1": filepath.Join(dir, "missing.xml")}); err == nil {
+		t.Errorf("HoldingStore.Reload: got nil, want error for missing file")
+	}
+	if !f.Apply(is) {
+		t.Errorf("HoldingFilter.Apply: got false, want true after failed reload")
+	}
+}
+
+// notifyReader signals each call to Read. UpdateFrom reads the next line only
+// after it applied the previous one.
+type notifyReader struct {
+	r     io.Reader
+	reads chan bool
+}
+
+func (r notifyReader) Read(p []byte) (int, error) {
+	r.reads <- true
+	return r.r.Read(p)
+}
+
+func TestHoldingStoreUpdate(t *testing.T) {
+	store := NewHoldingStore()
+	f := NewStoreHoldingFilter(store, "DE-1")
+	is := finc.IntermediateSchema{ISSN: []string{"1234-5678"}, Date: mustParseDate("2005-01-01")}
+	license := holdings.License("2000000000000000:ZZZZZZZZZZZZZZZZ:0")
+
+	r, w := io.Pipe()
+	reads := make(chan bool, 1)
+	done := make(chan error)
+	go func() {
+		_, err := store.UpdateFrom(notifyReader{r: r, reads: reads})
+		done <- err
+	}()
+	<-reads
+	send := func(m Mutation) {
+		b, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(append(b, '\n')); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the next read starts, once the mutation has been applied
+	waitFor := func() {
+		<-reads
+	}
+
+	if f.Apply(is) {
+		t.Errorf("HoldingFilter.Apply: got true, want false before add")
+	}
+	send(Mutation{Op: OpAdd, ISIL: "DE-1", ISSN: "1234-5678", License: license})
+	waitFor()
+	if !f.Apply(is) {
+		t.Errorf("HoldingFilter.Apply: got false, want true after add")
+	}
+	send(Mutation{Op: OpRemove, ISIL: "DE-1", ISSN: "1234-5678", License: license})
+	waitFor()
+	if f.Apply(is) {
+		t.Errorf("HoldingFilter.Apply: got true, want false after remove")
+	}
+	w.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Update(Mutation{Op: OpAdd, ISIL: "DE-1", ISSN: "1234-5678", License: "2000"}); err == nil {
+		t.Errorf("HoldingStore.Update: got nil, want error for invalid license")
+	}
+	err := store.Update(
+		Mutation{Op: OpAdd, ISIL: "DE-1", ISSN: "1234-5678", License: license},
+		Mutation{Op: "rename", ISIL: "DE-1", ISSN: "1234-5678"})
+	if err == nil {
+		t.Errorf("HoldingStore.Update: got nil, want error for unknown operation")
+	}
+	if f.Apply(is) {
+		t.Errorf("HoldingFilter.Apply: got true, want false after failed update")
+	}
+}
+
+func TestHoldingStoreUpdateFromBlankLines(t *testing.T) {
+	store := NewHoldingStore()
+	input := `{"op": "add", "isil": "DE-1", "issn": "1234-5678", "license": "2000000000000000:ZZZZZZZZZZZZZZZZ:0"}
+
+{"op": "add", "isil": "DE-1", "issn": "2345-6789", "license": "2000000000000000:ZZZZZZZZZZZZZZZZ:0"}
+  
+`
+	n, err := store.UpdateFrom(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("HoldingStore.UpdateFrom: got %d mutations, want 2", n)
+	}
+	if got := len(store.Licenses("DE-1")); got != 2 {
+		t.Errorf("HoldingStore.UpdateFrom: got %d ISSNs, want 2", got)
+	}
+}
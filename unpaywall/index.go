@@ -0,0 +1,82 @@
+package unpaywall
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/miku/span/finc"
+)
+
+// Status is the open access information of a DOI.
+type Status struct {
+	OAStatus string
+	URL      string
+}
+
+// Index maps lowercased DOIs to their open access status. Only open access
+// DOIs are kept, since all other DOIs have no status to contribute and the
+// snapshot is large.
+type Index struct {
+	m map[string]Status
+}
+
+// ReadIndex reads open access DOIs from a snapshot, which may be gzip
+// compressed.
+func ReadIndex(r io.Reader) (*Index, error) {
+	reader, err := newReader(r)
+	if err != nil {
+		return nil, err
+	}
+	index := &Index{m: make(map[string]Status)}
+	dec := json.NewDecoder(reader)
+	for {
+		var record Record
+		if err := dec.Decode(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if !record.IsOA || record.DOI == "" {
+			continue
+		}
+		index.m[strings.ToLower(record.DOI)] = Status{OAStatus: record.OAStatus, URL: record.BestURL()}
+	}
+	return index, nil
+}
+
+// Size returns the number of indexed DOIs.
+func (x *Index) Size() int {
+	return len(x.m)
+}
+
+// Lookup returns the status of a DOI, case insensitive.
+func (x *Index) Lookup(doi string) (Status, bool) {
+	s, ok := x.m[strings.ToLower(strings.TrimSpace(doi))]
+	return s, ok
+}
+
+// Enrich marks a record as open access, if its DOI is in the index, sets the
+// open access status and adds the best open access URL, if it is new. It
+// returns true, if the record was changed.
+func (x *Index) Enrich(is *finc.IntermediateSchema) bool {
+	if is.DOI == "" {
+		return false
+	}
+	s, ok := x.Lookup(is.DOI)
+	if !ok {
+		return false
+	}
+	is.OpenAccess = true
+	is.OAStatus = s.OAStatus
+	if s.URL == "" {
+		return true
+	}
+	for _, u := range is.URL {
+		if u == s.URL {
+			return true
+		}
+	}
+	is.URL = append(is.URL, s.URL)
+	return true
+}
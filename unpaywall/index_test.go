@@ -0,0 +1,61 @@
+package unpaywall
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span/finc"
+	"github.com/miku/span/internal/spantest"
+)
+
+// The snapshot has one record per line.
+const snapshot = `{"doi": "10.1000/UP.1", "title": "Open", "genre": "journal-article", "is_oa": true, "oa_status": "green", "best_oa_location": {"url": "https://repo.example.org/1.pdf", "host_type": "repository", "version": "acceptedVersion"}, "journal_name": "Journal of Examples", "journal_issns": "1234-5678,2345-6789", "publisher": "Example Press", "published_date": "2018-05-02", "year": 2018, "z_authors": [{"given": "Jane", "family": "Doe"}]}
+{"doi": "10.1000/up.2", "title": "Closed", "genre": "journal-article", "is_oa": false, "oa_status": "closed", "best_oa_location": null, "year": 2017}
+`
+
+func TestReadIndex(t *testing.T) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	gw.Write([]byte(snapshot))
+	gw.Close()
+	for _, r := range []*strings.Reader{strings.NewReader(snapshot), strings.NewReader(buf.String())} {
+		index, err := ReadIndex(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if index.Size() != 1 {
+			t.Fatalf("got %d DOIs, want 1", index.Size())
+		}
+		is := finc.IntermediateSchema{DOI: "10.1000/up.1", URL: []string{"https://example.org/1"}}
+		if !index.Enrich(&is) {
+			t.Fatal("want record to be enriched")
+		}
+		if !is.OpenAccess || is.OAStatus != "green" {
+			t.Errorf("got open access %v, status %q", is.OpenAccess, is.OAStatus)
+		}
+		if !reflect.DeepEqual(is.URL, []string{"https://example.org/1", "https://repo.example.org/1.pdf"}) {
+			t.Errorf("got URL %v", is.URL)
+		}
+		closed := finc.IntermediateSchema{DOI: "10.1000/up.2"}
+		if index.Enrich(&closed) || closed.OpenAccess {
+			t.Errorf("got %v, want closed record unchanged", closed)
+		}
+	}
+}
+
+func TestIterate(t *testing.T) {
+	results := spantest.MustConvertAll(t, Unpaywall{}, strings.NewReader(snapshot))
+	if len(results) != 2 {
+		t.Fatalf("got %d records, want 2", len(results))
+	}
+	is := results[0]
+	if is.Format != "ElectronicArticle" || !reflect.DeepEqual(is.ISSN, []string{"1234-5678", "2345-6789"}) || is.OAStatus != "green" {
+		t.Errorf("got format %s, ISSN %v, status %s", is.Format, is.ISSN, is.OAStatus)
+	}
+	if results[1].OpenAccess || len(results[1].URL) != 0 || results[1].Date.Year() != 2017 {
+		t.Errorf("got %v, want closed record without URL", results[1])
+	}
+}
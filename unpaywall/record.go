@@ -0,0 +1,207 @@
+// Package unpaywall reads the Unpaywall snapshot, one DOI record per line.
+// Records can be converted into the intermediate schema, like any other
+// source, or loaded into an Index, which enriches records from other sources
+// with the open access status and the best open access location.
+package unpaywall
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+)
+
+const (
+	// SourceID for internal bookkeeping.
+	SourceID = "165"
+	// Collection name.
+	Collection = "Unpaywall"
+	// BatchSize for grouped channel transport.
+	BatchSize = 10000
+)
+
+// genres maps Crossref types, as used by Unpaywall, to format, genre and RIS
+// type.
+var genres = map[string][3]string{
+	"journal-article":     {"ElectronicArticle", "article", "JOUR"},
+	"book":                {"eBook", "book", "EBOOK"},
+	"monograph":           {"eBook", "book", "EBOOK"},
+	"book-chapter":        {"ElectronicBookPart", "bookitem", "ECHAP"},
+	"proceedings-article": {"ElectronicProceeding", "proceeding", "CPAPER"},
+	"posted-content":      {"ElectronicPreprint", "preprint", "UNPB"},
+	"report":              {"ElectronicResourceRemoteAccess", "report", "RPRT"},
+	"dissertation":        {"ElectronicThesis", "document", "THES"},
+	"dataset":             {"ElectronicResourceRemoteAccess", "document", "DATA"},
+}
+
+// Unpaywall source.
+type Unpaywall struct{}
+
+// Location is a place, where a version of an article can be accessed.
+type Location struct {
+	URL               string `json:"url"`
+	URLForPDF         string `json:"url_for_pdf"`
+	URLForLandingPage string `json:"url_for_landing_page"`
+	HostType          string `json:"host_type"`
+	License           string `json:"license"`
+	Version           string `json:"version"`
+}
+
+// Record is a single DOI record of the snapshot.
+type Record struct {
+	DOI            string    `json:"doi"`
+	Title          string    `json:"title"`
+	Genre          string    `json:"genre"`
+	IsOA           bool      `json:"is_oa"`
+	OAStatus       string    `json:"oa_status"`
+	BestOALocation *Location `json:"best_oa_location"`
+	JournalName    string    `json:"journal_name"`
+	JournalISSNs   string    `json:"journal_issns"`
+	Publisher      string    `json:"publisher"`
+	PublishedDate  string    `json:"published_date"`
+	Year           int       `json:"year"`
+	Authors        []struct {
+		Given  string `json:"given"`
+		Family string `json:"family"`
+	} `json:"z_authors"`
+}
+
+// BestURL returns the URL of the best open access location, or the empty
+// string, if there is none.
+func (r *Record) BestURL() string {
+	if r.BestOALocation == nil {
+		return ""
+	}
+	return r.BestOALocation.URL
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(lines []string) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			record := new(Record)
+			err := json.Unmarshal([]byte(s.(string)), record)
+			return record, err
+		}, Items: make([]interface{}, len(lines))}
+	for i, line := range lines {
+		batch.Items[i] = line
+	}
+	return batch
+}
+
+// newReader returns a reader, that decompresses gzip compressed input, as
+// the snapshot is distributed compressed.
+func newReader(r io.Reader) (*bufio.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		return br, nil
+	}
+	gr, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, err
+	}
+	return bufio.NewReader(gr), nil
+}
+
+// Iterate emits Converter elements via JSON decoding.
+func (s Unpaywall) Iterate(r io.Reader) (<-chan interface{}, error) {
+	reader, err := newReader(r)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan interface{})
+	var lines []string
+	go func() {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil && err != io.EOF {
+				log.Fatal(err)
+			}
+			if strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+			if len(lines) == BatchSize {
+				ch <- NewBatch(lines)
+				lines = nil
+			}
+			if err == io.EOF {
+				break
+			}
+		}
+		ch <- NewBatch(lines)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Date returns the publication date, or the first day of the year.
+func (r *Record) Date() (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", r.PublishedDate); err == nil {
+		return t, nil
+	}
+	if r.Year > 0 {
+		return time.Date(r.Year, time.January, 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Time{}, fmt.Errorf("unpaywall: %s: no usable date", r.DOI)
+}
+
+// ToIntermediateSchema converts a record. Records without DOI or date are
+// skipped.
+func (r *Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	var err error
+	output := finc.NewIntermediateSchema()
+
+	if r.DOI == "" {
+		return output, span.Skip{Reason: "unpaywall: record without DOI"}
+	}
+	output.Date, err = r.Date()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
+
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(r.DOI)))
+	output.SourceID = SourceID
+	output.RecordID = strings.TrimRight(enc, "=")
+	output.MegaCollection = Collection
+	kind, ok := genres[r.Genre]
+	if !ok {
+		kind = [3]string{"ElectronicResourceRemoteAccess", "unknown", "GEN"}
+	}
+	output.Format, output.Genre, output.RefType = kind[0], kind[1], kind[2]
+
+	output.DOI = r.DOI
+	output.ArticleTitle = strings.TrimSpace(r.Title)
+	output.JournalTitle = strings.TrimSpace(r.JournalName)
+	for _, issn := range strings.Split(r.JournalISSNs, ",") {
+		if issn = strings.TrimSpace(issn); issn != "" {
+			output.ISSN = append(output.ISSN, issn)
+		}
+	}
+	if r.Publisher != "" {
+		output.Publishers = append(output.Publishers, r.Publisher)
+	}
+	for _, a := range r.Authors {
+		if a.Family != "" || a.Given != "" {
+			output.Authors = append(output.Authors, finc.Author{LastName: a.Family, FirstName: a.Given})
+		}
+	}
+	output.OpenAccess = r.IsOA
+	output.OAStatus = r.OAStatus
+	if u := r.BestURL(); u != "" {
+		output.URL = append(output.URL, u)
+	}
+	return output, nil
+}
@@ -0,0 +1,128 @@
+// Package wiso converts WISO delivery files into the intermediate schema.
+// WISO is delivered by GBI-Genios in the Genios document format, with the
+// identifier of the licensed package in an additional Package element. The
+// package becomes the collection of a record, so filters can select single
+// packages.
+package wiso
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/miku/span"
+	"github.com/miku/span/finc"
+	"github.com/miku/span/genios"
+)
+
+const (
+	// SourceID for internal bookkeeping.
+	SourceID = "157"
+	// Collection is the prefix of the collection name of a package.
+	Collection = "WISO"
+	// BatchSize number of documents per batch.
+	BatchSize = 2000
+)
+
+// WISO source. Packages maps package identifiers to collection names.
+// Packages without an entry get a collection named after their identifier,
+// like "WISO (wiwi)".
+type WISO struct {
+	Packages map[string]string
+}
+
+// ReadPackages reads a JSON object, that maps package identifiers to
+// collection names.
+func ReadPackages(r io.Reader) (map[string]string, error) {
+	packages := make(map[string]string)
+	if err := json.NewDecoder(r).Decode(&packages); err != nil {
+		return nil, err
+	}
+	return packages, nil
+}
+
+// Document is a Genios document with a package identifier.
+type Document struct {
+	genios.Document
+	Package string `xml:"Package"`
+
+	packages map[string]string
+}
+
+// NewBatch wraps up a new batch for channel com.
+func NewBatch(docs []*Document) span.Batcher {
+	batch := span.Batcher{
+		Apply: func(s interface{}) (span.Importer, error) {
+			return s.(span.Importer), nil
+		}, Items: make([]interface{}, len(docs))}
+	for i, doc := range docs {
+		batch.Items[i] = doc
+	}
+	return batch
+}
+
+// Iterate emits Converter elements via XML decoding, with the latin-1 and
+// entity handling of Genios dumps.
+func (s WISO) Iterate(r io.Reader) (<-chan interface{}, error) {
+	ch := make(chan interface{})
+	var docs []*Document
+	go func() {
+		decoder := genios.NewDecoder(bufio.NewReader(r))
+		for {
+			t, _ := decoder.Token()
+			if t == nil {
+				break
+			}
+			switch se := t.(type) {
+			case xml.StartElement:
+				if se.Name.Local == "Document" {
+					doc := &Document{packages: s.Packages}
+					if err := decoder.DecodeElement(doc, &se); err != nil {
+						log.Fatal(err)
+					}
+					docs = append(docs, doc)
+					if len(docs) == BatchSize {
+						ch <- NewBatch(docs)
+						docs = nil
+					}
+				}
+			}
+		}
+		ch <- NewBatch(docs)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Collection returns the collection name of the package of a document.
+// Documents without package use their database code instead.
+func (doc *Document) Collection() string {
+	id := strings.TrimSpace(doc.Package)
+	if id == "" {
+		id = strings.TrimSpace(doc.Source)
+	}
+	if name, ok := doc.packages[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", Collection, id)
+}
+
+// ToIntermediateSchema converts a document like a Genios document, with
+// WISO source, record id and package collection.
+func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	output, err := doc.Document.ToIntermediateSchema()
+	if err != nil {
+		return output, err
+	}
+	id := fmt.Sprintf("%s__%s", strings.TrimSpace(doc.Source), strings.TrimSpace(doc.ID))
+	enc := fmt.Sprintf("ai-%s-%s", SourceID, base64.URLEncoding.EncodeToString([]byte(id)))
+	output.SourceID = SourceID
+	output.RecordID = strings.TrimRight(enc, "=")
+	output.MegaCollection = doc.Collection()
+	return output, nil
+}
@@ -0,0 +1,65 @@
+package wiso
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/internal/spantest"
+)
+
+const example = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<Documents>
+<Document ID="zfbf_0001">
+	<Source>ZFBF</Source>
+	<Package>wiwi</Package>
+	<ISSN>0000-0019</ISSN>
+	<Publication-Title>Zeitschrift f&uuml;r Betriebswirtschaft</Publication-Title>
+	<Title>Kosten und Nutzen</Title>
+	<Date>20150101</Date>
+	<Authors><Author>Muster, Erika</Author></Authors>
+</Document>
+<Document ID="soz_0002">
+	<Source>SOZ</Source>
+	<Title>Ohne Paket</Title>
+	<Date>20140101</Date>
+</Document>
+</Documents>`
+
+func TestWISO(t *testing.T) {
+	results := spantest.MustConvertAll(t, WISO{}, strings.NewReader(example))
+	if len(results) != 2 {
+		t.Fatalf("got %d records, want 2", len(results))
+	}
+	is := results[0]
+	if is.SourceID != SourceID || is.MegaCollection != "WISO (wiwi)" || is.Database != "ZFBF" {
+		t.Errorf("got source %q, collection %q, database %q", is.SourceID, is.MegaCollection, is.Database)
+	}
+	if is.JournalTitle != "Zeitschrift für Betriebswirtschaft" || is.ArticleTitle != "Kosten und Nutzen" {
+		t.Errorf("got journal %q, title %q", is.JournalTitle, is.ArticleTitle)
+	}
+	if results[1].MegaCollection != "WISO (SOZ)" {
+		t.Errorf("got collection %q for a document without package", results[1].MegaCollection)
+	}
+}
+
+func TestPackages(t *testing.T) {
+	packages, err := ReadPackages(strings.NewReader(`{"wiwi": "WISO Wirtschaftswissenschaften"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := spantest.MustConvertAll(t, WISO{Packages: packages}, strings.NewReader(example))
+	if results[0].MegaCollection != "WISO Wirtschaftswissenschaften" {
+		t.Errorf("got collection %q", results[0].MegaCollection)
+	}
+	tagger, err := span.LoadISILTagger(strings.NewReader(`{"DE-15": [{"collection": ["WISO Wirtschaftswissenschaften"]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isils := tagger.Tags(*results[0]); len(isils) != 1 || isils[0] != "DE-15" {
+		t.Errorf("got ISILs %v, want DE-15", isils)
+	}
+	if isils := tagger.Tags(*results[1]); len(isils) != 0 {
+		t.Errorf("got ISILs %v, want none", isils)
+	}
+}